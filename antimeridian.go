@@ -0,0 +1,140 @@
+package insideout
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/golang/geo/s2"
+	"github.com/twpayne/go-geom"
+)
+
+// The edges of the indexed rings are the shortest arcs between their vertices: a ring crossing the antimeridian
+// can go from 179 to -179 or continue to 181, the loops of the rings around a pole are the spherical ones, and the
+// orientation of a ring is the one of its loop, counterclockwise when its interior is smaller than a hemisphere.
+
+// unwrapLng returns lng shifted by a multiple of 360 degrees to be the closest to prev
+func unwrapLng(prev, lng float64) float64 {
+	return lng - 360*math.Round((lng-prev)/360)
+}
+
+// unwrappedBounds returns the bounds of g with the longitudes of its vertices unwrapped, each one the closest
+// to the previous one, the bounds of a geometry crossing the antimeridian extend beyond 180 instead of spanning the globe
+func unwrappedBounds(g geom.T) *geom.Bounds {
+	coords := g.FlatCoords()
+	stride := g.Stride()
+	b := geom.NewBounds(geom.XY)
+	var prev float64
+	for i := 0; i+1 < len(coords); i += stride {
+		lng := coords[i]
+		if i > 0 {
+			lng = unwrapLng(prev, lng)
+		}
+		prev = lng
+		b.Extend(geom.NewPointFlat(geom.XY, []float64{lng, coords[i+1]}))
+	}
+	return b
+}
+
+// loopPoints returns points without the consecutive duplicates and the spikes, edges going back along the previous one,
+// left by the rings following the antimeridian or reaching a pole where different lng lat are the same point,
+// points is modified
+func loopPoints(points []s2.Point) []s2.Point {
+	out := points[:0]
+	for _, p := range points {
+		n := len(out)
+		switch {
+		case n > 0 && p == out[n-1]:
+		case n > 1 && p == out[n-2]:
+			out = out[:n-1]
+		default:
+			out = append(out, p)
+		}
+	}
+
+	// the same around the closing edge
+	for len(out) > 2 {
+		n := len(out)
+		switch {
+		case out[n-1] == out[0]:
+			out = out[:n-1]
+		case out[n-2] == out[0]:
+			out = out[:n-2]
+		case out[n-1] == out[1]:
+			out = out[1 : n-1]
+		default:
+			return out
+		}
+	}
+	return out
+}
+
+// clockwise returns true if the loop of points covers more than a hemisphere, the loop of a clockwise ring,
+// unlike the planar orientation it holds for the rings crossing the antimeridian or around a pole
+func clockwise(points []s2.Point) bool {
+	return s2.LoopFromPoints(points).Area() > 2*math.Pi
+}
+
+// LongitudeWarnings returns the rings of g with suspicious longitudes, still indexed: the rings crossing
+// the antimeridian, the longitudes beyond 180 and the rings around a pole
+func LongitudeWarnings(g geom.T) []Problem {
+	var problems []Problem
+	check := func(p *geom.Polygon, pi int) {
+		for ri := 0; ri < p.NumLinearRings(); ri++ {
+			for _, msg := range longitudeWarnings(p.LinearRing(ri).FlatCoords(), p.Stride()) {
+				problems = append(problems, Problem{Polygon: pi, Ring: ri, Msg: msg})
+			}
+		}
+	}
+	switch rg := g.(type) {
+	case *geom.Polygon:
+		check(rg, 0)
+	case *geom.MultiPolygon:
+		for i := 0; i < rg.NumPolygons(); i++ {
+			check(rg.Polygon(i), i)
+		}
+	}
+	return problems
+}
+
+// longitudeWarnings returns the suspicious longitudes of the ring c with stride coordinates per point
+func longitudeWarnings(c []float64, stride int) []string {
+	n := len(c) / stride
+	if n < 2 {
+		return nil
+	}
+
+	var warnings []string
+	beyond, crossings := -1, 0
+	var span, prev float64
+	for i := 0; i < n; i++ {
+		lng := c[i*stride]
+		if beyond == -1 && (lng < -180 || lng > 180) {
+			beyond = i
+		}
+		if i == 0 {
+			prev = lng
+			continue
+		}
+		// the edges along a pole or the antimeridian have no length
+		lat, plat := c[i*stride+1], c[(i-1)*stride+1]
+		if math.Abs(lng-c[(i-1)*stride]) > 180 && math.Abs(lat) != 90 && math.Abs(plat) != 90 {
+			crossings++
+		}
+		ulng := unwrapLng(prev, lng)
+		span += ulng - prev
+		prev = ulng
+	}
+
+	if beyond != -1 {
+		warnings = append(warnings, fmt.Sprintf("point #%d longitude %v beyond 180, read as %v",
+			beyond, c[beyond*stride], normalizeLngLat([2]float64{c[beyond*stride], 0})[0]))
+	}
+	if crossings > 0 {
+		warnings = append(warnings, fmt.Sprintf("ring crosses the antimeridian %d times", crossings))
+	}
+	// going around the globe once
+	if math.Abs(span) > 180 {
+		warnings = append(warnings, "ring goes around a pole")
+	}
+	return warnings
+}
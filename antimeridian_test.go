@@ -0,0 +1,139 @@
+package insideout
+
+import (
+	"testing"
+
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom"
+)
+
+func TestAntimeridianPolygons(t *testing.T) {
+	tests := []struct {
+		name    string
+		coords  []float64
+		inside  [][2]float64
+		outside [][2]float64
+	}{
+		{
+			// Fiji, the longitudes jump from 179 to -179
+			"jump",
+			[]float64{178, -18, 179, -18, -179, -18, -179, -16, 179, -16, 178, -16, 178, -18},
+			[][2]float64{{179.5, -17}, {-179.5, -17}},
+			[][2]float64{{0, -17}, {-178, -17}},
+		},
+		{
+			// Chukotka, the longitudes continue beyond 180
+			"beyond 180",
+			[]float64{177, 64, 190, 64, 190, 70, 177, 70, 177, 64},
+			[][2]float64{{179, 67}, {-175, 67}},
+			[][2]float64{{0, 67}, {175, 67}},
+		},
+		{
+			// Antarctica, following the antimeridian to the south pole
+			"south pole",
+			[]float64{-180, -70, -180, -90, 180, -90, 180, -70, 90, -70, 0, -70, -90, -70, -180, -70},
+			[][2]float64{{0, -89}, {45, -80}},
+			[][2]float64{{0, 0}, {0, -60}},
+		},
+		{
+			"north pole",
+			[]float64{0, 80, 120, 80, -120, 80, 0, 80},
+			[][2]float64{{0, 89}, {180, 87}},
+			[][2]float64{{0, 0}, {0, 70}},
+		},
+	}
+
+	coverer := &s2.RegionCoverer{MinLevel: 1, MaxLevel: 10, MaxCells: 16}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := geom.NewPolygonFlat(geom.XY, tt.coords, []int{len(tt.coords)})
+			require.Empty(t, ValidateGeometry(p))
+
+			_, fixes, err := RepairGeometry(p, 0)
+			require.NoError(t, err)
+			require.NotContains(t, fixes, fixReoriented)
+
+			l := LoopFromCoordinates(tt.coords)
+			cu, err := coverPolygon(p, coverer, false)
+			require.NoError(t, err)
+			for _, pt := range tt.inside {
+				ll := s2.LatLngFromDegrees(pt[1], pt[0])
+				require.True(t, l.ContainsPoint(s2.PointFromLatLng(ll)), "inside %v", pt)
+				require.True(t, cu.ContainsCellID(s2.CellIDFromLatLng(ll)), "covering %v", pt)
+			}
+			for _, pt := range tt.outside {
+				ll := s2.LatLngFromDegrees(pt[1], pt[0])
+				require.False(t, l.ContainsPoint(s2.PointFromLatLng(ll)), "outside %v", pt)
+			}
+			// the covering stays around the ring
+			require.False(t, cu.ContainsCellID(s2.CellIDFromLatLng(s2.LatLngFromDegrees(0, 0))))
+		})
+	}
+}
+
+func TestRepairGeometryAntimeridian(t *testing.T) {
+	// clockwise Chukotka
+	p := geom.NewPolygonFlat(geom.XY, []float64{177, 64, 177, 70, 190, 70, 190, 64, 177, 64}, []int{10})
+	g, fixes, err := RepairGeometry(p, 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{fixNormalized, fixReoriented}, fixes)
+	require.Equal(t, []float64{-170, 64, -170, 70, 177, 70, 177, 64, -170, 64}, g.FlatCoords())
+	require.Empty(t, ValidateGeometry(g))
+
+	// a hole across the antimeridian
+	p = geom.NewPolygonFlat(geom.XY, []float64{
+		178, -18, -178, -18, -178, -16, 178, -16, 178, -18,
+		179, -17.5, 179, -16.5, -179, -16.5, -179, -17.5, 179, -17.5,
+	}, []int{10, 20})
+	g, fixes, err = RepairGeometry(p, 0)
+	require.NoError(t, err)
+	require.Nil(t, fixes)
+	require.Equal(t, p, g)
+}
+
+func TestLongitudeWarnings(t *testing.T) {
+	tests := []struct {
+		name   string
+		coords []float64
+		want   []string
+	}{
+		{"none", []float64{2, 48, 3, 48, 3, 49, 2, 49, 2, 48}, nil},
+		{
+			"jump",
+			[]float64{178, -18, 179, -18, -179, -18, -179, -16, 179, -16, 178, -16, 178, -18},
+			[]string{"polygon #0 ring #0: ring crosses the antimeridian 2 times"},
+		},
+		{
+			"beyond 180",
+			[]float64{177, 64, 190, 64, 190, 70, 177, 70, 177, 64},
+			[]string{"polygon #0 ring #0: point #1 longitude 190 beyond 180, read as -170"},
+		},
+		{
+			"along the antimeridian to the pole",
+			[]float64{-180, -70, -90, -70, 0, -70, 90, -70, 180, -70, 180, -90, -180, -90, -180, -70},
+			[]string{"polygon #0 ring #0: ring goes around a pole"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, p := range LongitudeWarnings(geom.NewPolygonFlat(geom.XY, tt.coords, []int{len(tt.coords)})) {
+				got = append(got, p.String())
+			}
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLoopPoints(t *testing.T) {
+	a := s2.PointFromLatLng(s2.LatLngFromDegrees(0, 0))
+	b := s2.PointFromLatLng(s2.LatLngFromDegrees(0, 1))
+	c := s2.PointFromLatLng(s2.LatLngFromDegrees(1, 1))
+	d := s2.PointFromLatLng(s2.LatLngFromDegrees(1, 2))
+
+	require.Equal(t, []s2.Point{a, b, c}, loopPoints([]s2.Point{a, b, b, c, a}))
+	require.Equal(t, []s2.Point{a, b, c}, loopPoints([]s2.Point{a, b, c, d, c}))
+	require.Equal(t, []s2.Point{b, c, d}, loopPoints([]s2.Point{a, b, c, d, b}))
+}
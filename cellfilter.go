@@ -0,0 +1,164 @@
+package insideout
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/golang/geo/s2"
+)
+
+// CellFilter a Bloom filter of the inside and outside cells of an index,
+// a point is contained by an indexed cell only if the filter may contain one of its parents,
+// the points outside of the covers are rejected without reading the cells
+type CellFilter struct {
+	// IndexTime the index time of the DB the filter was built from, the filter is stale for a DB indexed or written later
+	IndexTime time.Time
+
+	// MinLevel and MaxLevel the levels of the indexed cells
+	MinLevel, MaxLevel int
+
+	// Hashes the count of bits set by a cell
+	Hashes uint32
+	Bits   []byte
+}
+
+// CellFilterStore is implemented by the storages persisting a filter of their cells
+type CellFilterStore interface {
+	// StoreCellFilter stores the filter of the cells
+	StoreCellFilter(f *CellFilter) error
+	// LoadCellFilter returns the filter of the cells, nil when it was never stored
+	LoadCellFilter() (*CellFilter, error)
+}
+
+// NewCellFilter returns an empty filter sized for n cells with a false positive rate of fpRate
+func NewCellFilter(n int, fpRate float64) (*CellFilter, error) {
+	if fpRate <= 0 || fpRate >= 1 {
+		return nil, fmt.Errorf("invalid false positive rate %v, 0 < rate < 1", fpRate)
+	}
+	if n < 1 {
+		n = 1
+	}
+	// optimal bits count and hashes count
+	m := math.Ceil(-float64(n) * math.Log(fpRate) / (math.Ln2 * math.Ln2))
+	k := math.Max(1, math.Round(m/float64(n)*math.Ln2))
+	return &CellFilter{
+		MinLevel: -1,
+		MaxLevel: -1,
+		Hashes:   uint32(k),
+		Bits:     make([]byte, (uint64(m)+7)/8),
+	}, nil
+}
+
+// BuildCellFilter returns the filter of all the cells of s with a false positive rate of fpRate
+func BuildCellFilter(s Store, fpRate float64) (*CellFilter, error) {
+	infos, err := s.LoadIndexInfos()
+	if err != nil {
+		return nil, err
+	}
+
+	// the cells are counted first to size the filter, the ones shared by several features are counted twice
+	var n int
+	err = s.LoadFeaturesCells(func(cellsIn []s2.CellUnion, cellsOut []s2.CellUnion, id uint32) {
+		n += CellsCount(cellsIn, cellsOut)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cells from storage: %w", err)
+	}
+
+	f, err := NewCellFilter(n, fpRate)
+	if err != nil {
+		return nil, err
+	}
+	f.IndexTime = infos.IndexTime
+	err = s.LoadFeaturesCells(func(cellsIn []s2.CellUnion, cellsOut []s2.CellUnion, id uint32) {
+		f.AddCells(cellsIn, cellsOut)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cells from storage: %w", err)
+	}
+	return f, nil
+}
+
+// AddCells adds all the cells of the covers
+func (f *CellFilter) AddCells(cus ...[]s2.CellUnion) {
+	for _, cu := range cus {
+		for _, c := range cu {
+			for _, cell := range c {
+				f.Add(cell)
+			}
+		}
+	}
+}
+
+// Add adds the cell c
+func (f *CellFilter) Add(c s2.CellID) {
+	if l := c.Level(); f.MinLevel == -1 {
+		f.MinLevel, f.MaxLevel = l, l
+	} else if l < f.MinLevel {
+		f.MinLevel = l
+	} else if l > f.MaxLevel {
+		f.MaxLevel = l
+	}
+
+	h1, h2 := cellHashes(c)
+	m := uint64(len(f.Bits)) * 8
+	for i := uint64(0); i < uint64(f.Hashes); i++ {
+		bit := (h1 + i*h2) % m
+		f.Bits[bit/8] |= 1 << (bit % 8)
+	}
+}
+
+// MayContain returns false if the cell c was never added
+func (f *CellFilter) MayContain(c s2.CellID) bool {
+	h1, h2 := cellHashes(c)
+	m := uint64(len(f.Bits)) * 8
+	for i := uint64(0); i < uint64(f.Hashes); i++ {
+		bit := (h1 + i*h2) % m
+		if f.Bits[bit/8]&(1<<(bit%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// MayCover returns false if no added cell contains the point at lat lng
+func (f *CellFilter) MayCover(lat, lng float64) bool {
+	if f.MinLevel == -1 {
+		return false
+	}
+	c := s2.CellIDFromLatLng(s2.LatLngFromDegrees(lat, lng))
+	for l := f.MinLevel; l <= f.MaxLevel; l++ {
+		if f.MayContain(c.Parent(l)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate returns an error if the filter can't be queried
+func (f *CellFilter) Validate() error {
+	if len(f.Bits) == 0 || f.Hashes == 0 {
+		return errors.New("invalid empty cell filter")
+	}
+	if f.MinLevel < -1 || f.MaxLevel > 30 || f.MinLevel > f.MaxLevel {
+		return fmt.Errorf("invalid cell filter levels min %d max %d", f.MinLevel, f.MaxLevel)
+	}
+	return nil
+}
+
+// cellHashes returns the 2 hashes of c combined to set the bits, splitmix64 finalizers
+func cellHashes(c s2.CellID) (uint64, uint64) {
+	h1 := mix64(uint64(c))
+	// odd, never 0
+	h2 := mix64(h1) | 1
+	return h1, h2
+}
+
+func mix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
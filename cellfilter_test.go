@@ -0,0 +1,92 @@
+package insideout
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/require"
+)
+
+// cellsStore a Store loading features covered by the same cells inside and outside
+type cellsStore struct {
+	Store
+	infos *IndexInfos
+	cells [][]s2.CellUnion
+}
+
+func (s *cellsStore) LoadIndexInfos() (*IndexInfos, error) {
+	return s.infos, nil
+}
+
+func (s *cellsStore) LoadFeaturesCells(add func([]s2.CellUnion, []s2.CellUnion, uint32)) error {
+	for id, cu := range s.cells {
+		add(cu, cu, uint32(id))
+	}
+	return nil
+}
+
+func TestCellFilter(t *testing.T) {
+	_, err := NewCellFilter(10, 0)
+	require.Error(t, err)
+	_, err = NewCellFilter(10, 1)
+	require.Error(t, err)
+
+	f, err := NewCellFilter(10000, 0.01)
+	require.NoError(t, err)
+	require.NoError(t, f.Validate())
+	require.False(t, f.MayCover(48.8, 2.3))
+
+	r := rand.New(rand.NewSource(42))
+	var added []s2.CellID
+	for i := 0; i < 10000; i++ {
+		c := s2.CellID(r.Uint64() | 1).Parent(10 + r.Intn(6))
+		if !c.IsValid() {
+			continue
+		}
+		f.Add(c)
+		added = append(added, c)
+	}
+	require.Equal(t, 10, f.MinLevel)
+	require.Equal(t, 15, f.MaxLevel)
+	for _, c := range added {
+		require.True(t, f.MayContain(c))
+	}
+
+	var fp int
+	for i := 0; i < 10000; i++ {
+		if f.MayContain(s2.CellID(r.Uint64() | 1).Parent(20)) {
+			fp++
+		}
+	}
+	require.Less(t, fp, 200)
+
+	f.MinLevel = 31
+	require.Error(t, f.Validate())
+	require.Error(t, (&CellFilter{}).Validate())
+}
+
+func TestBuildCellFilter(t *testing.T) {
+	paris := s2.CellIDFromLatLng(s2.LatLngFromDegrees(48.8, 2.3))
+	tokyo := s2.CellIDFromLatLng(s2.LatLngFromDegrees(35.7, 139.7))
+	s := &cellsStore{
+		infos: &IndexInfos{IndexTime: time.Unix(1600000000, 0)},
+		cells: [][]s2.CellUnion{
+			{{paris.Parent(8), paris.Parent(12)}},
+			{{tokyo.Parent(10)}},
+		},
+	}
+
+	f, err := BuildCellFilter(s, 0.001)
+	require.NoError(t, err)
+	require.NoError(t, f.Validate())
+	require.Equal(t, s.infos.IndexTime, f.IndexTime)
+	require.Equal(t, 8, f.MinLevel)
+	require.Equal(t, 12, f.MaxLevel)
+
+	require.True(t, f.MayCover(48.8, 2.3))
+	require.True(t, f.MayCover(35.7, 139.7))
+	require.False(t, f.MayCover(-33.9, 151.2))
+	require.False(t, f.MayCover(40.7, -74))
+}
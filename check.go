@@ -0,0 +1,190 @@
+package insideout
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/golang/geo/s2"
+)
+
+// CheckStore is implemented by the storages able to verify their integrity
+type CheckStore interface {
+	// Check scans the whole database, largest is the number of largest features to report
+	Check(largest int) (*CheckReport, error)
+}
+
+// CheckReport the integrity problems and statistics of a database
+type CheckReport struct {
+	Features int
+	Loops    int
+	Vertices int
+
+	// InvalidFeatures the features with a loop that can't be decoded or is invalid
+	InvalidFeatures []FeatureProblem
+
+	// FeaturesWithoutCells the features without stored cells, they can't be found by the db strategy
+	FeaturesWithoutCells []uint32
+
+	// OrphanEntries the cell entries of a missing feature or loop
+	OrphanEntries int
+
+	// OrphanCells the stored cells of a missing feature
+	OrphanCells []uint32
+
+	// Levels the number of inside and outside cells by S2 level, 0 to 30
+	Levels [31]LevelStats
+
+	// Largest the largest features by encoded size, largest first
+	Largest []FeatureStats
+
+	// Buckets the number of entries and the size of the keys and values by kind of entry
+	Buckets []BucketStats
+}
+
+// FeatureProblem an integrity problem of a feature
+type FeatureProblem struct {
+	ID  uint32
+	Msg string
+}
+
+// LevelStats the number of cells and feature entries at an S2 level
+type LevelStats struct {
+	InsideCells    int
+	InsideEntries  int
+	OutsideCells   int
+	OutsideEntries int
+}
+
+// FeatureStats the size of a stored feature
+type FeatureStats struct {
+	ID       uint32
+	Bytes    int
+	Loops    int
+	Vertices int
+}
+
+// BucketStats the size of a kind of entries
+type BucketStats struct {
+	Name  string
+	Keys  int
+	Bytes int64
+}
+
+// OK returns true when no integrity problem was found
+func (r *CheckReport) OK() bool {
+	return len(r.InvalidFeatures) == 0 && len(r.FeaturesWithoutCells) == 0 && r.OrphanEntries == 0 && len(r.OrphanCells) == 0
+}
+
+// FeatureChecker accumulates the entries of a database in a CheckReport, the features are added first
+type FeatureChecker struct {
+	report  *CheckReport
+	largest int
+	loops   map[uint32]int
+	cells   map[uint32]bool
+	buckets map[string]*BucketStats
+}
+
+// NewFeatureChecker returns a FeatureChecker reporting the largest features
+func NewFeatureChecker(largest int) *FeatureChecker {
+	return &FeatureChecker{
+		report:  &CheckReport{},
+		largest: largest,
+		loops:   make(map[uint32]int),
+		cells:   make(map[uint32]bool),
+		buckets: make(map[string]*BucketStats),
+	}
+}
+
+// AddFeature decodes and validates the loops of the stored feature id, size is the encoded size of the entry
+func (fc *FeatureChecker) AddFeature(fs *FeatureStorage, id uint32, size int) {
+	r := fc.report
+	r.Features++
+	fc.loops[id] = len(fs.LoopsBytes)
+
+	st := FeatureStats{ID: id, Bytes: size, Loops: len(fs.LoopsBytes)}
+	for i, lb := range fs.LoopsBytes {
+		l, err := DecodeLoop(lb)
+		if err != nil {
+			r.InvalidFeatures = append(r.InvalidFeatures, FeatureProblem{ID: id, Msg: fmt.Sprintf("can't decode loop %d: %v", i, err)})
+			continue
+		}
+		if err := l.Validate(); err != nil {
+			r.InvalidFeatures = append(r.InvalidFeatures, FeatureProblem{ID: id, Msg: fmt.Sprintf("invalid loop %d: %v", i, err)})
+		}
+		st.Vertices += l.NumVertices()
+	}
+	if len(fs.LoopsBytes) == 0 {
+		r.InvalidFeatures = append(r.InvalidFeatures, FeatureProblem{ID: id, Msg: "no loops"})
+	}
+	r.Loops += st.Loops
+	r.Vertices += st.Vertices
+
+	if fc.largest <= 0 {
+		return
+	}
+	r.Largest = append(r.Largest, st)
+	sort.Slice(r.Largest, func(i, j int) bool { return r.Largest[i].Bytes > r.Largest[j].Bytes })
+	if len(r.Largest) > fc.largest {
+		r.Largest = r.Largest[:fc.largest]
+	}
+}
+
+// AddCells records the stored cells of the feature id
+func (fc *FeatureChecker) AddCells(id uint32) {
+	fc.cells[id] = true
+	if _, ok := fc.loops[id]; !ok {
+		fc.report.OrphanCells = append(fc.report.OrphanCells, id)
+	}
+}
+
+// AddCell checks the feature entries of an inside or outside cell value v
+func (fc *FeatureChecker) AddCell(c s2.CellID, inside bool, v []byte) {
+	ls := &fc.report.Levels[c.Level()]
+	entries := len(v) / 6
+	if inside {
+		ls.InsideCells++
+		ls.InsideEntries += entries
+	} else {
+		ls.OutsideCells++
+		ls.OutsideEntries += entries
+	}
+
+	for i := 0; i+6 <= len(v); i += 6 {
+		id := binary.BigEndian.Uint32(v[i:])
+		pos := int(binary.BigEndian.Uint16(v[i+4:]))
+		if n, ok := fc.loops[id]; !ok || pos >= n {
+			fc.report.OrphanEntries++
+		}
+	}
+}
+
+// AddEntry adds an entry of size bytes to the bucket stats called name
+func (fc *FeatureChecker) AddEntry(name string, size int) {
+	b, ok := fc.buckets[name]
+	if !ok {
+		b = &BucketStats{Name: name}
+		fc.buckets[name] = b
+	}
+	b.Keys++
+	b.Bytes += int64(size)
+}
+
+// Report returns the report once all the entries are added
+func (fc *FeatureChecker) Report() *CheckReport {
+	r := fc.report
+	r.FeaturesWithoutCells = r.FeaturesWithoutCells[:0]
+	for id := range fc.loops {
+		if !fc.cells[id] {
+			r.FeaturesWithoutCells = append(r.FeaturesWithoutCells, id)
+		}
+	}
+	sort.Slice(r.FeaturesWithoutCells, func(i, j int) bool { return r.FeaturesWithoutCells[i] < r.FeaturesWithoutCells[j] })
+
+	r.Buckets = r.Buckets[:0]
+	for _, b := range fc.buckets {
+		r.Buckets = append(r.Buckets, *b)
+	}
+	sort.Slice(r.Buckets, func(i, j int) bool { return r.Buckets[i].Name < r.Buckets[j].Name })
+	return r
+}
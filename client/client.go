@@ -0,0 +1,453 @@
+// Package client is a Go client of the insided gRPC API: it dials with deadlines and retries,
+// and returns the features as Feature with their properties and go-geom geometries
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/geojson"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/akhenakh/insideout/insidesvc"
+)
+
+const (
+	// DefaultTimeout the deadline of the calls without one
+	DefaultTimeout = 10 * time.Second
+
+	// DefaultRetries the retries of the failed read calls
+	DefaultRetries = 2
+
+	// DefaultRetryBackoff the wait before the first retry, doubled for each next one
+	DefaultRetryBackoff = 100 * time.Millisecond
+
+	// DefaultAPIKeyHeader the gRPC metadata holding the API key, the insided -tenantKeyHeader default
+	DefaultAPIKeyHeader = "X-API-Key"
+)
+
+// Options of a Client, the zero value uses the defaults
+type Options struct {
+	// Timeout the deadline of the calls whose context has none, DefaultTimeout when 0, negative for none
+	Timeout time.Duration
+
+	// Retries the retries of the read calls failing with Unavailable or ResourceExhausted,
+	// DefaultRetries when 0, negative to disable, the writes are never retried
+	Retries int
+
+	// RetryBackoff the wait before the first retry, doubled for each next one, DefaultRetryBackoff when 0
+	RetryBackoff time.Duration
+
+	// TLSConfig connects with TLS, nil for a plaintext connection
+	TLSConfig *tls.Config
+
+	// APIKey sent in the APIKeyHeader metadata of every call, for the tenants and the rate limits, empty for none
+	APIKey string
+
+	// APIKeyHeader the metadata holding APIKey, DefaultAPIKeyHeader when empty
+	APIKeyHeader string
+
+	// Dataset queried by the calls without a dataset, empty for the default dataset of the server
+	Dataset string
+
+	// DialOptions added to the ones of the client
+	DialOptions []grpc.DialOption
+}
+
+// Client of the insided gRPC API, safe for concurrent use
+type Client struct {
+	conn *grpc.ClientConn
+	c    insidesvc.InsideClient
+	opts Options
+}
+
+// retried the read methods retried on transient errors
+var retried = map[string]bool{
+	"/Inside/Within":       true,
+	"/Inside/Get":          true,
+	"/Inside/GetFeature":   true,
+	"/Inside/ListFeatures": true,
+	"/Inside/Nearest":      true,
+	"/Inside/Intersect":    true,
+	"/Inside/Info":         true,
+}
+
+// New returns a Client of the insided at target, host:port, the connection is established in the background
+func New(target string, opts Options) (*Client, error) {
+	opts = opts.withDefaults()
+
+	dopts := []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(opts.unaryInterceptor()),
+		grpc.WithChainStreamInterceptor(opts.streamInterceptor()),
+	}
+	if opts.TLSConfig != nil {
+		dopts = append(dopts, grpc.WithTransportCredentials(credentials.NewTLS(opts.TLSConfig)))
+	} else {
+		dopts = append(dopts, grpc.WithInsecure())
+	}
+	dopts = append(dopts, opts.DialOptions...)
+
+	conn, err := grpc.Dial(target, dopts...)
+	if err != nil {
+		return nil, fmt.Errorf("can't connect to %s: %w", target, err)
+	}
+	return &Client{conn: conn, c: insidesvc.NewInsideClient(conn), opts: opts}, nil
+}
+
+// Close closes the connection
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Raw returns the generated client, for the calls not wrapped by Client,
+// they still have the deadlines, the retries and the API key of the options
+func (c *Client) Raw() insidesvc.InsideClient {
+	return c.c
+}
+
+func (o Options) withDefaults() Options {
+	if o.Timeout == 0 {
+		o.Timeout = DefaultTimeout
+	}
+	if o.Retries == 0 {
+		o.Retries = DefaultRetries
+	}
+	if o.RetryBackoff == 0 {
+		o.RetryBackoff = DefaultRetryBackoff
+	}
+	if o.APIKeyHeader == "" {
+		o.APIKeyHeader = DefaultAPIKeyHeader
+	}
+	return o
+}
+
+// callContext returns ctx with the API key and the default deadline when it has none
+func (o Options) callContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if o.APIKey != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, strings.ToLower(o.APIKeyHeader), o.APIKey)
+	}
+	if _, ok := ctx.Deadline(); ok || o.Timeout < 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, o.Timeout)
+}
+
+// unaryInterceptor applies the deadline and the API key and retries the read calls failing with a transient error
+func (o Options) unaryInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, cancel := o.callContext(ctx)
+		defer cancel()
+
+		backoff := o.RetryBackoff
+		for attempt := 0; ; attempt++ {
+			err := invoker(ctx, method, req, reply, cc, opts...)
+			if err == nil || !retried[method] || attempt >= o.Retries || !transient(err) {
+				return err
+			}
+			select {
+			case <-ctx.Done():
+				return err
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+	}
+}
+
+// streamInterceptor adds the API key to the streams, their deadline is the one of their context
+func (o Options) streamInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string,
+		streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		if o.APIKey != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, strings.ToLower(o.APIKeyHeader), o.APIKey)
+		}
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+}
+
+// transient returns true for the errors worth retrying, the server unreachable or rate limiting
+func transient(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted:
+		return true
+	}
+	return false
+}
+
+func (c *Client) dataset(dataset string) string {
+	if dataset == "" {
+		return c.opts.Dataset
+	}
+	return dataset
+}
+
+// WithinOptions the options of a within query, the zero value returns all the features containing the point
+// with all their properties and without their geometries
+type WithinOptions struct {
+	// Dataset to query, the Options one when empty
+	Dataset string
+	// Fields the properties returned, all when empty
+	Fields []string
+	// Filter conditions on the properties, comma separated key=value or key!=value
+	Filter string
+	// Geometries returns the polygons containing the point
+	Geometries bool
+	// BoundaryDistance computes the distance from the point to the boundary of each feature
+	BoundaryDistance bool
+	// Exact tests the point against every polygon
+	Exact bool
+	// Limit the max features returned, 0 for all
+	Limit int
+	// Hierarchy and DeepestOnly see the WithinRequest, they require a database indexed with -hierarchy
+	Hierarchy   bool
+	DeepestOnly bool
+	// At only returns the features valid at this time on a server with validity properties, the current time when zero
+	At time.Time
+	// Strategy answering the query among the ones loaded by the server, its strategy when empty
+	Strategy string
+}
+
+// NewWithinRequest returns the within request of the point at lat lng with opts, opts can be nil
+func NewWithinRequest(lat, lng float64, opts *WithinOptions) *insidesvc.WithinRequest {
+	if opts == nil {
+		opts = &WithinOptions{}
+	}
+	req := &insidesvc.WithinRequest{
+		Lat:              lat,
+		Lng:              lng,
+		RemoveGeometries: !opts.Geometries,
+		SelectProperties: strings.Join(opts.Fields, ","),
+		Filter:           opts.Filter,
+		Dataset:          opts.Dataset,
+		BoundaryDistance: opts.BoundaryDistance,
+		Exact:            opts.Exact,
+		Limit:            int32(opts.Limit),
+		Hierarchy:        opts.Hierarchy,
+		DeepestOnly:      opts.DeepestOnly,
+		Strategy:         opts.Strategy,
+	}
+	if !opts.At.IsZero() {
+		req.At = opts.At.UnixNano() / int64(time.Millisecond)
+	}
+	return req
+}
+
+func (c *Client) withinRequest(lat, lng float64, opts *WithinOptions) *insidesvc.WithinRequest {
+	req := NewWithinRequest(lat, lng, opts)
+	req.Dataset = c.dataset(req.Dataset)
+	return req
+}
+
+// Within returns the features containing the point at lat lng, opts can be nil
+func (c *Client) Within(ctx context.Context, lat, lng float64, opts *WithinOptions) ([]*Feature, error) {
+	resp, err := c.c.Within(ctx, c.withinRequest(lat, lng, opts))
+	if err != nil {
+		return nil, err
+	}
+	return FeaturesFromResponses(resp.Responses)
+}
+
+// Point a lat lng point of a batch
+type Point struct {
+	Lat, Lng float64
+}
+
+// WithinBatch returns the features containing each point, in the order of points, queried on one stream,
+// opts can be nil, the deadline applies to the whole batch
+func (c *Client) WithinBatch(ctx context.Context, points []Point, opts *WithinOptions) ([][]*Feature, error) {
+	ctx, cancel := c.opts.callContext(ctx)
+	defer cancel()
+
+	stream, err := c.c.WithinStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// the requests are sent while receiving the responses
+	errc := make(chan error, 1)
+	go func() {
+		for _, p := range points {
+			if err := stream.Send(c.withinRequest(p.Lat, p.Lng, opts)); err != nil {
+				errc <- err
+				return
+			}
+		}
+		errc <- stream.CloseSend()
+	}()
+
+	results := make([][]*Feature, 0, len(points))
+	for len(results) < len(points) {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if resp.Error != nil {
+			return nil, resp.Error.Err()
+		}
+		fs, err := FeaturesFromResponses(resp.Responses)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, fs)
+	}
+	if err := <-errc; err != nil && err != io.EOF {
+		return nil, err
+	}
+	if len(results) != len(points) {
+		return nil, fmt.Errorf("%d responses for %d points", len(results), len(points))
+	}
+	return results, nil
+}
+
+// Nearest returns the feature containing the point at lat lng or the closest one up to maxDistance meters,
+// with the distance in meters to its boundary, 0 when inside, nil when none is found,
+// maxDistance 0 uses the server limit
+func (c *Client) Nearest(ctx context.Context, lat, lng, maxDistance float64, dataset string) (*Feature, float64, error) {
+	resp, err := c.c.Nearest(ctx, &insidesvc.NearestRequest{
+		Lat:              lat,
+		Lng:              lng,
+		RemoveGeometries: true,
+		MaxDistance:      maxDistance,
+		Dataset:          c.dataset(dataset),
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	if resp.Response == nil {
+		return nil, 0, nil
+	}
+	f, err := featureFromResponse(resp.Response)
+	if err != nil {
+		return nil, 0, err
+	}
+	return f, resp.Distance, nil
+}
+
+// Intersect returns all the features intersecting g, a point, a linestring or a polygon, following the pages
+func (c *Client) Intersect(ctx context.Context, g geom.T, dataset string) ([]*Feature, error) {
+	gm, err := geometryMessage(g)
+	if err != nil {
+		return nil, err
+	}
+	req := &insidesvc.IntersectRequest{
+		Geometry:         gm,
+		RemoveGeometries: true,
+		Dataset:          c.dataset(dataset),
+	}
+	var features []*Feature
+	for {
+		resp, err := c.c.Intersect(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		fs, err := FeaturesFromResponses(resp.Responses)
+		if err != nil {
+			return nil, err
+		}
+		features = append(features, fs...)
+		if resp.NextCursor == "" {
+			return features, nil
+		}
+		req.Cursor = resp.NextCursor
+	}
+}
+
+// GetFeature returns the feature id with all its polygons
+func (c *Client) GetFeature(ctx context.Context, id uint32, dataset string) (*Feature, error) {
+	return c.getFeature(ctx, &insidesvc.GetFeatureRequest{Id: id, Dataset: c.dataset(dataset)})
+}
+
+// GetFeatureByExternalID returns the feature with the value externalID in the indexer -idProperty property
+func (c *Client) GetFeatureByExternalID(ctx context.Context, externalID, dataset string) (*Feature, error) {
+	return c.getFeature(ctx, &insidesvc.GetFeatureRequest{ExternalId: externalID, Dataset: c.dataset(dataset)})
+}
+
+func (c *Client) getFeature(ctx context.Context, req *insidesvc.GetFeatureRequest) (*Feature, error) {
+	fm, err := c.c.GetFeature(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return featureFromMessage(req.Id, fm)
+}
+
+// ListFeatures calls fn with each feature matching filter, comma separated key=value or key!=value conditions,
+// ordered by id and without their geometries, following the pages, it stops at the first error returned by fn
+func (c *Client) ListFeatures(ctx context.Context, filter string, fields []string, dataset string,
+	fn func(*Feature) error) error {
+	req := &insidesvc.ListFeaturesRequest{
+		Filter:           filter,
+		SelectProperties: strings.Join(fields, ","),
+		Dataset:          c.dataset(dataset),
+	}
+	for {
+		resp, err := c.c.ListFeatures(ctx, req)
+		if err != nil {
+			return err
+		}
+		for _, fresp := range resp.Responses {
+			f, err := featureFromResponse(fresp)
+			if err != nil {
+				return err
+			}
+			if err := fn(f); err != nil {
+				return err
+			}
+		}
+		if resp.NextCursor == "" {
+			return nil
+		}
+		req.Cursor = resp.NextCursor
+	}
+}
+
+// Info returns the server version and the served datasets
+func (c *Client) Info(ctx context.Context) (*insidesvc.InfoResponse, error) {
+	return c.c.Info(ctx, &insidesvc.InfoRequest{})
+}
+
+// InsertFeature indexes f, a polygon or a multipolygon, and returns its id, the server must be read write
+func (c *Client) InsertFeature(ctx context.Context, f *geojson.Feature, dataset string) (uint32, error) {
+	fm, err := featureMessage(f)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.c.InsertFeature(ctx, &insidesvc.InsertFeatureRequest{Feature: fm, Dataset: c.dataset(dataset)})
+	if err != nil {
+		return 0, err
+	}
+	return resp.Id, nil
+}
+
+// UpdateFeature replaces the feature id by f, the server must be read write
+func (c *Client) UpdateFeature(ctx context.Context, id uint32, f *geojson.Feature, dataset string) error {
+	fm, err := featureMessage(f)
+	if err != nil {
+		return err
+	}
+	_, err = c.c.UpdateFeature(ctx, &insidesvc.UpdateFeatureRequest{Id: id, Feature: fm, Dataset: c.dataset(dataset)})
+	return err
+}
+
+// DeleteFeature removes the feature id, the server must be read write
+func (c *Client) DeleteFeature(ctx context.Context, id uint32, dataset string) error {
+	_, err := c.c.DeleteFeature(ctx, &insidesvc.DeleteFeatureRequest{Id: id, Dataset: c.dataset(dataset)})
+	return err
+}
+
+// IsNotFound returns true if err is a NotFound error of the server, like an unknown feature id
+func IsNotFound(err error) bool {
+	return status.Code(err) == codes.NotFound
+}
@@ -0,0 +1,182 @@
+package client
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/geojson"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/insidesvc"
+	"github.com/akhenakh/insideout/server"
+	"github.com/akhenakh/insideout/storage/bbolt"
+)
+
+// setup returns a Client of a read write server holding a square of 1 degree named A at 0,0
+func setup(t *testing.T, opts Options, interceptors ...grpc.UnaryServerInterceptor) (*Client, func()) {
+	tmpFile, err := ioutil.TempFile(os.TempDir(), "insideout-test-")
+	require.NoError(t, err)
+	tmpFile.Close()
+
+	wstorage, wclose, err := bbolt.NewStorage(tmpFile.Name(), log.NewNopLogger())
+	require.NoError(t, err)
+	fc := geojson.FeatureCollection{Features: []*geojson.Feature{{
+		Geometry:   geom.NewPolygonFlat(geom.XY, []float64{0, 0, 1, 0, 1, 1, 0, 1, 0, 0}, []int{10}),
+		Properties: map[string]interface{}{"name": "A"},
+	}}}
+	icoverer := &s2.RegionCoverer{MinLevel: 5, MaxLevel: 12, MaxCells: 24}
+	ocoverer := &s2.RegionCoverer{MinLevel: 5, MaxLevel: 12, MaxCells: 16}
+	require.NoError(t, wstorage.Index(fc, icoverer, ocoverer, 100, "A", "unittest"))
+	require.NoError(t, wclose())
+
+	storage, sclose, err := bbolt.NewRWStorage(tmpFile.Name(), log.NewNopLogger())
+	require.NoError(t, err)
+	s, err := server.New(storage, log.NewNopLogger(), nil, server.Options{
+		Strategy:           insideout.DBStrategy,
+		ReadWrite:          true,
+		NearestMaxDistance: 10000,
+	})
+	require.NoError(t, err)
+
+	lis := bufconn.Listen(1 << 20)
+	gs := grpc.NewServer(grpc.ChainUnaryInterceptor(interceptors...))
+	insidesvc.RegisterInsideServer(gs, s)
+	go gs.Serve(lis)
+
+	opts.DialOptions = append(opts.DialOptions, grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return lis.Dial()
+	}))
+	c, err := New("bufnet", opts)
+	require.NoError(t, err)
+
+	return c, func() {
+		c.Close()
+		gs.Stop()
+		sclose()
+		os.Remove(tmpFile.Name())
+	}
+}
+
+func TestClient(t *testing.T) {
+	c, clean := setup(t, Options{})
+	defer clean()
+	ctx := context.Background()
+
+	fs, err := c.Within(ctx, 0.5, 0.5, &WithinOptions{Geometries: true})
+	require.NoError(t, err)
+	require.Len(t, fs, 1)
+	require.Equal(t, uint32(0), fs[0].ID)
+	require.Equal(t, map[string]interface{}{"name": "A"}, fs[0].Properties)
+	require.IsType(t, &geom.Polygon{}, fs[0].Geometry)
+
+	fs, err = c.Within(ctx, 2, 2, nil)
+	require.NoError(t, err)
+	require.Empty(t, fs)
+
+	batch, err := c.WithinBatch(ctx, []Point{{0.5, 0.5}, {2, 2}, {0.2, 0.8}}, nil)
+	require.NoError(t, err)
+	require.Len(t, batch, 3)
+	require.Len(t, batch[0], 1)
+	require.Empty(t, batch[1])
+	require.Len(t, batch[2], 1)
+
+	f, d, err := c.Nearest(ctx, 0.5, 1.01, 5000, "")
+	require.NoError(t, err)
+	require.NotNil(t, f)
+	require.InDelta(t, 1112, d, 10)
+
+	fs, err = c.Intersect(ctx, geom.NewLineStringFlat(geom.XY, []float64{-1, 0.5, 2, 0.5}), "")
+	require.NoError(t, err)
+	require.Len(t, fs, 1)
+
+	// writes
+	id, err := c.InsertFeature(ctx, &geojson.Feature{
+		Geometry:   geom.NewPolygonFlat(geom.XY, []float64{10, 10, 11, 10, 11, 11, 10, 11, 10, 10}, []int{10}),
+		Properties: map[string]interface{}{"name": "B"},
+	}, "")
+	require.NoError(t, err)
+
+	f, err = c.GetFeature(ctx, id, "")
+	require.NoError(t, err)
+	require.Equal(t, id, f.ID)
+	require.Equal(t, "B", f.Properties["name"])
+
+	var names []string
+	err = c.ListFeatures(ctx, "", nil, "", func(f *Feature) error {
+		names = append(names, f.Properties["name"].(string))
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"A", "B"}, names)
+
+	require.NoError(t, c.DeleteFeature(ctx, id, ""))
+	_, err = c.GetFeature(ctx, id, "")
+	require.True(t, IsNotFound(err))
+
+	_, err = c.InsertFeature(ctx, &geojson.Feature{Geometry: geom.NewPointFlat(geom.XY, []float64{1, 1})}, "")
+	require.Error(t, err)
+
+	// holes
+	id, err = c.InsertFeature(ctx, &geojson.Feature{
+		Geometry: geom.NewPolygonFlat(geom.XYZ, []float64{
+			10, 10, 0, 13, 10, 0, 13, 13, 0, 10, 13, 0, 10, 10, 0,
+			11, 11, 0, 11, 12, 0, 12, 12, 0, 12, 11, 0, 11, 11, 0,
+		}, []int{15, 30}),
+		Properties: map[string]interface{}{"name": "C"},
+	}, "")
+	require.NoError(t, err)
+	fs, err = c.Within(ctx, 11.5, 11.5, nil)
+	require.NoError(t, err)
+	require.Empty(t, fs)
+	f, err = c.GetFeature(ctx, id, "")
+	require.NoError(t, err)
+	require.Equal(t, 2, f.Geometry.(*geom.Polygon).NumLinearRings())
+}
+
+func TestClient_Retries(t *testing.T) {
+	var calls int32
+	unavailable := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+		if atomic.AddInt32(&calls, 1) <= 2 {
+			return nil, status.Error(codes.Unavailable, "not yet")
+		}
+		return handler(ctx, req)
+	}
+
+	c, clean := setup(t, Options{RetryBackoff: time.Millisecond}, unavailable)
+	defer clean()
+
+	fs, err := c.Within(context.Background(), 0.5, 0.5, nil)
+	require.NoError(t, err)
+	require.Len(t, fs, 1)
+	require.Equal(t, int32(3), atomic.LoadInt32(&calls))
+
+	// the writes are not retried
+	atomic.StoreInt32(&calls, 0)
+	_, err = c.InsertFeature(context.Background(), &geojson.Feature{
+		Geometry: geom.NewPolygonFlat(geom.XY, []float64{10, 10, 11, 10, 11, 11, 10, 11, 10, 10}, []int{10}),
+	}, "")
+	require.Equal(t, codes.Unavailable, status.Code(err))
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// retries disabled
+	c2, clean2 := setup(t, Options{Retries: -1}, unavailable)
+	defer clean2()
+	atomic.StoreInt32(&calls, 0)
+	_, err = c2.Within(context.Background(), 0.5, 0.5, nil)
+	require.Equal(t, codes.Unavailable, status.Code(err))
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
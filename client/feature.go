@@ -0,0 +1,222 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/geojson"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/insidesvc"
+)
+
+var errNoGeometry = errors.New("missing geometry")
+
+// Feature a feature returned by the server
+type Feature struct {
+	// ID the id of the feature in the index
+	ID uint32
+
+	// Pos the index of the matched polygon in a multipolygon, 0 for the whole feature
+	Pos uint16
+
+	// Properties without the ones added by insided
+	Properties map[string]interface{}
+
+	// Geometry the matched polygon or the whole feature, nil when not requested
+	Geometry geom.T
+
+	// BoundaryDistance the distance in meters from the point to the boundary, when requested
+	BoundaryDistance float64
+
+	// Exact the point was tested against the polygon, not answered from the covering cells
+	Exact bool
+
+	// AncestorIDs the ids of the features containing this one, when the hierarchy is requested
+	AncestorIDs []uint32
+}
+
+// GeoJSON returns f as a GeoJSON feature with its id
+func (f *Feature) GeoJSON() *geojson.Feature {
+	return &geojson.Feature{
+		ID:         fmt.Sprint(f.ID),
+		Geometry:   f.Geometry,
+		Properties: f.Properties,
+	}
+}
+
+// FeaturesFromResponses returns the features of the feature responses of a within or intersect response
+func FeaturesFromResponses(resps []*insidesvc.FeatureResponse) ([]*Feature, error) {
+	features := make([]*Feature, len(resps))
+	for i, fresp := range resps {
+		f, err := featureFromResponse(fresp)
+		if err != nil {
+			return nil, err
+		}
+		features[i] = f
+	}
+	return features, nil
+}
+
+func featureFromResponse(fresp *insidesvc.FeatureResponse) (*Feature, error) {
+	f, err := featureFromMessage(fresp.Id, fresp.Feature)
+	if err != nil {
+		return nil, err
+	}
+	f.BoundaryDistance = fresp.BoundaryDistance
+	f.Exact = fresp.Exact
+	f.AncestorIDs = fresp.AncestorIds
+	return f, nil
+}
+
+// featureFromMessage returns the feature of fm, id when fm has no id property
+func featureFromMessage(id uint32, fm *insidesvc.Feature) (*Feature, error) {
+	f := &Feature{ID: id}
+	if fm == nil {
+		return f, nil
+	}
+
+	f.Properties = insideout.ValueToProperties(fm.Properties)
+	if v, ok := f.Properties[insidesvc.FeatureIDProperty].(float64); ok {
+		f.ID = uint32(v)
+	}
+	if v, ok := f.Properties[insidesvc.LoopIndexProperty].(float64); ok {
+		f.Pos = uint16(v)
+	}
+	delete(f.Properties, insidesvc.FeatureIDProperty)
+	delete(f.Properties, insidesvc.LoopIndexProperty)
+
+	if fm.Geometry != nil {
+		g, err := geometry(fm.Geometry)
+		if err != nil {
+			return nil, fmt.Errorf("feature %d: %w", f.ID, err)
+		}
+		f.Geometry = g
+	}
+	return f, nil
+}
+
+// geometry returns the go-geom geometry of a geometry message
+func geometry(gm *insidesvc.Geometry) (geom.T, error) {
+	switch gm.Type {
+	case insidesvc.Geometry_POINT:
+		if len(gm.Coordinates) != 2 {
+			return nil, errors.New("invalid point")
+		}
+		return geom.NewPointFlat(geom.XY, gm.Coordinates), nil
+	case insidesvc.Geometry_LINESTRING:
+		if len(gm.Coordinates)%2 != 0 || len(gm.Coordinates) < 2*2 {
+			return nil, errors.New("invalid linestring")
+		}
+		return geom.NewLineStringFlat(geom.XY, gm.Coordinates), nil
+	case insidesvc.Geometry_POLYGON:
+		return polygon(gm.Coordinates, gm.Ends)
+	case insidesvc.Geometry_MULTIPOLYGON:
+		mp := geom.NewMultiPolygon(geom.XY)
+		for _, pm := range gm.Geometries {
+			if pm.Type != insidesvc.Geometry_POLYGON {
+				return nil, errors.New("invalid multipolygon")
+			}
+			p, err := polygon(pm.Coordinates, pm.Ends)
+			if err != nil {
+				return nil, err
+			}
+			if err := mp.Push(p); err != nil {
+				return nil, err
+			}
+		}
+		return mp, nil
+	}
+	return nil, fmt.Errorf("unsupported geometry type %v", gm.Type)
+}
+
+// polygon returns the polygon of the rings c ending at ends, the outer ring then its holes,
+// a single ring without ends
+func polygon(c []float64, ends []uint32) (*geom.Polygon, error) {
+	rends := []int{len(c)}
+	if len(ends) > 0 {
+		rends = make([]int, len(ends))
+		for i, e := range ends {
+			rends[i] = int(e)
+		}
+	}
+	start := 0
+	for _, e := range rends {
+		if e%2 != 0 || e-start < 2*3 {
+			return nil, errors.New("invalid polygon")
+		}
+		start = e
+	}
+	if start != len(c) {
+		return nil, errors.New("invalid polygon")
+	}
+	return geom.NewPolygonFlat(geom.XY, c, rends), nil
+}
+
+// geometryMessage returns the geometry message of a point, a linestring, a polygon or a multipolygon,
+// the polygons with their holes
+func geometryMessage(g geom.T) (*insidesvc.Geometry, error) {
+	switch g := g.(type) {
+	case nil:
+		return nil, errNoGeometry
+	case *geom.Point:
+		return &insidesvc.Geometry{Type: insidesvc.Geometry_POINT, Coordinates: g.FlatCoords()[:2]}, nil
+	case *geom.LineString:
+		return &insidesvc.Geometry{Type: insidesvc.Geometry_LINESTRING, Coordinates: xy(g)}, nil
+	case *geom.Polygon:
+		return polygonMessage(g), nil
+	case *geom.MultiPolygon:
+		gm := &insidesvc.Geometry{Type: insidesvc.Geometry_MULTIPOLYGON}
+		for i := 0; i < g.NumPolygons(); i++ {
+			gm.Geometries = append(gm.Geometries, polygonMessage(g.Polygon(i)))
+		}
+		return gm, nil
+	}
+	return nil, fmt.Errorf("unsupported geometry %T", g)
+}
+
+// polygonMessage returns the polygon message of p, the ends of its rings are set when it has holes
+func polygonMessage(p *geom.Polygon) *insidesvc.Geometry {
+	gm := &insidesvc.Geometry{Type: insidesvc.Geometry_POLYGON, Coordinates: xy(p)}
+	if p.NumLinearRings() > 1 {
+		for _, e := range p.Ends() {
+			gm.Ends = append(gm.Ends, uint32(e/p.Stride()*2))
+		}
+	}
+	return gm
+}
+
+// xy returns the lng lat coordinates of g without the other dimensions
+func xy(g geom.T) []float64 {
+	if g.Stride() == 2 {
+		return g.FlatCoords()
+	}
+	coords := g.FlatCoords()
+	c := make([]float64, 0, len(coords)/g.Stride()*2)
+	for i := 0; i < len(coords); i += g.Stride() {
+		c = append(c, coords[i], coords[i+1])
+	}
+	return c
+}
+
+// featureMessage returns the feature message of a polygon or multipolygon feature
+func featureMessage(f *geojson.Feature) (*insidesvc.Feature, error) {
+	if f == nil {
+		return nil, errNoGeometry
+	}
+	switch f.Geometry.(type) {
+	case *geom.Polygon, *geom.MultiPolygon:
+	default:
+		return nil, fmt.Errorf("unsupported geometry %T, polygon or multipolygon", f.Geometry)
+	}
+	gm, err := geometryMessage(f.Geometry)
+	if err != nil {
+		return nil, err
+	}
+	props, err := insideout.PropertiesToValues(&insideout.Feature{Properties: f.Properties})
+	if err != nil {
+		return nil, err
+	}
+	return &insidesvc.Feature{Geometry: gm, Properties: props}, nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+
+	"github.com/akhenakh/insideout"
+)
+
+// checkDB verifies the integrity of the database at dbPath and logs its statistics,
+// returns false when a problem was found
+func checkDB(dbPath, backend string, largest int, logger log.Logger) (bool, error) {
+	storage, clean, err := openROStorage(dbPath, backend, logger)
+	if err != nil {
+		return false, err
+	}
+	defer clean()
+
+	cs, ok := storage.(insideout.CheckStore)
+	if !ok {
+		return false, fmt.Errorf("check not supported by the storage backend %s", backend)
+	}
+
+	infos, err := storage.LoadIndexInfos()
+	if err != nil {
+		return false, fmt.Errorf("failed to read index infos: %w", err)
+	}
+	level.Info(logger).Log("msg", "checking database", "db_path", dbPath, "infos", infos.String())
+
+	r, err := cs.Check(largest)
+	if err != nil {
+		return false, err
+	}
+
+	for _, p := range r.InvalidFeatures {
+		level.Warn(logger).Log("msg", "invalid feature", "feature_id", p.ID, "problem", p.Msg)
+	}
+	for _, id := range r.FeaturesWithoutCells {
+		level.Warn(logger).Log("msg", "feature without cells", "feature_id", id)
+	}
+	for _, id := range r.OrphanCells {
+		level.Warn(logger).Log("msg", "cells of a missing feature", "feature_id", id)
+	}
+	if r.OrphanEntries > 0 {
+		level.Warn(logger).Log("msg", "cell entries of missing features", "count", r.OrphanEntries)
+	}
+	if uint32(r.Features) > infos.FeatureCount {
+		level.Warn(logger).Log("msg", "more features than the index infos count", "count", r.Features,
+			"feature_count", infos.FeatureCount)
+	}
+
+	for l, ls := range r.Levels {
+		if ls == (insideout.LevelStats{}) {
+			continue
+		}
+		level.Info(logger).Log("msg", "cells level", "level", l,
+			"inside_cells", ls.InsideCells, "inside_entries", ls.InsideEntries,
+			"outside_cells", ls.OutsideCells, "outside_entries", ls.OutsideEntries)
+	}
+	for _, fs := range r.Largest {
+		level.Info(logger).Log("msg", "large feature", "feature_id", fs.ID, "bytes", fs.Bytes,
+			"loops", fs.Loops, "vertices", fs.Vertices)
+	}
+	for _, b := range r.Buckets {
+		level.Info(logger).Log("msg", "bucket size", "name", b.Name, "keys", b.Keys, "bytes", b.Bytes)
+	}
+
+	level.Info(logger).Log("msg", "check done", "ok", r.OK(), "features", r.Features, "loops", r.Loops,
+		"vertices", r.Vertices, "invalid_features", len(r.InvalidFeatures),
+		"features_without_cells", len(r.FeaturesWithoutCells), "orphan_cells", len(r.OrphanCells),
+		"orphan_entries", r.OrphanEntries)
+
+	return r.OK(), nil
+}
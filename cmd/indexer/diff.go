@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+
+	"github.com/akhenakh/insideout"
+)
+
+// diffDatabase describes a compared database in the diff summary
+type diffDatabase struct {
+	Path           string                  `json:"path"`
+	Filename       string                  `json:"filename"`
+	IndexTime      int64                   `json:"index_time"`
+	FeatureCount   uint32                  `json:"feature_count"`
+	IndexerVersion string                  `json:"indexer_version"`
+	InsideCover    *insideout.CoverOptions `json:"inside_cover,omitempty"`
+	OutsideCover   *insideout.CoverOptions `json:"outside_cover,omitempty"`
+}
+
+// diffSummary the JSON summary written by -diff
+type diffSummary struct {
+	Old          diffDatabase `json:"old"`
+	New          diffDatabase `json:"new"`
+	IDProperty   string       `json:"id_property,omitempty"`
+	AddedCount   int          `json:"added_count"`
+	RemovedCount int          `json:"removed_count"`
+	ChangedCount int          `json:"changed_count"`
+	*insideout.DiffReport
+}
+
+// diffDB compares the database at oldPath with the one at newPath and writes the JSON summary to output,
+// the features are matched by idProperty, by feature id when empty, the ignore property is not compared
+func diffDB(oldPath, newPath, backend, idProperty, ignore, output string, logger log.Logger) error {
+	type opened struct {
+		storage insideout.Store
+		db      diffDatabase
+	}
+	open := func(path string) (*opened, func() error, error) {
+		storage, clean, err := openROStorage(path, backend, logger)
+		if err != nil {
+			return nil, nil, err
+		}
+		infos, err := storage.LoadIndexInfos()
+		if err != nil {
+			clean()
+			return nil, nil, fmt.Errorf("failed to read index infos of %s: %w", path, err)
+		}
+		return &opened{storage: storage, db: diffDatabase{
+			Path:           path,
+			Filename:       infos.Filename,
+			IndexTime:      infos.IndexTime.Unix(),
+			FeatureCount:   infos.FeatureCount,
+			IndexerVersion: infos.IndexerVersion,
+			InsideCover:    infos.InsideCover,
+			OutsideCover:   infos.OutsideCover,
+		}}, clean, nil
+	}
+
+	old, oclean, err := open(oldPath)
+	if err != nil {
+		return err
+	}
+	defer oclean()
+	cur, cclean, err := open(newPath)
+	if err != nil {
+		return err
+	}
+	defer cclean()
+
+	var ignored []string
+	if ignore != "" {
+		ignored = append(ignored, ignore)
+	}
+	r, err := insideout.DiffStores(old.storage, cur.storage, idProperty, ignored...)
+	if err != nil {
+		return err
+	}
+
+	s := diffSummary{
+		Old:          old.db,
+		New:          cur.db,
+		IDProperty:   idProperty,
+		AddedCount:   len(r.Added),
+		RemovedCount: len(r.Removed),
+		ChangedCount: len(r.Changed),
+		DiffReport:   r,
+	}
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(output, b, 0o644); err != nil {
+		return err
+	}
+
+	level.Info(logger).Log("msg", "databases compared", "old", oldPath, "new", newPath, "output", output,
+		"added", s.AddedCount, "removed", s.RemovedCount, "changed", s.ChangedCount, "unchanged", r.Unchanged)
+	return nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/twpayne/go-geom/encoding/geojson"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/input/fgb"
+	istorage "github.com/akhenakh/insideout/storage"
+)
+
+// exportDB writes all the features stored in the database at dbPath to path,
+// as FlatGeobuf for a .fgb file, as a GeoJSON FeatureCollection otherwise, returns the number of features
+func exportDB(dbPath, backend, path string, logger log.Logger) (int, error) {
+	storage, clean, err := openROStorage(dbPath, backend, logger)
+	if err != nil {
+		return 0, err
+	}
+	defer clean()
+
+	infos, err := storage.LoadIndexInfos()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read index infos: %w", err)
+	}
+	level.Info(logger).Log("msg", "exporting features", "db_path", dbPath, "export_path", path,
+		"feature_count", infos.FeatureCount, "filename", infos.Filename)
+
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	if strings.EqualFold(filepath.Ext(path), ".fgb") {
+		count, err = exportFlatGeobuf(storage, f)
+	} else {
+		count, err = exportGeoJSON(storage, f)
+	}
+	if err != nil {
+		f.Close()
+		return 0, err
+	}
+	return count, f.Close()
+}
+
+// openROStorage opens the database at dbPath read only
+func openROStorage(dbPath, backend string, logger log.Logger) (insideout.Store, func() error, error) {
+	storage, clean, err := istorage.Open(dbPath, backend, istorage.ReadOnly, logger)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open storage %s: %w", dbPath, err)
+	}
+	return storage, clean, nil
+}
+
+// storedFeature returns the GeoJSON feature of fs with its id, the properties are not copied
+func storedFeature(fs *insideout.FeatureStorage, id uint32) (*geojson.Feature, error) {
+	g, err := insideout.GeoJSONDecodePolygons(fs.LoopsBytes, fs.HolesBytes)
+	if err != nil {
+		return nil, fmt.Errorf("can't decode feature %d: %w", id, err)
+	}
+	return &geojson.Feature{
+		ID:         strconv.FormatUint(uint64(id), 10),
+		Geometry:   g,
+		Properties: fs.Properties,
+	}, nil
+}
+
+// exportGeoJSON streams the features of storage as a FeatureCollection, the features ids are the stored ids
+func exportGeoJSON(storage insideout.Store, f *os.File) (int, error) {
+	w := bufio.NewWriterSize(f, 1<<20)
+	if _, err := w.WriteString(`{"type":"FeatureCollection","features":[`); err != nil {
+		return 0, err
+	}
+
+	var count int
+	err := storage.LoadAllFeatures(func(fs *insideout.FeatureStorage, id uint32) error {
+		gf, err := storedFeature(fs, id)
+		if err != nil {
+			return err
+		}
+		b, err := json.Marshal(gf)
+		if err != nil {
+			return fmt.Errorf("can't encode feature %d: %w", id, err)
+		}
+		if count > 0 {
+			if err := w.WriteByte(','); err != nil {
+				return err
+			}
+		}
+		if _, err := w.Write(append([]byte{'\n'}, b...)); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := w.WriteString("\n]}\n"); err != nil {
+		return 0, err
+	}
+	return count, w.Flush()
+}
+
+// exportFlatGeobuf writes the features of storage as FlatGeobuf, reading them twice to know the columns
+func exportFlatGeobuf(storage insideout.Store, f *os.File) (int, error) {
+	schema := fgb.NewSchema()
+	var total uint64
+	err := storage.LoadAllFeatures(func(fs *insideout.FeatureStorage, id uint32) error {
+		schema.Add(fs.Properties)
+		total++
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	w, err := fgb.NewWriter(f, schema, total)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	err = storage.LoadAllFeatures(func(fs *insideout.FeatureStorage, id uint32) error {
+		gf, err := storedFeature(fs, id)
+		if err != nil {
+			return err
+		}
+		if err := w.Write(gf); err != nil {
+			return fmt.Errorf("can't encode feature %d: %w", id, err)
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, w.Flush()
+}
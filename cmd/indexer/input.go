@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/twpayne/go-geom/encoding/geojson"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/crs"
+	"github.com/akhenakh/insideout/input/fgb"
+	"github.com/akhenakh/insideout/input/gpkg"
+	"github.com/akhenakh/insideout/input/shapefile"
+)
+
+// inputFiles expands a comma separated list of files and glob patterns, a file listed twice is read once
+func inputFiles(list string) ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+
+	for _, p := range strings.Split(list, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+
+		matches := []string{p}
+		if strings.ContainsAny(p, "*?[") {
+			var err error
+			matches, err = filepath.Glob(p)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %s: %w", p, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no file matching %s", p)
+			}
+		}
+
+		for _, m := range matches {
+			if seen[filepath.Clean(m)] {
+				continue
+			}
+			seen[filepath.Clean(m)] = true
+			files = append(files, m)
+		}
+	}
+
+	if len(files) == 0 {
+		return nil, errors.New("no input file")
+	}
+
+	return files, nil
+}
+
+// multiFeatureReader reads the features of several files in sequence,
+// files are opened one at a time
+type multiFeatureReader struct {
+	files          []string
+	pos            int
+	sourceProperty string
+	logger         log.Logger
+
+	current      insideout.FeatureReader
+	closeCurrent func() error
+	// read the count of features read from the current file
+	read int
+}
+
+func newMultiFeatureReader(files []string, sourceProperty string, logger log.Logger) *multiFeatureReader {
+	return &multiFeatureReader{
+		files:          files,
+		sourceProperty: sourceProperty,
+		logger:         logger,
+	}
+}
+
+// Read returns the next feature of the current file, moving to the next file when exhausted
+func (m *multiFeatureReader) Read() (*geojson.Feature, error) {
+	for {
+		if m.current == nil {
+			if m.pos >= len(m.files) {
+				return nil, io.EOF
+			}
+
+			fpath := m.files[m.pos]
+			level.Info(m.logger).Log("msg", "reading input file", "file_path", fpath)
+			r, clean, err := openFeatureReader(fpath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read input file %s: %w", fpath, err)
+			}
+			m.current, m.closeCurrent = r, clean
+			m.read = 0
+		}
+
+		f, err := m.current.Read()
+		if err == io.EOF {
+			if err := m.Close(); err != nil {
+				return nil, err
+			}
+			m.pos++
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read input file %s: %w", m.files[m.pos], err)
+		}
+		m.read++
+
+		// the projected coordinates of a file without CRS would be indexed as degrees
+		if f.Geometry != nil {
+			if err := crs.CheckLatLng(f.Geometry); err != nil {
+				return nil, fmt.Errorf("input file %s feature #%d: %w", m.files[m.pos], m.read-1, err)
+			}
+			for _, p := range insideout.LongitudeWarnings(f.Geometry) {
+				level.Warn(m.logger).Log("msg", "suspicious longitudes", "file_path", m.files[m.pos],
+					"feature", m.read-1, "problem", p.String())
+			}
+		}
+
+		if m.sourceProperty != "" {
+			if f.Properties == nil {
+				f.Properties = make(map[string]interface{})
+			}
+			f.Properties[m.sourceProperty] = filepath.Base(m.files[m.pos])
+		}
+
+		return f, nil
+	}
+}
+
+// Close closes the current file if any
+func (m *multiFeatureReader) Close() error {
+	if m.current == nil {
+		return nil
+	}
+	err := m.closeCurrent()
+	m.current, m.closeCurrent = nil, nil
+	return err
+}
+
+// openFeatureReader returns a reader over the features of the input file,
+// FlatGeobuf (.fgb) files are streamed, other formats are loaded in memory
+func openFeatureReader(fpath string) (insideout.FeatureReader, func() error, error) {
+	if strings.ToLower(filepath.Ext(fpath)) == ".fgb" {
+		r, err := fgb.Open(fpath)
+		if err != nil {
+			return nil, nil, err
+		}
+		return r, r.Close, nil
+	}
+
+	fc, err := readFeatureCollection(fpath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return insideout.NewFeatureCollectionReader(fc), func() error { return nil }, nil
+}
+
+// readFeatureCollection reads the features from a GeoJSON FeatureCollection, a GeoPackage (.gpkg) or a Shapefile (.shp)
+func readFeatureCollection(fpath string) (*geojson.FeatureCollection, error) {
+	switch strings.ToLower(filepath.Ext(fpath)) {
+	case ".gpkg":
+		return gpkg.ReadFeatureCollection(fpath)
+	case ".shp":
+		return shapefile.ReadFeatureCollection(fpath)
+	}
+
+	// reading GeoJSON
+	file, err := os.Open(fpath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open GeoJSON: %w", err)
+	}
+	defer file.Close()
+
+	b, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GeoJSON: %w", err)
+	}
+	fc := &geojson.FeatureCollection{}
+	if err := json.Unmarshal(b, fc); err != nil {
+		return nil, fmt.Errorf("failed to decode GeoJSON: %w", err)
+	}
+
+	// the crs member of the 2008 GeoJSON specification, WGS84 when missing
+	var member geoJSONCRS
+	if err := json.Unmarshal(b, &member); err != nil {
+		return nil, fmt.Errorf("failed to decode GeoJSON: %w", err)
+	}
+	if member.CRS != nil {
+		if member.CRS.Type != "name" {
+			return nil, fmt.Errorf("unsupported GeoJSON crs type %q, only named CRS are supported", member.CRS.Type)
+		}
+		proj, err := crs.FromName(member.CRS.Properties.Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range fc.Features {
+			if f.Geometry != nil {
+				crs.Reproject(f.Geometry, proj)
+			}
+		}
+	}
+
+	return fc, nil
+}
+
+// geoJSONCRS the crs member of a GeoJSON object
+type geoJSONCRS struct {
+	CRS *struct {
+		Type       string `json:"type"`
+		Properties struct {
+			Name string `json:"name"`
+		} `json:"properties"`
+	} `json:"crs"`
+}
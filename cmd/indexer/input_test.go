@@ -0,0 +1,112 @@
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+
+	"github.com/akhenakh/insideout/insidesvc"
+)
+
+// writeInputs writes a GeoJSON FeatureCollection of one square named after each file in dir
+func writeInputs(t *testing.T, dir string, names ...string) {
+	for _, name := range names {
+		fc := `{"type": "FeatureCollection", "features": [{"type": "Feature", "properties": {"name": "` + name + `"},
+			"geometry": {"type": "Polygon", "coordinates": [[[0, 0], [1, 0], [1, 1], [0, 1], [0, 0]]]}}]}`
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(fc), 0600))
+	}
+}
+
+func TestInputFiles(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "indexer-input-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	writeInputs(t, dir, "a.geojson", "b.geojson", "c.json")
+
+	a, b, c := filepath.Join(dir, "a.geojson"), filepath.Join(dir, "b.geojson"), filepath.Join(dir, "c.json")
+	tests := []struct {
+		name    string
+		list    string
+		want    []string
+		wantErr string
+	}{
+		{name: "file", list: a, want: []string{a}},
+		{name: "files", list: c + ", " + a, want: []string{c, a}},
+		{name: "glob", list: filepath.Join(dir, "*.geojson"), want: []string{a, b}},
+		{name: "globs", list: filepath.Join(dir, "*.json") + "," + filepath.Join(dir, "?.geojson"), want: []string{c, a, b}},
+		{name: "duplicates", list: b + "," + filepath.Join(dir, "*.geojson") + "," + b, want: []string{b, a}},
+		{name: "unclean duplicates", list: a + "," + filepath.Join(dir, ".", "a.geojson"), want: []string{a}},
+		{name: "missing file is reported when read", list: filepath.Join(dir, "missing.geojson"),
+			want: []string{filepath.Join(dir, "missing.geojson")}},
+		{name: "no file matching", list: a + "," + filepath.Join(dir, "*.shp"), wantErr: "no file matching"},
+		{name: "invalid pattern", list: filepath.Join(dir, "["), wantErr: "invalid pattern"},
+		{name: "empty", list: "", wantErr: "no input file"},
+		{name: "empty items", list: " , ,", wantErr: "no input file"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := inputFiles(tt.list)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, files)
+		})
+	}
+}
+
+func TestMultiFeatureReader(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "indexer-input-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	writeInputs(t, dir, "a.geojson", "b.geojson")
+
+	files, err := inputFiles(filepath.Join(dir, "*.geojson"))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		sourceProperty string
+		want           []interface{}
+	}{
+		{name: "default source property", sourceProperty: insidesvc.SourceProperty, want: []interface{}{"a.geojson", "b.geojson"}},
+		{name: "custom source property", sourceProperty: "file", want: []interface{}{"a.geojson", "b.geojson"}},
+		{name: "no source property", want: []interface{}{nil, nil}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r := newMultiFeatureReader(files, tt.sourceProperty, log.NewNopLogger())
+			defer r.Close()
+
+			var names, sources []interface{}
+			for {
+				f, err := r.Read()
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err)
+				names = append(names, f.Properties["name"])
+				sources = append(sources, f.Properties[tt.sourceProperty])
+				if tt.sourceProperty == "" {
+					require.NotContains(t, f.Properties, insidesvc.SourceProperty)
+				}
+			}
+			require.Equal(t, []interface{}{"a.geojson", "b.geojson"}, names)
+			require.Equal(t, tt.want, sources)
+		})
+	}
+
+	// a missing file fails the read
+	r := newMultiFeatureReader([]string{filepath.Join(dir, "missing.geojson")}, "", log.NewNopLogger())
+	_, err = r.Read()
+	require.Error(t, err)
+}
@@ -1,19 +1,25 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	stdlog "log"
+	"net/http"
 	"os"
 	"path"
+	"runtime"
+	"strings"
+	"time"
 
 	log "github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
 	"github.com/golang/geo/s2"
 	"github.com/namsral/flag"
-	"github.com/twpayne/go-geom/encoding/geojson"
 
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/index"
+	"github.com/akhenakh/insideout/insidesvc"
 	"github.com/akhenakh/insideout/loglevel"
-	sbbolt "github.com/akhenakh/insideout/storage/bbolt"
+	istorage "github.com/akhenakh/insideout/storage"
 )
 
 /*
@@ -58,13 +64,47 @@ var (
 	insideMaxLevelCover  = flag.Int("insideMaxLevelCover", 16, "Max s2 level for inside cover")
 	insideMinLevelCover  = flag.Int("insideMinLevelCover", 10, "Min s2 level for inside cover")
 	insideMaxCellsCover  = flag.Int("insideMaxCellsCover", 24, "Max s2 Cells count for inside cover")
+	insideLevelModCover  = flag.Int("insideLevelModCover", 1, "s2 level mod for inside cover, only levels with (level - min level) multiple of it are used, 1 to 3")
 	outsideMaxLevelCover = flag.Int("outsideMaxLevelCover", 15, "Max s2 level for outside cover")
 	outsideMinLevelCover = flag.Int("outsideMinLevelCover", 10, "Min s2 level for outside cover")
 	outsideMaxCellsCover = flag.Int("outsideMaxCellsCover", 16, "Max s2 Cells count for outside cover")
+	outsideLevelModCover = flag.Int("outsideLevelModCover", 1, "s2 level mod for outside cover, only levels with (level - min level) multiple of it are used, 1 to 3")
 	warningCellsCover    = flag.Int("warningCellsCover", 1000, "warning limit cover count")
+	workers              = flag.Int("workers", runtime.NumCPU(), "Goroutines covering the features, bbolt only")
+	autoCover            = flag.Bool("autoCover", false, "Tune the cover levels of each feature to its extent, the cover flags are the levels for a feature fitting a cell at the min level")
+	compression          = flag.String("compression", "", "Codec compressing the stored features: snappy|zstd, empty for none, recorded in the DB and decompressed when read, bbolt only")
+	loopEncoding         = flag.String("loopEncoding", insideout.S2LoopEncoding, "Encoding of the stored loops: s2 the vertices as 3 float64, delta the vertices rounded to 1e-7 degrees as varint deltas, about 3 times smaller")
 
-	filePath = flag.String("filePath", "", "FeatureCollection GeoJSON file to index")
-	dbPath   = flag.String("dbPath", "inside.db", "Database path")
+	filePath       = flag.String("filePath", "", "FeatureCollection GeoJSON, GeoPackage (.gpkg), Shapefile (.shp) or FlatGeobuf (.fgb) files to index, comma separated, globs are expanded")
+	sourceProperty = flag.String("sourceProperty", insidesvc.SourceProperty, "Property set to the source file name on each feature, empty to disable")
+	dbPath         = flag.String("dbPath", "inside.db", "Database path")
+
+	storageBackend = flag.String("storageBackend", insideout.BBoltBackend, "Storage backend of the database paths without a backend:// prefix, like leveldb:///data/inside.db: bbolt|leveldb|badger|flat|sqlite")
+
+	appendMode              = flag.Bool("append", false, "Add the features to an existing database instead of creating a new one")
+	idProperty              = flag.String("idProperty", "", "Property holding the unique id of each feature, stored in the property index for insided to get the features by id, in append mode features with the same value as a stored feature replace it, in validate and diff modes the features id, GeoJSON id (feature id for diff) when empty")
+	repair                  = flag.Bool("repair", false, "Repair the geometries before indexing: snapping, closing and reorienting the rings, removing duplicate points and self-intersections")
+	orient                  = flag.Bool("orient", false, "Reorient the rings before indexing, exterior rings counterclockwise and holes clockwise, without the other repairs")
+	repairPrecision         = flag.Float64("repairPrecision", 1e-7, "Grid in degrees the coordinates are snapped to when repairing, 0 to disable snapping")
+	simplifyToleranceMeters = flag.Float64("simplifyToleranceMeters", 0, "Simplify the geometries before indexing, removing the vertices closer than this distance to the simplified edges, 0 to disable")
+	vertexCountProperty     = flag.String("vertexCountProperty", insidesvc.VertexCountProperty, "Property set to the original vertex count of each simplified feature, empty to disable")
+	check                   = flag.Bool("check", false, "Only check the integrity of the database at dbPath: every feature loop and cell entry is decoded, logs the cells per level, the largest features and the size of the buckets, bbolt only")
+	checkLargest            = flag.Int("checkLargest", 10, "Number of largest features reported by -check")
+	diffPath                = flag.String("diff", "", "Only compare the database at this path, the old version, with the one at dbPath and write a JSON summary of the added, removed and changed features to diffOutput, matched by idProperty or by feature id")
+	diffOutput              = flag.String("diffOutput", "diff.json", "File receiving the JSON summary of -diff")
+	exportPath              = flag.String("export", "", "Only export all the features of the database at dbPath to this file, FlatGeobuf for a .fgb file, GeoJSON otherwise, no database is written")
+	validate                = flag.Bool("validate", false, "Only report the invalid geometries and the duplicate ids of the input files, no database is written")
+	resume                  = flag.Bool("resume", false, "Resume an interrupted indexation from its checkpoint, with the same files and cover flags, bbolt only")
+	h3Resolution            = flag.Int("h3Resolution", -1, "Store an H3 cover of the polygons at this resolution, 0 to 15, for the h3 strategy, -1 to disable, bbolt, leveldb and badger only, requires cgo")
+	cellFilterRate          = flag.Float64("cellFilterRate", 0, "Store a Bloom filter of all the indexed cells with this false positive rate, 0.01 for 1%, insided rejects the points outside of the cells without reading them, 0 to disable, bbolt, leveldb and badger only")
+	indexProps              = flag.String("indexProps", "", "Store an index of the features by value of these properties, comma separated, insided filters on them without scanning the features, empty to disable, bbolt, leveldb and badger only")
+	hierarchy               = flag.Bool("hierarchy", false, "Compute the containment hierarchy of all the features after indexing, the parent of a feature is the smallest one containing it, all the polygons are loaded in memory, bbolt, leveldb and badger only")
+
+	preset = flag.String("preset", "", "Flags defaults suited to a known dataset: timezone (timezone-boundary-builder), the flags set explicitly have precedence, empty for none")
+
+	progressInterval = flag.Duration("progressInterval", 10*time.Second, "Interval between the progress logs, 0 to disable")
+	progressAddr     = flag.String("progressAddr", "", "HTTP address serving the progress as JSON on /progress, empty to disable")
+	countFeatures    = flag.Bool("countFeatures", true, "Count the input features before indexing to report the total and an ETA, reads the inputs twice")
 )
 
 func main() {
@@ -78,45 +118,157 @@ func main() {
 
 	level.Info(logger).Log("msg", "Starting app", "version", version)
 
-	var fc geojson.FeatureCollection
+	if *preset != "" {
+		applied, err := applyPreset(flag.CommandLine, *preset)
+		if err != nil {
+			level.Error(logger).Log("msg", "invalid preset", "error", err)
+			os.Exit(2)
+		}
+		level.Info(logger).Log("msg", "applied preset", "preset", *preset, "flags", strings.Join(applied, ","))
+	}
+
+	if *check {
+		ok, err := checkDB(*dbPath, *storageBackend, *checkLargest, logger)
+		if err != nil {
+			level.Error(logger).Log("msg", "check failed", "error", err, "db_path", *dbPath)
+			os.Exit(2)
+		}
+		if !ok {
+			os.Exit(1)
+		}
+		return
+	}
 
-	// reading GeoJSON
-	file, err := os.Open(*filePath)
-	if err != nil {
-		level.Error(logger).Log("msg", "failed to open GeoJSON", "error", err, "file_path", *filePath)
-		os.Exit(2)
+	if *diffPath != "" {
+		if err := diffDB(*diffPath, *dbPath, *storageBackend, *idProperty, *sourceProperty, *diffOutput, logger); err != nil {
+			level.Error(logger).Log("msg", "diff failed", "error", err, "db_path", *dbPath, "diff", *diffPath)
+			os.Exit(2)
+		}
+		return
+	}
+
+	if *exportPath != "" {
+		count, err := exportDB(*dbPath, *storageBackend, *exportPath, logger)
+		if err != nil {
+			level.Error(logger).Log("msg", "export failed", "error", err, "db_path", *dbPath, "export_path", *exportPath)
+			os.Exit(2)
+		}
+		level.Info(logger).Log("msg", "features exported", "count", count, "export_path", *exportPath)
+		return
 	}
-	defer file.Close()
 
-	decoder := json.NewDecoder(file)
-	err = decoder.Decode(&fc)
+	files, err := inputFiles(*filePath)
 	if err != nil {
-		level.Error(logger).Log("msg", "failed to decode GeoJSON", "error", err, "file_path", *filePath)
+		level.Error(logger).Log("msg", "invalid input files", "error", err, "file_path", *filePath)
 		os.Exit(2)
 	}
 
-	storage, clean, err := sbbolt.NewStorage(*dbPath, logger)
+	if *validate {
+		invalid, err := validateFiles(files, *idProperty, *repair, *orient, *repairPrecision, logger)
+		if err != nil {
+			level.Error(logger).Log("msg", "validation failed", "error", err)
+			os.Exit(2)
+		}
+		if invalid > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	var total uint64
+	if *countFeatures {
+		total, err = countInputFeatures(files, logger)
+		if err != nil {
+			level.Error(logger).Log("msg", "can't count features", "error", err)
+			os.Exit(2)
+		}
+		level.Info(logger).Log("msg", "counted features", "total", total)
+	}
+	p := newProgress(total)
+
+	fr := newMultiFeatureReader(files, *sourceProperty, logger)
+	defer fr.Close()
+
+	storage, clean, err := istorage.Open(*dbPath, *storageBackend, istorage.Create, logger)
 	if err != nil {
-		level.Error(logger).Log("msg", "failed to open storage", "error", err, "db_path", *dbPath)
+		level.Error(logger).Log("msg", "failed to open storage", "error", err, "db_path", *dbPath, "storage_backend", *storageBackend)
 		os.Exit(2)
 	}
 	defer clean()
 
-	icoverer := &s2.RegionCoverer{
-		MinLevel: *insideMinLevelCover,
-		MaxLevel: *insideMaxLevelCover,
-		MaxCells: *insideMaxCellsCover,
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if *progressInterval > 0 {
+		go p.run(ctx, logger, *progressInterval)
+	}
+	if *progressAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/progress", p)
+		httpServer := &http.Server{
+			Addr:         *progressAddr,
+			Handler:      mux,
+			ReadTimeout:  5 * time.Second,
+			WriteTimeout: 5 * time.Second,
+		}
+		go func() {
+			level.Info(logger).Log("msg", "progress HTTP server listening", "addr", *progressAddr)
+			if err := httpServer.ListenAndServe(); err != http.ErrServerClosed {
+				level.Error(logger).Log("msg", "progress HTTP server failed", "error", err)
+			}
+		}()
+		defer httpServer.Shutdown(ctx)
 	}
-	ocoverer := &s2.RegionCoverer{
-		MinLevel: *outsideMinLevelCover,
-		MaxLevel: *outsideMaxLevelCover,
-		MaxCells: *outsideMaxCellsCover,
+
+	names := make([]string, len(files))
+	for i, f := range files {
+		names[i] = path.Base(f)
+	}
+
+	backend, _ := istorage.ParseURI(*dbPath, *storageBackend)
+	var props []string
+	if *indexProps != "" {
+		props = strings.Split(*indexProps, ",")
 	}
 
-	err = storage.Index(fc, icoverer, ocoverer, *warningCellsCover, path.Base(*filePath), version)
+	err = index.Build(ctx, p.reader(fr), storage, index.Options{
+		InsideCoverer: &s2.RegionCoverer{
+			MinLevel: *insideMinLevelCover,
+			MaxLevel: *insideMaxLevelCover,
+			MaxCells: *insideMaxCellsCover,
+			LevelMod: *insideLevelModCover,
+		},
+		OutsideCoverer: &s2.RegionCoverer{
+			MinLevel: *outsideMinLevelCover,
+			MaxLevel: *outsideMaxLevelCover,
+			MaxCells: *outsideMaxCellsCover,
+			LevelMod: *outsideLevelModCover,
+		},
+		WarningCellsCover:       *warningCellsCover,
+		Workers:                 *workers,
+		AutoCover:               *autoCover,
+		Compression:             *compression,
+		LoopEncoding:            *loopEncoding,
+		Append:                  *appendMode,
+		Resume:                  *resume,
+		IDProperty:              *idProperty,
+		Repair:                  *repair,
+		RepairPrecision:         *repairPrecision,
+		Orient:                  *orient,
+		SimplifyToleranceMeters: *simplifyToleranceMeters,
+		VertexCountProperty:     *vertexCountProperty,
+		Hierarchy:               *hierarchy,
+		CellFilterRate:          *cellFilterRate,
+		IndexProps:              props,
+		H3Cover:                 *h3Resolution >= 0,
+		H3Resolution:            *h3Resolution,
+		FileName:                strings.Join(names, ","),
+		Version:                 version,
+		Progress:                &p.Progress,
+		Logger:                  log.With(logger, "storage_backend", backend),
+	})
 	if err != nil {
 		level.Error(logger).Log("msg", "indexation failed", "error", err)
 		os.Exit(2)
 	}
-	level.Info(logger).Log("msg", "stored index_infos")
+	p.log(logger)
 }
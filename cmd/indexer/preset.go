@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/namsral/flag"
+)
+
+// presets the flags values suited to known datasets
+var presets = map[string]map[string]string{
+	// timezone-boundary-builder releases, combined.json or combined-with-oceans.json,
+	// a few hundred large features identified by their IANA time zone in tzid
+	"timezone": {
+		"idProperty":           "tzid",
+		"autoCover":            "true",
+		"insideMaxCellsCover":  "64",
+		"outsideMaxCellsCover": "32",
+	},
+}
+
+// applyPreset sets the flags of the preset name not set on the command line or by the environment,
+// returns the names of the flags it set
+func applyPreset(fs *flag.FlagSet, name string) ([]string, error) {
+	preset, ok := presets[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown preset %q", name)
+	}
+
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	var applied []string
+	for k, v := range preset {
+		if set[k] {
+			continue
+		}
+		if err := fs.Set(k, v); err != nil {
+			return nil, fmt.Errorf("can't set %s from preset %s: %w", k, name, err)
+		}
+		applied = append(applied, k)
+	}
+	sort.Strings(applied)
+	return applied, nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/twpayne/go-geom/encoding/geojson"
+
+	"github.com/akhenakh/insideout"
+)
+
+// progress tracks the features read from the inputs and the features and cells indexed by the storage
+type progress struct {
+	insideout.Progress
+
+	read  uint64
+	total uint64
+	start time.Time
+}
+
+// progressReport the progress served as JSON and logged
+type progressReport struct {
+	Read    uint64 `json:"read"`
+	Total   uint64 `json:"total,omitempty"`
+	Indexed uint64 `json:"indexed"`
+	Cells   uint64 `json:"cells"`
+	Elapsed string `json:"elapsed"`
+	ETA     string `json:"eta,omitempty"`
+}
+
+func newProgress(total uint64) *progress {
+	return &progress{total: total, start: time.Now()}
+}
+
+// countingReader counts the features read
+type countingReader struct {
+	insideout.FeatureReader
+	p *progress
+}
+
+func (r *countingReader) Read() (*geojson.Feature, error) {
+	f, err := r.FeatureReader.Read()
+	if err == nil {
+		atomic.AddUint64(&r.p.read, 1)
+	}
+	return f, err
+}
+
+// reader wraps r to count the features read
+func (p *progress) reader(r insideout.FeatureReader) insideout.FeatureReader {
+	return &countingReader{FeatureReader: r, p: p}
+}
+
+func (p *progress) report() progressReport {
+	elapsed := time.Since(p.start)
+	rep := progressReport{
+		Read:    atomic.LoadUint64(&p.read),
+		Total:   p.total,
+		Indexed: p.Features(),
+		Cells:   p.Cells(),
+		Elapsed: elapsed.Round(time.Second).String(),
+	}
+	// the rate only uses the features indexed by this run, not the ones skipped when resuming
+	if rep.Total > rep.Read && rep.Indexed > 0 {
+		rate := float64(rep.Indexed) / elapsed.Seconds()
+		eta := time.Duration(float64(rep.Total-rep.Read) / rate * float64(time.Second))
+		rep.ETA = eta.Round(time.Second).String()
+	}
+	return rep
+}
+
+func (p *progress) log(logger log.Logger) {
+	rep := p.report()
+	level.Info(logger).Log(
+		"msg", "indexing progress",
+		"read", rep.Read,
+		"total", rep.Total,
+		"indexed", rep.Indexed,
+		"cells", rep.Cells,
+		"elapsed", rep.Elapsed,
+		"eta", rep.ETA,
+	)
+}
+
+// run logs the progress every interval until ctx is done
+func (p *progress) run(ctx context.Context, logger log.Logger, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.log(logger)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// ServeHTTP serves the progress as JSON
+func (p *progress) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(p.report()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// countInputFeatures reads all the features of files to count them
+func countInputFeatures(files []string, logger log.Logger) (uint64, error) {
+	fr := newMultiFeatureReader(files, "", logger)
+	defer fr.Close()
+
+	var count uint64
+	for {
+		_, err := fr.Read()
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		count++
+	}
+}
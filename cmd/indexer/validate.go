@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/index"
+)
+
+// validateFiles reads all the features of files and logs their problems without indexing them,
+// features are identified by their index in the file and their GeoJSON id or idProperty value,
+// with repair or orient the problems left after the repair or the reorientation are reported,
+// returns the count of invalid features
+func validateFiles(files []string, idProperty string, repair, orient bool, precision float64, logger log.Logger) (int, error) {
+	var count, invalid int
+	// first position of each id, to report duplicates
+	seen := make(map[string]string)
+
+	for _, fpath := range files {
+		level.Info(logger).Log("msg", "validating input file", "file_path", fpath)
+		r, clean, err := openFeatureReader(fpath)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read input file %s: %w", fpath, err)
+		}
+		if repair {
+			r = index.NewRepairReader(r, precision, logger)
+		} else if orient {
+			r = index.NewOrientReader(r, logger)
+		}
+
+		for i := 0; ; i++ {
+			f, err := r.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				clean()
+				return 0, fmt.Errorf("failed to read input file %s: %w", fpath, err)
+			}
+			count++
+
+			id := index.FeatureID(f, idProperty)
+			pos := fmt.Sprintf("%s#%d", path.Base(fpath), i)
+			flogger := log.With(logger, "file_path", fpath, "feature", i, "feature_id", id)
+
+			problems := insideout.ValidateGeometry(f.Geometry)
+			for _, p := range problems {
+				level.Warn(flogger).Log("msg", "invalid geometry", "problem", p.String())
+			}
+			// indexed as read, not counted as invalid
+			if f.Geometry != nil {
+				for _, p := range insideout.LongitudeWarnings(f.Geometry) {
+					level.Warn(flogger).Log("msg", "suspicious longitudes", "problem", p.String())
+				}
+			}
+
+			duplicate := false
+			if id != "" {
+				if first, ok := seen[id]; ok {
+					level.Warn(flogger).Log("msg", "duplicate feature id", "first_feature", first)
+					duplicate = true
+				} else {
+					seen[id] = pos
+				}
+			}
+
+			if len(problems) > 0 || duplicate {
+				invalid++
+			}
+		}
+
+		if err := clean(); err != nil {
+			return 0, err
+		}
+	}
+
+	level.Info(logger).Log("msg", "validation complete", "features", count, "invalid_features", invalid)
+
+	return invalid, nil
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/bits"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/geo/s2"
+
+	"github.com/akhenakh/insideout/insidesvc"
+)
+
+// queryFunc runs a within query
+type queryFunc func(ctx context.Context, req *insidesvc.WithinRequest) error
+
+// bucket a bucket of the latency histogram, the queries faster than UpperMicros and slower than the previous bucket
+type bucket struct {
+	UpperMicros int64 `json:"upper_micros"`
+	Count       int   `json:"count"`
+}
+
+// result the measures of a benchmark, the durations in microseconds
+type result struct {
+	Target     string  `json:"target"`
+	Queries    int     `json:"queries"`
+	Errors     int     `json:"errors"`
+	Seconds    float64 `json:"seconds"`
+	QPS        float64 `json:"qps"`
+	MeanMicros float64 `json:"mean_micros"`
+	P50Micros  float64 `json:"p50_micros"`
+	P95Micros  float64 `json:"p95_micros"`
+	P99Micros  float64 `json:"p99_micros"`
+	MaxMicros  float64 `json:"max_micros"`
+
+	// AllocsPerQuery and BytesPerQuery the heap allocations of the process per query, the client ones for a remote target
+	AllocsPerQuery float64 `json:"allocs_per_query"`
+	BytesPerQuery  float64 `json:"bytes_per_query"`
+	GCs            uint32  `json:"gcs"`
+
+	Histogram []bucket `json:"histogram"`
+}
+
+// bench runs warmup queries then queries all the points pts with query,
+// concurrency at a time, and returns the measures
+func bench(target string, query queryFunc, pts []s2.LatLng) (*result, error) {
+	newRequest := func(ll s2.LatLng) *insidesvc.WithinRequest {
+		return &insidesvc.WithinRequest{
+			Lat:              ll.Lat.Degrees(),
+			Lng:              ll.Lng.Degrees(),
+			RemoveGeometries: true,
+			Exact:            *exact,
+		}
+	}
+
+	for i := 0; i < *warmup; i++ {
+		if err := query(context.Background(), newRequest(pts[i%len(pts)])); err != nil {
+			return nil, fmt.Errorf("%s: warmup query failed: %w", target, err)
+		}
+	}
+
+	durations := make([]time.Duration, len(pts))
+	var next int64 = -1
+	var errCount int64
+	var firstErr error
+	var errOnce sync.Once
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+
+	var wg sync.WaitGroup
+	for w := 0; w < *concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= len(pts) {
+					return
+				}
+				req := newRequest(pts[i])
+				t := time.Now()
+				err := query(context.Background(), req)
+				durations[i] = time.Since(t)
+				if err != nil {
+					atomic.AddInt64(&errCount, 1)
+					errOnce.Do(func() { firstErr = err })
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	if int(errCount) == len(pts) {
+		return nil, fmt.Errorf("%s: all the queries failed: %w", target, firstErr)
+	}
+
+	n := float64(len(pts))
+	r := &result{
+		Target:         target,
+		Queries:        len(pts),
+		Errors:         int(errCount),
+		Seconds:        elapsed.Seconds(),
+		QPS:            n / elapsed.Seconds(),
+		AllocsPerQuery: float64(after.Mallocs-before.Mallocs) / n,
+		BytesPerQuery:  float64(after.TotalAlloc-before.TotalAlloc) / n,
+		GCs:            after.NumGC - before.NumGC,
+	}
+
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	var total time.Duration
+	for _, d := range durations {
+		total += d
+	}
+	r.MeanMicros = micros(total) / n
+	r.P50Micros = micros(percentile(durations, 50))
+	r.P95Micros = micros(percentile(durations, 95))
+	r.P99Micros = micros(percentile(durations, 99))
+	r.MaxMicros = micros(durations[len(durations)-1])
+	r.Histogram = histogram(durations)
+
+	return r, nil
+}
+
+// percentile returns the duration under which p percent of the sorted durations are, by nearest rank
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(p/100*float64(len(sorted))+0.5) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
+
+func micros(d time.Duration) float64 {
+	return float64(d) / float64(time.Microsecond)
+}
+
+// histogram returns the counts of durations in power of 2 microseconds buckets,
+// from the fastest to the slowest non empty ones
+func histogram(durations []time.Duration) []bucket {
+	counts := make([]int, 64)
+	first, last := len(counts), 0
+	for _, d := range durations {
+		i := bits.Len64(uint64(d / time.Microsecond))
+		counts[i]++
+		if i < first {
+			first = i
+		}
+		if i > last {
+			last = i
+		}
+	}
+	if first > last {
+		return nil
+	}
+	buckets := make([]bucket, 0, last-first+1)
+	for i := first; i <= last; i++ {
+		buckets = append(buckets, bucket{UpperMicros: int64(1) << i, Count: counts[i]})
+	}
+	return buckets
+}
+
+// reporter writes the results
+type reporter struct {
+	format string
+	w      io.Writer
+}
+
+func newReporter(format string, w io.Writer) (*reporter, error) {
+	switch format {
+	case "text", "json":
+		return &reporter{format: format, w: w}, nil
+	}
+	return nil, fmt.Errorf("unknown output format %s", format)
+}
+
+// report writes r as text or as a JSON line
+func (rep *reporter) report(r *result) error {
+	if rep.format == "json" {
+		return json.NewEncoder(rep.w).Encode(r)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %d queries in %.2fs, %.0f q/s, %d errors\n", r.Target, r.Queries, r.Seconds, r.QPS, r.Errors)
+	fmt.Fprintf(&b, "  latency mean %.1fµs p50 %.1fµs p95 %.1fµs p99 %.1fµs max %.1fµs\n",
+		r.MeanMicros, r.P50Micros, r.P95Micros, r.P99Micros, r.MaxMicros)
+	fmt.Fprintf(&b, "  %.1f allocs/query %.0f B/query %d GCs\n", r.AllocsPerQuery, r.BytesPerQuery, r.GCs)
+
+	max := 0
+	for _, bk := range r.Histogram {
+		if bk.Count > max {
+			max = bk.Count
+		}
+	}
+	for _, bk := range r.Histogram {
+		bar := 0
+		if max > 0 {
+			bar = (bk.Count*40 + max - 1) / max
+		}
+		fmt.Fprintf(&b, "  < %9dµs %8d %s\n", bk.UpperMicros, bk.Count, strings.Repeat("#", bar))
+	}
+	_, err := io.WriteString(rep.w, b.String())
+	return err
+}
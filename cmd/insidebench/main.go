@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/golang/geo/s2"
+	"github.com/namsral/flag"
+	"google.golang.org/grpc"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/insidesvc"
+	"github.com/akhenakh/insideout/loglevel"
+	"github.com/akhenakh/insideout/server"
+	"github.com/akhenakh/insideout/storage"
+)
+
+const appName = "insidebench"
+
+var (
+	version = "no version from LDFLAGS"
+
+	logLevel       = flag.String("logLevel", "INFO", "DEBUG|INFO|WARN|ERROR")
+	dbPath         = flag.String("dbPath", "", "Database queried with -strategy, and the coverage of the random points, empty with -insideURI and -pointsFile")
+	storageBackend = flag.String("storageBackend", insideout.BBoltBackend, "Storage backend of the database paths without a backend:// prefix, like leveldb:///data/inside.db: bbolt|leveldb|badger|flat|sqlite")
+	strategy       = flag.String("strategy", insideout.InsideTreeStrategy,
+		"Strategies benchmarked one after the other on the same points, comma separated: insidetree|db|shapeindex|memory|hybrid|h3")
+	insideURI = flag.String("insideURI", "", "insided gRPC URI benchmarked instead of the local strategies, empty to disable")
+	dataset   = flag.String("dataset", "", "Dataset queried with -insideURI, empty for the default dataset")
+
+	pointsFile  = flag.String("pointsFile", "", "File of lat,lng lines replayed in order, empty for random points in the coverage of the database")
+	points      = flag.Int("points", 100000, "Number of random points queried, or the points of -pointsFile replayed until reaching it, 0 to replay the file once")
+	seed        = flag.Int64("seed", 1, "Seed of the random points")
+	warmup      = flag.Int("warmup", 1000, "Queries run before measuring, to fill the caches and build the lazy indexes")
+	concurrency = flag.Int("concurrency", 1, "Queries running concurrently")
+	exact       = flag.Bool("exact", false, "Test the points against all the candidate polygons")
+
+	cacheCount            = flag.Int("cacheCount", 0, "Features cache count of the local strategies, 0 to disable")
+	stopOnFirstFound      = flag.Bool("stopOnFirstFound", false, "Stop at the first polygon found with the local strategies")
+	shapeIndexRegionLevel = flag.Int("shapeIndexRegionLevel", 0, "Level of the shapeindex strategy regions, 0 to disable")
+	pipWorkers            = flag.Int("pipWorkers", 0, "Goroutines testing the candidates of a query concurrently with the local strategies, 0 to disable")
+
+	output = flag.String("output", "text", "Output format: text|json")
+)
+
+func main() {
+	flag.Parse()
+
+	// the reports are written to stdout
+	logger := log.NewJSONLogger(log.NewSyncWriter(os.Stderr))
+	logger = log.With(logger, "caller", log.Caller(5), "ts", log.DefaultTimestampUTC)
+	logger = log.With(logger, "app", appName)
+	logger = loglevel.NewLevelFilterFromString(logger, *logLevel)
+
+	level.Info(logger).Log("msg", "Starting app", "version", version)
+
+	if err := run(logger); err != nil {
+		level.Error(logger).Log("msg", "benchmark failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+func run(logger log.Logger) error {
+	rep, err := newReporter(*output, os.Stdout)
+	if err != nil {
+		return err
+	}
+	if *concurrency < 1 {
+		return errors.New("concurrency must be at least 1")
+	}
+
+	var storage insideout.Store
+	if *dbPath != "" {
+		s, clean, err := openStorage(*dbPath, *storageBackend, logger)
+		if err != nil {
+			return fmt.Errorf("can't open storage %s: %w", *dbPath, err)
+		}
+		defer clean()
+		storage = s
+	}
+
+	pts, err := loadPoints(storage)
+	if err != nil {
+		return err
+	}
+	level.Info(logger).Log("msg", "benchmarking", "points", len(pts), "warmup", *warmup, "concurrency", *concurrency)
+
+	if *insideURI != "" {
+		conn, err := grpc.Dial(*insideURI, grpc.WithInsecure())
+		if err != nil {
+			return fmt.Errorf("can't connect to %s: %w", *insideURI, err)
+		}
+		defer conn.Close()
+		c := insidesvc.NewInsideClient(conn)
+		r, err := bench(*insideURI, func(ctx context.Context, req *insidesvc.WithinRequest) error {
+			req.Dataset = *dataset
+			_, err := c.Within(ctx, req)
+			return err
+		}, pts)
+		if err != nil {
+			return err
+		}
+		return rep.report(r)
+	}
+
+	if storage == nil {
+		return errors.New("a local benchmark needs a database, set dbPath")
+	}
+	for _, name := range strings.Split(*strategy, ",") {
+		start := time.Now()
+		s, err := server.New(storage, log.NewNopLogger(), nil, server.Options{
+			Strategy:              name,
+			CacheCount:            *cacheCount,
+			StopOnFirstFound:      *stopOnFirstFound,
+			ShapeIndexRegionLevel: *shapeIndexRegionLevel,
+			PIPWorkers:            *pipWorkers,
+		})
+		if err != nil {
+			return fmt.Errorf("strategy %s: %w", name, err)
+		}
+		level.Info(logger).Log("msg", "strategy loaded", "strategy", name, "load_time", time.Since(start))
+
+		r, err := bench(name, func(ctx context.Context, req *insidesvc.WithinRequest) error {
+			_, err := s.Within(ctx, req)
+			return err
+		}, pts)
+		if err != nil {
+			return err
+		}
+		if err := rep.report(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// openStorage opens the database at path read only
+func openStorage(path, backend string, logger log.Logger) (insideout.Store, func() error, error) {
+	return storage.Open(path, backend, storage.ReadOnly, logger)
+}
+
+// loadPoints returns the points of pointsFile, or random points in the coverage of storage
+func loadPoints(storage insideout.Store) ([]s2.LatLng, error) {
+	if *pointsFile != "" {
+		f, err := os.Open(*pointsFile)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		pts, err := readPoints(f)
+		if err != nil {
+			return nil, fmt.Errorf("can't read %s: %w", *pointsFile, err)
+		}
+		if len(pts) == 0 {
+			return nil, fmt.Errorf("no points in %s", *pointsFile)
+		}
+		// replayed in order until reaching points
+		for i := len(pts); i < *points; i++ {
+			pts = append(pts, pts[i%len(pts)])
+		}
+		return pts, nil
+	}
+
+	if storage == nil {
+		return nil, errors.New("random points need a database, set dbPath or pointsFile")
+	}
+	if *points <= 0 {
+		return nil, errors.New("points must be positive for random points")
+	}
+	infos, err := storage.LoadIndexInfos()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index infos: %w", err)
+	}
+	coverage := infos.Coverage
+	if coverage == nil {
+		// older DBs
+		if coverage, err = insideout.ComputeCoverage(storage); err != nil {
+			return nil, err
+		}
+	}
+	if len(coverage) == 0 {
+		return nil, errors.New("empty coverage, no features in the database")
+	}
+	return randomPoints(coverage, *points, rand.New(rand.NewSource(*seed))), nil
+}
+
+// readPoints reads the lat,lng lines of r, empty lines and lines starting with # are skipped
+func readPoints(r io.Reader) ([]s2.LatLng, error) {
+	var pts []s2.LatLng
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.Split(line, ",")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("line %d: expected lat,lng", n)
+		}
+		lat, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid lat: %w", n, err)
+		}
+		lng, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid lng: %w", n, err)
+		}
+		pts = append(pts, s2.LatLngFromDegrees(lat, lng))
+	}
+	return pts, scanner.Err()
+}
+
+// randomPoints returns n points uniformly distributed in the cells of coverage
+func randomPoints(coverage s2.CellUnion, n int, rnd *rand.Rand) []s2.LatLng {
+	// the cells picked by area
+	areas := make([]float64, len(coverage))
+	var total float64
+	for i, c := range coverage {
+		total += s2.CellFromCellID(c).ApproxArea()
+		areas[i] = total
+	}
+
+	pts := make([]s2.LatLng, n)
+	for i := range pts {
+		ci := sort.SearchFloat64s(areas, rnd.Float64()*total)
+		if ci == len(areas) {
+			ci--
+		}
+		c := coverage[ci]
+		// a random leaf cell of c, the leaves are every other cell id of its range
+		leaves := uint64(c.RangeMax()-c.RangeMin())/2 + 1
+		leaf := c.RangeMin() + s2.CellID(2*(rnd.Uint64()%leaves))
+		pts[i] = leaf.LatLng()
+	}
+	return pts
+}
@@ -18,6 +18,9 @@ var (
 	lat       = flag.Float64("lat", 48.8, "Lat")
 	lng       = flag.Float64("lng", 2.2, "Lng")
 	count     = flag.Int("count", 1, "how many requests to perform")
+	fields    = flag.String("fields", "", "comma separated list of properties to return, empty for all")
+	filter    = flag.String("filter", "", "comma separated list of conditions on properties key=value or key!=value")
+	strategy  = flag.String("strategy", "", "strategy answering the queries among the ones loaded by insided, empty for its -strategy")
 )
 
 func main() {
@@ -38,8 +41,11 @@ func main() {
 
 	for i := 0; i < *count; i++ {
 		resps, err := c.Within(ctx, &insidesvc.WithinRequest{
-			Lat: *lat,
-			Lng: *lng,
+			Lat:              *lat,
+			Lng:              *lng,
+			SelectProperties: *fields,
+			Filter:           *filter,
+			Strategy:         *strategy,
 		})
 		if err != nil {
 			log.Fatal(err)
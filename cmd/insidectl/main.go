@@ -0,0 +1,365 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/namsral/flag"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/akhenakh/insideout/insidesvc"
+)
+
+const usage = `Usage: insidectl [flags] command [command flags]
+
+Commands:
+  within   features containing a point
+  batch    features containing each "lat,lng" line read from stdin or -file
+  nearest  feature containing a point or the closest one
+  info     served datasets and their index infos
+  reload   reload the databases of insided, on the metrics port
+  compact  write a compacted copy of a bbolt database of insided, on the metrics port
+  version  version of insidectl
+
+Flags:
+`
+
+var (
+	version = "no version from LDFLAGS"
+
+	insideURI = flag.String("insideURI", "localhost:9200", "insided grpc URI")
+	adminURL  = flag.String("adminURL", "http://localhost:8088", "insided metrics port URL, for reload and compact")
+	output    = flag.String("output", "table", "Output format: table|json")
+	timeout   = flag.Duration("timeout", 10*time.Second, "Timeout of a command")
+
+	tlsCA   = flag.String("tlsCA", "", "CA certificates file verifying insided, enables TLS")
+	tlsCert = flag.String("tlsCert", "", "TLS client certificate file, for mTLS")
+	tlsKey  = flag.String("tlsKey", "", "TLS client private key file, for mTLS")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(flag.Arg(0), flag.Args()[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "insidectl:", err)
+		os.Exit(1)
+	}
+}
+
+func run(cmd string, args []string) error {
+	if cmd == "version" {
+		fmt.Println(version)
+		return nil
+	}
+
+	p, err := newPrinter(*output, os.Stdout)
+	if err != nil {
+		return err
+	}
+	defer p.Flush()
+
+	tlsConfig, err := newTLSConfig(*tlsCA, *tlsCert, *tlsKey)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	switch cmd {
+	case "reload":
+		return reload(ctx, p, tlsConfig, args)
+	case "compact":
+		return compact(ctx, p, tlsConfig, args)
+	}
+
+	opts := []grpc.DialOption{grpc.WithInsecure()}
+	if tlsConfig != nil {
+		opts = []grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))}
+	}
+	conn, err := grpc.DialContext(ctx, *insideURI, opts...)
+	if err != nil {
+		return fmt.Errorf("can't connect to %s: %w", *insideURI, err)
+	}
+	defer conn.Close()
+
+	c := insidesvc.NewInsideClient(conn)
+
+	switch cmd {
+	case "within":
+		return within(ctx, c, p, args)
+	case "batch":
+		return batch(ctx, c, p, args)
+	case "nearest":
+		return nearest(ctx, c, p, args)
+	case "info":
+		return info(ctx, c, p, args)
+	default:
+		return fmt.Errorf("unknown command %s", cmd)
+	}
+}
+
+func within(ctx context.Context, c insidesvc.InsideClient, p printer, args []string) error {
+	fs := flag.NewFlagSet("within", flag.ExitOnError)
+	lat := fs.Float64("lat", 0, "Lat")
+	lng := fs.Float64("lng", 0, "Lng")
+	fields := fs.String("fields", "", "comma separated list of properties to return, empty for all")
+	filter := fs.String("filter", "", "comma separated list of conditions on properties key=value or key!=value")
+	dataset := fs.String("dataset", "", "dataset to query, empty for the default dataset")
+	geometries := fs.Bool("geometries", false, "return the features geometries")
+	boundaryDistance := fs.Bool("boundaryDistance", false, "return the distance in meters to the features boundaries")
+	exact := fs.Bool("exact", false, "test the point against all the polygons")
+	at := fs.String("at", "", "only return the features valid at this RFC 3339 time or date, empty for now")
+	fs.Parse(args)
+
+	var atMillis int64
+	if *at != "" {
+		t, err := time.Parse(time.RFC3339, *at)
+		if err != nil {
+			if t, err = time.Parse("2006-01-02", *at); err != nil {
+				return fmt.Errorf("invalid at time %s", *at)
+			}
+		}
+		atMillis = t.UnixNano() / int64(time.Millisecond)
+	}
+
+	resp, err := c.Within(ctx, &insidesvc.WithinRequest{
+		Lat:              *lat,
+		Lng:              *lng,
+		RemoveGeometries: !*geometries,
+		SelectProperties: *fields,
+		Filter:           *filter,
+		Dataset:          *dataset,
+		BoundaryDistance: *boundaryDistance,
+		Exact:            *exact,
+		At:               atMillis,
+	})
+	if err != nil {
+		return err
+	}
+
+	return p.Within(resp)
+}
+
+func batch(ctx context.Context, c insidesvc.InsideClient, p printer, args []string) error {
+	fs := flag.NewFlagSet("batch", flag.ExitOnError)
+	file := fs.String("file", "", "file of lat,lng lines, empty for stdin")
+	fields := fs.String("fields", "", "comma separated list of properties to return, empty for all")
+	filter := fs.String("filter", "", "comma separated list of conditions on properties key=value or key!=value")
+	dataset := fs.String("dataset", "", "dataset to query, empty for the default dataset")
+	fs.Parse(args)
+
+	var r io.Reader = os.Stdin
+	if *file != "" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	stream, err := c.WithinStream(ctx)
+	if err != nil {
+		return err
+	}
+
+	// send the points while receiving the responses
+	errc := make(chan error, 1)
+	go func() {
+		errc <- sendPoints(r, func(lat, lng float64) error {
+			return stream.Send(&insidesvc.WithinRequest{
+				Lat:              lat,
+				Lng:              lng,
+				RemoveGeometries: true,
+				SelectProperties: *fields,
+				Filter:           *filter,
+				Dataset:          *dataset,
+			})
+		})
+		stream.CloseSend()
+	}()
+
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := p.Within(resp); err != nil {
+			return err
+		}
+	}
+
+	return <-errc
+}
+
+// sendPoints calls send for each lat,lng line of r, empty lines and lines starting with # are skipped
+func sendPoints(r io.Reader, send func(lat, lng float64) error) error {
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.Split(line, ",")
+		if len(parts) != 2 {
+			return fmt.Errorf("line %d: expected lat,lng", n)
+		}
+		lat, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+		if err != nil {
+			return fmt.Errorf("line %d: invalid lat: %w", n, err)
+		}
+		lng, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil {
+			return fmt.Errorf("line %d: invalid lng: %w", n, err)
+		}
+		if err := send(lat, lng); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func nearest(ctx context.Context, c insidesvc.InsideClient, p printer, args []string) error {
+	fs := flag.NewFlagSet("nearest", flag.ExitOnError)
+	lat := fs.Float64("lat", 0, "Lat")
+	lng := fs.Float64("lng", 0, "Lng")
+	maxDistance := fs.Float64("maxDistance", 0, "max distance in meters, 0 for the server limit")
+	dataset := fs.String("dataset", "", "dataset to query, empty for the default dataset")
+	geometries := fs.Bool("geometries", false, "return the feature geometry")
+	fs.Parse(args)
+
+	resp, err := c.Nearest(ctx, &insidesvc.NearestRequest{
+		Lat:              *lat,
+		Lng:              *lng,
+		RemoveGeometries: !*geometries,
+		MaxDistance:      *maxDistance,
+		Dataset:          *dataset,
+	})
+	if err != nil {
+		return err
+	}
+
+	return p.Nearest(resp)
+}
+
+func info(ctx context.Context, c insidesvc.InsideClient, p printer, args []string) error {
+	fs := flag.NewFlagSet("info", flag.ExitOnError)
+	fs.Parse(args)
+
+	resp, err := c.Info(ctx, &insidesvc.InfoRequest{})
+	if err != nil {
+		return err
+	}
+
+	return p.Info(resp)
+}
+
+// reload is exposed on the HTTP metrics port only, not on the public gRPC API
+func reload(ctx context.Context, p printer, tlsConfig *tls.Config, args []string) error {
+	fs := flag.NewFlagSet("reload", flag.ExitOnError)
+	fs.Parse(args)
+
+	b, err := postAdmin(ctx, tlsConfig, "/admin/reload", nil)
+	if err != nil {
+		return fmt.Errorf("reload failed %w", err)
+	}
+	return p.Reload(b)
+}
+
+// compact is exposed on the HTTP metrics port only, the compaction of a large DB may exceed the default timeout
+func compact(ctx context.Context, p printer, tlsConfig *tls.Config, args []string) error {
+	fs := flag.NewFlagSet("compact", flag.ExitOnError)
+	path := fs.String("path", "", "Path of the compacted copy, on the insided host, must not exist")
+	dataset := fs.String("dataset", "", "Dataset to compact, the default one when empty")
+	fs.Parse(args)
+	if *path == "" {
+		return errors.New("compact requires -path")
+	}
+
+	b, err := postAdmin(ctx, tlsConfig, "/admin/compact", url.Values{"path": {*path}, "dataset": {*dataset}})
+	if err != nil {
+		return fmt.Errorf("compact failed %w", err)
+	}
+	return p.Compact(b)
+}
+
+// postAdmin posts form to the endpoint on adminURL, returns the body of a 200 response
+func postAdmin(ctx context.Context, tlsConfig *tls.Config, endpoint string, form url.Values) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(*adminURL, "/")+endpoint,
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(b)))
+	}
+	return b, nil
+}
+
+// newTLSConfig returns nil when ca is empty
+func newTLSConfig(ca, cert, key string) (*tls.Config, error) {
+	if ca == "" {
+		if cert != "" || key != "" {
+			return nil, errors.New("tlsCert and tlsKey require tlsCA")
+		}
+		return nil, nil
+	}
+
+	b, err := ioutil.ReadFile(ca)
+	if err != nil {
+		return nil, fmt.Errorf("can't read CA certificates: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("no CA certificate found in %s", ca)
+	}
+	config := &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+
+	if cert != "" || key != "" {
+		c, err := tls.LoadX509KeyPair(cert, key)
+		if err != nil {
+			return nil, fmt.Errorf("can't load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{c}
+	}
+
+	return config, nil
+}
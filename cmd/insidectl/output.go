@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	structpb "github.com/golang/protobuf/ptypes/struct"
+
+	"github.com/akhenakh/insideout/insidesvc"
+)
+
+// printer writes the responses in an output format
+type printer interface {
+	Within(resp *insidesvc.WithinResponse) error
+	Nearest(resp *insidesvc.NearestResponse) error
+	Info(resp *insidesvc.InfoResponse) error
+	Reload(body []byte) error
+	Compact(body []byte) error
+	Flush() error
+}
+
+func newPrinter(format string, w io.Writer) (printer, error) {
+	switch format {
+	case "json":
+		return &jsonPrinter{w: w, m: &jsonpb.Marshaler{}}, nil
+	case "table":
+		return &tablePrinter{w: tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %s", format)
+	}
+}
+
+// jsonPrinter writes one JSON object per line
+type jsonPrinter struct {
+	w io.Writer
+	m *jsonpb.Marshaler
+}
+
+func (p *jsonPrinter) print(msg proto.Message) error {
+	if err := p.m.Marshal(p.w, msg); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(p.w)
+	return err
+}
+
+func (p *jsonPrinter) Within(resp *insidesvc.WithinResponse) error   { return p.print(resp) }
+func (p *jsonPrinter) Nearest(resp *insidesvc.NearestResponse) error { return p.print(resp) }
+func (p *jsonPrinter) Info(resp *insidesvc.InfoResponse) error       { return p.print(resp) }
+
+func (p *jsonPrinter) Reload(body []byte) error {
+	_, err := fmt.Fprintln(p.w, strings.TrimSpace(string(body)))
+	return err
+}
+
+func (p *jsonPrinter) Compact(body []byte) error { return p.Reload(body) }
+
+func (p *jsonPrinter) Flush() error { return nil }
+
+// tablePrinter writes aligned columns, the header is written once
+type tablePrinter struct {
+	w      *tabwriter.Writer
+	header bool
+}
+
+func (p *tablePrinter) row(header string, cols ...interface{}) {
+	if !p.header {
+		fmt.Fprintln(p.w, header)
+		p.header = true
+	}
+	for i, c := range cols {
+		if i > 0 {
+			fmt.Fprint(p.w, "\t")
+		}
+		fmt.Fprint(p.w, c)
+	}
+	fmt.Fprintln(p.w)
+}
+
+func (p *tablePrinter) Within(resp *insidesvc.WithinResponse) error {
+	const header = "LAT\tLNG\tID\tBOUNDARY\tPROPERTIES"
+	if resp.Error != nil {
+		p.row(header, resp.Point.GetLat(), resp.Point.GetLng(), "-", "", "error: "+resp.Error.Message)
+		return nil
+	}
+	if len(resp.Responses) == 0 {
+		p.row(header, resp.Point.GetLat(), resp.Point.GetLng(), "-", "", "")
+	}
+	for _, fr := range resp.Responses {
+		boundary := "-"
+		if fr.BoundaryDistance > 0 {
+			boundary = strconv.FormatFloat(fr.BoundaryDistance, 'f', 1, 64)
+		}
+		p.row(header, resp.Point.GetLat(), resp.Point.GetLng(), fr.Id, boundary, properties(fr.Feature.GetProperties()))
+	}
+	return nil
+}
+
+func (p *tablePrinter) Nearest(resp *insidesvc.NearestResponse) error {
+	const header = "LAT\tLNG\tID\tDISTANCE\tPROPERTIES"
+	if resp.Response == nil {
+		p.row(header, resp.Point.GetLat(), resp.Point.GetLng(), "-", "", "")
+		return nil
+	}
+	p.row(header, resp.Point.GetLat(), resp.Point.GetLng(), resp.Response.Id,
+		strconv.FormatFloat(resp.Distance, 'f', 1, 64), properties(resp.Response.Feature.GetProperties()))
+	return nil
+}
+
+func (p *tablePrinter) Info(resp *insidesvc.InfoResponse) error {
+	const header = "DATASET\tDEFAULT\tFILES\tFEATURES\tINDEXED\tINDEXER\tMIN LEVEL\tSTRATEGY"
+	for _, ds := range resp.Datasets {
+		p.row(header, ds.Name, ds.Name == resp.DefaultDataset, ds.Filename, ds.FeatureCount,
+			time.Unix(ds.IndexTime, 0).UTC().Format(time.RFC3339), ds.IndexerVersion, ds.MinCoverLevel, ds.Strategy)
+	}
+	return nil
+}
+
+func (p *tablePrinter) Reload(body []byte) error {
+	p.row("STATUS", "reloaded")
+	return nil
+}
+
+func (p *tablePrinter) Compact(body []byte) error {
+	var resp struct {
+		Dataset string `json:"dataset"`
+		Path    string `json:"path"`
+		Size    int64  `json:"size"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return fmt.Errorf("invalid compact response: %w", err)
+	}
+	p.row("STATUS\tDATASET\tPATH\tSIZE", "compacted", resp.Dataset, resp.Path, resp.Size)
+	return nil
+}
+
+func (p *tablePrinter) Flush() error {
+	return p.w.Flush()
+}
+
+// properties formats the properties as sorted key=value pairs
+func properties(props map[string]*structpb.Value) string {
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(valueString(props[k]))
+	}
+	return b.String()
+}
+
+func valueString(v *structpb.Value) string {
+	switch k := v.GetKind().(type) {
+	case *structpb.Value_StringValue:
+		return k.StringValue
+	case *structpb.Value_NumberValue:
+		return strconv.FormatFloat(k.NumberValue, 'f', -1, 64)
+	case *structpb.Value_BoolValue:
+		return strconv.FormatBool(k.BoolValue)
+	case *structpb.Value_NullValue, nil:
+		return "null"
+	default:
+		s, err := (&jsonpb.Marshaler{}).MarshalToString(v)
+		if err != nil {
+			return "?"
+		}
+		return s
+	}
+}
@@ -0,0 +1,11 @@
+package main
+
+// The storage backends compiled in, selected by the scheme of dbPath or by storageBackend,
+// a third party backend is compiled in by importing its package in another file like this one.
+import (
+	_ "github.com/akhenakh/insideout/storage/badger"
+	_ "github.com/akhenakh/insideout/storage/bbolt"
+	_ "github.com/akhenakh/insideout/storage/flat"
+	_ "github.com/akhenakh/insideout/storage/leveldb"
+	_ "github.com/akhenakh/insideout/storage/sqlite"
+)
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+
+	"github.com/akhenakh/insideout/server"
+)
+
+// candidates the candidate DBs compared with the datasets of the same name, reloadMu protects them like datasets
+var candidates []*dataset
+
+// parseCandidates returns the candidates from a comma separated list of name=path, a path alone is a candidate
+// of the default dataset
+func parseCandidates(list, defaultName string) ([]*dataset, error) {
+	var res []*dataset
+	seen := make(map[string]string)
+	for _, e := range strings.Split(list, ",") {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+		name, p := defaultName, e
+		if i := strings.Index(e, "="); i >= 0 {
+			name, p = strings.TrimSpace(e[:i]), strings.TrimSpace(e[i+1:])
+		}
+		if name == "" || p == "" {
+			return nil, fmt.Errorf("invalid candidate %q", e)
+		}
+		if prev, ok := seen[name]; ok {
+			return nil, fmt.Errorf("dataset %s has two candidates %s and %s", name, prev, p)
+		}
+		seen[name] = p
+		res = append(res, &dataset{name: name, path: p})
+	}
+	return res, nil
+}
+
+// swapCandidate opens the DB of cd and compares it with its dataset, the previous one is closed, reloadMu must be held
+func swapCandidate(logger log.Logger, s *server.Server, cd *dataset) error {
+	storage, nclean, err := openStorage(cd.path, logger)
+	if err != nil {
+		return fmt.Errorf("failed to open candidate storage %s: %w", cd.path, err)
+	}
+
+	ninfos, err := storage.LoadIndexInfos()
+	if err != nil {
+		nclean()
+		return fmt.Errorf("failed to read infos %s: %w", cd.path, err)
+	}
+
+	if _, err := s.SetCandidate(cd.name, storage); err != nil {
+		nclean()
+		return fmt.Errorf("failed to load the candidate of dataset %s: %w", cd.name, err)
+	}
+
+	if cd.clean != nil {
+		if err := cd.clean(); err != nil {
+			level.Warn(logger).Log("msg", "failed to close previous candidate storage", "error", err, "dataset", cd.name)
+		}
+	}
+	cd.clean = nclean
+	cd.infos = ninfos
+	cd.storage = storage
+
+	level.Info(logger).Log("msg", "loaded candidate storage", "dataset", cd.name, "db_path", cd.path,
+		"feature_count", cd.infos.FeatureCount)
+	return nil
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/geojson"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/insidesvc"
+	"github.com/akhenakh/insideout/server"
+	"github.com/akhenakh/insideout/storage/bbolt"
+)
+
+// indexSquare writes at path a DB holding a square of 1 degree at offset named name
+func indexSquare(t *testing.T, path, name string, offset float64) {
+	wstorage, wclose, err := bbolt.NewStorage(path, log.NewNopLogger())
+	require.NoError(t, err)
+
+	o := offset
+	fc := geojson.FeatureCollection{Features: []*geojson.Feature{{
+		Geometry:   geom.NewPolygonFlat(geom.XY, []float64{o, o, o + 1, o, o + 1, o + 1, o, o + 1, o, o}, []int{10}),
+		Properties: map[string]interface{}{"name": name},
+	}}}
+	icoverer := &s2.RegionCoverer{MinLevel: 5, MaxLevel: 12, MaxCells: 24}
+	ocoverer := &s2.RegionCoverer{MinLevel: 5, MaxLevel: 12, MaxCells: 16}
+	require.NoError(t, wstorage.Index(fc, icoverer, ocoverer, 100, name, "unittest"))
+	require.NoError(t, wclose())
+}
+
+// within returns the names of the features of s containing lat lng
+func within(s *server.Server, lat, lng float64) ([]string, error) {
+	resp, err := s.Within(context.Background(), &insidesvc.WithinRequest{Lat: lat, Lng: lng, RemoveGeometries: true})
+	if err != nil {
+		return nil, err
+	}
+	var res []string
+	for _, fr := range resp.Responses {
+		res = append(res, fr.Feature.Properties["name"].GetStringValue())
+	}
+	return res, nil
+}
+
+// names returns the names of the features of s containing lat lng, t fails on error
+func names(t *testing.T, s *server.Server, lat, lng float64) []string {
+	res, err := within(s, lat, lng)
+	require.NoError(t, err)
+	return res
+}
+
+// replace atomically replaces the file at path by a copy of src, like a deployment does
+func replace(t *testing.T, src, path string) {
+	b, err := ioutil.ReadFile(src)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(path+".tmp", b, 0600))
+	require.NoError(t, os.Rename(path+".tmp", path))
+}
+
+func TestReload(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "insided-reload-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// the DB replaced in place by the deployments, A and B
+	pathA := filepath.Join(dir, "a.db")
+	indexSquare(t, pathA, "A", 0)
+	pathB := filepath.Join(dir, "b.db")
+	indexSquare(t, pathB, "B", 10)
+	path := filepath.Join(dir, "inside.db")
+	replace(t, pathA, path)
+	invalid := filepath.Join(dir, "invalid.db")
+	require.NoError(t, ioutil.WriteFile(invalid, []byte("not a DB"), 0600))
+
+	logger := log.NewNopLogger()
+	storage, clean, err := openStorage(path, logger)
+	require.NoError(t, err)
+	infos, err := storage.LoadIndexInfos()
+	require.NoError(t, err)
+
+	prevDatasets, prevCandidates := datasets, candidates
+	defer func() { datasets, candidates = prevDatasets, prevCandidates }()
+	ds := &dataset{name: "inside", path: path, infos: infos, storage: storage, clean: clean}
+	datasets, candidates = []*dataset{ds}, nil
+	defer func() { ds.clean() }()
+
+	s, err := server.New(storage, logger, nil, server.Options{Strategy: insideout.DBStrategy, DatasetName: "inside"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"A"}, names(t, s, 0.5, 0.5))
+
+	// the queries run during the reload get one DB or the other, never an error
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				a, err := within(s, 0.5, 0.5)
+				if err != nil || (len(a) > 0 && (len(a) != 1 || a[0] != "A")) {
+					t.Errorf("within A during reload: %v %v", a, err)
+					return
+				}
+				b, err := within(s, 10.5, 10.5)
+				if err != nil || (len(b) > 0 && (len(b) != 1 || b[0] != "B")) {
+					t.Errorf("within B during reload: %v %v", b, err)
+					return
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 10; i++ {
+		replace(t, pathB, path)
+		require.NoError(t, reload(logger, s))
+		require.Equal(t, []string{"B"}, names(t, s, 10.5, 10.5))
+		replace(t, pathA, path)
+		require.NoError(t, reload(logger, s))
+		require.Equal(t, []string{"A"}, names(t, s, 0.5, 0.5))
+	}
+	replace(t, pathB, path)
+	require.NoError(t, reload(logger, s))
+	close(done)
+	wg.Wait()
+
+	require.Empty(t, names(t, s, 0.5, 0.5))
+	require.Equal(t, []string{"B"}, names(t, s, 10.5, 10.5))
+	require.True(t, storage != ds.storage)
+	require.Equal(t, "B", ds.infos.Filename)
+
+	// a failed reload keeps serving the previous DB
+	served := ds.storage
+	replace(t, invalid, path)
+	require.Error(t, reload(logger, s))
+	require.True(t, served == ds.storage)
+	require.Equal(t, []string{"B"}, names(t, s, 10.5, 10.5))
+	require.Empty(t, names(t, s, 0.5, 0.5))
+
+	require.NoError(t, os.Remove(path))
+	require.Error(t, reload(logger, s))
+	require.Equal(t, []string{"B"}, names(t, s, 10.5, 10.5))
+
+	// the DBs opened for writing are not reloaded
+	*readOnly = false
+	defer func() { *readOnly = true }()
+	require.Error(t, reload(logger, s))
+}
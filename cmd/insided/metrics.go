@@ -16,5 +16,5 @@ var (
 		Namespace: "insided",
 		Name:      "dataset_version",
 		Help:      "Dataset version.",
-	}, []string{"version"})
+	}, []string{"dataset", "version"})
 )
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+
+	"github.com/akhenakh/insideout/server"
+	"github.com/akhenakh/insideout/server/admin"
+	"github.com/akhenakh/insideout/storage"
+)
+
+// replicaFile returns the path of the DB file served by ds, without its backend
+func (ds *dataset) replicaFile() (string, error) {
+	p, err := ds.storagePath()
+	if err != nil {
+		return "", err
+	}
+	_, p = storage.ParseURI(p, *storageBackend)
+	return p, nil
+}
+
+// lookupReplica returns the path of the served DB file name, for the followers
+func lookupReplica(name string) (string, bool) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	for _, ds := range datasets {
+		p, err := ds.replicaFile()
+		if err == nil && filepath.Base(p) == name {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+// replicaFiles returns the DB files of the datasets replicated by a follower
+func replicaFiles() ([]string, error) {
+	var files []string
+	for _, ds := range datasets {
+		if ds.layout != nil {
+			return nil, fmt.Errorf("dataset %s: the versions directories can't be replicated", ds.name)
+		}
+		p, err := ds.replicaFile()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, p)
+	}
+	return files, nil
+}
+
+// swapReplicas serves the replicated DB files, reported as not serving meanwhile
+func swapReplicas(logger log.Logger, s *server.Server, healthStatus *admin.Status, files []string) error {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	defer setDataVersions()
+
+	level.Info(logger).Log("msg", "serving status to NOT_SERVING, swapping replicated DBs")
+	healthStatus.SetAvailable(false)
+	defer func() {
+		healthStatus.SetAvailable(true)
+		level.Info(logger).Log("msg", "serving status to SERVING")
+	}()
+
+	for _, ds := range datasets {
+		p, err := ds.replicaFile()
+		if err != nil {
+			return err
+		}
+		for _, f := range files {
+			if f != p {
+				continue
+			}
+			if err := swapStorage(logger, s, ds, ds.path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// blockingServer serves the gRPC health service on a local port,
+// each call is signaled on started then waits for release to be closed
+func blockingServer(t *testing.T) (*grpc.Server, *health.Server, string, chan struct{}, chan struct{}) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	started, release := make(chan struct{}, 16), make(chan struct{})
+	s := grpc.NewServer(grpc.UnaryInterceptor(
+		func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, h grpc.UnaryHandler) (interface{}, error) {
+			started <- struct{}{}
+			<-release
+			return h(ctx, req)
+		}))
+	hs := health.NewServer()
+	healthpb.RegisterHealthServer(s, hs)
+	go s.Serve(lis)
+
+	return s, hs, lis.Addr().String(), started, release
+}
+
+// check calls the health service at addr on a new connection
+func check(addr string, timeout time.Duration) (*healthpb.HealthCheckResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, addr, grpc.WithInsecure())
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+}
+
+// refused returns true once addr does not accept connections anymore
+func refused(addr string) func() bool {
+	return func() bool {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			return true
+		}
+		conn.Close()
+		return false
+	}
+}
+
+func TestWaitDrain(t *testing.T) {
+	logger := log.NewNopLogger()
+	s, hs, addr, started, release := blockingServer(t)
+	defer s.Stop()
+	close(release)
+
+	start := time.Now()
+	waitDrain(logger, 0, nil)
+	require.True(t, time.Since(start) < 100*time.Millisecond)
+
+	waitDrain(logger, 100*time.Millisecond, nil)
+	require.True(t, time.Since(start) >= 100*time.Millisecond)
+
+	// the new requests are still served while draining, reporting NOT_SERVING
+	hs.Shutdown()
+	interrupt := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	go func() {
+		waitDrain(logger, time.Hour, interrupt)
+		close(done)
+	}()
+
+	resp, err := check(addr, 5*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, resp.Status)
+	<-started
+	select {
+	case <-done:
+		t.Fatal("drain ended before the drain period or a second signal")
+	default:
+	}
+
+	// a second signal skips the wait
+	interrupt <- os.Interrupt
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("drain not ended by a second signal")
+	}
+}
+
+func TestGracefulStop(t *testing.T) {
+	logger := log.NewNopLogger()
+	s, _, addr, started, release := blockingServer(t)
+
+	inflight := make(chan error, 1)
+	go func() {
+		_, err := check(addr, 10*time.Second)
+		inflight <- err
+	}()
+	<-started
+
+	done := make(chan struct{})
+	go func() {
+		gracefulStop(context.Background(), logger, s)
+		close(done)
+	}()
+
+	// the new requests are refused while the in flight one is pending
+	require.Eventually(t, refused(addr), 5*time.Second, 10*time.Millisecond)
+	_, err := check(addr, time.Second)
+	require.Error(t, err)
+	select {
+	case <-done:
+		t.Fatal("stopped before the in flight request completed")
+	case err := <-inflight:
+		t.Fatalf("in flight request ended before being released: %v", err)
+	default:
+	}
+
+	// the in flight request completes then the server stops
+	close(release)
+	require.NoError(t, <-inflight)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("not stopped after the in flight request completed")
+	}
+}
+
+func TestGracefulStop_Timeout(t *testing.T) {
+	logger := log.NewNopLogger()
+	s, _, addr, started, release := blockingServer(t)
+	defer close(release)
+
+	inflight := make(chan error, 1)
+	go func() {
+		_, err := check(addr, 10*time.Second)
+		inflight <- err
+	}()
+	<-started
+
+	// the requests still pending when ctx is done are aborted
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	gracefulStop(ctx, logger, s)
+	require.True(t, time.Since(start) < 5*time.Second)
+
+	err := <-inflight
+	require.Error(t, err)
+	require.Equal(t, codes.Unavailable, status.Code(err))
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+
+	"github.com/akhenakh/insideout/systemd"
+	"github.com/akhenakh/insideout/upgrade"
+)
+
+// the FileDescriptorName= of the sockets used in place of the ports flags
+const (
+	grpcSocket    = "grpc"
+	healthSocket  = "health"
+	adminSocket   = "admin"
+	httpSocket    = "http"
+	metricsSocket = "metrics"
+	replicaSocket = "replica"
+	connectSocket = "connect"
+)
+
+var (
+	// activated the listeners passed by systemd socket activation or an upgrading process, by socket name,
+	// set before starting the servers
+	activated map[string]net.Listener
+
+	// listeners the listeners of the servers, by socket name, handed over on upgrade
+	listenersMu sync.Mutex
+	listeners   = make(map[string]net.Listener)
+)
+
+// activateSockets sets the listeners passed by systemd or handed over by an upgrading process, the unknown ones are closed.
+// The returned func must be called once serving, it notifies the upgrading process to shut down.
+func activateSockets(logger log.Logger) (func() error, error) {
+	lns, err := systemd.Listeners()
+	if err != nil {
+		return nil, err
+	}
+	inherited, ready, err := upgrade.Inherited()
+	if err != nil {
+		return nil, err
+	}
+	if len(inherited) > 0 {
+		level.Info(logger).Log("msg", "upgrading, using the sockets of the previous process")
+		lns = inherited
+	}
+	for name, ln := range lns {
+		switch name {
+		case grpcSocket, healthSocket, adminSocket, httpSocket, metricsSocket, replicaSocket, connectSocket:
+			level.Info(logger).Log("msg", "using handed over socket", "socket", name, "addr", ln.Addr().String())
+		default:
+			level.Warn(logger).Log("msg", "ignoring unknown handed over socket", "socket", name, "addr", ln.Addr().String())
+			ln.Close()
+			delete(lns, name)
+		}
+	}
+	activated = lns
+	return ready, nil
+}
+
+// listen returns the listener of the socket name passed by systemd or an upgrading process,
+// a new TCP listener on addr otherwise
+func listen(name, addr string) (net.Listener, error) {
+	ln, ok := activated[name]
+	if !ok {
+		var err error
+		ln, err = net.Listen("tcp", addr)
+		if err != nil {
+			return nil, err
+		}
+	}
+	listenersMu.Lock()
+	listeners[name] = ln
+	listenersMu.Unlock()
+	return ln, nil
+}
+
+// serve serves HTTPS if srv has a TLS configuration, HTTP otherwise, on the socket name or srv.Addr
+func serve(srv *http.Server, name string) error {
+	ln, err := listen(name, srv.Addr)
+	if err != nil {
+		return err
+	}
+	if srv.TLSConfig != nil {
+		return srv.ServeTLS(ln, "", "")
+	}
+	return srv.Serve(ln)
+}
+
+// notifySystemd sends the states to systemd when started by it
+func notifySystemd(logger log.Logger, states ...string) {
+	if _, err := systemd.Notify(states...); err != nil {
+		level.Warn(logger).Log("msg", "can't notify systemd", "error", err)
+	}
+}
+
+// watchdog notifies the systemd watchdog every interval until ctx is done
+func watchdog(ctx context.Context, logger log.Logger, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			notifySystemd(logger, systemd.Watchdog)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
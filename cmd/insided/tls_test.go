@@ -0,0 +1,223 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testCerts the PEM files of a CA, of a server and a client certificates signed by it
+type testCerts struct {
+	ca, cert, key, clientCert, clientKey string
+}
+
+// writeCerts writes the PEM files of a new CA and its certificates in dir
+func writeCerts(t *testing.T, dir string) testCerts {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "insided test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	ca, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	write := func(name, typ string, b []byte) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: b}), 0600))
+		return path
+	}
+	issue := func(name string, serial int64, usage x509.ExtKeyUsage) (string, string) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		tmpl := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: name},
+			DNSNames:     []string{"localhost"},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+		require.NoError(t, err)
+		kb, err := x509.MarshalECPrivateKey(key)
+		require.NoError(t, err)
+		return write(name+".pem", "CERTIFICATE", der), write(name+".key", "EC PRIVATE KEY", kb)
+	}
+
+	certs := testCerts{ca: write("ca.pem", "CERTIFICATE", caDER)}
+	certs.cert, certs.key = issue("server", 2, x509.ExtKeyUsageServerAuth)
+	certs.clientCert, certs.clientKey = issue("client", 3, x509.ExtKeyUsageClientAuth)
+	return certs
+}
+
+// handshake returns the error of a TLS handshake between a server and a client with these configurations
+func handshake(t *testing.T, server, client *tls.Config) error {
+	lis, err := tls.Listen("tcp", "127.0.0.1:0", server)
+	require.NoError(t, err)
+	defer lis.Close()
+
+	errc := make(chan error, 1)
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			errc <- err
+			return
+		}
+		defer conn.Close()
+		errc <- conn.(*tls.Conn).Handshake()
+	}()
+
+	conn, err := tls.Dial("tcp", lis.Addr().String(), client)
+	if err == nil {
+		conn.Close()
+	}
+	if serr := <-errc; serr != nil {
+		return serr
+	}
+	return err
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "insided-tls-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	c := writeCerts(t, dir)
+	missing := filepath.Join(dir, "missing.pem")
+	invalid := filepath.Join(dir, "invalid.pem")
+	require.NoError(t, ioutil.WriteFile(invalid, []byte("not a PEM file"), 0600))
+
+	tests := []struct {
+		name                string
+		cert, key, clientCA string
+		wantErr             bool
+		wantNil             bool
+		clientAuth          tls.ClientAuthType
+	}{
+		{name: "disabled", wantNil: true},
+		{name: "client CA without certificate", clientCA: c.ca, wantErr: true},
+		{name: "certificate without key", cert: c.cert, wantErr: true},
+		{name: "key without certificate", key: c.key, wantErr: true},
+		{name: "missing certificate", cert: missing, key: c.key, wantErr: true},
+		{name: "missing key", cert: c.cert, key: missing, wantErr: true},
+		{name: "invalid certificate", cert: invalid, key: c.key, wantErr: true},
+		{name: "mismatched key", cert: c.cert, key: c.clientKey, wantErr: true},
+		{name: "server TLS", cert: c.cert, key: c.key, clientAuth: tls.NoClientCert},
+		{name: "mutual TLS", cert: c.cert, key: c.key, clientCA: c.ca, clientAuth: tls.RequireAndVerifyClientCert},
+		{name: "missing client CA", cert: c.cert, key: c.key, clientCA: missing, wantErr: true},
+		{name: "invalid client CA", cert: c.cert, key: c.key, clientCA: invalid, wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := newTLSConfig(tt.cert, tt.key, tt.clientCA)
+			if tt.wantErr {
+				require.Error(t, err)
+				require.Nil(t, cfg)
+				return
+			}
+			require.NoError(t, err)
+			if tt.wantNil {
+				require.Nil(t, cfg)
+				return
+			}
+			require.Len(t, cfg.Certificates, 1)
+			require.Equal(t, uint16(tls.VersionTLS12), cfg.MinVersion)
+			require.Equal(t, tt.clientAuth, cfg.ClientAuth)
+			require.Equal(t, tt.clientCA != "", cfg.ClientCAs != nil)
+		})
+	}
+}
+
+func TestNewTLSConfig_ClientAuth(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "insided-tls-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	c := writeCerts(t, dir)
+	serverTLS, err := newTLSConfig(c.cert, c.key, "")
+	require.NoError(t, err)
+	mutualTLS, err := newTLSConfig(c.cert, c.key, c.ca)
+	require.NoError(t, err)
+
+	anonymous, err := newClientTLSConfig(c.ca, "", "")
+	require.NoError(t, err)
+	anonymous.ServerName = "localhost"
+	authenticated, err := newClientTLSConfig(c.ca, c.clientCert, c.clientKey)
+	require.NoError(t, err)
+	authenticated.ServerName = "localhost"
+	untrusted, err := newClientTLSConfig("", c.clientCert, c.clientKey)
+	require.NoError(t, err)
+	untrusted.ServerName = "localhost"
+
+	require.NoError(t, handshake(t, serverTLS, anonymous))
+	require.NoError(t, handshake(t, serverTLS, authenticated))
+	require.Error(t, handshake(t, mutualTLS, anonymous), "a client certificate is required")
+	require.NoError(t, handshake(t, mutualTLS, authenticated))
+	require.Error(t, handshake(t, serverTLS, untrusted), "the server CA is not trusted")
+}
+
+func TestNewClientTLSConfig(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "insided-tls-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	c := writeCerts(t, dir)
+	missing := filepath.Join(dir, "missing.pem")
+	invalid := filepath.Join(dir, "invalid.pem")
+	require.NoError(t, ioutil.WriteFile(invalid, []byte("not a PEM file"), 0600))
+
+	tests := []struct {
+		name              string
+		ca, cert, key     string
+		wantErr, wantNil  bool
+		wantCert, wantCAs bool
+	}{
+		{name: "disabled", wantNil: true},
+		{name: "CA", ca: c.ca, wantCAs: true},
+		{name: "client certificate", cert: c.clientCert, key: c.clientKey, wantCert: true},
+		{name: "CA and client certificate", ca: c.ca, cert: c.clientCert, key: c.clientKey, wantCert: true, wantCAs: true},
+		{name: "certificate without key", ca: c.ca, cert: c.clientCert, wantErr: true},
+		{name: "missing certificate", cert: missing, key: c.clientKey, wantErr: true},
+		{name: "missing CA", ca: missing, wantErr: true},
+		{name: "invalid CA", ca: invalid, wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := newClientTLSConfig(tt.ca, tt.cert, tt.key)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			if tt.wantNil {
+				require.Nil(t, cfg)
+				return
+			}
+			require.Equal(t, tt.wantCert, len(cfg.Certificates) == 1)
+			require.Equal(t, tt.wantCAs, cfg.RootCAs != nil)
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp"
+	"go.opentelemetry.io/otel/propagators"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/semconv"
+	"google.golang.org/grpc/credentials"
+)
+
+// newTracerProvider returns a tracer provider exporting the spans to the OTLP collector at endpoint host:port,
+// ratio of the traces started by insided are sampled, the traces sampled by the callers are always sampled.
+// The returned func flushes the pending spans and stops the exporter.
+func newTracerProvider(endpoint string, ratio float64, insecure bool) (*sdktrace.TracerProvider, func(context.Context) error, error) {
+	opts := []otlp.ExporterOption{otlp.WithAddress(endpoint)}
+	if insecure {
+		opts = append(opts, otlp.WithInsecure())
+	} else {
+		opts = append(opts, otlp.WithTLSCredentials(credentials.NewClientTLSFromCert(nil, "")))
+	}
+	exp, err := otlp.NewExporter(opts...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't create OTLP exporter: %w", err)
+	}
+
+	bsp := sdktrace.NewBatchSpanProcessor(exp)
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithConfig(sdktrace.Config{DefaultSampler: sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))}),
+		sdktrace.WithResource(resource.New(
+			semconv.ServiceNameKey.String(appName),
+			semconv.ServiceVersionKey.String(version),
+		)),
+		sdktrace.WithSpanProcessor(bsp),
+	)
+
+	return tp, func(ctx context.Context) error {
+		// unregistering the processor flushes it
+		tp.UnregisterSpanProcessor(bsp)
+		return exp.Shutdown(ctx)
+	}, nil
+}
+
+// newPropagator returns the W3C trace context and baggage propagator
+func newPropagator() otel.TextMapPropagator {
+	return otel.NewCompositeTextMapPropagator(propagators.TraceContext{}, propagators.Baggage{})
+}
+
+// tracingErrorHandler logs the errors of the tracing exporter
+type tracingErrorHandler struct {
+	logger log.Logger
+}
+
+func (h tracingErrorHandler) Handle(err error) {
+	level.Warn(h.logger).Log("msg", "tracing error", "error", err)
+}
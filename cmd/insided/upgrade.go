@@ -0,0 +1,48 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+
+	"github.com/akhenakh/insideout/upgrade"
+)
+
+// notifyUpgrade returns the channel receiving SIGUSR2, asking for an upgrade, and the func to stop it
+func notifyUpgrade() (<-chan os.Signal, func()) {
+	usr2 := make(chan os.Signal, 1)
+	signal.Notify(usr2, syscall.SIGUSR2)
+	return usr2, func() { signal.Stop(usr2) }
+}
+
+// upgradeBinary starts the executable, possibly upgraded, with the listeners of the servers,
+// it returns the pid of the new process once it is serving
+func upgradeBinary(ctx context.Context, logger log.Logger, timeout time.Duration) (int, error) {
+	listenersMu.Lock()
+	lns := make(map[string]net.Listener, len(listeners))
+	for name, ln := range listeners {
+		lns[name] = ln
+	}
+	listenersMu.Unlock()
+
+	start := time.Now()
+	pid, err := upgrade.Upgrade(ctx, lns, timeout)
+	if err != nil {
+		return 0, err
+	}
+	level.Info(logger).Log("msg", "upgraded, the new process is serving", "pid", pid, "duration", time.Since(start))
+
+	// the new process is now the main one of the unit
+	notifySystemd(logger, fmt.Sprintf("MAINPID=%d", pid))
+	return pid, nil
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	log "github.com/go-kit/kit/log"
+)
+
+// notifyUpgrade returns a channel never receiving, there is no SIGUSR2 on Windows
+func notifyUpgrade() (<-chan os.Signal, func()) {
+	return nil, func() {}
+}
+
+// upgradeBinary is not supported on Windows, the sockets can't be handed over
+func upgradeBinary(ctx context.Context, logger log.Logger, timeout time.Duration) (int, error) {
+	return 0, errors.New("upgrade is not supported on windows")
+}
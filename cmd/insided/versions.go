@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	log "github.com/go-kit/kit/log"
+
+	"github.com/akhenakh/insideout/server"
+	"github.com/akhenakh/insideout/storage"
+	"github.com/akhenakh/insideout/storage/versions"
+)
+
+// versioner serves the versions of the datasets served from a versions directory, see admin.Versioner
+type versioner struct {
+	s      *server.Server
+	logger log.Logger
+}
+
+// Layout returns the name and the versions directory of the dataset, the first one when empty
+func (v *versioner) Layout(name string) (string, *versions.Layout, error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	ds, err := findDataset(name)
+	if err != nil {
+		return name, nil, err
+	}
+	if ds.layout == nil {
+		return ds.name, nil, fmt.Errorf("dataset %s is not served from a versions directory", ds.name)
+	}
+	return ds.name, ds.layout, nil
+}
+
+// Serve opens the DB at path, a version of the dataset name, with the backend of the dataset and serves it
+func (v *versioner) Serve(name, path string) error {
+	// the DBs are locked by the writers and updated in place
+	if !*readOnly {
+		return errors.New("can't switch the versions of the DBs opened for writing")
+	}
+
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	defer setDataVersions()
+
+	ds, err := findDataset(name)
+	if err != nil {
+		return err
+	}
+	backend, _ := storage.ParseURI(ds.path, *storageBackend)
+	return swapStorage(v.logger, v.s, ds, backend+"://"+path)
+}
+
+// storagePath returns the path of the DB served by ds, the current version of its versions directory
+// prefixed by its backend
+func (ds *dataset) storagePath() (string, error) {
+	if ds.layout == nil {
+		return ds.path, nil
+	}
+	current, err := ds.layout.Current()
+	if err != nil {
+		return "", err
+	}
+	backend, _ := storage.ParseURI(ds.path, *storageBackend)
+	return backend + "://" + current.Path, nil
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/insidesvc"
+	"github.com/akhenakh/insideout/server"
+)
+
+// regionsSuffix names the shapeindex strategy partitioned by regions
+const regionsSuffix = "_regions"
+
+// minOffsetMeters the min distance of the points generated next to an edge, closer points depend on the rounding
+// of the stored vertices and their side is not meaningful
+const minOffsetMeters = 0.001
+
+// strategyServer a server answering with one strategy
+type strategyServer struct {
+	name string
+	s    *server.Server
+}
+
+// fuzzer compares the answers of the strategies
+type fuzzer struct {
+	servers []strategyServer
+}
+
+// disagreement the answers of every strategy for a point, the features ids and polygon indexes as "id:pos"
+type disagreement struct {
+	Lat       float64             `json:"lat"`
+	Lng       float64             `json:"lng"`
+	Exact     bool                `json:"exact"`
+	Kind      string              `json:"kind,omitempty"`
+	FeatureID uint32              `json:"feature_id,omitempty"`
+	Answers   map[string][]string `json:"answers"`
+	Reference string              `json:"reference"`
+	Differ    []string            `json:"differ,omitempty"`
+	Reproduce string              `json:"reproduce,omitempty"`
+}
+
+// agree returns true if all the strategies gave the answer of the reference one
+func (d *disagreement) agree() bool {
+	return len(d.Differ) == 0
+}
+
+// newFuzzer returns a fuzzer querying storage with each of the strategies names, the shapeindex strategy
+// partitioned by regions is added when regionLevel is not 0, h3 is skipped when storage has no H3 cover
+func newFuzzer(storage insideout.Store, names []string, regionLevel int, logger log.Logger) (*fuzzer, error) {
+	f := &fuzzer{}
+	add := func(name string, opts server.Options) error {
+		s, err := server.New(storage, log.NewNopLogger(), nil, opts)
+		if err != nil {
+			return fmt.Errorf("strategy %s: %w", name, err)
+		}
+		f.servers = append(f.servers, strategyServer{name: name, s: s})
+		return nil
+	}
+
+	for _, name := range names {
+		if name == insideout.H3Strategy {
+			ok, err := hasH3Cover(storage)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				level.Warn(logger).Log("msg", "no H3 cover in the database, h3 strategy skipped")
+				continue
+			}
+		}
+		if err := add(name, server.Options{Strategy: name}); err != nil {
+			return nil, err
+		}
+	}
+	if regionLevel > 0 {
+		err := add(insideout.ShapeIndexStrategy+regionsSuffix, server.Options{
+			Strategy:              insideout.ShapeIndexStrategy,
+			ShapeIndexRegionLevel: regionLevel,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(f.servers) < 2 {
+		return nil, fmt.Errorf("at least 2 strategies are needed, got %d", len(f.servers))
+	}
+	return f, nil
+}
+
+// hasH3Cover returns true if an H3 cover is stored in storage
+func hasH3Cover(storage insideout.Store) (bool, error) {
+	hs, ok := storage.(insideout.H3Store)
+	if !ok {
+		return false, nil
+	}
+	cover, err := hs.LoadH3Cover()
+	if err != nil {
+		return false, fmt.Errorf("can't read the H3 cover: %w", err)
+	}
+	return cover != nil, nil
+}
+
+// names returns the names of the compared strategies
+func (f *fuzzer) names() []string {
+	names := make([]string, len(f.servers))
+	for i, ss := range f.servers {
+		names[i] = ss.name
+	}
+	return names
+}
+
+// compare queries lat lng with every strategy and returns their answers,
+// the point in polygon tests are forced with exact
+func (f *fuzzer) compare(lat, lng float64, exact bool) (*disagreement, error) {
+	d := &disagreement{
+		Lat:       lat,
+		Lng:       lng,
+		Exact:     exact,
+		Answers:   make(map[string][]string, len(f.servers)),
+		Reference: f.servers[0].name,
+	}
+	var ref []string
+	for i, ss := range f.servers {
+		resp, err := ss.s.Within(context.Background(), &insidesvc.WithinRequest{
+			Lat:              lat,
+			Lng:              lng,
+			RemoveGeometries: true,
+			SelectProperties: insidesvc.LoopIndexProperty,
+			Exact:            exact,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("strategy %s: %w", ss.name, err)
+		}
+		answer := make([]string, 0, len(resp.Responses))
+		for _, fresp := range resp.Responses {
+			pos := fresp.Feature.Properties[insidesvc.LoopIndexProperty].GetNumberValue()
+			answer = append(answer, fmt.Sprintf("%d:%d", fresp.Id, int(pos)))
+		}
+		sort.Strings(answer)
+		d.Answers[ss.name] = answer
+
+		if i == 0 {
+			ref = answer
+			continue
+		}
+		if !equalAnswers(ref, answer) {
+			d.Differ = append(d.Differ, ss.name)
+		}
+	}
+	return d, nil
+}
+
+// equalAnswers returns true if a and b hold the same features
+func equalAnswers(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// generatedPoint a point to query and how it was generated
+type generatedPoint struct {
+	lat, lng float64
+	// kind random or boundary
+	kind string
+	// id the feature the point was generated from
+	id uint32
+}
+
+// generator generates random points in the bounds of the polygons and next to their edges
+type generator struct {
+	storage       insideout.Store
+	rnd           *rand.Rand
+	ids           []uint32
+	boundaryRatio float64
+	maxOffset     float64
+}
+
+// newGenerator returns a generator of points around the features of storage
+func newGenerator(storage insideout.Store, rnd *rand.Rand, boundaryRatio, maxOffsetMeters float64) (*generator, error) {
+	var ids []uint32
+	err := storage.LoadAllFeatures(func(fs *insideout.FeatureStorage, id uint32) error {
+		ids = append(ids, id)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no features in the database")
+	}
+	if maxOffsetMeters < minOffsetMeters {
+		maxOffsetMeters = minOffsetMeters
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return &generator{
+		storage:       storage,
+		rnd:           rnd,
+		ids:           ids,
+		boundaryRatio: boundaryRatio,
+		maxOffset:     maxOffsetMeters,
+	}, nil
+}
+
+// next returns the next point, from a random polygon of a random feature
+func (g *generator) next() (generatedPoint, error) {
+	id := g.ids[g.rnd.Intn(len(g.ids))]
+	f, err := g.storage.LoadFeature(id)
+	if err != nil {
+		return generatedPoint{}, fmt.Errorf("can't load feature %d: %w", id, err)
+	}
+	pos := g.rnd.Intn(len(f.Loops))
+
+	var p s2.Point
+	kind := "random"
+	if g.rnd.Float64() < g.boundaryRatio {
+		// an edge of the polygon or of one of its holes
+		loops := []*s2.Loop{f.Loops[pos]}
+		if f.Holes != nil {
+			loops = append(loops, f.Holes[pos]...)
+		}
+		p = g.nearEdge(loops[g.rnd.Intn(len(loops))])
+		kind = "boundary"
+	} else {
+		p = g.inRect(f.Loops[pos].RectBound())
+	}
+
+	ll := s2.LatLngFromPoint(p)
+	return generatedPoint{lat: ll.Lat.Degrees(), lng: ll.Lng.Degrees(), kind: kind, id: id}, nil
+}
+
+// inRect returns a random point of r
+func (g *generator) inRect(r s2.Rect) s2.Point {
+	lat := r.Lat.Lo + g.rnd.Float64()*r.Lat.Length()
+	lng := r.Lng.Lo + g.rnd.Float64()*r.Lng.Length()
+	if r.Lng.IsInverted() {
+		// crossing the antimeridian
+		lng = r.Lng.Lo + g.rnd.Float64()*(2*math.Pi-r.Lng.Lo+r.Lng.Hi)
+	}
+	return s2.PointFromLatLng(s2.LatLng{Lat: s1.Angle(lat), Lng: s1.Angle(lng)}.Normalized())
+}
+
+// nearEdge returns a random point of a random edge of l moved away from it on a random side,
+// by a distance between minOffsetMeters and maxOffset, closer distances more likely
+func (g *generator) nearEdge(l *s2.Loop) s2.Point {
+	e := l.Edge(g.rnd.Intn(l.NumEdges()))
+	p := s2.Interpolate(g.rnd.Float64(), e.V0, e.V1)
+
+	// log uniform
+	offset := minOffsetMeters * math.Pow(g.maxOffset/minOffsetMeters, g.rnd.Float64())
+	a := float64(insideout.MetersToAngle(offset))
+	if g.rnd.Intn(2) == 0 {
+		a = -a
+	}
+
+	// the normal of the edge great circle is orthogonal to p
+	n := e.V0.PointCross(e.V1)
+	return s2.Point{Vector: p.Mul(math.Cos(a)).Add(n.Normalize().Mul(math.Sin(a))).Normalize()}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/namsral/flag"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/loglevel"
+	"github.com/akhenakh/insideout/storage"
+)
+
+const appName = "insidefuzz"
+
+var (
+	version = "no version from LDFLAGS"
+
+	logLevel       = flag.String("logLevel", "INFO", "DEBUG|INFO|WARN|ERROR")
+	dbPath         = flag.String("dbPath", "inside.db", "Database path")
+	storageBackend = flag.String("storageBackend", insideout.BBoltBackend, "Storage backend of the database paths without a backend:// prefix, like leveldb:///data/inside.db: bbolt|leveldb|badger|flat|sqlite")
+	strategies     = flag.String("strategies", "db,insidetree,shapeindex,memory,hybrid,h3",
+		"Strategies compared, comma separated, the first one is the reference, h3 is skipped when the database has no H3 cover")
+	shapeIndexRegionLevel = flag.Int("shapeIndexRegionLevel", 0, "Also compare the shapeindex strategy partitioned by s2 cells of this level, 0 to disable")
+	points                = flag.Int("points", 10000, "Number of points generated")
+	boundaryRatio         = flag.Float64("boundaryRatio", 0.5, "Ratio of the points generated next to an edge of a polygon or a hole, the others are random in the bounds of a polygon")
+	maxOffsetMeters       = flag.Float64("maxOffsetMeters", 10, "Max distance in meters of the points generated next to an edge, on either side")
+	seed                  = flag.Int64("seed", 0, "Seed of the generated points, a seed is picked and logged when 0")
+	point                 = flag.String("point", "", "Only query this lat,lng point and print the answer of every strategy, to reproduce a disagreement")
+	maxReports            = flag.Int("maxReports", 100, "Stop after this number of disagreements, 0 for no limit")
+)
+
+func main() {
+	flag.Parse()
+
+	// the disagreements are written to stdout
+	logger := log.NewJSONLogger(log.NewSyncWriter(os.Stderr))
+	logger = log.With(logger, "caller", log.Caller(5), "ts", log.DefaultTimestampUTC)
+	logger = log.With(logger, "app", appName)
+	logger = loglevel.NewLevelFilterFromString(logger, *logLevel)
+
+	level.Info(logger).Log("msg", "Starting app", "version", version)
+
+	storage, clean, err := openStorage(*dbPath, *storageBackend, logger)
+	if err != nil {
+		level.Error(logger).Log("msg", "can't open storage", "error", err, "db_path", *dbPath)
+		os.Exit(2)
+	}
+	defer clean()
+
+	f, err := newFuzzer(storage, strings.Split(*strategies, ","), *shapeIndexRegionLevel, logger)
+	if err != nil {
+		level.Error(logger).Log("msg", "can't load the strategies", "error", err)
+		os.Exit(2)
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+
+	if *point != "" {
+		lat, lng, err := parseLatLng(*point)
+		if err != nil {
+			level.Error(logger).Log("msg", "invalid point", "error", err)
+			os.Exit(2)
+		}
+		code := 0
+		for _, exact := range []bool{false, true} {
+			d, err := f.compare(lat, lng, exact)
+			if err != nil {
+				level.Error(logger).Log("msg", "query failed", "error", err)
+				os.Exit(2)
+			}
+			if !d.agree() {
+				code = 1
+			}
+			if err := json.NewEncoder(w).Encode(d); err != nil {
+				level.Error(logger).Log("msg", "can't write the answers", "error", err)
+				os.Exit(2)
+			}
+		}
+		w.Flush()
+		os.Exit(code)
+	}
+
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	level.Info(logger).Log("msg", "generating points", "seed", *seed, "points", *points,
+		"strategies", strings.Join(f.names(), ","))
+
+	gen, err := newGenerator(storage, rand.New(rand.NewSource(*seed)), *boundaryRatio, *maxOffsetMeters)
+	if err != nil {
+		level.Error(logger).Log("msg", "can't read the features", "error", err)
+		os.Exit(2)
+	}
+
+	reports := 0
+	for i := 0; i < *points; i++ {
+		gp, err := gen.next()
+		if err != nil {
+			level.Error(logger).Log("msg", "can't generate a point", "error", err)
+			os.Exit(2)
+		}
+		for _, exact := range []bool{false, true} {
+			d, err := f.compare(gp.lat, gp.lng, exact)
+			if err != nil {
+				level.Error(logger).Log("msg", "query failed", "error", err, "lat", gp.lat, "lng", gp.lng)
+				os.Exit(2)
+			}
+			if d.agree() {
+				continue
+			}
+			d.Kind = gp.kind
+			d.FeatureID = gp.id
+			d.Reproduce = reproducer(gp.lat, gp.lng)
+			if err := json.NewEncoder(w).Encode(d); err != nil {
+				level.Error(logger).Log("msg", "can't write the disagreement", "error", err)
+				os.Exit(2)
+			}
+			reports++
+		}
+		if *maxReports > 0 && reports >= *maxReports {
+			level.Warn(logger).Log("msg", "too many disagreements, stopping", "points", i+1)
+			break
+		}
+	}
+
+	if reports > 0 {
+		level.Error(logger).Log("msg", "strategies disagree", "disagreements", reports, "seed", *seed)
+		w.Flush()
+		os.Exit(1)
+	}
+	level.Info(logger).Log("msg", "all strategies agree", "points", *points, "seed", *seed)
+}
+
+// openStorage opens the database at path read only
+func openStorage(path, backend string, logger log.Logger) (insideout.Store, func() error, error) {
+	return storage.Open(path, backend, storage.ReadOnly, logger)
+}
+
+// parseLatLng parses a "lat,lng" point
+func parseLatLng(s string) (float64, float64, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid point %q, expected lat,lng", s)
+	}
+	lat, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid lat %q: %w", parts[0], err)
+	}
+	lng, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid lng %q: %w", parts[1], err)
+	}
+	return lat, lng, nil
+}
+
+// reproducer returns the command querying again lat lng with the same database and strategies
+func reproducer(lat, lng float64) string {
+	cmd := fmt.Sprintf("insidefuzz -dbPath=%s -storageBackend=%s -strategies=%s -point=%s,%s",
+		*dbPath, *storageBackend, *strategies,
+		strconv.FormatFloat(lat, 'g', -1, 64), strconv.FormatFloat(lng, 'g', -1, 64))
+	if *shapeIndexRegionLevel > 0 {
+		cmd += " -shapeIndexRegionLevel=" + strconv.Itoa(*shapeIndexRegionLevel)
+	}
+	return cmd
+}
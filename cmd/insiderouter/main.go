@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
+	"github.com/namsral/flag"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+
+	"github.com/akhenakh/insideout/insidesvc"
+	"github.com/akhenakh/insideout/loglevel"
+	"github.com/akhenakh/insideout/server/shard"
+)
+
+const appName = "insiderouter"
+
+var (
+	version = "no version from LDFLAGS"
+
+	logLevel        = flag.String("logLevel", "INFO", "DEBUG|INFO|WARN|ERROR")
+	shardMap        = flag.String("shardMap", "shards.yaml", "YAML file of the shards partitioning the planet by S2 cells, with their gRPC addresses")
+	grpcPort        = flag.Int("grpcPort", 9200, "gRPC API port")
+	healthPort      = flag.Int("healthPort", 6666, "grpc health port")
+	httpMetricsPort = flag.Int("httpMetricsPort", 8088, "http port")
+	shutdownTimeout = flag.Duration("shutdownTimeout", 5*time.Second, "Time given to the in flight requests to complete once the servers stop accepting")
+)
+
+func main() {
+	flag.Parse()
+
+	logger := log.NewJSONLogger(log.NewSyncWriter(os.Stdout))
+	logger = log.With(logger, "caller", log.Caller(6), "ts", log.DefaultTimestampUTC)
+	logger = log.With(logger, "app", appName)
+	logLevelFilter, err := loglevel.NewDynamic(logger, *logLevel)
+	if err != nil {
+		level.Error(logger).Log("msg", "invalid log level", "error", err)
+		os.Exit(2)
+	}
+	logger = logLevelFilter
+
+	level.Info(logger).Log("msg", "Starting app", "version", version)
+
+	m, err := shard.Load(*shardMap)
+	if err != nil {
+		level.Error(logger).Log("msg", "can't load shard map", "error", err, "shard_map", *shardMap)
+		os.Exit(2)
+	}
+
+	// the connections are established in the background and reestablished when lost
+	router, closeShards, err := shard.Dial(m, logger, grpc.WithInsecure(),
+		grpc.WithUnaryInterceptor(grpc_prometheus.UnaryClientInterceptor))
+	if err != nil {
+		level.Error(logger).Log("msg", "can't dial shards", "error", err)
+		os.Exit(2)
+	}
+	defer closeShards()
+	for _, sh := range m.Shards {
+		level.Info(logger).Log("msg", "routing to shard", "shard", sh.Name, "addr", sh.Addr, "cells_count", len(sh.Cells))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+
+	g, ctx := errgroup.WithContext(ctx)
+
+	healthServer := health.NewServer()
+	grpcHealthServer := grpc.NewServer()
+	healthpb.RegisterHealthServer(grpcHealthServer, healthServer)
+	g.Go(func() error {
+		hln, err := net.Listen("tcp", fmt.Sprintf(":%d", *healthPort))
+		if err != nil {
+			return err
+		}
+		level.Info(logger).Log("msg", fmt.Sprintf("gRPC health server listening at :%d", *healthPort))
+		return grpcHealthServer.Serve(hln)
+	})
+
+	httpMetricsServer := &http.Server{
+		Addr:         fmt.Sprintf(":%d", *httpMetricsPort),
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+		Handler:      promhttp.Handler(),
+	}
+	g.Go(func() error {
+		level.Info(logger).Log("msg", fmt.Sprintf("HTTP Metrics server listening at :%d", *httpMetricsPort))
+		if err := httpMetricsServer.ListenAndServe(); err != http.ErrServerClosed {
+			return err
+		}
+		return nil
+	})
+
+	grpcServer := grpc.NewServer(
+		grpc.StreamInterceptor(grpc_prometheus.StreamServerInterceptor),
+		grpc.UnaryInterceptor(grpc_prometheus.UnaryServerInterceptor),
+	)
+	insidesvc.RegisterInsideServer(grpcServer, router)
+	reflection.Register(grpcServer)
+	grpc_prometheus.Register(grpcServer)
+	g.Go(func() error {
+		ln, err := net.Listen("tcp", fmt.Sprintf(":%d", *grpcPort))
+		if err != nil {
+			return err
+		}
+		level.Info(logger).Log("msg", fmt.Sprintf("gRPC server listening at :%d", *grpcPort))
+		return grpcServer.Serve(ln)
+	})
+
+	healthServer.SetServingStatus(fmt.Sprintf("grpc.health.v1.%s", appName), healthpb.HealthCheckResponse_SERVING)
+
+	select {
+	case <-interrupt:
+	case <-ctx.Done():
+	}
+
+	level.Warn(logger).Log("msg", "received shutdown signal")
+	healthServer.Shutdown()
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer shutdownCancel()
+
+	_ = httpMetricsServer.Shutdown(shutdownCtx)
+
+	done := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-shutdownCtx.Done():
+		grpcServer.Stop()
+	}
+	grpcHealthServer.Stop()
+
+	if err := g.Wait(); err != nil {
+		level.Error(logger).Log("msg", "router returning an error", "error", err)
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,118 @@
+//go:build js && wasm
+// +build js,wasm
+
+// Command insidewasm is the WebAssembly build of the query engine, it sets the global insideout object
+// whose load function indexes a downloaded flat index with the insidetree strategy, the point in polygon lookups
+// then run in the browser or the edge function, see the lookup package.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"syscall/js"
+
+	"github.com/go-kit/kit/log"
+	"github.com/golang/geo/s2"
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/geojson"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/lookup"
+	"github.com/akhenakh/insideout/storage/flat"
+)
+
+var version = "no version from LDFLAGS"
+
+// feature a GeoJSON feature returned by within, the geometry is null unless requested
+type feature struct {
+	Type       string                 `json:"type"`
+	ID         uint32                 `json:"id"`
+	Geometry   *geojson.Geometry      `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+func main() {
+	js.Global().Set("insideout", map[string]interface{}{
+		"version": version,
+		"load":    js.FuncOf(load),
+	})
+
+	// the functions are called by JavaScript until the page or the worker is gone
+	select {}
+}
+
+// load indexes the flat index in the Uint8Array args[0], with the options object args[1]: {stopOnFirstFound: bool},
+// it returns the index object or an Error
+func load(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || args[0].Type() != js.TypeObject {
+		return jsError(errors.New("load expects the Uint8Array of a flat index"))
+	}
+	data := make([]byte, args[0].Get("length").Int())
+	js.CopyBytesToGo(data, args[0])
+
+	var opts lookup.Options
+	if len(args) > 1 && args[1].Type() == js.TypeObject {
+		opts.StopOnFirstFound = args[1].Get("stopOnFirstFound").Truthy()
+	}
+
+	storage, err := flat.NewROStorageFromBytes(data, log.NewNopLogger())
+	if err != nil {
+		return jsError(fmt.Errorf("invalid flat index: %w", err))
+	}
+	idx, err := lookup.New(storage, opts)
+	if err != nil {
+		return jsError(err)
+	}
+
+	infos := idx.Infos()
+	return map[string]interface{}{
+		"filename":     infos.Filename,
+		"featureCount": int(infos.FeatureCount),
+		"within": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			return within(idx, args)
+		}),
+	}
+}
+
+// within returns the GeoJSON features containing the point args[0] lat args[1] lng, with their polygon
+// when args[2] is true, or an Error
+func within(idx *lookup.Index, args []js.Value) interface{} {
+	if len(args) < 2 || args[0].Type() != js.TypeNumber || args[1].Type() != js.TypeNumber {
+		return jsError(errors.New("within expects lat and lng"))
+	}
+	geometries := len(args) > 2 && args[2].Truthy()
+
+	matches, err := idx.Within(args[0].Float(), args[1].Float())
+	if err != nil {
+		return jsError(err)
+	}
+
+	features := make([]feature, len(matches))
+	for i, m := range matches {
+		features[i] = feature{Type: "Feature", ID: m.ID, Properties: m.Feature.Properties}
+		if geometries {
+			var holes []*s2.Loop
+			if int(m.Pos) < len(m.Feature.Holes) {
+				holes = m.Feature.Holes[m.Pos]
+			}
+			coords, ends := insideout.CoordinatesFromPolygon(m.Feature.Loops[m.Pos], holes)
+			g, err := geojson.Encode(geom.NewPolygonFlat(geom.XY, coords, ends))
+			if err != nil {
+				return jsError(err)
+			}
+			features[i].Geometry = g
+		}
+	}
+
+	b, err := json.Marshal(features)
+	if err != nil {
+		return jsError(err)
+	}
+	return js.Global().Get("JSON").Call("parse", string(b))
+}
+
+// jsError returns err as a JavaScript Error
+func jsError(err error) js.Value {
+	return js.Global().Get("Error").New(err.Error())
+}
@@ -0,0 +1,118 @@
+package insideout
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Codecs compressing the encoded features
+const (
+	SnappyCodec = "snappy"
+	ZstdCodec   = "zstd"
+)
+
+var (
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+	zstdErr     error
+)
+
+// CompressionStore is implemented by the storages able to compress the encoded features
+type CompressionStore interface {
+	SetCompression(codec string) error
+}
+
+// ValidateCodec returns an error if codec is unknown, empty is no compression
+func ValidateCodec(codec string) error {
+	switch codec {
+	case "", SnappyCodec, ZstdCodec:
+		return nil
+	}
+	return fmt.Errorf("unknown compression codec %q, snappy|zstd", codec)
+}
+
+// Compression compresses the encoded features with a codec, embedded by the storages,
+// set before indexing or from the index infos when opening a DB, empty is no compression.
+// The cells are not compressed, they are read by every query.
+type Compression struct {
+	codec string
+}
+
+// SetCompression sets the codec
+func (c *Compression) SetCompression(codec string) error {
+	if err := ValidateCodec(codec); err != nil {
+		return err
+	}
+	c.codec = codec
+	return nil
+}
+
+// Codec returns the codec, empty when not compressing
+func (c *Compression) Codec() string {
+	return c.codec
+}
+
+// Compress returns b compressed with the codec, b when not compressing
+func (c *Compression) Compress(b []byte) ([]byte, error) {
+	switch c.codec {
+	case SnappyCodec:
+		return snappy.Encode(nil, b), nil
+	case ZstdCodec:
+		enc, _, err := zstdCodec()
+		if err != nil {
+			return nil, err
+		}
+		return enc.EncodeAll(b, nil), nil
+	}
+	return b, nil
+}
+
+// Decompress returns b decompressed with the codec, appended to dst[:0] to reuse its memory,
+// b itself when not compressing
+func (c *Compression) Decompress(dst, b []byte) ([]byte, error) {
+	switch c.codec {
+	case SnappyCodec:
+		n, err := snappy.DecodedLen(b)
+		if err != nil {
+			return nil, fmt.Errorf("can't decompress feature: %w", err)
+		}
+		if cap(dst) < n {
+			dst = make([]byte, n)
+		}
+		v, err := snappy.Decode(dst[:n], b)
+		if err != nil {
+			return nil, fmt.Errorf("can't decompress feature: %w", err)
+		}
+		return v, nil
+	case ZstdCodec:
+		_, dec, err := zstdCodec()
+		if err != nil {
+			return nil, err
+		}
+		v, err := dec.DecodeAll(b, dst[:0])
+		if err != nil {
+			return nil, fmt.Errorf("can't decompress feature: %w", err)
+		}
+		return v, nil
+	}
+	return b, nil
+}
+
+// zstdCodec returns the zstd encoder and decoder, they are shared, safe for concurrent stateless use
+func zstdCodec() (*zstd.Encoder, *zstd.Decoder, error) {
+	zstdOnce.Do(func() {
+		zstdEncoder, zstdErr = zstd.NewWriter(nil)
+		if zstdErr != nil {
+			return
+		}
+		zstdDecoder, zstdErr = zstd.NewReader(nil)
+	})
+	if zstdErr != nil {
+		return nil, nil, fmt.Errorf("can't create zstd codec: %w", zstdErr)
+	}
+	return zstdEncoder, zstdDecoder, nil
+}
@@ -0,0 +1,42 @@
+package insideout
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompression(t *testing.T) {
+	b := bytes.Repeat([]byte("insideout loops "), 1000)
+	for _, codec := range []string{"", SnappyCodec, ZstdCodec} {
+		c := &Compression{}
+		require.NoError(t, c.SetCompression(codec))
+		require.Equal(t, codec, c.Codec())
+
+		cb, err := c.Compress(b)
+		require.NoError(t, err)
+		if codec != "" {
+			require.Less(t, len(cb), len(b)/10, codec)
+		}
+
+		got, err := c.Decompress(nil, cb)
+		require.NoError(t, err)
+		require.Equal(t, b, got, codec)
+
+		// the memory of dst is reused
+		dst := make([]byte, 0, 2*len(b))
+		got, err = c.Decompress(dst, cb)
+		require.NoError(t, err)
+		require.Equal(t, b, got, codec)
+		if codec != "" {
+			require.Equal(t, &dst[:1][0], &got[0], codec)
+			_, err = c.Decompress(nil, cb[:len(cb)/2])
+			require.Error(t, err, codec)
+		}
+	}
+
+	require.Error(t, (&Compression{}).SetCompression("lz4"))
+	require.NoError(t, (&IndexInfos{Compression: ZstdCodec}).CheckCompression(ZstdCodec))
+	require.Error(t, (&IndexInfos{}).CheckCompression(SnappyCodec))
+}
@@ -0,0 +1,186 @@
+// Package config loads the flags of a command from a YAML or TOML file
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/BurntSushi/toml"
+	"github.com/namsral/flag"
+	"gopkg.in/yaml.v3"
+)
+
+// Read returns the settings of the YAML or TOML file at path, keyed by flag name.
+// The format is chosen from the extension: .toml for TOML, YAML otherwise.
+// ${VAR}, $VAR and ${VAR:-default} are replaced by the environment variables before parsing, $$ by $.
+// Nested tables are flattened to camelCase flag names: kafka.brokers is kafkaBrokers,
+// lists are joined with commas.
+func Read(path string) (map[string]string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return Decode(Interpolate(string(b)), strings.EqualFold(filepath.Ext(path), ".toml"))
+}
+
+// Decode returns the settings of the YAML or TOML document doc, keyed by flag name
+func Decode(doc string, isTOML bool) (map[string]string, error) {
+	raw := make(map[string]interface{})
+	if isTOML {
+		if _, err := toml.Decode(doc, &raw); err != nil {
+			return nil, fmt.Errorf("can't parse TOML config: %w", err)
+		}
+	} else {
+		if err := yaml.Unmarshal([]byte(doc), &raw); err != nil {
+			return nil, fmt.Errorf("can't parse YAML config: %w", err)
+		}
+	}
+
+	values := make(map[string]string)
+	if err := flatten(values, "", raw); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// Interpolate replaces ${VAR}, $VAR and ${VAR:-default} in s by the environment variables, $$ by $
+func Interpolate(s string) string {
+	return os.Expand(s, func(name string) string {
+		if name == "$" {
+			return "$"
+		}
+		if i := strings.Index(name, ":-"); i > 0 {
+			if v, ok := os.LookupEnv(name[:i]); ok && v != "" {
+				return v
+			}
+			return name[i+2:]
+		}
+		return os.Getenv(name)
+	})
+}
+
+func flatten(values map[string]string, prefix string, raw map[string]interface{}) error {
+	for k, v := range raw {
+		name := k
+		if prefix != "" {
+			name = prefix + upperFirst(k)
+		}
+
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if err := flatten(values, name, v); err != nil {
+				return err
+			}
+		case []interface{}:
+			l := make([]string, len(v))
+			for i, e := range v {
+				s, err := scalar(name, e)
+				if err != nil {
+					return err
+				}
+				l[i] = s
+			}
+			values[name] = strings.Join(l, ",")
+		default:
+			s, err := scalar(name, v)
+			if err != nil {
+				return err
+			}
+			values[name] = s
+		}
+	}
+	return nil
+}
+
+func scalar(name string, v interface{}) (string, error) {
+	switch v.(type) {
+	case nil:
+		return "", nil
+	case map[string]interface{}, []interface{}:
+		return "", fmt.Errorf("invalid nested value for %s", name)
+	}
+	return fmt.Sprint(v), nil
+}
+
+func upperFirst(s string) string {
+	r, n := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[n:]
+}
+
+// Apply sets the flags of fs from values, the flags already set on the command line
+// or by the environment have precedence and are left untouched
+func Apply(fs *flag.FlagSet, values map[string]string) error {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if fs.Lookup(name) == nil {
+			return fmt.Errorf("config provided but not defined: %s", name)
+		}
+		if set[name] {
+			continue
+		}
+		if err := fs.Set(name, values[name]); err != nil {
+			return fmt.Errorf("invalid value %q for config %s: %w", values[name], name, err)
+		}
+	}
+	return nil
+}
+
+// Load reads the file at path and applies it to fs
+func Load(fs *flag.FlagSet, path string) error {
+	values, err := Read(path)
+	if err != nil {
+		return err
+	}
+	return Apply(fs, values)
+}
+
+// Print writes the effective values of the flags of fs as YAML, the flags named in skip are omitted,
+// the output can be loaded back with Load
+func Print(w io.Writer, fs *flag.FlagSet, skip ...string) error {
+	omit := make(map[string]bool, len(skip))
+	for _, name := range skip {
+		omit[name] = true
+	}
+
+	doc := &yaml.Node{Kind: yaml.MappingNode}
+	fs.VisitAll(func(f *flag.Flag) {
+		if omit[f.Name] {
+			return
+		}
+		value := &yaml.Node{Kind: yaml.ScalarNode, Value: f.Value.String()}
+		if value.Value == "" {
+			value.Style = yaml.DoubleQuotedStyle
+		}
+		doc.Content = append(doc.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: f.Name, LineComment: f.Usage},
+			value)
+	})
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/namsral/flag"
+	"github.com/stretchr/testify/require"
+)
+
+func newFlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Int("cacheCount", 200, "Features count to cache")
+	fs.String("dbPath", "inside.db", "Database paths")
+	fs.Bool("stopOnFirstFound", false, "Stop in first feature found")
+	fs.String("kafkaBrokers", "", "Kafka brokers")
+	fs.String("redisPassword", "", "Redis password")
+	fs.String("redisPrefix", "insided:", "Prefix of the Redis keys")
+	fs.Duration("drainPeriod", 0, "Drain period")
+	return fs
+}
+
+func TestDecode(t *testing.T) {
+	yamlDoc := `
+cacheCount: 1000
+dbPath: [a.db, b.db]
+stopOnFirstFound: true
+kafka:
+  brokers: k1:9092
+drainPeriod: 10s
+`
+	tomlDoc := `
+cacheCount = 1000
+dbPath = ["a.db", "b.db"]
+stopOnFirstFound = true
+drainPeriod = "10s"
+
+[kafka]
+brokers = "k1:9092"
+`
+	want := map[string]string{
+		"cacheCount":       "1000",
+		"dbPath":           "a.db,b.db",
+		"stopOnFirstFound": "true",
+		"kafkaBrokers":     "k1:9092",
+		"drainPeriod":      "10s",
+	}
+
+	values, err := Decode(yamlDoc, false)
+	require.NoError(t, err)
+	require.Equal(t, want, values)
+
+	values, err = Decode(tomlDoc, true)
+	require.NoError(t, err)
+	require.Equal(t, want, values)
+
+	_, err = Decode("cacheCount: [[1]]", false)
+	require.Error(t, err)
+}
+
+func TestInterpolate(t *testing.T) {
+	os.Setenv("INSIDEOUT_TEST_PASSWORD", "secret")
+	defer os.Unsetenv("INSIDEOUT_TEST_PASSWORD")
+
+	require.Equal(t, "redisPassword: secret", Interpolate("redisPassword: ${INSIDEOUT_TEST_PASSWORD}"))
+	require.Equal(t, "redisPassword: secret", Interpolate("redisPassword: $INSIDEOUT_TEST_PASSWORD"))
+	require.Equal(t, "redisPassword: def", Interpolate("redisPassword: ${INSIDEOUT_TEST_UNSET:-def}"))
+	require.Equal(t, "redisPassword: a$b", Interpolate("redisPassword: a$$b"))
+}
+
+func TestApply(t *testing.T) {
+	fs := newFlagSet()
+	require.NoError(t, fs.Parse([]string{"-cacheCount", "10"}))
+
+	err := Apply(fs, map[string]string{"cacheCount": "1000", "dbPath": "a.db,b.db", "stopOnFirstFound": "true"})
+	require.NoError(t, err)
+
+	// the command line has precedence
+	require.Equal(t, "10", fs.Lookup("cacheCount").Value.String())
+	require.Equal(t, "a.db,b.db", fs.Lookup("dbPath").Value.String())
+	require.Equal(t, "true", fs.Lookup("stopOnFirstFound").Value.String())
+
+	require.Error(t, Apply(fs, map[string]string{"unknown": "1"}))
+	require.Error(t, Apply(fs, map[string]string{"drainPeriod": "soon"}))
+}
+
+func TestPrintLoad(t *testing.T) {
+	fs := newFlagSet()
+	require.NoError(t, fs.Parse([]string{"-cacheCount", "10", "-dbPath", "a.db,b.db", "-drainPeriod", "3s"}))
+
+	var buf bytes.Buffer
+	require.NoError(t, Print(&buf, fs, "redisPassword"))
+	require.NotContains(t, buf.String(), "redisPassword")
+
+	dir, err := ioutil.TempDir("", "config")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "insided.yaml")
+	require.NoError(t, ioutil.WriteFile(path, buf.Bytes(), 0o600))
+
+	loaded := newFlagSet()
+	require.NoError(t, loaded.Parse(nil))
+	require.NoError(t, Load(loaded, path))
+
+	fs.VisitAll(func(f *flag.Flag) {
+		require.Equal(t, f.Value.String(), loaded.Lookup(f.Name).Value.String(), f.Name)
+	})
+	require.Equal(t, 3*time.Second, loaded.Lookup("drainPeriod").Value.(flag.Getter).Get())
+}
@@ -0,0 +1,121 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/namsral/flag"
+)
+
+// watchDelay groups the events of a single save, editors often write a file several times
+const watchDelay = 100 * time.Millisecond
+
+// ErrRestartRequired is reported for a changed flag that can't be applied at runtime
+var ErrRestartRequired = errors.New("can't be changed at runtime, requires a restart")
+
+// Change reports a flag changed by Reload, Err is not nil when it was not applied
+type Change struct {
+	Name string
+	Old  string
+	New  string
+	Err  error
+}
+
+// Watch calls fn with the settings of the file at path each time its content changes, until ctx is done.
+// The directory of path is watched so files replaced by a rename, like the Kubernetes ConfigMaps, are seen.
+func Watch(ctx context.Context, path string, fn func(map[string]string, error)) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	if err := w.Add(filepath.Dir(path)); err != nil {
+		return err
+	}
+
+	last, _ := ioutil.ReadFile(path)
+	timer := time.NewTimer(watchDelay)
+	timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err, ok := <-w.Errors:
+			if !ok {
+				return nil
+			}
+			fn(nil, err)
+		case _, ok := <-w.Events:
+			if !ok {
+				return nil
+			}
+			timer.Reset(watchDelay)
+		case <-timer.C:
+			b, err := ioutil.ReadFile(path)
+			if err != nil {
+				// a file being replaced may be missing for a moment
+				continue
+			}
+			if bytes.Equal(b, last) {
+				continue
+			}
+			last = b
+			fn(Read(path))
+		}
+	}
+}
+
+// Reload sets the flags of fs from values, except the ones named in skip, and calls the apply func
+// of each changed flag. A changed flag without apply func, or whose apply func fails, is restored
+// to its previous value. Flags missing from values are left untouched.
+func Reload(fs *flag.FlagSet, values map[string]string, skip map[string]bool,
+	apply map[string]func() error) []Change {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var changes []Change
+	for _, name := range names {
+		if skip[name] {
+			continue
+		}
+
+		f := fs.Lookup(name)
+		if f == nil {
+			changes = append(changes, Change{Name: name, New: values[name],
+				Err: errors.New("config provided but not defined")})
+			continue
+		}
+
+		old := f.Value.String()
+		if err := f.Value.Set(values[name]); err != nil {
+			changes = append(changes, Change{Name: name, Old: old, New: values[name], Err: err})
+			continue
+		}
+		c := Change{Name: name, Old: old, New: f.Value.String()}
+		if c.New == c.Old {
+			continue
+		}
+
+		if fn, ok := apply[name]; ok {
+			c.Err = fn()
+		} else {
+			c.Err = ErrRestartRequired
+		}
+		if c.Err != nil {
+			_ = f.Value.Set(old)
+		}
+		changes = append(changes, c)
+	}
+	return changes
+}
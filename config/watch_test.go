@@ -0,0 +1,85 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReload(t *testing.T) {
+	fs := newFlagSet()
+	require.NoError(t, fs.Parse([]string{"-stopOnFirstFound"}))
+
+	var applied []string
+	apply := map[string]func() error{
+		"cacheCount": func() error {
+			applied = append(applied, "cacheCount")
+			return nil
+		},
+		"drainPeriod": func() error { return errors.New("failed") },
+	}
+
+	changes := Reload(fs, map[string]string{
+		"cacheCount":       "1000",
+		"dbPath":           "other.db",
+		"drainPeriod":      "1s",
+		"redisPrefix":      "insided:",
+		"stopOnFirstFound": "false",
+	}, map[string]bool{"stopOnFirstFound": true}, apply)
+
+	require.Equal(t, []string{"cacheCount"}, applied)
+	require.Len(t, changes, 3)
+	require.Equal(t, Change{Name: "cacheCount", Old: "200", New: "1000"}, changes[0])
+	require.Equal(t, "dbPath", changes[1].Name)
+	require.Equal(t, ErrRestartRequired, changes[1].Err)
+	require.Equal(t, "drainPeriod", changes[2].Name)
+	require.Error(t, changes[2].Err)
+
+	// not applied changes are restored, skipped flags untouched
+	require.Equal(t, "1000", fs.Lookup("cacheCount").Value.String())
+	require.Equal(t, "inside.db", fs.Lookup("dbPath").Value.String())
+	require.Equal(t, "0s", fs.Lookup("drainPeriod").Value.String())
+	require.Equal(t, "true", fs.Lookup("stopOnFirstFound").Value.String())
+
+	// unchanged
+	require.Empty(t, Reload(fs, map[string]string{"cacheCount": "1000"}, nil, apply))
+}
+
+func TestWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "insided.yaml")
+	require.NoError(t, ioutil.WriteFile(path, []byte("cacheCount: 1\n"), 0o600))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch := make(chan map[string]string, 1)
+	go Watch(ctx, path, func(values map[string]string, err error) {
+		require.NoError(t, err)
+		ch <- values
+	})
+
+	// replaced by a rename, until the watcher is ready
+	tmp := filepath.Join(dir, "insided.yaml.tmp")
+	for i := 0; ; i++ {
+		count := strconv.Itoa(i + 2)
+		require.NoError(t, ioutil.WriteFile(tmp, []byte("cacheCount: "+count+"\n"), 0o600))
+		require.NoError(t, os.Rename(tmp, path))
+		select {
+		case values := <-ch:
+			require.Equal(t, map[string]string{"cacheCount": count}, values)
+			return
+		case <-time.After(500 * time.Millisecond):
+			require.Less(t, i, 10, "no change seen")
+		}
+	}
+}
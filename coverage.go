@@ -0,0 +1,47 @@
+package insideout
+
+import (
+	"fmt"
+
+	"github.com/golang/geo/s2"
+)
+
+// CoverageLevel the level of the cells of the coverage of an index, about 150km wide
+const CoverageLevel = 6
+
+// ComputeCoverage returns the coverage of all the features of s,
+// the cells at CoverageLevel, or lower once normalized, containing their outside covers
+func ComputeCoverage(s Store) (s2.CellUnion, error) {
+	var coverage s2.CellUnion
+	err := s.LoadFeaturesCells(func(cellsIn []s2.CellUnion, cellsOut []s2.CellUnion, id uint32) {
+		coverage = ExtendCoverage(coverage, cellsOut...)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cells from storage: %w", err)
+	}
+	return coverage, nil
+}
+
+// ExtendCoverage returns coverage extended to the cells of cus
+func ExtendCoverage(coverage s2.CellUnion, cus ...s2.CellUnion) s2.CellUnion {
+	extended := append(s2.CellUnion(nil), coverage...)
+	for _, cu := range cus {
+		for _, c := range cu {
+			if c.Level() > CoverageLevel {
+				c = c.Parent(CoverageLevel)
+			}
+			extended = append(extended, c)
+		}
+	}
+	extended.Normalize()
+	return extended
+}
+
+// Covers returns false if the point at lat lng is outside of the coverage of the index,
+// true when the index does not record its coverage
+func (infos *IndexInfos) Covers(lat, lng float64) bool {
+	if len(infos.Coverage) == 0 {
+		return true
+	}
+	return infos.Coverage.ContainsCellID(s2.CellIDFromLatLng(s2.LatLngFromDegrees(lat, lng)))
+}
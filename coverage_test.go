@@ -0,0 +1,42 @@
+package insideout
+
+import (
+	"testing"
+
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtendCoverage(t *testing.T) {
+	paris := s2.CellIDFromLatLng(s2.LatLngFromDegrees(48.8, 2.3))
+	tokyo := s2.CellIDFromLatLng(s2.LatLngFromDegrees(35.7, 139.7))
+
+	coverage := ExtendCoverage(nil, s2.CellUnion{paris.Parent(10), paris.Parent(12)})
+	require.Equal(t, s2.CellUnion{paris.Parent(CoverageLevel)}, coverage)
+
+	// the lower levels are kept
+	coverage = ExtendCoverage(coverage, s2.CellUnion{tokyo.Parent(3)})
+	require.Len(t, coverage, 2)
+	require.True(t, coverage.ContainsCellID(tokyo))
+
+	infos := &IndexInfos{}
+	require.True(t, infos.Covers(-33.9, 151.2))
+	infos.Coverage = coverage
+	require.True(t, infos.Covers(48.8, 2.3))
+	require.True(t, infos.Covers(35.7, 139.7))
+	require.False(t, infos.Covers(-33.9, 151.2))
+}
+
+func TestComputeCoverage(t *testing.T) {
+	paris := s2.CellIDFromLatLng(s2.LatLngFromDegrees(48.8, 2.3))
+	tokyo := s2.CellIDFromLatLng(s2.LatLngFromDegrees(35.7, 139.7))
+	s := &cellsStore{cells: [][]s2.CellUnion{
+		{{paris.Parent(8), paris.Parent(12)}},
+		{{tokyo.Parent(10)}},
+	}}
+	coverage, err := ComputeCoverage(s)
+	require.NoError(t, err)
+	require.Len(t, coverage, 2)
+	require.True(t, coverage.ContainsCellID(paris))
+	require.True(t, coverage.ContainsCellID(tokyo))
+}
@@ -0,0 +1,235 @@
+// Package crs converts coordinates from the reference systems found in WKT (.prj) definitions to WGS84
+//
+// Only the projections commonly used by national mapping agencies are supported:
+// Transverse Mercator (UTM), Lambert Conformal Conic (1SP & 2SP) and Mercator (including Web Mercator).
+// Datum shifts are not applied, the source ellipsoid is used for the inverse projection,
+// which is accurate to a few meters for modern datums (ETRS89, NAD83, GDA94...).
+package crs
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/twpayne/go-geom"
+)
+
+// Projection converts coordinates from a reference system to WGS84
+type Projection interface {
+	// ToWGS84 returns the lng lat in degrees of x y
+	ToWGS84(x, y float64) (lng, lat float64)
+}
+
+// ellipsoid defined by its semi major axis and eccentricity
+type ellipsoid struct {
+	a  float64
+	e2 float64
+}
+
+func newEllipsoid(a, invf float64) ellipsoid {
+	if invf == 0 {
+		return ellipsoid{a: a}
+	}
+	f := 1 / invf
+	return ellipsoid{a: a, e2: f * (2 - f)}
+}
+
+// geographic handles non projected systems, only the prime meridian is applied
+type geographic struct {
+	primeMeridian float64
+	unit          float64
+}
+
+func (g geographic) ToWGS84(x, y float64) (lng, lat float64) {
+	return x*g.unit + g.primeMeridian, y * g.unit
+}
+
+// FromWKT returns the projection described by a WKT CRS definition as found in .prj files
+func FromWKT(wkt string) (Projection, error) {
+	root, err := parseWKT(strings.TrimSpace(wkt))
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToUpper(root.keyword) {
+	case "GEOGCS":
+		geo, _, err := parseGeogcs(root)
+		if err != nil {
+			return nil, err
+		}
+		return geo, nil
+	case "PROJCS":
+		return parseProjcs(root)
+	default:
+		return nil, fmt.Errorf("unsupported CRS %s", root.keyword)
+	}
+}
+
+func parseGeogcs(n *wktNode) (geographic, ellipsoid, error) {
+	geo := geographic{unit: 1}
+	spheroid := n.child("DATUM").child("SPHEROID")
+	if spheroid == nil {
+		return geo, ellipsoid{}, errors.New("missing SPHEROID in GEOGCS")
+	}
+	a, err := spheroid.number(1)
+	if err != nil {
+		return geo, ellipsoid{}, fmt.Errorf("invalid SPHEROID: %w", err)
+	}
+	invf, err := spheroid.number(2)
+	if err != nil {
+		return geo, ellipsoid{}, fmt.Errorf("invalid SPHEROID: %w", err)
+	}
+
+	if pm := n.child("PRIMEM"); pm != nil {
+		if geo.primeMeridian, err = pm.number(1); err != nil {
+			return geo, ellipsoid{}, fmt.Errorf("invalid PRIMEM: %w", err)
+		}
+	}
+
+	// the angular unit is expressed in radians, only applied to geographic coordinates
+	if unit := n.child("UNIT"); unit != nil {
+		rad, err := unit.number(1)
+		if err != nil {
+			return geo, ellipsoid{}, fmt.Errorf("invalid UNIT: %w", err)
+		}
+		geo.unit = rad * 180 / math.Pi
+	}
+
+	return geo, newEllipsoid(a, invf), nil
+}
+
+// projParams the projection parameters, angles in radians, distances in meters
+type projParams struct {
+	lon0, lat0, lat1, lat2 float64
+	k0                     float64
+	fe, fn                 float64
+	unit                   float64
+	primeMeridian          float64
+}
+
+func parseProjcs(n *wktNode) (Projection, error) {
+	gn := n.child("GEOGCS")
+	if gn == nil {
+		return nil, errors.New("missing GEOGCS in PROJCS")
+	}
+	geo, ell, err := parseGeogcs(gn)
+	if err != nil {
+		return nil, err
+	}
+
+	pp := projParams{k0: 1, unit: 1, primeMeridian: geo.primeMeridian}
+	if unit := n.child("UNIT"); unit != nil {
+		if pp.unit, err = unit.number(1); err != nil {
+			return nil, fmt.Errorf("invalid UNIT: %w", err)
+		}
+	}
+
+	values := make(map[string]float64)
+	for _, c := range n.children {
+		if !strings.EqualFold(c.keyword, "PARAMETER") {
+			continue
+		}
+		v, err := c.number(1)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PARAMETER %s: %w", c.name(), err)
+		}
+		values[strings.ToLower(c.name())] = v
+	}
+
+	deg := func(names ...string) float64 {
+		for _, name := range names {
+			if v, ok := values[name]; ok {
+				return v * math.Pi / 180
+			}
+		}
+		return 0
+	}
+	pp.lon0 = deg("central_meridian", "longitude_of_origin", "longitude_of_center")
+	pp.lat0 = deg("latitude_of_origin", "latitude_of_center")
+	pp.lat1 = deg("standard_parallel_1")
+	pp.lat2 = deg("standard_parallel_2")
+	if v, ok := values["scale_factor"]; ok {
+		pp.k0 = v
+	}
+	pp.fe = values["false_easting"] * pp.unit
+	pp.fn = values["false_northing"] * pp.unit
+
+	method := strings.ToLower(n.child("PROJECTION").name())
+	switch method {
+	case "transverse_mercator", "gauss_kruger":
+		return newTransverseMercator(ell, pp), nil
+	case "lambert_conformal_conic", "lambert_conformal_conic_2sp":
+		// ESRI uses a single name, a missing second parallel means 1SP
+		if _, ok := values["standard_parallel_2"]; ok {
+			return newLambertConformalConic(ell, pp), nil
+		}
+		if _, ok := values["standard_parallel_1"]; ok {
+			pp.lat0 = pp.lat1
+		}
+		pp.lat1, pp.lat2 = pp.lat0, pp.lat0
+		return newLambertConformalConic(ell, pp), nil
+	case "lambert_conformal_conic_1sp":
+		pp.lat1, pp.lat2 = pp.lat0, pp.lat0
+		return newLambertConformalConic(ell, pp), nil
+	case "mercator", "mercator_1sp", "mercator_2sp":
+		if sp, ok := values["standard_parallel_1"]; ok {
+			// 2SP variant, derive the scale factor from the latitude of true scale
+			phi := sp * math.Pi / 180
+			pp.k0 = math.Cos(phi) / math.Sqrt(1-ell.e2*math.Sin(phi)*math.Sin(phi))
+		}
+		return newMercator(ell, pp), nil
+	case "mercator_auxiliary_sphere", "popular_visualisation_pseudo_mercator":
+		// Web Mercator uses the spherical formulas on the semi major axis
+		return newMercator(ellipsoid{a: ell.a}, pp), nil
+	default:
+		return nil, fmt.Errorf("unsupported projection %q", method)
+	}
+}
+
+// Reproject converts in place the coordinates of g to WGS84
+func Reproject(g geom.T, p Projection) {
+	coords := g.FlatCoords()
+	stride := g.Stride()
+	for i := 0; i+1 < len(coords); i += stride {
+		coords[i], coords[i+1] = p.ToWGS84(coords[i], coords[i+1])
+	}
+}
+
+// toDegrees returns the lng lat in degrees, applying the prime meridian
+func (pp projParams) toDegrees(lon, lat float64) (float64, float64) {
+	lng := lon*180/math.Pi + pp.primeMeridian
+	if lng > 180 {
+		lng -= 360
+	} else if lng < -180 {
+		lng += 360
+	}
+	return lng, lat * 180 / math.Pi
+}
+
+// phiFromT returns the latitude for t, the inverse of Snyder 7-9 computed iteratively
+func (e ellipsoid) phiFromT(t float64) float64 {
+	ecc := math.Sqrt(e.e2)
+	phi := math.Pi/2 - 2*math.Atan(t)
+	for i := 0; i < 15; i++ {
+		es := ecc * math.Sin(phi)
+		next := math.Pi/2 - 2*math.Atan(t*math.Pow((1-es)/(1+es), ecc/2))
+		if math.Abs(next-phi) < 1e-12 {
+			return next
+		}
+		phi = next
+	}
+	return phi
+}
+
+// t Snyder 15-9
+func (e ellipsoid) t(phi float64) float64 {
+	es := math.Sqrt(e.e2) * math.Sin(phi)
+	return math.Tan(math.Pi/4-phi/2) / math.Pow((1-es)/(1+es), math.Sqrt(e.e2)/2)
+}
+
+// m Snyder 14-15
+func (e ellipsoid) m(phi float64) float64 {
+	s := math.Sin(phi)
+	return math.Cos(phi) / math.Sqrt(1-e.e2*s*s)
+}
@@ -0,0 +1,173 @@
+package crs
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom"
+)
+
+const (
+	wktWGS84 = `GEOGCS["GCS_WGS_1984",DATUM["D_WGS_1984",SPHEROID["WGS_1984",6378137.0,298.257223563]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]]`
+
+	wktNTFParis = `GEOGCS["NTF (Paris)",DATUM["Nouvelle_Triangulation_Francaise_Paris",SPHEROID["Clarke 1880 (IGN)",6378249.2,293.4660212936269]],PRIMEM["Paris",2.33722917],UNIT["degree",0.0174532925199433]]`
+
+	wktUTM31N = `PROJCS["WGS_1984_UTM_Zone_31N",GEOGCS["GCS_WGS_1984",DATUM["D_WGS_1984",SPHEROID["WGS_1984",6378137.0,298.257223563]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]],PROJECTION["Transverse_Mercator"],PARAMETER["False_Easting",500000.0],PARAMETER["False_Northing",0.0],PARAMETER["Central_Meridian",3.0],PARAMETER["Scale_Factor",0.9996],PARAMETER["Latitude_Of_Origin",0.0],UNIT["Meter",1.0]]`
+
+	wktLambert93 = `PROJCS["RGF_1993_Lambert_93",GEOGCS["GCS_RGF_1993",DATUM["D_RGF_1993",SPHEROID["GRS_1980",6378137.0,298.257222101]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]],PROJECTION["Lambert_Conformal_Conic"],PARAMETER["False_Easting",700000.0],PARAMETER["False_Northing",6600000.0],PARAMETER["Central_Meridian",3.0],PARAMETER["Standard_Parallel_1",49.0],PARAMETER["Standard_Parallel_2",44.0],PARAMETER["Latitude_Of_Origin",46.5],UNIT["Meter",1.0]]`
+
+	wktWebMercator = `PROJCS["WGS_1984_Web_Mercator_Auxiliary_Sphere",GEOGCS["GCS_WGS_1984",DATUM["D_WGS_1984",SPHEROID["WGS_1984",6378137.0,298.257223563]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]],PROJECTION["Mercator_Auxiliary_Sphere"],PARAMETER["False_Easting",0.0],PARAMETER["False_Northing",0.0],PARAMETER["Central_Meridian",0.0],PARAMETER["Standard_Parallel_1",0.0],PARAMETER["Auxiliary_Sphere_Type",0.0],UNIT["Meter",1.0]]`
+)
+
+func TestFromWKT(t *testing.T) {
+	tests := []struct {
+		name     string
+		wkt      string
+		x, y     float64
+		lng, lat float64
+	}{
+		{"geographic", wktWGS84, 2.35, 48.85, 2.35, 48.85},
+		{"paris meridian", wktNTFParis, 0, 48.85, 2.33722917, 48.85},
+		{"utm central meridian", wktUTM31N, 500000, 4982950.400, 3, 45},
+		{"utm equator", wktUTM31N, 500000, 0, 3, 0},
+		{"lambert93 origin", wktLambert93, 700000, 6600000, 3, 46.5},
+		{"web mercator origin", wktWebMercator, 0, 0, 0, 0},
+		{"web mercator bounds", wktWebMercator, 10018754.171394622, 20037508.342789244, 90, 85.0511287798},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := FromWKT(tt.wkt)
+			require.NoError(t, err)
+			lng, lat := p.ToWGS84(tt.x, tt.y)
+			require.InDelta(t, tt.lng, lng, 1e-6)
+			require.InDelta(t, tt.lat, lat, 1e-6)
+		})
+	}
+}
+
+// lccForward Snyder 15-1 to 15-5, to validate the inverse off the origin
+func lccForward(lcc *lambertConformalConic, lng, lat float64) (x, y float64) {
+	phi, lam := lat*math.Pi/180, lng*math.Pi/180
+	rho := lcc.a * lcc.f * lcc.k0 * math.Pow(lcc.t(phi), lcc.n)
+	theta := lcc.n * (lam - lcc.lon0)
+	return lcc.fe + rho*math.Sin(theta), lcc.fn + lcc.rho0 - rho*math.Cos(theta)
+}
+
+func TestLambertRoundTrip(t *testing.T) {
+	p, err := FromWKT(wktLambert93)
+	require.NoError(t, err)
+	lcc := p.(*lambertConformalConic)
+
+	for _, c := range [][2]float64{{2.3488, 48.8534}, {-4.4861, 48.3904}, {7.2619, 43.7102}} {
+		x, y := lccForward(lcc, c[0], c[1])
+		lng, lat := lcc.ToWGS84(x, y)
+		require.InDelta(t, c[0], lng, 1e-9)
+		require.InDelta(t, c[1], lat, 1e-9)
+	}
+}
+
+// tmForward Snyder 8-9 to 8-10, to validate the inverse off the central meridian
+func tmForward(tm *transverseMercator, lng, lat float64) (x, y float64) {
+	phi, lam := lat*math.Pi/180, lng*math.Pi/180
+	ep2 := tm.e2 / (1 - tm.e2)
+	sin, cos, tan := math.Sin(phi), math.Cos(phi), math.Tan(phi)
+	n := tm.a / math.Sqrt(1-tm.e2*sin*sin)
+	t := tan * tan
+	c := ep2 * cos * cos
+	a := (lam - tm.lon0) * cos
+	x = tm.k0 * n * (a + (1-t+c)*math.Pow(a, 3)/6 + (5-18*t+t*t+72*c-58*ep2)*math.Pow(a, 5)/120)
+	y = tm.k0 * (tm.meridianArc(phi) - tm.m0 + n*tan*(a*a/2+(5-t+9*c+4*c*c)*math.Pow(a, 4)/24+
+		(61-58*t+t*t+600*c-330*ep2)*math.Pow(a, 6)/720))
+	return x + tm.fe, y + tm.fn
+}
+
+func TestTransverseMercatorRoundTrip(t *testing.T) {
+	p, err := FromWKT(wktUTM31N)
+	require.NoError(t, err)
+	tm := p.(*transverseMercator)
+
+	for _, c := range [][2]float64{{2.3488, 48.8534}, {0.5, 41.2}, {5.9, 52.1}} {
+		x, y := tmForward(tm, c[0], c[1])
+		lng, lat := tm.ToWGS84(x, y)
+		require.InDelta(t, c[0], lng, 1e-7)
+		require.InDelta(t, c[1], lat, 1e-7)
+	}
+}
+
+func TestReproject(t *testing.T) {
+	p, err := FromWKT(wktLambert93)
+	require.NoError(t, err)
+
+	poly := geom.NewPolygonFlat(geom.XY, []float64{
+		700000, 6600000, 710000, 6600000, 710000, 6610000, 700000, 6600000,
+	}, []int{8})
+	Reproject(poly, p)
+
+	require.InDelta(t, 3.0, poly.FlatCoords()[0], 1e-6)
+	require.InDelta(t, 46.5, poly.FlatCoords()[1], 1e-6)
+	require.True(t, poly.FlatCoords()[2] > 3.0)
+}
+
+func TestFromWKTErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		wkt  string
+	}{
+		{"empty", ""},
+		{"unterminated", `GEOGCS["WGS84",DATUM["D",SPHEROID["WGS_1984",6378137.0,298.257223563]]`},
+		{"unsupported projection", `PROJCS["x",GEOGCS["GCS_WGS_1984",DATUM["D_WGS_1984",SPHEROID["WGS_1984",6378137.0,298.257223563]]],PROJECTION["Polyconic"]]`},
+		{"geocentric", `GEOCCS["WGS 84"]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := FromWKT(tt.wkt)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestFromName(t *testing.T) {
+	tests := []struct {
+		name     string
+		x, y     float64
+		lng, lat float64
+	}{
+		{"urn:ogc:def:crs:OGC:1.3:CRS84", 2.35, 48.85, 2.35, 48.85},
+		{"EPSG:4326", 2.35, 48.85, 2.35, 48.85},
+		{"urn:ogc:def:crs:EPSG::2154", 700000, 6600000, 3, 46.5},
+		{"EPSG:32631", 500000, 4982950.400, 3, 45},
+		{"urn:ogc:def:crs:EPSG::32731", 500000, 10000000, 3, 0},
+		{"EPSG:25832", 500000, 0, 9, 0},
+		{"EPSG:3857", 10018754.171394622, 0, 90, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := FromName(tt.name)
+			require.NoError(t, err)
+			lng, lat := p.ToWGS84(tt.x, tt.y)
+			require.InDelta(t, tt.lng, lng, 1e-6)
+			require.InDelta(t, tt.lat, lat, 1e-6)
+		})
+	}
+
+	for _, name := range []string{"", "EPSG:27700", "urn:ogc:def:crs:EPSG::x", "ESRI:102003"} {
+		_, err := FromName(name)
+		require.Error(t, err, name)
+	}
+}
+
+func TestCheckLatLng(t *testing.T) {
+	require.NoError(t, CheckLatLng(geom.NewPolygonFlat(geom.XY, []float64{
+		-180, -90, 180, -90, 180, 90, -180, -90,
+	}, []int{8})))
+	require.NoError(t, CheckLatLng(geom.NewPolygonFlat(geom.XY, []float64{
+		177, 64, 190, 64, 190, 70, 177, 70, 177, 64,
+	}, []int{10})))
+	require.Error(t, CheckLatLng(geom.NewPolygonFlat(geom.XY, []float64{
+		700000, 6600000, 710000, 6600000, 710000, 6610000, 700000, 6600000,
+	}, []int{8})))
+}
@@ -0,0 +1,90 @@
+package crs
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/twpayne/go-geom"
+)
+
+var (
+	wgs84 = newEllipsoid(6378137, 298.257223563)
+	grs80 = newEllipsoid(6378137, 298.257222101)
+)
+
+// FromEPSG returns the projection of the EPSG code, only the codes of WGS84 and its common realizations,
+// Web Mercator, the UTM zones of WGS84, ETRS89 and NAD83 and Lambert 93 are known without a WKT definition
+func FromEPSG(code int) (Projection, error) {
+	switch {
+	// WGS84, ETRS89, NAD83, GDA94, RGF93 geographic
+	case code == 4326, code == 4258, code == 4269, code == 4283, code == 4171:
+		return geographic{unit: 1}, nil
+	case code == 3857, code == 900913, code == 102100:
+		return newMercator(ellipsoid{a: wgs84.a}, projParams{k0: 1, unit: 1}), nil
+	case code == 2154:
+		return newLambertConformalConic(grs80, projParams{
+			lon0: 3 * math.Pi / 180, lat0: 46.5 * math.Pi / 180,
+			lat1: 49 * math.Pi / 180, lat2: 44 * math.Pi / 180,
+			k0: 1, fe: 700000, fn: 6600000, unit: 1,
+		}), nil
+	case code >= 32601 && code <= 32660:
+		return utm(wgs84, code-32600, false), nil
+	case code >= 32701 && code <= 32760:
+		return utm(wgs84, code-32700, true), nil
+	case code >= 25828 && code <= 25838:
+		return utm(grs80, code-25800, false), nil
+	case code >= 26901 && code <= 26923:
+		return utm(grs80, code-26900, false), nil
+	}
+	return nil, fmt.Errorf("unsupported CRS EPSG:%d, reproject the file to EPSG:4326", code)
+}
+
+// utm returns the projection of the UTM zone on e
+func utm(e ellipsoid, zone int, south bool) Projection {
+	pp := projParams{
+		lon0: float64(zone*6-183) * math.Pi / 180,
+		k0:   0.9996,
+		fe:   500000,
+		unit: 1,
+	}
+	if south {
+		pp.fn = 10000000
+	}
+	return newTransverseMercator(e, pp)
+}
+
+// FromName returns the projection of a CRS name as found in the crs member of GeoJSON files:
+// urn:ogc:def:crs:EPSG::2154, EPSG:2154 or urn:ogc:def:crs:OGC:1.3:CRS84 for WGS84
+func FromName(name string) (Projection, error) {
+	n := strings.ToUpper(strings.TrimSpace(name))
+	if strings.HasSuffix(n, "CRS84") {
+		return geographic{unit: 1}, nil
+	}
+	i := strings.LastIndex(n, "EPSG:")
+	if i == -1 {
+		return nil, fmt.Errorf("unsupported CRS %s", name)
+	}
+	// the URNs have an empty version before the code
+	code, err := strconv.Atoi(strings.TrimLeft(n[i+len("EPSG:"):], ":"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid CRS %s", name)
+	}
+	return FromEPSG(code)
+}
+
+// CheckLatLng returns an error if a coordinate of g is not a lng lat in degrees,
+// the sign of projected coordinates read as degrees, the longitudes up to 360 of the rings
+// continuing across the antimeridian are accepted
+func CheckLatLng(g geom.T) error {
+	coords := g.FlatCoords()
+	stride := g.Stride()
+	for i := 0; i+1 < len(coords); i += stride {
+		lng, lat := coords[i], coords[i+1]
+		if math.IsNaN(lng) || math.IsNaN(lat) || lng < -360 || lng > 360 || lat < -90 || lat > 90 {
+			return fmt.Errorf("coordinates %v,%v out of the lng lat range, projected coordinates must declare their CRS", lng, lat)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package crs
+
+import "math"
+
+// transverseMercator inverse Snyder 8-18 to 8-25
+type transverseMercator struct {
+	ellipsoid
+	projParams
+	m0 float64
+}
+
+func newTransverseMercator(e ellipsoid, pp projParams) *transverseMercator {
+	tm := &transverseMercator{ellipsoid: e, projParams: pp}
+	tm.m0 = tm.meridianArc(pp.lat0)
+	return tm
+}
+
+// meridianArc Snyder 3-21
+func (tm *transverseMercator) meridianArc(phi float64) float64 {
+	e2 := tm.e2
+	e4 := e2 * e2
+	e6 := e4 * e2
+	return tm.a * ((1-e2/4-3*e4/64-5*e6/256)*phi -
+		(3*e2/8+3*e4/32+45*e6/1024)*math.Sin(2*phi) +
+		(15*e4/256+45*e6/1024)*math.Sin(4*phi) -
+		(35*e6/3072)*math.Sin(6*phi))
+}
+
+func (tm *transverseMercator) ToWGS84(x, y float64) (lng, lat float64) {
+	x = x*tm.unit - tm.fe
+	y = y*tm.unit - tm.fn
+
+	e2 := tm.e2
+	e4 := e2 * e2
+	e6 := e4 * e2
+	ep2 := e2 / (1 - e2)
+
+	m := tm.m0 + y/tm.k0
+	mu := m / (tm.a * (1 - e2/4 - 3*e4/64 - 5*e6/256))
+	e1 := (1 - math.Sqrt(1-e2)) / (1 + math.Sqrt(1-e2))
+	phi1 := mu + (3*e1/2-27*math.Pow(e1, 3)/32)*math.Sin(2*mu) +
+		(21*e1*e1/16-55*math.Pow(e1, 4)/32)*math.Sin(4*mu) +
+		(151*math.Pow(e1, 3)/96)*math.Sin(6*mu) +
+		(1097*math.Pow(e1, 4)/512)*math.Sin(8*mu)
+
+	sin1, cos1, tan1 := math.Sin(phi1), math.Cos(phi1), math.Tan(phi1)
+	c1 := ep2 * cos1 * cos1
+	t1 := tan1 * tan1
+	n1 := tm.a / math.Sqrt(1-e2*sin1*sin1)
+	r1 := tm.a * (1 - e2) / math.Pow(1-e2*sin1*sin1, 1.5)
+	d := x / (n1 * tm.k0)
+
+	phi := phi1 - (n1*tan1/r1)*(d*d/2-
+		(5+3*t1+10*c1-4*c1*c1-9*ep2)*math.Pow(d, 4)/24+
+		(61+90*t1+298*c1+45*t1*t1-252*ep2-3*c1*c1)*math.Pow(d, 6)/720)
+	lon := tm.lon0 + (d-
+		(1+2*t1+c1)*math.Pow(d, 3)/6+
+		(5-2*c1+28*t1-3*c1*c1+8*ep2+24*t1*t1)*math.Pow(d, 5)/120)/cos1
+
+	return tm.toDegrees(lon, phi)
+}
+
+// lambertConformalConic inverse Snyder 15-1 to 15-11
+type lambertConformalConic struct {
+	ellipsoid
+	projParams
+	n, f, rho0 float64
+}
+
+func newLambertConformalConic(e ellipsoid, pp projParams) *lambertConformalConic {
+	lcc := &lambertConformalConic{ellipsoid: e, projParams: pp}
+	m1, m2 := e.m(pp.lat1), e.m(pp.lat2)
+	t1, t2 := e.t(pp.lat1), e.t(pp.lat2)
+	if pp.lat1 == pp.lat2 {
+		lcc.n = math.Sin(pp.lat1)
+	} else {
+		lcc.n = (math.Log(m1) - math.Log(m2)) / (math.Log(t1) - math.Log(t2))
+	}
+	lcc.f = m1 / (lcc.n * math.Pow(t1, lcc.n))
+	lcc.rho0 = e.a * lcc.f * pp.k0 * math.Pow(e.t(pp.lat0), lcc.n)
+	return lcc
+}
+
+func (lcc *lambertConformalConic) ToWGS84(x, y float64) (lng, lat float64) {
+	x = x*lcc.unit - lcc.fe
+	y = lcc.rho0 - (y*lcc.unit - lcc.fn)
+
+	rho := math.Sqrt(x*x + y*y)
+	if lcc.n < 0 {
+		rho, x, y = -rho, -x, -y
+	}
+	theta := math.Atan2(x, y)
+	t := math.Pow(rho/(lcc.a*lcc.f*lcc.k0), 1/lcc.n)
+
+	return lcc.toDegrees(theta/lcc.n+lcc.lon0, lcc.phiFromT(t))
+}
+
+// mercator inverse Snyder 7-10 to 7-13
+type mercator struct {
+	ellipsoid
+	projParams
+}
+
+func newMercator(e ellipsoid, pp projParams) *mercator {
+	return &mercator{ellipsoid: e, projParams: pp}
+}
+
+func (m *mercator) ToWGS84(x, y float64) (lng, lat float64) {
+	x = x*m.unit - m.fe
+	y = y*m.unit - m.fn
+
+	t := math.Exp(-y / (m.a * m.k0))
+	return m.toDegrees(x/(m.a*m.k0)+m.lon0, m.phiFromT(t))
+}
@@ -0,0 +1,116 @@
+package crs
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// wktNode a WKT keyword with its arguments, eg: PARAMETER["False_Easting",700000.0]
+type wktNode struct {
+	keyword  string
+	values   []string
+	children []*wktNode
+}
+
+// child returns the first child named keyword
+func (n *wktNode) child(keyword string) *wktNode {
+	for _, c := range n.children {
+		if strings.EqualFold(c.keyword, keyword) {
+			return c
+		}
+	}
+	return nil
+}
+
+// name returns the first quoted value of the node
+func (n *wktNode) name() string {
+	if n == nil || len(n.values) == 0 {
+		return ""
+	}
+	return n.values[0]
+}
+
+// number returns the value at index i as a float
+func (n *wktNode) number(i int) (float64, error) {
+	if n == nil || len(n.values) <= i {
+		return 0, errors.New("missing WKT value")
+	}
+	return strconv.ParseFloat(n.values[i], 64)
+}
+
+// parseWKT parses an OGC or ESRI WKT coordinate reference system
+func parseWKT(s string) (*wktNode, error) {
+	p := &wktParser{s: s}
+	return p.node()
+}
+
+type wktParser struct {
+	s   string
+	pos int
+}
+
+func (p *wktParser) skipSpaces() {
+	for p.pos < len(p.s) && unicode.IsSpace(rune(p.s[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *wktParser) node() (*wktNode, error) {
+	p.skipSpaces()
+	start := p.pos
+	for p.pos < len(p.s) && (unicode.IsLetter(rune(p.s[p.pos])) || unicode.IsDigit(rune(p.s[p.pos])) || p.s[p.pos] == '_') {
+		p.pos++
+	}
+	n := &wktNode{keyword: p.s[start:p.pos]}
+	if n.keyword == "" {
+		return nil, fmt.Errorf("invalid WKT, expecting a keyword at %d", start)
+	}
+
+	p.skipSpaces()
+	if p.pos >= len(p.s) || (p.s[p.pos] != '[' && p.s[p.pos] != '(') {
+		// keyword without arguments eg: AXIS["X",EAST]
+		return n, nil
+	}
+	closing := byte(']')
+	if p.s[p.pos] == '(' {
+		closing = ')'
+	}
+	p.pos++
+
+	for {
+		p.skipSpaces()
+		if p.pos >= len(p.s) {
+			return nil, errors.New("invalid WKT, unexpected end")
+		}
+
+		switch c := p.s[p.pos]; {
+		case c == closing:
+			p.pos++
+			return n, nil
+		case c == ',':
+			p.pos++
+		case c == '"':
+			end := strings.IndexByte(p.s[p.pos+1:], '"')
+			if end < 0 {
+				return nil, errors.New("invalid WKT, unterminated string")
+			}
+			n.values = append(n.values, p.s[p.pos+1:p.pos+1+end])
+			p.pos += end + 2
+		case c == '-' || c == '+' || c == '.' || unicode.IsDigit(rune(c)):
+			start := p.pos
+			for p.pos < len(p.s) && strings.IndexByte("+-.eE0123456789", p.s[p.pos]) >= 0 {
+				p.pos++
+			}
+			n.values = append(n.values, p.s[start:p.pos])
+		default:
+			child, err := p.node()
+			if err != nil {
+				return nil, err
+			}
+			n.children = append(n.children, child)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package insideout
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/golang/geo/r1"
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+	"github.com/twpayne/go-geom/encoding/geojson"
+)
+
+// Encodings of the stored loops
+const (
+	// S2LoopEncoding the s2 loop encoding, the vertices as 3 float64
+	S2LoopEncoding = "s2"
+	// DeltaLoopEncoding the vertices lat and lng rounded to 1e-7 degrees (about 1cm),
+	// stored as varint deltas from the previous vertex, like TWKB
+	DeltaLoopEncoding = "delta"
+)
+
+// the delta loop encoding: version, origin inside, bound, vertices count as uvarint,
+// then lat and lng of each vertex in 1e-7 degrees, zigzag varint deltas from the previous vertex
+const (
+	// deltaLoopVersion can't be mistaken with the s2 encoding version 1
+	deltaLoopVersion = 'D'
+	deltaLoopScale   = 1e7
+	deltaHeaderSize  = 1 + 1 + 4*8
+)
+
+// LoopEncodingStore is implemented by the storages able to store the loops with another encoding
+type LoopEncodingStore interface {
+	SetLoopEncoding(encoding string) error
+}
+
+// ValidateLoopEncoding returns an error if encoding is unknown, empty is the s2 encoding
+func ValidateLoopEncoding(encoding string) error {
+	switch encoding {
+	case "", S2LoopEncoding, DeltaLoopEncoding:
+		return nil
+	}
+	return fmt.Errorf("unknown loop encoding %q, s2|delta", encoding)
+}
+
+// LoopEncoder encodes the loops of the features, embedded by the storages,
+// set before indexing, the s2 encoding when empty.
+// The loops are decoded whatever their encoding, see DecodeLoop.
+type LoopEncoder struct {
+	encoding string
+}
+
+// SetLoopEncoding sets the encoding
+func (e *LoopEncoder) SetLoopEncoding(encoding string) error {
+	if err := ValidateLoopEncoding(encoding); err != nil {
+		return err
+	}
+	// the default encoding is not recorded
+	if encoding == S2LoopEncoding {
+		encoding = ""
+	}
+	e.encoding = encoding
+	return nil
+}
+
+// LoopEncoding returns the encoding, empty for the s2 encoding
+func (e *LoopEncoder) LoopEncoding() string {
+	return e.encoding
+}
+
+// EncodeLoops encodes all MultiPolygons and Polygons as loops []byte with the encoding
+func (e *LoopEncoder) EncodeLoops(f *geojson.Feature) ([][]byte, error) {
+	return GeoJSONEncodeLoopsWith(f, e.encoding)
+}
+
+// EncodeHoles encodes the holes of all MultiPolygons and Polygons as loops []byte with the encoding
+func (e *LoopEncoder) EncodeHoles(f *geojson.Feature) ([][][]byte, error) {
+	return GeoJSONEncodeHolesWith(f, e.encoding)
+}
+
+// EncodeLoop encodes l with encoding, the s2 encoding is used for the loops altered by the delta encoding rounding:
+// too small to keep 3 vertices or changing orientation
+func EncodeLoop(l *s2.Loop, encoding string) ([]byte, error) {
+	if encoding == DeltaLoopEncoding {
+		if b := encodeDeltaLoop(l); b != nil {
+			return b, nil
+		}
+	}
+	var buf bytes.Buffer
+	if err := l.Encode(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeDeltaLoop returns the delta encoding of l, nil if the rounded loop differs from l
+func encodeDeltaLoop(l *s2.Loop) []byte {
+	n := l.NumVertices()
+	if n < 3 {
+		return nil
+	}
+
+	type e7 struct{ lat, lng int64 }
+	vs := make([]e7, 0, n)
+	for i := 0; i < n; i++ {
+		ll := s2.LatLngFromPoint(l.Vertex(i))
+		v := e7{
+			lat: int64(math.Round(ll.Lat.Degrees() * deltaLoopScale)),
+			lng: int64(math.Round(ll.Lng.Degrees() * deltaLoopScale)),
+		}
+		// vertices closer than the precision are merged
+		if len(vs) > 0 && vs[len(vs)-1] == v {
+			continue
+		}
+		vs = append(vs, v)
+	}
+	if len(vs) > 1 && vs[0] == vs[len(vs)-1] {
+		vs = vs[:len(vs)-1]
+	}
+	if len(vs) < 3 {
+		return nil
+	}
+
+	points := make([]s2.Point, len(vs))
+	for i, v := range vs {
+		points[i] = deltaPoint(v.lat, v.lng)
+	}
+	rl := s2.LoopFromPoints(points)
+	if rl.ContainsOrigin() != l.ContainsOrigin() {
+		return nil
+	}
+
+	b := make([]byte, deltaHeaderSize, deltaHeaderSize+binary.MaxVarintLen64+4*len(vs))
+	b[0] = deltaLoopVersion
+	if rl.ContainsOrigin() {
+		b[1] = 1
+	}
+	bound := rl.RectBound()
+	for i, f := range []float64{bound.Lat.Lo, bound.Lat.Hi, bound.Lng.Lo, bound.Lng.Hi} {
+		binary.LittleEndian.PutUint64(b[2+i*8:], math.Float64bits(f))
+	}
+
+	var tmp [binary.MaxVarintLen64]byte
+	b = append(b, tmp[:binary.PutUvarint(tmp[:], uint64(len(vs)))]...)
+	var prev e7
+	for _, v := range vs {
+		b = append(b, tmp[:binary.PutVarint(tmp[:], v.lat-prev.lat)]...)
+		b = append(b, tmp[:binary.PutVarint(tmp[:], v.lng-prev.lng)]...)
+		prev = v
+	}
+	return b
+}
+
+// DecodeLoop decodes a loop stored with any encoding
+func DecodeLoop(b []byte) (*s2.Loop, error) {
+	if len(b) > 0 && b[0] == deltaLoopVersion {
+		d, err := newDeltaLoop(b)
+		if err != nil {
+			return nil, err
+		}
+		points := make([]s2.Point, 0, d.n)
+		for i := 0; i < d.n; i++ {
+			p, err := d.next()
+			if err != nil {
+				return nil, err
+			}
+			points = append(points, p)
+		}
+		return s2.LoopFromPoints(points), nil
+	}
+
+	l := &s2.Loop{}
+	if err := l.Decode(bytes.NewReader(b)); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// LoopBytesContainsPoint reports whether the loop stored in b with any encoding contains p,
+// without decoding the loop nor allocating
+func LoopBytesContainsPoint(b []byte, p s2.Point) (bool, error) {
+	if len(b) == 0 || b[0] != deltaLoopVersion {
+		l, err := NewEncodedLoop(b)
+		if err != nil {
+			return false, err
+		}
+		return l.ContainsPoint(p), nil
+	}
+
+	d, err := newDeltaLoop(b)
+	if err != nil {
+		return false, err
+	}
+	if !d.bound.ContainsPoint(p) {
+		return false, nil
+	}
+	inside := d.originInside
+	v0, err := d.next()
+	if err != nil {
+		return false, err
+	}
+	crosser := s2.NewChainEdgeCrosser(s2.OriginPoint(), p, v0)
+	for i := 1; i < d.n; i++ {
+		v, err := d.next()
+		if err != nil {
+			return false, err
+		}
+		inside = inside != crosser.EdgeOrVertexChainCrossing(v)
+	}
+	// closes the loop
+	return inside != crosser.EdgeOrVertexChainCrossing(v0), nil
+}
+
+// deltaLoop reads the vertices of a delta encoded loop
+type deltaLoop struct {
+	b            []byte
+	off          int
+	n            int
+	originInside bool
+	bound        s2.Rect
+	lat, lng     int64
+}
+
+// newDeltaLoop reads the header of the delta encoded loop b
+func newDeltaLoop(b []byte) (deltaLoop, error) {
+	if len(b) < deltaHeaderSize || b[0] != deltaLoopVersion {
+		return deltaLoop{}, errors.New("invalid delta encoded loop")
+	}
+	f := func(i int) float64 {
+		return math.Float64frombits(binary.LittleEndian.Uint64(b[2+i*8:]))
+	}
+	d := deltaLoop{
+		b:            b,
+		originInside: b[1] != 0,
+		bound: s2.Rect{
+			Lat: r1.Interval{Lo: f(0), Hi: f(1)},
+			Lng: s1.Interval{Lo: f(2), Hi: f(3)},
+		},
+	}
+	n, size := binary.Uvarint(b[deltaHeaderSize:])
+	// a vertex takes 2 bytes at least
+	if size <= 0 || n < 3 || n > uint64(len(b)-deltaHeaderSize-size)/2 {
+		return deltaLoop{}, errors.New("invalid delta encoded loop vertices count")
+	}
+	d.n = int(n)
+	d.off = deltaHeaderSize + size
+	return d, nil
+}
+
+// next returns the next vertex
+func (d *deltaLoop) next() (s2.Point, error) {
+	dlat, size := binary.Varint(d.b[d.off:])
+	if size <= 0 {
+		return s2.Point{}, errors.New("invalid delta encoded loop vertex")
+	}
+	d.off += size
+	dlng, size := binary.Varint(d.b[d.off:])
+	if size <= 0 {
+		return s2.Point{}, errors.New("invalid delta encoded loop vertex")
+	}
+	d.off += size
+	d.lat += dlat
+	d.lng += dlng
+	return deltaPoint(d.lat, d.lng), nil
+}
+
+// deltaPoint returns the point at lat, lng in 1e-7 degrees
+func deltaPoint(lat, lng int64) s2.Point {
+	return s2.PointFromLatLng(s2.LatLngFromDegrees(float64(lat)/deltaLoopScale, float64(lng)/deltaLoopScale))
+}
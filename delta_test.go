@@ -0,0 +1,107 @@
+package insideout
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/require"
+	geom "github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/geojson"
+)
+
+func TestEncodeLoop_Delta(t *testing.T) {
+	center := s2.PointFromLatLng(s2.LatLngFromDegrees(48.8, 2.3))
+	hole := s2.RegularLoop(center, s1.Degree/2, 100)
+	hole.Invert()
+	loops := []*s2.Loop{
+		s2.RegularLoop(center, s1.Degree, 500),
+		rectLoop(2, 48, 3, 49),
+		hole,
+	}
+
+	r := rand.New(rand.NewSource(42))
+	for _, l := range loops {
+		b, err := EncodeLoop(l, DeltaLoopEncoding)
+		require.NoError(t, err)
+		require.Equal(t, byte(deltaLoopVersion), b[0])
+		require.Less(t, len(b), len(encodeLoop(t, l))/2)
+
+		dl, err := DecodeLoop(b)
+		require.NoError(t, err)
+		require.Equal(t, l.NumVertices(), dl.NumVertices())
+		for i := 0; i < l.NumVertices(); i++ {
+			require.InDelta(t, 0, l.Vertex(i).Distance(dl.Vertex(i)).Degrees(), 1e-7)
+		}
+		require.Equal(t, l.ContainsOrigin(), dl.ContainsOrigin())
+
+		for i := 0; i < 2000; i++ {
+			p := s2.PointFromLatLng(s2.LatLngFromDegrees(46+r.Float64()*6, r.Float64()*5))
+			inside, err := LoopBytesContainsPoint(b, p)
+			require.NoError(t, err)
+			require.Equal(t, dl.ContainsPoint(p), inside, "point %v", p)
+		}
+
+		_, err = DecodeLoop(b[:len(b)-1])
+		require.Error(t, err)
+		_, err = LoopBytesContainsPoint(b[:deltaHeaderSize], center)
+		require.Error(t, err)
+	}
+
+	// the s2 encoded loops are still read
+	b, err := EncodeLoop(loops[1], S2LoopEncoding)
+	require.NoError(t, err)
+	require.Equal(t, encodeLoop(t, loops[1]), b)
+	inside, err := LoopBytesContainsPoint(b, s2.PointFromLatLng(s2.LatLngFromDegrees(48.5, 2.5)))
+	require.NoError(t, err)
+	require.True(t, inside)
+
+	// a loop smaller than the precision keeps the s2 encoding
+	tiny := rectLoop(2, 48, 2+1e-8, 48+1e-8)
+	b, err = EncodeLoop(tiny, DeltaLoopEncoding)
+	require.NoError(t, err)
+	require.Equal(t, encodeLoop(t, tiny), b)
+}
+
+func TestLoopEncoder(t *testing.T) {
+	e := &LoopEncoder{}
+	require.Error(t, e.SetLoopEncoding("wkb"))
+	require.NoError(t, e.SetLoopEncoding(S2LoopEncoding))
+	require.Empty(t, e.LoopEncoding())
+	require.NoError(t, e.SetLoopEncoding(DeltaLoopEncoding))
+	require.Equal(t, DeltaLoopEncoding, e.LoopEncoding())
+
+	f := &geojson.Feature{Geometry: geom.NewMultiPolygonFlat(geom.XY,
+		[]float64{2, 48, 3, 48, 3, 49, 2, 49, 2, 48, 4, 48, 5, 48, 5, 49, 4, 48},
+		[][]int{{10}, {18}},
+	)}
+	lbs, err := e.EncodeLoops(f)
+	require.NoError(t, err)
+	require.Len(t, lbs, 2)
+	g, err := GeoJSONDecodeLoops(lbs)
+	require.NoError(t, err)
+	require.Equal(t, 2, g.(*geom.MultiPolygon).NumPolygons())
+
+	require.NoError(t, (&IndexInfos{}).CheckLoopEncoding(S2LoopEncoding))
+	require.Error(t, (&IndexInfos{}).CheckLoopEncoding(DeltaLoopEncoding))
+}
+
+// BenchmarkDeltaLoopContainsPoint the stored delta encoded loop tested in place
+func BenchmarkDeltaLoopContainsPoint(b *testing.B) {
+	center := s2.PointFromLatLng(s2.LatLngFromDegrees(48.8, 2.3))
+	lb, err := EncodeLoop(s2.RegularLoop(center, s1.Degree, 5000), DeltaLoopEncoding)
+	require.NoError(b, err)
+	p := s2.PointFromLatLng(s2.LatLngFromDegrees(48.9, 2.4))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		inside, err := LoopBytesContainsPoint(lb, p)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if !inside {
+			b.Fatal("not inside")
+		}
+	}
+}
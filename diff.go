@@ -0,0 +1,203 @@
+package insideout
+
+import (
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/golang/geo/s2"
+)
+
+// DiffReport the differences between two versions of a database
+type DiffReport struct {
+	Added     []DiffFeature   `json:"added"`
+	Removed   []DiffFeature   `json:"removed"`
+	Changed   []FeatureChange `json:"changed"`
+	Unchanged int             `json:"unchanged"`
+}
+
+// DiffFeature a feature only present in one of the databases
+type DiffFeature struct {
+	ID         uint32                 `json:"id"`
+	Key        string                 `json:"key"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
+// FeatureChange a feature present in both databases with different properties, geometry or cover
+type FeatureChange struct {
+	Key   string `json:"key"`
+	OldID uint32 `json:"old_id"`
+	NewID uint32 `json:"new_id"`
+
+	// Properties the names of the added, removed or modified properties
+	Properties []string `json:"properties,omitempty"`
+
+	Geometry     bool        `json:"geometry"`
+	InsideCells  CellsChange `json:"inside_cells"`
+	OutsideCells CellsChange `json:"outside_cells"`
+}
+
+// CellsChange the number of cells added to and removed from a cover
+type CellsChange struct {
+	Added   int `json:"added"`
+	Removed int `json:"removed"`
+}
+
+// Changed returns true when the cover is different
+func (c CellsChange) Changed() bool {
+	return c.Added > 0 || c.Removed > 0
+}
+
+// diffEntry a feature of the old database
+type diffEntry struct {
+	id         uint32
+	properties map[string]interface{}
+	geometry   uint64
+}
+
+// DiffStores compares the features of the databases old and new,
+// the features are matched by their idProperty value, by their feature id when idProperty is empty.
+// The properties in ignore, like the source file name, are not compared.
+func DiffStores(old, new Store, idProperty string, ignore ...string) (*DiffReport, error) {
+	key := func(fs *FeatureStorage, id uint32) string {
+		if idProperty == "" {
+			return strconv.FormatUint(uint64(id), 10)
+		}
+		if v, ok := fs.Properties[idProperty]; ok {
+			return fmt.Sprint(v)
+		}
+		// can't be matched
+		return "#" + strconv.FormatUint(uint64(id), 10)
+	}
+
+	olds := make(map[string]*diffEntry)
+	err := old.LoadAllFeatures(func(fs *FeatureStorage, id uint32) error {
+		olds[key(fs, id)] = &diffEntry{id: id, properties: copyProperties(fs.Properties), geometry: loopsHash(fs)}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can't load the old features: %w", err)
+	}
+
+	r := &DiffReport{}
+	err = new.LoadAllFeatures(func(fs *FeatureStorage, id uint32) error {
+		k := key(fs, id)
+		e, ok := olds[k]
+		if !ok {
+			r.Added = append(r.Added, DiffFeature{ID: id, Key: k, Properties: copyProperties(fs.Properties)})
+			return nil
+		}
+		delete(olds, k)
+
+		c := FeatureChange{
+			Key:        k,
+			OldID:      e.id,
+			NewID:      id,
+			Properties: changedProperties(e.properties, fs.Properties, ignore),
+			Geometry:   e.geometry != loopsHash(fs),
+		}
+		ocs, err := old.LoadCellStorage(e.id)
+		if err != nil {
+			return fmt.Errorf("can't load the old cells of feature %d: %w", e.id, err)
+		}
+		ncs, err := new.LoadCellStorage(id)
+		if err != nil {
+			return fmt.Errorf("can't load the new cells of feature %d: %w", id, err)
+		}
+		c.InsideCells = diffCells(ocs.CellsIn, ncs.CellsIn)
+		c.OutsideCells = diffCells(ocs.CellsOut, ncs.CellsOut)
+
+		if len(c.Properties) == 0 && !c.Geometry && !c.InsideCells.Changed() && !c.OutsideCells.Changed() {
+			r.Unchanged++
+			return nil
+		}
+		r.Changed = append(r.Changed, c)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can't load the new features: %w", err)
+	}
+
+	for k, e := range olds {
+		r.Removed = append(r.Removed, DiffFeature{ID: e.id, Key: k, Properties: e.properties})
+	}
+	sort.Slice(r.Removed, func(i, j int) bool { return r.Removed[i].ID < r.Removed[j].ID })
+
+	return r, nil
+}
+
+// copyProperties returns a copy of p, the storages reuse the properties maps
+func copyProperties(p map[string]interface{}) map[string]interface{} {
+	c := make(map[string]interface{}, len(p))
+	for k, v := range p {
+		c[k] = v
+	}
+	return c
+}
+
+// loopsHash returns a hash of the encoded loops of fs and of their holes
+func loopsHash(fs *FeatureStorage) uint64 {
+	h := fnv.New64a()
+	for _, lb := range fs.LoopsBytes {
+		h.Write([]byte(strconv.Itoa(len(lb))))
+		h.Write(lb)
+	}
+	for i, hbs := range fs.HolesBytes {
+		for _, hb := range hbs {
+			h.Write([]byte("h" + strconv.Itoa(i) + ":" + strconv.Itoa(len(hb))))
+			h.Write(hb)
+		}
+	}
+	return h.Sum64()
+}
+
+// changedProperties returns the sorted names of the properties different in old and new, except ignore
+func changedProperties(old, new map[string]interface{}, ignore []string) []string {
+	skip := make(map[string]bool, len(ignore))
+	for _, k := range ignore {
+		skip[k] = true
+	}
+
+	var names []string
+	for k, v := range old {
+		if nv, ok := new[k]; !skip[k] && (!ok || !reflect.DeepEqual(v, nv)) {
+			names = append(names, k)
+		}
+	}
+	for k := range new {
+		if _, ok := old[k]; !skip[k] && !ok {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// diffCells counts the cells of the loops covers new not in old and the ones of old not in new
+func diffCells(old, new []s2.CellUnion) CellsChange {
+	cells := func(cus []s2.CellUnion) map[s2.CellID]bool {
+		m := make(map[s2.CellID]bool)
+		for _, cu := range cus {
+			for _, c := range cu {
+				m[c] = true
+			}
+		}
+		return m
+	}
+	om, nm := cells(old), cells(new)
+
+	var c CellsChange
+	for id := range nm {
+		if !om[id] {
+			c.Added++
+		}
+	}
+	for id := range om {
+		if !nm[id] {
+			c.Removed++
+		}
+	}
+	return c
+}
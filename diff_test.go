@@ -0,0 +1,87 @@
+package insideout
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/require"
+)
+
+// featuresStore a Store of rectangles with their properties and covers
+type featuresStore struct {
+	Store
+	features []*FeatureStorage
+	cells    []*CellsStorage
+}
+
+func (s *featuresStore) add(l *s2.Loop, props map[string]interface{}) {
+	var buf bytes.Buffer
+	if err := l.Encode(&buf); err != nil {
+		panic(err)
+	}
+	s.features = append(s.features, &FeatureStorage{Properties: props, LoopsBytes: [][]byte{buf.Bytes()}})
+	coverer := &s2.RegionCoverer{MinLevel: 4, MaxLevel: 10, MaxCells: 8}
+	s.cells = append(s.cells, &CellsStorage{
+		CellsIn:  []s2.CellUnion{coverer.InteriorCovering(l)},
+		CellsOut: []s2.CellUnion{coverer.Covering(l)},
+	})
+}
+
+func (s *featuresStore) LoadAllFeatures(add func(*FeatureStorage, uint32) error) error {
+	for id, fs := range s.features {
+		if err := add(fs, uint32(id)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *featuresStore) LoadCellStorage(id uint32) (*CellsStorage, error) {
+	if int(id) >= len(s.cells) {
+		return nil, errors.New("not found")
+	}
+	return s.cells[id], nil
+}
+
+func TestDiffStores(t *testing.T) {
+	old := &featuresStore{}
+	old.add(rectLoop(0, 0, 1, 1), map[string]interface{}{"code": "A", "name": "a", "insided_source": "v1.geojson"})
+	old.add(rectLoop(2, 0, 3, 1), map[string]interface{}{"code": "B", "name": "b", "insided_source": "v1.geojson"})
+	old.add(rectLoop(4, 0, 5, 1), map[string]interface{}{"code": "C", "name": "c", "insided_source": "v1.geojson"})
+
+	new := &featuresStore{}
+	// unchanged, but another id and source
+	new.add(rectLoop(0, 0, 1, 1), map[string]interface{}{"code": "A", "name": "a", "insided_source": "v2.geojson"})
+	// renamed and moved
+	new.add(rectLoop(2, 0, 3.5, 1), map[string]interface{}{"code": "B", "name": "bb", "pop": float64(3)})
+	new.add(rectLoop(6, 0, 7, 1), map[string]interface{}{"code": "D", "name": "d"})
+
+	r, err := DiffStores(old, new, "code", "insided_source")
+	require.NoError(t, err)
+	require.Equal(t, 1, r.Unchanged)
+	require.Len(t, r.Added, 1)
+	require.Equal(t, "D", r.Added[0].Key)
+	require.Equal(t, uint32(2), r.Added[0].ID)
+	require.Len(t, r.Removed, 1)
+	require.Equal(t, "C", r.Removed[0].Key)
+	require.Equal(t, "c", r.Removed[0].Properties["name"])
+
+	require.Len(t, r.Changed, 1)
+	c := r.Changed[0]
+	require.Equal(t, "B", c.Key)
+	require.Equal(t, []string{"name", "pop"}, c.Properties)
+	require.True(t, c.Geometry)
+	require.True(t, c.OutsideCells.Changed())
+
+	// matched by feature id
+	r, err = DiffStores(old, new, "")
+	require.NoError(t, err)
+	require.Empty(t, r.Added)
+	require.Empty(t, r.Removed)
+	require.Len(t, r.Changed, 3)
+	require.Equal(t, []string{"insided_source"}, r.Changed[0].Properties)
+	require.False(t, r.Changed[0].Geometry)
+	require.False(t, r.Changed[0].InsideCells.Changed())
+}
@@ -0,0 +1,11 @@
+package embedded
+
+// The storage backends compiled in, selected by the scheme of the path or by Options.StorageBackend,
+// a third party backend is compiled in by importing its package in a file of the application.
+import (
+	_ "github.com/akhenakh/insideout/storage/badger"
+	_ "github.com/akhenakh/insideout/storage/bbolt"
+	_ "github.com/akhenakh/insideout/storage/flat"
+	_ "github.com/akhenakh/insideout/storage/leveldb"
+	_ "github.com/akhenakh/insideout/storage/sqlite"
+)
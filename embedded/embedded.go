@@ -0,0 +1,98 @@
+// Package embedded queries an insideout database in process, without running insided:
+// it opens the storage and serves it with the same strategies and code as the server.
+//
+// It is not part of the insideout package since the storages and the strategies import it.
+package embedded
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/client"
+	"github.com/akhenakh/insideout/server"
+	"github.com/akhenakh/insideout/storage"
+)
+
+// Options the options of an embedded database, the zero value opens a bbolt database with the insidetree strategy
+type Options struct {
+	// StorageBackend bbolt, leveldb, badger or flat, bbolt when empty
+	StorageBackend string
+
+	// Server the options of the strategy serving the database, insidetree when Server.Strategy is empty
+	Server server.Options
+
+	// Logger the logger of the storage and the server, none when nil
+	Logger log.Logger
+}
+
+// DB an embedded database, safe for concurrent use
+type DB struct {
+	storage insideout.Store
+	clean   func() error
+	s       *server.Server
+}
+
+// Open opens the database at dbPath read only and loads its strategy
+func Open(dbPath string, opts Options) (*DB, error) {
+	logger := opts.Logger
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+	sopts := opts.Server
+	if sopts.Strategy == "" {
+		sopts.Strategy = insideout.InsideTreeStrategy
+	}
+
+	storage, clean, err := openStorage(dbPath, opts.StorageBackend, logger)
+	if err != nil {
+		return nil, fmt.Errorf("can't open storage %s: %w", dbPath, err)
+	}
+
+	s, err := server.New(storage, logger, nil, sopts)
+	if err != nil {
+		clean()
+		return nil, err
+	}
+
+	return &DB{storage: storage, clean: clean, s: s}, nil
+}
+
+// openStorage opens the database at path read only with backend
+func openStorage(path, backend string, logger log.Logger) (insideout.Store, func() error, error) {
+	if backend == "" {
+		backend = insideout.BBoltBackend
+	}
+	return storage.Open(path, backend, storage.ReadOnly, logger)
+}
+
+// Close closes the storage, the DB can't be queried afterward
+func (db *DB) Close() error {
+	return db.clean()
+}
+
+// Within returns the features containing the point at lat lng, with all their properties and without their geometries
+func (db *DB) Within(lat, lng float64) ([]*client.Feature, error) {
+	return db.WithinContext(context.Background(), lat, lng, nil)
+}
+
+// WithinContext returns the features containing the point at lat lng, opts can be nil
+func (db *DB) WithinContext(ctx context.Context, lat, lng float64, opts *client.WithinOptions) ([]*client.Feature, error) {
+	resp, err := db.s.Within(ctx, client.NewWithinRequest(lat, lng, opts))
+	if err != nil {
+		return nil, err
+	}
+	return client.FeaturesFromResponses(resp.Responses)
+}
+
+// IndexInfos returns the infos of the index
+func (db *DB) IndexInfos() (*insideout.IndexInfos, error) {
+	return db.storage.LoadIndexInfos()
+}
+
+// Server returns the server answering the queries, for the other queries of the gRPC API
+func (db *DB) Server() *server.Server {
+	return db.s
+}
@@ -0,0 +1,77 @@
+package embedded
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/geojson"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/client"
+	"github.com/akhenakh/insideout/server"
+	"github.com/akhenakh/insideout/storage/bbolt"
+)
+
+// setup returns the path of a bbolt database holding a square of 1 degree named A at 0,0
+func setup(t *testing.T) string {
+	tmpFile, err := ioutil.TempFile(os.TempDir(), "insideout-test-")
+	require.NoError(t, err)
+	tmpFile.Close()
+
+	storage, clean, err := bbolt.NewStorage(tmpFile.Name(), log.NewNopLogger())
+	require.NoError(t, err)
+	fc := geojson.FeatureCollection{Features: []*geojson.Feature{{
+		Geometry:   geom.NewPolygonFlat(geom.XY, []float64{0, 0, 1, 0, 1, 1, 0, 1, 0, 0}, []int{10}),
+		Properties: map[string]interface{}{"name": "A"},
+	}}}
+	icoverer := &s2.RegionCoverer{MinLevel: 5, MaxLevel: 12, MaxCells: 24}
+	ocoverer := &s2.RegionCoverer{MinLevel: 5, MaxLevel: 12, MaxCells: 16}
+	require.NoError(t, storage.Index(fc, icoverer, ocoverer, 100, "A", "unittest"))
+	require.NoError(t, clean())
+	return tmpFile.Name()
+}
+
+func TestOpen(t *testing.T) {
+	path := setup(t)
+	defer os.Remove(path)
+
+	for _, strategy := range []string{
+		"", insideout.DBStrategy, insideout.ShapeIndexStrategy, insideout.MemoryStrategy, insideout.HybridStrategy,
+	} {
+		t.Run(strategy, func(t *testing.T) {
+			db, err := Open(path, Options{Server: server.Options{Strategy: strategy}})
+			require.NoError(t, err)
+			defer db.Close()
+
+			fs, err := db.Within(0.5, 0.5)
+			require.NoError(t, err)
+			require.Len(t, fs, 1)
+			require.Equal(t, map[string]interface{}{"name": "A"}, fs[0].Properties)
+			require.Nil(t, fs[0].Geometry)
+
+			fs, err = db.Within(2, 2)
+			require.NoError(t, err)
+			require.Empty(t, fs)
+
+			fs, err = db.WithinContext(context.Background(), 0.5, 0.5, &client.WithinOptions{Geometries: true})
+			require.NoError(t, err)
+			require.Len(t, fs, 1)
+			require.IsType(t, &geom.Polygon{}, fs[0].Geometry)
+
+			infos, err := db.IndexInfos()
+			require.NoError(t, err)
+			require.Equal(t, uint32(1), infos.FeatureCount)
+		})
+	}
+
+	_, err := Open(path, Options{StorageBackend: "nope"})
+	require.Error(t, err)
+	_, err = Open(path, Options{Server: server.Options{Strategy: "nope"}})
+	require.Error(t, err)
+}
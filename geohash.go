@@ -0,0 +1,85 @@
+package insideout
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/golang/geo/r1"
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+)
+
+const (
+	geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+	// MaxGeohashPrecision the longest geohash, 60 bits
+	MaxGeohashPrecision = 12
+)
+
+// DecodeGeohash returns the rectangle of the geohash cell hash
+func DecodeGeohash(hash string) (s2.Rect, error) {
+	if hash == "" || len(hash) > MaxGeohashPrecision {
+		return s2.EmptyRect(), fmt.Errorf("invalid geohash length %d", len(hash))
+	}
+
+	lat, lng := r1.Interval{Lo: -90, Hi: 90}, r1.Interval{Lo: -180, Hi: 180}
+	// the bits alternate between longitude and latitude, starting with longitude
+	even := true
+	for _, c := range strings.ToLower(hash) {
+		v := strings.IndexRune(geohashAlphabet, c)
+		if v < 0 {
+			return s2.EmptyRect(), fmt.Errorf("invalid geohash character %q", c)
+		}
+		for bit := 4; bit >= 0; bit-- {
+			in := &lat
+			if even {
+				in = &lng
+			}
+			mid := in.Center()
+			if v&(1<<uint(bit)) != 0 {
+				in.Lo = mid
+			} else {
+				in.Hi = mid
+			}
+			even = !even
+		}
+	}
+
+	return s2.Rect{
+		Lat: r1.Interval{Lo: lat.Lo * s1.Degree.Radians(), Hi: lat.Hi * s1.Degree.Radians()},
+		Lng: s1.Interval{Lo: lng.Lo * s1.Degree.Radians(), Hi: lng.Hi * s1.Degree.Radians()},
+	}, nil
+}
+
+// EncodeGeohash returns the geohash of lat lng with precision characters, 1 to MaxGeohashPrecision
+func EncodeGeohash(lat, lng float64, precision int) string {
+	if precision < 1 {
+		precision = 1
+	}
+	if precision > MaxGeohashPrecision {
+		precision = MaxGeohashPrecision
+	}
+
+	latIn, lngIn := r1.Interval{Lo: -90, Hi: 90}, r1.Interval{Lo: -180, Hi: 180}
+	even := true
+	hash := make([]byte, precision)
+	for i := range hash {
+		var v int
+		for bit := 4; bit >= 0; bit-- {
+			in, x := &latIn, lat
+			if even {
+				in, x = &lngIn, lng
+			}
+			mid := in.Center()
+			if x >= mid {
+				v |= 1 << uint(bit)
+				in.Lo = mid
+			} else {
+				in.Hi = mid
+			}
+			even = !even
+		}
+		hash[i] = geohashAlphabet[v]
+	}
+	return string(hash)
+}
@@ -0,0 +1,27 @@
+package insideout
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeohash(t *testing.T) {
+	require.Equal(t, "u4pruydqqvj", EncodeGeohash(57.64911, 10.40744, 11))
+	require.Equal(t, "u09t", EncodeGeohash(48.8566, 2.3522, 4))
+
+	r, err := DecodeGeohash("u09tvw")
+	require.NoError(t, err)
+	require.InDelta(t, 48.856, r.Center().Lat.Degrees(), 0.003)
+	require.InDelta(t, 2.352, r.Center().Lng.Degrees(), 0.006)
+	require.Equal(t, "u09tvw", EncodeGeohash(r.Center().Lat.Degrees(), r.Center().Lng.Degrees(), 6))
+
+	r2, err := DecodeGeohash("U09TVW")
+	require.NoError(t, err)
+	require.Equal(t, r, r2)
+
+	for _, invalid := range []string{"", "u09a", "u09tvw0f6szyz"} {
+		_, err := DecodeGeohash(invalid)
+		require.Error(t, err, invalid)
+	}
+}
@@ -3,32 +3,58 @@ module github.com/akhenakh/insideout
 go 1.13
 
 require (
+	github.com/BurntSushi/toml v0.3.1
 	github.com/akhenakh/insidetree v0.0.0-20200117162430-1aba251a8a6a
+	github.com/alicebob/miniredis/v2 v2.11.0
+	github.com/andybalholm/brotli v1.0.1
+	github.com/dgraph-io/badger v1.6.1
 	github.com/dgraph-io/ristretto v0.0.2
+	github.com/eclipse/paho.mqtt.golang v1.2.0
+	github.com/fsnotify/fsnotify v1.4.9
 	github.com/fxamacker/cbor v1.5.0
 	github.com/go-kit/kit v0.9.0
 	github.com/go-logfmt/logfmt v0.5.0 // indirect
-	github.com/gogo/protobuf v1.2.1
+	github.com/go-redis/redis v6.15.9+incompatible
+	github.com/gogo/protobuf v1.3.1
 	github.com/golang/geo v0.0.0-20190916061304-5b978397cfec
-	github.com/golang/protobuf v1.3.2
-	github.com/google/go-cmp v0.4.0
+	github.com/golang/protobuf v1.4.2
+	github.com/golang/snappy v0.0.1
+	github.com/google/flatbuffers v1.12.0
+	github.com/google/go-cmp v0.5.2
 	github.com/gorilla/handlers v1.4.2
 	github.com/gorilla/mux v1.7.3
+	github.com/gorilla/websocket v1.4.2
 	github.com/grpc-ecosystem/go-grpc-middleware v1.1.0
 	github.com/grpc-ecosystem/go-grpc-prometheus v1.2.0
+	github.com/jonas-p/go-shp v0.1.1
+	github.com/klauspost/compress v1.9.8
+	github.com/lib/pq v1.3.0
+	github.com/linkedin/goavro/v2 v2.9.8
 	github.com/mattn/go-sqlite3 v2.0.3+incompatible
 	github.com/namsral/flag v1.7.4-pre
-	github.com/opentracing/opentracing-go v1.1.0
+	github.com/nats-io/nats-server/v2 v2.1.4
+	github.com/nats-io/nats.go v1.9.2
 	github.com/pkg/errors v0.9.1
 	github.com/prometheus/client_golang v1.4.0
+	github.com/prometheus/client_model v0.2.0
 	github.com/rcrowley/go-metrics v0.0.0-20190826022208-cac0b30c2563
+	github.com/segmentio/kafka-go v0.4.8
 	github.com/slok/go-http-metrics v0.6.1
-	github.com/spaolacci/murmur3 v1.1.0 // indirect
-	github.com/stretchr/testify v1.4.0
+	github.com/stretchr/testify v1.6.1
+	github.com/syndtr/goleveldb v1.0.0
 	github.com/twpayne/go-geom v1.0.5
+	github.com/uber/h3-go/v3 v3.7.1
+	github.com/vmihailenco/msgpack/v4 v4.3.12
 	go.etcd.io/bbolt v1.3.3
-	golang.org/x/net v0.0.0-20190620200207-3b0461eec859
+	go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc v0.13.0
+	go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp v0.13.0
+	go.opentelemetry.io/otel v0.13.0
+	go.opentelemetry.io/otel/exporters/otlp v0.13.0
+	go.opentelemetry.io/otel/sdk v0.13.0
+	golang.org/x/net v0.0.0-20200301022130-244492dfa37a
 	golang.org/x/sync v0.0.0-20190911185100-cd5d95a43a6e
-	google.golang.org/grpc v1.27.0
+	golang.org/x/time v0.0.0-20191024005414-555d28b269f0
+	google.golang.org/grpc v1.32.0
 	gopkg.in/yaml.v2 v2.2.7 // indirect
+	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c
 )
@@ -0,0 +1,20 @@
+package insideout
+
+// H3Cover the H3 cells covering the polygons of a database at one resolution, see the h3index package
+type H3Cover struct {
+	Resolution int
+
+	// Inside the polygons containing each cell, the cells are compacted to coarser resolutions
+	Inside map[uint64][]FeatureIndexResponse
+
+	// MayBeInside the polygons crossing each cell, at Resolution
+	MayBeInside map[uint64][]FeatureIndexResponse
+}
+
+// H3Store is implemented by the storages persisting an H3 cover of their polygons
+type H3Store interface {
+	// StoreH3Cover stores the H3 cover of the polygons
+	StoreH3Cover(cover *H3Cover) error
+	// LoadH3Cover returns the H3 cover of the polygons, nil when it was never stored
+	LoadH3Cover() (*H3Cover, error)
+}
@@ -0,0 +1,198 @@
+package insideout
+
+import (
+	"fmt"
+
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+)
+
+const (
+	// interiorOffset the angle in radians an interior point is offset from the edge of a loop, about 6mm
+	interiorOffset = 1e-9
+
+	// touchTolerance edges crossing closer than this to one of their vertices are touching, about 6cm
+	touchTolerance = s1.Angle(1e-8)
+)
+
+// HierarchyStore is implemented by the storages persisting the containment hierarchy of their features
+type HierarchyStore interface {
+	// StoreHierarchy stores the parent id of each contained feature, see ComputeHierarchy
+	StoreHierarchy(parents map[uint32]uint32) error
+	// LoadHierarchy returns the parent id of each contained feature, nil when it was never stored
+	LoadHierarchy() (map[uint32]uint32, error)
+}
+
+// ownedLoop a loop of the features[owner] indexed in the shape index
+type ownedLoop struct {
+	*s2.Loop
+	owner int
+}
+
+// hierarchyFeature the loops of a feature and its area
+type hierarchyFeature struct {
+	id    uint32
+	loops []ownedLoop
+	area  float64
+}
+
+// ComputeHierarchy returns the parent id of each feature of s contained by another feature:
+// the smallest feature containing all its polygons, a feature without parent is a root.
+// The polygons may share edges with their parent. The features with the same geometry
+// are chained by ascending id. All the polygons are loaded in memory.
+func ComputeHierarchy(s Store) (map[uint32]uint32, error) {
+	var features []*hierarchyFeature
+	index := s2.NewShapeIndex()
+
+	err := s.LoadAllFeatures(func(fs *FeatureStorage, id uint32) error {
+		hf := &hierarchyFeature{id: id, loops: make([]ownedLoop, len(fs.LoopsBytes))}
+		for i, b := range fs.LoopsBytes {
+			l, err := DecodeLoop(b)
+			if err != nil {
+				return fmt.Errorf("can't decode loop %d of feature %d: %w", i, id, err)
+			}
+			hf.loops[i] = ownedLoop{Loop: l, owner: len(features)}
+			hf.area += l.Area()
+			index.Add(hf.loops[i])
+		}
+		features = append(features, hf)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pq := s2.NewContainsPointQuery(index, s2.VertexModelSemiOpen)
+	cq := s2.NewCrossingEdgeQuery(index)
+	parents := make(map[uint32]uint32)
+	for _, f := range features {
+		if len(f.loops) == 0 {
+			continue
+		}
+
+		// the candidates contain a point of the first polygon and are larger
+		p, ok := interiorPoint(f.loops[0].Loop)
+		if !ok {
+			continue
+		}
+		var candidates []*hierarchyFeature
+		for _, shape := range pq.ContainingShapes(p) {
+			c := features[shape.(ownedLoop).owner]
+			if c != f && before(c, f) {
+				candidates = append(candidates, c)
+			}
+		}
+		if len(candidates) == 0 {
+			continue
+		}
+
+		polygons, ok := newContainedPolygons(cq, f)
+		if !ok {
+			continue
+		}
+
+		var parent *hierarchyFeature
+		for _, c := range candidates {
+			// keeps the smallest one
+			if (parent == nil || before(parent, c)) && polygons.insideOf(c) {
+				parent = c
+			}
+		}
+		if parent != nil {
+			parents[f.id] = parent.id
+		}
+	}
+
+	return parents, nil
+}
+
+// before returns true if a can be the parent of b: a is larger, or as large with a lower id
+func before(a, b *hierarchyFeature) bool {
+	if a.area != b.area {
+		return a.area > b.area
+	}
+	return a.id < b.id
+}
+
+// containedPolygon a polygon tested for containment, a point inside it and the loops its edges cross
+type containedPolygon struct {
+	interior s2.Point
+	crossed  map[s2.Shape]bool
+}
+
+type containedPolygons []containedPolygon
+
+// newContainedPolygons returns the polygons of f, false if a point inside one of them can't be found
+func newContainedPolygons(cq *s2.CrossingEdgeQuery, f *hierarchyFeature) (containedPolygons, bool) {
+	polygons := make(containedPolygons, len(f.loops))
+	for i, l := range f.loops {
+		p, ok := interiorPoint(l.Loop)
+		if !ok {
+			return nil, false
+		}
+		polygons[i] = containedPolygon{interior: p, crossed: make(map[s2.Shape]bool)}
+		for j := 0; j < l.NumEdges(); j++ {
+			e := l.Edge(j)
+			for shape, edges := range cq.CrossingsEdgeMap(e.V0, e.V1, s2.CrossingTypeInterior) {
+				for _, id := range edges {
+					if !touching(e, shape.Edge(id)) {
+						polygons[i].crossed[shape] = true
+						break
+					}
+				}
+			}
+		}
+	}
+	return polygons, true
+}
+
+// touching returns true if a and b cross next to one of their vertices, like an edge starting on another one
+func touching(a, b s2.Edge) bool {
+	x := s2.Intersection(a.V0, a.V1, b.V0, b.V1)
+	for _, v := range []s2.Point{a.V0, a.V1, b.V0, b.V1} {
+		if x.Distance(v) < touchTolerance {
+			return true
+		}
+	}
+	return false
+}
+
+// insideOf returns true if every polygon is inside a polygon of a: a polygon of a contains its interior point
+// and none of its edges crosses it
+func (polygons containedPolygons) insideOf(a *hierarchyFeature) bool {
+	for _, p := range polygons {
+		found := false
+		for _, al := range a.loops {
+			if !p.crossed[al] && al.ContainsPoint(p.interior) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// interiorPoint returns a point inside l next to the middle of one of its edges,
+// so it is inside the loops sharing this edge and containing l
+func interiorPoint(l *s2.Loop) (s2.Point, bool) {
+	if l.IsEmpty() || l.IsFull() {
+		return s2.Point{}, false
+	}
+	for i := 0; i < l.NumEdges(); i++ {
+		e := l.Edge(i)
+		mid := e.V0.Add(e.V1.Vector)
+		// the interior of a counter clockwise loop is on the left of its edges
+		left := e.V0.Cross(e.V1.Vector)
+		if mid.Norm() == 0 || left.Norm() == 0 {
+			continue
+		}
+		p := s2.Point{Vector: mid.Normalize().Add(left.Normalize().Mul(interiorOffset)).Normalize()}
+		if l.ContainsPoint(p) {
+			return p, true
+		}
+	}
+	return s2.Point{}, false
+}
@@ -0,0 +1,72 @@
+package insideout
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/require"
+)
+
+// loopsStore a Store loading features made of rectangles
+type loopsStore struct {
+	Store
+	features [][]*s2.Loop
+}
+
+func (s *loopsStore) LoadAllFeatures(add func(*FeatureStorage, uint32) error) error {
+	for id, loops := range s.features {
+		fs := &FeatureStorage{}
+		for _, l := range loops {
+			var buf bytes.Buffer
+			if err := l.Encode(&buf); err != nil {
+				return err
+			}
+			fs.LoopsBytes = append(fs.LoopsBytes, buf.Bytes())
+		}
+		if err := add(fs, uint32(id)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func rectLoop(minLng, minLat, maxLng, maxLat float64) *s2.Loop {
+	return s2.LoopFromPoints([]s2.Point{
+		s2.PointFromLatLng(s2.LatLngFromDegrees(minLat, minLng)),
+		s2.PointFromLatLng(s2.LatLngFromDegrees(minLat, maxLng)),
+		s2.PointFromLatLng(s2.LatLngFromDegrees(maxLat, maxLng)),
+		s2.PointFromLatLng(s2.LatLngFromDegrees(maxLat, minLng)),
+	})
+}
+
+func TestComputeHierarchy(t *testing.T) {
+	s := &loopsStore{features: [][]*s2.Loop{
+		// 0 a city of the west state
+		{rectLoop(0.1, 0.1, 0.2, 0.2)},
+		// 1 the west state, sharing its edges with the country
+		{rectLoop(0, 0, 0.5, 1)},
+		// 2 the east state
+		{rectLoop(0.5, 0, 1, 1)},
+		// 3 the country
+		{rectLoop(0, 0, 1, 1)},
+		// 4 a district with the same geometry as the city
+		{rectLoop(0.1, 0.1, 0.2, 0.2)},
+		// 5 a multi polygon over both states
+		{rectLoop(0.3, 0.3, 0.4, 0.4), rectLoop(0.6, 0.6, 0.7, 0.7)},
+		// 6 elsewhere
+		{rectLoop(5, 5, 6, 6)},
+		// 7 overlapping the country border
+		{rectLoop(0.9, 0.9, 1.1, 1.1)},
+	}}
+
+	parents, err := ComputeHierarchy(s)
+	require.NoError(t, err)
+	require.Equal(t, map[uint32]uint32{
+		0: 1,
+		1: 3,
+		2: 3,
+		4: 0,
+		5: 3,
+	}, parents)
+}
@@ -1,6 +1,9 @@
 package insideout
 
 import (
+	"context"
+
+	"github.com/golang/geo/s1"
 	"github.com/golang/geo/s2"
 )
 
@@ -10,6 +13,27 @@ type Index interface {
 	Stab(lat, lng float64) (IndexResponse, error)
 }
 
+// ContextIndex is implemented by the indexes reading the storage or building their index by query,
+// the stab stops once ctx is done and returns ctx.Err()
+type ContextIndex interface {
+	StabContext(ctx context.Context, lat, lng float64) (IndexResponse, error)
+}
+
+// noStopKey the context key of WithoutStop
+type noStopKey struct{}
+
+// WithoutStop returns ctx asking the indexes supporting StopOnInsideFound to return all their candidates,
+// for the queries filtering the features found, a feature filtered out must not end the search
+func WithoutStop(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noStopKey{}, true)
+}
+
+// StopAllowed returns false when ctx was returned by WithoutStop
+func StopAllowed(ctx context.Context) bool {
+	noStop, _ := ctx.Value(noStopKey{}).(bool)
+	return !noStop
+}
+
 // IndexResponse a response to find back a feature from an index
 type IndexResponse struct {
 	IDsInside      []FeatureIndexResponse
@@ -28,4 +52,47 @@ type FeatureIndexResponse struct {
 type Feature struct {
 	Loops      []*s2.Loop
 	Properties map[string]interface{}
+
+	// Holes the holes of each loop, normalized to contain the area they remove, nil for a feature without holes
+	Holes [][]*s2.Loop
+}
+
+// PolygonContainsPoint returns true if the loop pos of f contains p outside of its holes
+func (f *Feature) PolygonContainsPoint(pos uint16, p s2.Point) bool {
+	if !f.Loops[pos].ContainsPoint(p) {
+		return false
+	}
+	if int(pos) >= len(f.Holes) {
+		return true
+	}
+	for _, h := range f.Holes[pos] {
+		if h.ContainsPoint(p) {
+			return false
+		}
+	}
+	return true
+}
+
+// PolygonArea returns the area of the loop pos of f without its holes, in steradians
+func (f *Feature) PolygonArea(pos uint16) float64 {
+	a := f.Loops[pos].Area()
+	if int(pos) < len(f.Holes) {
+		for _, h := range f.Holes[pos] {
+			a -= h.Area()
+		}
+	}
+	return a
+}
+
+// DistanceToBoundary returns the distance from p to the closest edge of the loop pos of f or of its holes
+func (f *Feature) DistanceToBoundary(pos uint16, p s2.Point) s1.Angle {
+	d := DistanceToLoop(p, f.Loops[pos])
+	if int(pos) < len(f.Holes) {
+		for _, h := range f.Holes[pos] {
+			if hd := DistanceToLoop(p, h); hd < d {
+				d = hd
+			}
+		}
+	}
+	return d
 }
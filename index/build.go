@@ -0,0 +1,311 @@
+// Package index builds an index in process, the pipeline of the indexer command for the services
+// indexing their own data sources: the features read are repaired, reoriented, checked and simplified,
+// indexed into the storage, then the hierarchy, the cell filter, the property index and the H3 cover are stored.
+//
+// The strategies querying the indexes are in the subpackages.
+package index
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/golang/geo/s2"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/index/h3index"
+)
+
+// DefaultWarningCellsCover the cover count above which a feature is logged and not stored in the cells index
+const DefaultWarningCellsCover = 1000
+
+// Options the options of Build, the zero value indexes the features as read with the default covers
+type Options struct {
+	// InsideCoverer and OutsideCoverer the covers of the features,
+	// levels 10 to 16 with 24 cells and 10 to 15 with 16 cells when nil
+	InsideCoverer  *s2.RegionCoverer
+	OutsideCoverer *s2.RegionCoverer
+
+	// WarningCellsCover see DefaultWarningCellsCover, used when 0
+	WarningCellsCover int
+
+	// Workers the goroutines covering the features, for the storages implementing insideout.ParallelStore, 0 for one
+	Workers int
+
+	// AutoCover tunes the cover levels of each feature to its extent, see insideout.AutoCover
+	AutoCover bool
+
+	// Compression the codec compressing the stored features, snappy or zstd, empty for none
+	Compression string
+
+	// LoopEncoding the encoding of the stored loops, insideout.S2LoopEncoding when empty
+	LoopEncoding string
+
+	// Append adds the features to the existing index, the ones with the IDProperty value of a stored feature replace it
+	Append bool
+
+	// Resume continues an interrupted build from its checkpoint, with the same source and covers
+	Resume bool
+
+	// IDProperty the property holding the unique id of each feature, a feature without it or with a duplicate fails
+	// the build, stored in the property index, empty to disable
+	IDProperty string
+
+	// Repair the geometries before indexing, snapping them to RepairPrecision degrees
+	Repair          bool
+	RepairPrecision float64
+
+	// Orient reverses the rings with a wrong orientation, without the other repairs
+	Orient bool
+
+	// SimplifyToleranceMeters simplifies the geometries, 0 to disable,
+	// the original vertex count of each feature is set to VertexCountProperty when not empty
+	SimplifyToleranceMeters float64
+	VertexCountProperty     string
+
+	// Hierarchy computes and stores the containment hierarchy of the features
+	Hierarchy bool
+
+	// CellFilterRate the false positive rate of the stored cell filter, 0 to disable
+	CellFilterRate float64
+
+	// IndexProps the properties indexed by value, with IDProperty
+	IndexProps []string
+
+	// H3Cover stores an H3 cover of the polygons at H3Resolution for the h3 strategy, requires cgo
+	H3Cover      bool
+	H3Resolution int
+
+	// FileName and Version recorded in the index infos
+	FileName string
+	Version  string
+
+	// Progress counts the features and cells indexed, for the storages implementing insideout.ProgressStore
+	Progress *insideout.Progress
+
+	// Logger none when nil
+	Logger log.Logger
+}
+
+// Build indexes the features read from source into dest, and stores the extra indexes of opts.
+// The features are not read once ctx is done, the storages are not rolled back.
+func Build(ctx context.Context, source insideout.FeatureReader, dest insideout.Store, opts Options) error {
+	logger := opts.Logger
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+
+	icoverer, ocoverer := opts.InsideCoverer, opts.OutsideCoverer
+	if icoverer == nil {
+		icoverer = &s2.RegionCoverer{MinLevel: 10, MaxLevel: 16, MaxCells: 24}
+	}
+	if ocoverer == nil {
+		ocoverer = &s2.RegionCoverer{MinLevel: 10, MaxLevel: 15, MaxCells: 16}
+	}
+	if err := insideout.NewCoverOptions(icoverer).Validate(); err != nil {
+		return fmt.Errorf("invalid inside cover: %w", err)
+	}
+	if err := insideout.NewCoverOptions(ocoverer).Validate(); err != nil {
+		return fmt.Errorf("invalid outside cover: %w", err)
+	}
+	warningCellsCover := opts.WarningCellsCover
+	if warningCellsCover == 0 {
+		warningCellsCover = DefaultWarningCellsCover
+	}
+
+	if err := configure(dest, opts, logger); err != nil {
+		return err
+	}
+
+	// the extra indexes supported by dest, checked before indexing
+	hs, ok := dest.(insideout.HierarchyStore)
+	if opts.Hierarchy && !ok {
+		return errors.New("hierarchy not supported by the storage")
+	}
+	cfs, ok := dest.(insideout.CellFilterStore)
+	if opts.CellFilterRate > 0 && !ok {
+		return errors.New("cell filter not supported by the storage")
+	}
+	pis, ok := dest.(insideout.PropertyIndexStore)
+	if len(opts.IndexProps) > 0 && !ok {
+		return errors.New("property index not supported by the storage")
+	}
+	if opts.IDProperty != "" && !ok {
+		level.Warn(logger).Log("msg", "property index not supported by the storage, the features ids are not indexed")
+	}
+	h3s, ok := dest.(insideout.H3Store)
+	if opts.H3Cover && !ok {
+		return errors.New("H3 cover not supported by the storage")
+	}
+	rs, ok := dest.(insideout.ResumableStore)
+	if opts.Resume && !ok {
+		return errors.New("resume not supported by the storage")
+	}
+
+	r := insideout.FeatureReader(&contextReader{FeatureReader: source, ctx: ctx})
+	var rr *RepairReader
+	if opts.Repair {
+		rr = NewRepairReader(r, opts.RepairPrecision, logger)
+		r = rr
+	}
+	var or *OrientReader
+	if opts.Orient && !opts.Repair {
+		or = NewOrientReader(r, logger)
+		r = or
+	}
+	if opts.IDProperty != "" {
+		r = newIDReader(r, opts.IDProperty)
+	}
+	var sr *simplifyReader
+	if opts.SimplifyToleranceMeters > 0 {
+		sr = newSimplifyReader(r, opts.SimplifyToleranceMeters, opts.VertexCountProperty, logger)
+		r = sr
+	}
+
+	var err error
+	switch {
+	case opts.Resume:
+		if cp, cerr := rs.LoadCheckpoint(); cerr == nil && cp != nil {
+			level.Info(logger).Log("msg", "resuming indexation", "read", cp.Read, "feature_count", cp.FeatureCount)
+		}
+		err = rs.Resume(r, icoverer, ocoverer, warningCellsCover, opts.FileName, opts.Version)
+	case opts.Append:
+		err = dest.Append(r, opts.IDProperty, icoverer, ocoverer, warningCellsCover, opts.FileName, opts.Version)
+	default:
+		err = dest.IndexReader(r, icoverer, ocoverer, warningCellsCover, opts.FileName, opts.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("indexation failed: %w", err)
+	}
+	if rr != nil {
+		rr.Log()
+	}
+	if or != nil {
+		or.Log()
+	}
+	if sr != nil {
+		sr.log()
+	}
+	level.Info(logger).Log("msg", "stored index_infos")
+
+	if opts.Hierarchy {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		parents, err := insideout.ComputeHierarchy(dest)
+		if err != nil {
+			return fmt.Errorf("can't compute hierarchy: %w", err)
+		}
+		if err := hs.StoreHierarchy(parents); err != nil {
+			return fmt.Errorf("can't store hierarchy: %w", err)
+		}
+		level.Info(logger).Log("msg", "stored hierarchy", "contained_features", len(parents))
+	}
+
+	if opts.CellFilterRate > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		filter, err := insideout.BuildCellFilter(dest, opts.CellFilterRate)
+		if err != nil {
+			return fmt.Errorf("can't build cell filter: %w", err)
+		}
+		if err := cfs.StoreCellFilter(filter); err != nil {
+			return fmt.Errorf("can't store cell filter: %w", err)
+		}
+		level.Info(logger).Log("msg", "stored cell filter", "bytes", len(filter.Bits), "hashes", filter.Hashes,
+			"min_level", filter.MinLevel, "max_level", filter.MaxLevel)
+	}
+
+	if pis != nil && (len(opts.IndexProps) > 0 || opts.IDProperty != "") {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		props := append([]string(nil), opts.IndexProps...)
+		if opts.IDProperty != "" && !strings.Contains(","+strings.Join(props, ",")+",", ","+opts.IDProperty+",") {
+			props = append(props, opts.IDProperty)
+		}
+		pi, err := insideout.BuildPropertyIndex(dest, props)
+		if err != nil {
+			return fmt.Errorf("can't build property index: %w", err)
+		}
+		if opts.IDProperty != "" {
+			// the features of a resumed indexation read before the checkpoint were not checked
+			if err := pi.CheckIDs(opts.IDProperty); err != nil {
+				return fmt.Errorf("invalid ids: %w", err)
+			}
+			pi.IDProperty = opts.IDProperty
+		}
+		if err := pis.StorePropertyIndex(pi); err != nil {
+			return fmt.Errorf("can't store property index: %w", err)
+		}
+		level.Info(logger).Log("msg", "stored property index", "properties", strings.Join(props, ","),
+			"id_property", opts.IDProperty, "values", len(pi.IDs))
+	}
+
+	if opts.H3Cover {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		cover, err := h3index.Cover(dest, opts.H3Resolution)
+		if err != nil {
+			return fmt.Errorf("can't compute H3 cover: %w", err)
+		}
+		if err := h3s.StoreH3Cover(cover); err != nil {
+			return fmt.Errorf("can't store H3 cover: %w", err)
+		}
+		level.Info(logger).Log("msg", "stored H3 cover", "h3_resolution", cover.Resolution,
+			"inside_cells", len(cover.Inside), "crossing_cells", len(cover.MayBeInside))
+	}
+
+	return nil
+}
+
+// configure sets the indexing options of opts supported by dest
+func configure(dest insideout.Store, opts Options, logger log.Logger) error {
+	if ps, ok := dest.(insideout.ParallelStore); ok {
+		if opts.Workers > 0 {
+			ps.SetWorkers(opts.Workers)
+		}
+	} else if opts.Workers > 1 {
+		level.Info(logger).Log("msg", "parallel indexing not supported by the storage, using one worker")
+	}
+
+	if opts.AutoCover {
+		acs, ok := dest.(insideout.AutoCoverStore)
+		if !ok {
+			return errors.New("auto cover not supported by the storage")
+		}
+		acs.SetAutoCover(true)
+	}
+
+	if opts.Compression != "" {
+		cs, ok := dest.(insideout.CompressionStore)
+		if !ok {
+			return errors.New("compression not supported by the storage")
+		}
+		if err := cs.SetCompression(opts.Compression); err != nil {
+			return fmt.Errorf("invalid compression: %w", err)
+		}
+	}
+
+	if les, ok := dest.(insideout.LoopEncodingStore); ok {
+		if opts.LoopEncoding != "" {
+			if err := les.SetLoopEncoding(opts.LoopEncoding); err != nil {
+				return fmt.Errorf("invalid loop encoding: %w", err)
+			}
+		}
+	} else if opts.LoopEncoding != "" && opts.LoopEncoding != insideout.S2LoopEncoding {
+		return errors.New("loop encoding not supported by the storage")
+	}
+
+	if opts.Progress != nil {
+		if ps, ok := dest.(insideout.ProgressStore); ok {
+			ps.SetProgress(opts.Progress)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/geojson"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/storage/bbolt"
+)
+
+func square(x, y, size float64, name string) *geojson.Feature {
+	return &geojson.Feature{
+		Geometry: geom.NewPolygonFlat(geom.XY,
+			[]float64{x, y, x + size, y, x + size, y + size, x, y + size, x, y}, []int{10}),
+		Properties: map[string]interface{}{"name": name},
+	}
+}
+
+func setup(t *testing.T) (*bbolt.Storage, func()) {
+	tmpFile, err := ioutil.TempFile(os.TempDir(), "insideout-test-")
+	require.NoError(t, err)
+	tmpFile.Close()
+
+	storage, clean, err := bbolt.NewStorage(tmpFile.Name(), log.NewNopLogger())
+	require.NoError(t, err)
+	return storage, func() {
+		clean()
+		os.Remove(tmpFile.Name())
+	}
+}
+
+func TestBuild(t *testing.T) {
+	storage, clean := setup(t)
+	defer clean()
+
+	// B inside A, with a clockwise exterior ring reoriented
+	b := square(0.2, 0.2, 0.5, "B")
+	b.Geometry = geom.NewPolygonFlat(geom.XY, []float64{0.2, 0.2, 0.2, 0.7, 0.7, 0.7, 0.7, 0.2, 0.2, 0.2}, []int{10})
+	fc := &geojson.FeatureCollection{Features: []*geojson.Feature{square(0, 0, 1, "A"), b, square(10, 10, 1, "C")}}
+
+	err := Build(context.Background(), insideout.NewFeatureCollectionReader(fc), storage, Options{
+		Orient:         true,
+		IDProperty:     "name",
+		Hierarchy:      true,
+		CellFilterRate: 0.01,
+		FileName:       "unittest",
+	})
+	require.NoError(t, err)
+
+	infos, err := storage.LoadIndexInfos()
+	require.NoError(t, err)
+	require.Equal(t, uint32(3), infos.FeatureCount)
+	require.Equal(t, "unittest", infos.Filename)
+
+	idx, err := storage.StabDB(0.5, 0.5, false)
+	require.NoError(t, err)
+	require.Len(t, append(idx.IDsInside, idx.IDsMayBeInside...), 2)
+
+	parents, err := storage.LoadHierarchy()
+	require.NoError(t, err)
+	require.Equal(t, map[uint32]uint32{1: 0}, parents)
+
+	filter, err := storage.LoadCellFilter()
+	require.NoError(t, err)
+	require.NotNil(t, filter)
+
+	pi, err := storage.LoadPropertyIndex()
+	require.NoError(t, err)
+	require.Equal(t, "name", pi.IDProperty)
+	require.Equal(t, []uint32{2}, pi.Lookup("name", "C"))
+}
+
+func TestBuild_Errors(t *testing.T) {
+	fc := &geojson.FeatureCollection{Features: []*geojson.Feature{square(0, 0, 1, "A"), square(2, 2, 1, "A")}}
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		opts Options
+		err  error
+	}{
+		{"duplicate ids", context.Background(), Options{IDProperty: "name"}, nil},
+		{"canceled", canceled, Options{}, context.Canceled},
+		{"invalid compression", context.Background(), Options{Compression: "nope"}, nil},
+		{"invalid cover", context.Background(), Options{InsideCoverer: &s2.RegionCoverer{MinLevel: 12, MaxLevel: 10}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage, clean := setup(t)
+			defer clean()
+
+			err := Build(tt.ctx, insideout.NewFeatureCollectionReader(fc), storage, tt.opts)
+			require.Error(t, err)
+			if tt.err != nil {
+				require.True(t, errors.Is(err, tt.err), err)
+			}
+		})
+	}
+}
@@ -1,6 +1,9 @@
 package dbindex
 
 import (
+	"context"
+	"sync/atomic"
+
 	"github.com/akhenakh/insideout"
 )
 
@@ -9,6 +12,8 @@ type Index struct {
 	storage insideout.Store
 
 	opts Options
+	// stop is opts.StopOnInsideFound, changed at runtime by SetStopOnInsideFound
+	stop int32
 }
 
 // Options for the dbindex
@@ -19,13 +24,42 @@ type Options struct {
 }
 
 func New(storage insideout.Store, opts Options) *Index {
-	return &Index{
+	idx := &Index{
 		storage: storage,
 		opts:    opts,
 	}
+	idx.SetStopOnInsideFound(opts.StopOnInsideFound)
+	return idx
+}
+
+// SetStopOnInsideFound changes the StopOnInsideFound option, safe to call while the index is queried
+func (idx *Index) SetStopOnInsideFound(stop bool) {
+	var v int32
+	if stop {
+		v = 1
+	}
+	atomic.StoreInt32(&idx.stop, v)
+}
+
+func (idx *Index) stopOnInsideFound() bool {
+	return atomic.LoadInt32(&idx.stop) == 1
 }
 
 // Stab returns polygon's ids containing lat lng and polygon's ids that may be
 func (idx *Index) Stab(lat, lng float64) (insideout.IndexResponse, error) {
-	return idx.storage.StabDB(lat, lng, idx.opts.StopOnInsideFound)
+	return idx.storage.StabDB(lat, lng, idx.stopOnInsideFound())
+}
+
+// StabContext returns polygon's ids containing lat lng and polygon's ids that may be,
+// the storage query is canceled once ctx is done when supported,
+// StopOnInsideFound is ignored when ctx was returned by insideout.WithoutStop
+func (idx *Index) StabContext(ctx context.Context, lat, lng float64) (insideout.IndexResponse, error) {
+	stop := idx.stopOnInsideFound() && insideout.StopAllowed(ctx)
+	if cs, ok := idx.storage.(insideout.ContextStore); ok {
+		return cs.StabDBContext(ctx, lat, lng, stop)
+	}
+	if err := ctx.Err(); err != nil {
+		return insideout.IndexResponse{}, err
+	}
+	return idx.storage.StabDB(lat, lng, stop)
 }
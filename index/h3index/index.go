@@ -0,0 +1,175 @@
+//go:build cgo
+// +build cgo
+
+// Package h3index indexes the polygons with H3 cells, as an alternative to the S2 cells of the other indexes,
+// it requires cgo
+package h3index
+
+import (
+	"fmt"
+
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+	"github.com/uber/h3-go/v3"
+
+	"github.com/akhenakh/insideout"
+)
+
+// Index the polygons containing or crossing each H3 cell of a cover
+type Index struct {
+	cover *insideout.H3Cover
+}
+
+// New returns an index of cover, computed by Cover
+func New(cover *insideout.H3Cover) (*Index, error) {
+	if err := validResolution(cover.Resolution); err != nil {
+		return nil, err
+	}
+	return &Index{cover: cover}, nil
+}
+
+// Stab returns the polygons containing the cell of lat lng or one of its parents as inside,
+// the polygons crossing it as may be inside
+func (idx *Index) Stab(lat, lng float64) (insideout.IndexResponse, error) {
+	var resp insideout.IndexResponse
+	c := idx.H3Cell(lat, lng)
+	for res := idx.cover.Resolution; res >= 0; res-- {
+		resp.IDsInside = append(resp.IDsInside, idx.cover.Inside[uint64(h3.ToParent(h3.H3Index(c), res))]...)
+	}
+	resp.IDsMayBeInside = idx.cover.MayBeInside[c]
+	return resp, nil
+}
+
+// H3Cell returns the H3 cell of lat lng at the resolution of the cover
+func (idx *Index) H3Cell(lat, lng float64) uint64 {
+	return uint64(h3.FromGeo(h3.GeoCoord{Latitude: lat, Longitude: lng}, idx.cover.Resolution))
+}
+
+// Cover returns the H3 cells at resolution res covering the polygons of storage:
+// the cells inside each polygon, compacted, and the cells crossing it
+func Cover(storage insideout.Store, res int) (*insideout.H3Cover, error) {
+	if err := validResolution(res); err != nil {
+		return nil, err
+	}
+	cover := &insideout.H3Cover{
+		Resolution:  res,
+		Inside:      make(map[uint64][]insideout.FeatureIndexResponse),
+		MayBeInside: make(map[uint64][]insideout.FeatureIndexResponse),
+	}
+
+	err := storage.LoadAllFeatures(func(fs *insideout.FeatureStorage, id uint32) error {
+		holes, err := insideout.DecodeHoles(fs.HolesBytes)
+		if err != nil {
+			return fmt.Errorf("can't decode holes of feature %d: %w", id, err)
+		}
+		for i, b := range fs.LoopsBytes {
+			l, err := insideout.DecodeLoop(b)
+			if err != nil {
+				return fmt.Errorf("can't decode loop %d of feature %d: %w", i, id, err)
+			}
+			fid := insideout.FeatureIndexResponse{ID: id, Pos: uint16(i)}
+			var hs []*s2.Loop
+			if i < len(holes) {
+				hs = holes[i]
+			}
+			inside, crossing := coverLoop(l, hs, res)
+			for _, c := range inside {
+				cover.Inside[c] = append(cover.Inside[c], fid)
+			}
+			for _, c := range crossing {
+				cover.MayBeInside[c] = append(cover.MayBeInside[c], fid)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cover, nil
+}
+
+// coverLoop returns the compacted cells inside l and the cells crossing l at resolution res,
+// the cells inside a hole of l are skipped and the ones crossing a hole are crossing l
+func coverLoop(l *s2.Loop, holes []*s2.Loop, res int) (inside, crossing []uint64) {
+	if l.IsEmpty() || l.IsFull() {
+		return nil, nil
+	}
+
+	// the cells along the edges, sampled at less than an edge length, and their neighbors, then the neighbors
+	// of every cell intersecting l, h3.Polyfill is planar and misses the interior of the loops around a pole
+	var queue []h3.H3Index
+	seen := make(map[h3.H3Index]bool)
+	visit := func(c h3.H3Index) {
+		for _, k := range h3.KRing(c, 1) {
+			if !seen[k] {
+				seen[k] = true
+				queue = append(queue, k)
+			}
+		}
+	}
+	step := s1.Angle(h3.EdgeLengthM(res) / 2 / insideout.EarthRadiusMeters)
+	for i := 0; i < l.NumEdges(); i++ {
+		e := l.Edge(i)
+		n := int(e.V0.Distance(e.V1)/step) + 1
+		for j := 0; j <= n; j++ {
+			ll := s2.LatLngFromPoint(s2.Interpolate(float64(j)/float64(n), e.V0, e.V1))
+			visit(h3.FromGeo(h3.GeoCoord{Latitude: ll.Lat.Degrees(), Longitude: ll.Lng.Degrees()}, res))
+		}
+	}
+
+	var insideCells []h3.H3Index
+	for len(queue) > 0 {
+		c := queue[0]
+		queue = queue[1:]
+		cl := cellLoop(c)
+		if !l.Intersects(cl) {
+			continue
+		}
+		visit(c)
+
+		in, cross := false, false
+		for _, h := range holes {
+			if h.Contains(cl) {
+				in = true
+				break
+			}
+			if h.Intersects(cl) {
+				cross = true
+			}
+		}
+		switch {
+		case in:
+		case !cross && l.Contains(cl):
+			insideCells = append(insideCells, c)
+		default:
+			crossing = append(crossing, uint64(c))
+		}
+	}
+	if len(insideCells) == 0 {
+		return nil, crossing
+	}
+	for _, c := range h3.Compact(insideCells) {
+		inside = append(inside, uint64(c))
+	}
+	return inside, crossing
+}
+
+// cellLoop returns the boundary of the cell c
+func cellLoop(c h3.H3Index) *s2.Loop {
+	gb := h3.ToGeoBoundary(c)
+	points := make([]s2.Point, len(gb))
+	for i, g := range gb {
+		points[i] = s2.PointFromLatLng(s2.LatLngFromDegrees(g.Latitude, g.Longitude))
+	}
+	l := s2.LoopFromPoints(points)
+	// reverses a clockwise boundary
+	l.Normalize()
+	return l
+}
+
+func validResolution(res int) error {
+	if res < 0 || res > h3.MaxResolution {
+		return fmt.Errorf("invalid H3 resolution %d, 0 to %d", res, h3.MaxResolution)
+	}
+	return nil
+}
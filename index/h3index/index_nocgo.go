@@ -0,0 +1,36 @@
+//go:build !cgo
+// +build !cgo
+
+package h3index
+
+import (
+	"errors"
+
+	"github.com/akhenakh/insideout"
+)
+
+// ErrNoCGO H3 is implemented in C, the binary was built without cgo
+var ErrNoCGO = errors.New("H3 requires a binary built with cgo")
+
+// Index the polygons containing or crossing each H3 cell of a cover
+type Index struct{}
+
+// New returns ErrNoCGO
+func New(cover *insideout.H3Cover) (*Index, error) {
+	return nil, ErrNoCGO
+}
+
+// Stab returns ErrNoCGO
+func (idx *Index) Stab(lat, lng float64) (insideout.IndexResponse, error) {
+	return insideout.IndexResponse{}, ErrNoCGO
+}
+
+// H3Cell returns 0
+func (idx *Index) H3Cell(lat, lng float64) uint64 {
+	return 0
+}
+
+// Cover returns ErrNoCGO
+func Cover(storage insideout.Store, res int) (*insideout.H3Cover, error) {
+	return nil, ErrNoCGO
+}
@@ -0,0 +1,120 @@
+//go:build cgo
+// +build cgo
+
+package h3index
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom/encoding/geojson"
+	"github.com/uber/h3-go/v3"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/storage/bbolt"
+)
+
+func TestIndex_Stab(t *testing.T) {
+	idx, storage, clean := setup(t)
+	defer clean()
+
+	resp, err := idx.Stab(47.39650628189986, -2.9876390969486524)
+	require.NoError(t, err)
+	require.Equal(t, []insideout.FeatureIndexResponse{{ID: 0, Pos: 1}}, resp.IDsInside)
+
+	resp, err = idx.Stab(47.37616957736262, -3.004367209321472)
+	require.NoError(t, err)
+	require.Empty(t, resp.IDsInside)
+	require.Empty(t, resp.IDsMayBeInside)
+
+	// every polygon containing a point is returned, the inside ones contain it
+	f, err := storage.LoadFeature(0)
+	require.NoError(t, err)
+	for lat := 47.37; lat < 47.41; lat += 0.001 {
+		for lng := -3.01; lng < -2.97; lng += 0.001 {
+			resp, err := idx.Stab(lat, lng)
+			require.NoError(t, err)
+			p := s2.PointFromLatLng(s2.LatLngFromDegrees(lat, lng))
+			found := make(map[uint16]bool)
+			for _, fid := range resp.IDsInside {
+				require.True(t, f.Loops[fid.Pos].ContainsPoint(p), "%f %f", lat, lng)
+				found[fid.Pos] = true
+			}
+			for _, fid := range resp.IDsMayBeInside {
+				found[fid.Pos] = true
+			}
+			for i, l := range f.Loops {
+				if l.ContainsPoint(p) {
+					require.True(t, found[uint16(i)], "%f %f", lat, lng)
+				}
+			}
+		}
+	}
+
+	require.NotZero(t, idx.H3Cell(47.39, -2.98))
+}
+
+func TestCoverLoop_Pole(t *testing.T) {
+	// a cap around the south pole, h3.Polyfill does not fill it
+	l := insideout.LoopFromCoordinates([]float64{180, -80, 90, -80, 0, -80, -90, -80, -180, -80, 180, -80})
+	require.True(t, l.ContainsPoint(s2.PointFromLatLng(s2.LatLngFromDegrees(-90, 0))))
+
+	inside, crossing := coverLoop(l, nil, 2)
+	require.NotEmpty(t, inside)
+	require.NotEmpty(t, crossing)
+	c := h3.FromGeo(h3.GeoCoord{Latitude: -89, Longitude: 45}, 2)
+	found := false
+	for _, ic := range inside {
+		if h3.ToParent(c, h3.Resolution(h3.H3Index(ic))) == h3.H3Index(ic) {
+			found = true
+		}
+	}
+	require.True(t, found)
+}
+
+func TestNew_InvalidResolution(t *testing.T) {
+	_, err := New(&insideout.H3Cover{Resolution: 16})
+	require.Error(t, err)
+}
+
+func setup(t *testing.T) (*Index, insideout.Store, func()) {
+	logger := log.NewNopLogger()
+
+	tmpFile, err := ioutil.TempFile(os.TempDir(), "insideout-test-")
+	require.NoError(t, err)
+	wstorage, wclose, err := bbolt.NewStorage(tmpFile.Name(), logger)
+	require.NoError(t, err)
+
+	var fc geojson.FeatureCollection
+	file, err := os.Open("../testdata/poly.geojson")
+	require.NoError(t, err)
+	defer file.Close()
+	require.NoError(t, json.NewDecoder(file).Decode(&fc))
+
+	icoverer := &s2.RegionCoverer{MinLevel: 10, MaxLevel: 16, MaxCells: 24}
+	ocoverer := &s2.RegionCoverer{MinLevel: 10, MaxLevel: 15, MaxCells: 16}
+	require.NoError(t, wstorage.Index(fc, icoverer, ocoverer, 100, "poly.geojson", "unittest"))
+
+	cover, err := Cover(wstorage, 10)
+	require.NoError(t, err)
+	require.NoError(t, wstorage.StoreH3Cover(cover))
+	require.NoError(t, wclose())
+
+	storage, sclose, err := bbolt.NewROStorage(tmpFile.Name(), logger)
+	require.NoError(t, err)
+
+	cover, err = storage.LoadH3Cover()
+	require.NoError(t, err)
+	idx, err := New(cover)
+	require.NoError(t, err)
+
+	return idx, storage, func() {
+		sclose()
+		os.Remove(tmpFile.Name())
+	}
+}
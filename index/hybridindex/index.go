@@ -0,0 +1,146 @@
+package hybridindex
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/akhenakh/insidetree"
+	"github.com/golang/geo/s2"
+
+	"github.com/akhenakh/insideout"
+)
+
+// Index using insidetree for the interior cells only,
+// the boundary cells are read from storage, memory is bounded to the inside covers
+type Index struct {
+	itree   *insidetree.Tree
+	storage insideout.Store
+
+	opts Options
+	// stop is opts.StopOnInsideFound, changed at runtime by SetStopOnInsideFound
+	stop int32
+
+	// gens the generation of the features updated or removed since loaded,
+	// the tree can't remove cells, the ones indexed by a previous generation are skipped
+	gens map[uint32]uint32
+}
+
+// entry a cell indexed in the tree
+type entry struct {
+	insideout.FeatureIndexResponse
+	gen uint32
+}
+
+// Options for the hybrid Index
+type Options struct {
+	// StopOnInside, if you know your data does not overlap (eg countries) set it to true
+	// so it won't go looking further and response faster
+	StopOnInsideFound bool
+}
+
+func New(storage insideout.Store, opts Options) *Index {
+	idx := &Index{
+		itree:   insidetree.NewTree(),
+		storage: storage,
+		opts:    opts,
+		gens:    make(map[uint32]uint32),
+	}
+	idx.SetStopOnInsideFound(opts.StopOnInsideFound)
+	return idx
+}
+
+// SetStopOnInsideFound changes the StopOnInsideFound option, safe to call while the index is queried
+func (idx *Index) SetStopOnInsideFound(stop bool) {
+	var v int32
+	if stop {
+		v = 1
+	}
+	atomic.StoreInt32(&idx.stop, v)
+}
+
+func (idx *Index) stopOnInsideFound() bool {
+	return atomic.LoadInt32(&idx.stop) == 1
+}
+
+// Add indexes the inside cells, the outside cells are ignored
+func (idx *Index) Add(cellsIn []s2.CellUnion, cellsOut []s2.CellUnion, id uint32) {
+	gen := idx.gens[id]
+	for i, cu := range cellsIn {
+		for _, c := range cu {
+			idx.itree.Index(c, entry{FeatureIndexResponse: insideout.FeatureIndexResponse{
+				ID:  id,
+				Pos: uint16(i),
+			}, gen: gen})
+		}
+	}
+}
+
+// Update replaces the inside cells of the feature id, the storage must already hold its new cells,
+// not safe to call while the index is queried
+func (idx *Index) Update(fs *insideout.FeatureStorage, cs *insideout.CellsStorage, id uint32) error {
+	idx.Remove(id)
+	idx.Add(cs.CellsIn, cs.CellsOut, id)
+	return nil
+}
+
+// Remove hides the inside cells of the feature id, not safe to call while the index is queried
+func (idx *Index) Remove(id uint32) {
+	idx.gens[id]++
+}
+
+// Load fills the tree with the inside cells from storage
+func (idx *Index) Load() error {
+	if err := idx.storage.LoadFeaturesCells(idx.Add); err != nil {
+		return fmt.Errorf("failed to load cells from storage: %w", err)
+	}
+	return nil
+}
+
+// Stab returns polygon's ids containing lat lng from the tree and polygon's ids that may be from storage,
+// storage is not queried when an inside polygon is found and StopOnInsideFound is set
+func (idx *Index) Stab(lat, lng float64) (insideout.IndexResponse, error) {
+	return idx.StabContext(context.Background(), lat, lng)
+}
+
+// StabContext is Stab stopping before reading the storage once ctx is done,
+// StopOnInsideFound is ignored when ctx was returned by insideout.WithoutStop
+func (idx *Index) StabContext(ctx context.Context, lat, lng float64) (insideout.IndexResponse, error) {
+	var idxResp insideout.IndexResponse
+
+	p := s2.PointFromLatLng(s2.LatLngFromDegrees(lat, lng))
+
+	c := s2.CellFromPoint(p).ID()
+	for _, r := range idx.itree.Stab(c) {
+		// skips the cells of a previous generation
+		if e := r.(entry); e.gen == idx.gens[e.ID] {
+			idxResp.IDsInside = append(idxResp.IDsInside, e.FeatureIndexResponse)
+		}
+	}
+
+	if idx.stopOnInsideFound() && insideout.StopAllowed(ctx) && len(idxResp.IDsInside) > 0 {
+		return idxResp, nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return idxResp, err
+	}
+	dbResp, err := idx.storage.StabDB(lat, lng, false)
+	if err != nil {
+		return idxResp, err
+	}
+
+	for _, fres := range dbResp.IDsMayBeInside {
+		// remove any answer matching inside
+		found := false
+		for _, ires := range idxResp.IDsInside {
+			if ires.ID == fres.ID {
+				found = true
+			}
+		}
+		if !found {
+			idxResp.IDsMayBeInside = append(idxResp.IDsMayBeInside, fres)
+		}
+	}
+	return idxResp, nil
+}
@@ -0,0 +1,134 @@
+package memoryindex
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/golang/geo/s2"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/index/treeindex"
+)
+
+// Index using insidetree with all the features decoded in memory,
+// storage is never read once loaded
+type Index struct {
+	*treeindex.Index
+
+	mu       sync.RWMutex
+	features map[uint32]*insideout.Feature
+}
+
+// Options for the memory Index
+type Options struct {
+	// StopOnInside, if you know your data does not overlap (eg countries) set it to true
+	// so it won't go looking further and response faster
+	StopOnInsideFound bool
+}
+
+func New(opts Options) *Index {
+	return &Index{
+		Index:    treeindex.New(treeindex.Options{StopOnInsideFound: opts.StopOnInsideFound}),
+		features: make(map[uint32]*insideout.Feature),
+	}
+}
+
+// AddFeature decodes and keeps the feature in memory
+func (idx *Index) AddFeature(fs *insideout.FeatureStorage, id uint32) error {
+	loops := make([]*s2.Loop, len(fs.LoopsBytes))
+	for i := 0; i < len(loops); i++ {
+		l, err := insideout.DecodeLoop(fs.LoopsBytes[i])
+		if err != nil {
+			return err
+		}
+		loops[i] = l
+	}
+	holes, err := insideout.DecodeHoles(fs.HolesBytes)
+	if err != nil {
+		return err
+	}
+
+	// fs is reused by the storage, copy the properties
+	prop := make(map[string]interface{}, len(fs.Properties))
+	for k, v := range fs.Properties {
+		prop[k] = v
+	}
+
+	idx.mu.Lock()
+	idx.features[id] = &insideout.Feature{
+		Loops:      loops,
+		Holes:      holes,
+		Properties: prop,
+	}
+	idx.mu.Unlock()
+
+	return nil
+}
+
+// Load fills the index with all the features and cells from storage
+func (idx *Index) Load(storage insideout.Store) error {
+	if err := storage.LoadAllFeatures(idx.AddFeature); err != nil {
+		return fmt.Errorf("failed to load features from storage: %w", err)
+	}
+	if err := storage.LoadFeaturesCells(idx.Index.Add); err != nil {
+		return fmt.Errorf("failed to load cells from storage: %w", err)
+	}
+	return nil
+}
+
+// Update replaces the feature id and its cells, not safe to call while the index is queried
+func (idx *Index) Update(fs *insideout.FeatureStorage, cs *insideout.CellsStorage, id uint32) error {
+	if err := idx.AddFeature(fs, id); err != nil {
+		return err
+	}
+	return idx.Index.Update(fs, cs, id)
+}
+
+// Remove removes the feature id and hides its cells, not safe to call while the index is queried
+func (idx *Index) Remove(id uint32) {
+	idx.mu.Lock()
+	delete(idx.features, id)
+	idx.mu.Unlock()
+	idx.Index.Remove(id)
+}
+
+// Feature returns the in memory feature for id
+func (idx *Index) Feature(id uint32) (*insideout.Feature, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	f, ok := idx.features[id]
+	return f, ok
+}
+
+// Stab returns polygon's ids we are inside,
+// the point in polygon test is performed in memory so no polygon's ids are returned as may be inside
+func (idx *Index) Stab(lat, lng float64) (insideout.IndexResponse, error) {
+	return idx.StabContext(context.Background(), lat, lng)
+}
+
+// StabContext is Stab ignoring StopOnInsideFound when ctx was returned by insideout.WithoutStop
+func (idx *Index) StabContext(ctx context.Context, lat, lng float64) (insideout.IndexResponse, error) {
+	idxResp, err := idx.Index.StabContext(ctx, lat, lng)
+	if err != nil {
+		return idxResp, err
+	}
+
+	if len(idxResp.IDsMayBeInside) == 0 {
+		return idxResp, nil
+	}
+
+	p := s2.PointFromLatLng(s2.LatLngFromDegrees(lat, lng))
+	for _, fres := range idxResp.IDsMayBeInside {
+		f, ok := idx.Feature(fres.ID)
+		if !ok {
+			return idxResp, fmt.Errorf("feature id not found: %d", fres.ID)
+		}
+		if f.PolygonContainsPoint(fres.Pos, p) {
+			idxResp.IDsInside = append(idxResp.IDsInside, fres)
+		}
+	}
+	idxResp.IDsMayBeInside = nil
+
+	return idxResp, nil
+}
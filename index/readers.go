@@ -0,0 +1,202 @@
+package index
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/twpayne/go-geom/encoding/geojson"
+
+	"github.com/akhenakh/insideout"
+)
+
+// contextReader fails with the error of ctx once it is done
+type contextReader struct {
+	insideout.FeatureReader
+	ctx context.Context
+}
+
+func (r *contextReader) Read() (*geojson.Feature, error) {
+	if err := r.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return r.FeatureReader.Read()
+}
+
+// RepairReader repairs the geometries of the features read,
+// a feature that can't be repaired is returned as is
+type RepairReader struct {
+	insideout.FeatureReader
+	precision float64
+	logger    log.Logger
+
+	repaired, failed int
+}
+
+// NewRepairReader returns a RepairReader of r snapping the coordinates to precision degrees
+func NewRepairReader(r insideout.FeatureReader, precision float64, logger log.Logger) *RepairReader {
+	return &RepairReader{
+		FeatureReader: r,
+		precision:     precision,
+		logger:        logger,
+	}
+}
+
+func (r *RepairReader) Read() (*geojson.Feature, error) {
+	f, err := r.FeatureReader.Read()
+	if err != nil {
+		return nil, err
+	}
+	r.repair(f)
+	return f, nil
+}
+
+// repair replaces the geometry of f by its repaired version
+func (r *RepairReader) repair(f *geojson.Feature) {
+	g, fixes, err := insideout.RepairGeometry(f.Geometry, r.precision)
+	if err != nil {
+		r.failed++
+		level.Warn(r.logger).Log("msg", "can't repair geometry", "error", err, "feature_properties", f.Properties)
+		return
+	}
+	if len(fixes) == 0 {
+		return
+	}
+	r.repaired++
+	level.Debug(r.logger).Log("msg", "repaired geometry", "fixes", strings.Join(fixes, ", "), "feature_properties", f.Properties)
+	f.Geometry = g
+}
+
+// Log logs the counts of repaired geometries
+func (r *RepairReader) Log() {
+	level.Info(r.logger).Log("msg", "geometries repaired", "repaired", r.repaired, "failed", r.failed)
+}
+
+// OrientReader reverses the rings of the features read with a wrong orientation,
+// exterior rings counterclockwise and holes clockwise
+type OrientReader struct {
+	insideout.FeatureReader
+	logger log.Logger
+
+	oriented int
+}
+
+// NewOrientReader returns an OrientReader of r
+func NewOrientReader(r insideout.FeatureReader, logger log.Logger) *OrientReader {
+	return &OrientReader{
+		FeatureReader: r,
+		logger:        logger,
+	}
+}
+
+func (r *OrientReader) Read() (*geojson.Feature, error) {
+	f, err := r.FeatureReader.Read()
+	if err != nil {
+		return nil, err
+	}
+	g, reversed := insideout.OrientGeometry(f.Geometry)
+	if reversed > 0 {
+		r.oriented++
+		level.Debug(r.logger).Log("msg", "reoriented rings", "rings", reversed, "feature_properties", f.Properties)
+		f.Geometry = g
+	}
+	return f, nil
+}
+
+// Log logs the count of reoriented geometries
+func (r *OrientReader) Log() {
+	level.Info(r.logger).Log("msg", "geometries reoriented", "reoriented", r.oriented)
+}
+
+// simplifyReader simplifies the geometries of the features read,
+// the original vertex count is set to vertexCountProperty when not empty
+type simplifyReader struct {
+	insideout.FeatureReader
+	toleranceMeters     float64
+	vertexCountProperty string
+	logger              log.Logger
+
+	before, after int
+}
+
+func newSimplifyReader(r insideout.FeatureReader, toleranceMeters float64, vertexCountProperty string,
+	logger log.Logger) *simplifyReader {
+	return &simplifyReader{
+		FeatureReader:       r,
+		toleranceMeters:     toleranceMeters,
+		vertexCountProperty: vertexCountProperty,
+		logger:              logger,
+	}
+}
+
+func (r *simplifyReader) Read() (*geojson.Feature, error) {
+	f, err := r.FeatureReader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	g, before, after := insideout.SimplifyGeometry(f.Geometry, r.toleranceMeters)
+	f.Geometry = g
+	r.before += before
+	r.after += after
+
+	if r.vertexCountProperty != "" && before > 0 {
+		if f.Properties == nil {
+			f.Properties = make(map[string]interface{})
+		}
+		f.Properties[r.vertexCountProperty] = before
+	}
+
+	return f, nil
+}
+
+func (r *simplifyReader) log() {
+	level.Info(r.logger).Log("msg", "geometries simplified", "vertices_before", r.before, "vertices_after", r.after)
+}
+
+// idReader fails on a feature read without a value for the id property or with the value of a feature read before
+type idReader struct {
+	insideout.FeatureReader
+	idProperty string
+
+	seen map[string]int
+	read int
+}
+
+func newIDReader(r insideout.FeatureReader, idProperty string) *idReader {
+	return &idReader{
+		FeatureReader: r,
+		idProperty:    idProperty,
+		seen:          make(map[string]int),
+	}
+}
+
+func (r *idReader) Read() (*geojson.Feature, error) {
+	f, err := r.FeatureReader.Read()
+	if err != nil {
+		return nil, err
+	}
+	r.read++
+	id := FeatureID(f, r.idProperty)
+	if id == "" {
+		return nil, fmt.Errorf("feature #%d has no id property %s", r.read, r.idProperty)
+	}
+	if prev, ok := r.seen[id]; ok {
+		return nil, fmt.Errorf("feature #%d has the same id property %s %s as feature #%d", r.read, r.idProperty, id, prev)
+	}
+	r.seen[id] = r.read
+	return f, nil
+}
+
+// FeatureID returns the value of idProperty or the GeoJSON id of f, empty when missing
+func FeatureID(f *geojson.Feature, idProperty string) string {
+	if idProperty != "" {
+		if v, ok := f.Properties[idProperty]; ok && v != nil {
+			return fmt.Sprint(v)
+		}
+		return ""
+	}
+	return f.ID
+}
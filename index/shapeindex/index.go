@@ -1,7 +1,6 @@
 package shapeindex
 
 import (
-	"bytes"
 	"sync"
 
 	"github.com/golang/geo/s2"
@@ -14,6 +13,14 @@ type Index struct {
 	sync.Mutex
 	*s2.ShapeIndex
 	*s2.ContainsPointQuery
+
+	// loops the indexed loops of each feature
+	loops map[uint32][]indexedLoop
+	// holes the holes of the indexed loops, a point inside a hole is not inside its loop
+	holes map[insideout.FeatureIndexResponse][]*s2.Loop
+	// stale the shape index must be rebuilt from loops,
+	// s2.ShapeIndex can't apply a removal or an addition once queried
+	stale bool
 }
 
 type indexedLoop struct {
@@ -24,15 +31,25 @@ type indexedLoop struct {
 func New() *Index {
 	return &Index{
 		ShapeIndex: s2.NewShapeIndex(),
+		loops:      make(map[uint32][]indexedLoop),
+		holes:      make(map[insideout.FeatureIndexResponse][]*s2.Loop),
 	}
 }
 
 func (idx *Index) Add(si *insideout.FeatureStorage, id uint32) error {
 	idx.Lock()
 	defer idx.Unlock()
+	return idx.add(si, id)
+}
+
+func (idx *Index) add(si *insideout.FeatureStorage, id uint32) error {
+	holes, err := insideout.DecodeHoles(si.HolesBytes)
+	if err != nil {
+		return err
+	}
 	for i := 0; i < len(si.LoopsBytes); i++ {
-		l := &s2.Loop{}
-		if err := l.Decode(bytes.NewReader(si.LoopsBytes[i])); err != nil {
+		l, err := insideout.DecodeLoop(si.LoopsBytes[i])
+		if err != nil {
 			return err
 		}
 
@@ -45,10 +62,74 @@ func (idx *Index) Add(si *insideout.FeatureStorage, id uint32) error {
 		}
 
 		idx.ShapeIndex.Add(il)
+		idx.loops[id] = append(idx.loops[id], il)
+		if i < len(holes) && len(holes[i]) > 0 {
+			idx.holes[il.FeatureIndexResponse] = holes[i]
+		}
+	}
+	return nil
+}
+
+// Update replaces the loops of the feature id, the shape index is rebuilt by the next Stab
+func (idx *Index) Update(fs *insideout.FeatureStorage, cs *insideout.CellsStorage, id uint32) error {
+	idx.Lock()
+	defer idx.Unlock()
+	idx.remove(id)
+	if err := idx.add(fs, id); err != nil {
+		return err
 	}
+	idx.stale = true
 	return nil
 }
 
+// Remove removes the loops of the feature id, the shape index is rebuilt by the next Stab
+func (idx *Index) Remove(id uint32) {
+	idx.Lock()
+	defer idx.Unlock()
+	idx.remove(id)
+}
+
+func (idx *Index) remove(id uint32) {
+	ils, ok := idx.loops[id]
+	if !ok {
+		return
+	}
+	for _, il := range ils {
+		delete(idx.holes, il.FeatureIndexResponse)
+	}
+	delete(idx.loops, id)
+	idx.stale = true
+}
+
+// rebuild replaces the shape index by one holding loops
+func (idx *Index) rebuild() {
+	idx.ShapeIndex = s2.NewShapeIndex()
+	for _, ils := range idx.loops {
+		for _, il := range ils {
+			idx.ShapeIndex.Add(il)
+		}
+	}
+	idx.ContainsPointQuery = nil
+	idx.stale = false
+}
+
+// Warmup builds the shape index, s2.ShapeIndex applies the added loops on the first query
+func (idx *Index) Warmup() {
+	idx.Lock()
+	defer idx.Unlock()
+	idx.ready()
+}
+
+// ready builds the shape index and its query if needed, idx must be locked
+func (idx *Index) ready() {
+	if idx.stale {
+		idx.rebuild()
+	}
+	if idx.ContainsPointQuery == nil {
+		idx.ContainsPointQuery = s2.NewContainsPointQuery(idx.ShapeIndex, s2.VertexModelOpen)
+	}
+}
+
 // Stab returns polygon's ids we are inside and polygon's ids we may be inside
 // in case of this index we are always in
 func (idx *Index) Stab(lat, lng float64) (insideout.IndexResponse, error) {
@@ -58,15 +139,26 @@ func (idx *Index) Stab(lat, lng float64) (insideout.IndexResponse, error) {
 
 	var idxResp insideout.IndexResponse
 
-	if idx.ContainsPointQuery == nil {
-		idx.ContainsPointQuery = s2.NewContainsPointQuery(idx.ShapeIndex, s2.VertexModelOpen)
-	}
+	idx.ready()
 
 	shapes := idx.ContainsPointQuery.ContainingShapes(p)
 
 	for _, shape := range shapes {
 		il := shape.(indexedLoop)
+		if inHole(idx.holes[il.FeatureIndexResponse], p) {
+			continue
+		}
 		idxResp.IDsInside = append(idxResp.IDsInside, il.FeatureIndexResponse)
 	}
 	return idxResp, nil
 }
+
+// inHole returns true if one of holes contains p
+func inHole(holes []*s2.Loop, p s2.Point) bool {
+	for _, h := range holes {
+		if h.ContainsPoint(p) {
+			return true
+		}
+	}
+	return false
+}
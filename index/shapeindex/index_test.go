@@ -1,6 +1,7 @@
 package shapeindex
 
 import (
+	"bytes"
 	"encoding/json"
 	"io/ioutil"
 	"os"
@@ -63,6 +64,36 @@ func TestShapeIndex_Stab(t *testing.T) {
 	}
 }
 
+func TestShapeIndex_Update(t *testing.T) {
+	shapeidx, clean := setup(t)
+	defer clean()
+
+	lat, lng := 47.3944602327291, -2.9924373872714556
+	got, err := shapeidx.Stab(lat, lng)
+	require.NoError(t, err)
+	require.Len(t, got.IDsInside, 1)
+
+	// moved to a square around 48 2
+	lb := new(bytes.Buffer)
+	l := insideout.LoopFromCoordinates([]float64{1.9, 47.9, 2.1, 47.9, 2.1, 48.1, 1.9, 48.1, 1.9, 47.9})
+	require.NoError(t, l.Encode(lb))
+	err = shapeidx.Update(&insideout.FeatureStorage{LoopsBytes: [][]byte{lb.Bytes()}}, nil, 0)
+	require.NoError(t, err)
+
+	got, err = shapeidx.Stab(lat, lng)
+	require.NoError(t, err)
+	require.Empty(t, got.IDsInside)
+
+	got, err = shapeidx.Stab(48, 2)
+	require.NoError(t, err)
+	require.Equal(t, []insideout.FeatureIndexResponse{{ID: 0, Pos: 0}}, got.IDsInside)
+
+	shapeidx.Remove(0)
+	got, err = shapeidx.Stab(48, 2)
+	require.NoError(t, err)
+	require.Empty(t, got.IDsInside)
+}
+
 func setup(t *testing.T) (*Index, func()) {
 	logger := log.NewNopLogger()
 
@@ -111,3 +142,16 @@ func setup(t *testing.T) (*Index, func()) {
 		os.Remove(tmpFile.Name())
 	}
 }
+
+func TestShapeIndex_Warmup(t *testing.T) {
+	shapeidx, clean := setup(t)
+	defer clean()
+
+	require.False(t, shapeidx.IsFresh())
+	shapeidx.Warmup()
+	require.True(t, shapeidx.IsFresh())
+
+	got, err := shapeidx.Stab(47.3944602327291, -2.9924373872714556)
+	require.NoError(t, err)
+	require.Len(t, got.IDsInside, 1)
+}
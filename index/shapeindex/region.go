@@ -0,0 +1,306 @@
+package shapeindex
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/golang/geo/s2"
+
+	"github.com/akhenakh/insideout"
+)
+
+// RegionIndex a shape index partitioned by coarse s2 cells, the regions,
+// the shape index of a region is built from the storage by its first query,
+// the least recently queried regions are evicted above MaxVertices
+type RegionIndex struct {
+	storage insideout.Store
+	opts    RegionOptions
+
+	mu sync.Mutex
+	// loops the loops of the features crossing each region, from their outside cover
+	loops map[s2.CellID][]insideout.FeatureIndexResponse
+	// features the regions crossed by each feature
+	features map[uint32][]s2.CellID
+	// regions the built regions, lru the built regions by last query, most recent first
+	regions  map[s2.CellID]*region
+	lru      *list.List
+	vertices int
+}
+
+// RegionOptions for the RegionIndex
+type RegionOptions struct {
+	// Level the s2 level of the regions, it can't exceed the min cover level of the index,
+	// level 3 regions are about 1000km wide
+	Level int
+
+	// MaxVertices the max count of vertices held by the built regions, 0 for no limit,
+	// a loop crossing several regions is counted in each of them
+	MaxVertices int
+}
+
+// region the shape index of the loops crossing a region
+type region struct {
+	id   s2.CellID
+	elem *list.Element
+
+	// ready is closed once built
+	ready    chan struct{}
+	err      error
+	vertices int
+
+	mu sync.Mutex
+	*s2.ContainsPointQuery
+	// holes the holes of the loops of the region
+	holes map[insideout.FeatureIndexResponse][]*s2.Loop
+}
+
+// NewRegionIndex returns a RegionIndex reading the features from storage
+func NewRegionIndex(storage insideout.Store, opts RegionOptions) *RegionIndex {
+	return &RegionIndex{
+		storage:  storage,
+		opts:     opts,
+		loops:    make(map[s2.CellID][]insideout.FeatureIndexResponse),
+		features: make(map[uint32][]s2.CellID),
+		regions:  make(map[s2.CellID]*region),
+		lru:      list.New(),
+	}
+}
+
+// Load reads the regions crossed by the features from their cells in storage, no feature is loaded
+func (idx *RegionIndex) Load() error {
+	infos, err := idx.storage.LoadIndexInfos()
+	if err != nil {
+		return err
+	}
+	if idx.opts.Level < 0 || idx.opts.Level > infos.MinCoverLevel {
+		return fmt.Errorf("invalid region level %d, the min cover level of the index is %d",
+			idx.opts.Level, infos.MinCoverLevel)
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if err := idx.storage.LoadFeaturesCells(idx.add); err != nil {
+		return fmt.Errorf("failed to load cells from storage: %w", err)
+	}
+	return nil
+}
+
+// add records the regions crossed by the loops of the feature id, idx.mu must be held
+func (idx *RegionIndex) add(cellsIn []s2.CellUnion, cellsOut []s2.CellUnion, id uint32) {
+	for i, cu := range cellsOut {
+		seen := make(map[s2.CellID]bool)
+		for _, c := range cu {
+			rid := c.Parent(idx.opts.Level)
+			if seen[rid] {
+				continue
+			}
+			seen[rid] = true
+			if len(idx.loops[rid]) == 0 || idx.loops[rid][len(idx.loops[rid])-1].ID != id {
+				idx.features[id] = append(idx.features[id], rid)
+			}
+			idx.loops[rid] = append(idx.loops[rid], insideout.FeatureIndexResponse{ID: id, Pos: uint16(i)})
+		}
+	}
+}
+
+// Update replaces the loops of the feature id, the storage must already hold it,
+// the regions it crossed and crosses are rebuilt by their next query
+func (idx *RegionIndex) Update(fs *insideout.FeatureStorage, cs *insideout.CellsStorage, id uint32) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.remove(id)
+	idx.add(cs.CellsIn, cs.CellsOut, id)
+	for _, rid := range idx.features[id] {
+		idx.evict(rid)
+	}
+	return nil
+}
+
+// Remove removes the loops of the feature id, the regions it crossed are rebuilt by their next query
+func (idx *RegionIndex) Remove(id uint32) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.remove(id)
+}
+
+// remove removes the feature id from its regions and evicts them, idx.mu must be held
+func (idx *RegionIndex) remove(id uint32) {
+	for _, rid := range idx.features[id] {
+		loops := idx.loops[rid][:0]
+		for _, l := range idx.loops[rid] {
+			if l.ID != id {
+				loops = append(loops, l)
+			}
+		}
+		if len(loops) == 0 {
+			delete(idx.loops, rid)
+		} else {
+			idx.loops[rid] = loops
+		}
+		idx.evict(rid)
+	}
+	delete(idx.features, id)
+}
+
+// evict forgets the built region rid, idx.mu must be held
+func (idx *RegionIndex) evict(rid s2.CellID) {
+	r, ok := idx.regions[rid]
+	if !ok {
+		return
+	}
+	delete(idx.regions, rid)
+	idx.lru.Remove(r.elem)
+	// the regions being built are not counted yet
+	if isClosed(r.ready) {
+		idx.vertices -= r.vertices
+	}
+}
+
+// Regions returns the count of built regions and the vertices they hold
+func (idx *RegionIndex) Regions() (int, int) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	return len(idx.regions), idx.vertices
+}
+
+// Stab returns polygon's ids we are inside, the region of the point is built if needed
+func (idx *RegionIndex) Stab(lat, lng float64) (insideout.IndexResponse, error) {
+	return idx.StabContext(context.Background(), lat, lng)
+}
+
+// StabContext is Stab returning ctx.Err() once ctx is done while the region is built,
+// the build goes on for the next queries
+func (idx *RegionIndex) StabContext(ctx context.Context, lat, lng float64) (insideout.IndexResponse, error) {
+	var idxResp insideout.IndexResponse
+
+	p := s2.PointFromLatLng(s2.LatLngFromDegrees(lat, lng))
+	r, err := idx.region(ctx, s2.CellFromPoint(p).ID().Parent(idx.opts.Level))
+	if err != nil || r == nil {
+		return idxResp, err
+	}
+
+	r.mu.Lock()
+	shapes := r.ContainingShapes(p)
+	r.mu.Unlock()
+
+	for _, shape := range shapes {
+		il := shape.(indexedLoop)
+		if inHole(r.holes[il.FeatureIndexResponse], p) {
+			continue
+		}
+		idxResp.IDsInside = append(idxResp.IDsInside, il.FeatureIndexResponse)
+	}
+	return idxResp, nil
+}
+
+// region returns the built region rid, nil when no feature crosses it,
+// concurrent queries of a region being built wait for it until ctx is done
+func (idx *RegionIndex) region(ctx context.Context, rid s2.CellID) (*region, error) {
+	idx.mu.Lock()
+	if r, ok := idx.regions[rid]; ok {
+		idx.lru.MoveToFront(r.elem)
+		idx.mu.Unlock()
+		return r.wait(ctx)
+	}
+
+	loops := idx.loops[rid]
+	if len(loops) == 0 {
+		idx.mu.Unlock()
+		return nil, nil
+	}
+	loops = append([]insideout.FeatureIndexResponse(nil), loops...)
+	r := &region{id: rid, ready: make(chan struct{})}
+	r.elem = idx.lru.PushFront(r)
+	idx.regions[rid] = r
+	idx.mu.Unlock()
+
+	// not tied to ctx, the region is kept for the next queries
+	go func() {
+		r.err = idx.build(r, loops)
+
+		idx.mu.Lock()
+		if r.err != nil {
+			if idx.regions[rid] == r {
+				delete(idx.regions, rid)
+				idx.lru.Remove(r.elem)
+			}
+		} else if idx.regions[rid] == r {
+			idx.vertices += r.vertices
+			idx.shrink(r)
+		}
+		close(r.ready)
+		idx.mu.Unlock()
+	}()
+
+	return r.wait(ctx)
+}
+
+// wait returns r once built, or ctx.Err() once ctx is done
+func (r *region) wait(ctx context.Context) (*region, error) {
+	select {
+	case <-r.ready:
+		return r, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// build fills the shape index of r with loops read from the storage
+func (idx *RegionIndex) build(r *region, loops []insideout.FeatureIndexResponse) error {
+	si := s2.NewShapeIndex()
+	// the loops of a feature are contiguous
+	var f *insideout.Feature
+	var fid uint32
+	for _, l := range loops {
+		if f == nil || l.ID != fid {
+			var err error
+			if f, err = idx.storage.LoadFeature(l.ID); err != nil {
+				return fmt.Errorf("failed to load feature %d of region %s: %w", l.ID, r.id.ToToken(), err)
+			}
+			fid = l.ID
+		}
+		if int(l.Pos) >= len(f.Loops) {
+			return fmt.Errorf("invalid loop %d of feature %d", l.Pos, l.ID)
+		}
+		si.Add(indexedLoop{Loop: f.Loops[l.Pos], FeatureIndexResponse: l})
+		r.vertices += f.Loops[l.Pos].NumVertices()
+		if int(l.Pos) < len(f.Holes) && len(f.Holes[l.Pos]) > 0 {
+			if r.holes == nil {
+				r.holes = make(map[insideout.FeatureIndexResponse][]*s2.Loop)
+			}
+			r.holes[l] = f.Holes[l.Pos]
+			for _, h := range f.Holes[l.Pos] {
+				r.vertices += h.NumVertices()
+			}
+		}
+	}
+	// applies the added loops
+	r.ContainsPointQuery = s2.NewContainsPointQuery(si, s2.VertexModelOpen)
+	return nil
+}
+
+// shrink evicts the least recently queried regions above MaxVertices, except keep, idx.mu must be held
+func (idx *RegionIndex) shrink(keep *region) {
+	if idx.opts.MaxVertices <= 0 {
+		return
+	}
+	for e := idx.lru.Back(); e != nil && idx.vertices > idx.opts.MaxVertices; {
+		prev := e.Prev()
+		if r := e.Value.(*region); r != keep && isClosed(r.ready) {
+			idx.evict(r.id)
+		}
+		e = prev
+	}
+}
+
+// isClosed returns true if c is closed
+func isClosed(c chan struct{}) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
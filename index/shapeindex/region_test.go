@@ -0,0 +1,138 @@
+package shapeindex
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/require"
+	geom "github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/geojson"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/storage/bbolt"
+)
+
+func square(lng, lat float64) *geojson.Feature {
+	return &geojson.Feature{
+		Geometry: geom.NewPolygonFlat(geom.XY, []float64{
+			lng, lat, lng + 0.1, lat, lng + 0.1, lat + 0.1, lng, lat + 0.1, lng, lat,
+		}, []int{10}),
+		Properties: map[string]interface{}{},
+	}
+}
+
+// setupSquares returns a storage holding 4 squares far apart, in 4 regions
+func setupSquares(t *testing.T) (*bbolt.Storage, func()) {
+	logger := log.NewNopLogger()
+
+	tmpFile, err := ioutil.TempFile(os.TempDir(), "insideout-test-")
+	require.NoError(t, err)
+	wstorage, wclose, err := bbolt.NewStorage(tmpFile.Name(), logger)
+	require.NoError(t, err)
+
+	fc := geojson.FeatureCollection{Features: []*geojson.Feature{
+		square(0, 0), square(40, 0), square(0, 40), square(40, 40),
+	}}
+	icoverer := &s2.RegionCoverer{MinLevel: 10, MaxLevel: 16, MaxCells: 24}
+	ocoverer := &s2.RegionCoverer{MinLevel: 10, MaxLevel: 15, MaxCells: 16}
+	require.NoError(t, wstorage.Index(fc, icoverer, ocoverer, 100, "squares.geojson", "unittest"))
+	require.NoError(t, wclose())
+
+	storage, close, err := bbolt.NewRWStorage(tmpFile.Name(), logger)
+	require.NoError(t, err)
+	return storage, func() {
+		close()
+		os.Remove(tmpFile.Name())
+	}
+}
+
+func TestRegionIndex_Stab(t *testing.T) {
+	storage, clean := setupSquares(t)
+	defer clean()
+
+	require.Error(t, NewRegionIndex(storage, RegionOptions{Level: 11}).Load())
+
+	idx := NewRegionIndex(storage, RegionOptions{Level: 3, MaxVertices: 8})
+	require.NoError(t, idx.Load())
+	count, vertices := idx.Regions()
+	require.Zero(t, count)
+	require.Zero(t, vertices)
+
+	for i, p := range [][2]float64{{0.05, 0.05}, {0.05, 40.05}, {40.05, 0.05}, {40.05, 40.05}} {
+		got, err := idx.Stab(p[0], p[1])
+		require.NoError(t, err)
+		require.Equal(t, []insideout.FeatureIndexResponse{{ID: uint32(i)}}, got.IDsInside)
+	}
+	// the least recently queried regions are evicted
+	count, vertices = idx.Regions()
+	require.Equal(t, 2, count)
+	require.Equal(t, 8, vertices)
+
+	// no feature in the region
+	got, err := idx.Stab(-40, -40)
+	require.NoError(t, err)
+	require.Empty(t, got.IDsInside)
+	got, err = idx.Stab(0.15, 0.15)
+	require.NoError(t, err)
+	require.Empty(t, got.IDsInside)
+
+	// feature 0 moved from 0 0 to 40 40, the storage is written first
+	ok, err := storage.WriteFeature(square(40.2, 40), 0)
+	require.NoError(t, err)
+	require.True(t, ok)
+	cs, err := storage.LoadCellStorage(0)
+	require.NoError(t, err)
+	require.NoError(t, idx.Update(nil, cs, 0))
+
+	got, err = idx.Stab(0.05, 0.05)
+	require.NoError(t, err)
+	require.Empty(t, got.IDsInside)
+	got, err = idx.Stab(40.05, 40.25)
+	require.NoError(t, err)
+	require.Equal(t, []insideout.FeatureIndexResponse{{ID: 0}}, got.IDsInside)
+
+	idx.Remove(0)
+	got, err = idx.Stab(40.05, 40.25)
+	require.NoError(t, err)
+	require.Empty(t, got.IDsInside)
+	got, err = idx.Stab(40.05, 40.05)
+	require.NoError(t, err)
+	require.Equal(t, []insideout.FeatureIndexResponse{{ID: 3}}, got.IDsInside)
+}
+
+// blockingStore a Store loading the features once unblocked
+type blockingStore struct {
+	insideout.Store
+	unblock chan struct{}
+}
+
+func (s *blockingStore) LoadFeature(id uint32) (*insideout.Feature, error) {
+	<-s.unblock
+	return s.Store.LoadFeature(id)
+}
+
+func TestRegionIndex_StabContext(t *testing.T) {
+	storage, clean := setupSquares(t)
+	defer clean()
+
+	bs := &blockingStore{Store: storage, unblock: make(chan struct{})}
+	idx := NewRegionIndex(bs, RegionOptions{Level: 3})
+	require.NoError(t, idx.Load())
+
+	// the query gives up while the region is built
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := idx.StabContext(ctx, 0.05, 0.05)
+	require.Equal(t, context.DeadlineExceeded, err)
+
+	// the build goes on for the next queries
+	close(bs.unblock)
+	got, err := idx.Stab(0.05, 0.05)
+	require.NoError(t, err)
+	require.Equal(t, []insideout.FeatureIndexResponse{{ID: 0}}, got.IDsInside)
+}
@@ -1,6 +1,9 @@
 package treeindex
 
 import (
+	"context"
+	"sync/atomic"
+
 	"github.com/akhenakh/insidetree"
 	"github.com/golang/geo/s2"
 
@@ -13,6 +16,18 @@ type Index struct {
 	otree *insidetree.Tree
 
 	opts Options
+	// stop is opts.StopOnInsideFound, changed at runtime by SetStopOnInsideFound
+	stop int32
+
+	// gens the generation of the features updated or removed since loaded,
+	// the tree can't remove cells, the ones indexed by a previous generation are skipped
+	gens map[uint32]uint32
+}
+
+// entry a cell indexed in the tree
+type entry struct {
+	insideout.FeatureIndexResponse
+	gen uint32
 }
 
 // Options for the insidetree Index
@@ -23,56 +38,99 @@ type Options struct {
 }
 
 func New(opts Options) *Index {
-	return &Index{
+	idx := &Index{
 		itree: insidetree.NewTree(),
 		otree: insidetree.NewTree(),
 		opts:  opts,
+		gens:  make(map[uint32]uint32),
+	}
+	idx.SetStopOnInsideFound(opts.StopOnInsideFound)
+	return idx
+}
+
+// SetStopOnInsideFound changes the StopOnInsideFound option, safe to call while the index is queried
+func (idx *Index) SetStopOnInsideFound(stop bool) {
+	var v int32
+	if stop {
+		v = 1
 	}
+	atomic.StoreInt32(&idx.stop, v)
+}
+
+func (idx *Index) stopOnInsideFound() bool {
+	return atomic.LoadInt32(&idx.stop) == 1
 }
 
 func (idx *Index) Add(cellsIn []s2.CellUnion, cellsOut []s2.CellUnion, id uint32) {
+	gen := idx.gens[id]
 	for i, cu := range cellsIn {
 		for _, c := range cu {
-			idx.itree.Index(c, insideout.FeatureIndexResponse{
+			idx.itree.Index(c, entry{FeatureIndexResponse: insideout.FeatureIndexResponse{
 				ID:  id,
 				Pos: uint16(i),
-			})
+			}, gen: gen})
 		}
 	}
 	for i, cu := range cellsOut {
 		for _, c := range cu {
-			idx.otree.Index(c, insideout.FeatureIndexResponse{
+			idx.otree.Index(c, entry{FeatureIndexResponse: insideout.FeatureIndexResponse{
 				ID:  id,
 				Pos: uint16(i),
-			})
+			}, gen: gen})
 		}
 	}
 }
 
+// Update replaces the cells of the feature id, not safe to call while the index is queried
+func (idx *Index) Update(fs *insideout.FeatureStorage, cs *insideout.CellsStorage, id uint32) error {
+	idx.Remove(id)
+	idx.Add(cs.CellsIn, cs.CellsOut, id)
+	return nil
+}
+
+// Remove hides the cells of the feature id, not safe to call while the index is queried
+func (idx *Index) Remove(id uint32) {
+	idx.gens[id]++
+}
+
+// current returns the feature of e, false if it was indexed by a previous generation
+func (idx *Index) current(e interface{}) (insideout.FeatureIndexResponse, bool) {
+	en := e.(entry)
+	return en.FeatureIndexResponse, en.gen == idx.gens[en.ID]
+}
+
 // Stab returns polygon's ids containing lat lng and polygon's ids that may be
 func (idx *Index) Stab(lat, lng float64) (insideout.IndexResponse, error) {
+	return idx.StabContext(context.Background(), lat, lng)
+}
+
+// StabContext is Stab ignoring StopOnInsideFound when ctx was returned by insideout.WithoutStop
+func (idx *Index) StabContext(ctx context.Context, lat, lng float64) (insideout.IndexResponse, error) {
 	var idxResp insideout.IndexResponse
 
 	p := s2.PointFromLatLng(s2.LatLngFromDegrees(lat, lng))
 
 	c := s2.CellFromPoint(p).ID()
-	res := idx.itree.Stab(c)
-	for _, r := range res {
-		fres := r.(insideout.FeatureIndexResponse)
-		idxResp.IDsInside = append(idxResp.IDsInside, fres)
+	for _, r := range idx.itree.Stab(c) {
+		if fres, ok := idx.current(r); ok {
+			idxResp.IDsInside = append(idxResp.IDsInside, fres)
+		}
 	}
 
-	if idx.opts.StopOnInsideFound && len(res) > 0 {
+	if idx.stopOnInsideFound() && insideout.StopAllowed(ctx) && len(idxResp.IDsInside) > 0 {
 		return idxResp, nil
 	}
 
-	res = idx.otree.Stab(c)
+	res := idx.otree.Stab(c)
 	if len(res) == 0 {
 		return idxResp, nil
 	}
 
 	for _, r := range res {
-		fres := r.(insideout.FeatureIndexResponse)
+		fres, ok := idx.current(r)
+		if !ok {
+			continue
+		}
 		// remove any answer matching inside
 		found := false
 		for _, ires := range idxResp.IDsInside {
@@ -85,6 +85,35 @@ func TestTreeIndex_Stab(t *testing.T) {
 	}
 }
 
+func TestTreeIndex_Update(t *testing.T) {
+	treeidx, clean := setup(t)
+	defer clean()
+
+	lat, lng := 47.39650628189986, -2.9876390969486524
+	got, err := treeidx.Stab(lat, lng)
+	require.NoError(t, err)
+	require.Len(t, got.IDsInside, 1)
+
+	// moved to another cell
+	c := s2.CellIDFromLatLng(s2.LatLngFromDegrees(48, 2)).Parent(15)
+	err = treeidx.Update(nil, &insideout.CellsStorage{CellsIn: []s2.CellUnion{{c}}}, 0)
+	require.NoError(t, err)
+
+	got, err = treeidx.Stab(lat, lng)
+	require.NoError(t, err)
+	require.Empty(t, got.IDsInside)
+	require.Empty(t, got.IDsMayBeInside)
+
+	got, err = treeidx.Stab(48, 2)
+	require.NoError(t, err)
+	require.Equal(t, []insideout.FeatureIndexResponse{{ID: 0, Pos: 0}}, got.IDsInside)
+
+	treeidx.Remove(0)
+	got, err = treeidx.Stab(48, 2)
+	require.NoError(t, err)
+	require.Empty(t, got.IDsInside)
+}
+
 func setup(t *testing.T) (*Index, func()) {
 	logger := log.NewLogfmtLogger(os.Stdout)
 
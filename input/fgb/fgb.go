@@ -0,0 +1,398 @@
+// Package fgb streams features from and to FlatGeobuf files
+package fgb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+
+	flatbuffers "github.com/google/flatbuffers/go"
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/geojson"
+
+	"github.com/akhenakh/insideout/crs"
+)
+
+// WGS84Code the EPSG code of WGS84, features using it are not reprojected
+const WGS84Code = 4326
+
+var magic = []byte{'f', 'g', 'b', 3, 'f', 'g', 'b', 0}
+
+// maxFeatureSize protects against corrupted size prefixes
+const maxFeatureSize = 1 << 30
+
+// geometry types
+const (
+	geometryUnknown      = 0
+	geometryPolygon      = 3
+	geometryMultiPolygon = 6
+)
+
+// column types
+const (
+	columnByte = iota
+	columnUByte
+	columnBool
+	columnShort
+	columnUShort
+	columnInt
+	columnUInt
+	columnLong
+	columnULong
+	columnFloat
+	columnDouble
+	columnString
+	columnJSON
+	columnDateTime
+	columnBinary
+)
+
+type column struct {
+	name  string
+	ctype uint8
+}
+
+// Reader reads the features of a FlatGeobuf file one at a time,
+// only the current feature is kept in memory
+type Reader struct {
+	f    *os.File
+	r    *bufio.Reader
+	buf  []byte
+	proj crs.Projection
+
+	geometryType uint8
+	columns      []column
+	count        uint64
+}
+
+// Open opens the FlatGeobuf file at path and reads its header
+func Open(path string) (*Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't open FlatGeobuf %s: %w", path, err)
+	}
+
+	r := &Reader{f: f, r: bufio.NewReaderSize(f, 1<<20)}
+	if err := r.readHeader(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("can't read FlatGeobuf %s header: %w", path, err)
+	}
+
+	return r, nil
+}
+
+// Close closes the underlying file
+func (r *Reader) Close() error {
+	return r.f.Close()
+}
+
+// FeaturesCount returns the number of features as stated in the header, 0 if unknown
+func (r *Reader) FeaturesCount() uint64 {
+	return r.count
+}
+
+func (r *Reader) readHeader() error {
+	m := make([]byte, len(magic))
+	if _, err := io.ReadFull(r.r, m); err != nil {
+		return err
+	}
+	// only the major version is checked
+	if !bytes.Equal(m[:3], magic[:3]) || m[3] != magic[3] {
+		return errors.New("not a FlatGeobuf v3 file")
+	}
+
+	b, err := r.readSizePrefixed()
+	if err != nil {
+		return err
+	}
+
+	h := rootTable(b)
+	r.geometryType = h.GetUint8Slot(slot(2), geometryUnknown)
+	if h.GetBoolSlot(slot(3), false) || h.GetBoolSlot(slot(4), false) {
+		return errors.New("geometries with Z or M values are not supported")
+	}
+	r.count = h.GetUint64Slot(slot(8), 0)
+	indexNodeSize := h.GetUint16Slot(slot(9), 16)
+
+	for i, n := 0, vectorLen(h, 7); i < n; i++ {
+		c := vectorTable(h, 7, i)
+		r.columns = append(r.columns, column{
+			name:  stringField(c, 0),
+			ctype: c.GetUint8Slot(slot(1), 0),
+		})
+	}
+
+	if c, ok := subTable(h, 10); ok {
+		code := c.GetInt32Slot(slot(1), 0)
+		wkt := stringField(c, 4)
+		switch {
+		case wkt != "" && code != WGS84Code:
+			if r.proj, err = crs.FromWKT(wkt); err != nil {
+				return err
+			}
+		case code != 0 && code != WGS84Code:
+			if r.proj, err = crs.FromEPSG(int(code)); err != nil {
+				return err
+			}
+		}
+	}
+
+	// skip the spatial index, features are read sequentially
+	if indexNodeSize > 0 && r.count > 0 {
+		if _, err := io.CopyN(ioutil.Discard, r.r, treeSize(r.count, uint64(indexNodeSize))); err != nil {
+			return fmt.Errorf("can't skip spatial index: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Read returns the next feature, io.EOF at the end of the file
+func (r *Reader) Read() (*geojson.Feature, error) {
+	b, err := r.readSizePrefixed()
+	if err != nil {
+		return nil, err
+	}
+
+	ft := rootTable(b)
+	f := &geojson.Feature{Properties: make(map[string]interface{}, len(r.columns))}
+
+	if gt, ok := subTable(ft, 0); ok {
+		g, err := r.decodeGeometry(gt, r.geometryType)
+		if err != nil {
+			return nil, err
+		}
+		f.Geometry = g
+	}
+
+	columns := r.columns
+	if n := vectorLen(ft, 2); n > 0 {
+		// per feature schema
+		columns = make([]column, n)
+		for i := range columns {
+			c := vectorTable(ft, 2, i)
+			columns[i] = column{name: stringField(c, 0), ctype: c.GetUint8Slot(slot(1), 0)}
+		}
+	}
+	if err := decodeProperties(byteVector(ft, 1), columns, f.Properties); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// readSizePrefixed reads a little endian uint32 size followed by a flatbuffer, reusing the same buffer
+func (r *Reader) readSizePrefixed() ([]byte, error) {
+	var size uint32
+	if err := binary.Read(r.r, binary.LittleEndian, &size); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, errors.New("truncated FlatGeobuf file")
+		}
+		return nil, err
+	}
+	if size > maxFeatureSize {
+		return nil, fmt.Errorf("invalid FlatGeobuf buffer size %d", size)
+	}
+	if cap(r.buf) < int(size) {
+		r.buf = make([]byte, size)
+	}
+	r.buf = r.buf[:size]
+	if _, err := io.ReadFull(r.r, r.buf); err != nil {
+		return nil, errors.New("truncated FlatGeobuf file")
+	}
+	return r.buf, nil
+}
+
+// decodeGeometry converts a FlatGeobuf geometry to a Polygon or MultiPolygon
+func (r *Reader) decodeGeometry(t *flatbuffers.Table, gtype uint8) (geom.T, error) {
+	if gtype == geometryUnknown {
+		gtype = t.GetUint8Slot(slot(6), geometryUnknown)
+	}
+
+	switch gtype {
+	case geometryPolygon:
+		return r.decodePolygon(t), nil
+	case geometryMultiPolygon:
+		mp := geom.NewMultiPolygon(geom.XY)
+		for i, n := 0, vectorLen(t, 7); i < n; i++ {
+			if err := mp.Push(r.decodePolygon(vectorTable(t, 7, i))); err != nil {
+				return nil, err
+			}
+		}
+		return mp, nil
+	default:
+		return nil, fmt.Errorf("unsupported FlatGeobuf geometry type %d, only polygons are supported", gtype)
+	}
+}
+
+func (r *Reader) decodePolygon(t *flatbuffers.Table) *geom.Polygon {
+	n := vectorLen(t, 1)
+	coords := make([]float64, n)
+	start := vectorStart(t, 1)
+	for i := range coords {
+		coords[i] = t.GetFloat64(start + flatbuffers.UOffsetT(i*8))
+	}
+	if r.proj != nil {
+		for i := 0; i+1 < len(coords); i += 2 {
+			coords[i], coords[i+1] = r.proj.ToWGS84(coords[i], coords[i+1])
+		}
+	}
+
+	// ends are expressed in points
+	var ends []int
+	if m := vectorLen(t, 0); m > 0 {
+		es := vectorStart(t, 0)
+		ends = make([]int, m)
+		for i := range ends {
+			ends[i] = 2 * int(t.GetUint32(es+flatbuffers.UOffsetT(i*4)))
+		}
+	} else {
+		ends = []int{len(coords)}
+	}
+
+	return geom.NewPolygonFlat(geom.XY, coords, ends)
+}
+
+// decodeProperties decodes the properties buffer: a column index followed by its value
+func decodeProperties(b []byte, columns []column, props map[string]interface{}) error {
+	for len(b) > 0 {
+		if len(b) < 2 {
+			return errors.New("invalid FlatGeobuf properties")
+		}
+		ci := int(binary.LittleEndian.Uint16(b))
+		b = b[2:]
+		if ci >= len(columns) {
+			return fmt.Errorf("invalid FlatGeobuf column index %d", ci)
+		}
+		c := columns[ci]
+
+		var size int
+		switch c.ctype {
+		case columnByte, columnUByte, columnBool:
+			size = 1
+		case columnShort, columnUShort:
+			size = 2
+		case columnInt, columnUInt, columnFloat:
+			size = 4
+		case columnLong, columnULong, columnDouble:
+			size = 8
+		case columnString, columnJSON, columnDateTime, columnBinary:
+			if len(b) < 4 {
+				return errors.New("invalid FlatGeobuf properties")
+			}
+			size = int(binary.LittleEndian.Uint32(b))
+			b = b[4:]
+		default:
+			return fmt.Errorf("unsupported FlatGeobuf column type %d", c.ctype)
+		}
+		if len(b) < size {
+			return errors.New("invalid FlatGeobuf properties")
+		}
+		v := b[:size]
+		b = b[size:]
+
+		switch c.ctype {
+		case columnByte:
+			props[c.name] = float64(int8(v[0]))
+		case columnUByte:
+			props[c.name] = float64(v[0])
+		case columnBool:
+			props[c.name] = v[0] != 0
+		case columnShort:
+			props[c.name] = float64(int16(binary.LittleEndian.Uint16(v)))
+		case columnUShort:
+			props[c.name] = float64(binary.LittleEndian.Uint16(v))
+		case columnInt:
+			props[c.name] = float64(int32(binary.LittleEndian.Uint32(v)))
+		case columnUInt:
+			props[c.name] = float64(binary.LittleEndian.Uint32(v))
+		case columnLong:
+			props[c.name] = float64(int64(binary.LittleEndian.Uint64(v)))
+		case columnULong:
+			props[c.name] = float64(binary.LittleEndian.Uint64(v))
+		case columnFloat:
+			props[c.name] = float64(math.Float32frombits(binary.LittleEndian.Uint32(v)))
+		case columnDouble:
+			props[c.name] = math.Float64frombits(binary.LittleEndian.Uint64(v))
+		case columnBinary:
+			// binary values are not representable in GeoJSON properties
+		default:
+			props[c.name] = string(v)
+		}
+	}
+	return nil
+}
+
+// treeSize returns the size in bytes of the packed Hilbert R-tree
+func treeSize(count, nodeSize uint64) int64 {
+	const nodeItemSize = 40
+	if nodeSize < 2 {
+		nodeSize = 2
+	}
+	n := count
+	nodes := n
+	for {
+		n = (n + nodeSize - 1) / nodeSize
+		nodes += n
+		if n <= 1 {
+			break
+		}
+	}
+	return int64(nodes * nodeItemSize)
+}
+
+func slot(i int) flatbuffers.VOffsetT {
+	return flatbuffers.VOffsetT(4 + 2*i)
+}
+
+func rootTable(b []byte) *flatbuffers.Table {
+	return &flatbuffers.Table{Bytes: b, Pos: flatbuffers.GetUOffsetT(b)}
+}
+
+func subTable(t *flatbuffers.Table, i int) (*flatbuffers.Table, bool) {
+	o := flatbuffers.UOffsetT(t.Offset(slot(i)))
+	if o == 0 {
+		return nil, false
+	}
+	return &flatbuffers.Table{Bytes: t.Bytes, Pos: t.Indirect(o + t.Pos)}, true
+}
+
+func stringField(t *flatbuffers.Table, i int) string {
+	o := flatbuffers.UOffsetT(t.Offset(slot(i)))
+	if o == 0 {
+		return ""
+	}
+	// copied, the buffer is reused for the next features
+	return string(t.ByteVector(o + t.Pos))
+}
+
+func byteVector(t *flatbuffers.Table, i int) []byte {
+	o := flatbuffers.UOffsetT(t.Offset(slot(i)))
+	if o == 0 {
+		return nil
+	}
+	return t.ByteVector(o + t.Pos)
+}
+
+func vectorLen(t *flatbuffers.Table, i int) int {
+	o := flatbuffers.UOffsetT(t.Offset(slot(i)))
+	if o == 0 {
+		return 0
+	}
+	return t.VectorLen(o)
+}
+
+func vectorStart(t *flatbuffers.Table, i int) flatbuffers.UOffsetT {
+	return t.Vector(flatbuffers.UOffsetT(t.Offset(slot(i))))
+}
+
+func vectorTable(t *flatbuffers.Table, i, j int) *flatbuffers.Table {
+	x := vectorStart(t, i) + flatbuffers.UOffsetT(j*4)
+	return &flatbuffers.Table{Bytes: t.Bytes, Pos: t.Indirect(x)}
+}
@@ -0,0 +1,171 @@
+package fgb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	flatbuffers "github.com/google/flatbuffers/go"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom"
+)
+
+func TestReader(t *testing.T) {
+	path, clean := setup(t)
+	defer clean()
+
+	r, err := Open(path)
+	require.NoError(t, err)
+	defer r.Close()
+	require.Equal(t, uint64(2), r.FeaturesCount())
+
+	f, err := r.Read()
+	require.NoError(t, err)
+	require.Equal(t, "Bretagne", f.Properties["name"])
+	require.Equal(t, float64(4), f.Properties["admin_level"])
+	p, ok := f.Geometry.(*geom.Polygon)
+	require.True(t, ok)
+	require.Equal(t, []float64{-3, 47, -2, 47, -2, 48, -3, 48, -3, 47}, p.FlatCoords())
+
+	f, err = r.Read()
+	require.NoError(t, err)
+	require.Equal(t, "Islands", f.Properties["name"])
+	mp, ok := f.Geometry.(*geom.MultiPolygon)
+	require.True(t, ok)
+	require.Equal(t, 2, mp.NumPolygons())
+	require.Equal(t, 2, mp.Polygon(1).NumLinearRings())
+
+	_, err = r.Read()
+	require.Equal(t, io.EOF, err)
+}
+
+func TestOpenInvalid(t *testing.T) {
+	tmpFile, err := ioutil.TempFile(os.TempDir(), "insideout-test-*.fgb")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+	_, err = tmpFile.WriteString(`{"type": "FeatureCollection"}`)
+	require.NoError(t, err)
+	tmpFile.Close()
+
+	_, err = Open(tmpFile.Name())
+	require.Error(t, err)
+}
+
+// setup writes a FlatGeobuf file with a spatial index, a polygon and a multipolygon with a hole
+func setup(t *testing.T) (string, func()) {
+	b := flatbuffers.NewBuilder(1024)
+	var buf bytes.Buffer
+	buf.Write(magic)
+
+	// header
+	cname := b.CreateString("name")
+	b.StartObject(11)
+	b.PrependUOffsetTSlot(0, cname, 0)
+	b.PrependUint8Slot(1, columnString, 0)
+	c0 := b.EndObject()
+	clevel := b.CreateString("admin_level")
+	b.StartObject(11)
+	b.PrependUOffsetTSlot(0, clevel, 0)
+	b.PrependUint8Slot(1, columnInt, 0)
+	c1 := b.EndObject()
+	b.StartVector(4, 2, 4)
+	b.PrependUOffsetT(c1)
+	b.PrependUOffsetT(c0)
+	cols := b.EndVector(2)
+	b.StartObject(14)
+	b.PrependUOffsetTSlot(7, cols, 0)
+	b.PrependUint64Slot(8, 2, 0)
+	b.Finish(b.EndObject())
+	writeSizePrefixed(&buf, b.FinishedBytes())
+
+	// fake spatial index, skipped by the reader
+	buf.Write(make([]byte, treeSize(2, 16)))
+
+	writeSizePrefixed(&buf, buildFeature(b, "Bretagne", 4, geometryPolygon,
+		[][]float64{{-3, 47, -2, 47, -2, 48, -3, 48, -3, 47}}))
+	writeSizePrefixed(&buf, buildFeature(b, "Islands", 8, geometryMultiPolygon,
+		[][]float64{{0, 0, 1, 0, 1, 1, 0, 0}},
+		[][]float64{{10, 0, 13, 0, 13, 3, 10, 3, 10, 0}, {11, 1, 11, 2, 12, 2, 12, 1, 11, 1}}))
+
+	tmpFile, err := ioutil.TempFile(os.TempDir(), "insideout-test-*.fgb")
+	require.NoError(t, err)
+	_, err = tmpFile.Write(buf.Bytes())
+	require.NoError(t, err)
+	tmpFile.Close()
+
+	return tmpFile.Name(), func() {
+		os.Remove(tmpFile.Name())
+	}
+}
+
+func writeSizePrefixed(w *bytes.Buffer, b []byte) {
+	binary.Write(w, binary.LittleEndian, uint32(len(b)))
+	w.Write(b)
+}
+
+// buildFeature encodes a feature, polygons are a list of rings
+func buildFeature(b *flatbuffers.Builder, name string, level int32, gtype uint8, polygons ...[][]float64) []byte {
+	b.Reset()
+
+	polygon := func(rings [][]float64) flatbuffers.UOffsetT {
+		var xy []float64
+		var ends []uint32
+		for _, r := range rings {
+			xy = append(xy, r...)
+			ends = append(ends, uint32(len(xy)/2))
+		}
+		b.StartVector(8, len(xy), 8)
+		for i := len(xy) - 1; i >= 0; i-- {
+			b.PrependFloat64(xy[i])
+		}
+		xyv := b.EndVector(len(xy))
+		b.StartVector(4, len(ends), 4)
+		for i := len(ends) - 1; i >= 0; i-- {
+			b.PrependUint32(ends[i])
+		}
+		endsv := b.EndVector(len(ends))
+		b.StartObject(8)
+		b.PrependUOffsetTSlot(0, endsv, 0)
+		b.PrependUOffsetTSlot(1, xyv, 0)
+		b.PrependUint8Slot(6, geometryPolygon, 0)
+		return b.EndObject()
+	}
+
+	var g flatbuffers.UOffsetT
+	if gtype == geometryPolygon {
+		g = polygon(polygons[0])
+	} else {
+		parts := make([]flatbuffers.UOffsetT, len(polygons))
+		for i, p := range polygons {
+			parts[i] = polygon(p)
+		}
+		b.StartVector(4, len(parts), 4)
+		for i := len(parts) - 1; i >= 0; i-- {
+			b.PrependUOffsetT(parts[i])
+		}
+		partsv := b.EndVector(len(parts))
+		b.StartObject(8)
+		b.PrependUint8Slot(6, gtype, 0)
+		b.PrependUOffsetTSlot(7, partsv, 0)
+		g = b.EndObject()
+	}
+
+	var props bytes.Buffer
+	binary.Write(&props, binary.LittleEndian, uint16(0))
+	binary.Write(&props, binary.LittleEndian, uint32(len(name)))
+	props.WriteString(name)
+	binary.Write(&props, binary.LittleEndian, uint16(1))
+	binary.Write(&props, binary.LittleEndian, level)
+	pv := b.CreateByteVector(props.Bytes())
+
+	b.StartObject(3)
+	b.PrependUOffsetTSlot(0, g, 0)
+	b.PrependUOffsetTSlot(1, pv, 0)
+	b.Finish(b.EndObject())
+
+	// the builder is reused, copy the bytes
+	return append([]byte(nil), b.FinishedBytes()...)
+}
@@ -0,0 +1,262 @@
+package fgb
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	flatbuffers "github.com/google/flatbuffers/go"
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/geojson"
+)
+
+// Schema the columns of the features properties, a property with several types is stored as JSON
+type Schema struct {
+	types map[string]uint8
+}
+
+// NewSchema returns an empty Schema
+func NewSchema() *Schema {
+	return &Schema{types: make(map[string]uint8)}
+}
+
+// Add adds the properties of a feature to the schema
+func (s *Schema) Add(props map[string]interface{}) {
+	for k, v := range props {
+		ct, ok := columnType(v)
+		if !ok {
+			continue
+		}
+		if pct, ok := s.types[k]; ok && pct != ct {
+			ct = columnJSON
+		}
+		s.types[k] = ct
+	}
+}
+
+// columns returns the columns sorted by name
+func (s *Schema) columns() []column {
+	columns := make([]column, 0, len(s.types))
+	for name, ct := range s.types {
+		columns = append(columns, column{name: name, ctype: ct})
+	}
+	sort.Slice(columns, func(i, j int) bool { return columns[i].name < columns[j].name })
+	return columns
+}
+
+// columnType returns the column type of a GeoJSON property value, false for null values
+func columnType(v interface{}) (uint8, bool) {
+	switch v.(type) {
+	case nil:
+		return 0, false
+	case bool:
+		return columnBool, true
+	case float64:
+		return columnDouble, true
+	case string:
+		return columnString, true
+	default:
+		return columnJSON, true
+	}
+}
+
+// Writer writes polygon and multipolygon features to a FlatGeobuf file in WGS84, without spatial index
+type Writer struct {
+	w       *bufio.Writer
+	b       *flatbuffers.Builder
+	columns []column
+	props   []byte
+}
+
+// NewWriter writes the header of a FlatGeobuf file of count features with the columns of schema to w,
+// count is 0 when unknown
+func NewWriter(w io.Writer, schema *Schema, count uint64) (*Writer, error) {
+	fw := &Writer{
+		w:       bufio.NewWriterSize(w, 1<<20),
+		b:       flatbuffers.NewBuilder(1024),
+		columns: schema.columns(),
+	}
+	if len(fw.columns) > math.MaxUint16 {
+		return nil, fmt.Errorf("too many columns %d", len(fw.columns))
+	}
+	if _, err := fw.w.Write(magic); err != nil {
+		return nil, err
+	}
+	if err := fw.writeSizePrefixed(fw.header(count)); err != nil {
+		return nil, err
+	}
+	return fw, nil
+}
+
+// header encodes the header, the geometry type is set per feature
+func (fw *Writer) header(count uint64) []byte {
+	b := fw.b
+	b.Reset()
+
+	cols := make([]flatbuffers.UOffsetT, len(fw.columns))
+	for i, c := range fw.columns {
+		name := b.CreateString(c.name)
+		b.StartObject(11)
+		b.PrependUOffsetTSlot(0, name, 0)
+		b.PrependUint8Slot(1, c.ctype, 0)
+		cols[i] = b.EndObject()
+	}
+	b.StartVector(4, len(cols), 4)
+	for i := len(cols) - 1; i >= 0; i-- {
+		b.PrependUOffsetT(cols[i])
+	}
+	colsv := b.EndVector(len(cols))
+
+	org := b.CreateString("EPSG")
+	b.StartObject(6)
+	b.PrependUOffsetTSlot(0, org, 0)
+	b.PrependInt32Slot(1, WGS84Code, 0)
+	crs := b.EndObject()
+
+	b.StartObject(14)
+	b.PrependUint8Slot(2, geometryUnknown, 0)
+	b.PrependUOffsetTSlot(7, colsv, 0)
+	b.PrependUint64Slot(8, count, 0)
+	// no spatial index, the default node size is 16
+	b.PrependUint16Slot(9, 0, 16)
+	b.PrependUOffsetTSlot(10, crs, 0)
+	b.Finish(b.EndObject())
+	return b.FinishedBytes()
+}
+
+// Write writes a polygon or multipolygon feature, the properties missing from the schema are ignored
+func (fw *Writer) Write(f *geojson.Feature) error {
+	b := fw.b
+	b.Reset()
+
+	var g flatbuffers.UOffsetT
+	switch rg := f.Geometry.(type) {
+	case *geom.Polygon:
+		g = fw.polygon(rg, true)
+	case *geom.MultiPolygon:
+		parts := make([]flatbuffers.UOffsetT, rg.NumPolygons())
+		for i := range parts {
+			parts[i] = fw.polygon(rg.Polygon(i), false)
+		}
+		b.StartVector(4, len(parts), 4)
+		for i := len(parts) - 1; i >= 0; i-- {
+			b.PrependUOffsetT(parts[i])
+		}
+		partsv := b.EndVector(len(parts))
+		b.StartObject(8)
+		b.PrependUint8Slot(6, geometryMultiPolygon, 0)
+		b.PrependUOffsetTSlot(7, partsv, 0)
+		g = b.EndObject()
+	default:
+		return errors.New("unsupported geometry, only polygons are supported")
+	}
+
+	props, err := fw.encodeProperties(f.Properties)
+	if err != nil {
+		return err
+	}
+	pv := b.CreateByteVector(props)
+
+	b.StartObject(3)
+	b.PrependUOffsetTSlot(0, g, 0)
+	b.PrependUOffsetTSlot(1, pv, 0)
+	b.Finish(b.EndObject())
+	return fw.writeSizePrefixed(b.FinishedBytes())
+}
+
+// Flush writes the buffered data to the underlying writer
+func (fw *Writer) Flush() error {
+	return fw.w.Flush()
+}
+
+// polygon encodes p, typed when not a part of a multipolygon
+func (fw *Writer) polygon(p *geom.Polygon, typed bool) flatbuffers.UOffsetT {
+	b := fw.b
+	xy := p.FlatCoords()
+	b.StartVector(8, len(xy), 8)
+	for i := len(xy) - 1; i >= 0; i-- {
+		b.PrependFloat64(xy[i])
+	}
+	xyv := b.EndVector(len(xy))
+
+	// ends are expressed in points
+	ends := p.Ends()
+	b.StartVector(4, len(ends), 4)
+	for i := len(ends) - 1; i >= 0; i-- {
+		b.PrependUint32(uint32(ends[i] / 2))
+	}
+	endsv := b.EndVector(len(ends))
+
+	b.StartObject(8)
+	b.PrependUOffsetTSlot(0, endsv, 0)
+	b.PrependUOffsetTSlot(1, xyv, 0)
+	if typed {
+		b.PrependUint8Slot(6, geometryPolygon, 0)
+	}
+	return b.EndObject()
+}
+
+// encodeProperties encodes the properties buffer: a column index followed by its value
+func (fw *Writer) encodeProperties(props map[string]interface{}) ([]byte, error) {
+	buf := fw.props[:0]
+	for i, c := range fw.columns {
+		v, ok := props[c.name]
+		if !ok || v == nil {
+			continue
+		}
+		if ct, _ := columnType(v); ct != c.ctype && c.ctype != columnJSON {
+			return nil, fmt.Errorf("property %s does not match the type of its column", c.name)
+		}
+		buf = appendUint16(buf, uint16(i))
+
+		switch c.ctype {
+		case columnBool:
+			if v.(bool) {
+				buf = append(buf, 1)
+			} else {
+				buf = append(buf, 0)
+			}
+		case columnDouble:
+			buf = appendUint64(buf, math.Float64bits(v.(float64)))
+		case columnString:
+			s := v.(string)
+			buf = appendUint32(buf, uint32(len(s)))
+			buf = append(buf, s...)
+		default:
+			jb, err := json.Marshal(v)
+			if err != nil {
+				return nil, fmt.Errorf("can't encode property %s: %w", c.name, err)
+			}
+			buf = appendUint32(buf, uint32(len(jb)))
+			buf = append(buf, jb...)
+		}
+	}
+	fw.props = buf
+	return buf, nil
+}
+
+// writeSizePrefixed writes a little endian uint32 size followed by the flatbuffer b
+func (fw *Writer) writeSizePrefixed(b []byte) error {
+	if err := binary.Write(fw.w, binary.LittleEndian, uint32(len(b))); err != nil {
+		return err
+	}
+	_, err := fw.w.Write(b)
+	return err
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v), byte(v>>8))
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	return appendUint32(appendUint32(b, uint32(v)), uint32(v>>32))
+}
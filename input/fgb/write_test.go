@@ -0,0 +1,73 @@
+package fgb
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/geojson"
+)
+
+func TestWriter(t *testing.T) {
+	features := []*geojson.Feature{
+		{
+			Geometry: geom.NewPolygonFlat(geom.XY, []float64{-3, 47, -2, 47, -2, 48, -3, 48, -3, 47}, []int{10}),
+			Properties: map[string]interface{}{
+				"name": "Bretagne", "admin_level": float64(4), "capital": true, "code": "53",
+			},
+		},
+		{
+			Geometry: geom.NewMultiPolygonFlat(geom.XY,
+				[]float64{0, 0, 1, 0, 1, 1, 0, 0, 10, 0, 13, 0, 13, 3, 10, 3, 10, 0, 11, 1, 11, 2, 12, 2, 12, 1, 11, 1},
+				[][]int{{8}, {18, 28}}),
+			Properties: map[string]interface{}{
+				"name": "Islands", "code": float64(8), "tags": []interface{}{"a", "b"}, "empty": nil,
+			},
+		},
+	}
+
+	schema := NewSchema()
+	for _, f := range features {
+		schema.Add(f.Properties)
+	}
+
+	tmpFile, err := ioutil.TempFile(os.TempDir(), "insideout-test-*.fgb")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+
+	w, err := NewWriter(tmpFile, schema, uint64(len(features)))
+	require.NoError(t, err)
+	for _, f := range features {
+		require.NoError(t, w.Write(f))
+	}
+	require.Error(t, w.Write(&geojson.Feature{Geometry: geom.NewPointFlat(geom.XY, []float64{1, 1})}))
+	require.NoError(t, w.Flush())
+	require.NoError(t, tmpFile.Close())
+
+	r, err := Open(tmpFile.Name())
+	require.NoError(t, err)
+	defer r.Close()
+	require.Equal(t, uint64(2), r.FeaturesCount())
+
+	f, err := r.Read()
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"name": "Bretagne", "admin_level": float64(4), "capital": true, "code": `"53"`,
+	}, f.Properties)
+	require.Equal(t, features[0].Geometry.FlatCoords(), f.Geometry.FlatCoords())
+
+	f, err = r.Read()
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"name": "Islands", "code": "8", "tags": `["a","b"]`}, f.Properties)
+	mp, ok := f.Geometry.(*geom.MultiPolygon)
+	require.True(t, ok)
+	require.Equal(t, 2, mp.NumPolygons())
+	require.Equal(t, 2, mp.Polygon(1).NumLinearRings())
+	require.Equal(t, features[1].Geometry.FlatCoords(), mp.FlatCoords())
+
+	_, err = r.Read()
+	require.Equal(t, io.EOF, err)
+}
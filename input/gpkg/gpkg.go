@@ -0,0 +1,187 @@
+// Package gpkg reads features from GeoPackage files
+package gpkg
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/geojson"
+	"github.com/twpayne/go-geom/encoding/wkb"
+
+	"github.com/akhenakh/insideout/crs"
+
+	// sqlite driver
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// WGS84SRSID the spatial reference system of the features read as is, the others are reprojected to it
+const WGS84SRSID = 4326
+
+// undefinedGeographicSRSID the spatial reference system of the undefined geographic coordinates, read as WGS84
+const undefinedGeographicSRSID = 0
+
+// envelope sizes in bytes indexed by the envelope contents indicator
+var envelopeSizes = [...]int{0, 32, 48, 48, 64}
+
+// ReadFeatureCollection reads all the features of all the features tables in the GeoPackage at path
+func ReadFeatureCollection(path string) (*geojson.FeatureCollection, error) {
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?mode=ro", path))
+	if err != nil {
+		return nil, fmt.Errorf("can't open GeoPackage %s: %w", path, err)
+	}
+	defer db.Close()
+
+	layers, err := featuresTables(db)
+	if err != nil {
+		return nil, err
+	}
+
+	fc := &geojson.FeatureCollection{}
+	for _, l := range layers {
+		if l.srsID != WGS84SRSID && l.srsID != undefinedGeographicSRSID {
+			if l.proj, err = projection(db, l.srsID); err != nil {
+				return nil, fmt.Errorf("table %s: srs_id %d: %w", l.name, l.srsID, err)
+			}
+		}
+		if err := readTable(db, l, fc); err != nil {
+			return nil, fmt.Errorf("table %s: %w", l.name, err)
+		}
+	}
+
+	return fc, nil
+}
+
+type featuresTable struct {
+	name           string
+	geometryColumn string
+	srsID          int
+	// proj the projection of the geometries, nil for WGS84
+	proj crs.Projection
+}
+
+// projection returns the projection of the spatial reference system srsID, from its WKT definition
+// or its EPSG code
+func projection(db *sql.DB, srsID int) (crs.Projection, error) {
+	var org, definition sql.NullString
+	var code sql.NullInt64
+	err := db.QueryRow(`SELECT organization, organization_coordsys_id, definition FROM gpkg_spatial_ref_sys
+		WHERE srs_id = ?`, srsID).Scan(&org, &code, &definition)
+	if err != nil {
+		return nil, fmt.Errorf("can't read the spatial reference system: %w", err)
+	}
+	if definition.Valid && definition.String != "" && definition.String != "undefined" {
+		return crs.FromWKT(definition.String)
+	}
+	if strings.EqualFold(org.String, "EPSG") && code.Valid {
+		return crs.FromEPSG(int(code.Int64))
+	}
+	return nil, fmt.Errorf("unsupported spatial reference system %s:%d", org.String, code.Int64)
+}
+
+// featuresTables returns the list of the features tables (layers)
+func featuresTables(db *sql.DB) ([]featuresTable, error) {
+	rows, err := db.Query(`SELECT c.table_name, g.column_name, g.srs_id FROM gpkg_contents c
+		JOIN gpkg_geometry_columns g ON c.table_name = g.table_name
+		WHERE c.data_type = 'features'`)
+	if err != nil {
+		return nil, fmt.Errorf("can't list GeoPackage features tables: %w", err)
+	}
+	defer rows.Close()
+
+	var layers []featuresTable
+	for rows.Next() {
+		var l featuresTable
+		if err := rows.Scan(&l.name, &l.geometryColumn, &l.srsID); err != nil {
+			return nil, err
+		}
+		layers = append(layers, l)
+	}
+
+	return layers, rows.Err()
+}
+
+func readTable(db *sql.DB, l featuresTable, fc *geojson.FeatureCollection) error {
+	rows, err := db.Query(fmt.Sprintf(`SELECT * FROM "%s"`, l.name))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	values := make([]interface{}, len(cols))
+	ptrs := make([]interface{}, len(cols))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return err
+		}
+
+		f := &geojson.Feature{Properties: make(map[string]interface{})}
+		for i, col := range cols {
+			if col == l.geometryColumn {
+				b, ok := values[i].([]byte)
+				if !ok {
+					continue
+				}
+				g, err := decodeGeometry(b)
+				if err != nil {
+					return err
+				}
+				if l.proj != nil {
+					crs.Reproject(g, l.proj)
+				}
+				f.Geometry = g
+				continue
+			}
+
+			switch v := values[i].(type) {
+			case int64:
+				f.Properties[col] = float64(v)
+			case []byte:
+				f.Properties[col] = string(v)
+			case string, float64, bool:
+				f.Properties[col] = v
+			}
+		}
+
+		if f.Geometry == nil {
+			continue
+		}
+		fc.Features = append(fc.Features, f)
+	}
+
+	return rows.Err()
+}
+
+// decodeGeometry decodes a GeoPackage geometry blob: a GeoPackage header followed by WKB
+func decodeGeometry(b []byte) (geom.T, error) {
+	if len(b) < 8 || b[0] != 'G' || b[1] != 'P' {
+		return nil, errors.New("invalid GeoPackage geometry header")
+	}
+	flags := b[3]
+	envelope := int(flags>>1) & 0x7
+	if envelope >= len(envelopeSizes) {
+		return nil, fmt.Errorf("invalid GeoPackage envelope indicator %d", envelope)
+	}
+	hlen := 8 + envelopeSizes[envelope]
+	if len(b) < hlen {
+		return nil, errors.New("invalid GeoPackage geometry header")
+	}
+
+	g, err := wkb.Unmarshal(b[hlen:])
+	if err != nil {
+		return nil, fmt.Errorf("can't decode WKB geometry: %w", err)
+	}
+
+	return g, nil
+}
@@ -0,0 +1,93 @@
+package gpkg
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/wkb"
+)
+
+func TestReadFeatureCollection(t *testing.T) {
+	path, clean := setup(t)
+	defer clean()
+
+	fc, err := ReadFeatureCollection(path)
+	require.NoError(t, err)
+	require.Len(t, fc.Features, 1)
+
+	f := fc.Features[0]
+	require.Equal(t, "Bretagne", f.Properties["name"])
+	require.Equal(t, float64(4), f.Properties["admin_level"])
+
+	p, ok := f.Geometry.(*geom.Polygon)
+	require.True(t, ok)
+	require.Equal(t, []float64{-3, 47, -2, 47, -2, 48, -3, 48, -3, 47}, p.FlatCoords())
+}
+
+func TestReadFeatureCollectionProjected(t *testing.T) {
+	// Lambert 93 origin, 3 46.5
+	path, clean := setupSRS(t, 2154, []float64{700000, 6600000, 710000, 6600000, 710000, 6610000, 700000, 6600000})
+	defer clean()
+
+	fc, err := ReadFeatureCollection(path)
+	require.NoError(t, err)
+	require.Len(t, fc.Features, 1)
+	coords := fc.Features[0].Geometry.FlatCoords()
+	require.InDelta(t, 3.0, coords[0], 1e-6)
+	require.InDelta(t, 46.5, coords[1], 1e-6)
+
+	path, clean = setupSRS(t, 27700, []float64{0, 0, 1, 0, 1, 1, 0, 0})
+	defer clean()
+	_, err = ReadFeatureCollection(path)
+	require.Error(t, err)
+}
+
+func setup(t *testing.T) (string, func()) {
+	return setupSRS(t, WGS84SRSID, []float64{-3, 47, -2, 47, -2, 48, -3, 48, -3, 47})
+}
+
+// setupSRS returns the path of a GeoPackage holding a polygon of the coordinates coords in the EPSG srsID
+func setupSRS(t *testing.T, srsID int, coords []float64) (string, func()) {
+	tmpFile, err := ioutil.TempFile(os.TempDir(), "insideout-test-*.gpkg")
+	require.NoError(t, err)
+	tmpFile.Close()
+
+	db, err := sql.Open("sqlite3", tmpFile.Name())
+	require.NoError(t, err)
+	defer db.Close()
+
+	for _, q := range []string{
+		`CREATE TABLE gpkg_contents (table_name TEXT, data_type TEXT)`,
+		`CREATE TABLE gpkg_geometry_columns (table_name TEXT, column_name TEXT, srs_id INTEGER)`,
+		`CREATE TABLE regions (fid INTEGER PRIMARY KEY, geom BLOB, name TEXT, admin_level INTEGER)`,
+		`INSERT INTO gpkg_contents VALUES ('regions', 'features')`,
+		`CREATE TABLE gpkg_spatial_ref_sys (srs_id INTEGER, organization TEXT, organization_coordsys_id INTEGER, definition TEXT)`,
+	} {
+		_, err = db.Exec(q)
+		require.NoError(t, err)
+	}
+	_, err = db.Exec(`INSERT INTO gpkg_geometry_columns VALUES ('regions', 'geom', ?)`, srsID)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO gpkg_spatial_ref_sys VALUES (?, 'EPSG', ?, 'undefined')`, srsID, srsID)
+	require.NoError(t, err)
+
+	p := geom.NewPolygonFlat(geom.XY, coords, []int{len(coords)})
+	b, err := wkb.Marshal(p, binary.LittleEndian)
+	require.NoError(t, err)
+
+	// GeoPackage header: magic, version, flags little endian no envelope, srs_id
+	h := []byte{'G', 'P', 0, 1, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint32(h[4:], uint32(srsID))
+
+	_, err = db.Exec(`INSERT INTO regions (geom, name, admin_level) VALUES (?, ?, ?)`, append(h, b...), "Bretagne", 4)
+	require.NoError(t, err)
+
+	return tmpFile.Name(), func() {
+		os.Remove(tmpFile.Name())
+	}
+}
@@ -0,0 +1,204 @@
+// Package shapefile reads features from ESRI Shapefiles and their DBF attributes
+package shapefile
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jonas-p/go-shp"
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/geojson"
+
+	"github.com/akhenakh/insideout/crs"
+)
+
+// ReadFeatureCollection reads all the polygons of the Shapefile at path,
+// coordinates are reprojected to WGS84 using the .prj file when present
+func ReadFeatureCollection(path string) (*geojson.FeatureCollection, error) {
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+
+	var proj crs.Projection
+	prj, err := ioutil.ReadFile(base + ".prj")
+	switch {
+	case err == nil:
+		proj, err = crs.FromWKT(string(prj))
+		if err != nil {
+			return nil, fmt.Errorf("can't read Shapefile projection %s.prj: %w", base, err)
+		}
+	case !os.IsNotExist(err):
+		return nil, err
+	}
+
+	r, err := shp.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't open Shapefile %s: %w", path, err)
+	}
+	defer r.Close()
+
+	var fields []shp.Field
+	if _, err := os.Stat(base + ".dbf"); err == nil {
+		fields = r.Fields()
+	}
+
+	fc := &geojson.FeatureCollection{}
+	for r.Next() {
+		n, s := r.Shape()
+
+		var parts []int32
+		var points []shp.Point
+		switch p := s.(type) {
+		case *shp.Polygon:
+			parts, points = p.Parts, p.Points
+		case *shp.PolygonZ:
+			parts, points = p.Parts, p.Points
+		case *shp.PolygonM:
+			parts, points = p.Parts, p.Points
+		case *shp.Null:
+			continue
+		default:
+			return nil, fmt.Errorf("unsupported Shapefile shape type %T, only polygons are supported", s)
+		}
+
+		g, err := polygonFromParts(parts, points, proj)
+		if err != nil {
+			return nil, fmt.Errorf("shape %d: %w", n, err)
+		}
+
+		f := &geojson.Feature{
+			Geometry:   g,
+			Properties: make(map[string]interface{}, len(fields)),
+		}
+		for i, field := range fields {
+			if v, ok := attribute(field, r.ReadAttribute(n, i)); ok {
+				f.Properties[field.String()] = v
+			}
+		}
+		fc.Features = append(fc.Features, f)
+	}
+	if err := r.Err(); err != nil {
+		return nil, fmt.Errorf("can't read Shapefile %s: %w", path, err)
+	}
+
+	return fc, nil
+}
+
+// polygonFromParts converts the Shapefile rings into a Polygon or a MultiPolygon,
+// Shapefile outer rings are clockwise followed by their holes counter clockwise,
+// rings are reversed to follow the GeoJSON right hand rule
+func polygonFromParts(parts []int32, points []shp.Point, proj crs.Projection) (geom.T, error) {
+	mp := geom.NewMultiPolygon(geom.XY)
+	var current *geom.Polygon
+
+	for i, start := range parts {
+		end := int32(len(points))
+		if i+1 < len(parts) {
+			end = parts[i+1]
+		}
+		if start < 0 || start > end || end > int32(len(points)) {
+			return nil, errors.New("invalid Shapefile polygon parts")
+		}
+
+		ring := make([]float64, 0, 2*(end-start))
+		for _, p := range points[start:end] {
+			x, y := p.X, p.Y
+			if proj != nil {
+				x, y = proj.ToWGS84(x, y)
+			}
+			ring = append(ring, x, y)
+		}
+		if len(ring) < 2*4 {
+			continue
+		}
+
+		if signedArea(ring) < 0 {
+			// clockwise outer ring
+			if current != nil {
+				if err := mp.Push(current); err != nil {
+					return nil, err
+				}
+			}
+			current = geom.NewPolygonFlat(geom.XY, reverse(ring), []int{len(ring)})
+			continue
+		}
+
+		if current == nil {
+			// a lone counter clockwise ring, ring direction was not respected
+			current = geom.NewPolygonFlat(geom.XY, ring, []int{len(ring)})
+			continue
+		}
+		hole := geom.NewLinearRingFlat(geom.XY, reverse(ring))
+		if err := current.Push(hole); err != nil {
+			return nil, err
+		}
+	}
+
+	if current != nil {
+		if err := mp.Push(current); err != nil {
+			return nil, err
+		}
+	}
+
+	if mp.NumPolygons() == 1 {
+		return mp.Polygon(0), nil
+	}
+	return mp, nil
+}
+
+// signedArea returns twice the signed area of the ring, positive for counter clockwise
+func signedArea(c []float64) float64 {
+	var a float64
+	for i := 0; i+3 < len(c); i += 2 {
+		a += c[i]*c[i+3] - c[i+2]*c[i+1]
+	}
+	return a
+}
+
+// reverse reverses the order of the points of a flat XY ring in place
+func reverse(c []float64) []float64 {
+	for i, j := 0, len(c)-2; i < j; i, j = i+2, j-2 {
+		c[i], c[j] = c[j], c[i]
+		c[i+1], c[j+1] = c[j+1], c[i+1]
+	}
+	return c
+}
+
+// attribute converts a DBF value according to its field type
+func attribute(f shp.Field, v string) (interface{}, bool) {
+	v = strings.TrimRight(v, "\x00")
+	if v == "" {
+		return nil, false
+	}
+
+	switch f.Fieldtype {
+	case 'N', 'F':
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, false
+		}
+		return n, true
+	case 'L':
+		switch v {
+		case "T", "t", "Y", "y":
+			return true, true
+		case "F", "f", "N", "n":
+			return false, true
+		}
+		return nil, false
+	default:
+		if !utf8.ValidString(v) {
+			// legacy DBF files are often encoded in ISO-8859-1
+			r := make([]rune, len(v))
+			for i := 0; i < len(v); i++ {
+				r[i] = rune(v[i])
+			}
+			return string(r), true
+		}
+		return v, true
+	}
+}
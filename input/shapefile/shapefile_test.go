@@ -0,0 +1,101 @@
+package shapefile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jonas-p/go-shp"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom"
+)
+
+const wktLambert93 = `PROJCS["RGF_1993_Lambert_93",GEOGCS["GCS_RGF_1993",DATUM["D_RGF_1993",SPHEROID["GRS_1980",6378137.0,298.257222101]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]],PROJECTION["Lambert_Conformal_Conic"],PARAMETER["False_Easting",700000.0],PARAMETER["False_Northing",6600000.0],PARAMETER["Central_Meridian",3.0],PARAMETER["Standard_Parallel_1",49.0],PARAMETER["Standard_Parallel_2",44.0],PARAMETER["Latitude_Of_Origin",46.5],UNIT["Meter",1.0]]`
+
+func TestReadFeatureCollection(t *testing.T) {
+	path, clean := setup(t, wktLambert93)
+	defer clean()
+
+	fc, err := ReadFeatureCollection(path)
+	require.NoError(t, err)
+	require.Len(t, fc.Features, 2)
+
+	f := fc.Features[0]
+	require.Equal(t, "Centre", f.Properties["name"])
+	require.Equal(t, float64(4), f.Properties["admin_level"])
+
+	p, ok := f.Geometry.(*geom.Polygon)
+	require.True(t, ok)
+	require.Equal(t, 2, p.NumLinearRings())
+
+	// outer ring reprojected and counter clockwise
+	outer := p.LinearRing(0).FlatCoords()
+	require.InDelta(t, 3.0, outer[0], 1e-6)
+	require.InDelta(t, 46.5, outer[1], 1e-6)
+	require.True(t, signedArea(outer) > 0)
+
+	// hole clockwise
+	require.True(t, signedArea(p.LinearRing(1).FlatCoords()) < 0)
+
+	f = fc.Features[1]
+	require.Equal(t, "Bretagne", f.Properties["name"])
+	_, ok = f.Geometry.(*geom.MultiPolygon)
+	require.True(t, ok)
+}
+
+func TestReadFeatureCollectionNoPrj(t *testing.T) {
+	path, clean := setup(t, "")
+	defer clean()
+
+	fc, err := ReadFeatureCollection(path)
+	require.NoError(t, err)
+	require.Len(t, fc.Features, 2)
+
+	outer := fc.Features[0].Geometry.(*geom.Polygon).LinearRing(0).FlatCoords()
+	require.Equal(t, 700000.0, outer[0])
+	require.Equal(t, 6600000.0, outer[1])
+}
+
+// setup writes a Shapefile with a polygon with a hole and a multipolygon
+func setup(t *testing.T, prj string) (string, func()) {
+	dir, err := ioutil.TempDir(os.TempDir(), "insideout-test")
+	require.NoError(t, err)
+	path := filepath.Join(dir, "regions.shp")
+
+	w, err := shp.Create(path, shp.POLYGON)
+	require.NoError(t, err)
+	require.NoError(t, w.SetFields([]shp.Field{
+		shp.StringField("name", 32),
+		shp.NumberField("admin_level", 4),
+	}))
+
+	// clockwise outer rings, counter clockwise holes
+	centre := shp.Polygon(*shp.NewPolyLine([][]shp.Point{
+		{{X: 700000, Y: 6600000}, {X: 700000, Y: 6700000}, {X: 800000, Y: 6700000}, {X: 800000, Y: 6600000}, {X: 700000, Y: 6600000}},
+		{{X: 720000, Y: 6620000}, {X: 780000, Y: 6620000}, {X: 780000, Y: 6680000}, {X: 720000, Y: 6680000}, {X: 720000, Y: 6620000}},
+	}))
+	bretagne := shp.Polygon(*shp.NewPolyLine([][]shp.Point{
+		{{X: 200000, Y: 6800000}, {X: 200000, Y: 6850000}, {X: 250000, Y: 6850000}, {X: 250000, Y: 6800000}, {X: 200000, Y: 6800000}},
+		{{X: 300000, Y: 6800000}, {X: 300000, Y: 6850000}, {X: 350000, Y: 6850000}, {X: 350000, Y: 6800000}, {X: 300000, Y: 6800000}},
+	}))
+
+	n := w.Write(&centre)
+	require.NoError(t, w.WriteAttribute(int(n), 0, "Centre"))
+	require.NoError(t, w.WriteAttribute(int(n), 1, 4))
+	n = w.Write(&bretagne)
+	require.NoError(t, w.WriteAttribute(int(n), 0, "Bretagne"))
+	require.NoError(t, w.WriteAttribute(int(n), 1, 4))
+	w.Close()
+
+	// go-shp writer misses the dot of the DBF extension
+	require.NoError(t, os.Rename(filepath.Join(dir, "regionsdbf"), filepath.Join(dir, "regions.dbf")))
+
+	if prj != "" {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "regions.prj"), []byte(prj), 0644))
+	}
+
+	return path, func() {
+		os.RemoveAll(dir)
+	}
+}
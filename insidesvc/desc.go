@@ -0,0 +1,7 @@
+package insidesvc
+
+import "google.golang.org/grpc"
+
+// InsideServiceDesc describes the Inside service to the servers translating other protocols to gRPC,
+// see the server/connect package
+var InsideServiceDesc *grpc.ServiceDesc = &_Inside_serviceDesc
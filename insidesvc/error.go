@@ -0,0 +1,17 @@
+package insidesvc
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// StatusError returns the error message of the gRPC status of err
+func StatusError(err error) *Error {
+	st := status.Convert(err)
+	return &Error{Code: int32(st.Code()), Message: st.Message()}
+}
+
+// Err returns the gRPC status error of e
+func (e *Error) Err() error {
+	return status.Error(codes.Code(e.Code), e.Message)
+}
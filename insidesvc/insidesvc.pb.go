@@ -24,6 +24,62 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
+// ordering of the responses when the point is inside several features
+type WithinRequest_Order int32
+
+const (
+	// by feature id then polygon index, the order of the features in the indexed files
+	WithinRequest_INSERTION WithinRequest_Order = 0
+	// by area of the matched polygon, smallest first
+	WithinRequest_AREA WithinRequest_Order = 1
+	// by the value of order_property, numbers before strings, features without it last
+	WithinRequest_PROPERTY WithinRequest_Order = 2
+)
+
+var WithinRequest_Order_name = map[int32]string{
+	0: "INSERTION",
+	1: "AREA",
+	2: "PROPERTY",
+}
+var WithinRequest_Order_value = map[string]int32{
+	"INSERTION": 0,
+	"AREA":      1,
+	"PROPERTY":  2,
+}
+
+func (x WithinRequest_Order) String() string {
+	return proto.EnumName(WithinRequest_Order_name, int32(x))
+}
+func (WithinRequest_Order) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_insidesvc_d86dece99e9ccc55, []int{0, 0}
+}
+
+type GeofenceEvent_Type int32
+
+const (
+	GeofenceEvent_ENTER GeofenceEvent_Type = 0
+	GeofenceEvent_EXIT  GeofenceEvent_Type = 1
+	GeofenceEvent_DWELL GeofenceEvent_Type = 2
+)
+
+var GeofenceEvent_Type_name = map[int32]string{
+	0: "ENTER",
+	1: "EXIT",
+	2: "DWELL",
+}
+var GeofenceEvent_Type_value = map[string]int32{
+	"ENTER": 0,
+	"EXIT":  1,
+	"DWELL": 2,
+}
+
+func (x GeofenceEvent_Type) String() string {
+	return proto.EnumName(GeofenceEvent_Type_name, int32(x))
+}
+func (GeofenceEvent_Type) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_insidesvc_d86dece99e9ccc55, []int{9, 0}
+}
+
 type Geometry_Type int32
 
 const (
@@ -50,7 +106,30 @@ func (x Geometry_Type) String() string {
 	return proto.EnumName(Geometry_Type_name, int32(x))
 }
 func (Geometry_Type) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_insidesvc_b16b5f17af4aa25f, []int{5, 0}
+	return fileDescriptor_insidesvc_d86dece99e9ccc55, []int{24, 0}
+}
+
+type ResizeCacheRequest_Cache int32
+
+const (
+	ResizeCacheRequest_FEATURES ResizeCacheRequest_Cache = 0
+	ResizeCacheRequest_RESULTS  ResizeCacheRequest_Cache = 1
+)
+
+var ResizeCacheRequest_Cache_name = map[int32]string{
+	0: "FEATURES",
+	1: "RESULTS",
+}
+var ResizeCacheRequest_Cache_value = map[string]int32{
+	"FEATURES": 0,
+	"RESULTS":  1,
+}
+
+func (x ResizeCacheRequest_Cache) String() string {
+	return proto.EnumName(ResizeCacheRequest_Cache_name, int32(x))
+}
+func (ResizeCacheRequest_Cache) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_insidesvc_d86dece99e9ccc55, []int{33, 0}
 }
 
 type WithinRequest struct {
@@ -60,7 +139,39 @@ type WithinRequest struct {
 	// saving extra bytes
 	RemoveGeometries bool `protobuf:"varint,3,opt,name=remove_geometries,json=removeGeometries,proto3" json:"remove_geometries,omitempty"`
 	// comma separated list of property so returns to save extra bytes, leave empty for all
-	SelectProperties     string   `protobuf:"bytes,4,opt,name=select_properties,json=selectProperties,proto3" json:"select_properties,omitempty"`
+	SelectProperties string `protobuf:"bytes,4,opt,name=select_properties,json=selectProperties,proto3" json:"select_properties,omitempty"`
+	// comma separated list of conditions on properties key=value or key!=value,
+	// only features matching all conditions are returned, leave empty for all
+	Filter string `protobuf:"bytes,5,opt,name=filter,proto3" json:"filter,omitempty"`
+	// dataset to query, leave empty for the default dataset
+	Dataset string `protobuf:"bytes,6,opt,name=dataset,proto3" json:"dataset,omitempty"`
+	// compute the distance from the point to the boundary of each matched feature
+	BoundaryDistance bool `protobuf:"varint,7,opt,name=boundary_distance,json=boundaryDistance,proto3" json:"boundary_distance,omitempty"`
+	// test the point against the polygons even when it lies in an inside covering cell,
+	// slower but all the responses are exact
+	Exact bool                `protobuf:"varint,8,opt,name=exact,proto3" json:"exact,omitempty"`
+	Order WithinRequest_Order `protobuf:"varint,9,opt,name=order,proto3,enum=WithinRequest_Order" json:"order,omitempty"`
+	// property ordering the responses with the PROPERTY order
+	OrderProperty string `protobuf:"bytes,10,opt,name=order_property,json=orderProperty,proto3" json:"order_property,omitempty"`
+	// reverse the order, the features without order_property are still last
+	OrderDesc bool `protobuf:"varint,11,opt,name=order_desc,json=orderDesc,proto3" json:"order_desc,omitempty"`
+	// max number of responses returned after filtering and ordering, 0 for all
+	Limit int32 `protobuf:"varint,12,opt,name=limit,proto3" json:"limit,omitempty"`
+	// return the responses from the outermost to the deepest feature, each with the ids of its ancestors,
+	// the features of the same depth keep the order, requires a DB indexed with -hierarchy
+	Hierarchy bool `protobuf:"varint,13,opt,name=hierarchy,proto3" json:"hierarchy,omitempty"`
+	// only return the deepest features, the ones not containing another matched feature,
+	// requires a DB indexed with -hierarchy
+	DeepestOnly bool `protobuf:"varint,14,opt,name=deepest_only,json=deepestOnly,proto3" json:"deepest_only,omitempty"`
+	// return the diagnostics of the lookup in the debug field of the response,
+	// the results cache is skipped
+	Debug bool `protobuf:"varint,15,opt,name=debug,proto3" json:"debug,omitempty"`
+	// only return the features valid at this time as unix milliseconds, from the validity properties
+	// of the server, leave 0 for the current time
+	At int64 `protobuf:"varint,16,opt,name=at,proto3" json:"at,omitempty"`
+	// strategy answering the query, one of the strategies of the dataset info, to compare them on a live server,
+	// the results cache is skipped, leave empty for the strategy of the server
+	Strategy             string   `protobuf:"bytes,17,opt,name=strategy,proto3" json:"strategy,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -70,7 +181,7 @@ func (m *WithinRequest) Reset()         { *m = WithinRequest{} }
 func (m *WithinRequest) String() string { return proto.CompactTextString(m) }
 func (*WithinRequest) ProtoMessage()    {}
 func (*WithinRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_insidesvc_b16b5f17af4aa25f, []int{0}
+	return fileDescriptor_insidesvc_d86dece99e9ccc55, []int{0}
 }
 func (m *WithinRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_WithinRequest.Unmarshal(m, b)
@@ -118,19 +229,114 @@ func (m *WithinRequest) GetSelectProperties() string {
 	return ""
 }
 
+func (m *WithinRequest) GetFilter() string {
+	if m != nil {
+		return m.Filter
+	}
+	return ""
+}
+
+func (m *WithinRequest) GetDataset() string {
+	if m != nil {
+		return m.Dataset
+	}
+	return ""
+}
+
+func (m *WithinRequest) GetBoundaryDistance() bool {
+	if m != nil {
+		return m.BoundaryDistance
+	}
+	return false
+}
+
+func (m *WithinRequest) GetExact() bool {
+	if m != nil {
+		return m.Exact
+	}
+	return false
+}
+
+func (m *WithinRequest) GetOrder() WithinRequest_Order {
+	if m != nil {
+		return m.Order
+	}
+	return WithinRequest_INSERTION
+}
+
+func (m *WithinRequest) GetOrderProperty() string {
+	if m != nil {
+		return m.OrderProperty
+	}
+	return ""
+}
+
+func (m *WithinRequest) GetOrderDesc() bool {
+	if m != nil {
+		return m.OrderDesc
+	}
+	return false
+}
+
+func (m *WithinRequest) GetLimit() int32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+func (m *WithinRequest) GetHierarchy() bool {
+	if m != nil {
+		return m.Hierarchy
+	}
+	return false
+}
+
+func (m *WithinRequest) GetDeepestOnly() bool {
+	if m != nil {
+		return m.DeepestOnly
+	}
+	return false
+}
+
+func (m *WithinRequest) GetDebug() bool {
+	if m != nil {
+		return m.Debug
+	}
+	return false
+}
+
+func (m *WithinRequest) GetAt() int64 {
+	if m != nil {
+		return m.At
+	}
+	return 0
+}
+
+func (m *WithinRequest) GetStrategy() string {
+	if m != nil {
+		return m.Strategy
+	}
+	return ""
+}
+
 type WithinResponse struct {
-	Point                *Point             `protobuf:"bytes,1,opt,name=point,proto3" json:"point,omitempty"`
-	Responses            []*FeatureResponse `protobuf:"bytes,2,rep,name=responses,proto3" json:"responses,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
-	XXX_unrecognized     []byte             `json:"-"`
-	XXX_sizecache        int32              `json:"-"`
+	Point     *Point             `protobuf:"bytes,1,opt,name=point,proto3" json:"point,omitempty"`
+	Responses []*FeatureResponse `protobuf:"bytes,2,rep,name=responses,proto3" json:"responses,omitempty"`
+	// the diagnostics of the lookup, set when requested with debug
+	Debug *WithinDebug `protobuf:"bytes,3,opt,name=debug,proto3" json:"debug,omitempty"`
+	// the error of the request on a WithinStream, the stream goes on with the next requests
+	Error                *Error   `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
 func (m *WithinResponse) Reset()         { *m = WithinResponse{} }
 func (m *WithinResponse) String() string { return proto.CompactTextString(m) }
 func (*WithinResponse) ProtoMessage()    {}
 func (*WithinResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_insidesvc_b16b5f17af4aa25f, []int{1}
+	return fileDescriptor_insidesvc_d86dece99e9ccc55, []int{1}
 }
 func (m *WithinResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_WithinResponse.Unmarshal(m, b)
@@ -164,402 +370,3355 @@ func (m *WithinResponse) GetResponses() []*FeatureResponse {
 	return nil
 }
 
-type GetRequest struct {
-	Id uint32 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
-	// internally stored as uint16
-	LoopIndex            uint32   `protobuf:"varint,2,opt,name=loop_index,json=loopIndex,proto3" json:"loop_index,omitempty"`
+func (m *WithinResponse) GetDebug() *WithinDebug {
+	if m != nil {
+		return m.Debug
+	}
+	return nil
+}
+
+func (m *WithinResponse) GetError() *Error {
+	if m != nil {
+		return m.Error
+	}
+	return nil
+}
+
+// diagnostics of a within lookup, to investigate a wrong answer
+type WithinDebug struct {
+	// the cell of the point at the finest level of the inside cover of the dataset
+	CellToken string `protobuf:"bytes,1,opt,name=cell_token,json=cellToken,proto3" json:"cell_token,omitempty"`
+	CellLevel int32  `protobuf:"varint,2,opt,name=cell_level,json=cellLevel,proto3" json:"cell_level,omitempty"`
+	Strategy  string `protobuf:"bytes,3,opt,name=strategy,proto3" json:"strategy,omitempty"`
+	// the polygons returned by the index for the point, in the scanning order
+	Candidates []*WithinCandidate `protobuf:"bytes,4,rep,name=candidates,proto3" json:"candidates,omitempty"`
+	// durations in microseconds of the index lookup, of the point in polygon tests with the features loads,
+	// and of the whole lookup
+	IndexMicros          int64    `protobuf:"varint,5,opt,name=index_micros,json=indexMicros,proto3" json:"index_micros,omitempty"`
+	PipMicros            int64    `protobuf:"varint,6,opt,name=pip_micros,json=pipMicros,proto3" json:"pip_micros,omitempty"`
+	TotalMicros          int64    `protobuf:"varint,7,opt,name=total_micros,json=totalMicros,proto3" json:"total_micros,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *GetRequest) Reset()         { *m = GetRequest{} }
-func (m *GetRequest) String() string { return proto.CompactTextString(m) }
-func (*GetRequest) ProtoMessage()    {}
-func (*GetRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_insidesvc_b16b5f17af4aa25f, []int{2}
+func (m *WithinDebug) Reset()         { *m = WithinDebug{} }
+func (m *WithinDebug) String() string { return proto.CompactTextString(m) }
+func (*WithinDebug) ProtoMessage()    {}
+func (*WithinDebug) Descriptor() ([]byte, []int) {
+	return fileDescriptor_insidesvc_d86dece99e9ccc55, []int{2}
 }
-func (m *GetRequest) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_GetRequest.Unmarshal(m, b)
+func (m *WithinDebug) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WithinDebug.Unmarshal(m, b)
 }
-func (m *GetRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_GetRequest.Marshal(b, m, deterministic)
+func (m *WithinDebug) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WithinDebug.Marshal(b, m, deterministic)
 }
-func (dst *GetRequest) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_GetRequest.Merge(dst, src)
+func (dst *WithinDebug) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WithinDebug.Merge(dst, src)
 }
-func (m *GetRequest) XXX_Size() int {
-	return xxx_messageInfo_GetRequest.Size(m)
+func (m *WithinDebug) XXX_Size() int {
+	return xxx_messageInfo_WithinDebug.Size(m)
 }
-func (m *GetRequest) XXX_DiscardUnknown() {
-	xxx_messageInfo_GetRequest.DiscardUnknown(m)
+func (m *WithinDebug) XXX_DiscardUnknown() {
+	xxx_messageInfo_WithinDebug.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_GetRequest proto.InternalMessageInfo
+var xxx_messageInfo_WithinDebug proto.InternalMessageInfo
 
-func (m *GetRequest) GetId() uint32 {
+func (m *WithinDebug) GetCellToken() string {
 	if m != nil {
-		return m.Id
+		return m.CellToken
+	}
+	return ""
+}
+
+func (m *WithinDebug) GetCellLevel() int32 {
+	if m != nil {
+		return m.CellLevel
 	}
 	return 0
 }
 
-func (m *GetRequest) GetLoopIndex() uint32 {
+func (m *WithinDebug) GetStrategy() string {
 	if m != nil {
-		return m.LoopIndex
+		return m.Strategy
+	}
+	return ""
+}
+
+func (m *WithinDebug) GetCandidates() []*WithinCandidate {
+	if m != nil {
+		return m.Candidates
+	}
+	return nil
+}
+
+func (m *WithinDebug) GetIndexMicros() int64 {
+	if m != nil {
+		return m.IndexMicros
 	}
 	return 0
 }
 
-type FeatureResponse struct {
-	// id in the index
-	Id                   uint32   `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
-	Feature              *Feature `protobuf:"bytes,3,opt,name=feature,proto3" json:"feature,omitempty"`
+func (m *WithinDebug) GetPipMicros() int64 {
+	if m != nil {
+		return m.PipMicros
+	}
+	return 0
+}
+
+func (m *WithinDebug) GetTotalMicros() int64 {
+	if m != nil {
+		return m.TotalMicros
+	}
+	return 0
+}
+
+// a polygon returned by the index
+type WithinCandidate struct {
+	Id  uint32 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Pos uint32 `protobuf:"varint,2,opt,name=pos,proto3" json:"pos,omitempty"`
+	// returned as inside by the index, accepted from the cover only unless tested
+	InsideCell bool `protobuf:"varint,3,opt,name=inside_cell,json=insideCell,proto3" json:"inside_cell,omitempty"`
+	// tested against the polygon, point in polygon
+	Tested bool `protobuf:"varint,4,opt,name=tested,proto3" json:"tested,omitempty"`
+	// the point is inside the polygon, before the filter is applied
+	Accepted             bool     `protobuf:"varint,5,opt,name=accepted,proto3" json:"accepted,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *FeatureResponse) Reset()         { *m = FeatureResponse{} }
-func (m *FeatureResponse) String() string { return proto.CompactTextString(m) }
-func (*FeatureResponse) ProtoMessage()    {}
-func (*FeatureResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_insidesvc_b16b5f17af4aa25f, []int{3}
+func (m *WithinCandidate) Reset()         { *m = WithinCandidate{} }
+func (m *WithinCandidate) String() string { return proto.CompactTextString(m) }
+func (*WithinCandidate) ProtoMessage()    {}
+func (*WithinCandidate) Descriptor() ([]byte, []int) {
+	return fileDescriptor_insidesvc_d86dece99e9ccc55, []int{3}
 }
-func (m *FeatureResponse) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_FeatureResponse.Unmarshal(m, b)
+func (m *WithinCandidate) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WithinCandidate.Unmarshal(m, b)
 }
-func (m *FeatureResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_FeatureResponse.Marshal(b, m, deterministic)
+func (m *WithinCandidate) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WithinCandidate.Marshal(b, m, deterministic)
 }
-func (dst *FeatureResponse) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_FeatureResponse.Merge(dst, src)
+func (dst *WithinCandidate) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WithinCandidate.Merge(dst, src)
 }
-func (m *FeatureResponse) XXX_Size() int {
-	return xxx_messageInfo_FeatureResponse.Size(m)
+func (m *WithinCandidate) XXX_Size() int {
+	return xxx_messageInfo_WithinCandidate.Size(m)
 }
-func (m *FeatureResponse) XXX_DiscardUnknown() {
-	xxx_messageInfo_FeatureResponse.DiscardUnknown(m)
+func (m *WithinCandidate) XXX_DiscardUnknown() {
+	xxx_messageInfo_WithinCandidate.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_FeatureResponse proto.InternalMessageInfo
+var xxx_messageInfo_WithinCandidate proto.InternalMessageInfo
 
-func (m *FeatureResponse) GetId() uint32 {
+func (m *WithinCandidate) GetId() uint32 {
 	if m != nil {
 		return m.Id
 	}
 	return 0
 }
 
-func (m *FeatureResponse) GetFeature() *Feature {
+func (m *WithinCandidate) GetPos() uint32 {
 	if m != nil {
-		return m.Feature
+		return m.Pos
 	}
-	return nil
+	return 0
 }
 
-type Feature struct {
-	Geometry             *Geometry                 `protobuf:"bytes,1,opt,name=geometry,proto3" json:"geometry,omitempty"`
-	Properties           map[string]*_struct.Value `protobuf:"bytes,2,rep,name=properties,proto3" json:"properties,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
-	XXX_NoUnkeyedLiteral struct{}                  `json:"-"`
-	XXX_unrecognized     []byte                    `json:"-"`
-	XXX_sizecache        int32                     `json:"-"`
+func (m *WithinCandidate) GetInsideCell() bool {
+	if m != nil {
+		return m.InsideCell
+	}
+	return false
 }
 
-func (m *Feature) Reset()         { *m = Feature{} }
-func (m *Feature) String() string { return proto.CompactTextString(m) }
-func (*Feature) ProtoMessage()    {}
-func (*Feature) Descriptor() ([]byte, []int) {
-	return fileDescriptor_insidesvc_b16b5f17af4aa25f, []int{4}
+func (m *WithinCandidate) GetTested() bool {
+	if m != nil {
+		return m.Tested
+	}
+	return false
 }
-func (m *Feature) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_Feature.Unmarshal(m, b)
+
+func (m *WithinCandidate) GetAccepted() bool {
+	if m != nil {
+		return m.Accepted
+	}
+	return false
 }
-func (m *Feature) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_Feature.Marshal(b, m, deterministic)
+
+// several within queries in one HTTP request, see the /api/within POST endpoint
+type WithinBatchRequest struct {
+	Requests             []*WithinRequest `protobuf:"bytes,1,rep,name=requests,proto3" json:"requests,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
 }
-func (dst *Feature) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_Feature.Merge(dst, src)
+
+func (m *WithinBatchRequest) Reset()         { *m = WithinBatchRequest{} }
+func (m *WithinBatchRequest) String() string { return proto.CompactTextString(m) }
+func (*WithinBatchRequest) ProtoMessage()    {}
+func (*WithinBatchRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_insidesvc_d86dece99e9ccc55, []int{4}
 }
-func (m *Feature) XXX_Size() int {
-	return xxx_messageInfo_Feature.Size(m)
+func (m *WithinBatchRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WithinBatchRequest.Unmarshal(m, b)
 }
-func (m *Feature) XXX_DiscardUnknown() {
-	xxx_messageInfo_Feature.DiscardUnknown(m)
+func (m *WithinBatchRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WithinBatchRequest.Marshal(b, m, deterministic)
+}
+func (dst *WithinBatchRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WithinBatchRequest.Merge(dst, src)
+}
+func (m *WithinBatchRequest) XXX_Size() int {
+	return xxx_messageInfo_WithinBatchRequest.Size(m)
+}
+func (m *WithinBatchRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_WithinBatchRequest.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_Feature proto.InternalMessageInfo
+var xxx_messageInfo_WithinBatchRequest proto.InternalMessageInfo
 
-func (m *Feature) GetGeometry() *Geometry {
+func (m *WithinBatchRequest) GetRequests() []*WithinRequest {
 	if m != nil {
-		return m.Geometry
+		return m.Requests
 	}
 	return nil
 }
 
-func (m *Feature) GetProperties() map[string]*_struct.Value {
+type WithinBatchResponse struct {
+	Responses            []*WithinResponse `protobuf:"bytes,1,rep,name=responses,proto3" json:"responses,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *WithinBatchResponse) Reset()         { *m = WithinBatchResponse{} }
+func (m *WithinBatchResponse) String() string { return proto.CompactTextString(m) }
+func (*WithinBatchResponse) ProtoMessage()    {}
+func (*WithinBatchResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_insidesvc_d86dece99e9ccc55, []int{5}
+}
+func (m *WithinBatchResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WithinBatchResponse.Unmarshal(m, b)
+}
+func (m *WithinBatchResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WithinBatchResponse.Marshal(b, m, deterministic)
+}
+func (dst *WithinBatchResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WithinBatchResponse.Merge(dst, src)
+}
+func (m *WithinBatchResponse) XXX_Size() int {
+	return xxx_messageInfo_WithinBatchResponse.Size(m)
+}
+func (m *WithinBatchResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_WithinBatchResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WithinBatchResponse proto.InternalMessageInfo
+
+func (m *WithinBatchResponse) GetResponses() []*WithinResponse {
 	if m != nil {
-		return m.Properties
+		return m.Responses
 	}
 	return nil
 }
 
-type Geometry struct {
-	Type                 Geometry_Type `protobuf:"varint,1,opt,name=type,proto3,enum=Geometry_Type" json:"type,omitempty"`
-	Geometries           []*Geometry   `protobuf:"bytes,2,rep,name=geometries,proto3" json:"geometries,omitempty"`
-	Coordinates          []float64     `protobuf:"fixed64,3,rep,packed,name=coordinates,proto3" json:"coordinates,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
-	XXX_unrecognized     []byte        `json:"-"`
-	XXX_sizecache        int32         `json:"-"`
+// the reply to a within request over NATS, either the response or the error
+type WithinReply struct {
+	Response             *WithinResponse `protobuf:"bytes,1,opt,name=response,proto3" json:"response,omitempty"`
+	Error                *Error          `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
 }
 
-func (m *Geometry) Reset()         { *m = Geometry{} }
-func (m *Geometry) String() string { return proto.CompactTextString(m) }
-func (*Geometry) ProtoMessage()    {}
-func (*Geometry) Descriptor() ([]byte, []int) {
-	return fileDescriptor_insidesvc_b16b5f17af4aa25f, []int{5}
+func (m *WithinReply) Reset()         { *m = WithinReply{} }
+func (m *WithinReply) String() string { return proto.CompactTextString(m) }
+func (*WithinReply) ProtoMessage()    {}
+func (*WithinReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_insidesvc_d86dece99e9ccc55, []int{6}
 }
-func (m *Geometry) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_Geometry.Unmarshal(m, b)
+func (m *WithinReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WithinReply.Unmarshal(m, b)
 }
-func (m *Geometry) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_Geometry.Marshal(b, m, deterministic)
+func (m *WithinReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WithinReply.Marshal(b, m, deterministic)
 }
-func (dst *Geometry) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_Geometry.Merge(dst, src)
+func (dst *WithinReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WithinReply.Merge(dst, src)
 }
-func (m *Geometry) XXX_Size() int {
-	return xxx_messageInfo_Geometry.Size(m)
+func (m *WithinReply) XXX_Size() int {
+	return xxx_messageInfo_WithinReply.Size(m)
 }
-func (m *Geometry) XXX_DiscardUnknown() {
-	xxx_messageInfo_Geometry.DiscardUnknown(m)
+func (m *WithinReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_WithinReply.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_Geometry proto.InternalMessageInfo
-
-func (m *Geometry) GetType() Geometry_Type {
-	if m != nil {
-		return m.Type
-	}
-	return Geometry_POINT
-}
+var xxx_messageInfo_WithinReply proto.InternalMessageInfo
 
-func (m *Geometry) GetGeometries() []*Geometry {
+func (m *WithinReply) GetResponse() *WithinResponse {
 	if m != nil {
-		return m.Geometries
+		return m.Response
 	}
 	return nil
 }
 
-func (m *Geometry) GetCoordinates() []float64 {
+func (m *WithinReply) GetError() *Error {
 	if m != nil {
-		return m.Coordinates
+		return m.Error
 	}
 	return nil
 }
 
-type Point struct {
-	Lat                  float64  `protobuf:"fixed64,1,opt,name=lat,proto3" json:"lat,omitempty"`
-	Lng                  float64  `protobuf:"fixed64,2,opt,name=lng,proto3" json:"lng,omitempty"`
+// an error with its gRPC status code
+type Error struct {
+	Code                 int32    `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"`
+	Message              string   `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Error) Reset()         { *m = Error{} }
+func (m *Error) String() string { return proto.CompactTextString(m) }
+func (*Error) ProtoMessage()    {}
+func (*Error) Descriptor() ([]byte, []int) {
+	return fileDescriptor_insidesvc_d86dece99e9ccc55, []int{7}
+}
+func (m *Error) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Error.Unmarshal(m, b)
+}
+func (m *Error) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Error.Marshal(b, m, deterministic)
+}
+func (dst *Error) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Error.Merge(dst, src)
+}
+func (m *Error) XXX_Size() int {
+	return xxx_messageInfo_Error.Size(m)
+}
+func (m *Error) XXX_DiscardUnknown() {
+	xxx_messageInfo_Error.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Error proto.InternalMessageInfo
+
+func (m *Error) GetCode() int32 {
+	if m != nil {
+		return m.Code
+	}
+	return 0
+}
+
+func (m *Error) GetMessage() string {
+	if m != nil {
+		return m.Message
+	}
+	return ""
+}
+
+// a position of a tracked object
+type TrackRequest struct {
+	// id of the tracked object
+	Id  string  `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Lat float64 `protobuf:"fixed64,2,opt,name=lat,proto3" json:"lat,omitempty"`
+	Lng float64 `protobuf:"fixed64,3,opt,name=lng,proto3" json:"lng,omitempty"`
+	// position time as unix milliseconds, leave 0 for the reception time
+	Time int64 `protobuf:"varint,4,opt,name=time,proto3" json:"time,omitempty"`
+	// dataset to query, leave empty for the default dataset
+	Dataset string `protobuf:"bytes,5,opt,name=dataset,proto3" json:"dataset,omitempty"`
+	// comma separated list of property so returns to save extra bytes, leave empty for all
+	SelectProperties string `protobuf:"bytes,6,opt,name=select_properties,json=selectProperties,proto3" json:"select_properties,omitempty"`
+	// comma separated list of conditions on properties key=value or key!=value,
+	// only features matching all conditions are tracked, leave empty for all
+	Filter               string   `protobuf:"bytes,7,opt,name=filter,proto3" json:"filter,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TrackRequest) Reset()         { *m = TrackRequest{} }
+func (m *TrackRequest) String() string { return proto.CompactTextString(m) }
+func (*TrackRequest) ProtoMessage()    {}
+func (*TrackRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_insidesvc_d86dece99e9ccc55, []int{8}
+}
+func (m *TrackRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TrackRequest.Unmarshal(m, b)
+}
+func (m *TrackRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TrackRequest.Marshal(b, m, deterministic)
+}
+func (dst *TrackRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TrackRequest.Merge(dst, src)
+}
+func (m *TrackRequest) XXX_Size() int {
+	return xxx_messageInfo_TrackRequest.Size(m)
+}
+func (m *TrackRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_TrackRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TrackRequest proto.InternalMessageInfo
+
+func (m *TrackRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *TrackRequest) GetLat() float64 {
+	if m != nil {
+		return m.Lat
+	}
+	return 0
+}
+
+func (m *TrackRequest) GetLng() float64 {
+	if m != nil {
+		return m.Lng
+	}
+	return 0
+}
+
+func (m *TrackRequest) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+func (m *TrackRequest) GetDataset() string {
+	if m != nil {
+		return m.Dataset
+	}
+	return ""
+}
+
+func (m *TrackRequest) GetSelectProperties() string {
+	if m != nil {
+		return m.SelectProperties
+	}
+	return ""
+}
+
+func (m *TrackRequest) GetFilter() string {
+	if m != nil {
+		return m.Filter
+	}
+	return ""
+}
+
+type GeofenceEvent struct {
+	Type GeofenceEvent_Type `protobuf:"varint,1,opt,name=type,proto3,enum=GeofenceEvent_Type" json:"type,omitempty"`
+	// id of the tracked object
+	Id      string `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	Dataset string `protobuf:"bytes,3,opt,name=dataset,proto3" json:"dataset,omitempty"`
+	// id in the index of the feature entered, exited or dwelled in
+	FeatureId  uint32                    `protobuf:"varint,4,opt,name=feature_id,json=featureId,proto3" json:"feature_id,omitempty"`
+	Properties map[string]*_struct.Value `protobuf:"bytes,5,rep,name=properties,proto3" json:"properties,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Point      *Point                    `protobuf:"bytes,6,opt,name=point,proto3" json:"point,omitempty"`
+	// position time as unix milliseconds
+	Time int64 `protobuf:"varint,7,opt,name=time,proto3" json:"time,omitempty"`
+	// milliseconds spent inside the feature, for EXIT and DWELL
+	Duration             int64    `protobuf:"varint,8,opt,name=duration,proto3" json:"duration,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GeofenceEvent) Reset()         { *m = GeofenceEvent{} }
+func (m *GeofenceEvent) String() string { return proto.CompactTextString(m) }
+func (*GeofenceEvent) ProtoMessage()    {}
+func (*GeofenceEvent) Descriptor() ([]byte, []int) {
+	return fileDescriptor_insidesvc_d86dece99e9ccc55, []int{9}
+}
+func (m *GeofenceEvent) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GeofenceEvent.Unmarshal(m, b)
+}
+func (m *GeofenceEvent) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GeofenceEvent.Marshal(b, m, deterministic)
+}
+func (dst *GeofenceEvent) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GeofenceEvent.Merge(dst, src)
+}
+func (m *GeofenceEvent) XXX_Size() int {
+	return xxx_messageInfo_GeofenceEvent.Size(m)
+}
+func (m *GeofenceEvent) XXX_DiscardUnknown() {
+	xxx_messageInfo_GeofenceEvent.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GeofenceEvent proto.InternalMessageInfo
+
+func (m *GeofenceEvent) GetType() GeofenceEvent_Type {
+	if m != nil {
+		return m.Type
+	}
+	return GeofenceEvent_ENTER
+}
+
+func (m *GeofenceEvent) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *GeofenceEvent) GetDataset() string {
+	if m != nil {
+		return m.Dataset
+	}
+	return ""
+}
+
+func (m *GeofenceEvent) GetFeatureId() uint32 {
+	if m != nil {
+		return m.FeatureId
+	}
+	return 0
+}
+
+func (m *GeofenceEvent) GetProperties() map[string]*_struct.Value {
+	if m != nil {
+		return m.Properties
+	}
+	return nil
+}
+
+func (m *GeofenceEvent) GetPoint() *Point {
+	if m != nil {
+		return m.Point
+	}
+	return nil
+}
+
+func (m *GeofenceEvent) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+func (m *GeofenceEvent) GetDuration() int64 {
+	if m != nil {
+		return m.Duration
+	}
+	return 0
+}
+
+type NearestRequest struct {
+	Lat float64 `protobuf:"fixed64,1,opt,name=lat,proto3" json:"lat,omitempty"`
+	Lng float64 `protobuf:"fixed64,2,opt,name=lng,proto3" json:"lng,omitempty"`
+	// return features geometries or not
+	// saving extra bytes
+	RemoveGeometries bool `protobuf:"varint,3,opt,name=remove_geometries,json=removeGeometries,proto3" json:"remove_geometries,omitempty"`
+	// max distance in meters to look for a feature, 0 or above the server limit uses the server limit
+	MaxDistance float64 `protobuf:"fixed64,4,opt,name=max_distance,json=maxDistance,proto3" json:"max_distance,omitempty"`
+	// dataset to query, leave empty for the default dataset
+	Dataset              string   `protobuf:"bytes,5,opt,name=dataset,proto3" json:"dataset,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *NearestRequest) Reset()         { *m = NearestRequest{} }
+func (m *NearestRequest) String() string { return proto.CompactTextString(m) }
+func (*NearestRequest) ProtoMessage()    {}
+func (*NearestRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_insidesvc_d86dece99e9ccc55, []int{10}
+}
+func (m *NearestRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NearestRequest.Unmarshal(m, b)
+}
+func (m *NearestRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NearestRequest.Marshal(b, m, deterministic)
+}
+func (dst *NearestRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NearestRequest.Merge(dst, src)
+}
+func (m *NearestRequest) XXX_Size() int {
+	return xxx_messageInfo_NearestRequest.Size(m)
+}
+func (m *NearestRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_NearestRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NearestRequest proto.InternalMessageInfo
+
+func (m *NearestRequest) GetLat() float64 {
+	if m != nil {
+		return m.Lat
+	}
+	return 0
+}
+
+func (m *NearestRequest) GetLng() float64 {
+	if m != nil {
+		return m.Lng
+	}
+	return 0
+}
+
+func (m *NearestRequest) GetRemoveGeometries() bool {
+	if m != nil {
+		return m.RemoveGeometries
+	}
+	return false
+}
+
+func (m *NearestRequest) GetMaxDistance() float64 {
+	if m != nil {
+		return m.MaxDistance
+	}
+	return 0
+}
+
+func (m *NearestRequest) GetDataset() string {
+	if m != nil {
+		return m.Dataset
+	}
+	return ""
+}
+
+type NearestResponse struct {
+	Point *Point `protobuf:"bytes,1,opt,name=point,proto3" json:"point,omitempty"`
+	// empty if no feature was found within max distance
+	Response *FeatureResponse `protobuf:"bytes,2,opt,name=response,proto3" json:"response,omitempty"`
+	// distance in meters to the feature boundary, 0 when inside
+	Distance             float64  `protobuf:"fixed64,3,opt,name=distance,proto3" json:"distance,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *NearestResponse) Reset()         { *m = NearestResponse{} }
+func (m *NearestResponse) String() string { return proto.CompactTextString(m) }
+func (*NearestResponse) ProtoMessage()    {}
+func (*NearestResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_insidesvc_d86dece99e9ccc55, []int{11}
+}
+func (m *NearestResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NearestResponse.Unmarshal(m, b)
+}
+func (m *NearestResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NearestResponse.Marshal(b, m, deterministic)
+}
+func (dst *NearestResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NearestResponse.Merge(dst, src)
+}
+func (m *NearestResponse) XXX_Size() int {
+	return xxx_messageInfo_NearestResponse.Size(m)
+}
+func (m *NearestResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_NearestResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NearestResponse proto.InternalMessageInfo
+
+func (m *NearestResponse) GetPoint() *Point {
+	if m != nil {
+		return m.Point
+	}
+	return nil
+}
+
+func (m *NearestResponse) GetResponse() *FeatureResponse {
+	if m != nil {
+		return m.Response
+	}
+	return nil
+}
+
+func (m *NearestResponse) GetDistance() float64 {
+	if m != nil {
+		return m.Distance
+	}
+	return 0
+}
+
+type IntersectRequest struct {
+	// coordinates as lng lat, only the outer ring for polygons
+	Geometry *Geometry `protobuf:"bytes,1,opt,name=geometry,proto3" json:"geometry,omitempty"`
+	// return features geometries or not
+	// saving extra bytes
+	RemoveGeometries bool `protobuf:"varint,2,opt,name=remove_geometries,json=removeGeometries,proto3" json:"remove_geometries,omitempty"`
+	// dataset to query, leave empty for the default dataset
+	Dataset string `protobuf:"bytes,3,opt,name=dataset,proto3" json:"dataset,omitempty"`
+	// max number of responses returned, 0 or above the server limit uses the server limit
+	Limit int32 `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"`
+	// next_cursor of the previous response to get the next responses, empty for the first ones
+	Cursor               string   `protobuf:"bytes,5,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *IntersectRequest) Reset()         { *m = IntersectRequest{} }
+func (m *IntersectRequest) String() string { return proto.CompactTextString(m) }
+func (*IntersectRequest) ProtoMessage()    {}
+func (*IntersectRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_insidesvc_d86dece99e9ccc55, []int{12}
+}
+func (m *IntersectRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_IntersectRequest.Unmarshal(m, b)
+}
+func (m *IntersectRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_IntersectRequest.Marshal(b, m, deterministic)
+}
+func (dst *IntersectRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_IntersectRequest.Merge(dst, src)
+}
+func (m *IntersectRequest) XXX_Size() int {
+	return xxx_messageInfo_IntersectRequest.Size(m)
+}
+func (m *IntersectRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_IntersectRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_IntersectRequest proto.InternalMessageInfo
+
+func (m *IntersectRequest) GetGeometry() *Geometry {
+	if m != nil {
+		return m.Geometry
+	}
+	return nil
+}
+
+func (m *IntersectRequest) GetRemoveGeometries() bool {
+	if m != nil {
+		return m.RemoveGeometries
+	}
+	return false
+}
+
+func (m *IntersectRequest) GetDataset() string {
+	if m != nil {
+		return m.Dataset
+	}
+	return ""
+}
+
+func (m *IntersectRequest) GetLimit() int32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+func (m *IntersectRequest) GetCursor() string {
+	if m != nil {
+		return m.Cursor
+	}
+	return ""
+}
+
+type IntersectResponse struct {
+	Responses []*FeatureResponse `protobuf:"bytes,1,rep,name=responses,proto3" json:"responses,omitempty"`
+	// opaque cursor to pass in the next request when more features intersect the geometry, empty otherwise
+	NextCursor           string   `protobuf:"bytes,2,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *Point) Reset()         { *m = Point{} }
-func (m *Point) String() string { return proto.CompactTextString(m) }
-func (*Point) ProtoMessage()    {}
-func (*Point) Descriptor() ([]byte, []int) {
-	return fileDescriptor_insidesvc_b16b5f17af4aa25f, []int{6}
+func (m *IntersectResponse) Reset()         { *m = IntersectResponse{} }
+func (m *IntersectResponse) String() string { return proto.CompactTextString(m) }
+func (*IntersectResponse) ProtoMessage()    {}
+func (*IntersectResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_insidesvc_d86dece99e9ccc55, []int{13}
+}
+func (m *IntersectResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_IntersectResponse.Unmarshal(m, b)
+}
+func (m *IntersectResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_IntersectResponse.Marshal(b, m, deterministic)
+}
+func (dst *IntersectResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_IntersectResponse.Merge(dst, src)
+}
+func (m *IntersectResponse) XXX_Size() int {
+	return xxx_messageInfo_IntersectResponse.Size(m)
+}
+func (m *IntersectResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_IntersectResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_IntersectResponse proto.InternalMessageInfo
+
+func (m *IntersectResponse) GetResponses() []*FeatureResponse {
+	if m != nil {
+		return m.Responses
+	}
+	return nil
+}
+
+func (m *IntersectResponse) GetNextCursor() string {
+	if m != nil {
+		return m.NextCursor
+	}
+	return ""
+}
+
+type GetRequest struct {
+	Id uint32 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	// internally stored as uint16
+	LoopIndex uint32 `protobuf:"varint,2,opt,name=loop_index,json=loopIndex,proto3" json:"loop_index,omitempty"`
+	// dataset to query, leave empty for the default dataset
+	Dataset              string   `protobuf:"bytes,3,opt,name=dataset,proto3" json:"dataset,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetRequest) Reset()         { *m = GetRequest{} }
+func (m *GetRequest) String() string { return proto.CompactTextString(m) }
+func (*GetRequest) ProtoMessage()    {}
+func (*GetRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_insidesvc_d86dece99e9ccc55, []int{14}
+}
+func (m *GetRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetRequest.Unmarshal(m, b)
+}
+func (m *GetRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetRequest.Marshal(b, m, deterministic)
+}
+func (dst *GetRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetRequest.Merge(dst, src)
+}
+func (m *GetRequest) XXX_Size() int {
+	return xxx_messageInfo_GetRequest.Size(m)
+}
+func (m *GetRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetRequest proto.InternalMessageInfo
+
+func (m *GetRequest) GetId() uint32 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *GetRequest) GetLoopIndex() uint32 {
+	if m != nil {
+		return m.LoopIndex
+	}
+	return 0
+}
+
+func (m *GetRequest) GetDataset() string {
+	if m != nil {
+		return m.Dataset
+	}
+	return ""
+}
+
+type GetFeatureRequest struct {
+	Id uint32 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	// dataset to query, leave empty for the default dataset
+	Dataset string `protobuf:"bytes,2,opt,name=dataset,proto3" json:"dataset,omitempty"`
+	// Douglas-Peucker tolerance in meters to simplify the geometry, 0 for the stored geometry
+	Simplify float64 `protobuf:"fixed64,3,opt,name=simplify,proto3" json:"simplify,omitempty"`
+	// value of the id property of the feature, the indexer -idProperty, instead of id
+	ExternalId           string   `protobuf:"bytes,4,opt,name=external_id,json=externalId,proto3" json:"external_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetFeatureRequest) Reset()         { *m = GetFeatureRequest{} }
+func (m *GetFeatureRequest) String() string { return proto.CompactTextString(m) }
+func (*GetFeatureRequest) ProtoMessage()    {}
+func (*GetFeatureRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_insidesvc_d86dece99e9ccc55, []int{15}
+}
+func (m *GetFeatureRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetFeatureRequest.Unmarshal(m, b)
+}
+func (m *GetFeatureRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetFeatureRequest.Marshal(b, m, deterministic)
+}
+func (dst *GetFeatureRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetFeatureRequest.Merge(dst, src)
+}
+func (m *GetFeatureRequest) XXX_Size() int {
+	return xxx_messageInfo_GetFeatureRequest.Size(m)
+}
+func (m *GetFeatureRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetFeatureRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetFeatureRequest proto.InternalMessageInfo
+
+func (m *GetFeatureRequest) GetId() uint32 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *GetFeatureRequest) GetDataset() string {
+	if m != nil {
+		return m.Dataset
+	}
+	return ""
+}
+
+func (m *GetFeatureRequest) GetSimplify() float64 {
+	if m != nil {
+		return m.Simplify
+	}
+	return 0
+}
+
+func (m *GetFeatureRequest) GetExternalId() string {
+	if m != nil {
+		return m.ExternalId
+	}
+	return ""
+}
+
+type ListFeaturesRequest struct {
+	// comma separated list of conditions on properties key=value or key!=value,
+	// only features matching all conditions are returned, leave empty for all
+	Filter string `protobuf:"bytes,1,opt,name=filter,proto3" json:"filter,omitempty"`
+	// comma separated list of properties to return, leave empty for all
+	SelectProperties string `protobuf:"bytes,2,opt,name=select_properties,json=selectProperties,proto3" json:"select_properties,omitempty"`
+	// max number of responses returned, 0 or above the server limit uses the server limit
+	Limit int32 `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	// next_cursor of the previous response to get the next responses, empty for the first ones
+	Cursor string `protobuf:"bytes,4,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	// dataset to query, leave empty for the default dataset
+	Dataset              string   `protobuf:"bytes,5,opt,name=dataset,proto3" json:"dataset,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListFeaturesRequest) Reset()         { *m = ListFeaturesRequest{} }
+func (m *ListFeaturesRequest) String() string { return proto.CompactTextString(m) }
+func (*ListFeaturesRequest) ProtoMessage()    {}
+func (*ListFeaturesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_insidesvc_d86dece99e9ccc55, []int{16}
+}
+func (m *ListFeaturesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListFeaturesRequest.Unmarshal(m, b)
+}
+func (m *ListFeaturesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListFeaturesRequest.Marshal(b, m, deterministic)
+}
+func (dst *ListFeaturesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListFeaturesRequest.Merge(dst, src)
+}
+func (m *ListFeaturesRequest) XXX_Size() int {
+	return xxx_messageInfo_ListFeaturesRequest.Size(m)
+}
+func (m *ListFeaturesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListFeaturesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListFeaturesRequest proto.InternalMessageInfo
+
+func (m *ListFeaturesRequest) GetFilter() string {
+	if m != nil {
+		return m.Filter
+	}
+	return ""
+}
+
+func (m *ListFeaturesRequest) GetSelectProperties() string {
+	if m != nil {
+		return m.SelectProperties
+	}
+	return ""
+}
+
+func (m *ListFeaturesRequest) GetLimit() int32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+func (m *ListFeaturesRequest) GetCursor() string {
+	if m != nil {
+		return m.Cursor
+	}
+	return ""
+}
+
+func (m *ListFeaturesRequest) GetDataset() string {
+	if m != nil {
+		return m.Dataset
+	}
+	return ""
+}
+
+type ListFeaturesResponse struct {
+	// the matching features ordered by id, without their geometries
+	Responses []*FeatureResponse `protobuf:"bytes,1,rep,name=responses,proto3" json:"responses,omitempty"`
+	// opaque cursor to pass in the next request when more features match, empty otherwise
+	NextCursor           string   `protobuf:"bytes,2,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListFeaturesResponse) Reset()         { *m = ListFeaturesResponse{} }
+func (m *ListFeaturesResponse) String() string { return proto.CompactTextString(m) }
+func (*ListFeaturesResponse) ProtoMessage()    {}
+func (*ListFeaturesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_insidesvc_d86dece99e9ccc55, []int{17}
+}
+func (m *ListFeaturesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListFeaturesResponse.Unmarshal(m, b)
+}
+func (m *ListFeaturesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListFeaturesResponse.Marshal(b, m, deterministic)
+}
+func (dst *ListFeaturesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListFeaturesResponse.Merge(dst, src)
+}
+func (m *ListFeaturesResponse) XXX_Size() int {
+	return xxx_messageInfo_ListFeaturesResponse.Size(m)
+}
+func (m *ListFeaturesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListFeaturesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListFeaturesResponse proto.InternalMessageInfo
+
+func (m *ListFeaturesResponse) GetResponses() []*FeatureResponse {
+	if m != nil {
+		return m.Responses
+	}
+	return nil
+}
+
+func (m *ListFeaturesResponse) GetNextCursor() string {
+	if m != nil {
+		return m.NextCursor
+	}
+	return ""
+}
+
+type InsertFeatureRequest struct {
+	// polygon or multipolygon, coordinates as lng lat, the holes of the polygons follow their outer ring
+	// with the ring ends in ends
+	Feature *Feature `protobuf:"bytes,1,opt,name=feature,proto3" json:"feature,omitempty"`
+	// dataset to write, leave empty for the default dataset
+	Dataset              string   `protobuf:"bytes,2,opt,name=dataset,proto3" json:"dataset,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *InsertFeatureRequest) Reset()         { *m = InsertFeatureRequest{} }
+func (m *InsertFeatureRequest) String() string { return proto.CompactTextString(m) }
+func (*InsertFeatureRequest) ProtoMessage()    {}
+func (*InsertFeatureRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_insidesvc_d86dece99e9ccc55, []int{18}
+}
+func (m *InsertFeatureRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_InsertFeatureRequest.Unmarshal(m, b)
+}
+func (m *InsertFeatureRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_InsertFeatureRequest.Marshal(b, m, deterministic)
+}
+func (dst *InsertFeatureRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_InsertFeatureRequest.Merge(dst, src)
+}
+func (m *InsertFeatureRequest) XXX_Size() int {
+	return xxx_messageInfo_InsertFeatureRequest.Size(m)
+}
+func (m *InsertFeatureRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_InsertFeatureRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_InsertFeatureRequest proto.InternalMessageInfo
+
+func (m *InsertFeatureRequest) GetFeature() *Feature {
+	if m != nil {
+		return m.Feature
+	}
+	return nil
+}
+
+func (m *InsertFeatureRequest) GetDataset() string {
+	if m != nil {
+		return m.Dataset
+	}
+	return ""
+}
+
+type UpdateFeatureRequest struct {
+	Id uint32 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	// polygon or multipolygon, coordinates as lng lat, the holes of the polygons follow their outer ring
+	// with the ring ends in ends
+	Feature *Feature `protobuf:"bytes,2,opt,name=feature,proto3" json:"feature,omitempty"`
+	// dataset to write, leave empty for the default dataset
+	Dataset              string   `protobuf:"bytes,3,opt,name=dataset,proto3" json:"dataset,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *UpdateFeatureRequest) Reset()         { *m = UpdateFeatureRequest{} }
+func (m *UpdateFeatureRequest) String() string { return proto.CompactTextString(m) }
+func (*UpdateFeatureRequest) ProtoMessage()    {}
+func (*UpdateFeatureRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_insidesvc_d86dece99e9ccc55, []int{19}
+}
+func (m *UpdateFeatureRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UpdateFeatureRequest.Unmarshal(m, b)
+}
+func (m *UpdateFeatureRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UpdateFeatureRequest.Marshal(b, m, deterministic)
+}
+func (dst *UpdateFeatureRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UpdateFeatureRequest.Merge(dst, src)
+}
+func (m *UpdateFeatureRequest) XXX_Size() int {
+	return xxx_messageInfo_UpdateFeatureRequest.Size(m)
+}
+func (m *UpdateFeatureRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_UpdateFeatureRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UpdateFeatureRequest proto.InternalMessageInfo
+
+func (m *UpdateFeatureRequest) GetId() uint32 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *UpdateFeatureRequest) GetFeature() *Feature {
+	if m != nil {
+		return m.Feature
+	}
+	return nil
+}
+
+func (m *UpdateFeatureRequest) GetDataset() string {
+	if m != nil {
+		return m.Dataset
+	}
+	return ""
+}
+
+type DeleteFeatureRequest struct {
+	Id uint32 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	// dataset to write, leave empty for the default dataset
+	Dataset              string   `protobuf:"bytes,2,opt,name=dataset,proto3" json:"dataset,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteFeatureRequest) Reset()         { *m = DeleteFeatureRequest{} }
+func (m *DeleteFeatureRequest) String() string { return proto.CompactTextString(m) }
+func (*DeleteFeatureRequest) ProtoMessage()    {}
+func (*DeleteFeatureRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_insidesvc_d86dece99e9ccc55, []int{20}
+}
+func (m *DeleteFeatureRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteFeatureRequest.Unmarshal(m, b)
+}
+func (m *DeleteFeatureRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteFeatureRequest.Marshal(b, m, deterministic)
+}
+func (dst *DeleteFeatureRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteFeatureRequest.Merge(dst, src)
+}
+func (m *DeleteFeatureRequest) XXX_Size() int {
+	return xxx_messageInfo_DeleteFeatureRequest.Size(m)
+}
+func (m *DeleteFeatureRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteFeatureRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteFeatureRequest proto.InternalMessageInfo
+
+func (m *DeleteFeatureRequest) GetId() uint32 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *DeleteFeatureRequest) GetDataset() string {
+	if m != nil {
+		return m.Dataset
+	}
+	return ""
+}
+
+type WriteFeatureResponse struct {
+	// id of the written feature
+	Id                   uint32   `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WriteFeatureResponse) Reset()         { *m = WriteFeatureResponse{} }
+func (m *WriteFeatureResponse) String() string { return proto.CompactTextString(m) }
+func (*WriteFeatureResponse) ProtoMessage()    {}
+func (*WriteFeatureResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_insidesvc_d86dece99e9ccc55, []int{21}
+}
+func (m *WriteFeatureResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WriteFeatureResponse.Unmarshal(m, b)
+}
+func (m *WriteFeatureResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WriteFeatureResponse.Marshal(b, m, deterministic)
+}
+func (dst *WriteFeatureResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WriteFeatureResponse.Merge(dst, src)
+}
+func (m *WriteFeatureResponse) XXX_Size() int {
+	return xxx_messageInfo_WriteFeatureResponse.Size(m)
+}
+func (m *WriteFeatureResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_WriteFeatureResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WriteFeatureResponse proto.InternalMessageInfo
+
+func (m *WriteFeatureResponse) GetId() uint32 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+type FeatureResponse struct {
+	// id in the index
+	Id      uint32   `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Feature *Feature `protobuf:"bytes,3,opt,name=feature,proto3" json:"feature,omitempty"`
+	// distance in meters from the query point to the feature boundary,
+	// only set by Within when boundary_distance is requested
+	BoundaryDistance float64 `protobuf:"fixed64,4,opt,name=boundary_distance,json=boundaryDistance,proto3" json:"boundary_distance,omitempty"`
+	// true when the point was tested against the feature polygon,
+	// false when answered from an inside covering cell or from the results cache
+	Exact bool `protobuf:"varint,5,opt,name=exact,proto3" json:"exact,omitempty"`
+	// ids of the features containing this one, from the outermost to its parent,
+	// only set by Within when hierarchy is requested
+	AncestorIds          []uint32 `protobuf:"varint,6,rep,packed,name=ancestor_ids,json=ancestorIds,proto3" json:"ancestor_ids,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *FeatureResponse) Reset()         { *m = FeatureResponse{} }
+func (m *FeatureResponse) String() string { return proto.CompactTextString(m) }
+func (*FeatureResponse) ProtoMessage()    {}
+func (*FeatureResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_insidesvc_d86dece99e9ccc55, []int{22}
+}
+func (m *FeatureResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FeatureResponse.Unmarshal(m, b)
+}
+func (m *FeatureResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FeatureResponse.Marshal(b, m, deterministic)
+}
+func (dst *FeatureResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FeatureResponse.Merge(dst, src)
+}
+func (m *FeatureResponse) XXX_Size() int {
+	return xxx_messageInfo_FeatureResponse.Size(m)
+}
+func (m *FeatureResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_FeatureResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FeatureResponse proto.InternalMessageInfo
+
+func (m *FeatureResponse) GetId() uint32 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *FeatureResponse) GetFeature() *Feature {
+	if m != nil {
+		return m.Feature
+	}
+	return nil
+}
+
+func (m *FeatureResponse) GetBoundaryDistance() float64 {
+	if m != nil {
+		return m.BoundaryDistance
+	}
+	return 0
+}
+
+func (m *FeatureResponse) GetExact() bool {
+	if m != nil {
+		return m.Exact
+	}
+	return false
+}
+
+func (m *FeatureResponse) GetAncestorIds() []uint32 {
+	if m != nil {
+		return m.AncestorIds
+	}
+	return nil
+}
+
+type Feature struct {
+	Geometry             *Geometry                 `protobuf:"bytes,1,opt,name=geometry,proto3" json:"geometry,omitempty"`
+	Properties           map[string]*_struct.Value `protobuf:"bytes,2,rep,name=properties,proto3" json:"properties,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}                  `json:"-"`
+	XXX_unrecognized     []byte                    `json:"-"`
+	XXX_sizecache        int32                     `json:"-"`
+}
+
+func (m *Feature) Reset()         { *m = Feature{} }
+func (m *Feature) String() string { return proto.CompactTextString(m) }
+func (*Feature) ProtoMessage()    {}
+func (*Feature) Descriptor() ([]byte, []int) {
+	return fileDescriptor_insidesvc_d86dece99e9ccc55, []int{23}
+}
+func (m *Feature) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Feature.Unmarshal(m, b)
+}
+func (m *Feature) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Feature.Marshal(b, m, deterministic)
+}
+func (dst *Feature) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Feature.Merge(dst, src)
+}
+func (m *Feature) XXX_Size() int {
+	return xxx_messageInfo_Feature.Size(m)
+}
+func (m *Feature) XXX_DiscardUnknown() {
+	xxx_messageInfo_Feature.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Feature proto.InternalMessageInfo
+
+func (m *Feature) GetGeometry() *Geometry {
+	if m != nil {
+		return m.Geometry
+	}
+	return nil
+}
+
+func (m *Feature) GetProperties() map[string]*_struct.Value {
+	if m != nil {
+		return m.Properties
+	}
+	return nil
+}
+
+type Geometry struct {
+	Type        Geometry_Type `protobuf:"varint,1,opt,name=type,proto3,enum=Geometry_Type" json:"type,omitempty"`
+	Geometries  []*Geometry   `protobuf:"bytes,2,rep,name=geometries,proto3" json:"geometries,omitempty"`
+	Coordinates []float64     `protobuf:"fixed64,3,rep,packed,name=coordinates,proto3" json:"coordinates,omitempty"`
+	// the end index in coordinates of each ring of a POLYGON, the outer ring then its holes,
+	// empty for a polygon without holes
+	Ends                 []uint32 `protobuf:"varint,4,rep,packed,name=ends,proto3" json:"ends,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Geometry) Reset()         { *m = Geometry{} }
+func (m *Geometry) String() string { return proto.CompactTextString(m) }
+func (*Geometry) ProtoMessage()    {}
+func (*Geometry) Descriptor() ([]byte, []int) {
+	return fileDescriptor_insidesvc_d86dece99e9ccc55, []int{24}
+}
+func (m *Geometry) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Geometry.Unmarshal(m, b)
+}
+func (m *Geometry) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Geometry.Marshal(b, m, deterministic)
+}
+func (dst *Geometry) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Geometry.Merge(dst, src)
+}
+func (m *Geometry) XXX_Size() int {
+	return xxx_messageInfo_Geometry.Size(m)
+}
+func (m *Geometry) XXX_DiscardUnknown() {
+	xxx_messageInfo_Geometry.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Geometry proto.InternalMessageInfo
+
+func (m *Geometry) GetType() Geometry_Type {
+	if m != nil {
+		return m.Type
+	}
+	return Geometry_POINT
+}
+
+func (m *Geometry) GetGeometries() []*Geometry {
+	if m != nil {
+		return m.Geometries
+	}
+	return nil
+}
+
+func (m *Geometry) GetCoordinates() []float64 {
+	if m != nil {
+		return m.Coordinates
+	}
+	return nil
+}
+
+func (m *Geometry) GetEnds() []uint32 {
+	if m != nil {
+		return m.Ends
+	}
+	return nil
+}
+
+type InfoRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *InfoRequest) Reset()         { *m = InfoRequest{} }
+func (m *InfoRequest) String() string { return proto.CompactTextString(m) }
+func (*InfoRequest) ProtoMessage()    {}
+func (*InfoRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_insidesvc_d86dece99e9ccc55, []int{25}
+}
+func (m *InfoRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_InfoRequest.Unmarshal(m, b)
+}
+func (m *InfoRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_InfoRequest.Marshal(b, m, deterministic)
+}
+func (dst *InfoRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_InfoRequest.Merge(dst, src)
+}
+func (m *InfoRequest) XXX_Size() int {
+	return xxx_messageInfo_InfoRequest.Size(m)
+}
+func (m *InfoRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_InfoRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_InfoRequest proto.InternalMessageInfo
+
+type InfoResponse struct {
+	// name of the dataset served when a request does not name one
+	DefaultDataset string         `protobuf:"bytes,1,opt,name=default_dataset,json=defaultDataset,proto3" json:"default_dataset,omitempty"`
+	Datasets       []*DatasetInfo `protobuf:"bytes,2,rep,name=datasets,proto3" json:"datasets,omitempty"`
+	// version of the running server
+	Version string `protobuf:"bytes,3,opt,name=version,proto3" json:"version,omitempty"`
+	// server start time as unix seconds
+	StartTime int64 `protobuf:"varint,4,opt,name=start_time,json=startTime,proto3" json:"start_time,omitempty"`
+	// seconds since the server start
+	Uptime int64 `protobuf:"varint,5,opt,name=uptime,proto3" json:"uptime,omitempty"`
+	// tenant of the API key of the request, empty without tenants
+	Tenant string `protobuf:"bytes,6,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	// max number of features the tenant can own, 0 for no limit
+	TenantQuota          uint32   `protobuf:"varint,7,opt,name=tenant_quota,json=tenantQuota,proto3" json:"tenant_quota,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *InfoResponse) Reset()         { *m = InfoResponse{} }
+func (m *InfoResponse) String() string { return proto.CompactTextString(m) }
+func (*InfoResponse) ProtoMessage()    {}
+func (*InfoResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_insidesvc_d86dece99e9ccc55, []int{26}
+}
+func (m *InfoResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_InfoResponse.Unmarshal(m, b)
+}
+func (m *InfoResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_InfoResponse.Marshal(b, m, deterministic)
+}
+func (dst *InfoResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_InfoResponse.Merge(dst, src)
+}
+func (m *InfoResponse) XXX_Size() int {
+	return xxx_messageInfo_InfoResponse.Size(m)
+}
+func (m *InfoResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_InfoResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_InfoResponse proto.InternalMessageInfo
+
+func (m *InfoResponse) GetDefaultDataset() string {
+	if m != nil {
+		return m.DefaultDataset
+	}
+	return ""
+}
+
+func (m *InfoResponse) GetDatasets() []*DatasetInfo {
+	if m != nil {
+		return m.Datasets
+	}
+	return nil
+}
+
+func (m *InfoResponse) GetVersion() string {
+	if m != nil {
+		return m.Version
+	}
+	return ""
+}
+
+func (m *InfoResponse) GetStartTime() int64 {
+	if m != nil {
+		return m.StartTime
+	}
+	return 0
+}
+
+func (m *InfoResponse) GetUptime() int64 {
+	if m != nil {
+		return m.Uptime
+	}
+	return 0
+}
+
+func (m *InfoResponse) GetTenant() string {
+	if m != nil {
+		return m.Tenant
+	}
+	return ""
+}
+
+func (m *InfoResponse) GetTenantQuota() uint32 {
+	if m != nil {
+		return m.TenantQuota
+	}
+	return 0
+}
+
+type DatasetInfo struct {
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// comma separated list of the indexed files
+	Filename     string `protobuf:"bytes,2,opt,name=filename,proto3" json:"filename,omitempty"`
+	FeatureCount uint32 `protobuf:"varint,3,opt,name=feature_count,json=featureCount,proto3" json:"feature_count,omitempty"`
+	// index time as unix seconds
+	IndexTime      int64  `protobuf:"varint,4,opt,name=index_time,json=indexTime,proto3" json:"index_time,omitempty"`
+	IndexerVersion string `protobuf:"bytes,5,opt,name=indexer_version,json=indexerVersion,proto3" json:"indexer_version,omitempty"`
+	MinCoverLevel  int32  `protobuf:"varint,6,opt,name=min_cover_level,json=minCoverLevel,proto3" json:"min_cover_level,omitempty"`
+	// strategy used to query the dataset
+	Strategy string `protobuf:"bytes,7,opt,name=strategy,proto3" json:"strategy,omitempty"`
+	// coverers parameters used to index, empty for older DBs
+	InsideCover  *CoverOptions `protobuf:"bytes,8,opt,name=inside_cover,json=insideCover,proto3" json:"inside_cover,omitempty"`
+	OutsideCover *CoverOptions `protobuf:"bytes,9,opt,name=outside_cover,json=outsideCover,proto3" json:"outside_cover,omitempty"`
+	// the cover levels of each feature were tuned to its extent
+	AutoCover bool `protobuf:"varint,10,opt,name=auto_cover,json=autoCover,proto3" json:"auto_cover,omitempty"`
+	// number of features of the dataset owned by the tenant of the request
+	TenantFeatureCount uint32 `protobuf:"varint,11,opt,name=tenant_feature_count,json=tenantFeatureCount,proto3" json:"tenant_feature_count,omitempty"`
+	// codec compressing the stored features, empty when not compressed
+	Compression string `protobuf:"bytes,12,opt,name=compression,proto3" json:"compression,omitempty"`
+	// encoding of the stored loops, empty for the s2 encoding
+	LoopEncoding string `protobuf:"bytes,13,opt,name=loop_encoding,json=loopEncoding,proto3" json:"loop_encoding,omitempty"`
+	// strategies whose indexes are loaded, selectable by the within requests, strategy first
+	Strategies           []string `protobuf:"bytes,14,rep,name=strategies,proto3" json:"strategies,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DatasetInfo) Reset()         { *m = DatasetInfo{} }
+func (m *DatasetInfo) String() string { return proto.CompactTextString(m) }
+func (*DatasetInfo) ProtoMessage()    {}
+func (*DatasetInfo) Descriptor() ([]byte, []int) {
+	return fileDescriptor_insidesvc_d86dece99e9ccc55, []int{27}
+}
+func (m *DatasetInfo) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DatasetInfo.Unmarshal(m, b)
+}
+func (m *DatasetInfo) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DatasetInfo.Marshal(b, m, deterministic)
+}
+func (dst *DatasetInfo) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DatasetInfo.Merge(dst, src)
+}
+func (m *DatasetInfo) XXX_Size() int {
+	return xxx_messageInfo_DatasetInfo.Size(m)
+}
+func (m *DatasetInfo) XXX_DiscardUnknown() {
+	xxx_messageInfo_DatasetInfo.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DatasetInfo proto.InternalMessageInfo
+
+func (m *DatasetInfo) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *DatasetInfo) GetFilename() string {
+	if m != nil {
+		return m.Filename
+	}
+	return ""
+}
+
+func (m *DatasetInfo) GetFeatureCount() uint32 {
+	if m != nil {
+		return m.FeatureCount
+	}
+	return 0
+}
+
+func (m *DatasetInfo) GetIndexTime() int64 {
+	if m != nil {
+		return m.IndexTime
+	}
+	return 0
+}
+
+func (m *DatasetInfo) GetIndexerVersion() string {
+	if m != nil {
+		return m.IndexerVersion
+	}
+	return ""
+}
+
+func (m *DatasetInfo) GetMinCoverLevel() int32 {
+	if m != nil {
+		return m.MinCoverLevel
+	}
+	return 0
+}
+
+func (m *DatasetInfo) GetStrategy() string {
+	if m != nil {
+		return m.Strategy
+	}
+	return ""
+}
+
+func (m *DatasetInfo) GetInsideCover() *CoverOptions {
+	if m != nil {
+		return m.InsideCover
+	}
+	return nil
+}
+
+func (m *DatasetInfo) GetOutsideCover() *CoverOptions {
+	if m != nil {
+		return m.OutsideCover
+	}
+	return nil
+}
+
+func (m *DatasetInfo) GetAutoCover() bool {
+	if m != nil {
+		return m.AutoCover
+	}
+	return false
+}
+
+func (m *DatasetInfo) GetTenantFeatureCount() uint32 {
+	if m != nil {
+		return m.TenantFeatureCount
+	}
+	return 0
+}
+
+func (m *DatasetInfo) GetCompression() string {
+	if m != nil {
+		return m.Compression
+	}
+	return ""
+}
+
+func (m *DatasetInfo) GetLoopEncoding() string {
+	if m != nil {
+		return m.LoopEncoding
+	}
+	return ""
+}
+
+func (m *DatasetInfo) GetStrategies() []string {
+	if m != nil {
+		return m.Strategies
+	}
+	return nil
+}
+
+// parameters of an S2 region coverer
+type CoverOptions struct {
+	MinLevel             int32    `protobuf:"varint,1,opt,name=min_level,json=minLevel,proto3" json:"min_level,omitempty"`
+	MaxLevel             int32    `protobuf:"varint,2,opt,name=max_level,json=maxLevel,proto3" json:"max_level,omitempty"`
+	MaxCells             int32    `protobuf:"varint,3,opt,name=max_cells,json=maxCells,proto3" json:"max_cells,omitempty"`
+	LevelMod             int32    `protobuf:"varint,4,opt,name=level_mod,json=levelMod,proto3" json:"level_mod,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CoverOptions) Reset()         { *m = CoverOptions{} }
+func (m *CoverOptions) String() string { return proto.CompactTextString(m) }
+func (*CoverOptions) ProtoMessage()    {}
+func (*CoverOptions) Descriptor() ([]byte, []int) {
+	return fileDescriptor_insidesvc_d86dece99e9ccc55, []int{28}
+}
+func (m *CoverOptions) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CoverOptions.Unmarshal(m, b)
+}
+func (m *CoverOptions) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CoverOptions.Marshal(b, m, deterministic)
+}
+func (dst *CoverOptions) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CoverOptions.Merge(dst, src)
+}
+func (m *CoverOptions) XXX_Size() int {
+	return xxx_messageInfo_CoverOptions.Size(m)
+}
+func (m *CoverOptions) XXX_DiscardUnknown() {
+	xxx_messageInfo_CoverOptions.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CoverOptions proto.InternalMessageInfo
+
+func (m *CoverOptions) GetMinLevel() int32 {
+	if m != nil {
+		return m.MinLevel
+	}
+	return 0
+}
+
+func (m *CoverOptions) GetMaxLevel() int32 {
+	if m != nil {
+		return m.MaxLevel
+	}
+	return 0
+}
+
+func (m *CoverOptions) GetMaxCells() int32 {
+	if m != nil {
+		return m.MaxCells
+	}
+	return 0
+}
+
+func (m *CoverOptions) GetLevelMod() int32 {
+	if m != nil {
+		return m.LevelMod
+	}
+	return 0
+}
+
+type Point struct {
+	Lat                  float64  `protobuf:"fixed64,1,opt,name=lat,proto3" json:"lat,omitempty"`
+	Lng                  float64  `protobuf:"fixed64,2,opt,name=lng,proto3" json:"lng,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Point) Reset()         { *m = Point{} }
+func (m *Point) String() string { return proto.CompactTextString(m) }
+func (*Point) ProtoMessage()    {}
+func (*Point) Descriptor() ([]byte, []int) {
+	return fileDescriptor_insidesvc_d86dece99e9ccc55, []int{29}
+}
+func (m *Point) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Point.Unmarshal(m, b)
+}
+func (m *Point) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Point.Marshal(b, m, deterministic)
+}
+func (dst *Point) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Point.Merge(dst, src)
+}
+func (m *Point) XXX_Size() int {
+	return xxx_messageInfo_Point.Size(m)
+}
+func (m *Point) XXX_DiscardUnknown() {
+	xxx_messageInfo_Point.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Point proto.InternalMessageInfo
+
+func (m *Point) GetLat() float64 {
+	if m != nil {
+		return m.Lat
+	}
+	return 0
+}
+
+func (m *Point) GetLng() float64 {
+	if m != nil {
+		return m.Lng
+	}
+	return 0
+}
+
+type StatusRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *StatusRequest) Reset()         { *m = StatusRequest{} }
+func (m *StatusRequest) String() string { return proto.CompactTextString(m) }
+func (*StatusRequest) ProtoMessage()    {}
+func (*StatusRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_insidesvc_d86dece99e9ccc55, []int{30}
+}
+func (m *StatusRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StatusRequest.Unmarshal(m, b)
+}
+func (m *StatusRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StatusRequest.Marshal(b, m, deterministic)
+}
+func (dst *StatusRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StatusRequest.Merge(dst, src)
+}
+func (m *StatusRequest) XXX_Size() int {
+	return xxx_messageInfo_StatusRequest.Size(m)
+}
+func (m *StatusRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_StatusRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StatusRequest proto.InternalMessageInfo
+
+type SetLogLevelRequest struct {
+	Level                string   `protobuf:"bytes,1,opt,name=level,proto3" json:"level,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SetLogLevelRequest) Reset()         { *m = SetLogLevelRequest{} }
+func (m *SetLogLevelRequest) String() string { return proto.CompactTextString(m) }
+func (*SetLogLevelRequest) ProtoMessage()    {}
+func (*SetLogLevelRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_insidesvc_d86dece99e9ccc55, []int{31}
+}
+func (m *SetLogLevelRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SetLogLevelRequest.Unmarshal(m, b)
+}
+func (m *SetLogLevelRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SetLogLevelRequest.Marshal(b, m, deterministic)
+}
+func (dst *SetLogLevelRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SetLogLevelRequest.Merge(dst, src)
+}
+func (m *SetLogLevelRequest) XXX_Size() int {
+	return xxx_messageInfo_SetLogLevelRequest.Size(m)
+}
+func (m *SetLogLevelRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SetLogLevelRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SetLogLevelRequest proto.InternalMessageInfo
+
+func (m *SetLogLevelRequest) GetLevel() string {
+	if m != nil {
+		return m.Level
+	}
+	return ""
+}
+
+type SetStopOnFirstFoundRequest struct {
+	StopOnFirstFound     bool     `protobuf:"varint,1,opt,name=stop_on_first_found,json=stopOnFirstFound,proto3" json:"stop_on_first_found,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SetStopOnFirstFoundRequest) Reset()         { *m = SetStopOnFirstFoundRequest{} }
+func (m *SetStopOnFirstFoundRequest) String() string { return proto.CompactTextString(m) }
+func (*SetStopOnFirstFoundRequest) ProtoMessage()    {}
+func (*SetStopOnFirstFoundRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_insidesvc_d86dece99e9ccc55, []int{32}
+}
+func (m *SetStopOnFirstFoundRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SetStopOnFirstFoundRequest.Unmarshal(m, b)
+}
+func (m *SetStopOnFirstFoundRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SetStopOnFirstFoundRequest.Marshal(b, m, deterministic)
+}
+func (dst *SetStopOnFirstFoundRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SetStopOnFirstFoundRequest.Merge(dst, src)
+}
+func (m *SetStopOnFirstFoundRequest) XXX_Size() int {
+	return xxx_messageInfo_SetStopOnFirstFoundRequest.Size(m)
+}
+func (m *SetStopOnFirstFoundRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SetStopOnFirstFoundRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SetStopOnFirstFoundRequest proto.InternalMessageInfo
+
+func (m *SetStopOnFirstFoundRequest) GetStopOnFirstFound() bool {
+	if m != nil {
+		return m.StopOnFirstFound
+	}
+	return false
+}
+
+type ResizeCacheRequest struct {
+	Cache                ResizeCacheRequest_Cache `protobuf:"varint,1,opt,name=cache,proto3,enum=ResizeCacheRequest_Cache" json:"cache,omitempty"`
+	Count                int32                    `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                 `json:"-"`
+	XXX_unrecognized     []byte                   `json:"-"`
+	XXX_sizecache        int32                    `json:"-"`
+}
+
+func (m *ResizeCacheRequest) Reset()         { *m = ResizeCacheRequest{} }
+func (m *ResizeCacheRequest) String() string { return proto.CompactTextString(m) }
+func (*ResizeCacheRequest) ProtoMessage()    {}
+func (*ResizeCacheRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_insidesvc_d86dece99e9ccc55, []int{33}
+}
+func (m *ResizeCacheRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ResizeCacheRequest.Unmarshal(m, b)
+}
+func (m *ResizeCacheRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ResizeCacheRequest.Marshal(b, m, deterministic)
+}
+func (dst *ResizeCacheRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ResizeCacheRequest.Merge(dst, src)
+}
+func (m *ResizeCacheRequest) XXX_Size() int {
+	return xxx_messageInfo_ResizeCacheRequest.Size(m)
+}
+func (m *ResizeCacheRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ResizeCacheRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ResizeCacheRequest proto.InternalMessageInfo
+
+func (m *ResizeCacheRequest) GetCache() ResizeCacheRequest_Cache {
+	if m != nil {
+		return m.Cache
+	}
+	return ResizeCacheRequest_FEATURES
+}
+
+func (m *ResizeCacheRequest) GetCount() int32 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+type DrainRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DrainRequest) Reset()         { *m = DrainRequest{} }
+func (m *DrainRequest) String() string { return proto.CompactTextString(m) }
+func (*DrainRequest) ProtoMessage()    {}
+func (*DrainRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_insidesvc_d86dece99e9ccc55, []int{34}
+}
+func (m *DrainRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DrainRequest.Unmarshal(m, b)
+}
+func (m *DrainRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DrainRequest.Marshal(b, m, deterministic)
+}
+func (dst *DrainRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DrainRequest.Merge(dst, src)
+}
+func (m *DrainRequest) XXX_Size() int {
+	return xxx_messageInfo_DrainRequest.Size(m)
+}
+func (m *DrainRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DrainRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DrainRequest proto.InternalMessageInfo
+
+// the runtime settings of a server
+type AdminStatus struct {
+	LogLevel             string   `protobuf:"bytes,1,opt,name=log_level,json=logLevel,proto3" json:"log_level,omitempty"`
+	StopOnFirstFound     bool     `protobuf:"varint,2,opt,name=stop_on_first_found,json=stopOnFirstFound,proto3" json:"stop_on_first_found,omitempty"`
+	CacheCount           int32    `protobuf:"varint,3,opt,name=cache_count,json=cacheCount,proto3" json:"cache_count,omitempty"`
+	ResultCacheCount     int32    `protobuf:"varint,4,opt,name=result_cache_count,json=resultCacheCount,proto3" json:"result_cache_count,omitempty"`
+	Draining             bool     `protobuf:"varint,5,opt,name=draining,proto3" json:"draining,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AdminStatus) Reset()         { *m = AdminStatus{} }
+func (m *AdminStatus) String() string { return proto.CompactTextString(m) }
+func (*AdminStatus) ProtoMessage()    {}
+func (*AdminStatus) Descriptor() ([]byte, []int) {
+	return fileDescriptor_insidesvc_d86dece99e9ccc55, []int{35}
+}
+func (m *AdminStatus) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AdminStatus.Unmarshal(m, b)
+}
+func (m *AdminStatus) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AdminStatus.Marshal(b, m, deterministic)
+}
+func (dst *AdminStatus) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AdminStatus.Merge(dst, src)
+}
+func (m *AdminStatus) XXX_Size() int {
+	return xxx_messageInfo_AdminStatus.Size(m)
+}
+func (m *AdminStatus) XXX_DiscardUnknown() {
+	xxx_messageInfo_AdminStatus.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AdminStatus proto.InternalMessageInfo
+
+func (m *AdminStatus) GetLogLevel() string {
+	if m != nil {
+		return m.LogLevel
+	}
+	return ""
+}
+
+func (m *AdminStatus) GetStopOnFirstFound() bool {
+	if m != nil {
+		return m.StopOnFirstFound
+	}
+	return false
+}
+
+func (m *AdminStatus) GetCacheCount() int32 {
+	if m != nil {
+		return m.CacheCount
+	}
+	return 0
+}
+
+func (m *AdminStatus) GetResultCacheCount() int32 {
+	if m != nil {
+		return m.ResultCacheCount
+	}
+	return 0
+}
+
+func (m *AdminStatus) GetDraining() bool {
+	if m != nil {
+		return m.Draining
+	}
+	return false
+}
+
+type VersionsRequest struct {
+	// dataset served from a versions directory, leave empty for the default dataset
+	Dataset              string   `protobuf:"bytes,1,opt,name=dataset,proto3" json:"dataset,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *VersionsRequest) Reset()         { *m = VersionsRequest{} }
+func (m *VersionsRequest) String() string { return proto.CompactTextString(m) }
+func (*VersionsRequest) ProtoMessage()    {}
+func (*VersionsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_insidesvc_d86dece99e9ccc55, []int{36}
+}
+func (m *VersionsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_VersionsRequest.Unmarshal(m, b)
+}
+func (m *VersionsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_VersionsRequest.Marshal(b, m, deterministic)
+}
+func (dst *VersionsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_VersionsRequest.Merge(dst, src)
+}
+func (m *VersionsRequest) XXX_Size() int {
+	return xxx_messageInfo_VersionsRequest.Size(m)
+}
+func (m *VersionsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_VersionsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_VersionsRequest proto.InternalMessageInfo
+
+func (m *VersionsRequest) GetDataset() string {
+	if m != nil {
+		return m.Dataset
+	}
+	return ""
+}
+
+type PromoteVersionRequest struct {
+	Dataset string `protobuf:"bytes,1,opt,name=dataset,proto3" json:"dataset,omitempty"`
+	// name of the version in the versions subdirectory
+	Version              string   `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PromoteVersionRequest) Reset()         { *m = PromoteVersionRequest{} }
+func (m *PromoteVersionRequest) String() string { return proto.CompactTextString(m) }
+func (*PromoteVersionRequest) ProtoMessage()    {}
+func (*PromoteVersionRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_insidesvc_d86dece99e9ccc55, []int{37}
+}
+func (m *PromoteVersionRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PromoteVersionRequest.Unmarshal(m, b)
+}
+func (m *PromoteVersionRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PromoteVersionRequest.Marshal(b, m, deterministic)
+}
+func (dst *PromoteVersionRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PromoteVersionRequest.Merge(dst, src)
+}
+func (m *PromoteVersionRequest) XXX_Size() int {
+	return xxx_messageInfo_PromoteVersionRequest.Size(m)
+}
+func (m *PromoteVersionRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_PromoteVersionRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PromoteVersionRequest proto.InternalMessageInfo
+
+func (m *PromoteVersionRequest) GetDataset() string {
+	if m != nil {
+		return m.Dataset
+	}
+	return ""
+}
+
+func (m *PromoteVersionRequest) GetVersion() string {
+	if m != nil {
+		return m.Version
+	}
+	return ""
+}
+
+type DatasetVersion struct {
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// size in bytes
+	Size int64 `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+	// modification time as unix milliseconds
+	ModTime              int64    `protobuf:"varint,3,opt,name=mod_time,json=modTime,proto3" json:"mod_time,omitempty"`
+	Current              bool     `protobuf:"varint,4,opt,name=current,proto3" json:"current,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DatasetVersion) Reset()         { *m = DatasetVersion{} }
+func (m *DatasetVersion) String() string { return proto.CompactTextString(m) }
+func (*DatasetVersion) ProtoMessage()    {}
+func (*DatasetVersion) Descriptor() ([]byte, []int) {
+	return fileDescriptor_insidesvc_d86dece99e9ccc55, []int{38}
+}
+func (m *DatasetVersion) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DatasetVersion.Unmarshal(m, b)
+}
+func (m *DatasetVersion) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DatasetVersion.Marshal(b, m, deterministic)
+}
+func (dst *DatasetVersion) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DatasetVersion.Merge(dst, src)
+}
+func (m *DatasetVersion) XXX_Size() int {
+	return xxx_messageInfo_DatasetVersion.Size(m)
+}
+func (m *DatasetVersion) XXX_DiscardUnknown() {
+	xxx_messageInfo_DatasetVersion.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DatasetVersion proto.InternalMessageInfo
+
+func (m *DatasetVersion) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *DatasetVersion) GetSize() int64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+func (m *DatasetVersion) GetModTime() int64 {
+	if m != nil {
+		return m.ModTime
+	}
+	return 0
+}
+
+func (m *DatasetVersion) GetCurrent() bool {
+	if m != nil {
+		return m.Current
+	}
+	return false
+}
+
+type VersionsResponse struct {
+	Dataset string `protobuf:"bytes,1,opt,name=dataset,proto3" json:"dataset,omitempty"`
+	// the served version
+	Current string `protobuf:"bytes,2,opt,name=current,proto3" json:"current,omitempty"`
+	// the version restored by a rollback, empty for none
+	Previous             string            `protobuf:"bytes,3,opt,name=previous,proto3" json:"previous,omitempty"`
+	Versions             []*DatasetVersion `protobuf:"bytes,4,rep,name=versions,proto3" json:"versions,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *VersionsResponse) Reset()         { *m = VersionsResponse{} }
+func (m *VersionsResponse) String() string { return proto.CompactTextString(m) }
+func (*VersionsResponse) ProtoMessage()    {}
+func (*VersionsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_insidesvc_d86dece99e9ccc55, []int{39}
+}
+func (m *VersionsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_VersionsResponse.Unmarshal(m, b)
+}
+func (m *VersionsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_VersionsResponse.Marshal(b, m, deterministic)
+}
+func (dst *VersionsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_VersionsResponse.Merge(dst, src)
+}
+func (m *VersionsResponse) XXX_Size() int {
+	return xxx_messageInfo_VersionsResponse.Size(m)
+}
+func (m *VersionsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_VersionsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_VersionsResponse proto.InternalMessageInfo
+
+func (m *VersionsResponse) GetDataset() string {
+	if m != nil {
+		return m.Dataset
+	}
+	return ""
+}
+
+func (m *VersionsResponse) GetCurrent() string {
+	if m != nil {
+		return m.Current
+	}
+	return ""
+}
+
+func (m *VersionsResponse) GetPrevious() string {
+	if m != nil {
+		return m.Previous
+	}
+	return ""
+}
+
+func (m *VersionsResponse) GetVersions() []*DatasetVersion {
+	if m != nil {
+		return m.Versions
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*WithinRequest)(nil), "WithinRequest")
+	proto.RegisterType((*WithinResponse)(nil), "WithinResponse")
+	proto.RegisterType((*WithinDebug)(nil), "WithinDebug")
+	proto.RegisterType((*WithinCandidate)(nil), "WithinCandidate")
+	proto.RegisterType((*WithinBatchRequest)(nil), "WithinBatchRequest")
+	proto.RegisterType((*WithinBatchResponse)(nil), "WithinBatchResponse")
+	proto.RegisterType((*WithinReply)(nil), "WithinReply")
+	proto.RegisterType((*Error)(nil), "Error")
+	proto.RegisterType((*TrackRequest)(nil), "TrackRequest")
+	proto.RegisterType((*GeofenceEvent)(nil), "GeofenceEvent")
+	proto.RegisterMapType((map[string]*_struct.Value)(nil), "GeofenceEvent.PropertiesEntry")
+	proto.RegisterType((*NearestRequest)(nil), "NearestRequest")
+	proto.RegisterType((*NearestResponse)(nil), "NearestResponse")
+	proto.RegisterType((*IntersectRequest)(nil), "IntersectRequest")
+	proto.RegisterType((*IntersectResponse)(nil), "IntersectResponse")
+	proto.RegisterType((*GetRequest)(nil), "GetRequest")
+	proto.RegisterType((*GetFeatureRequest)(nil), "GetFeatureRequest")
+	proto.RegisterType((*ListFeaturesRequest)(nil), "ListFeaturesRequest")
+	proto.RegisterType((*ListFeaturesResponse)(nil), "ListFeaturesResponse")
+	proto.RegisterType((*InsertFeatureRequest)(nil), "InsertFeatureRequest")
+	proto.RegisterType((*UpdateFeatureRequest)(nil), "UpdateFeatureRequest")
+	proto.RegisterType((*DeleteFeatureRequest)(nil), "DeleteFeatureRequest")
+	proto.RegisterType((*WriteFeatureResponse)(nil), "WriteFeatureResponse")
+	proto.RegisterType((*FeatureResponse)(nil), "FeatureResponse")
+	proto.RegisterType((*Feature)(nil), "Feature")
+	proto.RegisterMapType((map[string]*_struct.Value)(nil), "Feature.PropertiesEntry")
+	proto.RegisterType((*Geometry)(nil), "Geometry")
+	proto.RegisterType((*InfoRequest)(nil), "InfoRequest")
+	proto.RegisterType((*InfoResponse)(nil), "InfoResponse")
+	proto.RegisterType((*DatasetInfo)(nil), "DatasetInfo")
+	proto.RegisterType((*CoverOptions)(nil), "CoverOptions")
+	proto.RegisterType((*Point)(nil), "Point")
+	proto.RegisterType((*StatusRequest)(nil), "StatusRequest")
+	proto.RegisterType((*SetLogLevelRequest)(nil), "SetLogLevelRequest")
+	proto.RegisterType((*SetStopOnFirstFoundRequest)(nil), "SetStopOnFirstFoundRequest")
+	proto.RegisterType((*ResizeCacheRequest)(nil), "ResizeCacheRequest")
+	proto.RegisterType((*DrainRequest)(nil), "DrainRequest")
+	proto.RegisterType((*AdminStatus)(nil), "AdminStatus")
+	proto.RegisterType((*VersionsRequest)(nil), "VersionsRequest")
+	proto.RegisterType((*PromoteVersionRequest)(nil), "PromoteVersionRequest")
+	proto.RegisterType((*DatasetVersion)(nil), "DatasetVersion")
+	proto.RegisterType((*VersionsResponse)(nil), "VersionsResponse")
+	proto.RegisterEnum("WithinRequest_Order", WithinRequest_Order_name, WithinRequest_Order_value)
+	proto.RegisterEnum("GeofenceEvent_Type", GeofenceEvent_Type_name, GeofenceEvent_Type_value)
+	proto.RegisterEnum("Geometry_Type", Geometry_Type_name, Geometry_Type_value)
+	proto.RegisterEnum("ResizeCacheRequest_Cache", ResizeCacheRequest_Cache_name, ResizeCacheRequest_Cache_value)
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// InsideClient is the client API for Inside service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type InsideClient interface {
+	//  Stab returns features containing lat lng
+	Within(ctx context.Context, in *WithinRequest, opts ...grpc.CallOption) (*WithinResponse, error)
+	// Get returns a feature by its internal ID and polygon index
+	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*Feature, error)
+	// GetFeature returns a feature by its internal ID with all its polygons, optionally simplified
+	GetFeature(ctx context.Context, in *GetFeatureRequest, opts ...grpc.CallOption) (*Feature, error)
+	// ListFeatures returns the ids and properties of the features matching conditions on their properties
+	ListFeatures(ctx context.Context, in *ListFeaturesRequest, opts ...grpc.CallOption) (*ListFeaturesResponse, error)
+	// WithinStream returns features containing lat lng for each request sent on the stream,
+	// the error of a request is set in its response and the stream goes on
+	WithinStream(ctx context.Context, opts ...grpc.CallOption) (Inside_WithinStreamClient, error)
+	// Nearest returns the feature containing lat lng or the closest one up to a max distance
+	Nearest(ctx context.Context, in *NearestRequest, opts ...grpc.CallOption) (*NearestResponse, error)
+	// Intersect returns features intersecting a geometry (point, polygon or linestring)
+	Intersect(ctx context.Context, in *IntersectRequest, opts ...grpc.CallOption) (*IntersectResponse, error)
+	// Info returns the server version, uptime, the served datasets and their index infos
+	Info(ctx context.Context, in *InfoRequest, opts ...grpc.CallOption) (*InfoResponse, error)
+	// Track returns the geofence events of the tracked objects positions sent on the stream
+	Track(ctx context.Context, opts ...grpc.CallOption) (Inside_TrackClient, error)
+	// InsertFeature indexes a new feature, served in read write mode only
+	InsertFeature(ctx context.Context, in *InsertFeatureRequest, opts ...grpc.CallOption) (*WriteFeatureResponse, error)
+	// UpdateFeature replaces the feature id, served in read write mode only
+	UpdateFeature(ctx context.Context, in *UpdateFeatureRequest, opts ...grpc.CallOption) (*WriteFeatureResponse, error)
+	// DeleteFeature removes the feature id, served in read write mode only
+	DeleteFeature(ctx context.Context, in *DeleteFeatureRequest, opts ...grpc.CallOption) (*WriteFeatureResponse, error)
+}
+
+type insideClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewInsideClient(cc *grpc.ClientConn) InsideClient {
+	return &insideClient{cc}
+}
+
+func (c *insideClient) Within(ctx context.Context, in *WithinRequest, opts ...grpc.CallOption) (*WithinResponse, error) {
+	out := new(WithinResponse)
+	err := c.cc.Invoke(ctx, "/Inside/Within", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *insideClient) Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*Feature, error) {
+	out := new(Feature)
+	err := c.cc.Invoke(ctx, "/Inside/Get", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *insideClient) GetFeature(ctx context.Context, in *GetFeatureRequest, opts ...grpc.CallOption) (*Feature, error) {
+	out := new(Feature)
+	err := c.cc.Invoke(ctx, "/Inside/GetFeature", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *insideClient) ListFeatures(ctx context.Context, in *ListFeaturesRequest, opts ...grpc.CallOption) (*ListFeaturesResponse, error) {
+	out := new(ListFeaturesResponse)
+	err := c.cc.Invoke(ctx, "/Inside/ListFeatures", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *insideClient) WithinStream(ctx context.Context, opts ...grpc.CallOption) (Inside_WithinStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Inside_serviceDesc.Streams[0], "/Inside/WithinStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &insideWithinStreamClient{stream}
+	return x, nil
+}
+
+type Inside_WithinStreamClient interface {
+	Send(*WithinRequest) error
+	Recv() (*WithinResponse, error)
+	grpc.ClientStream
+}
+
+type insideWithinStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *insideWithinStreamClient) Send(m *WithinRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *insideWithinStreamClient) Recv() (*WithinResponse, error) {
+	m := new(WithinResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *insideClient) Nearest(ctx context.Context, in *NearestRequest, opts ...grpc.CallOption) (*NearestResponse, error) {
+	out := new(NearestResponse)
+	err := c.cc.Invoke(ctx, "/Inside/Nearest", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *insideClient) Intersect(ctx context.Context, in *IntersectRequest, opts ...grpc.CallOption) (*IntersectResponse, error) {
+	out := new(IntersectResponse)
+	err := c.cc.Invoke(ctx, "/Inside/Intersect", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *insideClient) Info(ctx context.Context, in *InfoRequest, opts ...grpc.CallOption) (*InfoResponse, error) {
+	out := new(InfoResponse)
+	err := c.cc.Invoke(ctx, "/Inside/Info", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *insideClient) Track(ctx context.Context, opts ...grpc.CallOption) (Inside_TrackClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Inside_serviceDesc.Streams[1], "/Inside/Track", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &insideTrackClient{stream}
+	return x, nil
+}
+
+type Inside_TrackClient interface {
+	Send(*TrackRequest) error
+	Recv() (*GeofenceEvent, error)
+	grpc.ClientStream
+}
+
+type insideTrackClient struct {
+	grpc.ClientStream
+}
+
+func (x *insideTrackClient) Send(m *TrackRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *insideTrackClient) Recv() (*GeofenceEvent, error) {
+	m := new(GeofenceEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *insideClient) InsertFeature(ctx context.Context, in *InsertFeatureRequest, opts ...grpc.CallOption) (*WriteFeatureResponse, error) {
+	out := new(WriteFeatureResponse)
+	err := c.cc.Invoke(ctx, "/Inside/InsertFeature", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *insideClient) UpdateFeature(ctx context.Context, in *UpdateFeatureRequest, opts ...grpc.CallOption) (*WriteFeatureResponse, error) {
+	out := new(WriteFeatureResponse)
+	err := c.cc.Invoke(ctx, "/Inside/UpdateFeature", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *insideClient) DeleteFeature(ctx context.Context, in *DeleteFeatureRequest, opts ...grpc.CallOption) (*WriteFeatureResponse, error) {
+	out := new(WriteFeatureResponse)
+	err := c.cc.Invoke(ctx, "/Inside/DeleteFeature", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// InsideServer is the server API for Inside service.
+type InsideServer interface {
+	//  Stab returns features containing lat lng
+	Within(context.Context, *WithinRequest) (*WithinResponse, error)
+	// Get returns a feature by its internal ID and polygon index
+	Get(context.Context, *GetRequest) (*Feature, error)
+	// GetFeature returns a feature by its internal ID with all its polygons, optionally simplified
+	GetFeature(context.Context, *GetFeatureRequest) (*Feature, error)
+	// ListFeatures returns the ids and properties of the features matching conditions on their properties
+	ListFeatures(context.Context, *ListFeaturesRequest) (*ListFeaturesResponse, error)
+	// WithinStream returns features containing lat lng for each request sent on the stream,
+	// the error of a request is set in its response and the stream goes on
+	WithinStream(Inside_WithinStreamServer) error
+	// Nearest returns the feature containing lat lng or the closest one up to a max distance
+	Nearest(context.Context, *NearestRequest) (*NearestResponse, error)
+	// Intersect returns features intersecting a geometry (point, polygon or linestring)
+	Intersect(context.Context, *IntersectRequest) (*IntersectResponse, error)
+	// Info returns the server version, uptime, the served datasets and their index infos
+	Info(context.Context, *InfoRequest) (*InfoResponse, error)
+	// Track returns the geofence events of the tracked objects positions sent on the stream
+	Track(Inside_TrackServer) error
+	// InsertFeature indexes a new feature, served in read write mode only
+	InsertFeature(context.Context, *InsertFeatureRequest) (*WriteFeatureResponse, error)
+	// UpdateFeature replaces the feature id, served in read write mode only
+	UpdateFeature(context.Context, *UpdateFeatureRequest) (*WriteFeatureResponse, error)
+	// DeleteFeature removes the feature id, served in read write mode only
+	DeleteFeature(context.Context, *DeleteFeatureRequest) (*WriteFeatureResponse, error)
+}
+
+func RegisterInsideServer(s *grpc.Server, srv InsideServer) {
+	s.RegisterService(&_Inside_serviceDesc, srv)
+}
+
+func _Inside_Within_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WithinRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InsideServer).Within(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Inside/Within",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InsideServer).Within(ctx, req.(*WithinRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Inside_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InsideServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Inside/Get",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InsideServer).Get(ctx, req.(*GetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Inside_GetFeature_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetFeatureRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InsideServer).GetFeature(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Inside/GetFeature",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InsideServer).GetFeature(ctx, req.(*GetFeatureRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Inside_ListFeatures_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListFeaturesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InsideServer).ListFeatures(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Inside/ListFeatures",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InsideServer).ListFeatures(ctx, req.(*ListFeaturesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Inside_WithinStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(InsideServer).WithinStream(&insideWithinStreamServer{stream})
+}
+
+type Inside_WithinStreamServer interface {
+	Send(*WithinResponse) error
+	Recv() (*WithinRequest, error)
+	grpc.ServerStream
+}
+
+type insideWithinStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *insideWithinStreamServer) Send(m *WithinResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *insideWithinStreamServer) Recv() (*WithinRequest, error) {
+	m := new(WithinRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Inside_Nearest_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NearestRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InsideServer).Nearest(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Inside/Nearest",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InsideServer).Nearest(ctx, req.(*NearestRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Inside_Intersect_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IntersectRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InsideServer).Intersect(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Inside/Intersect",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InsideServer).Intersect(ctx, req.(*IntersectRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Inside_Info_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InfoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InsideServer).Info(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Inside/Info",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InsideServer).Info(ctx, req.(*InfoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Inside_Track_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(InsideServer).Track(&insideTrackServer{stream})
+}
+
+type Inside_TrackServer interface {
+	Send(*GeofenceEvent) error
+	Recv() (*TrackRequest, error)
+	grpc.ServerStream
+}
+
+type insideTrackServer struct {
+	grpc.ServerStream
+}
+
+func (x *insideTrackServer) Send(m *GeofenceEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *insideTrackServer) Recv() (*TrackRequest, error) {
+	m := new(TrackRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Inside_InsertFeature_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InsertFeatureRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InsideServer).InsertFeature(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Inside/InsertFeature",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InsideServer).InsertFeature(ctx, req.(*InsertFeatureRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Inside_UpdateFeature_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateFeatureRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InsideServer).UpdateFeature(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Inside/UpdateFeature",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InsideServer).UpdateFeature(ctx, req.(*UpdateFeatureRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Inside_DeleteFeature_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteFeatureRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InsideServer).DeleteFeature(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Inside/DeleteFeature",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InsideServer).DeleteFeature(ctx, req.(*DeleteFeatureRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Inside_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "Inside",
+	HandlerType: (*InsideServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Within",
+			Handler:    _Inside_Within_Handler,
+		},
+		{
+			MethodName: "Get",
+			Handler:    _Inside_Get_Handler,
+		},
+		{
+			MethodName: "GetFeature",
+			Handler:    _Inside_GetFeature_Handler,
+		},
+		{
+			MethodName: "ListFeatures",
+			Handler:    _Inside_ListFeatures_Handler,
+		},
+		{
+			MethodName: "Nearest",
+			Handler:    _Inside_Nearest_Handler,
+		},
+		{
+			MethodName: "Intersect",
+			Handler:    _Inside_Intersect_Handler,
+		},
+		{
+			MethodName: "Info",
+			Handler:    _Inside_Info_Handler,
+		},
+		{
+			MethodName: "InsertFeature",
+			Handler:    _Inside_InsertFeature_Handler,
+		},
+		{
+			MethodName: "UpdateFeature",
+			Handler:    _Inside_UpdateFeature_Handler,
+		},
+		{
+			MethodName: "DeleteFeature",
+			Handler:    _Inside_DeleteFeature_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WithinStream",
+			Handler:       _Inside_WithinStream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Track",
+			Handler:       _Inside_Track_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "insidesvc.proto",
+}
+
+// AdminServiceClient is the client API for AdminService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type AdminServiceClient interface {
+	// Status returns the current settings
+	Status(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (*AdminStatus, error)
+	// SetLogLevel changes the log level: DEBUG, INFO, WARN or ERROR
+	SetLogLevel(ctx context.Context, in *SetLogLevelRequest, opts ...grpc.CallOption) (*AdminStatus, error)
+	// SetStopOnFirstFound changes whether the lookups stop at the first feature found
+	SetStopOnFirstFound(ctx context.Context, in *SetStopOnFirstFoundRequest, opts ...grpc.CallOption) (*AdminStatus, error)
+	// ResizeCache replaces a cache by an empty one of the requested size, 0 disables it
+	ResizeCache(ctx context.Context, in *ResizeCacheRequest, opts ...grpc.CallOption) (*AdminStatus, error)
+	// Drain reports the server as not serving to the health checks, the requests are still served
+	Drain(ctx context.Context, in *DrainRequest, opts ...grpc.CallOption) (*AdminStatus, error)
+	// Undrain reports the server as serving again, if its databases are available
+	Undrain(ctx context.Context, in *DrainRequest, opts ...grpc.CallOption) (*AdminStatus, error)
+	// ListVersions returns the versions of a dataset served from a versions directory
+	ListVersions(ctx context.Context, in *VersionsRequest, opts ...grpc.CallOption) (*VersionsResponse, error)
+	// PromoteVersion serves a version of a dataset, the previous one is restored by RollbackVersion
+	PromoteVersion(ctx context.Context, in *PromoteVersionRequest, opts ...grpc.CallOption) (*VersionsResponse, error)
+	// RollbackVersion serves again the version of a dataset served before the current one
+	RollbackVersion(ctx context.Context, in *VersionsRequest, opts ...grpc.CallOption) (*VersionsResponse, error)
 }
-func (m *Point) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_Point.Unmarshal(m, b)
+
+type adminServiceClient struct {
+	cc *grpc.ClientConn
 }
-func (m *Point) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_Point.Marshal(b, m, deterministic)
+
+func NewAdminServiceClient(cc *grpc.ClientConn) AdminServiceClient {
+	return &adminServiceClient{cc}
 }
-func (dst *Point) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_Point.Merge(dst, src)
+
+func (c *adminServiceClient) Status(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (*AdminStatus, error) {
+	out := new(AdminStatus)
+	err := c.cc.Invoke(ctx, "/AdminService/Status", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
-func (m *Point) XXX_Size() int {
-	return xxx_messageInfo_Point.Size(m)
+
+func (c *adminServiceClient) SetLogLevel(ctx context.Context, in *SetLogLevelRequest, opts ...grpc.CallOption) (*AdminStatus, error) {
+	out := new(AdminStatus)
+	err := c.cc.Invoke(ctx, "/AdminService/SetLogLevel", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
-func (m *Point) XXX_DiscardUnknown() {
-	xxx_messageInfo_Point.DiscardUnknown(m)
+
+func (c *adminServiceClient) SetStopOnFirstFound(ctx context.Context, in *SetStopOnFirstFoundRequest, opts ...grpc.CallOption) (*AdminStatus, error) {
+	out := new(AdminStatus)
+	err := c.cc.Invoke(ctx, "/AdminService/SetStopOnFirstFound", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
 
-var xxx_messageInfo_Point proto.InternalMessageInfo
+func (c *adminServiceClient) ResizeCache(ctx context.Context, in *ResizeCacheRequest, opts ...grpc.CallOption) (*AdminStatus, error) {
+	out := new(AdminStatus)
+	err := c.cc.Invoke(ctx, "/AdminService/ResizeCache", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
 
-func (m *Point) GetLat() float64 {
-	if m != nil {
-		return m.Lat
+func (c *adminServiceClient) Drain(ctx context.Context, in *DrainRequest, opts ...grpc.CallOption) (*AdminStatus, error) {
+	out := new(AdminStatus)
+	err := c.cc.Invoke(ctx, "/AdminService/Drain", in, out, opts...)
+	if err != nil {
+		return nil, err
 	}
-	return 0
+	return out, nil
 }
 
-func (m *Point) GetLng() float64 {
-	if m != nil {
-		return m.Lng
+func (c *adminServiceClient) Undrain(ctx context.Context, in *DrainRequest, opts ...grpc.CallOption) (*AdminStatus, error) {
+	out := new(AdminStatus)
+	err := c.cc.Invoke(ctx, "/AdminService/Undrain", in, out, opts...)
+	if err != nil {
+		return nil, err
 	}
-	return 0
+	return out, nil
 }
 
-func init() {
-	proto.RegisterType((*WithinRequest)(nil), "WithinRequest")
-	proto.RegisterType((*WithinResponse)(nil), "WithinResponse")
-	proto.RegisterType((*GetRequest)(nil), "GetRequest")
-	proto.RegisterType((*FeatureResponse)(nil), "FeatureResponse")
-	proto.RegisterType((*Feature)(nil), "Feature")
-	proto.RegisterMapType((map[string]*_struct.Value)(nil), "Feature.PropertiesEntry")
-	proto.RegisterType((*Geometry)(nil), "Geometry")
-	proto.RegisterType((*Point)(nil), "Point")
-	proto.RegisterEnum("Geometry_Type", Geometry_Type_name, Geometry_Type_value)
+func (c *adminServiceClient) ListVersions(ctx context.Context, in *VersionsRequest, opts ...grpc.CallOption) (*VersionsResponse, error) {
+	out := new(VersionsResponse)
+	err := c.cc.Invoke(ctx, "/AdminService/ListVersions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
 
-// Reference imports to suppress errors if they are not otherwise used.
-var _ context.Context
-var _ grpc.ClientConn
+func (c *adminServiceClient) PromoteVersion(ctx context.Context, in *PromoteVersionRequest, opts ...grpc.CallOption) (*VersionsResponse, error) {
+	out := new(VersionsResponse)
+	err := c.cc.Invoke(ctx, "/AdminService/PromoteVersion", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
 
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the grpc package it is being compiled against.
-const _ = grpc.SupportPackageIsVersion4
+func (c *adminServiceClient) RollbackVersion(ctx context.Context, in *VersionsRequest, opts ...grpc.CallOption) (*VersionsResponse, error) {
+	out := new(VersionsResponse)
+	err := c.cc.Invoke(ctx, "/AdminService/RollbackVersion", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
 
-// InsideClient is the client API for Inside service.
-//
-// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
-type InsideClient interface {
-	//  Stab returns features containing lat lng
-	Within(ctx context.Context, in *WithinRequest, opts ...grpc.CallOption) (*WithinResponse, error)
-	// Get returns a feature by its internal ID and polygon index
-	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*Feature, error)
+// AdminServiceServer is the server API for AdminService service.
+type AdminServiceServer interface {
+	// Status returns the current settings
+	Status(context.Context, *StatusRequest) (*AdminStatus, error)
+	// SetLogLevel changes the log level: DEBUG, INFO, WARN or ERROR
+	SetLogLevel(context.Context, *SetLogLevelRequest) (*AdminStatus, error)
+	// SetStopOnFirstFound changes whether the lookups stop at the first feature found
+	SetStopOnFirstFound(context.Context, *SetStopOnFirstFoundRequest) (*AdminStatus, error)
+	// ResizeCache replaces a cache by an empty one of the requested size, 0 disables it
+	ResizeCache(context.Context, *ResizeCacheRequest) (*AdminStatus, error)
+	// Drain reports the server as not serving to the health checks, the requests are still served
+	Drain(context.Context, *DrainRequest) (*AdminStatus, error)
+	// Undrain reports the server as serving again, if its databases are available
+	Undrain(context.Context, *DrainRequest) (*AdminStatus, error)
+	// ListVersions returns the versions of a dataset served from a versions directory
+	ListVersions(context.Context, *VersionsRequest) (*VersionsResponse, error)
+	// PromoteVersion serves a version of a dataset, the previous one is restored by RollbackVersion
+	PromoteVersion(context.Context, *PromoteVersionRequest) (*VersionsResponse, error)
+	// RollbackVersion serves again the version of a dataset served before the current one
+	RollbackVersion(context.Context, *VersionsRequest) (*VersionsResponse, error)
 }
 
-type insideClient struct {
-	cc *grpc.ClientConn
+func RegisterAdminServiceServer(s *grpc.Server, srv AdminServiceServer) {
+	s.RegisterService(&_AdminService_serviceDesc, srv)
 }
 
-func NewInsideClient(cc *grpc.ClientConn) InsideClient {
-	return &insideClient{cc}
+func _AdminService_Status_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).Status(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/AdminService/Status",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).Status(ctx, req.(*StatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (c *insideClient) Within(ctx context.Context, in *WithinRequest, opts ...grpc.CallOption) (*WithinResponse, error) {
-	out := new(WithinResponse)
-	err := c.cc.Invoke(ctx, "/Inside/Within", in, out, opts...)
-	if err != nil {
+func _AdminService_SetLogLevel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetLogLevelRequest)
+	if err := dec(in); err != nil {
 		return nil, err
 	}
-	return out, nil
+	if interceptor == nil {
+		return srv.(AdminServiceServer).SetLogLevel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/AdminService/SetLogLevel",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).SetLogLevel(ctx, req.(*SetLogLevelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (c *insideClient) Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*Feature, error) {
-	out := new(Feature)
-	err := c.cc.Invoke(ctx, "/Inside/Get", in, out, opts...)
-	if err != nil {
+func _AdminService_SetStopOnFirstFound_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetStopOnFirstFoundRequest)
+	if err := dec(in); err != nil {
 		return nil, err
 	}
-	return out, nil
+	if interceptor == nil {
+		return srv.(AdminServiceServer).SetStopOnFirstFound(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/AdminService/SetStopOnFirstFound",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).SetStopOnFirstFound(ctx, req.(*SetStopOnFirstFoundRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-// InsideServer is the server API for Inside service.
-type InsideServer interface {
-	//  Stab returns features containing lat lng
-	Within(context.Context, *WithinRequest) (*WithinResponse, error)
-	// Get returns a feature by its internal ID and polygon index
-	Get(context.Context, *GetRequest) (*Feature, error)
+func _AdminService_ResizeCache_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResizeCacheRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).ResizeCache(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/AdminService/ResizeCache",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).ResizeCache(ctx, req.(*ResizeCacheRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func RegisterInsideServer(s *grpc.Server, srv InsideServer) {
-	s.RegisterService(&_Inside_serviceDesc, srv)
+func _AdminService_Drain_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DrainRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).Drain(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/AdminService/Drain",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).Drain(ctx, req.(*DrainRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func _Inside_Within_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(WithinRequest)
+func _AdminService_Undrain_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DrainRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(InsideServer).Within(ctx, in)
+		return srv.(AdminServiceServer).Undrain(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/Inside/Within",
+		FullMethod: "/AdminService/Undrain",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(InsideServer).Within(ctx, req.(*WithinRequest))
+		return srv.(AdminServiceServer).Undrain(ctx, req.(*DrainRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Inside_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(GetRequest)
+func _AdminService_ListVersions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VersionsRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(InsideServer).Get(ctx, in)
+		return srv.(AdminServiceServer).ListVersions(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/Inside/Get",
+		FullMethod: "/AdminService/ListVersions",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(InsideServer).Get(ctx, req.(*GetRequest))
+		return srv.(AdminServiceServer).ListVersions(ctx, req.(*VersionsRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-var _Inside_serviceDesc = grpc.ServiceDesc{
-	ServiceName: "Inside",
-	HandlerType: (*InsideServer)(nil),
+func _AdminService_PromoteVersion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PromoteVersionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).PromoteVersion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/AdminService/PromoteVersion",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).PromoteVersion(ctx, req.(*PromoteVersionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_RollbackVersion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VersionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).RollbackVersion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/AdminService/RollbackVersion",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).RollbackVersion(ctx, req.(*VersionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _AdminService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "AdminService",
+	HandlerType: (*AdminServiceServer)(nil),
 	Methods: []grpc.MethodDesc{
 		{
-			MethodName: "Within",
-			Handler:    _Inside_Within_Handler,
+			MethodName: "Status",
+			Handler:    _AdminService_Status_Handler,
 		},
 		{
-			MethodName: "Get",
-			Handler:    _Inside_Get_Handler,
+			MethodName: "SetLogLevel",
+			Handler:    _AdminService_SetLogLevel_Handler,
+		},
+		{
+			MethodName: "SetStopOnFirstFound",
+			Handler:    _AdminService_SetStopOnFirstFound_Handler,
+		},
+		{
+			MethodName: "ResizeCache",
+			Handler:    _AdminService_ResizeCache_Handler,
+		},
+		{
+			MethodName: "Drain",
+			Handler:    _AdminService_Drain_Handler,
+		},
+		{
+			MethodName: "Undrain",
+			Handler:    _AdminService_Undrain_Handler,
+		},
+		{
+			MethodName: "ListVersions",
+			Handler:    _AdminService_ListVersions_Handler,
+		},
+		{
+			MethodName: "PromoteVersion",
+			Handler:    _AdminService_PromoteVersion_Handler,
+		},
+		{
+			MethodName: "RollbackVersion",
+			Handler:    _AdminService_RollbackVersion_Handler,
 		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "insidesvc.proto",
 }
 
-func init() { proto.RegisterFile("insidesvc.proto", fileDescriptor_insidesvc_b16b5f17af4aa25f) }
-
-var fileDescriptor_insidesvc_b16b5f17af4aa25f = []byte{
-	// 527 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x53, 0x4f, 0xaf, 0xd2, 0x4e,
-	0x14, 0xa5, 0x2d, 0xff, 0x7a, 0xfb, 0xa3, 0xf4, 0x37, 0x0b, 0x43, 0xc8, 0xd3, 0x90, 0x49, 0x4c,
-	0x30, 0x98, 0x79, 0x49, 0xdd, 0xbc, 0xe8, 0xc6, 0x0d, 0x92, 0x26, 0x08, 0x64, 0x04, 0x8d, 0x1b,
-	0x09, 0x0f, 0xee, 0xc3, 0x46, 0x5e, 0xa7, 0xb6, 0x03, 0x91, 0x0f, 0xe1, 0x47, 0x72, 0xe1, 0x37,
-	0x33, 0x9d, 0x69, 0x4b, 0xdf, 0x73, 0xe3, 0x6e, 0xe6, 0x9c, 0xc3, 0xe1, 0x9e, 0xdb, 0x33, 0xd0,
-	0x0d, 0xa3, 0x34, 0xdc, 0x61, 0x7a, 0xda, 0xb2, 0x38, 0x11, 0x52, 0xf4, 0xaf, 0xf6, 0x42, 0xec,
-	0x0f, 0x78, 0xad, 0x6e, 0xb7, 0xc7, 0xbb, 0xeb, 0x54, 0x26, 0xc7, 0xad, 0xd4, 0x2c, 0xfd, 0x69,
-	0x40, 0xe7, 0x53, 0x28, 0xbf, 0x86, 0x11, 0xc7, 0xef, 0x47, 0x4c, 0x25, 0xf1, 0xc0, 0x3a, 0x6c,
-	0x64, 0xcf, 0x18, 0x18, 0x43, 0x83, 0x67, 0x47, 0x85, 0x44, 0xfb, 0x9e, 0x99, 0x23, 0xd1, 0x9e,
-	0x8c, 0xe0, 0xff, 0x04, 0xef, 0xc5, 0x09, 0xd7, 0x7b, 0x14, 0xf7, 0x28, 0x93, 0x10, 0xd3, 0x9e,
-	0x35, 0x30, 0x86, 0x6d, 0xee, 0x69, 0x62, 0x52, 0xe2, 0x99, 0x38, 0xc5, 0x03, 0x6e, 0xe5, 0x3a,
-	0x4e, 0x44, 0x8c, 0x89, 0xcc, 0xc4, 0xf5, 0x81, 0x31, 0xb4, 0xb9, 0xa7, 0x89, 0x45, 0x89, 0xd3,
-	0x2f, 0xe0, 0x16, 0xe3, 0xa4, 0xb1, 0x88, 0x52, 0x24, 0x57, 0xd0, 0x88, 0x45, 0x18, 0xe9, 0x89,
-	0x1c, 0xbf, 0xc9, 0x16, 0xd9, 0x8d, 0x6b, 0x90, 0x30, 0xb0, 0x93, 0x5c, 0x99, 0xf6, 0xcc, 0x81,
-	0x35, 0x74, 0x7c, 0x8f, 0xbd, 0xc3, 0x8d, 0x3c, 0x26, 0x58, 0x58, 0xf0, 0x8b, 0x84, 0xbe, 0x01,
-	0x98, 0xa0, 0x2c, 0xb2, 0xba, 0x60, 0x86, 0x3b, 0x65, 0xdc, 0xe1, 0x66, 0xb8, 0x23, 0x4f, 0x01,
-	0x0e, 0x42, 0xc4, 0xeb, 0x30, 0xda, 0xe1, 0x0f, 0x15, 0xb8, 0xc3, 0xed, 0x0c, 0x09, 0x32, 0x80,
-	0x8e, 0xa1, 0xfb, 0xc8, 0xfa, 0x2f, 0x07, 0x0a, 0xad, 0x3b, 0x2d, 0x51, 0xfb, 0x70, 0xfc, 0x76,
-	0x39, 0x4d, 0x41, 0xd0, 0xdf, 0x06, 0xb4, 0x72, 0x90, 0x3c, 0x87, 0x76, 0xbe, 0xc2, 0x73, 0x1e,
-	0xd0, 0x66, 0xf9, 0xee, 0xce, 0xbc, 0xa4, 0xc8, 0x0d, 0x40, 0x65, 0x79, 0x3a, 0x67, 0xaf, 0x70,
-	0x66, 0x97, 0xfd, 0x8d, 0xa3, 0xec, 0x77, 0x15, 0x6d, 0x7f, 0x05, 0xdd, 0x47, 0x74, 0xf6, 0x3d,
-	0xbf, 0xa1, 0xfe, 0x3b, 0x9b, 0x67, 0x47, 0xf2, 0x12, 0x1a, 0xa7, 0xcd, 0xe1, 0x88, 0x2a, 0xb2,
-	0xe3, 0x3f, 0x61, 0xba, 0x33, 0xac, 0xe8, 0x0c, 0xfb, 0x98, 0xb1, 0x5c, 0x8b, 0x5e, 0x9b, 0x37,
-	0x06, 0xfd, 0x65, 0x40, 0xbb, 0x98, 0x93, 0x50, 0xa8, 0xcb, 0x73, 0x8c, 0xca, 0xd1, 0xf5, 0xdd,
-	0x32, 0x00, 0x5b, 0x9e, 0x63, 0xe4, 0x8a, 0x23, 0x2f, 0x00, 0x2a, 0x5d, 0xd1, 0x09, 0x2a, 0x51,
-	0x2b, 0x24, 0x19, 0x80, 0xb3, 0x15, 0x22, 0xd9, 0x85, 0xd1, 0x46, 0xaa, 0x5e, 0x59, 0x43, 0x83,
-	0x57, 0x21, 0xfa, 0x16, 0xea, 0x99, 0x35, 0xb1, 0xa1, 0xb1, 0x98, 0x07, 0xb3, 0xa5, 0x57, 0x23,
-	0x0e, 0xb4, 0x16, 0xf3, 0xe9, 0xe7, 0xc9, 0x7c, 0xe6, 0x19, 0xc4, 0x83, 0xff, 0xde, 0xaf, 0xa6,
-	0xcb, 0xa0, 0x40, 0x4c, 0xe2, 0x02, 0x4c, 0x83, 0xd9, 0xf8, 0xc3, 0x92, 0x07, 0xb3, 0x89, 0x67,
-	0xd1, 0x11, 0x34, 0x54, 0x8f, 0xfe, 0xa5, 0xee, 0xfe, 0x0a, 0x9a, 0x81, 0x7a, 0x55, 0x64, 0x04,
-	0x4d, 0x5d, 0x4f, 0xe2, 0xb2, 0x07, 0xcf, 0xa6, 0xdf, 0x65, 0x0f, 0x7b, 0x4b, 0x6b, 0xe4, 0x19,
-	0x58, 0x13, 0x94, 0xc4, 0x61, 0x97, 0xc6, 0xf5, 0xcb, 0x3a, 0xd0, 0xda, 0x6d, 0x53, 0xad, 0xf7,
-	0xd5, 0x9f, 0x00, 0x00, 0x00, 0xff, 0xff, 0xd1, 0xca, 0x1b, 0xcc, 0xb3, 0x03, 0x00, 0x00,
+func init() { proto.RegisterFile("insidesvc.proto", fileDescriptor_insidesvc_d86dece99e9ccc55) }
+
+var fileDescriptor_insidesvc_d86dece99e9ccc55 = []byte{
+	// 2533 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x58, 0xcd, 0x6f, 0xdb, 0xc8,
+	0x15, 0x37, 0x45, 0x7d, 0x3e, 0x7d, 0x7a, 0x6c, 0x07, 0x5a, 0x6d, 0xb2, 0xeb, 0x4c, 0xb1, 0x59,
+	0x75, 0x93, 0xe5, 0x2e, 0xdc, 0x06, 0x08, 0x0a, 0xb4, 0x4d, 0xd6, 0x56, 0x0c, 0x61, 0x1d, 0xdb,
+	0x1d, 0xcb, 0x9b, 0xdd, 0x93, 0xc0, 0x90, 0x63, 0x99, 0x08, 0x45, 0x72, 0xc9, 0x91, 0x61, 0xed,
+	0xa5, 0x40, 0x4f, 0x3d, 0x14, 0xed, 0x7f, 0x50, 0x14, 0xbd, 0x16, 0xe8, 0xad, 0xc7, 0x1e, 0x0a,
+	0xf4, 0x1f, 0xe8, 0x3f, 0xd1, 0x5b, 0xcf, 0xbd, 0x15, 0xc5, 0x7c, 0x51, 0xa4, 0x24, 0x3b, 0xbe,
+	0xe4, 0xc6, 0xf7, 0x31, 0x33, 0xef, 0xbd, 0x79, 0xf3, 0x7b, 0xef, 0x11, 0xda, 0x5e, 0x90, 0x78,
+	0x2e, 0x4d, 0xae, 0x1c, 0x2b, 0x8a, 0x43, 0x16, 0xf6, 0xee, 0x4f, 0xc2, 0x70, 0xe2, 0xd3, 0x2f,
+	0x04, 0xf5, 0x66, 0x76, 0xf1, 0x45, 0xc2, 0xe2, 0x99, 0xc3, 0xa4, 0x14, 0xff, 0xbd, 0x08, 0xcd,
+	0xd7, 0x1e, 0xbb, 0xf4, 0x02, 0x42, 0xbf, 0x9f, 0xd1, 0x84, 0xa1, 0x0e, 0x98, 0xbe, 0xcd, 0xba,
+	0xc6, 0xae, 0xd1, 0x37, 0x08, 0xff, 0x14, 0x9c, 0x60, 0xd2, 0x2d, 0x28, 0x4e, 0x30, 0x41, 0x8f,
+	0x61, 0x33, 0xa6, 0xd3, 0xf0, 0x8a, 0x8e, 0x27, 0x34, 0x9c, 0x52, 0x16, 0x7b, 0x34, 0xe9, 0x9a,
+	0xbb, 0x46, 0xbf, 0x4a, 0x3a, 0x52, 0x70, 0x98, 0xf2, 0xb9, 0x72, 0x42, 0x7d, 0xea, 0xb0, 0x71,
+	0x14, 0x87, 0x11, 0x8d, 0x19, 0x57, 0x2e, 0xee, 0x1a, 0xfd, 0x1a, 0xe9, 0x48, 0xc1, 0x69, 0xca,
+	0x47, 0xf7, 0xa0, 0x7c, 0xe1, 0xf9, 0x8c, 0xc6, 0xdd, 0x92, 0xd0, 0x50, 0x14, 0xea, 0x42, 0xc5,
+	0xb5, 0x99, 0x9d, 0x50, 0xd6, 0x2d, 0x0b, 0x81, 0x26, 0xf9, 0xf6, 0x6f, 0xc2, 0x59, 0xe0, 0xda,
+	0xf1, 0x7c, 0xec, 0x7a, 0x09, 0xb3, 0x03, 0x87, 0x76, 0x2b, 0xd2, 0x16, 0x2d, 0x38, 0x50, 0x7c,
+	0xb4, 0x0d, 0x25, 0x7a, 0x6d, 0x3b, 0xac, 0x5b, 0x15, 0x0a, 0x92, 0x40, 0x9f, 0x41, 0x29, 0x8c,
+	0x5d, 0x1a, 0x77, 0x6b, 0xbb, 0x46, 0xbf, 0xb5, 0xb7, 0x6d, 0xe5, 0x22, 0x62, 0x9d, 0x70, 0x19,
+	0x91, 0x2a, 0xe8, 0x13, 0x68, 0x89, 0x0f, 0xed, 0xcc, 0xbc, 0x0b, 0xc2, 0x9e, 0xa6, 0xe0, 0x2a,
+	0x4f, 0xe6, 0xe8, 0x01, 0x80, 0x54, 0x73, 0x69, 0xe2, 0x74, 0xeb, 0xe2, 0xb4, 0x9a, 0xe0, 0x1c,
+	0xd0, 0xc4, 0xe1, 0x76, 0xf8, 0xde, 0xd4, 0x63, 0xdd, 0xc6, 0xae, 0xd1, 0x2f, 0x11, 0x49, 0xa0,
+	0xfb, 0x50, 0xbb, 0xf4, 0x68, 0x6c, 0xc7, 0xce, 0xe5, 0xbc, 0xdb, 0x94, 0x6b, 0x52, 0x06, 0x7a,
+	0x08, 0x0d, 0x97, 0xd2, 0x88, 0x26, 0x6c, 0x1c, 0x06, 0xfe, 0xbc, 0xdb, 0x12, 0x0a, 0x75, 0xc5,
+	0x3b, 0x09, 0xfc, 0x39, 0xdf, 0xd6, 0xa5, 0x6f, 0x66, 0x93, 0x6e, 0x5b, 0xba, 0x27, 0x08, 0xd4,
+	0x82, 0x82, 0xcd, 0xba, 0x9d, 0x5d, 0xa3, 0x6f, 0x92, 0x82, 0xcd, 0x50, 0x0f, 0xaa, 0x09, 0x8b,
+	0x6d, 0x46, 0x27, 0xf3, 0xee, 0xa6, 0x30, 0x3e, 0xa5, 0xb1, 0x05, 0x25, 0xe1, 0x2e, 0x6a, 0x42,
+	0x6d, 0x78, 0x7c, 0x36, 0x20, 0xa3, 0xe1, 0xc9, 0x71, 0x67, 0x03, 0x55, 0xa1, 0xf8, 0x82, 0x0c,
+	0x5e, 0x74, 0x0c, 0xd4, 0x80, 0xea, 0x29, 0x39, 0x39, 0x1d, 0x90, 0xd1, 0x77, 0x9d, 0x02, 0xfe,
+	0x93, 0x01, 0x2d, 0x1d, 0xad, 0x24, 0x0a, 0x83, 0x84, 0xa2, 0xfb, 0x50, 0x8a, 0x42, 0x2f, 0x90,
+	0x29, 0x54, 0xdf, 0x2b, 0x5b, 0xa7, 0x9c, 0x22, 0x92, 0x89, 0x2c, 0xa8, 0xc5, 0x4a, 0x33, 0xe9,
+	0x16, 0x76, 0xcd, 0x7e, 0x7d, 0xaf, 0x63, 0xbd, 0xa4, 0x36, 0x9b, 0xc5, 0x54, 0x6f, 0x41, 0x16,
+	0x2a, 0x08, 0x6b, 0x97, 0x4c, 0xb1, 0x5b, 0x43, 0xdd, 0xcd, 0x01, 0xe7, 0x69, 0x07, 0xef, 0x43,
+	0x89, 0xc6, 0x71, 0x18, 0x77, 0x8b, 0xea, 0xc4, 0x01, 0xa7, 0x88, 0x64, 0xe2, 0xff, 0x19, 0x50,
+	0xcf, 0x2c, 0xe2, 0x57, 0xe3, 0x50, 0xdf, 0x1f, 0xb3, 0xf0, 0x2d, 0x0d, 0x84, 0x91, 0x35, 0x52,
+	0xe3, 0x9c, 0x11, 0x67, 0xa4, 0x62, 0x9f, 0x5e, 0x51, 0x5f, 0x24, 0x7d, 0x49, 0x8a, 0x8f, 0x38,
+	0x23, 0x17, 0x3c, 0x33, 0x1f, 0x3c, 0xf4, 0x25, 0x80, 0x63, 0x07, 0xae, 0xe7, 0xda, 0x4c, 0xa4,
+	0xb8, 0x74, 0x4e, 0x9e, 0xbd, 0xaf, 0x05, 0x24, 0xa3, 0xc3, 0xef, 0xd4, 0x0b, 0x5c, 0x7a, 0x3d,
+	0x9e, 0x7a, 0x4e, 0x1c, 0x26, 0x22, 0xe9, 0x4d, 0x52, 0x17, 0xbc, 0x57, 0x82, 0xc5, 0xed, 0x89,
+	0xbc, 0x48, 0x2b, 0x94, 0x85, 0x42, 0x2d, 0xf2, 0x22, 0x25, 0x7e, 0x08, 0x0d, 0x16, 0x32, 0xdb,
+	0xd7, 0x0a, 0x15, 0xb9, 0x83, 0xe0, 0x49, 0x15, 0xfc, 0x5b, 0x03, 0xda, 0x4b, 0x46, 0xf0, 0x9c,
+	0xf0, 0x5c, 0xe1, 0x7c, 0x93, 0x14, 0x3c, 0x97, 0xbf, 0xf1, 0x28, 0x4c, 0x84, 0xbb, 0x4d, 0xc2,
+	0x3f, 0xd1, 0xc7, 0x50, 0x97, 0x50, 0x32, 0xe6, 0xce, 0xab, 0xd7, 0x0d, 0x92, 0xb5, 0x4f, 0x7d,
+	0x9f, 0x3f, 0x55, 0x46, 0x13, 0x46, 0x5d, 0x11, 0xf6, 0x2a, 0x51, 0x14, 0x8f, 0x90, 0xed, 0x38,
+	0x34, 0xe2, 0x92, 0x92, 0x90, 0xa4, 0x34, 0x7e, 0x0e, 0x48, 0x5a, 0xf2, 0x95, 0xcd, 0x9c, 0x4b,
+	0x0d, 0x39, 0x9f, 0x41, 0x35, 0x96, 0x9f, 0x49, 0xd7, 0x10, 0x51, 0x6b, 0xe5, 0x9f, 0x20, 0x49,
+	0xe5, 0xf8, 0x00, 0xb6, 0x72, 0x3b, 0xa8, 0xa4, 0xfb, 0x3c, 0x9b, 0x56, 0x72, 0x8f, 0xb6, 0x95,
+	0x4f, 0xcc, 0x4c, 0x56, 0xe1, 0x6f, 0x75, 0x4a, 0x10, 0x1a, 0xf9, 0x73, 0xf4, 0x18, 0xaa, 0x5a,
+	0xa6, 0xb2, 0x76, 0x65, 0x71, 0xaa, 0xb0, 0xc8, 0xb6, 0xc2, 0xba, 0x6c, 0x7b, 0x0a, 0x25, 0x41,
+	0x23, 0x04, 0x45, 0x27, 0x74, 0xe5, 0x7e, 0x25, 0x22, 0xbe, 0x39, 0x8a, 0x4d, 0x69, 0x92, 0xd8,
+	0x13, 0x2a, 0x16, 0xd7, 0x88, 0x26, 0xf1, 0xdf, 0x0c, 0x68, 0x8c, 0x62, 0xdb, 0x79, 0xab, 0x63,
+	0xb2, 0xb8, 0xa0, 0x9a, 0xbe, 0x20, 0x0e, 0xcb, 0x85, 0x15, 0x58, 0x36, 0x17, 0xb0, 0x8c, 0xa0,
+	0xc8, 0xbc, 0x29, 0x15, 0xf7, 0x61, 0x12, 0xf1, 0x9d, 0x05, 0xce, 0xd2, 0x0a, 0x70, 0xae, 0xe2,
+	0x72, 0xf9, 0x9d, 0xb8, 0x5c, 0xc9, 0xe2, 0x32, 0xfe, 0xbd, 0x09, 0xcd, 0x43, 0x1a, 0x5e, 0xd0,
+	0xc0, 0xa1, 0x83, 0x2b, 0x1a, 0x30, 0xf4, 0x29, 0x14, 0xd9, 0x3c, 0x92, 0x7e, 0xb7, 0xf6, 0xb6,
+	0xac, 0x9c, 0xd4, 0x1a, 0xcd, 0x23, 0x4a, 0x84, 0x82, 0xf2, 0xb0, 0x90, 0x7a, 0x98, 0xb1, 0xd4,
+	0xcc, 0x5b, 0xfa, 0x00, 0xe0, 0x42, 0x22, 0xc4, 0xd8, 0x93, 0xd9, 0xd6, 0x24, 0x35, 0xc5, 0x19,
+	0xba, 0xe8, 0x17, 0x00, 0x19, 0x0f, 0x4a, 0xe2, 0xf2, 0x3f, 0x5a, 0x3a, 0x77, 0xe1, 0xca, 0x20,
+	0x60, 0xf1, 0x9c, 0x64, 0x56, 0x2c, 0x00, 0xab, 0xbc, 0x0e, 0xb0, 0x74, 0x50, 0x2b, 0x99, 0xa0,
+	0xf6, 0xa0, 0xea, 0xce, 0x62, 0x9b, 0x79, 0x61, 0x20, 0x2a, 0x89, 0x49, 0x52, 0xba, 0x77, 0x0e,
+	0xed, 0xa5, 0xc3, 0xf8, 0x4d, 0xbd, 0xa5, 0x73, 0x75, 0x99, 0xfc, 0x13, 0x3d, 0x81, 0xd2, 0x95,
+	0xed, 0xcf, 0xa8, 0xca, 0xa1, 0x7b, 0x96, 0x2c, 0xd2, 0x96, 0x2e, 0xd2, 0xd6, 0x37, 0x5c, 0x4a,
+	0xa4, 0xd2, 0xcf, 0x0a, 0xcf, 0x0c, 0xfc, 0x08, 0x8a, 0x3c, 0x76, 0xa8, 0x06, 0xa5, 0xc1, 0xf1,
+	0x68, 0x40, 0x24, 0x26, 0x0f, 0xbe, 0x1d, 0x8e, 0x3a, 0x06, 0x67, 0x1e, 0xbc, 0x1e, 0x1c, 0x1d,
+	0x75, 0x0a, 0xf8, 0x8f, 0x06, 0xb4, 0x8e, 0xa9, 0x1d, 0xf3, 0x57, 0xf3, 0xbe, 0x2a, 0xfa, 0x43,
+	0x68, 0x4c, 0xed, 0xeb, 0x45, 0xb5, 0x2d, 0x8a, 0x7d, 0xea, 0x53, 0xfb, 0x3a, 0x2d, 0xb4, 0x37,
+	0xa6, 0x1d, 0x9e, 0x43, 0x3b, 0xb5, 0xef, 0x4e, 0x15, 0xe3, 0x49, 0xe6, 0x71, 0xca, 0x70, 0xad,
+	0x16, 0x8c, 0xc5, 0xeb, 0xe4, 0x57, 0xa3, 0xed, 0x92, 0x4f, 0x23, 0xa5, 0xf1, 0x5f, 0x0d, 0xe8,
+	0x0c, 0x03, 0x46, 0xe3, 0x84, 0x3a, 0x69, 0x74, 0x3e, 0x81, 0xaa, 0x72, 0x79, 0xae, 0xce, 0xaf,
+	0x59, 0xca, 0xd7, 0x39, 0x49, 0x45, 0xeb, 0x03, 0x54, 0xb8, 0x21, 0x40, 0x37, 0xa7, 0x72, 0x5a,
+	0xf8, 0x8b, 0xd9, 0xc2, 0x7f, 0x0f, 0xca, 0xce, 0x2c, 0x4e, 0xc2, 0xb4, 0xeb, 0x91, 0x14, 0x76,
+	0x61, 0x33, 0x63, 0xaf, 0xf2, 0xd0, 0x5a, 0x85, 0xba, 0x5b, 0x2b, 0xe8, 0xc7, 0x50, 0x0f, 0xe8,
+	0x35, 0x1b, 0xab, 0x13, 0xe4, 0x83, 0x03, 0xce, 0xda, 0x97, 0xa7, 0x9c, 0x03, 0x1c, 0x52, 0xb6,
+	0x0a, 0x3c, 0xb2, 0x32, 0x3c, 0x00, 0xf0, 0xc3, 0x30, 0x1a, 0x8b, 0x9a, 0xa4, 0x0a, 0x44, 0x8d,
+	0x73, 0x86, 0x9c, 0x71, 0xb3, 0xab, 0xf8, 0x07, 0xd8, 0x3c, 0xa4, 0x2c, 0x35, 0x6c, 0xfd, 0xee,
+	0x99, 0xe5, 0x85, 0x7c, 0xa4, 0x78, 0xa1, 0xf5, 0xa6, 0x91, 0xef, 0x5d, 0xcc, 0xf5, 0x45, 0x6a,
+	0x9a, 0xbb, 0x44, 0xaf, 0x19, 0x8d, 0x03, 0xdb, 0xd7, 0x88, 0x50, 0x23, 0xa0, 0x59, 0x43, 0x17,
+	0xff, 0xd9, 0x80, 0xad, 0x23, 0x2f, 0xd1, 0xa7, 0x27, 0xfa, 0xf8, 0x05, 0x8c, 0x19, 0xb9, 0xf6,
+	0x72, 0x2d, 0x16, 0x16, 0x6e, 0xc0, 0xc2, 0xf4, 0x0e, 0xcd, 0xf5, 0x77, 0x58, 0xcc, 0xde, 0xe1,
+	0x2d, 0x2f, 0x61, 0x02, 0xdb, 0x79, 0x1b, 0xdf, 0xd7, 0x05, 0x8f, 0x60, 0x7b, 0x18, 0x24, 0x34,
+	0x5e, 0xbe, 0x0c, 0x0c, 0x15, 0x85, 0xa2, 0x2a, 0xf3, 0xab, 0xe9, 0x31, 0x5a, 0x70, 0xf3, 0x05,
+	0x61, 0x17, 0xb6, 0xcf, 0x23, 0xde, 0x4c, 0xbc, 0xe3, 0x8a, 0x33, 0xa7, 0x14, 0xee, 0x70, 0xca,
+	0x52, 0x16, 0x3d, 0x87, 0xed, 0x03, 0xea, 0xd3, 0x77, 0x9e, 0x72, 0xb3, 0x9d, 0x8f, 0x60, 0xfb,
+	0x75, 0xec, 0x65, 0x36, 0x50, 0x61, 0x5e, 0xda, 0x01, 0xff, 0xc5, 0x80, 0xf6, 0x3b, 0x74, 0xb2,
+	0xbe, 0x98, 0x37, 0xf9, 0xb2, 0x76, 0x20, 0x91, 0x10, 0x79, 0xcb, 0x40, 0x52, 0xca, 0x0e, 0x24,
+	0x0f, 0xa1, 0xc1, 0xa5, 0x09, 0x0b, 0xe3, 0xb1, 0xe7, 0xf2, 0xaa, 0x6c, 0xf6, 0x9b, 0xa4, 0xae,
+	0x79, 0x43, 0x37, 0xc1, 0xff, 0x30, 0xa0, 0xa2, 0x8e, 0xbe, 0x2b, 0x84, 0x3d, 0xcb, 0xd5, 0x49,
+	0xd9, 0x7b, 0x77, 0xb5, 0xfd, 0xb7, 0x55, 0xc8, 0xf7, 0x55, 0xd3, 0xfe, 0x65, 0x40, 0x55, 0xdb,
+	0x89, 0x70, 0xae, 0x6f, 0x68, 0xa5, 0x0e, 0x64, 0x5b, 0x86, 0x1f, 0x03, 0xe4, 0xd0, 0xd7, 0xcc,
+	0xbb, 0x9a, 0x11, 0xa2, 0x5d, 0xa8, 0x3b, 0x61, 0x18, 0xbb, 0x5e, 0x20, 0x9a, 0x71, 0x73, 0xd7,
+	0xe4, 0x25, 0x2a, 0xc3, 0xe2, 0x85, 0x9d, 0x06, 0xae, 0xec, 0xd3, 0x9b, 0x44, 0x7c, 0xe3, 0xe7,
+	0x8b, 0x2a, 0x7b, 0x7a, 0x32, 0x3c, 0x1e, 0x75, 0x36, 0x50, 0x1d, 0x2a, 0xa7, 0x27, 0x47, 0xdf,
+	0x1d, 0x9e, 0x1c, 0x77, 0x0c, 0xd4, 0x81, 0xc6, 0xab, 0xf3, 0xa3, 0xd1, 0x50, 0x73, 0x0a, 0xa8,
+	0x05, 0x70, 0x34, 0x3c, 0x1e, 0x9c, 0x8d, 0xc8, 0xf0, 0xf8, 0xb0, 0x63, 0xe2, 0x26, 0xd4, 0x87,
+	0xc1, 0x45, 0xa8, 0xd2, 0x14, 0xff, 0xc7, 0x80, 0x86, 0xa4, 0x55, 0x46, 0x7d, 0x0a, 0x6d, 0x97,
+	0x5e, 0xd8, 0x33, 0x9f, 0x8d, 0x75, 0xbe, 0xca, 0x18, 0xb6, 0x14, 0xfb, 0x40, 0x72, 0x51, 0x1f,
+	0xaa, 0x4a, 0x41, 0x7b, 0xda, 0xb0, 0x94, 0x4c, 0x6c, 0x98, 0x4a, 0x79, 0xea, 0x5f, 0xd1, 0x38,
+	0xe1, 0xcd, 0x88, 0x7a, 0x3c, 0x8a, 0xe4, 0xd8, 0x9d, 0x30, 0x3b, 0x66, 0xe3, 0x4c, 0x5b, 0x58,
+	0x13, 0x9c, 0x11, 0x6f, 0x63, 0xee, 0x41, 0x79, 0x16, 0x09, 0x91, 0x9c, 0x3b, 0x14, 0x85, 0x44,
+	0x67, 0x1f, 0xd8, 0x81, 0x9e, 0xb5, 0x15, 0x25, 0x66, 0x0d, 0xf1, 0x35, 0xfe, 0x7e, 0x16, 0x32,
+	0x5b, 0xb4, 0x44, 0x4d, 0x52, 0x97, 0xbc, 0x5f, 0x71, 0x16, 0xfe, 0x5d, 0x11, 0xea, 0x19, 0x2b,
+	0x79, 0x90, 0x03, 0x7b, 0x4a, 0x95, 0x8f, 0xe2, 0x9b, 0x23, 0xfb, 0x85, 0xe7, 0x53, 0xc1, 0x97,
+	0x6f, 0x35, 0xa5, 0xd1, 0x8f, 0xa0, 0xa9, 0x5b, 0x3d, 0x27, 0x9c, 0x05, 0x12, 0x0e, 0x9a, 0xa4,
+	0xa1, 0x98, 0xfb, 0x9c, 0xc7, 0xdd, 0x92, 0x53, 0x53, 0xd6, 0x2d, 0xc1, 0x11, 0x6e, 0x7d, 0xca,
+	0x7f, 0x82, 0xb8, 0xf4, 0x9a, 0xc6, 0x63, 0x1d, 0x17, 0x89, 0xbc, 0x2d, 0xc5, 0xfe, 0x46, 0x85,
+	0xe7, 0x11, 0xb4, 0xa7, 0x5e, 0x30, 0x76, 0xc2, 0x2b, 0x1a, 0xab, 0x79, 0xaf, 0x2c, 0x20, 0xbd,
+	0x39, 0xf5, 0x82, 0x7d, 0xce, 0x5d, 0x9d, 0xf9, 0x2a, 0x2b, 0x33, 0x5f, 0x43, 0x8f, 0x49, 0x7c,
+	0x81, 0x68, 0x07, 0xeb, 0x7b, 0x4d, 0x4b, 0x2c, 0x3f, 0x89, 0x78, 0x4b, 0x98, 0x10, 0x35, 0x49,
+	0x09, 0x1e, 0xda, 0x83, 0x66, 0x38, 0x63, 0x99, 0x25, 0xb5, 0x75, 0x4b, 0x1a, 0x4a, 0x47, 0xae,
+	0x79, 0x00, 0x60, 0xcf, 0x58, 0xa8, 0x16, 0x80, 0xfc, 0x35, 0xc0, 0x39, 0x52, 0xfc, 0x25, 0x6c,
+	0xab, 0x8b, 0xc9, 0x07, 0xaf, 0x2e, 0x82, 0x87, 0xa4, 0xec, 0x65, 0x36, 0x84, 0xe2, 0x79, 0x4c,
+	0xa3, 0x98, 0x26, 0x22, 0x3e, 0x0d, 0xe1, 0x55, 0x96, 0xc5, 0x6f, 0x42, 0xd4, 0x7d, 0x1a, 0x38,
+	0xa1, 0xeb, 0x05, 0x13, 0xf1, 0x43, 0xa2, 0x46, 0x1a, 0x9c, 0x39, 0x50, 0x3c, 0xf4, 0x11, 0x80,
+	0x8a, 0x04, 0x7f, 0x90, 0xad, 0x5d, 0x93, 0x57, 0x9e, 0x05, 0x07, 0xff, 0xc6, 0x80, 0x46, 0xd6,
+	0x2d, 0xf4, 0x21, 0xd4, 0x78, 0xc8, 0x65, 0xb0, 0xe5, 0x68, 0x54, 0x9d, 0x7a, 0x81, 0x8c, 0x33,
+	0x17, 0xda, 0xd7, 0xb9, 0xc9, 0xbb, 0x3a, 0xb5, 0xaf, 0x73, 0x42, 0x3e, 0x8c, 0x26, 0x5d, 0x33,
+	0x15, 0xf2, 0x51, 0x54, 0x6c, 0x2b, 0x56, 0x8d, 0xa7, 0xa1, 0xab, 0x5a, 0xab, 0xaa, 0x60, 0xbc,
+	0x0a, 0x5d, 0xfc, 0x18, 0x4a, 0xa2, 0xa1, 0xbc, 0x4b, 0x23, 0x8c, 0xdb, 0xd0, 0x3c, 0x63, 0x36,
+	0x9b, 0xe9, 0x96, 0x01, 0x7f, 0x06, 0xe8, 0x8c, 0xb2, 0xa3, 0x70, 0x22, 0xcc, 0x50, 0x5c, 0xd1,
+	0x03, 0xa4, 0x3e, 0xd4, 0x88, 0x24, 0xf0, 0xd7, 0xd0, 0x3b, 0xa3, 0xec, 0x8c, 0x85, 0xd1, 0x49,
+	0xf0, 0xd2, 0x8b, 0x13, 0xf6, 0x92, 0xe3, 0xbd, 0x5e, 0xf3, 0x39, 0x6c, 0x25, 0x2c, 0x8c, 0xc6,
+	0x61, 0x30, 0xbe, 0xe0, 0xc2, 0xf1, 0x05, 0x97, 0x8a, 0x1d, 0xaa, 0xa4, 0x93, 0x2c, 0xad, 0xc2,
+	0xbf, 0x06, 0x44, 0x68, 0xe2, 0xfd, 0x40, 0xf7, 0x6d, 0xe7, 0x32, 0xad, 0x7b, 0x5f, 0x40, 0xc9,
+	0xe1, 0xb4, 0xc2, 0xc9, 0x0f, 0xac, 0x55, 0x1d, 0x4b, 0x12, 0x52, 0x8f, 0x5b, 0x2a, 0x93, 0x41,
+	0x06, 0x54, 0x12, 0x18, 0x43, 0x49, 0x68, 0xf1, 0xdf, 0x39, 0x2f, 0x07, 0x2f, 0x46, 0xe7, 0x64,
+	0x70, 0x26, 0xc1, 0x8e, 0x0c, 0xce, 0xce, 0x8f, 0x46, 0x67, 0x1d, 0x03, 0xb7, 0xa0, 0x71, 0x10,
+	0xdb, 0xe9, 0x10, 0x8e, 0xff, 0x69, 0x40, 0xfd, 0x85, 0x3b, 0xf5, 0x02, 0x19, 0x20, 0x11, 0xf4,
+	0x70, 0x32, 0xce, 0xc6, 0xa1, 0xea, 0xab, 0x38, 0xdd, 0xe4, 0x6c, 0x61, 0xbd, 0xb3, 0xbc, 0x87,
+	0x11, 0xe6, 0x66, 0x5e, 0x7d, 0x89, 0xff, 0x29, 0x71, 0x2e, 0x55, 0xc2, 0x3e, 0x01, 0x14, 0xd3,
+	0x84, 0xc3, 0x66, 0x56, 0x4f, 0x5e, 0x75, 0x47, 0x4a, 0xf6, 0x17, 0xda, 0x7c, 0x0a, 0xe0, 0xa6,
+	0xf3, 0xbc, 0x55, 0xff, 0x20, 0x34, 0x8d, 0x1f, 0x43, 0x5b, 0x01, 0x40, 0xda, 0x16, 0x66, 0x9a,
+	0x07, 0x23, 0xdf, 0x3c, 0x7c, 0x0d, 0x3b, 0xa7, 0x71, 0x38, 0x0d, 0x19, 0x55, 0x6b, 0xde, 0xb9,
+	0x24, 0x0b, 0xc7, 0x85, 0x1c, 0x1c, 0xe3, 0x29, 0xb4, 0x14, 0x36, 0x6a, 0x04, 0x5a, 0x07, 0x8f,
+	0x08, 0x8a, 0xfc, 0x46, 0xc5, 0x62, 0x93, 0x88, 0x6f, 0xf4, 0x01, 0x54, 0xa7, 0xa1, 0x2b, 0xf1,
+	0xce, 0x14, 0xfc, 0xca, 0x34, 0x74, 0x47, 0x6a, 0xc0, 0x77, 0x66, 0x71, 0x4c, 0x55, 0x34, 0xaa,
+	0x44, 0x93, 0xf8, 0x0f, 0x06, 0x74, 0x16, 0x9e, 0xaa, 0xfa, 0x73, 0xab, 0xdd, 0x7a, 0x23, 0x65,
+	0xb7, 0x22, 0x79, 0x34, 0xa3, 0x98, 0x5e, 0x79, 0xe1, 0x2c, 0xd1, 0xff, 0xbc, 0x34, 0xcd, 0x7f,
+	0x9d, 0x28, 0xf7, 0xf4, 0x1f, 0xaf, 0xb6, 0x95, 0x77, 0x92, 0xa4, 0x0a, 0x7b, 0xff, 0x2d, 0x42,
+	0x79, 0x28, 0xa0, 0x10, 0x3d, 0x86, 0xb2, 0xfc, 0xc3, 0x82, 0x96, 0xfe, 0xf5, 0xf4, 0x96, 0x7f,
+	0xbd, 0xe0, 0x0d, 0xf4, 0x11, 0x98, 0x87, 0x94, 0xa1, 0xba, 0xb5, 0x98, 0x53, 0x7a, 0x69, 0xe7,
+	0x85, 0x37, 0xd0, 0x13, 0x31, 0xc1, 0x28, 0x1a, 0x21, 0x6b, 0x65, 0xee, 0xc8, 0x69, 0xff, 0x1c,
+	0x1a, 0xd9, 0xbe, 0x1b, 0x6d, 0x5b, 0x6b, 0x46, 0x85, 0xde, 0x8e, 0xb5, 0xae, 0x39, 0xc7, 0x1b,
+	0xe8, 0x29, 0x34, 0xa4, 0x81, 0x67, 0x2c, 0xa6, 0xf6, 0xf4, 0x0e, 0xf6, 0xf7, 0x8d, 0x2f, 0x0d,
+	0x64, 0x41, 0x45, 0xcd, 0xbd, 0xa8, 0x6d, 0xe5, 0x27, 0xf4, 0x5e, 0xc7, 0x5a, 0x1a, 0x89, 0xf1,
+	0x06, 0xfa, 0x29, 0xd4, 0xd2, 0xd9, 0x0f, 0x6d, 0x5a, 0xcb, 0x73, 0x6b, 0x0f, 0x59, 0x2b, 0xa3,
+	0x21, 0xde, 0x40, 0x9f, 0x40, 0x51, 0xd4, 0xdd, 0x86, 0x95, 0xe9, 0x42, 0x7a, 0x4d, 0x2b, 0xdb,
+	0x83, 0x88, 0x80, 0x95, 0xc4, 0xdf, 0x26, 0xd4, 0xb4, 0xb2, 0x7f, 0x9d, 0x7a, 0xad, 0xfc, 0x6f,
+	0x13, 0x65, 0xfa, 0x2f, 0xa1, 0x99, 0x9b, 0x1f, 0xd0, 0x8e, 0xb5, 0x6e, 0x9e, 0xe8, 0xed, 0x58,
+	0xeb, 0x1a, 0x6d, 0xbc, 0xc1, 0x37, 0xc8, 0x8d, 0x0a, 0x68, 0xc7, 0x5a, 0x37, 0x3a, 0xdc, 0xba,
+	0x41, 0x6e, 0x0a, 0x40, 0x3b, 0xd6, 0xba, 0xa9, 0xe0, 0xc6, 0x0d, 0xf6, 0xfe, 0x6d, 0x42, 0x43,
+	0x62, 0x17, 0x8d, 0xaf, 0x3c, 0x87, 0xa2, 0x3e, 0x94, 0x15, 0x8c, 0xb5, 0xac, 0x1c, 0xe0, 0xf7,
+	0x1a, 0x56, 0x06, 0xe4, 0xf0, 0x06, 0xda, 0x83, 0x7a, 0xa6, 0x00, 0xa0, 0x2d, 0x6b, 0xb5, 0x1c,
+	0xac, 0xac, 0xf9, 0x0a, 0xb6, 0xd6, 0x14, 0x02, 0xf4, 0xa1, 0x75, 0x73, 0x79, 0x58, 0x77, 0x6e,
+	0x06, 0xdb, 0xd1, 0xd6, 0x1a, 0xa4, 0x5f, 0x59, 0xf3, 0x08, 0x4a, 0x02, 0xb2, 0x51, 0xd3, 0xca,
+	0x42, 0xf7, 0x8a, 0x5e, 0x1f, 0x2a, 0xe7, 0x81, 0x7b, 0x17, 0xcd, 0xa7, 0xf2, 0xb1, 0x68, 0x1c,
+	0x41, 0x1d, 0x6b, 0x09, 0x3c, 0x7b, 0x9b, 0xd6, 0x32, 0xc8, 0x88, 0x37, 0xd6, 0xca, 0xe3, 0x26,
+	0xba, 0x67, 0xad, 0x05, 0xd2, 0xf5, 0xcb, 0x9f, 0x41, 0x9b, 0x84, 0xbe, 0xff, 0xc6, 0x76, 0xde,
+	0xea, 0xf5, 0x77, 0x3b, 0xf8, 0x4d, 0x59, 0x8c, 0x1b, 0x3f, 0xf9, 0xff, 0x00, 0x03, 0x6a, 0x73,
+	0x55, 0x08, 0x1b, 0x00, 0x00,
 }
@@ -1,8 +1,20 @@
 package insidesvc
 
 const (
-	LoopIndexProperty = "insided_loop_index"
-	FeatureIDProperty = "insided_fid"
-	CellsInProperty   = "insided_cells_in"
-	CellsOutProperty  = "insided_cells_out"
+	LoopIndexProperty            = "insided_loop_index"
+	FeatureIDProperty            = "insided_fid"
+	CellsInProperty              = "insided_cells_in"
+	CellsOutProperty             = "insided_cells_out"
+	DistanceProperty             = "insided_distance"
+	BoundaryDistanceProperty     = "insided_boundary_distance"
+	ExactProperty                = "insided_exact"
+	AncestorIDsProperty          = "insided_ancestor_ids"
+	UTCOffsetProperty            = "insided_utc_offset"
+	DSTProperty                  = "insided_dst"
+	TimezoneAbbreviationProperty = "insided_tz_abbreviation"
+	H3CellProperty               = "insided_h3_cell"
+	CentroidGeohashProperty      = "insided_centroid_geohash"
+	SourceProperty               = "insided_source"
+	VertexCountProperty          = "insided_vertex_count"
+	TenantProperty               = "insided_tenant"
 )
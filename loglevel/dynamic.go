@@ -0,0 +1,62 @@
+package loglevel
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	log "github.com/go-kit/kit/log"
+)
+
+// Dynamic filters the log level like NewLevelFilterFromString, the level can be changed while logging
+type Dynamic struct {
+	next log.Logger
+
+	mu     sync.RWMutex
+	level  string
+	filter log.Logger
+}
+
+// NewDynamic returns a Dynamic filtering next at the level ls "DEBUG|INFO|WARN|ERROR"
+func NewDynamic(next log.Logger, ls string) (*Dynamic, error) {
+	d := &Dynamic{next: next}
+	if err := d.SetLevel(ls); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Log implements log.Logger
+func (d *Dynamic) Log(keyvals ...interface{}) error {
+	d.mu.RLock()
+	filter := d.filter
+	d.mu.RUnlock()
+	return filter.Log(keyvals...)
+}
+
+// SetLevel changes the level to ls "DEBUG|INFO|WARN|ERROR"
+func (d *Dynamic) SetLevel(ls string) error {
+	ls = strings.ToUpper(ls)
+	switch ls {
+	case "DEBUG", "INFO", "WARN", "ERROR":
+	case "WARNING":
+		ls = "WARN"
+	case "ERR":
+		ls = "ERROR"
+	default:
+		return fmt.Errorf("unknown log level %s", ls)
+	}
+
+	filter := NewLevelFilterFromString(d.next, ls)
+	d.mu.Lock()
+	d.level, d.filter = ls, filter
+	d.mu.Unlock()
+	return nil
+}
+
+// Level returns the current level
+func (d *Dynamic) Level() string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.level
+}
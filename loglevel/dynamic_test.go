@@ -0,0 +1,33 @@
+package loglevel
+
+import (
+	"bytes"
+	"testing"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDynamic(t *testing.T) {
+	var buf bytes.Buffer
+	d, err := NewDynamic(log.NewLogfmtLogger(&buf), "info")
+	require.NoError(t, err)
+	require.Equal(t, "INFO", d.Level())
+
+	level.Debug(d).Log("msg", "hidden")
+	require.Empty(t, buf.String())
+
+	require.NoError(t, d.SetLevel("DEBUG"))
+	level.Debug(d).Log("msg", "shown")
+	require.Contains(t, buf.String(), "shown")
+
+	require.NoError(t, d.SetLevel("warning"))
+	require.Equal(t, "WARN", d.Level())
+	buf.Reset()
+	level.Info(d).Log("msg", "hidden")
+	require.Empty(t, buf.String())
+
+	require.Error(t, d.SetLevel("verbose"))
+	require.Equal(t, "WARN", d.Level())
+}
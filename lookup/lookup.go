@@ -0,0 +1,112 @@
+// Package lookup answers the within queries of a storage with the insidetree strategy, in process,
+// without any of the dependencies of the server so it compiles to WebAssembly, see cmd/insidewasm:
+// a browser or an edge function downloads a flat index then runs the point in polygon lookups itself.
+//
+// The embedded package serves all the strategies and queries of the server for the other platforms.
+package lookup
+
+import (
+	"fmt"
+
+	"github.com/golang/geo/s2"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/index/treeindex"
+)
+
+// Options the options of an Index, the zero value returns all the features containing a point
+type Options struct {
+	// StopOnFirstFound stops on the first feature found inside its inside cover
+	StopOnFirstFound bool
+}
+
+// Index the insidetree index of a storage, safe for concurrent use
+type Index struct {
+	storage insideout.Store
+	idx     *treeindex.Index
+	infos   *insideout.IndexInfos
+
+	// ls tests the loops in place, nil when not supported by the storage
+	ls insideout.LoopStore
+}
+
+// Match a feature containing a point
+type Match struct {
+	ID uint32
+	// Pos the polygon of the feature containing the point
+	Pos     uint16
+	Feature *insideout.Feature
+}
+
+// New loads the cells of the features of storage in an insidetree index
+func New(storage insideout.Store, opts Options) (*Index, error) {
+	infos, err := storage.LoadIndexInfos()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index infos: %w", err)
+	}
+	if err := infos.Validate(); err != nil {
+		return nil, fmt.Errorf("incompatible index: %w", err)
+	}
+
+	idx := treeindex.New(treeindex.Options{StopOnInsideFound: opts.StopOnFirstFound})
+	if err := storage.LoadFeaturesCells(idx.Add); err != nil {
+		return nil, fmt.Errorf("failed to load cells from storage: %w", err)
+	}
+
+	ls, _ := storage.(insideout.LoopStore)
+	return &Index{storage: storage, idx: idx, infos: infos, ls: ls}, nil
+}
+
+// Infos returns the infos of the indexed storage
+func (idx *Index) Infos() *insideout.IndexInfos {
+	return idx.infos
+}
+
+// Within returns the features containing the point at lat lng, the features inside their inside cover
+// are returned without testing their polygon, like the insidetree strategy of the server
+func (idx *Index) Within(lat, lng float64) ([]Match, error) {
+	resp, err := idx.idx.Stab(lat, lng)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []Match
+	for _, fid := range resp.IDsInside {
+		f, err := idx.storage.LoadFeature(fid.ID)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, Match{ID: fid.ID, Pos: fid.Pos, Feature: f})
+	}
+
+	p := s2.PointFromLatLng(s2.LatLngFromDegrees(lat, lng))
+	for _, fid := range resp.IDsMayBeInside {
+		f, ok, err := idx.test(fid, p)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			res = append(res, Match{ID: fid.ID, Pos: fid.Pos, Feature: f})
+		}
+	}
+	return res, nil
+}
+
+// test returns the feature of fid and whether its loop contains p outside of its holes,
+// the loop is tested in place when supported by the storage, the feature is then only loaded when accepted
+func (idx *Index) test(fid insideout.FeatureIndexResponse, p s2.Point) (*insideout.Feature, bool, error) {
+	if idx.ls != nil {
+		inside, err := idx.ls.LoopContainsPoint(fid.ID, fid.Pos, p)
+		if err != nil || !inside {
+			return nil, false, err
+		}
+		f, err := idx.storage.LoadFeature(fid.ID)
+		return f, err == nil, err
+	}
+
+	f, err := idx.storage.LoadFeature(fid.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	return f, f.PolygonContainsPoint(fid.Pos, p), nil
+}
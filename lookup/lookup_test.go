@@ -0,0 +1,71 @@
+package lookup
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom/encoding/geojson"
+
+	"github.com/akhenakh/insideout/storage/flat"
+)
+
+func TestIndex_Within(t *testing.T) {
+	logger := log.NewNopLogger()
+
+	tmpDir, err := ioutil.TempDir(os.TempDir(), "insideout-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+	path := filepath.Join(tmpDir, "inside.flat")
+
+	wstorage, wclose, err := flat.NewStorage(path, logger)
+	require.NoError(t, err)
+
+	var fc geojson.FeatureCollection
+	b, err := ioutil.ReadFile("../index/testdata/poly.geojson")
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(b, &fc))
+
+	icoverer := &s2.RegionCoverer{MinLevel: 10, MaxLevel: 16, MaxCells: 24}
+	ocoverer := &s2.RegionCoverer{MinLevel: 10, MaxLevel: 15, MaxCells: 16}
+	require.NoError(t, wstorage.Index(fc, icoverer, ocoverer, 100, "poly.geojson", "unittest"))
+	require.NoError(t, wclose())
+
+	// a downloaded index
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	storage, err := flat.NewROStorageFromBytes(data, logger)
+	require.NoError(t, err)
+
+	idx, err := New(storage, Options{})
+	require.NoError(t, err)
+	require.Equal(t, "poly.geojson", idx.Infos().Filename)
+
+	tests := []struct {
+		name     string
+		lat, lng float64
+		want     []uint16
+	}{
+		{"inside loop not within inside index", 47.39444367083928, -2.992874768945723, []uint16{1}},
+		{"inside loop within inside index", 47.39650628189986, -2.9876390969486524, []uint16{1}},
+		{"outside loop outside outside index", 47.37616957736262, -3.004367209321472, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, err := idx.Within(tt.lat, tt.lng)
+			require.NoError(t, err)
+			var got []uint16
+			for _, m := range matches {
+				require.Equal(t, uint32(0), m.ID)
+				require.NotNil(t, m.Feature)
+				got = append(got, m.Pos)
+			}
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,343 @@
+package insideout
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/golang/geo/r1"
+	"github.com/golang/geo/r3"
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+)
+
+// LoopStore is implemented by the storages testing a point against a stored loop in place,
+// the feature is not decoded
+type LoopStore interface {
+	LoopContainsPoint(id uint32, pos uint16, p s2.Point) (bool, error)
+}
+
+// the s2 loop encoding: version, vertices count, vertices, origin inside, depth, bound
+const (
+	loopHeaderSize = 1 + 4
+	vertexSize     = 3 * 8
+	loopFooterSize = 1 + 4 + 1 + 4*8
+)
+
+// EncodedLoop an s2 encoded loop read in place, it holds no copy of the encoded bytes
+type EncodedLoop []byte
+
+// NewEncodedLoop returns the EncodedLoop of b, b must not be modified while it is used
+func NewEncodedLoop(b []byte) (EncodedLoop, error) {
+	if len(b) < loopHeaderSize+loopFooterSize || b[0] != 1 {
+		return nil, errors.New("invalid encoded loop")
+	}
+	n := binary.LittleEndian.Uint32(b[1:])
+	if uint64(len(b)) != loopHeaderSize+uint64(n)*vertexSize+loopFooterSize {
+		return nil, fmt.Errorf("invalid encoded loop size %d for %d vertices", len(b), n)
+	}
+	return EncodedLoop(b), nil
+}
+
+// NumVertices returns the number of vertices of the loop
+func (l EncodedLoop) NumVertices() int {
+	return int(binary.LittleEndian.Uint32(l[1:]))
+}
+
+// Vertex returns the vertex i, modulo the number of vertices
+func (l EncodedLoop) Vertex(i int) s2.Point {
+	off := loopHeaderSize + (i%l.NumVertices())*vertexSize
+	return s2.Point{Vector: r3.Vector{
+		X: l.float64(off),
+		Y: l.float64(off + 8),
+		Z: l.float64(off + 16),
+	}}
+}
+
+// ContainsOrigin reports true if the loop contains s2.OriginPoint()
+func (l EncodedLoop) ContainsOrigin() bool {
+	return l[len(l)-loopFooterSize] != 0
+}
+
+// RectBound returns the bounding rectangle of the loop
+func (l EncodedLoop) RectBound() s2.Rect {
+	off := len(l) - 4*8
+	return s2.Rect{
+		Lat: r1.Interval{Lo: l.float64(off), Hi: l.float64(off + 8)},
+		Lng: s1.Interval{Lo: l.float64(off + 16), Hi: l.float64(off + 24)},
+	}
+}
+
+// ContainsPoint reports whether the loop contains p, like s2.Loop ContainsPoint
+// but counting the crossings of all the edges, without building the index of the loop
+func (l EncodedLoop) ContainsPoint(p s2.Point) bool {
+	n := l.NumVertices()
+	// the empty and full loops
+	if n < 3 {
+		return l.ContainsOrigin()
+	}
+	if !l.RectBound().ContainsPoint(p) {
+		return false
+	}
+
+	inside := l.ContainsOrigin()
+	crosser := s2.NewChainEdgeCrosser(s2.OriginPoint(), p, l.Vertex(0))
+	for i := 1; i <= n; i++ { // add vertex 0 twice
+		inside = inside != crosser.EdgeOrVertexChainCrossing(l.Vertex(i))
+	}
+	return inside
+}
+
+func (l EncodedLoop) float64(off int) float64 {
+	return math.Float64frombits(binary.LittleEndian.Uint64(l[off:]))
+}
+
+// FeatureLoopBytes returns the encoded loop pos of a CBOR encoded FeatureStorage,
+// the returned bytes are a slice of b
+func FeatureLoopBytes(b []byte, pos int) ([]byte, error) {
+	d := cborReader{b: b}
+	major, n, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	if major != cborMap {
+		return nil, errors.New("invalid feature storage")
+	}
+	for i := uint64(0); i < n; i++ {
+		major, kn, err := d.head()
+		if err != nil {
+			return nil, err
+		}
+		if major != cborText || kn > uint64(len(d.b)-d.off) {
+			return nil, errors.New("invalid feature storage key")
+		}
+		key := d.b[d.off : d.off+int(kn)]
+		d.off += int(kn)
+		if string(key) != "LoopsBytes" {
+			if err := d.skip(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		major, count, err := d.head()
+		if err != nil {
+			return nil, err
+		}
+		if major != cborArray {
+			return nil, errors.New("invalid feature storage loops")
+		}
+		if uint64(pos) >= count {
+			return nil, fmt.Errorf("no loop %d in %d loops", pos, count)
+		}
+		for j := 0; j < pos; j++ {
+			if err := d.skip(); err != nil {
+				return nil, err
+			}
+		}
+		major, ln, err := d.head()
+		if err != nil {
+			return nil, err
+		}
+		if major != cborBytes || ln > uint64(len(d.b)-d.off) {
+			return nil, errors.New("invalid feature storage loop")
+		}
+		return d.b[d.off : d.off+int(ln)], nil
+	}
+	return nil, errors.New("no loops in feature storage")
+}
+
+// FeatureHolesBytes returns the encoded holes of the loop pos of a CBOR encoded FeatureStorage, nil without holes,
+// the returned bytes are slices of b
+func FeatureHolesBytes(b []byte, pos int) ([][]byte, error) {
+	d := cborReader{b: b}
+	major, n, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	if major != cborMap {
+		return nil, errors.New("invalid feature storage")
+	}
+	for i := uint64(0); i < n; i++ {
+		major, kn, err := d.head()
+		if err != nil {
+			return nil, err
+		}
+		if major != cborText || kn > uint64(len(d.b)-d.off) {
+			return nil, errors.New("invalid feature storage key")
+		}
+		key := d.b[d.off : d.off+int(kn)]
+		d.off += int(kn)
+		if string(key) != "HolesBytes" {
+			if err := d.skip(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		major, count, err := d.head()
+		if err != nil {
+			return nil, err
+		}
+		if major != cborArray {
+			return nil, errors.New("invalid feature storage holes")
+		}
+		if uint64(pos) >= count {
+			return nil, nil
+		}
+		for j := 0; j < pos; j++ {
+			if err := d.skip(); err != nil {
+				return nil, err
+			}
+		}
+		// the loops without holes are null
+		major, hn, err := d.head()
+		if err != nil || major != cborArray {
+			return nil, err
+		}
+		holes := make([][]byte, 0, hn)
+		for j := uint64(0); j < hn; j++ {
+			major, ln, err := d.head()
+			if err != nil {
+				return nil, err
+			}
+			if major != cborBytes || ln > uint64(len(d.b)-d.off) {
+				return nil, errors.New("invalid feature storage hole")
+			}
+			holes = append(holes, d.b[d.off:d.off+int(ln)])
+			d.off += int(ln)
+		}
+		return holes, nil
+	}
+	return nil, nil
+}
+
+// FeaturePolygonContainsPoint reports whether the loop pos of a CBOR encoded FeatureStorage contains p
+// outside of its holes, tested in place
+func FeaturePolygonContainsPoint(b []byte, pos int, p s2.Point) (bool, error) {
+	lb, err := FeatureLoopBytes(b, pos)
+	if err != nil {
+		return false, err
+	}
+	inside, err := LoopBytesContainsPoint(lb, p)
+	if err != nil || !inside {
+		return false, err
+	}
+	holes, err := FeatureHolesBytes(b, pos)
+	if err != nil {
+		return false, err
+	}
+	for _, hb := range holes {
+		in, err := LoopBytesContainsPoint(hb, p)
+		if err != nil {
+			return false, err
+		}
+		if in {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// CBOR major types
+const (
+	cborBytes = 2
+	cborText  = 3
+	cborArray = 4
+	cborMap   = 5
+	cborTag   = 6
+)
+
+// cborIndefinite the length of the indefinite strings, arrays and maps
+const cborIndefinite = math.MaxUint64
+
+// cborReader walks CBOR items without decoding them
+type cborReader struct {
+	b   []byte
+	off int
+}
+
+// head reads the head of an item, its major type and its argument, the length of the strings, arrays and maps
+func (d *cborReader) head() (byte, uint64, error) {
+	if d.off >= len(d.b) {
+		return 0, 0, errors.New("unexpected end of CBOR data")
+	}
+	major, ai := d.b[d.off]>>5, d.b[d.off]&31
+	d.off++
+
+	var size int
+	switch {
+	case ai < 24:
+		return major, uint64(ai), nil
+	case ai == 24:
+		size = 1
+	case ai == 25:
+		size = 2
+	case ai == 26:
+		size = 4
+	case ai == 27:
+		size = 8
+	case ai == 31 && major >= cborBytes && major <= cborMap:
+		return major, cborIndefinite, nil
+	case ai == 31 && major == 7:
+		// a break
+		return major, 0, nil
+	default:
+		return 0, 0, fmt.Errorf("invalid CBOR additional information %d", ai)
+	}
+	if len(d.b)-d.off < size {
+		return 0, 0, errors.New("unexpected end of CBOR data")
+	}
+	var v uint64
+	for _, c := range d.b[d.off : d.off+size] {
+		v = v<<8 | uint64(c)
+	}
+	d.off += size
+	return major, v, nil
+}
+
+// skip skips the next item
+func (d *cborReader) skip() error {
+	major, n, err := d.head()
+	if err != nil {
+		return err
+	}
+	if n == cborIndefinite {
+		for !d.isBreak() {
+			if err := d.skip(); err != nil {
+				return err
+			}
+		}
+		d.off++
+		return nil
+	}
+
+	switch major {
+	case cborBytes, cborText:
+		if n > uint64(len(d.b)-d.off) {
+			return errors.New("unexpected end of CBOR data")
+		}
+		d.off += int(n)
+	case cborArray:
+		for i := uint64(0); i < n; i++ {
+			if err := d.skip(); err != nil {
+				return err
+			}
+		}
+	case cborMap:
+		for i := uint64(0); i < 2*n; i++ {
+			if err := d.skip(); err != nil {
+				return err
+			}
+		}
+	case cborTag:
+		return d.skip()
+	}
+	return nil
+}
+
+// isBreak reports whether the next byte ends an indefinite item
+func (d *cborReader) isBreak() bool {
+	return d.off < len(d.b) && d.b[d.off] == 0xff
+}
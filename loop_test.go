@@ -0,0 +1,140 @@
+package insideout
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/fxamacker/cbor"
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/require"
+)
+
+func encodeLoop(t testing.TB, l *s2.Loop) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, l.Encode(&buf))
+	return buf.Bytes()
+}
+
+func TestEncodedLoop_ContainsPoint(t *testing.T) {
+	center := s2.PointFromLatLng(s2.LatLngFromDegrees(48.8, 2.3))
+	loops := []*s2.Loop{
+		s2.RegularLoop(center, s1.Degree, 500),
+		rectLoop(2, 48, 3, 49),
+		s2.EmptyLoop(),
+		s2.FullLoop(),
+	}
+	// a hole
+	hole := s2.RegularLoop(center, s1.Degree/2, 100)
+	hole.Invert()
+	loops = append(loops, hole)
+
+	r := rand.New(rand.NewSource(42))
+	for _, l := range loops {
+		el, err := NewEncodedLoop(encodeLoop(t, l))
+		require.NoError(t, err)
+		require.Equal(t, l.NumVertices(), el.NumVertices())
+		require.Equal(t, l.RectBound(), el.RectBound())
+		for i := 0; i < 2000; i++ {
+			p := s2.PointFromLatLng(s2.LatLngFromDegrees(46+r.Float64()*6, r.Float64()*5))
+			require.Equal(t, l.ContainsPoint(p), el.ContainsPoint(p), "point %v", p)
+		}
+	}
+
+	_, err := NewEncodedLoop([]byte{1, 2, 3})
+	require.Error(t, err)
+	b := encodeLoop(t, loops[1])
+	_, err = NewEncodedLoop(b[:len(b)-1])
+	require.Error(t, err)
+}
+
+func TestFeatureLoopBytes(t *testing.T) {
+	lb1 := encodeLoop(t, rectLoop(0, 0, 1, 1))
+	lb2 := encodeLoop(t, rectLoop(2, 2, 3, 3))
+	fs := &FeatureStorage{
+		Properties: map[string]interface{}{
+			"name":   "a",
+			"pop":    12345678.5,
+			"count":  uint64(1) << 40,
+			"neg":    int64(-300),
+			"ok":     true,
+			"none":   nil,
+			"tags":   []interface{}{"x", 1.5, map[string]interface{}{"k": "v"}},
+			"nested": map[string]interface{}{"a": []interface{}{}},
+		},
+		LoopsBytes: [][]byte{lb1, lb2},
+	}
+	b, err := cbor.Marshal(fs, cbor.CanonicalEncOptions())
+	require.NoError(t, err)
+
+	got, err := FeatureLoopBytes(b, 0)
+	require.NoError(t, err)
+	require.Equal(t, lb1, got)
+	got, err = FeatureLoopBytes(b, 1)
+	require.NoError(t, err)
+	require.Equal(t, lb2, got)
+
+	_, err = FeatureLoopBytes(b, 2)
+	require.Error(t, err)
+	_, err = FeatureLoopBytes(b[:len(b)/2], 1)
+	require.Error(t, err)
+
+	// without properties
+	b, err = cbor.Marshal(&FeatureStorage{LoopsBytes: [][]byte{lb2}}, cbor.EncOptions{})
+	require.NoError(t, err)
+	got, err = FeatureLoopBytes(b, 0)
+	require.NoError(t, err)
+	require.Equal(t, lb2, got)
+}
+
+func benchmarkLoop(b *testing.B) ([]byte, s2.Point) {
+	center := s2.PointFromLatLng(s2.LatLngFromDegrees(48.8, 2.3))
+	fs := &FeatureStorage{
+		Properties: map[string]interface{}{"name": "paris"},
+		LoopsBytes: [][]byte{encodeLoop(b, s2.RegularLoop(center, s1.Degree, 5000))},
+	}
+	v, err := cbor.Marshal(fs, cbor.CanonicalEncOptions())
+	require.NoError(b, err)
+	return v, s2.PointFromLatLng(s2.LatLngFromDegrees(48.9, 2.4))
+}
+
+// BenchmarkLoopDecodeContainsPoint a feature decoded to test a point, like a LoadFeature without cache
+func BenchmarkLoopDecodeContainsPoint(b *testing.B) {
+	v, p := benchmarkLoop(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		fs := &FeatureStorage{}
+		if err := cbor.NewDecoder(bytes.NewReader(v)).Decode(fs); err != nil {
+			b.Fatal(err)
+		}
+		l := &s2.Loop{}
+		if err := l.Decode(bytes.NewReader(fs.LoopsBytes[0])); err != nil {
+			b.Fatal(err)
+		}
+		if !l.ContainsPoint(p) {
+			b.Fatal("not inside")
+		}
+	}
+}
+
+// BenchmarkEncodedLoopContainsPoint the stored loop tested in place
+func BenchmarkEncodedLoopContainsPoint(b *testing.B) {
+	v, p := benchmarkLoop(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		lb, err := FeatureLoopBytes(v, 0)
+		if err != nil {
+			b.Fatal(err)
+		}
+		l, err := NewEncodedLoop(lb)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if !l.ContainsPoint(p) {
+			b.Fatal("not inside")
+		}
+	}
+}
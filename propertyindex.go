@@ -0,0 +1,122 @@
+package insideout
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PropertyIndex the ids of the features by value of some of their properties, built at index time
+type PropertyIndex struct {
+	// IndexTime the index time of the DB the index was built from, the index is stale for a DB indexed or written later
+	IndexTime time.Time
+
+	// Properties the names of the indexed properties
+	Properties []string
+
+	// IDs the sorted ids of the features by PropertyIndexKey
+	IDs map[string][]uint32
+
+	// IDProperty the indexed property holding the unique external id of each feature, empty for none, see CheckIDs
+	IDProperty string
+}
+
+// PropertyIndexStore is implemented by the storages persisting an index of the properties of their features
+type PropertyIndexStore interface {
+	// StorePropertyIndex stores the index of the properties
+	StorePropertyIndex(pi *PropertyIndex) error
+	// LoadPropertyIndex returns the index of the properties, nil when it was never stored
+	LoadPropertyIndex() (*PropertyIndex, error)
+}
+
+// PropertyIndexKey returns the key of the value v of the property name,
+// the numbers and booleans are formatted the same way as the values parsed from the query filters
+func PropertyIndexKey(name string, v interface{}) string {
+	var sv string
+	switch tv := v.(type) {
+	case string:
+		sv = tv
+	case float64:
+		sv = strconv.FormatFloat(tv, 'g', -1, 64)
+	case bool:
+		sv = strconv.FormatBool(tv)
+	default:
+		sv = fmt.Sprint(tv)
+	}
+	return name + "\x00" + sv
+}
+
+// BuildPropertyIndex returns the index of the properties props of all the features of s
+func BuildPropertyIndex(s Store, props []string) (*PropertyIndex, error) {
+	infos, err := s.LoadIndexInfos()
+	if err != nil {
+		return nil, err
+	}
+
+	indexed := make(map[string]bool, len(props))
+	for _, p := range props {
+		if p == "" {
+			return nil, fmt.Errorf("invalid empty property name")
+		}
+		indexed[p] = true
+	}
+
+	pi := &PropertyIndex{
+		IndexTime:  infos.IndexTime,
+		Properties: props,
+		IDs:        make(map[string][]uint32),
+	}
+	err = s.LoadAllFeatures(func(fs *FeatureStorage, id uint32) error {
+		for name, v := range fs.Properties {
+			if !indexed[name] {
+				continue
+			}
+			k := PropertyIndexKey(name, v)
+			pi.IDs[k] = append(pi.IDs[k], id)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load features from storage: %w", err)
+	}
+	for _, ids := range pi.IDs {
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	}
+	return pi, nil
+}
+
+// Lookup returns the sorted ids of the features with the value v for the property name
+func (pi *PropertyIndex) Lookup(name string, v interface{}) []uint32 {
+	return pi.IDs[PropertyIndexKey(name, v)]
+}
+
+// CheckIDs returns an error if several features share the same value for the indexed property name
+func (pi *PropertyIndex) CheckIDs(name string) error {
+	if !pi.Indexes(name) {
+		return fmt.Errorf("property %s is not indexed", name)
+	}
+	prefix := PropertyIndexKey(name, "")
+	var dups []string
+	for k, ids := range pi.IDs {
+		if len(ids) > 1 && strings.HasPrefix(k, prefix) {
+			dups = append(dups, fmt.Sprintf("%s (features %v)", k[len(prefix):], ids))
+		}
+	}
+	if len(dups) > 0 {
+		sort.Strings(dups)
+		return fmt.Errorf("%d duplicate values for the id property %s: %s", len(dups), name, strings.Join(dups, ", "))
+	}
+	return nil
+}
+
+// Indexes returns true if the property name is indexed
+func (pi *PropertyIndex) Indexes(name string) bool {
+	for _, p := range pi.Properties {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
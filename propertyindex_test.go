@@ -0,0 +1,72 @@
+package insideout
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// propertiesStore a Store loading features with properties only
+type propertiesStore struct {
+	Store
+	infos    *IndexInfos
+	features []map[string]interface{}
+}
+
+func (s *propertiesStore) LoadIndexInfos() (*IndexInfos, error) {
+	return s.infos, nil
+}
+
+func (s *propertiesStore) LoadAllFeatures(add func(*FeatureStorage, uint32) error) error {
+	for id := len(s.features) - 1; id >= 0; id-- {
+		if err := add(&FeatureStorage{Properties: s.features[id]}, uint32(id)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestBuildPropertyIndex(t *testing.T) {
+	s := &propertiesStore{
+		infos: &IndexInfos{IndexTime: time.Unix(42, 0)},
+		features: []map[string]interface{}{
+			{"iso_a2": "FR", "admin_level": 2.0, "capital": true},
+			{"iso_a2": "CA", "admin_level": 4.0},
+			{"iso_a2": "FR", "admin_level": 8.0, "capital": true},
+			{"name": "none"},
+		},
+	}
+
+	_, err := BuildPropertyIndex(s, []string{"iso_a2", ""})
+	require.Error(t, err)
+
+	pi, err := BuildPropertyIndex(s, []string{"iso_a2", "admin_level", "capital"})
+	require.NoError(t, err)
+	require.Equal(t, s.infos.IndexTime, pi.IndexTime)
+	require.True(t, pi.Indexes("capital"))
+	require.False(t, pi.Indexes("name"))
+
+	require.Equal(t, []uint32{0, 2}, pi.Lookup("iso_a2", "FR"))
+	require.Equal(t, []uint32{1}, pi.Lookup("iso_a2", "CA"))
+	require.Equal(t, []uint32{2}, pi.Lookup("admin_level", 8.0))
+	require.Equal(t, []uint32{0, 2}, pi.Lookup("capital", true))
+	require.Empty(t, pi.Lookup("iso_a2", "US"))
+	require.Empty(t, pi.Lookup("name", "none"))
+}
+
+func TestPropertyIndex_CheckIDs(t *testing.T) {
+	s := &propertiesStore{
+		infos: &IndexInfos{},
+		features: []map[string]interface{}{
+			{"id": "a", "code": 1.0},
+			{"id": "b", "code": 2.0},
+			{"id": "c", "code": 1.0},
+		},
+	}
+	pi, err := BuildPropertyIndex(s, []string{"id", "code"})
+	require.NoError(t, err)
+	require.NoError(t, pi.CheckIDs("id"))
+	require.EqualError(t, pi.CheckIDs("code"), "1 duplicate values for the id property code: 1 (features [0 2])")
+	require.Error(t, pi.CheckIDs("name"))
+}
@@ -0,0 +1,371 @@
+package insideout
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/golang/geo/s2"
+	"github.com/twpayne/go-geom"
+)
+
+// maxRingSplits limits the self-intersections removed from a ring, a ring crossing itself more is not repairable
+const maxRingSplits = 1000
+
+// ring lng lat points without the closing one
+type ring [][2]float64
+
+// fixes applied by RepairGeometry
+const (
+	fixDimensions    = "dropped extra dimensions"
+	fixSnapped       = "snapped coordinates"
+	fixClosed        = "closed ring"
+	fixDuplicates    = "removed duplicate points"
+	fixDegenerate    = "removed degenerate ring"
+	fixSelfIntersect = "split self-intersecting ring"
+	fixReoriented    = "reoriented ring"
+	fixOrphanHole    = "removed hole outside its polygon"
+	fixNormalized    = "normalized longitudes"
+	fixEmptyPolygon  = "removed empty polygon"
+)
+
+var (
+	errTooManySplits = errors.New("can't repair ring, too many self-intersections")
+	errNoPolygon     = errors.New("can't repair geometry, no valid polygon left")
+)
+
+// RepairGeometry returns g with its polygons fixed for the indexer and the fixes applied, g itself when none was needed.
+// Coordinates are snapped to a grid of precision degrees (0 to disable), rings are closed and their duplicate points removed,
+// longitudes are normalized to (-180, 180], self-intersecting rings are split into simple rings,
+// exterior rings are oriented counterclockwise and holes clockwise on the sphere.
+// A Polygon split into several becomes a MultiPolygon, geometries other than polygons are returned as is.
+func RepairGeometry(g geom.T, precision float64) (geom.T, []string, error) {
+	fixes := make(map[string]struct{})
+
+	var polygons []*geom.Polygon
+	switch rg := g.(type) {
+	case *geom.Polygon:
+		polygons = []*geom.Polygon{rg}
+	case *geom.MultiPolygon:
+		for i := 0; i < rg.NumPolygons(); i++ {
+			polygons = append(polygons, rg.Polygon(i))
+		}
+	default:
+		return g, nil, nil
+	}
+
+	var repaired [][]ring
+	for _, p := range polygons {
+		rps, err := repairPolygon(p, precision, fixes)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(rps) == 0 {
+			fixes[fixEmptyPolygon] = struct{}{}
+		}
+		repaired = append(repaired, rps...)
+	}
+
+	if len(fixes) == 0 {
+		return g, nil, nil
+	}
+	if len(repaired) == 0 {
+		return nil, nil, errNoPolygon
+	}
+
+	list := make([]string, 0, len(fixes))
+	for fix := range fixes {
+		list = append(list, fix)
+	}
+	sort.Strings(list)
+
+	if _, ok := g.(*geom.Polygon); ok && len(repaired) == 1 {
+		return polygonFromRings(repaired[0]), list, nil
+	}
+	mp := geom.NewMultiPolygon(geom.XY)
+	for _, rs := range repaired {
+		if err := mp.Push(polygonFromRings(rs)); err != nil {
+			return nil, nil, err
+		}
+	}
+	return mp, list, nil
+}
+
+// OrientGeometry returns g with its exterior rings counterclockwise and its holes clockwise on the sphere
+// and the count of rings reversed, g itself when none was, without the other fixes of RepairGeometry.
+// Geometries other than polygons are returned as is.
+func OrientGeometry(g geom.T) (geom.T, int) {
+	var endss [][]int
+	switch rg := g.(type) {
+	case *geom.Polygon:
+		endss = [][]int{rg.Ends()}
+	case *geom.MultiPolygon:
+		endss = rg.Endss()
+	default:
+		return g, 0
+	}
+
+	stride := g.Stride()
+	var flat []float64
+	reversed := 0
+	offset := 0
+	for _, ends := range endss {
+		for ri, end := range ends {
+			c := g.FlatCoords()[offset:end]
+			offset = end
+			if (ri == 0 && !ringClockwise(c, stride)) || (ri > 0 && !ringCounterclockwise(c, stride)) {
+				continue
+			}
+			if flat == nil {
+				flat = append([]float64(nil), g.FlatCoords()...)
+			}
+			rc := flat[end-len(c) : end]
+			for i, j := 0, len(rc)-stride; i < j; i, j = i+stride, j-stride {
+				for k := 0; k < stride; k++ {
+					rc[i+k], rc[j+k] = rc[j+k], rc[i+k]
+				}
+			}
+			reversed++
+		}
+	}
+	if reversed == 0 {
+		return g, 0
+	}
+
+	if _, ok := g.(*geom.Polygon); ok {
+		return geom.NewPolygonFlat(g.Layout(), flat, endss[0]), reversed
+	}
+	return geom.NewMultiPolygonFlat(g.Layout(), flat, endss), reversed
+}
+
+// ringClockwise returns true if the ring c with stride coordinates per point is a clockwise loop,
+// false for a degenerate ring
+func ringClockwise(c []float64, stride int) bool {
+	points := ringPoints(c, stride)
+	return len(points) >= 3 && clockwise(points)
+}
+
+// ringCounterclockwise returns true if the ring c with stride coordinates per point is a counterclockwise loop,
+// false for a degenerate ring
+func ringCounterclockwise(c []float64, stride int) bool {
+	points := ringPoints(c, stride)
+	return len(points) >= 3 && !clockwise(points)
+}
+
+// ringPoints returns the distinct points of the ring c with stride coordinates per point, see loopPoints
+func ringPoints(c []float64, stride int) []s2.Point {
+	points := make([]s2.Point, 0, len(c)/stride)
+	for i := 0; i+1 < len(c); i += stride {
+		points = append(points, s2.PointFromLatLng(s2.LatLngFromDegrees(c[i+1], c[i])))
+	}
+	return loopPoints(points)
+}
+
+// repairPolygon returns the polygons, exterior ring first, resulting of the repair of p
+func repairPolygon(p *geom.Polygon, precision float64, fixes map[string]struct{}) ([][]ring, error) {
+	if p.Stride() != 2 {
+		fixes[fixDimensions] = struct{}{}
+	}
+
+	var exteriors [][]ring
+	var holes []ring
+	for ri := 0; ri < p.NumLinearRings(); ri++ {
+		rs, err := repairRing(p.LinearRing(ri).FlatCoords(), p.Stride(), precision, ri == 0, fixes)
+		if err != nil {
+			return nil, err
+		}
+		if ri == 0 {
+			for _, r := range rs {
+				exteriors = append(exteriors, []ring{r})
+			}
+			continue
+		}
+		holes = append(holes, rs...)
+	}
+
+	// assign the holes to the exterior containing them
+	for _, h := range holes {
+		found := false
+		for i := range exteriors {
+			if ringContains(exteriors[i][0], h[0]) {
+				exteriors[i] = append(exteriors[i], h)
+				found = true
+				break
+			}
+		}
+		if !found {
+			fixes[fixOrphanHole] = struct{}{}
+		}
+	}
+
+	return exteriors, nil
+}
+
+// repairRing returns the simple rings resulting of the repair of the flat coordinates c
+func repairRing(c []float64, stride int, precision float64, exterior bool, fixes map[string]struct{}) ([]ring, error) {
+	n := len(c) / stride
+	r := make(ring, 0, n)
+	for i := 0; i < n; i++ {
+		pt := [2]float64{c[i*stride], c[i*stride+1]}
+		if precision > 0 {
+			s := [2]float64{snap(pt[0], precision), snap(pt[1], precision)}
+			if s != pt {
+				fixes[fixSnapped] = struct{}{}
+			}
+			pt = s
+		}
+		// a point has a single coordinates, -180 and 180 being the same longitude it isn't a fix
+		if n := normalizeLngLat(pt); n != pt {
+			if pt[0] != -180 || n[1] != pt[1] {
+				fixes[fixNormalized] = struct{}{}
+			}
+			pt = n
+		}
+		r = append(r, pt)
+	}
+
+	if len(r) > 1 && r[0] == r[len(r)-1] {
+		r = r[:len(r)-1]
+	} else if len(r) > 1 {
+		fixes[fixClosed] = struct{}{}
+	}
+
+	r = removeDuplicates(r, fixes)
+
+	var simple []ring
+	work := []ring{r}
+	for splits := 0; len(work) > 0; splits++ {
+		if splits > maxRingSplits {
+			return nil, errTooManySplits
+		}
+		r := work[len(work)-1]
+		work = work[:len(work)-1]
+
+		if len(r) < 3 {
+			fixes[fixDegenerate] = struct{}{}
+			continue
+		}
+
+		a, b, ok := splitRing(r)
+		if !ok {
+			if ringArea(r) <= precision*precision {
+				fixes[fixDegenerate] = struct{}{}
+				continue
+			}
+			simple = append(simple, r)
+			continue
+		}
+		fixes[fixSelfIntersect] = struct{}{}
+		work = append(work, removeDuplicates(a, fixes), removeDuplicates(b, fixes))
+	}
+
+	for _, r := range simple {
+		if cw := clockwise(r.points()); cw == exterior {
+			fixes[fixReoriented] = struct{}{}
+			for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+				r[i], r[j] = r[j], r[i]
+			}
+		}
+	}
+
+	return simple, nil
+}
+
+func snap(v, precision float64) float64 {
+	return math.Round(v/precision) * precision
+}
+
+// removeDuplicates removes the consecutive duplicate points of r, the last point being followed by the first
+func removeDuplicates(r ring, fixes map[string]struct{}) ring {
+	out := r[:0]
+	for i, pt := range r {
+		if i > 0 && pt == out[len(out)-1] {
+			fixes[fixDuplicates] = struct{}{}
+			continue
+		}
+		out = append(out, pt)
+	}
+	for len(out) > 1 && out[0] == out[len(out)-1] {
+		fixes[fixDuplicates] = struct{}{}
+		out = out[:len(out)-1]
+	}
+	return out
+}
+
+// splitRing splits r at its first self-intersection into two rings, returns false when r is simple
+func splitRing(r ring) (ring, ring, bool) {
+	// touching at a vertex
+	seen := make(map[[2]float64]int, len(r))
+	for j, pt := range r {
+		if i, ok := seen[pt]; ok {
+			a := append(append(ring{}, r[:i]...), r[j:]...)
+			b := append(ring{}, r[i:j]...)
+			return a, b, true
+		}
+		seen[pt] = j
+	}
+
+	points := r.points()
+	i, j, ok := selfIntersection(points)
+	if !ok {
+		return nil, nil, false
+	}
+	if i > j {
+		i, j = j, i
+	}
+
+	ll := s2.LatLngFromPoint(s2.Intersection(points[i], points[(i+1)%len(r)], points[j], points[(j+1)%len(r)]))
+	x := [2]float64{ll.Lng.Degrees(), ll.Lat.Degrees()}
+
+	// r[0..i] x r[j+1..] and x r[i+1..j]
+	a := append(append(append(ring{}, r[:i+1]...), x), r[j+1:]...)
+	b := append(ring{x}, r[i+1:j+1]...)
+	return a, b, true
+}
+
+// ringArea returns the area in square degrees of the smaller of the two regions bounded by r on the sphere
+func ringArea(r ring) float64 {
+	a := s2.LoopFromPoints(r.points()).Area()
+	a = math.Min(a, 4*math.Pi-a)
+	return a * (180 / math.Pi) * (180 / math.Pi)
+}
+
+// ringContains returns true if pt is inside the counterclockwise ring r
+func ringContains(r ring, pt [2]float64) bool {
+	return s2.LoopFromPoints(r.points()).ContainsPoint(s2.PointFromLatLng(s2.LatLngFromDegrees(pt[1], pt[0])))
+}
+
+// points returns the s2 points of r
+func (r ring) points() []s2.Point {
+	points := make([]s2.Point, len(r))
+	for i, pt := range r {
+		points[i] = s2.PointFromLatLng(s2.LatLngFromDegrees(pt[1], pt[0]))
+	}
+	return points
+}
+
+// normalizeLngLat returns pt with its longitude in (-180, 180], 0 at the poles
+func normalizeLngLat(pt [2]float64) [2]float64 {
+	if pt[1] == 90 || pt[1] == -90 {
+		return [2]float64{0, pt[1]}
+	}
+	lng := pt[0]
+	if lng <= -180 || lng > 180 {
+		lng -= 360 * math.Ceil((lng-180)/360)
+	}
+	return [2]float64{lng, pt[1]}
+}
+
+// polygonFromRings returns an XY polygon from closed versions of rs
+func polygonFromRings(rs []ring) *geom.Polygon {
+	var flat []float64
+	var ends []int
+	for _, r := range rs {
+		for _, pt := range r {
+			flat = append(flat, pt[0], pt[1])
+		}
+		flat = append(flat, r[0][0], r[0][1])
+		ends = append(ends, len(flat))
+	}
+	return geom.NewPolygonFlat(geom.XY, flat, ends)
+}
@@ -0,0 +1,108 @@
+package insideout
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom"
+)
+
+func TestRepairGeometry(t *testing.T) {
+	square := []float64{2, 48, 3, 48, 3, 49, 2, 49, 2, 48}
+
+	tests := []struct {
+		name      string
+		g         geom.T
+		precision float64
+		fixes     []string
+		polygons  int
+	}{
+		{"valid", geom.NewPolygonFlat(geom.XY, square, []int{10}), 1e-7, nil, 1},
+		{
+			"clockwise",
+			geom.NewPolygonFlat(geom.XY, []float64{2, 48, 2, 49, 3, 49, 3, 48, 2, 48}, []int{10}),
+			1e-7, []string{fixReoriented}, 1,
+		},
+		{
+			"bowtie",
+			geom.NewPolygonFlat(geom.XY, []float64{2, 48, 3, 49, 3, 48, 2, 49, 2, 48}, []int{10}),
+			1e-7, []string{fixReoriented, fixSelfIntersect}, 2,
+		},
+		{
+			"not closed with duplicates",
+			geom.NewPolygonFlat(geom.XY, []float64{2, 48, 3, 48, 3, 48, 3, 49, 2, 49}, []int{10}),
+			0, []string{fixClosed, fixDuplicates}, 1,
+		},
+		{
+			"touching itself",
+			geom.NewPolygonFlat(geom.XY, []float64{2, 48, 3, 48, 4, 48, 4, 49, 3, 48, 2, 49, 2, 48}, []int{14}),
+			0, []string{fixSelfIntersect}, 2,
+		},
+		{
+			"snapped",
+			geom.NewPolygonFlat(geom.XY, []float64{2.00000001, 48, 3, 48, 3, 49, 2, 49, 2.00000001, 48}, []int{10}),
+			1e-7, []string{fixSnapped}, 1,
+		},
+		{
+			"dimensions",
+			geom.NewPolygonFlat(geom.XYZ, []float64{2, 48, 0, 3, 48, 0, 3, 49, 0, 2, 49, 0, 2, 48, 0}, []int{15}),
+			1e-7, []string{fixDimensions}, 1,
+		},
+		{
+			"hole outside",
+			geom.NewPolygonFlat(geom.XY, append(square, 5, 5, 5, 6, 6, 6, 6, 5, 5, 5), []int{10, 20}),
+			1e-7, []string{fixOrphanHole}, 1,
+		},
+		{
+			"multipolygon with a degenerate polygon",
+			geom.NewMultiPolygonFlat(geom.XY, append(square, 4, 48, 5, 48, 4, 48, 4, 48), [][]int{{10}, {18}}),
+			1e-7, []string{fixDegenerate, fixDuplicates, fixEmptyPolygon}, 1,
+		},
+		{"point", geom.NewPointFlat(geom.XY, []float64{2, 48}), 1e-7, nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, fixes, err := RepairGeometry(tt.g, tt.precision)
+			require.NoError(t, err)
+			require.Equal(t, tt.fixes, fixes)
+			if len(tt.fixes) == 0 {
+				require.Equal(t, tt.g, g)
+				return
+			}
+			require.Empty(t, ValidateGeometry(g))
+			switch rg := g.(type) {
+			case *geom.Polygon:
+				require.Equal(t, 1, tt.polygons)
+			case *geom.MultiPolygon:
+				require.Equal(t, tt.polygons, rg.NumPolygons())
+			}
+		})
+	}
+
+	// nothing left
+	_, _, err := RepairGeometry(geom.NewPolygonFlat(geom.XY, []float64{2, 48, 3, 48, 2, 48, 2, 48}, []int{8}), 1e-7)
+	require.Error(t, err)
+}
+
+func TestOrientGeometry(t *testing.T) {
+	square := []float64{2, 48, 3, 48, 3, 49, 2, 49, 2, 48}
+	hole := []float64{2.2, 48.2, 2.2, 48.8, 2.8, 48.8, 2.8, 48.2, 2.2, 48.2}
+
+	// valid, returned as is
+	g := geom.NewPolygonFlat(geom.XY, append(square, hole...), []int{10, 20})
+	og, reversed := OrientGeometry(g)
+	require.Equal(t, 0, reversed)
+	require.True(t, og == geom.T(g))
+
+	// clockwise exterior ring and counterclockwise hole
+	cw := []float64{2, 48, 2, 49, 3, 49, 3, 48, 2, 48}
+	ccw := []float64{2.2, 48.2, 2.8, 48.2, 2.8, 48.8, 2.2, 48.8, 2.2, 48.2}
+	mp := geom.NewMultiPolygonFlat(geom.XY, append(append(cw, ccw...), square...), [][]int{{10, 20}, {30}})
+	og, reversed = OrientGeometry(mp)
+	require.Equal(t, 2, reversed)
+	require.Empty(t, ValidateGeometry(og))
+	require.Equal(t, 2, og.(*geom.MultiPolygon).NumPolygons())
+	// the original is untouched
+	require.Equal(t, cw, mp.FlatCoords()[:10])
+}
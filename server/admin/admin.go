@@ -0,0 +1,240 @@
+// Package admin serves the AdminService changing the settings of a running server
+package admin
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/akhenakh/insideout/insidesvc"
+	"github.com/akhenakh/insideout/loglevel"
+	"github.com/akhenakh/insideout/server"
+	"github.com/akhenakh/insideout/storage/versions"
+)
+
+// Versioner serves the versions of the datasets served from a versions directory
+type Versioner interface {
+	// Layout returns the name and the versions directory of the dataset, the default one when empty
+	Layout(dataset string) (string, *versions.Layout, error)
+	// Serve replaces the DB served by the dataset name by the DB at path
+	Serve(name, path string) error
+}
+
+// Admin implements insidesvc.AdminServiceServer
+type Admin struct {
+	server    *server.Server
+	level     *loglevel.Dynamic
+	status    *Status
+	versioner Versioner
+	logger    log.Logger
+
+	// versionsMu serializes the promotes and rollbacks
+	versionsMu sync.Mutex
+}
+
+// New returns an Admin changing the settings of s, the level of the logger filtered by lvl,
+// the serving status st and the versions served by v, nil when the datasets are not versioned
+func New(s *server.Server, lvl *loglevel.Dynamic, st *Status, v Versioner, logger log.Logger) *Admin {
+	return &Admin{
+		server:    s,
+		level:     lvl,
+		status:    st,
+		versioner: v,
+		logger:    log.With(logger, "component", "admin"),
+	}
+}
+
+// Status returns the current settings
+func (a *Admin) Status(ctx context.Context, req *insidesvc.StatusRequest) (*insidesvc.AdminStatus, error) {
+	return a.adminStatus(), nil
+}
+
+// SetLogLevel changes the log level
+func (a *Admin) SetLogLevel(ctx context.Context, req *insidesvc.SetLogLevelRequest) (*insidesvc.AdminStatus, error) {
+	if err := a.level.SetLevel(req.Level); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	level.Warn(a.logger).Log("msg", "log level changed", "log_level", a.level.Level())
+	return a.adminStatus(), nil
+}
+
+// SetStopOnFirstFound changes StopOnFirstFound of all the datasets
+func (a *Admin) SetStopOnFirstFound(ctx context.Context, req *insidesvc.SetStopOnFirstFoundRequest) (
+	*insidesvc.AdminStatus, error) {
+	a.server.SetStopOnFirstFound(req.StopOnFirstFound)
+	level.Warn(a.logger).Log("msg", "stopOnFirstFound changed", "stop_on_first_found", req.StopOnFirstFound)
+	return a.adminStatus(), nil
+}
+
+// ResizeCache replaces the features or results caches by empty ones of the requested size
+func (a *Admin) ResizeCache(ctx context.Context, req *insidesvc.ResizeCacheRequest) (*insidesvc.AdminStatus, error) {
+	var err error
+	switch req.Cache {
+	case insidesvc.ResizeCacheRequest_FEATURES:
+		err = a.server.ResizeCache(int(req.Count))
+	case insidesvc.ResizeCacheRequest_RESULTS:
+		err = a.server.ResizeResultCache(int(req.Count))
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "unknown cache %d", req.Cache)
+	}
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	level.Warn(a.logger).Log("msg", "cache resized", "cache", req.Cache.String(), "count", req.Count)
+	return a.adminStatus(), nil
+}
+
+// Drain reports the server as not serving
+func (a *Admin) Drain(ctx context.Context, req *insidesvc.DrainRequest) (*insidesvc.AdminStatus, error) {
+	a.status.SetDraining(true)
+	level.Warn(a.logger).Log("msg", "draining, serving status to NOT_SERVING")
+	return a.adminStatus(), nil
+}
+
+// Undrain reports the server as serving, if its databases are available
+func (a *Admin) Undrain(ctx context.Context, req *insidesvc.DrainRequest) (*insidesvc.AdminStatus, error) {
+	a.status.SetDraining(false)
+	level.Warn(a.logger).Log("msg", "undrained", "serving", a.status.Serving())
+	return a.adminStatus(), nil
+}
+
+// ListVersions returns the versions of a dataset
+func (a *Admin) ListVersions(ctx context.Context, req *insidesvc.VersionsRequest) (*insidesvc.VersionsResponse, error) {
+	a.versionsMu.Lock()
+	defer a.versionsMu.Unlock()
+
+	name, l, err := a.layout(req.Dataset)
+	if err != nil {
+		return nil, err
+	}
+	return a.versionsResponse(name, l)
+}
+
+// PromoteVersion serves a version of a dataset, the state of the versions directory is only updated
+// once the version is served
+func (a *Admin) PromoteVersion(ctx context.Context, req *insidesvc.PromoteVersionRequest) (
+	*insidesvc.VersionsResponse, error) {
+	a.versionsMu.Lock()
+	defer a.versionsMu.Unlock()
+
+	name, l, err := a.layout(req.Dataset)
+	if err != nil {
+		return nil, err
+	}
+	v, err := l.Version(req.Version)
+	if err != nil {
+		return nil, versionsError(err)
+	}
+	if err := a.serve(name, l, v, l.Promote); err != nil {
+		return nil, err
+	}
+	level.Warn(a.logger).Log("msg", "version promoted", "dataset", name, "version", v.Name)
+	return a.versionsResponse(name, l)
+}
+
+// RollbackVersion serves again the version of a dataset served before the current one
+func (a *Admin) RollbackVersion(ctx context.Context, req *insidesvc.VersionsRequest) (
+	*insidesvc.VersionsResponse, error) {
+	a.versionsMu.Lock()
+	defer a.versionsMu.Unlock()
+
+	name, l, err := a.layout(req.Dataset)
+	if err != nil {
+		return nil, err
+	}
+	v, err := l.Previous()
+	if err != nil {
+		return nil, versionsError(err)
+	}
+	rollback := func(string) error {
+		_, err := l.Rollback()
+		return err
+	}
+	if err := a.serve(name, l, v, rollback); err != nil {
+		return nil, err
+	}
+	level.Warn(a.logger).Log("msg", "version rolled back", "dataset", name, "version", v.Name)
+	return a.versionsResponse(name, l)
+}
+
+// serve serves v then records it in the state of l with update, the current version is served again
+// when the state can't be updated, a.versionsMu must be held
+func (a *Admin) serve(name string, l *versions.Layout, v versions.Version, update func(string) error) error {
+	// the current version may have been removed
+	current, cerr := l.Current()
+	if err := a.versioner.Serve(name, v.Path); err != nil {
+		return status.Errorf(codes.FailedPrecondition, "can't serve version %s: %v", v.Name, err)
+	}
+	if err := update(v.Name); err != nil {
+		if cerr != nil {
+			return status.Errorf(codes.Internal, "can't update the versions state: %v", err)
+		}
+		if rerr := a.versioner.Serve(name, current.Path); rerr != nil {
+			level.Error(a.logger).Log("msg", "failed to serve the current version again", "error", rerr,
+				"dataset", name, "version", current.Name)
+		}
+		return status.Errorf(codes.Internal, "can't update the versions state: %v", err)
+	}
+	return nil
+}
+
+func (a *Admin) layout(dataset string) (string, *versions.Layout, error) {
+	if a.versioner == nil {
+		return "", nil, status.Error(codes.Unimplemented, "the datasets are not versioned")
+	}
+	name, l, err := a.versioner.Layout(dataset)
+	if err != nil {
+		return "", nil, status.Error(codes.NotFound, err.Error())
+	}
+	return name, l, nil
+}
+
+func (a *Admin) versionsResponse(name string, l *versions.Layout) (*insidesvc.VersionsResponse, error) {
+	vs, err := l.List()
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	resp := &insidesvc.VersionsResponse{Dataset: name}
+	for _, v := range vs {
+		if v.Current {
+			resp.Current = v.Name
+		}
+		resp.Versions = append(resp.Versions, &insidesvc.DatasetVersion{
+			Name:    v.Name,
+			Size:    v.Size,
+			ModTime: v.ModTime.UnixNano() / 1e6,
+			Current: v.Current,
+		})
+	}
+	if prev, err := l.Previous(); err == nil {
+		resp.Previous = prev.Name
+	}
+	return resp, nil
+}
+
+// versionsError returns the status of a versions error
+func versionsError(err error) error {
+	switch {
+	case errors.Is(err, versions.ErrUnknownVersion):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, versions.ErrNoPrevious):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+func (a *Admin) adminStatus() *insidesvc.AdminStatus {
+	settings := a.server.Settings()
+	return &insidesvc.AdminStatus{
+		LogLevel:         a.level.Level(),
+		StopOnFirstFound: settings.StopOnFirstFound,
+		CacheCount:       int32(settings.CacheCount),
+		ResultCacheCount: int32(settings.ResultCacheCount),
+		Draining:         a.status.Draining(),
+	}
+}
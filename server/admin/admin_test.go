@@ -0,0 +1,311 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/geojson"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/insidesvc"
+	"github.com/akhenakh/insideout/loglevel"
+	"github.com/akhenakh/insideout/server"
+	"github.com/akhenakh/insideout/storage/bbolt"
+	"github.com/akhenakh/insideout/storage/versions"
+)
+
+const service = "grpc.health.v1.test"
+
+func TestStatus(t *testing.T) {
+	hs := health.NewServer()
+	st := NewStatus(hs, service)
+
+	check := func() healthpb.HealthCheckResponse_ServingStatus {
+		resp, err := hs.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+		require.NoError(t, err)
+		return resp.Status
+	}
+	require.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, check())
+
+	st.SetAvailable(true)
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, check())
+
+	st.SetDraining(true)
+	require.True(t, st.Draining())
+	require.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, check())
+
+	// back from a database failure while draining
+	st.SetAvailable(false)
+	st.SetAvailable(true)
+	require.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, check())
+
+	st.SetDraining(false)
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, check())
+
+	st.SetAvailable(false)
+	require.False(t, st.Serving())
+	require.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, check())
+}
+
+func TestStatus_Probes(t *testing.T) {
+	hs := health.NewServer()
+	st := NewStatus(hs, service)
+
+	probe := func(h http.HandlerFunc) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		h(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return w.Code, body
+	}
+
+	// loading the databases
+	code, body := probe(st.ReadyHandler)
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, false, body["available"])
+	code, _ = probe(LiveHandler)
+	require.Equal(t, http.StatusOK, code)
+
+	st.SetAvailable(true)
+	code, body = probe(st.ReadyHandler)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "SERVING", body["status"])
+
+	st.SetDraining(true)
+	code, body = probe(st.ReadyHandler)
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, true, body["draining"])
+	st.SetDraining(false)
+
+	// not undrained once shutting down
+	st.Shutdown()
+	st.SetDraining(false)
+	st.SetAvailable(true)
+	require.False(t, st.Serving())
+	code, body = probe(st.ReadyHandler)
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, true, body["shutting_down"])
+	code, _ = probe(LiveHandler)
+	require.Equal(t, http.StatusOK, code)
+
+	resp, err := hs.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+	require.NoError(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, resp.Status)
+}
+
+func TestAdmin(t *testing.T) {
+	storage, clean := setup(t)
+	defer clean()
+
+	logger := log.NewNopLogger()
+	hs := health.NewServer()
+	s, err := server.New(storage, logger, hs, server.Options{
+		Strategy:         insideout.DBStrategy,
+		CacheCount:       10,
+		ResultCacheLevel: 10,
+		ResultCacheCount: 10,
+	})
+	require.NoError(t, err)
+
+	lvl, err := loglevel.NewDynamic(logger, "INFO")
+	require.NoError(t, err)
+	st := NewStatus(hs, service)
+	st.SetAvailable(true)
+
+	a := New(s, lvl, st, nil, logger)
+	ctx := context.Background()
+
+	resp, err := a.Status(ctx, &insidesvc.StatusRequest{})
+	require.NoError(t, err)
+	require.Equal(t, "INFO", resp.LogLevel)
+	require.EqualValues(t, 10, resp.CacheCount)
+	require.EqualValues(t, 10, resp.ResultCacheCount)
+
+	resp, err = a.SetLogLevel(ctx, &insidesvc.SetLogLevelRequest{Level: "debug"})
+	require.NoError(t, err)
+	require.Equal(t, "DEBUG", resp.LogLevel)
+	_, err = a.SetLogLevel(ctx, &insidesvc.SetLogLevelRequest{Level: "verbose"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	resp, err = a.SetStopOnFirstFound(ctx, &insidesvc.SetStopOnFirstFoundRequest{StopOnFirstFound: true})
+	require.NoError(t, err)
+	require.True(t, resp.StopOnFirstFound)
+
+	resp, err = a.ResizeCache(ctx, &insidesvc.ResizeCacheRequest{Cache: insidesvc.ResizeCacheRequest_FEATURES, Count: 100})
+	require.NoError(t, err)
+	require.EqualValues(t, 100, resp.CacheCount)
+	resp, err = a.ResizeCache(ctx, &insidesvc.ResizeCacheRequest{Cache: insidesvc.ResizeCacheRequest_RESULTS, Count: 0})
+	require.NoError(t, err)
+	require.EqualValues(t, 0, resp.ResultCacheCount)
+	_, err = a.ResizeCache(ctx, &insidesvc.ResizeCacheRequest{Cache: insidesvc.ResizeCacheRequest_FEATURES, Count: -1})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	resp, err = a.Drain(ctx, &insidesvc.DrainRequest{})
+	require.NoError(t, err)
+	require.True(t, resp.Draining)
+	require.False(t, st.Serving())
+
+	resp, err = a.Undrain(ctx, &insidesvc.DrainRequest{})
+	require.NoError(t, err)
+	require.False(t, resp.Draining)
+	require.True(t, st.Serving())
+
+	wresp, err := s.Within(ctx, &insidesvc.WithinRequest{Lat: 0.5, Lng: 0.5})
+	require.NoError(t, err)
+	require.Len(t, wresp.Responses, 1)
+}
+
+func setup(t *testing.T) (insideout.Store, func()) {
+	tmpFile, err := ioutil.TempFile(os.TempDir(), "insideout-test-")
+	require.NoError(t, err)
+	tmpFile.Close()
+
+	indexDB(t, tmpFile.Name(), "A")
+	storage, sclose, err := bbolt.NewROStorage(tmpFile.Name(), log.NewNopLogger())
+	require.NoError(t, err)
+
+	return storage, func() {
+		sclose()
+		os.Remove(tmpFile.Name())
+	}
+}
+
+// indexDB writes a DB of a square feature named name at path
+func indexDB(t *testing.T, path, name string) {
+	wstorage, wclose, err := bbolt.NewStorage(path, log.NewNopLogger())
+	require.NoError(t, err)
+
+	fc := geojson.FeatureCollection{Features: []*geojson.Feature{{
+		Geometry:   geom.NewPolygonFlat(geom.XY, []float64{0, 0, 1, 0, 1, 1, 0, 1, 0, 0}, []int{10}),
+		Properties: map[string]interface{}{"name": name},
+	}}}
+
+	icoverer := &s2.RegionCoverer{MinLevel: 5, MaxLevel: 12, MaxCells: 24}
+	ocoverer := &s2.RegionCoverer{MinLevel: 5, MaxLevel: 12, MaxCells: 16}
+	require.NoError(t, wstorage.Index(fc, icoverer, ocoverer, 100, name, "unittest"))
+	require.NoError(t, wclose())
+}
+
+// testVersioner serves the versions of a single dataset with a bbolt storage
+type testVersioner struct {
+	s      *server.Server
+	l      *versions.Layout
+	clean  func() error
+	failOn string
+}
+
+func (v *testVersioner) Layout(dataset string) (string, *versions.Layout, error) {
+	if dataset != "" && dataset != "countries" {
+		return "", nil, errors.New("unknown dataset")
+	}
+	return "countries", v.l, nil
+}
+
+func (v *testVersioner) Serve(name, path string) error {
+	if path == v.failOn {
+		return errors.New("corrupted DB")
+	}
+	storage, clean, err := bbolt.NewROStorage(path, log.NewNopLogger())
+	if err != nil {
+		return err
+	}
+	if _, err := v.s.ReloadDataset(name, storage); err != nil {
+		clean()
+		return err
+	}
+	v.clean()
+	v.clean = clean
+	return nil
+}
+
+func TestAdmin_Versions(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "insideout-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, os.Mkdir(filepath.Join(dir, versions.Subdir), 0755))
+	for _, name := range []string{"2020-05", "2020-06", "2020-07"} {
+		indexDB(t, filepath.Join(dir, versions.Subdir, name+".db"), name)
+	}
+	l, err := versions.Open(dir)
+	require.NoError(t, err)
+	current, err := l.Current()
+	require.NoError(t, err)
+
+	logger := log.NewNopLogger()
+	storage, clean, err := bbolt.NewROStorage(current.Path, logger)
+	require.NoError(t, err)
+	s, err := server.New(storage, logger, nil, server.Options{Strategy: insideout.DBStrategy, DatasetName: "countries"})
+	require.NoError(t, err)
+	v := &testVersioner{s: s, l: l, clean: clean}
+	defer func() { v.clean() }()
+
+	lvl, err := loglevel.NewDynamic(logger, "INFO")
+	require.NoError(t, err)
+	a := New(s, lvl, NewStatus(health.NewServer(), service), v, logger)
+	ctx := context.Background()
+
+	served := func() string {
+		resp, err := s.Within(ctx, &insidesvc.WithinRequest{Lat: 0.5, Lng: 0.5})
+		require.NoError(t, err)
+		require.Len(t, resp.Responses, 1)
+		return resp.Responses[0].Feature.Properties["name"].GetStringValue()
+	}
+
+	resp, err := a.ListVersions(ctx, &insidesvc.VersionsRequest{})
+	require.NoError(t, err)
+	require.Equal(t, "2020-07.db", resp.Current)
+	require.Empty(t, resp.Previous)
+	require.Len(t, resp.Versions, 3)
+	require.True(t, resp.Versions[2].Current)
+	require.Equal(t, "2020-07", served())
+
+	_, err = a.RollbackVersion(ctx, &insidesvc.VersionsRequest{})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	resp, err = a.PromoteVersion(ctx, &insidesvc.PromoteVersionRequest{Version: "2020-05.db"})
+	require.NoError(t, err)
+	require.Equal(t, "2020-05.db", resp.Current)
+	require.Equal(t, "2020-07.db", resp.Previous)
+	require.Equal(t, "2020-05", served())
+
+	resp, err = a.PromoteVersion(ctx, &insidesvc.PromoteVersionRequest{Dataset: "countries", Version: "2020-06.db"})
+	require.NoError(t, err)
+	require.Equal(t, "2020-06", served())
+
+	// a version failing to load leaves the current one served
+	v.failOn = filepath.Join(dir, versions.Subdir, "2020-07.db")
+	_, err = a.PromoteVersion(ctx, &insidesvc.PromoteVersionRequest{Version: "2020-07.db"})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	require.Equal(t, "2020-06", served())
+	v.failOn = ""
+
+	resp, err = a.RollbackVersion(ctx, &insidesvc.VersionsRequest{})
+	require.NoError(t, err)
+	require.Equal(t, "2020-05.db", resp.Current)
+	require.Equal(t, "2020-07.db", resp.Previous)
+	require.Equal(t, "2020-05", served())
+
+	_, err = a.PromoteVersion(ctx, &insidesvc.PromoteVersionRequest{Version: "2020-08.db"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+	_, err = a.ListVersions(ctx, &insidesvc.VersionsRequest{Dataset: "cities"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	na := New(s, lvl, NewStatus(health.NewServer(), service), nil, logger)
+	_, err = na.ListVersions(ctx, &insidesvc.VersionsRequest{})
+	require.Equal(t, codes.Unimplemented, status.Code(err))
+}
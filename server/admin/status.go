@@ -0,0 +1,116 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Status reports the serving status of a service to a health server,
+// the service is serving while its databases are available and it is not draining nor shutting down
+type Status struct {
+	health  *health.Server
+	service string
+
+	mu           sync.Mutex
+	available    bool
+	draining     bool
+	shuttingDown bool
+}
+
+// NewStatus returns a Status of service, not serving until SetAvailable(true) is called
+func NewStatus(hs *health.Server, service string) *Status {
+	st := &Status{health: hs, service: service}
+	st.update()
+	return st
+}
+
+// SetAvailable sets the availability of the databases
+func (st *Status) SetAvailable(available bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.available = available
+	st.update()
+}
+
+// SetDraining drains or undrains the service
+func (st *Status) SetDraining(draining bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.draining = draining
+	st.update()
+}
+
+// Draining returns true if the service is draining
+func (st *Status) Draining() bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.draining
+}
+
+// Shutdown reports the service as not serving for good, Undrain does not bring it back
+func (st *Status) Shutdown() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.shuttingDown = true
+	st.update()
+	st.health.Shutdown()
+}
+
+// Serving returns true if the service is reported as serving
+func (st *Status) Serving() bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.serving()
+}
+
+// serving returns true if the service is serving, st.mu must be held
+func (st *Status) serving() bool {
+	return st.available && !st.draining && !st.shuttingDown
+}
+
+// update sets the status of the health server, st.mu must be held
+func (st *Status) update() {
+	s := healthpb.HealthCheckResponse_NOT_SERVING
+	if st.serving() {
+		s = healthpb.HealthCheckResponse_SERVING
+	}
+	st.health.SetServingStatus(st.service, s)
+}
+
+// ReadyHandler serves the readiness probe, 200 once the databases are loaded and warmed up,
+// 503 while they are unavailable, the service is draining or shutting down
+func (st *Status) ReadyHandler(w http.ResponseWriter, r *http.Request) {
+	st.mu.Lock()
+	serving := st.serving()
+	resp := struct {
+		Status       string `json:"status"`
+		Available    bool   `json:"available"`
+		Draining     bool   `json:"draining"`
+		ShuttingDown bool   `json:"shutting_down"`
+	}{
+		Status:       healthpb.HealthCheckResponse_NOT_SERVING.String(),
+		Available:    st.available,
+		Draining:     st.draining,
+		ShuttingDown: st.shuttingDown,
+	}
+	st.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if serving {
+		resp.Status = healthpb.HealthCheckResponse_SERVING.String()
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// LiveHandler serves the liveness probe, 200 as long as the process answers,
+// whatever the databases or draining state so an orchestrator only restarts a stuck process
+func LiveHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte(`{"status": "ok"}`))
+}
@@ -0,0 +1,159 @@
+// Package bridge enriches the positions consumed from a message bus with the features containing them,
+// or turns them into geofence events, shared by the Kafka, MQTT and NATS integrations
+package bridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/insidesvc"
+	"github.com/akhenakh/insideout/server/geofence"
+)
+
+const (
+	// EnrichMode produces a Result per position
+	EnrichMode = "enrich"
+	// GeofenceMode produces the geofence events of each position
+	GeofenceMode = "geofence"
+)
+
+// Querier queries the features containing a position, implemented by server.Server
+type Querier interface {
+	Within(context.Context, *insidesvc.WithinRequest) (*insidesvc.WithinResponse, error)
+	Geofence(context.Context, *insidesvc.TrackRequest) ([]geofence.Event, error)
+}
+
+// Position a position message of a tracked object
+type Position struct {
+	ID  string  `json:"id"`
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+	// Time as unix milliseconds, 0 for the reception time
+	Time int64 `json:"time,omitempty"`
+	// Dataset to query, empty for the default dataset
+	Dataset string `json:"dataset,omitempty"`
+}
+
+// Result a position enriched with the features containing it
+type Result struct {
+	Position
+	Features []Feature `json:"features"`
+}
+
+// Feature a feature containing a position
+type Feature struct {
+	ID         uint32                 `json:"fid"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// Message a message to produce, Key is the tracked object id to keep its messages ordered
+type Message struct {
+	Key   string
+	Value []byte
+}
+
+// Options for a Processor
+type Options struct {
+	// Mode enrich or geofence
+	Mode string
+
+	// Format of the messages json or avro
+	Format string
+
+	// SelectProperties comma separated list of properties to return, empty for all
+	SelectProperties string
+
+	// Filter comma separated list of conditions on properties key=value or key!=value
+	Filter string
+}
+
+// Processor turns position messages into result or event messages
+type Processor struct {
+	querier Querier
+	codec   Codec
+	opts    Options
+}
+
+// NewProcessor returns a Processor querying q
+func NewProcessor(q Querier, opts Options) (*Processor, error) {
+	if opts.Mode != EnrichMode && opts.Mode != GeofenceMode {
+		return nil, fmt.Errorf("unknown mode %s", opts.Mode)
+	}
+	codec, err := NewCodec(opts.Format)
+	if err != nil {
+		return nil, err
+	}
+	return &Processor{querier: q, codec: codec, opts: opts}, nil
+}
+
+// Process decodes the position msg and returns the messages to produce,
+// no message in geofence mode when the position triggered no event
+func (p *Processor) Process(ctx context.Context, msg []byte) ([]Message, error) {
+	return p.ProcessID(ctx, msg, "")
+}
+
+// ProcessID is Process using id for the positions without id,
+// for buses identifying the objects outside of the message
+func (p *Processor) ProcessID(ctx context.Context, msg []byte, id string) ([]Message, error) {
+	pos, err := p.codec.DecodePosition(msg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid position message: %w", err)
+	}
+	if pos.ID == "" {
+		pos.ID = id
+	}
+	if pos.ID == "" {
+		return nil, errors.New("invalid position message: missing id")
+	}
+
+	if p.opts.Mode == GeofenceMode {
+		events, err := p.querier.Geofence(ctx, &insidesvc.TrackRequest{
+			Id:               pos.ID,
+			Lat:              pos.Lat,
+			Lng:              pos.Lng,
+			Time:             pos.Time,
+			Dataset:          pos.Dataset,
+			SelectProperties: p.opts.SelectProperties,
+			Filter:           p.opts.Filter,
+		})
+		if err != nil {
+			return nil, err
+		}
+		msgs := make([]Message, len(events))
+		for i, e := range events {
+			b, err := p.codec.EncodeEvent(e)
+			if err != nil {
+				return nil, err
+			}
+			msgs[i] = Message{Key: pos.ID, Value: b}
+		}
+		return msgs, nil
+	}
+
+	resp, err := p.querier.Within(ctx, &insidesvc.WithinRequest{
+		Lat:              pos.Lat,
+		Lng:              pos.Lng,
+		RemoveGeometries: true,
+		SelectProperties: p.opts.SelectProperties,
+		Filter:           p.opts.Filter,
+		Dataset:          pos.Dataset,
+	})
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{Position: *pos, Features: make([]Feature, len(resp.Responses))}
+	if res.Time == 0 {
+		res.Time = time.Now().UnixNano() / int64(time.Millisecond)
+	}
+	for i, fr := range resp.Responses {
+		res.Features[i] = Feature{ID: fr.Id, Properties: insideout.ValueToProperties(fr.Feature.Properties)}
+	}
+	b, err := p.codec.EncodeResult(res)
+	if err != nil {
+		return nil, err
+	}
+	return []Message{{Key: pos.ID, Value: b}}, nil
+}
@@ -0,0 +1,111 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	structpb "github.com/golang/protobuf/ptypes/struct"
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/require"
+
+	"github.com/akhenakh/insideout/insidesvc"
+	"github.com/akhenakh/insideout/server/geofence"
+)
+
+type fakeQuerier struct{}
+
+func (fakeQuerier) Within(ctx context.Context, req *insidesvc.WithinRequest) (*insidesvc.WithinResponse, error) {
+	if req.Lat < 0 {
+		return &insidesvc.WithinResponse{}, nil
+	}
+	return &insidesvc.WithinResponse{Responses: []*insidesvc.FeatureResponse{{
+		Id: 3,
+		Feature: &insidesvc.Feature{Properties: map[string]*structpb.Value{
+			"name": {Kind: &structpb.Value_StringValue{StringValue: "A"}},
+		}},
+	}}}, nil
+}
+
+func (fakeQuerier) Geofence(ctx context.Context, req *insidesvc.TrackRequest) ([]geofence.Event, error) {
+	return []geofence.Event{{
+		Type:       geofence.Exit,
+		ObjectID:   req.Id,
+		FeatureID:  3,
+		Properties: map[string]interface{}{"name": "A", "level": 4.0},
+		Time:       time.Unix(0, req.Time*int64(time.Millisecond)),
+		Duration:   time.Second,
+	}}, nil
+}
+
+func TestProcessor_JSON(t *testing.T) {
+	p, err := NewProcessor(fakeQuerier{}, Options{Mode: EnrichMode, Format: JSONFormat})
+	require.NoError(t, err)
+
+	msgs, err := p.Process(context.Background(), []byte(`{"id": "truck", "lat": 1, "lng": 2, "time": 1000}`))
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	require.Equal(t, "truck", msgs[0].Key)
+
+	var res Result
+	require.NoError(t, json.Unmarshal(msgs[0].Value, &res))
+	require.Equal(t, int64(1000), res.Time)
+	require.Len(t, res.Features, 1)
+	require.Equal(t, uint32(3), res.Features[0].ID)
+	require.Equal(t, "A", res.Features[0].Properties["name"])
+
+	_, err = p.Process(context.Background(), []byte(`{"lat": 1, "lng": 2}`))
+	require.Error(t, err)
+	msgs, err = p.ProcessID(context.Background(), []byte(`{"lat": 1, "lng": 2}`), "device")
+	require.NoError(t, err)
+	require.Equal(t, "device", msgs[0].Key)
+	_, err = p.Process(context.Background(), []byte(`{`))
+	require.Error(t, err)
+
+	_, err = NewProcessor(fakeQuerier{}, Options{Mode: "unknown"})
+	require.Error(t, err)
+	_, err = NewProcessor(fakeQuerier{}, Options{Mode: EnrichMode, Format: "xml"})
+	require.Error(t, err)
+}
+
+func TestProcessor_Avro(t *testing.T) {
+	position, err := goavro.NewCodec(PositionSchema)
+	require.NoError(t, err)
+	msg, err := position.BinaryFromNative(nil, map[string]interface{}{
+		"id": "truck", "lat": 1.0, "lng": 2.0, "time": int64(1000), "dataset": "",
+	})
+	require.NoError(t, err)
+
+	p, err := NewProcessor(fakeQuerier{}, Options{Mode: EnrichMode, Format: AvroFormat})
+	require.NoError(t, err)
+	msgs, err := p.Process(context.Background(), msg)
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+
+	result, err := goavro.NewCodec(ResultSchema)
+	require.NoError(t, err)
+	native, _, err := result.NativeFromBinary(msgs[0].Value)
+	require.NoError(t, err)
+	res := native.(map[string]interface{})
+	require.Equal(t, "truck", res["id"])
+	features := res["features"].([]interface{})
+	require.Len(t, features, 1)
+	props := features[0].(map[string]interface{})["properties"].(map[string]interface{})
+	require.Equal(t, map[string]interface{}{"string": "A"}, props["name"])
+
+	p, err = NewProcessor(fakeQuerier{}, Options{Mode: GeofenceMode, Format: AvroFormat})
+	require.NoError(t, err)
+	msgs, err = p.Process(context.Background(), msg)
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+
+	event, err := goavro.NewCodec(EventSchema)
+	require.NoError(t, err)
+	native, _, err = event.NativeFromBinary(msgs[0].Value)
+	require.NoError(t, err)
+	e := native.(map[string]interface{})
+	require.Equal(t, "EXIT", e["type"])
+	require.Equal(t, int64(1000), e["time"])
+	require.Equal(t, int64(1000), e["duration"])
+}
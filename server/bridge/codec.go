@@ -0,0 +1,192 @@
+package bridge
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+
+	"github.com/akhenakh/insideout/server/geofence"
+)
+
+const (
+	// JSONFormat messages are JSON objects
+	JSONFormat = "json"
+	// AvroFormat messages are Avro binary records of PositionSchema, ResultSchema and EventSchema, without schema registry framing
+	AvroFormat = "avro"
+)
+
+// Codec decodes positions and encodes results and events
+type Codec interface {
+	DecodePosition([]byte) (*Position, error)
+	EncodeResult(*Result) ([]byte, error)
+	EncodeEvent(geofence.Event) ([]byte, error)
+}
+
+// NewCodec returns the Codec of format json or avro
+func NewCodec(format string) (Codec, error) {
+	switch format {
+	case JSONFormat, "":
+		return jsonCodec{}, nil
+	case AvroFormat:
+		return newAvroCodec()
+	}
+	return nil, fmt.Errorf("unknown format %s", format)
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) DecodePosition(b []byte) (*Position, error) {
+	var p Position
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (jsonCodec) EncodeResult(r *Result) ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func (jsonCodec) EncodeEvent(e geofence.Event) ([]byte, error) {
+	return json.Marshal(e)
+}
+
+const (
+	// PositionSchema the Avro schema of the positions
+	PositionSchema = `{
+  "type": "record", "name": "Position", "namespace": "insided",
+  "fields": [
+    {"name": "id", "type": "string"},
+    {"name": "lat", "type": "double"},
+    {"name": "lng", "type": "double"},
+    {"name": "time", "type": "long", "default": 0},
+    {"name": "dataset", "type": "string", "default": ""}
+  ]
+}`
+
+	// ResultSchema the Avro schema of the enriched positions
+	ResultSchema = `{
+  "type": "record", "name": "Result", "namespace": "insided",
+  "fields": [
+    {"name": "id", "type": "string"},
+    {"name": "lat", "type": "double"},
+    {"name": "lng", "type": "double"},
+    {"name": "time", "type": "long"},
+    {"name": "dataset", "type": "string"},
+    {"name": "features", "type": {"type": "array", "items": {
+      "type": "record", "name": "Feature",
+      "fields": [
+        {"name": "fid", "type": "long"},
+        {"name": "properties", "type": {"type": "map", "values": ["null", "boolean", "double", "string"]}}
+      ]
+    }}}
+  ]
+}`
+
+	// EventSchema the Avro schema of the geofence events, time and duration in milliseconds
+	EventSchema = `{
+  "type": "record", "name": "Event", "namespace": "insided",
+  "fields": [
+    {"name": "type", "type": {"type": "enum", "name": "EventType", "symbols": ["ENTER", "EXIT", "DWELL"]}},
+    {"name": "object_id", "type": "string"},
+    {"name": "dataset", "type": "string"},
+    {"name": "feature_id", "type": "long"},
+    {"name": "properties", "type": {"type": "map", "values": ["null", "boolean", "double", "string"]}},
+    {"name": "lat", "type": "double"},
+    {"name": "lng", "type": "double"},
+    {"name": "time", "type": "long"},
+    {"name": "duration", "type": "long"}
+  ]
+}`
+)
+
+type avroCodec struct {
+	position, result, event *goavro.Codec
+}
+
+func newAvroCodec() (*avroCodec, error) {
+	var c avroCodec
+	var err error
+	if c.position, err = goavro.NewCodec(PositionSchema); err != nil {
+		return nil, err
+	}
+	if c.result, err = goavro.NewCodec(ResultSchema); err != nil {
+		return nil, err
+	}
+	if c.event, err = goavro.NewCodec(EventSchema); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (c *avroCodec) DecodePosition(b []byte) (*Position, error) {
+	native, _, err := c.position.NativeFromBinary(b)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := native.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected Avro position %T", native)
+	}
+	p := &Position{}
+	p.ID, _ = m["id"].(string)
+	p.Lat, _ = m["lat"].(float64)
+	p.Lng, _ = m["lng"].(float64)
+	p.Time, _ = m["time"].(int64)
+	p.Dataset, _ = m["dataset"].(string)
+	return p, nil
+}
+
+func (c *avroCodec) EncodeResult(r *Result) ([]byte, error) {
+	features := make([]interface{}, len(r.Features))
+	for i, f := range r.Features {
+		features[i] = map[string]interface{}{
+			"fid":        int64(f.ID),
+			"properties": avroProperties(f.Properties),
+		}
+	}
+	return c.result.BinaryFromNative(nil, map[string]interface{}{
+		"id":       r.ID,
+		"lat":      r.Lat,
+		"lng":      r.Lng,
+		"time":     r.Time,
+		"dataset":  r.Dataset,
+		"features": features,
+	})
+}
+
+func (c *avroCodec) EncodeEvent(e geofence.Event) ([]byte, error) {
+	return c.event.BinaryFromNative(nil, map[string]interface{}{
+		"type":       e.Type.String(),
+		"object_id":  e.ObjectID,
+		"dataset":    e.Dataset,
+		"feature_id": int64(e.FeatureID),
+		"properties": avroProperties(e.Properties),
+		"lat":        e.Lat,
+		"lng":        e.Lng,
+		"time":       e.Time.UnixNano() / int64(time.Millisecond),
+		"duration":   int64(e.Duration / time.Millisecond),
+	})
+}
+
+// avroProperties returns the properties as values of the Avro union, unsupported types as strings
+func avroProperties(props map[string]interface{}) map[string]interface{} {
+	m := make(map[string]interface{}, len(props))
+	for k, v := range props {
+		switch tv := v.(type) {
+		case nil:
+			m[k] = nil
+		case bool:
+			m[k] = goavro.Union("boolean", tv)
+		case float64:
+			m[k] = goavro.Union("double", tv)
+		case string:
+			m[k] = goavro.Union("string", tv)
+		default:
+			m[k] = goavro.Union("string", fmt.Sprint(tv))
+		}
+	}
+	return m
+}
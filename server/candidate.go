@@ -0,0 +1,170 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+
+	"github.com/go-kit/kit/log/level"
+
+	"github.com/akhenakh/insideout"
+)
+
+// candidateSuffix is appended to the name of a dataset to label the metrics of its candidate
+const candidateSuffix = ":candidate"
+
+// SetCandidate loads storage as the candidate of the dataset name, a new release of its features evaluated
+// against the production traffic before being promoted with ReloadDataset: a CandidateRatio fraction of the within
+// queries of the dataset is run again with the candidate in the background and the result diffs are logged and counted.
+// It returns the previous candidate storage, nil if none, which is no longer used by the server and can be closed.
+func (s *Server) SetCandidate(name string, storage insideout.Store) (insideout.Store, error) {
+	s.mu.RLock()
+	_, err := s.dataset(name)
+	s.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	cds, err := s.newDataset(name+candidateSuffix, storage)
+	if err != nil {
+		return nil, err
+	}
+	cds.candidate = true
+	s.warmup(cds)
+
+	// waiting for in flight candidate queries to complete
+	s.mu.Lock()
+	old := s.candidates[name]
+	s.candidates[name] = cds
+	s.mu.Unlock()
+
+	level.Info(s.logger).Log("msg", "candidate loaded", "dataset", name,
+		"index_time", cds.infos.IndexTime, "ratio", s.opts.CandidateRatio)
+
+	if old == nil {
+		return nil, nil
+	}
+	old.close()
+	return old.storage, nil
+}
+
+// RemoveCandidate stops evaluating the candidate of the dataset name, it returns its storage,
+// which is no longer used by the server and can be closed, nil if none
+func (s *Server) RemoveCandidate(name string) insideout.Store {
+	s.mu.Lock()
+	old := s.candidates[name]
+	delete(s.candidates, name)
+	s.mu.Unlock()
+
+	if old == nil {
+		return nil
+	}
+	old.close()
+	return old.storage
+}
+
+// candidate returns the candidate of ds when the within query must be run again with it, nil otherwise,
+// the caller must hold s.mu
+func (s *Server) candidate(ds *dataset) *dataset {
+	cds, ok := s.candidates[ds.name]
+	if !ok || rand.Float64() >= s.opts.CandidateRatio {
+		return nil
+	}
+	return cds
+}
+
+// compareCandidate runs again the within query of lat lng of the dataset name with its candidate cds
+// in the background, then compares its features with the features of the loops fids, the query is dropped
+// when too many are running
+func (s *Server) compareCandidate(name string, cds *dataset, lat, lng float64, exact bool,
+	fids []insideout.FeatureIndexResponse, features []*insideout.Feature) {
+	select {
+	case s.candidateSem <- struct{}{}:
+	default:
+		candidateCounter.WithLabelValues(name, shadowDropped).Inc()
+		return
+	}
+
+	go func() {
+		defer func() { <-s.candidateSem }()
+
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		// the storage of a replaced candidate may be closed
+		if s.candidates[name] != cds {
+			candidateCounter.WithLabelValues(name, shadowDropped).Inc()
+			return
+		}
+
+		ctx, cancel := s.queryContext(context.Background())
+		defer cancel()
+		cfids, cfeatures, _, err := s.stab(ctx, cds, s.opts.Strategy, lat, lng, exact, nil, nil)
+		if err != nil {
+			candidateCounter.WithLabelValues(name, shadowError).Inc()
+			level.Warn(s.logger).Log("msg", "candidate query failed", "dataset", name,
+				"lat", lat, "lng", lng, "error", err)
+			return
+		}
+
+		missing, extra := diffKeys(s.candidateKeys(fids, features), s.candidateKeys(cfids, cfeatures))
+		if len(missing) == 0 && len(extra) == 0 {
+			candidateCounter.WithLabelValues(name, shadowMatch).Inc()
+			return
+		}
+		candidateCounter.WithLabelValues(name, shadowMismatch).Inc()
+		level.Warn(s.logger).Log("msg", "candidate result mismatch", "dataset", name,
+			"lat", lat, "lng", lng, "missing", missing, "extra", extra)
+	}()
+}
+
+// candidateKeys returns the sorted and deduplicated CandidateKey values of the features of the loops fids,
+// identifying them across releases, their ids when CandidateKey is empty or a feature has no such property
+func (s *Server) candidateKeys(fids []insideout.FeatureIndexResponse, features []*insideout.Feature) []string {
+	seen := make(map[string]struct{}, len(features))
+	keys := make([]string, 0, len(features))
+	for i, f := range features {
+		var key string
+		if v, ok := f.Properties[s.opts.CandidateKey]; ok && s.opts.CandidateKey != "" {
+			key = fmt.Sprint(v)
+		} else {
+			key = fmt.Sprintf("id:%d", fids[i].ID)
+		}
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// diffKeys returns the sorted keys of a missing from the sorted keys b and the extra ones of b
+func diffKeys(a, b []string) (missing, extra []string) {
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case j == len(b) || (i < len(a) && a[i] < b[j]):
+			missing = append(missing, a[i])
+			i++
+		case i == len(a) || b[j] < a[i]:
+			extra = append(extra, b[j])
+			j++
+		default:
+			i++
+			j++
+		}
+	}
+	return missing, extra
+}
+
+// close releases the caches of a dataset no longer served, its storage is closed by the owner
+func (ds *dataset) close() {
+	if ds.cache != nil {
+		ds.cache.Close()
+	}
+	if ds.results != nil {
+		ds.results.Close()
+	}
+}
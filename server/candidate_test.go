@@ -0,0 +1,83 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/insidesvc"
+)
+
+func TestServer_Candidate(t *testing.T) {
+	storage, clean := setupRects(t, map[string][4]float64{
+		"inner": {0, 0, 1, 1},
+		"outer": {-1, -1, 2, 2},
+	})
+	defer clean()
+	// the next release grows inner
+	cstorage, cclean := setupRects(t, map[string][4]float64{
+		"inner": {0, 0, 1.6, 1.6},
+		"outer": {-1, -1, 2, 2},
+	})
+	defer cclean()
+	logger := log.NewNopLogger()
+	ctx := context.Background()
+
+	_, err := New(storage, logger, nil, Options{Strategy: insideout.DBStrategy, CandidateRatio: 2})
+	require.Error(t, err)
+
+	s, err := New(storage, logger, nil, Options{
+		DatasetName:    "candidate",
+		Strategy:       insideout.DBStrategy,
+		CandidateRatio: 1,
+		CandidateKey:   "name",
+	})
+	require.NoError(t, err)
+
+	_, err = s.SetCandidate("unknown", cstorage)
+	require.Error(t, err)
+	old, err := s.SetCandidate("candidate", cstorage)
+	require.NoError(t, err)
+	require.Nil(t, old)
+
+	match := candidateCounter.WithLabelValues("candidate", shadowMatch)
+	mismatch := candidateCounter.WithLabelValues("candidate", shadowMismatch)
+	matches, mismatches := testutil.ToFloat64(match), testutil.ToFloat64(mismatch)
+	for _, p := range [][2]float64{{0.5, 0.5}, {1.5, 1.5}, {-0.5, -0.5}} {
+		resp, err := s.Within(ctx, &insidesvc.WithinRequest{Lat: p[0], Lng: p[1]})
+		require.NoError(t, err)
+		require.NotEmpty(t, resp.Responses)
+	}
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(match) == matches+2 && testutil.ToFloat64(mismatch) == mismatches+1
+	}, time.Second, 10*time.Millisecond)
+
+	// the live dataset still answers
+	resp, err := s.Within(ctx, &insidesvc.WithinRequest{Lat: 1.5, Lng: 1.5})
+	require.NoError(t, err)
+	require.Len(t, resp.Responses, 1)
+	require.Eventually(t, func() bool { return testutil.ToFloat64(mismatch) == mismatches+2 }, time.Second, 10*time.Millisecond)
+
+	require.Equal(t, cstorage, s.RemoveCandidate("candidate"))
+	require.Nil(t, s.RemoveCandidate("candidate"))
+	_, err = s.Within(ctx, &insidesvc.WithinRequest{Lat: 1.5, Lng: 1.5})
+	require.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+	require.Equal(t, mismatches+2, testutil.ToFloat64(mismatch))
+	require.Equal(t, matches+2, testutil.ToFloat64(match))
+}
+
+func TestDiffKeys(t *testing.T) {
+	missing, extra := diffKeys([]string{"a", "b", "d"}, []string{"b", "c", "d", "e"})
+	require.Equal(t, []string{"a"}, missing)
+	require.Equal(t, []string{"c", "e"}, extra)
+
+	missing, extra = diffKeys([]string{"a"}, []string{"a"})
+	require.Empty(t, missing)
+	require.Empty(t, extra)
+}
@@ -0,0 +1,39 @@
+package server
+
+import (
+	"github.com/go-kit/kit/log/level"
+
+	"github.com/akhenakh/insideout"
+)
+
+// filtered returns true for the strategies reading the cells from the storage by query,
+// the other ones hold them in memory or don't use them
+func filtered(strategy string) bool {
+	return strategy == insideout.DBStrategy || strategy == insideout.HybridStrategy
+}
+
+// loadCellFilter returns the cell filter of storage, nil when disabled, never stored or stale
+func (s *Server) loadCellFilter(name string, storage insideout.Store, infos *insideout.IndexInfos, opts Options) (
+	*insideout.CellFilter, error) {
+	if !opts.CellFilter || !filtered(opts.Strategy) {
+		return nil, nil
+	}
+	cfs, ok := storage.(insideout.CellFilterStore)
+	if !ok {
+		return nil, nil
+	}
+	filter, err := cfs.LoadCellFilter()
+	if err != nil || filter == nil {
+		return nil, err
+	}
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	// the features written since are missing from the stored filter
+	if !filter.IndexTime.Equal(infos.IndexTime) {
+		level.Warn(s.logger).Log("msg", "stale cell filter, not used, index the DB again to rebuild it",
+			"dataset", name, "filter_index_time", filter.IndexTime, "index_time", infos.IndexTime)
+		return nil, nil
+	}
+	return filter, nil
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/insidesvc"
+)
+
+func TestServer_CellFilter(t *testing.T) {
+	storage, clean := setupRW(t, "A", 0)
+	defer clean()
+
+	filter, err := insideout.BuildCellFilter(storage, 0.01)
+	require.NoError(t, err)
+	require.NoError(t, storage.(insideout.CellFilterStore).StoreCellFilter(filter))
+
+	opts := Options{Strategy: insideout.DBStrategy, CellFilter: true, ReadWrite: true, DatasetName: "filtered"}
+	s, err := New(storage, log.NewNopLogger(), nil, opts)
+	require.NoError(t, err)
+	ds, err := s.dataset("filtered")
+	require.NoError(t, err)
+	require.NotNil(t, ds.filter)
+
+	ctx := context.Background()
+	within := func(lat, lng float64) []string {
+		resp, err := s.Within(ctx, &insidesvc.WithinRequest{Lat: lat, Lng: lng})
+		require.NoError(t, err)
+		var names []string
+		for _, fresp := range resp.Responses {
+			names = append(names, fresp.Feature.Properties["name"].GetStringValue())
+		}
+		return names
+	}
+	rejects := func() float64 {
+		return testutil.ToFloat64(cellFilterCounter.WithLabelValues("filtered"))
+	}
+
+	require.Equal(t, []string{"A"}, within(0.5, 0.5))
+	require.Zero(t, rejects())
+	// in the coverage of the DB but outside of the cells
+	require.Empty(t, within(-0.5, -0.5))
+	require.Equal(t, 1.0, rejects())
+
+	// the written features are added to the filter
+	_, err = s.InsertFeature(ctx, &insidesvc.InsertFeatureRequest{Feature: squareFeature("B", 10)})
+	require.NoError(t, err)
+	require.Equal(t, []string{"B"}, within(10.5, 10.5))
+	require.Equal(t, 1.0, rejects())
+
+	// the stored filter is stale after the write
+	s, err = New(storage, log.NewNopLogger(), nil, opts)
+	require.NoError(t, err)
+	ds, err = s.dataset("filtered")
+	require.NoError(t, err)
+	require.Nil(t, ds.filter)
+	require.Equal(t, []string{"B"}, within(10.5, 10.5))
+
+	// disabled
+	opts.CellFilter = false
+	s, err = New(storage, log.NewNopLogger(), nil, opts)
+	require.NoError(t, err)
+	ds, err = s.dataset("filtered")
+	require.NoError(t, err)
+	require.Nil(t, ds.filter)
+}
@@ -0,0 +1,203 @@
+// Package compress compresses the HTTP responses with brotli, gzip or deflate, negotiated with Accept-Encoding
+package compress
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/flate"
+	"github.com/klauspost/compress/gzip"
+)
+
+// brotliQuality the brotli quality of the responses, the higher ones cost too much for dynamic content
+const brotliQuality = 4
+
+// encodings supported by preference order, brotli is the smallest
+var encodings = []string{"br", "gzip", "deflate"}
+
+var pools = map[string]*sync.Pool{
+	"br": {New: func() interface{} {
+		return brotli.NewWriterLevel(nil, brotliQuality)
+	}},
+	"gzip": {New: func() interface{} {
+		w, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+		return w
+	}},
+	"deflate": {New: func() interface{} {
+		w, _ := flate.NewWriter(nil, flate.DefaultCompression)
+		return w
+	}},
+}
+
+// encoder a compressor reusable with Reset
+type encoder interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+	Flush() error
+}
+
+// Options for Handler
+type Options struct {
+	// MinSize the min size in bytes of the compressed responses, the smaller ones are sent as is
+	MinSize int
+}
+
+// Handler is an HTTP middleware compressing the responses of next with the encoding preferred by the client,
+// the responses smaller than MinSize or already encoded are sent as is
+func Handler(next http.Handler, opts Options) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := Negotiate(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &responseWriter{ResponseWriter: w, encoding: encoding, minSize: opts.MinSize}
+		defer cw.close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// Negotiate returns the supported encoding preferred by the Accept-Encoding header value,
+// brotli then gzip then deflate for equal weights, empty if none is accepted
+func Negotiate(accept string) string {
+	weights := make(map[string]float64)
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				v, err := strconv.ParseFloat(param[2:], 64)
+				if err != nil {
+					v = 0
+				}
+				q = v
+			}
+		}
+		weights[name] = q
+	}
+
+	var best string
+	var bestQ float64
+	for _, enc := range encodings {
+		q, ok := weights[enc]
+		if !ok {
+			q, ok = weights["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = enc, q
+		}
+	}
+	return best
+}
+
+// responseWriter buffers the start of the response until MinSize is reached to decide whether to compress it
+type responseWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+
+	status int
+	buf    []byte
+	// started the headers were sent
+	started bool
+	// enc the compressor once started compressed, nil otherwise
+	enc encoder
+}
+
+func (cw *responseWriter) WriteHeader(status int) {
+	if cw.status == 0 {
+		cw.status = status
+	}
+}
+
+func (cw *responseWriter) Write(b []byte) (int, error) {
+	if cw.started {
+		if cw.enc != nil {
+			return cw.enc.Write(b)
+		}
+		return cw.ResponseWriter.Write(b)
+	}
+
+	cw.buf = append(cw.buf, b...)
+	if len(cw.buf) < cw.minSize {
+		return len(b), nil
+	}
+	if err := cw.start(true); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// start sends the headers then the buffered bytes, compressed if compress and the response is not encoded yet
+func (cw *responseWriter) start(compress bool) error {
+	cw.started = true
+	h := cw.Header()
+	if h.Get("Content-Encoding") != "" || cw.status == http.StatusNoContent || cw.status == http.StatusNotModified {
+		compress = false
+	}
+	if compress {
+		if h.Get("Content-Type") == "" {
+			h.Set("Content-Type", http.DetectContentType(cw.buf))
+		}
+		h.Set("Content-Encoding", cw.encoding)
+		h.Del("Content-Length")
+		cw.enc = pools[cw.encoding].Get().(encoder)
+		cw.enc.Reset(cw.ResponseWriter)
+	}
+	if cw.status != 0 {
+		cw.ResponseWriter.WriteHeader(cw.status)
+	}
+
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if cw.enc != nil {
+		_, err = cw.enc.Write(buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// Flush sends what was written so far, compressed even below MinSize, for the streamed responses
+func (cw *responseWriter) Flush() {
+	if !cw.started {
+		if err := cw.start(len(cw.buf) > 0); err != nil {
+			return
+		}
+	}
+	if cw.enc != nil {
+		cw.enc.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// close ends the response, sent as is if it did not reach MinSize
+func (cw *responseWriter) close() {
+	if !cw.started {
+		cw.start(false)
+	}
+	if cw.enc != nil {
+		cw.enc.Close()
+		cw.enc.Reset(nil)
+		pools[cw.encoding].Put(cw.enc)
+		cw.enc = nil
+	}
+}
@@ -0,0 +1,104 @@
+package compress
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/flate"
+	"github.com/klauspost/compress/gzip"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiate(t *testing.T) {
+	require.Equal(t, "br", Negotiate("gzip, deflate, br"))
+	require.Equal(t, "gzip", Negotiate("gzip, deflate"))
+	require.Equal(t, "gzip", Negotiate("br;q=0.5, gzip"))
+	require.Equal(t, "deflate", Negotiate("br;q=0, gzip;q=0, deflate"))
+	require.Equal(t, "br", Negotiate("*"))
+	require.Equal(t, "gzip", Negotiate("br;q=0, *;q=0.1"))
+	require.Equal(t, "", Negotiate("identity"))
+	require.Equal(t, "", Negotiate(""))
+}
+
+func TestHandler(t *testing.T) {
+	large := bytes.Repeat([]byte(`{"type":"Feature","properties":{"name":"Paris"}}`), 100)
+	h := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/small":
+			w.Write([]byte(`{}`))
+		case "/encoded":
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(large)
+		case "/notfound":
+			http.Error(w, string(large), 404)
+		default:
+			// written in chunks
+			for i := 0; i < len(large); i += 100 {
+				w.Write(large[i : i+100])
+			}
+		}
+	}), Options{MinSize: 1024})
+
+	get := func(path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Accept-Encoding", accept)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		require.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+		return w
+	}
+	decode := func(w *httptest.ResponseRecorder) []byte {
+		var r io.Reader
+		switch w.Header().Get("Content-Encoding") {
+		case "br":
+			r = brotli.NewReader(w.Body)
+		case "gzip":
+			gr, err := gzip.NewReader(w.Body)
+			require.NoError(t, err)
+			r = gr
+		case "deflate":
+			r = flate.NewReader(w.Body)
+		default:
+			r = w.Body
+		}
+		b, err := ioutil.ReadAll(r)
+		require.NoError(t, err)
+		return b
+	}
+
+	for _, enc := range []string{"br", "gzip", "deflate"} {
+		w := get("/large", enc)
+		require.Equal(t, 200, w.Code)
+		require.Equal(t, enc, w.Header().Get("Content-Encoding"))
+		require.Less(t, w.Body.Len(), len(large)/10)
+		require.Equal(t, large, decode(w))
+	}
+
+	// the status is kept
+	w := get("/notfound", "gzip")
+	require.Equal(t, 404, w.Code)
+	require.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	require.Equal(t, string(large)+"\n", string(decode(w)))
+
+	// below the min size
+	w = get("/small", "br")
+	require.Empty(t, w.Header().Get("Content-Encoding"))
+	require.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	require.Equal(t, `{}`, w.Body.String())
+
+	// not accepted
+	w = get("/large", "")
+	require.Empty(t, w.Header().Get("Content-Encoding"))
+	require.Equal(t, large, w.Body.Bytes())
+
+	// already encoded
+	w = get("/encoded", "br")
+	require.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	require.Equal(t, large, w.Body.Bytes())
+}
@@ -0,0 +1,491 @@
+// Package connect serves a gRPC server over the Connect and Twirp protocols, JSON or protobuf, and gRPC-Web,
+// next to gRPC itself, on one port accepting HTTP/1.1 and cleartext HTTP/2, for the clients where gRPC is blocked
+// like the browsers or behind HTTP/1.1 proxies.
+//
+// The requests are translated to gRPC requests served by grpc.Server.ServeHTTP, so they go through the interceptors
+// of the server, their HTTP headers are the metadata. Only the unary methods are served over Connect and Twirp,
+// the gRPC-Web responses are sent once the call is done, and the bidirectional streams require gRPC.
+package connect
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxMessageSize the max size of a request, the default max message size of the gRPC servers
+const maxMessageSize = 4 << 20
+
+const (
+	grpcType        = "application/grpc"
+	grpcWebType     = "application/grpc-web"
+	grpcWebTextType = "application/grpc-web-text"
+	jsonType        = "application/json"
+
+	// twirpPrefix the prefix of the paths of the Twirp methods, /twirp/Service/Method
+	twirpPrefix = "/twirp"
+
+	// trailersFlag flags the gRPC-Web frame holding the trailers
+	trailersFlag = 0x80
+)
+
+// method the Go types of the request and the response of a unary method
+type method struct {
+	in, out reflect.Type
+}
+
+// protocol a unary protocol
+type protocol struct {
+	// protoType the content type of the protobuf messages, the other one is JSON
+	protoType string
+
+	marshaler *jsonpb.Marshaler
+
+	// trailerPrefix prefixes the trailers sent as headers, they are dropped when empty
+	trailerPrefix string
+
+	// writeError writes st with the HTTP status code, mapped from the code of st when 0
+	writeError func(w http.ResponseWriter, st *status.Status, code int)
+}
+
+var (
+	connectProtocol = &protocol{
+		protoType:     "application/proto",
+		marshaler:     &jsonpb.Marshaler{},
+		trailerPrefix: "Trailer-",
+		writeError:    writeConnectError,
+	}
+
+	// the Twirp servers use the names of the proto fields and emit the default values
+	twirpProtocol = &protocol{
+		protoType:  "application/protobuf",
+		marshaler:  &jsonpb.Marshaler{OrigName: true, EmitDefaults: true},
+		writeError: writeTwirpError,
+	}
+)
+
+type handler struct {
+	grpc *grpc.Server
+
+	// methods the unary methods of the services by path, /Service/Method
+	methods map[string]method
+}
+
+// New returns the handler serving srv, the unary methods of the services descs, registered in srv,
+// are served over Connect and Twirp, all the services registered in srv are served over gRPC-Web and gRPC
+func New(srv *grpc.Server, descs ...*grpc.ServiceDesc) http.Handler {
+	h := &handler{grpc: srv, methods: make(map[string]method)}
+	for _, desc := range descs {
+		typ := reflect.TypeOf(desc.HandlerType).Elem()
+		for _, md := range desc.Methods {
+			m, ok := typ.MethodByName(md.MethodName)
+			if !ok {
+				continue
+			}
+			h.methods["/"+desc.ServiceName+"/"+md.MethodName] = method{in: m.Type.In(1), out: m.Type.Out(0)}
+		}
+	}
+	// the gRPC clients speak HTTP/2 without TLS
+	return h2c.NewHandler(h, &http2.Server{})
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ct := r.Header.Get("Content-Type")
+	switch {
+	case strings.HasPrefix(ct, grpcWebType):
+		h.serveGRPCWeb(w, r, ct)
+	case strings.HasPrefix(ct, grpcType):
+		h.grpc.ServeHTTP(w, r)
+	case strings.HasPrefix(r.URL.Path, twirpPrefix+"/"):
+		h.serveUnary(w, r, twirpProtocol, strings.TrimPrefix(r.URL.Path, twirpPrefix))
+	default:
+		h.serveUnary(w, r, connectProtocol, r.URL.Path)
+	}
+}
+
+// serveUnary serves a call of the unary method at path with the protocol p
+func (h *handler) serveUnary(w http.ResponseWriter, r *http.Request, p *protocol, path string) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		p.writeError(w, status.New(codes.Unimplemented, "only POST is supported"), http.StatusMethodNotAllowed)
+		return
+	}
+	m, ok := h.methods[path]
+	if !ok {
+		p.writeError(w, status.Newf(codes.Unimplemented, "unknown method %s", path), http.StatusNotFound)
+		return
+	}
+	ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if ct != jsonType && ct != p.protoType {
+		p.writeError(w, status.Newf(codes.InvalidArgument, "unsupported content type %q", ct), http.StatusUnsupportedMediaType)
+		return
+	}
+	if enc := r.Header.Get("Content-Encoding"); enc != "" && enc != "identity" {
+		p.writeError(w, status.Newf(codes.Unimplemented, "unsupported content encoding %s", enc), 0)
+		return
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxMessageSize+1))
+	if err != nil {
+		p.writeError(w, status.Newf(codes.InvalidArgument, "can't read the request: %v", err), 0)
+		return
+	}
+	if len(body) > maxMessageSize {
+		p.writeError(w, status.Newf(codes.ResourceExhausted, "request larger than %d bytes", maxMessageSize), 0)
+		return
+	}
+	if ct == jsonType {
+		msg := reflect.New(m.in.Elem()).Interface().(proto.Message)
+		if len(bytes.TrimSpace(body)) > 0 {
+			if err := (&jsonpb.Unmarshaler{AllowUnknownFields: true}).Unmarshal(bytes.NewReader(body), msg); err != nil {
+				p.writeError(w, status.Newf(codes.InvalidArgument, "invalid JSON request: %v", err), 0)
+				return
+			}
+		}
+		if body, err = proto.Marshal(msg); err != nil {
+			p.writeError(w, status.New(codes.Internal, err.Error()), 0)
+			return
+		}
+	}
+
+	header := grpcHeader(r)
+	if ms := r.Header.Get("Connect-Timeout-Ms"); ms != "" {
+		timeout, err := grpcTimeout(ms)
+		if err != nil {
+			p.writeError(w, status.Newf(codes.InvalidArgument, "invalid Connect-Timeout-Ms %q", ms), 0)
+			return
+		}
+		header.Set("Grpc-Timeout", timeout)
+	}
+
+	rec := h.call(r, path, header, frame(0, body))
+	rheader, rtrailer := rec.metadata()
+	copyHeader(w.Header(), rheader, "")
+	if st := rec.status(); st.Code() != codes.OK {
+		p.writeError(w, st, 0)
+		return
+	}
+	msgs, err := rec.messages()
+	if err == nil && len(msgs) != 1 {
+		err = fmt.Errorf("%d response messages", len(msgs))
+	}
+	if err != nil {
+		p.writeError(w, status.Newf(codes.Internal, "invalid gRPC response: %v", err), 0)
+		return
+	}
+
+	out := msgs[0]
+	if ct == jsonType {
+		msg := reflect.New(m.out.Elem()).Interface().(proto.Message)
+		if err := proto.Unmarshal(out, msg); err != nil {
+			p.writeError(w, status.Newf(codes.Internal, "invalid gRPC response: %v", err), 0)
+			return
+		}
+		var buf bytes.Buffer
+		if err := p.marshaler.Marshal(&buf, msg); err != nil {
+			p.writeError(w, status.New(codes.Internal, err.Error()), 0)
+			return
+		}
+		out = buf.Bytes()
+	}
+
+	if p.trailerPrefix != "" {
+		copyHeader(w.Header(), rtrailer, p.trailerPrefix)
+	}
+	w.Header().Set("Content-Type", ct)
+	w.Header().Set("Content-Length", strconv.Itoa(len(out)))
+	_, _ = w.Write(out)
+}
+
+// serveGRPCWeb serves a gRPC-Web call, binary or base64 text, the messages are already framed like gRPC ones
+// and the trailers are sent in a last frame
+func (h *handler) serveGRPCWeb(w http.ResponseWriter, r *http.Request, ct string) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	text := strings.HasPrefix(ct, grpcWebTextType)
+
+	var rd io.Reader = io.LimitReader(r.Body, 2*maxMessageSize)
+	if text {
+		rd = base64.NewDecoder(base64.StdEncoding, rd)
+	}
+	body, err := ioutil.ReadAll(rd)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("can't read the request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	rec := h.call(r, r.URL.Path, grpcHeader(r), body)
+	rheader, rtrailer := rec.metadata()
+	st := rec.status()
+
+	var out []byte
+	if _, err := rec.messages(); err == nil {
+		out = rec.body.Bytes()
+	}
+	var trailers bytes.Buffer
+	fmt.Fprintf(&trailers, "grpc-status: %d\r\n", st.Code())
+	if st.Message() != "" {
+		fmt.Fprintf(&trailers, "grpc-message: %s\r\n", encodeMessage(st.Message()))
+	}
+	if v := rec.header.Get("Grpc-Status-Details-Bin"); v != "" {
+		fmt.Fprintf(&trailers, "grpc-status-details-bin: %s\r\n", v)
+	}
+	for k, vv := range rtrailer {
+		for _, v := range vv {
+			fmt.Fprintf(&trailers, "%s: %s\r\n", strings.ToLower(k), v)
+		}
+	}
+	out = append(out, frame(trailersFlag, trailers.Bytes())...)
+	if text {
+		out = []byte(base64.StdEncoding.EncodeToString(out))
+	}
+
+	copyHeader(w.Header(), rheader, "")
+	w.Header().Set("Content-Type", ct)
+	w.Header().Set("Content-Length", strconv.Itoa(len(out)))
+	_, _ = w.Write(out)
+}
+
+// call serves the gRPC request of the method at path, with the metadata header and the framed messages body,
+// and returns its recorded response once done
+func (h *handler) call(r *http.Request, path string, header http.Header, body []byte) *recorder {
+	req := r.Clone(r.Context())
+	req.Method = http.MethodPost
+	req.Proto, req.ProtoMajor, req.ProtoMinor = "HTTP/2.0", 2, 0
+	req.URL.Path = path
+	req.Header = header
+	req.Header.Set("Content-Type", grpcType+"+proto")
+	req.Header.Set("Te", "trailers")
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+
+	rec := &recorder{header: make(http.Header)}
+	h.grpc.ServeHTTP(rec, req)
+	return rec
+}
+
+// grpcHeader returns the headers of r passed as the metadata of the gRPC request,
+// without the ones of the HTTP and the translated protocols
+func grpcHeader(r *http.Request) http.Header {
+	header := make(http.Header, len(r.Header))
+	for k, vv := range r.Header {
+		switch {
+		case strings.HasPrefix(k, "Content-"), strings.HasPrefix(k, "Accept"), strings.HasPrefix(k, "Connect-"),
+			k == "Connection", k == "Te", k == "Twirp-Version", k == "X-Grpc-Web", k == "X-User-Agent":
+			continue
+		}
+		header[k] = vv
+	}
+	return header
+}
+
+// grpcTimeout returns the grpc-timeout of the Connect timeout ms, in milliseconds,
+// in seconds above the 8 digits of grpc-timeout
+func grpcTimeout(ms string) (string, error) {
+	v, err := strconv.ParseUint(ms, 10, 64)
+	if err != nil || len(ms) > 10 {
+		return "", errors.New("invalid timeout")
+	}
+	if v >= 1e8 {
+		return strconv.FormatUint(v/1000, 10) + "S", nil
+	}
+	return strconv.FormatUint(v, 10) + "m", nil
+}
+
+// frame returns msg prefixed by flags and its length, the framing of the gRPC messages
+func frame(flags byte, msg []byte) []byte {
+	b := make([]byte, 5+len(msg))
+	b[0] = flags
+	binary.BigEndian.PutUint32(b[1:], uint32(len(msg)))
+	copy(b[5:], msg)
+	return b
+}
+
+// copyHeader adds the values of src to dst, their names prefixed by prefix
+func copyHeader(dst, src http.Header, prefix string) {
+	for k, vv := range src {
+		for _, v := range vv {
+			dst.Add(prefix+k, v)
+		}
+	}
+}
+
+// encodeMessage percent encodes the status message msg like the grpc-message header
+func encodeMessage(msg string) string {
+	var b strings.Builder
+	for i := 0; i < len(msg); i++ {
+		c := msg[i]
+		if c >= ' ' && c <= '~' && c != '%' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+// recorder records the response of a gRPC request served by grpc.Server.ServeHTTP
+type recorder struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+}
+
+func (rec *recorder) Header() http.Header { return rec.header }
+
+func (rec *recorder) Write(b []byte) (int, error) { return rec.body.Write(b) }
+
+func (rec *recorder) WriteHeader(code int) { rec.code = code }
+
+// Flush is required by the gRPC handler, the response is only read once complete
+func (rec *recorder) Flush() {}
+
+// status returns the status of the call, Internal when the response is not a gRPC response
+func (rec *recorder) status() *status.Status {
+	s := rec.header.Get("Grpc-Status")
+	code, err := strconv.Atoi(s)
+	if err != nil {
+		// a request rejected before the call, like a timeout in an invalid format
+		return status.Newf(codes.Internal, "invalid gRPC response, HTTP status %d: %s",
+			rec.code, bytes.TrimSpace(rec.body.Bytes()))
+	}
+	msg := rec.header.Get("Grpc-Message")
+	if m, err := url.PathUnescape(msg); err == nil {
+		msg = m
+	}
+	return status.New(codes.Code(code), msg)
+}
+
+// metadata returns the header and trailer metadata of the response
+func (rec *recorder) metadata() (header, trailer http.Header) {
+	header, trailer = make(http.Header), make(http.Header)
+	for k, vv := range rec.header {
+		if strings.HasPrefix(k, http2.TrailerPrefix) {
+			trailer[http.CanonicalHeaderKey(strings.TrimPrefix(k, http2.TrailerPrefix))] = vv
+			continue
+		}
+		switch k {
+		case "Content-Type", "Date", "Trailer", "Grpc-Status", "Grpc-Message", "Grpc-Status-Details-Bin":
+			continue
+		}
+		header[k] = vv
+	}
+	return header, trailer
+}
+
+// messages returns the uncompressed messages of the response body
+func (rec *recorder) messages() ([][]byte, error) {
+	if rec.header.Get("Grpc-Status") == "" {
+		return nil, errors.New("not a gRPC response")
+	}
+	var msgs [][]byte
+	b := rec.body.Bytes()
+	for len(b) > 0 {
+		if len(b) < 5 {
+			return nil, errors.New("truncated message")
+		}
+		if b[0] != 0 {
+			return nil, errors.New("compressed message")
+		}
+		n := binary.BigEndian.Uint32(b[1:])
+		if uint64(len(b)-5) < uint64(n) {
+			return nil, errors.New("truncated message")
+		}
+		msgs = append(msgs, b[5:5+n])
+		b = b[5+n:]
+	}
+	return msgs, nil
+}
+
+// errorCode the name and the HTTP statuses of a gRPC code in the Connect and Twirp protocols
+type errorCode struct {
+	name           string
+	connect, twirp int
+}
+
+var errorCodes = map[codes.Code]errorCode{
+	codes.Canceled:           {"canceled", 499, http.StatusRequestTimeout},
+	codes.Unknown:            {"unknown", http.StatusInternalServerError, http.StatusInternalServerError},
+	codes.InvalidArgument:    {"invalid_argument", http.StatusBadRequest, http.StatusBadRequest},
+	codes.DeadlineExceeded:   {"deadline_exceeded", http.StatusGatewayTimeout, http.StatusRequestTimeout},
+	codes.NotFound:           {"not_found", http.StatusNotFound, http.StatusNotFound},
+	codes.AlreadyExists:      {"already_exists", http.StatusConflict, http.StatusConflict},
+	codes.PermissionDenied:   {"permission_denied", http.StatusForbidden, http.StatusForbidden},
+	codes.ResourceExhausted:  {"resource_exhausted", http.StatusTooManyRequests, http.StatusTooManyRequests},
+	codes.FailedPrecondition: {"failed_precondition", http.StatusBadRequest, http.StatusPreconditionFailed},
+	codes.Aborted:            {"aborted", http.StatusConflict, http.StatusConflict},
+	codes.OutOfRange:         {"out_of_range", http.StatusBadRequest, http.StatusBadRequest},
+	codes.Unimplemented:      {"unimplemented", http.StatusNotImplemented, http.StatusNotImplemented},
+	codes.Internal:           {"internal", http.StatusInternalServerError, http.StatusInternalServerError},
+	codes.Unavailable:        {"unavailable", http.StatusServiceUnavailable, http.StatusServiceUnavailable},
+	codes.DataLoss:           {"data_loss", http.StatusInternalServerError, http.StatusInternalServerError},
+	codes.Unauthenticated:    {"unauthenticated", http.StatusUnauthorized, http.StatusUnauthorized},
+}
+
+// errorCodeOf returns the errorCode of st, Unknown for the unknown codes
+func errorCodeOf(st *status.Status) errorCode {
+	if e, ok := errorCodes[st.Code()]; ok {
+		return e
+	}
+	return errorCodes[codes.Unknown]
+}
+
+// writeConnectError writes the Connect error st, {"code": "not_found", "message": "..."}
+func writeConnectError(w http.ResponseWriter, st *status.Status, code int) {
+	e := errorCodeOf(st)
+	if code == 0 {
+		code = e.connect
+	}
+	writeJSON(w, code, struct {
+		Code    string `json:"code"`
+		Message string `json:"message,omitempty"`
+	}{e.name, st.Message()})
+}
+
+// writeTwirpError writes the Twirp error st, {"code": "not_found", "msg": "..."}, the requests not routed to a method
+// are bad_route errors
+func writeTwirpError(w http.ResponseWriter, st *status.Status, code int) {
+	e := errorCodeOf(st)
+	name := e.name
+	switch {
+	case code != 0:
+		name, code = "bad_route", http.StatusNotFound
+	case st.Code() == codes.DataLoss:
+		name, code = "dataloss", e.twirp
+	default:
+		code = e.twirp
+	}
+	writeJSON(w, code, struct {
+		Code string `json:"code"`
+		Msg  string `json:"msg"`
+	}{name, st.Message()})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	b, _ := json.Marshal(v)
+	w.Header().Set("Content-Type", jsonType)
+	w.WriteHeader(code)
+	_, _ = w.Write(b)
+}
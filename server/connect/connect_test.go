@@ -0,0 +1,230 @@
+package connect
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/akhenakh/insideout/insidesvc"
+)
+
+// insideServer answers Within with the point of the request and the api key of its metadata as dataset,
+// Info fails
+type insideServer struct {
+	insidesvc.InsideServer
+}
+
+func (s *insideServer) Within(ctx context.Context, req *insidesvc.WithinRequest) (*insidesvc.WithinResponse, error) {
+	if req.Lat > 90 {
+		return nil, status.Error(codes.InvalidArgument, "invalid lat")
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	_ = grpc.SetHeader(ctx, metadata.Pairs("x-dataset", strings.Join(md.Get("x-api-key"), ",")))
+	_ = grpc.SetTrailer(ctx, metadata.Pairs("x-count", "1"))
+	return &insidesvc.WithinResponse{
+		Point:     &insidesvc.Point{Lat: req.Lat, Lng: req.Lng},
+		Responses: []*insidesvc.FeatureResponse{{Id: 4}},
+	}, nil
+}
+
+func (s *insideServer) Info(ctx context.Context, req *insidesvc.InfoRequest) (*insidesvc.InfoResponse, error) {
+	return nil, status.Error(codes.NotFound, "no dataset ☹")
+}
+
+func newTestServer() *httptest.Server {
+	gs := grpc.NewServer()
+	insidesvc.RegisterInsideServer(gs, &insideServer{})
+	return httptest.NewServer(New(gs, insidesvc.InsideServiceDesc))
+}
+
+func post(t *testing.T, url, ct string, body []byte, header ...string) (*http.Response, []byte) {
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", ct)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, b
+}
+
+func TestHandler_Connect(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	resp, b := post(t, ts.URL+"/Inside/Within", "application/json", []byte(`{"lat":48.8,"lng":2.2,"unknown":1}`),
+		"X-API-Key", "k1", "Connect-Timeout-Ms", "5000")
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(b))
+	require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	require.Equal(t, "k1", resp.Header.Get("X-Dataset"))
+	require.Equal(t, "1", resp.Header.Get("Trailer-X-Count"))
+	require.JSONEq(t, `{"point":{"lat":48.8,"lng":2.2},"responses":[{"id":4}]}`, string(b))
+
+	// protobuf
+	in, err := proto.Marshal(&insidesvc.WithinRequest{Lat: 1, Lng: 2})
+	require.NoError(t, err)
+	resp, b = post(t, ts.URL+"/Inside/Within", "application/proto", in)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "application/proto", resp.Header.Get("Content-Type"))
+	var out insidesvc.WithinResponse
+	require.NoError(t, proto.Unmarshal(b, &out))
+	require.Equal(t, 2.0, out.Point.Lng)
+
+	tests := []struct {
+		name, path, ct, body string
+		code                 int
+		want                 string
+	}{
+		{"invalid argument", "/Inside/Within", "application/json", `{"lat":91}`,
+			http.StatusBadRequest, `{"code":"invalid_argument","message":"invalid lat"}`},
+		{"not found", "/Inside/Info", "application/json", `{}`,
+			http.StatusNotFound, `{"code":"not_found","message":"no dataset ☹"}`},
+		{"invalid JSON", "/Inside/Within", "application/json", `{"lat":`,
+			http.StatusBadRequest, ""},
+		{"unknown method", "/Inside/Unknown", "application/json", `{}`,
+			http.StatusNotFound, `{"code":"unimplemented","message":"unknown method /Inside/Unknown"}`},
+		{"streaming method", "/Inside/WithinStream", "application/json", `{}`,
+			http.StatusNotFound, ""},
+		{"unsupported content type", "/Inside/Within", "text/plain", `{}`,
+			http.StatusUnsupportedMediaType, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, b := post(t, ts.URL+tt.path, tt.ct, []byte(tt.body))
+			require.Equal(t, tt.code, resp.StatusCode, string(b))
+			require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+			if tt.want != "" {
+				require.JSONEq(t, tt.want, string(b))
+			}
+		})
+	}
+
+	resp, err = http.Get(ts.URL + "/Inside/Within")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestHandler_Twirp(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	resp, b := post(t, ts.URL+"/twirp/Inside/Within", "application/json", []byte(`{"lat":48.8,"lng":2.2}`))
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(b))
+	// the proto names and the default values
+	var out map[string]interface{}
+	require.NoError(t, json.Unmarshal(b, &out))
+	require.Contains(t, out, "debug")
+	require.Equal(t, float64(4), out["responses"].([]interface{})[0].(map[string]interface{})["id"])
+	require.Empty(t, resp.Header.Get("Trailer-X-Count"))
+
+	in, err := proto.Marshal(&insidesvc.WithinRequest{Lat: 1, Lng: 2})
+	require.NoError(t, err)
+	resp, b = post(t, ts.URL+"/twirp/Inside/Within", "application/protobuf", in)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var pout insidesvc.WithinResponse
+	require.NoError(t, proto.Unmarshal(b, &pout))
+	require.Equal(t, 1.0, pout.Point.Lat)
+
+	resp, b = post(t, ts.URL+"/twirp/Inside/Info", "application/json", []byte(`{}`))
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	require.JSONEq(t, `{"code":"not_found","msg":"no dataset ☹"}`, string(b))
+
+	resp, b = post(t, ts.URL+"/twirp/Inside/Unknown", "application/json", []byte(`{}`))
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	require.JSONEq(t, `{"code":"bad_route","msg":"unknown method /Inside/Unknown"}`, string(b))
+}
+
+// readFrames returns the gRPC-Web frames of b
+func readFrames(t *testing.T, b []byte) (msgs [][]byte, trailers string) {
+	for len(b) > 0 {
+		require.True(t, len(b) >= 5)
+		n := binary.BigEndian.Uint32(b[1:])
+		if b[0]&trailersFlag != 0 {
+			trailers = string(b[5 : 5+n])
+		} else {
+			msgs = append(msgs, b[5:5+n])
+		}
+		b = b[5+n:]
+	}
+	return msgs, trailers
+}
+
+func TestHandler_GRPCWeb(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	in, err := proto.Marshal(&insidesvc.WithinRequest{Lat: 1, Lng: 2})
+	require.NoError(t, err)
+
+	resp, b := post(t, ts.URL+"/Inside/Within", "application/grpc-web+proto", frame(0, in), "X-API-Key", "k1")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "application/grpc-web+proto", resp.Header.Get("Content-Type"))
+	require.Equal(t, "k1", resp.Header.Get("X-Dataset"))
+	msgs, trailers := readFrames(t, b)
+	require.Len(t, msgs, 1)
+	var out insidesvc.WithinResponse
+	require.NoError(t, proto.Unmarshal(msgs[0], &out))
+	require.Equal(t, 1.0, out.Point.Lat)
+	require.Equal(t, "grpc-status: 0\r\nx-count: 1\r\n", trailers)
+
+	// text
+	resp, b = post(t, ts.URL+"/Inside/Info", "application/grpc-web-text",
+		[]byte(base64.StdEncoding.EncodeToString(frame(0, nil))))
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	b, err = base64.StdEncoding.DecodeString(string(b))
+	require.NoError(t, err)
+	msgs, trailers = readFrames(t, b)
+	require.Empty(t, msgs)
+	require.Equal(t, "grpc-status: 5\r\ngrpc-message: no dataset %E2%98%B9\r\n", trailers)
+}
+
+func TestHandler_GRPC(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	conn, err := grpc.Dial(strings.TrimPrefix(ts.URL, "http://"), grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+	c := insidesvc.NewInsideClient(conn)
+
+	out, err := c.Within(context.Background(), &insidesvc.WithinRequest{Lat: 1, Lng: 2})
+	require.NoError(t, err)
+	require.Equal(t, 2.0, out.Point.Lng)
+
+	_, err = c.Info(context.Background(), &insidesvc.InfoRequest{})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestGRPCTimeout(t *testing.T) {
+	v, err := grpcTimeout("5000")
+	require.NoError(t, err)
+	require.Equal(t, "5000m", v)
+
+	v, err = grpcTimeout("1234567890")
+	require.NoError(t, err)
+	require.Equal(t, "1234567S", v)
+
+	_, err = grpcTimeout("-1")
+	require.Error(t, err)
+	_, err = grpcTimeout("12345678901")
+	require.Error(t, err)
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/golang/geo/s2"
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/akhenakh/insideout/server/debug"
+)
+
+// Coverage returns the cells containing all the features of the dataset, see insideout.ComputeCoverage,
+// the default dataset if name is empty
+func (s *Server) Coverage(name string) (s2.CellUnion, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ds, err := s.dataset(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(ds.infos.Coverage) == 0 {
+		return nil, status.Errorf(codes.NotFound, "dataset %s does not record its coverage, index it again", ds.name)
+	}
+	return ds.infos.Coverage, nil
+}
+
+// CoverageHandler HTTP 1.1 Handler returning the coverage of a dataset as GeoJSON, a feature by cell
+func (s *Server) CoverageHandler(w http.ResponseWriter, r *http.Request) {
+	_, span := tracer().Start(r.Context(), "CoverageHandler")
+	defer span.End()
+
+	cu, err := s.Coverage(mux.Vars(r)["dataset"])
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			http.Error(w, status.Convert(err).Message(), 404)
+			return
+		}
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(debug.CellUnionToGeoJSON(cu))
+}
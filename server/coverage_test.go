@@ -0,0 +1,76 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/insidesvc"
+)
+
+func TestServer_Coverage(t *testing.T) {
+	storage, clean := setupRW(t, "A", 0)
+	defer clean()
+
+	s, err := New(storage, log.NewNopLogger(), nil, Options{
+		Strategy:    insideout.ShapeIndexStrategy,
+		ReadWrite:   true,
+		DatasetName: "covered",
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	count := func(lat, lng float64) int {
+		resp, err := s.Within(ctx, &insidesvc.WithinRequest{Lat: lat, Lng: lng})
+		require.NoError(t, err)
+		return len(resp.Responses)
+	}
+	rejects := func() float64 {
+		return testutil.ToFloat64(coverageCounter.WithLabelValues("covered"))
+	}
+
+	require.Equal(t, 1, count(0.5, 0.5))
+	require.Zero(t, rejects())
+	require.Zero(t, count(40, 40))
+	require.Equal(t, 1.0, rejects())
+
+	coverage := func(dataset string) (int, []map[string]interface{}) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/api/coverage/"+dataset, nil)
+		s.CoverageHandler(w, mux.SetURLVars(r, map[string]string{"dataset": dataset}))
+		if w.Code != 200 {
+			return w.Code, nil
+		}
+		var fc struct {
+			Features []struct {
+				Properties map[string]interface{}
+			}
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &fc))
+		var props []map[string]interface{}
+		for _, f := range fc.Features {
+			props = append(props, f.Properties)
+		}
+		return w.Code, props
+	}
+	code, cells := coverage("")
+	require.Equal(t, 200, code)
+	require.Len(t, cells, 4)
+	require.Equal(t, float64(insideout.CoverageLevel), cells[0]["level"])
+	code, _ = coverage("unknown")
+	require.Equal(t, 404, code)
+
+	// the written features extend the coverage
+	_, err = s.InsertFeature(ctx, &insidesvc.InsertFeatureRequest{Feature: squareFeature("B", 40)})
+	require.NoError(t, err)
+	require.Equal(t, 1, count(40.5, 40.5))
+	_, cells = coverage("covered")
+	require.True(t, len(cells) > 4)
+}
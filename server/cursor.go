@@ -0,0 +1,38 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+
+	"github.com/akhenakh/insideout"
+)
+
+// EncodeCursor returns the opaque cursor of a paginated query resuming after the polygon fid,
+// the servers of the same DB share the cursors, the shard router merges their pages
+func EncodeCursor(fid insideout.FeatureIndexResponse) string {
+	b := make([]byte, 6)
+	binary.BigEndian.PutUint32(b, fid.ID)
+	binary.BigEndian.PutUint16(b[4:], fid.Pos)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor returns the polygon the cursor resumes after
+func DecodeCursor(cursor string) (insideout.FeatureIndexResponse, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(b) != 6 {
+		return insideout.FeatureIndexResponse{}, errors.New("invalid cursor")
+	}
+	return insideout.FeatureIndexResponse{
+		ID:  binary.BigEndian.Uint32(b),
+		Pos: binary.BigEndian.Uint16(b[4:]),
+	}, nil
+}
+
+// fidLess returns true if the polygon a is before b, by feature id then polygon index
+func fidLess(a, b insideout.FeatureIndexResponse) bool {
+	if a.ID == b.ID {
+		return a.Pos < b.Pos
+	}
+	return a.ID < b.ID
+}
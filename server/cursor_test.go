@@ -0,0 +1,102 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/insidesvc"
+)
+
+func TestCursor(t *testing.T) {
+	fid := insideout.FeatureIndexResponse{ID: 1 << 20, Pos: 3}
+	c, err := DecodeCursor(EncodeCursor(fid))
+	require.NoError(t, err)
+	require.Equal(t, fid, c)
+
+	_, err = DecodeCursor("AAAA")
+	require.Error(t, err)
+	_, err = DecodeCursor("not a cursor")
+	require.Error(t, err)
+}
+
+func TestServer_IntersectPagination(t *testing.T) {
+	var squares []nestedSquare
+	for i := 0; i < 5; i++ {
+		squares = append(squares, nestedSquare{name: fmt.Sprintf("sq%d", i), size: 1 - float64(i)*0.1})
+	}
+	storage, clean := setupNested(t, squares)
+	defer clean()
+
+	s, err := New(storage, log.NewNopLogger(), nil, Options{Strategy: insideout.DBStrategy, MaxIntersectResults: 3})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	point := &insidesvc.Geometry{Type: insidesvc.Geometry_POINT, Coordinates: []float64{0.5, 0.5}}
+	page := func(limit int32, cursor string) ([]string, string) {
+		resp, err := s.Intersect(ctx, &insidesvc.IntersectRequest{
+			Geometry: point, RemoveGeometries: true, Limit: limit, Cursor: cursor,
+		})
+		require.NoError(t, err)
+		var names []string
+		for _, r := range resp.Responses {
+			names = append(names, r.Feature.Properties["name"].GetStringValue())
+		}
+		return names, resp.NextCursor
+	}
+
+	names, cursor := page(2, "")
+	require.Equal(t, []string{"sq0", "sq1"}, names)
+	names, cursor = page(2, cursor)
+	require.Equal(t, []string{"sq2", "sq3"}, names)
+	names, cursor = page(2, cursor)
+	require.Equal(t, []string{"sq4"}, names)
+	require.Empty(t, cursor)
+
+	// capped by the server
+	names, cursor = page(0, "")
+	require.Equal(t, []string{"sq0", "sq1", "sq2"}, names)
+	require.NotEmpty(t, cursor)
+	names, cursor = page(10, cursor)
+	require.Equal(t, []string{"sq3", "sq4"}, names)
+	require.Empty(t, cursor)
+
+	// resumes after the feature of the cursor
+	names, _ = page(1, EncodeCursor(insideout.FeatureIndexResponse{ID: 0}))
+	require.Equal(t, []string{"sq1"}, names)
+	names, cursor = page(1, EncodeCursor(insideout.FeatureIndexResponse{ID: 4}))
+	require.Empty(t, names)
+	require.Empty(t, cursor)
+
+	_, err = s.Intersect(ctx, &insidesvc.IntersectRequest{Geometry: point, Cursor: "invalid"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = s.Intersect(ctx, &insidesvc.IntersectRequest{Geometry: point, Limit: -1})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	r := mux.NewRouter()
+	for _, route := range s.APIRoutes() {
+		r.Handle(route.Path, route.Handler).Methods(route.Methods...)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/intersect?bbox=0.4,0.4,0.6,0.6&limit=4", nil))
+	require.Equal(t, 200, w.Code)
+	next := w.Header().Get(nextCursorHeader)
+	require.NotEmpty(t, next)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/intersect?bbox=0.4,0.4,0.6,0.6&cursor="+next, nil))
+	require.Equal(t, 200, w.Code)
+	require.Empty(t, w.Header().Get(nextCursorHeader))
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/intersect?bbox=0.4,0.4,0.6,0.6&limit=-1", nil))
+	require.Equal(t, 400, w.Code)
+}
@@ -0,0 +1,32 @@
+package server
+
+import (
+	"context"
+
+	"google.golang.org/grpc/status"
+)
+
+// queryContext returns ctx capped by the MaxQueryDuration option
+func (s *Server) queryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.opts.MaxQueryDuration <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, s.opts.MaxQueryDuration)
+}
+
+// queryDone returns the DeadlineExceeded or Canceled status once ctx is done, nil otherwise,
+// checked between the candidates to stop a query midway
+func queryDone(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return status.FromContextError(err).Err()
+	}
+	return nil
+}
+
+// queryError returns the status of ctx when err was caused by ctx being done, err otherwise
+func queryError(ctx context.Context, err error) error {
+	if cerr := queryDone(ctx); cerr != nil {
+		return cerr
+	}
+	return err
+}
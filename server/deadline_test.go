@@ -0,0 +1,56 @@
+package server
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/insidesvc"
+)
+
+func TestServer_MaxQueryDuration(t *testing.T) {
+	a, clean := setup(t, "A", 0)
+	defer clean()
+
+	for _, strategy := range []string{insideout.DBStrategy, insideout.InsideTreeStrategy, insideout.ShapeIndexStrategy} {
+		t.Run(strategy, func(t *testing.T) {
+			s, err := New(a, log.NewNopLogger(), nil, Options{Strategy: strategy})
+			require.NoError(t, err)
+
+			resp, err := s.Within(context.Background(), &insidesvc.WithinRequest{Lat: 0.5, Lng: 0.5})
+			require.NoError(t, err)
+			require.Len(t, resp.Responses, 1)
+
+			// canceled by the client
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			_, err = s.Within(ctx, &insidesvc.WithinRequest{Lat: 0.5, Lng: 0.5})
+			require.Equal(t, codes.Canceled, status.Code(err))
+			_, err = s.Intersect(ctx, &insidesvc.IntersectRequest{
+				Geometry: &insidesvc.Geometry{Type: insidesvc.Geometry_POINT, Coordinates: []float64{0.5, 0.5}},
+			})
+			require.Equal(t, codes.Canceled, status.Code(err))
+
+			// already exceeded
+			s.opts.MaxQueryDuration = time.Nanosecond
+			_, err = s.Within(context.Background(), &insidesvc.WithinRequest{Lat: 0.5, Lng: 0.5})
+			require.Equal(t, codes.DeadlineExceeded, status.Code(err))
+
+			r := mux.NewRouter()
+			for _, route := range s.APIRoutes() {
+				r.Handle(route.Path, route.Handler).Methods(route.Methods...)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest("GET", "/api/within/0.5/0.5", nil))
+			require.Equal(t, 504, w.Code)
+		})
+	}
+}
@@ -2,6 +2,11 @@
 package debug
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"strconv"
 	"strings"
@@ -9,11 +14,22 @@ import (
 	"github.com/golang/geo/s2"
 	"github.com/twpayne/go-geom"
 	"github.com/twpayne/go-geom/encoding/geojson"
+
+	"github.com/akhenakh/insideout"
 )
 
+// maxBodySize max size of a POSTed geometry
+const maxBodySize = 10 << 20
+
 // S2CellQueryHandler returns a GeoJSON containing the cells passed in the query
 // ?cells=TokenID,...
+// or the cells covering a POSTed GeoJSON geometry or feature, see S2CoverHandler
 func S2CellQueryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		S2CoverHandler(w, r)
+		return
+	}
+
 	query := r.URL.Query()
 	sval := query.Get("cells")
 	if sval == "" {
@@ -38,6 +54,121 @@ func S2CellQueryHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write(CellUnionToGeoJSON(cu))
 }
 
+// S2CoverHandler returns a GeoJSON containing the cells covering the POSTed GeoJSON geometry or feature,
+// with the coverer parameters of the indexer in the query, defaulting to the inside cover flags:
+// ?min_level=10&max_level=16&max_cells=24&level_mod=1&interior=false
+func S2CoverHandler(w http.ResponseWriter, r *http.Request) {
+	opts := insideout.CoverOptions{MinLevel: 10, MaxLevel: 16, MaxCells: 24, LevelMod: 1}
+	query := r.URL.Query()
+	for name, v := range map[string]*int{
+		"min_level": &opts.MinLevel,
+		"max_level": &opts.MaxLevel,
+		"max_cells": &opts.MaxCells,
+		"level_mod": &opts.LevelMod,
+	} {
+		s := query.Get(name)
+		if s == "" {
+			continue
+		}
+		i, err := strconv.Atoi(s)
+		if err != nil {
+			http.Error(w, "invalid parameter "+name, 400)
+			return
+		}
+		*v = i
+	}
+	if err := opts.Validate(); err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	interior := query.Get("interior") == "true"
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxBodySize))
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	g, err := readGeometry(body)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	coverer := &s2.RegionCoverer{
+		MinLevel: opts.MinLevel,
+		MaxLevel: opts.MaxLevel,
+		MaxCells: opts.MaxCells,
+		LevelMod: opts.LevelMod,
+	}
+	cu, err := cover(g, coverer, interior)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(CellUnionToGeoJSON(cu))
+}
+
+// readGeometry returns the geometry of a GeoJSON geometry or feature
+func readGeometry(body []byte) (geom.T, error) {
+	var typed struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(body, &typed); err != nil {
+		return nil, fmt.Errorf("invalid GeoJSON: %w", err)
+	}
+	if typed.Type == "Feature" {
+		f := &geojson.Feature{}
+		if err := f.UnmarshalJSON(body); err != nil {
+			return nil, fmt.Errorf("invalid GeoJSON feature: %w", err)
+		}
+		if f.Geometry == nil {
+			return nil, errors.New("feature without geometry")
+		}
+		return f.Geometry, nil
+	}
+	var g geom.T
+	if err := geojson.Unmarshal(body, &g); err != nil {
+		return nil, fmt.Errorf("invalid GeoJSON geometry: %w", err)
+	}
+	return g, nil
+}
+
+// cover returns the cells covering g, a point, a line string or a polygon, only the interior cells with interior
+func cover(g geom.T, coverer *s2.RegionCoverer, interior bool) (s2.CellUnion, error) {
+	switch tg := g.(type) {
+	case *geom.Point:
+		// no interior
+		if interior {
+			return nil, nil
+		}
+		ll := s2.LatLngFromDegrees(tg.Y(), tg.X())
+		return s2.CellUnion{s2.CellIDFromLatLng(ll).Parent(coverer.MaxLevel)}, nil
+	case *geom.LineString:
+		pl := insideout.PolylineFromCoordinates(tg.FlatCoords())
+		if pl == nil {
+			return nil, errors.New("invalid line string not enough coordinates")
+		}
+		if interior {
+			return nil, nil
+		}
+		return coverer.Covering(pl), nil
+	case *geom.Polygon, *geom.MultiPolygon:
+		cus, err := insideout.GeoJSONCoverCellUnion(&geojson.Feature{Geometry: g}, coverer, interior)
+		if err != nil {
+			return nil, err
+		}
+		var cu s2.CellUnion
+		for _, c := range cus {
+			cu = append(cu, c...)
+		}
+		cu.Normalize()
+		return cu, nil
+	}
+	return nil, fmt.Errorf("unsupported geometry %T", g)
+}
+
 // CellUnionToGeoJSON helpers to display s2 cells on maps with GeoJSON
 // exports cell union into its GeoJSON representation
 func CellUnionToGeoJSON(cu s2.CellUnion) []byte {
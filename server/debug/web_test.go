@@ -0,0 +1,54 @@
+package debug
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom/encoding/geojson"
+)
+
+func TestS2CoverHandler(t *testing.T) {
+	polygon := `{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}`
+
+	cover := func(query, body string) (int, *geojson.FeatureCollection) {
+		w := httptest.NewRecorder()
+		S2CellQueryHandler(w, httptest.NewRequest("POST", "/debug/cells"+query, strings.NewReader(body)))
+		if w.Code != 200 {
+			return w.Code, nil
+		}
+		fc := &geojson.FeatureCollection{}
+		require.NoError(t, fc.UnmarshalJSON(w.Body.Bytes()))
+		return w.Code, fc
+	}
+
+	code, fc := cover("?min_level=5&max_level=8&max_cells=10", polygon)
+	require.Equal(t, 200, code)
+	require.NotEmpty(t, fc.Features)
+	require.True(t, len(fc.Features) <= 10)
+	for _, f := range fc.Features {
+		require.True(t, f.Properties["level"].(float64) >= 5 && f.Properties["level"].(float64) <= 8)
+	}
+
+	code, interior := cover("?min_level=5&max_level=8&max_cells=10&interior=true",
+		`{"type":"Feature","properties":{},"geometry":`+polygon+`}`)
+	require.Equal(t, 200, code)
+	require.NotEmpty(t, interior.Features)
+
+	code, fc = cover("?max_level=12", `{"type":"Point","coordinates":[2.35,48.85]}`)
+	require.Equal(t, 200, code)
+	require.Len(t, fc.Features, 1)
+	require.Equal(t, 12.0, fc.Features[0].Properties["level"])
+
+	code, fc = cover("", `{"type":"LineString","coordinates":[[2.35,48.85],[2.36,48.86]]}`)
+	require.Equal(t, 200, code)
+	require.NotEmpty(t, fc.Features)
+
+	code, _ = cover("?min_level=10&max_level=5", polygon)
+	require.Equal(t, 400, code)
+	code, _ = cover("?max_cells=a", polygon)
+	require.Equal(t, 400, code)
+	code, _ = cover("", `{"type":"Polygon"`)
+	require.Equal(t, 400, code)
+}
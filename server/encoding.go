@@ -0,0 +1,218 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/vmihailenco/msgpack/v4"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/insidesvc"
+)
+
+const (
+	jsonContentType     = "application/json"
+	protobufContentType = "application/x-protobuf"
+	msgpackContentType  = "application/msgpack"
+)
+
+// contentType returns the supported content type named by a Content-Type or Accept header value,
+// the first supported one in case of a list, empty when none is supported
+func contentType(header string) string {
+	for _, v := range strings.Split(header, ",") {
+		mt, _, err := mime.ParseMediaType(strings.TrimSpace(v))
+		if err != nil {
+			continue
+		}
+		switch mt {
+		case protobufContentType, "application/protobuf":
+			return protobufContentType
+		case msgpackContentType, "application/x-msgpack":
+			return msgpackContentType
+		case jsonContentType:
+			return jsonContentType
+		}
+	}
+	return ""
+}
+
+// writeMessage writes m encoded as ct, protobuf, msgpack or JSON as jsonpb
+func writeMessage(w http.ResponseWriter, ct string, m proto.Message) {
+	ct, b, err := encodeMessage(ct, m)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	w.Header().Set("Content-Type", ct)
+	w.Write(b)
+}
+
+// encodeMessage returns m encoded as ct and the content type used, JSON when ct is not supported
+func encodeMessage(ct string, m proto.Message) (string, []byte, error) {
+	var b []byte
+	var err error
+	switch ct {
+	case protobufContentType:
+		b, err = proto.Marshal(m)
+	case msgpackContentType:
+		b, err = msgpack.Marshal(msgpackMessage(m))
+	default:
+		ct = jsonContentType
+		var buf bytes.Buffer
+		err = (&jsonpb.Marshaler{OrigName: true}).Marshal(&buf, m)
+		b = buf.Bytes()
+	}
+	return ct, b, err
+}
+
+// readMessage decodes body encoded as ct into m
+func readMessage(ct string, body []byte, m proto.Message) error {
+	switch ct {
+	case protobufContentType:
+		return proto.Unmarshal(body, m)
+	case msgpackContentType:
+		req, ok := m.(*insidesvc.WithinBatchRequest)
+		if !ok {
+			return fmt.Errorf("unsupported msgpack message %T", m)
+		}
+		var mreq mpWithinBatchRequest
+		if err := msgpack.Unmarshal(body, &mreq); err != nil {
+			return err
+		}
+		for _, r := range mreq.Requests {
+			req.Requests = append(req.Requests, &insidesvc.WithinRequest{
+				Lat:              r.Lat,
+				Lng:              r.Lng,
+				RemoveGeometries: r.RemoveGeometries,
+				SelectProperties: r.SelectProperties,
+				Filter:           r.Filter,
+				Dataset:          r.Dataset,
+				BoundaryDistance: r.BoundaryDistance,
+				Exact:            r.Exact,
+			})
+		}
+		return nil
+	default:
+		return jsonpb.Unmarshal(bytes.NewReader(body), m)
+	}
+}
+
+// msgpack mirrors of the messages, with the proto field names and plain properties values
+type (
+	mpWithinBatchRequest struct {
+		Requests []mpWithinRequest `msgpack:"requests"`
+	}
+
+	mpWithinRequest struct {
+		Lat              float64 `msgpack:"lat"`
+		Lng              float64 `msgpack:"lng"`
+		RemoveGeometries bool    `msgpack:"remove_geometries"`
+		SelectProperties string  `msgpack:"select_properties"`
+		Filter           string  `msgpack:"filter"`
+		Dataset          string  `msgpack:"dataset"`
+		BoundaryDistance bool    `msgpack:"boundary_distance"`
+		Exact            bool    `msgpack:"exact"`
+	}
+
+	mpWithinBatchResponse struct {
+		Responses []*mpWithinResponse `msgpack:"responses"`
+	}
+
+	mpWithinResponse struct {
+		Point     mpPoint              `msgpack:"point"`
+		Responses []*mpFeatureResponse `msgpack:"responses"`
+		Debug     *mpWithinDebug       `msgpack:"debug,omitempty"`
+	}
+
+	mpWithinDebug struct {
+		CellToken   string              `msgpack:"cell_token"`
+		CellLevel   int32               `msgpack:"cell_level"`
+		Strategy    string              `msgpack:"strategy"`
+		Candidates  []mpWithinCandidate `msgpack:"candidates"`
+		IndexMicros int64               `msgpack:"index_micros"`
+		PipMicros   int64               `msgpack:"pip_micros"`
+		TotalMicros int64               `msgpack:"total_micros"`
+	}
+
+	mpWithinCandidate struct {
+		ID         uint32 `msgpack:"id"`
+		Pos        uint32 `msgpack:"pos"`
+		InsideCell bool   `msgpack:"inside_cell"`
+		Tested     bool   `msgpack:"tested"`
+		Accepted   bool   `msgpack:"accepted"`
+	}
+
+	mpPoint struct {
+		Lat float64 `msgpack:"lat"`
+		Lng float64 `msgpack:"lng"`
+	}
+
+	mpFeatureResponse struct {
+		ID               uint32    `msgpack:"id"`
+		Feature          mpFeature `msgpack:"feature"`
+		BoundaryDistance float64   `msgpack:"boundary_distance,omitempty"`
+		Exact            bool      `msgpack:"exact"`
+	}
+
+	mpFeature struct {
+		Geometry   *mpGeometry            `msgpack:"geometry,omitempty"`
+		Properties map[string]interface{} `msgpack:"properties"`
+	}
+
+	mpGeometry struct {
+		Type        string    `msgpack:"type"`
+		Coordinates []float64 `msgpack:"coordinates"`
+	}
+)
+
+// msgpackMessage returns the msgpack mirror of the within messages, m itself for the others
+func msgpackMessage(m proto.Message) interface{} {
+	switch rm := m.(type) {
+	case *insidesvc.WithinResponse:
+		return mpWithin(rm)
+	case *insidesvc.WithinBatchResponse:
+		resp := &mpWithinBatchResponse{}
+		for _, r := range rm.Responses {
+			resp.Responses = append(resp.Responses, mpWithin(r))
+		}
+		return resp
+	}
+	return m
+}
+
+func mpWithin(r *insidesvc.WithinResponse) *mpWithinResponse {
+	resp := &mpWithinResponse{Point: mpPoint{Lat: r.Point.GetLat(), Lng: r.Point.GetLng()}}
+	for _, fr := range r.Responses {
+		mfr := &mpFeatureResponse{
+			ID:               fr.Id,
+			Feature:          mpFeature{Properties: insideout.ValueToProperties(fr.Feature.GetProperties())},
+			BoundaryDistance: fr.BoundaryDistance,
+			Exact:            fr.Exact,
+		}
+		if g := fr.Feature.GetGeometry(); g != nil {
+			mfr.Feature.Geometry = &mpGeometry{Type: g.Type.String(), Coordinates: g.Coordinates}
+		}
+		resp.Responses = append(resp.Responses, mfr)
+	}
+	if d := r.Debug; d != nil {
+		resp.Debug = &mpWithinDebug{
+			CellToken:   d.CellToken,
+			CellLevel:   d.CellLevel,
+			Strategy:    d.Strategy,
+			IndexMicros: d.IndexMicros,
+			PipMicros:   d.PipMicros,
+			TotalMicros: d.TotalMicros,
+		}
+		for _, c := range d.Candidates {
+			resp.Debug.Candidates = append(resp.Debug.Candidates, mpWithinCandidate{
+				ID: c.Id, Pos: c.Pos, InsideCell: c.InsideCell, Tested: c.Tested, Accepted: c.Accepted,
+			})
+		}
+	}
+	return resp
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/akhenakh/insideout"
+)
+
+// expiryETagPeriod the expired features disappear from the responses before being swept, and the features
+// valid now change, the ETags of the responses change with this period
+const expiryETagPeriod = time.Minute
+
+var expiredCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "insided_server",
+	Name:      "expired_features_deleted_total",
+	Help:      "Expired features deleted from the storage by the sweeper",
+}, []string{"dataset"})
+
+// expired returns true if the feature with properties p has expired at now,
+// the features without a valid expiry property never expire
+func (s *Server) expired(p map[string]interface{}, now time.Time) bool {
+	if s.opts.ExpiryProperty == "" {
+		return false
+	}
+	t, ok := propertyTime(p[s.opts.ExpiryProperty])
+	return ok && !now.Before(t)
+}
+
+// live returns true if the feature with properties p is visible by the tenant of the request and has not expired
+func (s *Server) live(ctx context.Context, p map[string]interface{}) bool {
+	return visible(ctx, p) && !s.expired(p, time.Now())
+}
+
+// SweepExpired deletes the expired features from the storages and the indexes of the writable datasets,
+// returns the number of features deleted
+func (s *Server) SweepExpired(ctx context.Context) (int, error) {
+	if s.opts.ExpiryProperty == "" {
+		return 0, nil
+	}
+
+	count := 0
+	for _, name := range s.Datasets() {
+		n, err := s.sweepDataset(ctx, name, time.Now())
+		count += n
+		if err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+// sweepDataset deletes the features of the dataset name expired at now, the datasets not writable are skipped
+func (s *Server) sweepDataset(ctx context.Context, name string, now time.Time) (int, error) {
+	// the features are scanned without blocking the queries
+	s.mu.RLock()
+	ds, _, err := s.writableDataset(name)
+	if err != nil {
+		s.mu.RUnlock()
+		return 0, nil
+	}
+	var ids []uint32
+	err = ds.storage.LoadAllFeatures(func(fs *insideout.FeatureStorage, id uint32) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if s.expired(fs.Properties, now) {
+			ids = append(ids, id)
+		}
+		return nil
+	})
+	s.mu.RUnlock()
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// the dataset may have been reloaded and the features updated since the scan
+	ds, fw, err := s.writableDataset(name)
+	if err != nil {
+		return 0, nil
+	}
+	count := 0
+	for _, id := range ids {
+		f, err := ds.storage.LoadFeature(id)
+		if err != nil || f == nil || !s.expired(f.Properties, now) {
+			continue
+		}
+		found, err := s.removeFeature(ds, fw, id, f.Properties)
+		if err != nil {
+			return count, err
+		}
+		if found {
+			count++
+			expiredCounter.WithLabelValues(ds.name).Inc()
+		}
+	}
+	return count, nil
+}
+
+// RunExpirySweeper deletes the expired features every interval until ctx is done
+func (s *Server) RunExpirySweeper(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		count, err := s.SweepExpired(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			level.Error(s.logger).Log("msg", "failed to delete the expired features", "error", err)
+			continue
+		}
+		if count > 0 {
+			level.Info(s.logger).Log("msg", "expired features deleted", "count", count)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	structpb "github.com/golang/protobuf/ptypes/struct"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/insidesvc"
+)
+
+func TestServer_Expiry(t *testing.T) {
+	for _, strategy := range []string{insideout.DBStrategy, insideout.ShapeIndexStrategy} {
+		t.Run(strategy, func(t *testing.T) {
+			storage, clean := setupRW(t, "A", 0)
+			defer clean()
+
+			s, err := New(storage, log.NewNopLogger(), nil, Options{
+				Strategy:         strategy,
+				CacheCount:       10,
+				ResultCacheLevel: 5,
+				ResultCacheCount: 100,
+				ReadWrite:        true,
+				ExpiryProperty:   "expires",
+			})
+			require.NoError(t, err)
+
+			ctx := context.Background()
+			insert := func(name string, expires time.Time) uint32 {
+				f := squareFeature(name, 0)
+				f.Properties["expires"] = &structpb.Value{
+					Kind: &structpb.Value_StringValue{StringValue: expires.Format(time.RFC3339)},
+				}
+				resp, err := s.InsertFeature(ctx, &insidesvc.InsertFeatureRequest{Feature: f})
+				require.NoError(t, err)
+				return resp.Id
+			}
+			within := func() []string {
+				resp, err := s.Within(ctx, &insidesvc.WithinRequest{Lat: 0.5, Lng: 0.5})
+				require.NoError(t, err)
+				var names []string
+				for _, fresp := range resp.Responses {
+					names = append(names, fresp.Feature.Properties["name"].GetStringValue())
+				}
+				return names
+			}
+
+			require.Equal(t, []string{"A"}, within())
+			expired := insert("concert", time.Now().Add(-time.Minute))
+			insert("festival", time.Now().Add(time.Hour))
+
+			// the expired feature is ignored before being swept
+			require.ElementsMatch(t, []string{"A", "festival"}, within())
+			_, err = s.GetFeature(ctx, &insidesvc.GetFeatureRequest{Id: expired})
+			require.Equal(t, codes.NotFound, status.Code(err))
+
+			count, err := s.SweepExpired(ctx)
+			require.NoError(t, err)
+			require.Equal(t, 1, count)
+			_, err = storage.LoadFeature(expired)
+			require.Error(t, err)
+			require.ElementsMatch(t, []string{"A", "festival"}, within())
+
+			count, err = s.SweepExpired(ctx)
+			require.NoError(t, err)
+			require.Zero(t, count)
+		})
+	}
+}
+
+func TestServer_ExpiryStopOnFirstFound(t *testing.T) {
+	for _, strategy := range []string{
+		insideout.DBStrategy, insideout.InsideTreeStrategy, insideout.MemoryStrategy, insideout.HybridStrategy,
+	} {
+		strategy := strategy
+		t.Run(strategy, func(t *testing.T) {
+			storage, clean := setupRW(t, "A", 0)
+			defer clean()
+
+			s, err := New(storage, log.NewNopLogger(), nil, Options{
+				Strategy:         strategy,
+				ReadWrite:        true,
+				ExpiryProperty:   "expires",
+				StopOnFirstFound: true,
+			})
+			require.NoError(t, err)
+
+			ctx := context.Background()
+			// the expired square is found first, it must not end the search
+			for _, sf := range []struct {
+				name    string
+				expires time.Time
+			}{{"concert", time.Now().Add(-time.Minute)}, {"festival", time.Now().Add(time.Hour)}} {
+				f := squareFeature(sf.name, 10)
+				f.Properties["expires"] = &structpb.Value{
+					Kind: &structpb.Value_StringValue{StringValue: sf.expires.Format(time.RFC3339)},
+				}
+				_, err := s.InsertFeature(ctx, &insidesvc.InsertFeatureRequest{Feature: f})
+				require.NoError(t, err)
+			}
+
+			// in the inside cover and along the edges
+			for _, p := range [][2]float64{{10.5, 10.5}, {10.01, 10.01}} {
+				resp, err := s.Within(ctx, &insidesvc.WithinRequest{Lat: p[0], Lng: p[1]})
+				require.NoError(t, err)
+				require.Len(t, resp.Responses, 1, p)
+				require.Equal(t, "festival", resp.Responses[0].Feature.Properties["name"].GetStringValue())
+			}
+		})
+	}
+}
+
+func TestServer_SweepExpiredReadOnly(t *testing.T) {
+	storage, clean := setupRW(t, "A", 0)
+	defer clean()
+
+	s, err := New(storage, log.NewNopLogger(), nil, Options{Strategy: insideout.DBStrategy, ExpiryProperty: "expires"})
+	require.NoError(t, err)
+
+	// the read only datasets are skipped
+	count, err := s.SweepExpired(context.Background())
+	require.NoError(t, err)
+	require.Zero(t, count)
+}
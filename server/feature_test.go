@@ -0,0 +1,191 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/golang/geo/s2"
+	"github.com/golang/protobuf/proto"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/geojson"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/insidesvc"
+	"github.com/akhenakh/insideout/storage/bbolt"
+)
+
+func TestServer_GetFeature(t *testing.T) {
+	storage, clean := setupRW(t, "A", 0)
+	defer clean()
+
+	s, err := New(storage, log.NewNopLogger(), nil, Options{Strategy: insideout.DBStrategy, ReadWrite: true})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	mp := squareFeature("B", 10)
+	mp.Geometry = &insidesvc.Geometry{
+		Type:       insidesvc.Geometry_MULTIPOLYGON,
+		Geometries: []*insidesvc.Geometry{squareFeature("", 10).Geometry, squareFeature("", 20).Geometry},
+	}
+	wresp, err := s.InsertFeature(ctx, &insidesvc.InsertFeatureRequest{Feature: mp})
+	require.NoError(t, err)
+
+	f, err := s.GetFeature(ctx, &insidesvc.GetFeatureRequest{Id: 0})
+	require.NoError(t, err)
+	require.Equal(t, insidesvc.Geometry_POLYGON, f.Geometry.Type)
+	require.Equal(t, "A", f.Properties["name"].GetStringValue())
+	require.Equal(t, 0.0, f.Properties[insidesvc.FeatureIDProperty].GetNumberValue())
+
+	f, err = s.GetFeature(ctx, &insidesvc.GetFeatureRequest{Id: wresp.Id, Simplify: 100})
+	require.NoError(t, err)
+	require.Equal(t, insidesvc.Geometry_MULTIPOLYGON, f.Geometry.Type)
+	require.Len(t, f.Geometry.Geometries, 2)
+	require.Equal(t, "B", f.Properties["name"].GetStringValue())
+
+	_, err = s.GetFeature(ctx, &insidesvc.GetFeatureRequest{Id: 42})
+	require.Equal(t, codes.NotFound, status.Code(err))
+	_, err = s.GetFeature(ctx, &insidesvc.GetFeatureRequest{Id: 0, Dataset: "unknown"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+	_, err = s.GetFeature(ctx, &insidesvc.GetFeatureRequest{Id: 0, Simplify: -1})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	r := mux.NewRouter()
+	for _, route := range s.APIRoutes() {
+		r.Handle(route.Path, route.Handler).Methods(route.Methods...)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/feature/1?simplify=10", nil))
+	require.Equal(t, 200, w.Code)
+	gf := &geojson.Feature{}
+	require.NoError(t, gf.UnmarshalJSON(w.Body.Bytes()))
+	require.Equal(t, "1", gf.ID)
+	require.Equal(t, "B", gf.Properties["name"])
+	require.IsType(t, &geom.MultiPolygon{}, gf.Geometry)
+
+	req := httptest.NewRequest("GET", "/api/feature/0", nil)
+	req.Header.Set("Accept", protobufContentType)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, 200, w.Code)
+	pf := &insidesvc.Feature{}
+	require.NoError(t, proto.Unmarshal(w.Body.Bytes(), pf))
+	require.Equal(t, "A", pf.Properties["name"].GetStringValue())
+
+	for path, code := range map[string]int{
+		"/api/feature/42":             404,
+		"/api/feature/unknown/0":      404,
+		"/api/feature/a":              400,
+		"/api/feature/0?simplify=-10": 400,
+	} {
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		require.Equal(t, code, w.Code, path)
+	}
+}
+
+func TestServer_GetFeatureHoles(t *testing.T) {
+	tmpFile, err := ioutil.TempFile(os.TempDir(), "insideout-test-")
+	require.NoError(t, err)
+	tmpFile.Close()
+	defer os.Remove(tmpFile.Name())
+
+	wstorage, wclose, err := bbolt.NewStorage(tmpFile.Name(), log.NewNopLogger())
+	require.NoError(t, err)
+	fc := geojson.FeatureCollection{Features: []*geojson.Feature{{
+		Geometry: geom.NewPolygonFlat(geom.XY, []float64{
+			0, 0, 3, 0, 3, 3, 0, 3, 0, 0,
+			1, 1, 1, 2, 2, 2, 2, 1, 1, 1,
+		}, []int{10, 20}),
+		Properties: map[string]interface{}{"name": "A"},
+	}}}
+	icoverer := &s2.RegionCoverer{MinLevel: 5, MaxLevel: 12, MaxCells: 24}
+	ocoverer := &s2.RegionCoverer{MinLevel: 5, MaxLevel: 12, MaxCells: 16}
+	require.NoError(t, wstorage.Index(fc, icoverer, ocoverer, 100, "A", "unittest"))
+	require.NoError(t, wclose())
+
+	storage, sclose, err := bbolt.NewStorage(tmpFile.Name(), log.NewNopLogger())
+	require.NoError(t, err)
+	defer sclose()
+	s, err := New(storage, log.NewNopLogger(), nil, Options{Strategy: insideout.DBStrategy})
+	require.NoError(t, err)
+
+	f, err := s.GetFeature(context.Background(), &insidesvc.GetFeatureRequest{Id: 0})
+	require.NoError(t, err)
+	require.Equal(t, insidesvc.Geometry_POLYGON, f.Geometry.Type)
+	require.Equal(t, []uint32{10, 20}, f.Geometry.Ends)
+	hole := geom.NewLinearRingFlat(geom.XY, f.Geometry.Coordinates[10:20]).Bounds()
+	require.InDelta(t, 1, hole.Min(0), 1e-9)
+	require.InDelta(t, 1, hole.Min(1), 1e-9)
+	require.InDelta(t, 2, hole.Max(0), 1e-9)
+	require.InDelta(t, 2, hole.Max(1), 1e-9)
+}
+
+func TestServer_GetFeatureExternalID(t *testing.T) {
+	storage, clean := setupRW(t, "A", 0)
+	defer clean()
+
+	ctx := context.Background()
+	s, err := New(storage, log.NewNopLogger(), nil, Options{Strategy: insideout.DBStrategy, ReadWrite: true})
+	require.NoError(t, err)
+	_, err = s.GetFeature(ctx, &insidesvc.GetFeatureRequest{ExternalId: "A"})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	pi, err := insideout.BuildPropertyIndex(storage, []string{"name"})
+	require.NoError(t, err)
+	require.NoError(t, pi.CheckIDs("name"))
+	pi.IDProperty = "name"
+	require.NoError(t, storage.(insideout.PropertyIndexStore).StorePropertyIndex(pi))
+
+	s, err = New(storage, log.NewNopLogger(), nil, Options{Strategy: insideout.DBStrategy, ReadWrite: true})
+	require.NoError(t, err)
+
+	wresp, err := s.InsertFeature(ctx, &insidesvc.InsertFeatureRequest{Feature: squareFeature("B", 10)})
+	require.NoError(t, err)
+	f, err := s.GetFeature(ctx, &insidesvc.GetFeatureRequest{ExternalId: "B"})
+	require.NoError(t, err)
+	require.Equal(t, float64(wresp.Id), f.Properties[insidesvc.FeatureIDProperty].GetNumberValue())
+	_, err = s.GetFeature(ctx, &insidesvc.GetFeatureRequest{ExternalId: "C"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	// the external ids are unique
+	_, err = s.InsertFeature(ctx, &insidesvc.InsertFeatureRequest{Feature: squareFeature("A", 20)})
+	require.Equal(t, codes.AlreadyExists, status.Code(err))
+	_, err = s.UpdateFeature(ctx, &insidesvc.UpdateFeatureRequest{Id: wresp.Id, Feature: squareFeature("A", 10)})
+	require.Equal(t, codes.AlreadyExists, status.Code(err))
+	_, err = s.UpdateFeature(ctx, &insidesvc.UpdateFeatureRequest{Id: wresp.Id, Feature: squareFeature("B", 20)})
+	require.NoError(t, err)
+	noID := squareFeature("", 30)
+	delete(noID.Properties, "name")
+	_, err = s.InsertFeature(ctx, &insidesvc.InsertFeatureRequest{Feature: noID})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// the id property is kept when the stale index is rebuilt
+	s, err = New(storage, log.NewNopLogger(), nil, Options{Strategy: insideout.DBStrategy})
+	require.NoError(t, err)
+	r := mux.NewRouter()
+	for _, route := range s.APIRoutes() {
+		r.Handle(route.Path, route.Handler).Methods(route.Methods...)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/feature/B?external=true", nil))
+	require.Equal(t, 200, w.Code)
+	gf := &geojson.Feature{}
+	require.NoError(t, gf.UnmarshalJSON(w.Body.Bytes()))
+	require.Equal(t, "1", gf.ID)
+	require.Equal(t, "B", gf.Properties["name"])
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/feature/B", nil))
+	require.Equal(t, 400, w.Code)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/feature/C?external=true", nil))
+	require.Equal(t, 404, w.Code)
+}
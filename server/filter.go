@@ -0,0 +1,91 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// propertyFilter a list of conditions on feature properties, all of them must match
+type propertyFilter []propertyCondition
+
+type propertyCondition struct {
+	key    string
+	value  string
+	negate bool
+}
+
+// parsePropertyFilter parses a comma separated list of key=value or key!=value conditions
+func parsePropertyFilter(expr string) (propertyFilter, error) {
+	if expr == "" {
+		return nil, nil
+	}
+
+	var pf propertyFilter
+	for _, e := range strings.Split(expr, ",") {
+		var c propertyCondition
+		kv := strings.SplitN(e, "!=", 2)
+		if len(kv) == 2 {
+			c.negate = true
+		} else {
+			kv = strings.SplitN(e, "=", 2)
+		}
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid filter condition %q", e)
+		}
+		c.key = strings.TrimSpace(kv[0])
+		c.value = strings.TrimSpace(kv[1])
+		if c.key == "" {
+			return nil, errors.New("invalid filter, empty property name")
+		}
+		pf = append(pf, c)
+	}
+
+	return pf, nil
+}
+
+// Match returns true if all the conditions match the properties
+func (pf propertyFilter) Match(props map[string]interface{}) bool {
+	for _, c := range pf {
+		if c.match(props[c.key]) == c.negate {
+			return false
+		}
+	}
+	return true
+}
+
+func (c propertyCondition) match(v interface{}) bool {
+	switch tv := v.(type) {
+	case string:
+		return tv == c.value
+	case bool:
+		b, err := strconv.ParseBool(c.value)
+		return err == nil && b == tv
+	case float64:
+		f, err := strconv.ParseFloat(c.value, 64)
+		return err == nil && f == tv
+	case int:
+		f, err := strconv.ParseFloat(c.value, 64)
+		return err == nil && f == float64(tv)
+	case uint64:
+		f, err := strconv.ParseFloat(c.value, 64)
+		return err == nil && f == float64(tv)
+	case int64:
+		f, err := strconv.ParseFloat(c.value, 64)
+		return err == nil && f == float64(tv)
+	}
+	return false
+}
+
+// parseFields parses a comma separated list of properties names
+func parseFields(s string) []string {
+	if s == "" {
+		return nil
+	}
+	fields := strings.Split(s, ",")
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+	return fields
+}
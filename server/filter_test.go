@@ -0,0 +1,51 @@
+package server
+
+import (
+	"testing"
+)
+
+func TestPropertyFilter_Match(t *testing.T) {
+	props := map[string]interface{}{
+		"name":        "Bretagne",
+		"admin_level": float64(4),
+		"active":      true,
+	}
+
+	tests := []struct {
+		name    string
+		expr    string
+		want    bool
+		wantErr bool
+	}{
+		{"empty filter", "", true, false},
+		{"string match", "name=Bretagne", true, false},
+		{"string no match", "name=Normandie", false, false},
+		{"number match", "admin_level=4", true, false},
+		{"number no match", "admin_level=8", false, false},
+		{"bool match", "active=true", true, false},
+		{"negate", "admin_level!=8", true, false},
+		{"negate missing property", "missing!=8", true, false},
+		{"missing property", "missing=8", false, false},
+		{"and", "admin_level=4,name=Bretagne", true, false},
+		{"and no match", "admin_level=4,name=Normandie", false, false},
+		{"invalid", "admin_level", false, true},
+		{"invalid empty key", "=4", false, true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			pf, err := parsePropertyFilter(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parsePropertyFilter() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+			if got := pf.Match(props); got != tt.want {
+				t.Errorf("Match() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+// Package geofence detects tracked objects entering, exiting and dwelling in features,
+// remembering per object the features it was last inside
+package geofence
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var eventsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "insided_geofence",
+	Name:      "events_total",
+	Help:      "The total number of geofence events",
+}, []string{"type"})
+
+// ErrTooManyObjects is returned when a new object can't be tracked
+var ErrTooManyObjects = errors.New("too many tracked objects")
+
+// EventType the kind of an Event
+type EventType int
+
+const (
+	// Enter the object entered the feature
+	Enter EventType = iota
+	// Exit the object exited the feature
+	Exit
+	// Dwell the object has been inside the feature for the dwell time
+	Dwell
+)
+
+func (t EventType) String() string {
+	switch t {
+	case Enter:
+		return "ENTER"
+	case Exit:
+		return "EXIT"
+	case Dwell:
+		return "DWELL"
+	}
+	return "UNKNOWN"
+}
+
+// MarshalText encodes t as its name
+func (t EventType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// Feature a feature containing a position
+type Feature struct {
+	ID         uint32
+	Properties map[string]interface{}
+}
+
+// Position of a tracked object
+type Position struct {
+	ObjectID string
+	// Dataset the features come from, objects are tracked separately per dataset
+	Dataset string
+	Lat     float64
+	Lng     float64
+	Time    time.Time
+}
+
+// Event a change of the features containing a tracked object
+type Event struct {
+	Type       EventType              `json:"type"`
+	ObjectID   string                 `json:"object_id"`
+	Dataset    string                 `json:"dataset,omitempty"`
+	FeatureID  uint32                 `json:"feature_id"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+	Lat        float64                `json:"lat"`
+	Lng        float64                `json:"lng"`
+	Time       time.Time              `json:"time"`
+	// Duration the time spent inside the feature, for Exit and Dwell
+	Duration time.Duration `json:"-"`
+}
+
+// MarshalJSON encodes e with its duration in seconds
+func (e Event) MarshalJSON() ([]byte, error) {
+	type event Event
+	return json.Marshal(struct {
+		event
+		Duration float64 `json:"duration"`
+	}{event(e), e.Duration.Seconds()})
+}
+
+// Options for an Engine
+type Options struct {
+	// DwellTime the time an object must stay inside a feature to emit a Dwell event, 0 to disable Dwell events
+	DwellTime time.Duration
+
+	// TTL objects without positions for this long are forgotten without Exit events, 0 to never forget them
+	TTL time.Duration
+
+	// MaxObjects the max number of tracked objects, 0 for no limit
+	MaxObjects int
+}
+
+// Engine holds the state of the tracked objects
+type Engine struct {
+	opts Options
+
+	mu        sync.Mutex
+	objects   map[objectKey]*object
+	lastSweep time.Time
+}
+
+type objectKey struct {
+	dataset, id string
+}
+
+type object struct {
+	// last the time of the last position, updated the wall time it was received for the TTL
+	last, updated time.Time
+	inside        map[uint32]*presence
+}
+
+// presence of an object inside a feature
+type presence struct {
+	since      time.Time
+	properties map[string]interface{}
+	dwelled    bool
+}
+
+// New returns an Engine
+func New(opts Options) *Engine {
+	return &Engine{
+		opts:      opts,
+		objects:   make(map[objectKey]*object),
+		lastSweep: time.Now(),
+	}
+}
+
+// Update records features as the features containing the object at p
+// and returns the events since its previous position,
+// a position older than the previous one of the object is ignored
+func (e *Engine) Update(p Position, features []Feature) ([]Event, error) {
+	now := time.Now()
+	if p.Time.IsZero() {
+		p.Time = now
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.sweep(now)
+
+	key := objectKey{dataset: p.Dataset, id: p.ObjectID}
+	o, ok := e.objects[key]
+	if !ok {
+		if e.opts.MaxObjects > 0 && len(e.objects) >= e.opts.MaxObjects {
+			return nil, ErrTooManyObjects
+		}
+		o = &object{inside: make(map[uint32]*presence)}
+		e.objects[key] = o
+	} else if p.Time.Before(o.last) {
+		return nil, nil
+	}
+	o.last, o.updated = p.Time, now
+
+	var events []Event
+	event := func(t EventType, id uint32, pr *presence) {
+		events = append(events, Event{
+			Type:       t,
+			ObjectID:   p.ObjectID,
+			Dataset:    p.Dataset,
+			FeatureID:  id,
+			Properties: pr.properties,
+			Lat:        p.Lat,
+			Lng:        p.Lng,
+			Time:       p.Time,
+			Duration:   p.Time.Sub(pr.since),
+		})
+		eventsCounter.WithLabelValues(t.String()).Inc()
+	}
+
+	current := make(map[uint32]struct{}, len(features))
+	for _, f := range features {
+		current[f.ID] = struct{}{}
+		pr, ok := o.inside[f.ID]
+		if !ok {
+			pr = &presence{since: p.Time, properties: f.Properties}
+			o.inside[f.ID] = pr
+			event(Enter, f.ID, pr)
+			continue
+		}
+		pr.properties = f.Properties
+		if e.opts.DwellTime > 0 && !pr.dwelled && p.Time.Sub(pr.since) >= e.opts.DwellTime {
+			pr.dwelled = true
+			event(Dwell, f.ID, pr)
+		}
+	}
+
+	var exited []uint32
+	for id := range o.inside {
+		if _, ok := current[id]; !ok {
+			exited = append(exited, id)
+		}
+	}
+	sort.Slice(exited, func(i, j int) bool { return exited[i] < exited[j] })
+	for _, id := range exited {
+		event(Exit, id, o.inside[id])
+		delete(o.inside, id)
+	}
+
+	return events, nil
+}
+
+// Len returns the number of tracked objects
+func (e *Engine) Len() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.objects)
+}
+
+// sweep forgets the objects not seen for TTL, at most once per TTL, e.mu must be held
+func (e *Engine) sweep(now time.Time) {
+	if e.opts.TTL <= 0 || now.Sub(e.lastSweep) < e.opts.TTL {
+		return
+	}
+	for k, o := range e.objects {
+		if now.Sub(o.updated) > e.opts.TTL {
+			delete(e.objects, k)
+		}
+	}
+	e.lastSweep = now
+}
@@ -0,0 +1,110 @@
+package geofence
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEngine_Update(t *testing.T) {
+	e := New(Options{DwellTime: time.Minute, MaxObjects: 1})
+	t0 := time.Unix(1600000000, 0)
+	a := Feature{ID: 1, Properties: map[string]interface{}{"name": "A"}}
+	b := Feature{ID: 2}
+
+	events, err := e.Update(Position{ObjectID: "truck", Time: t0}, []Feature{a})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, Enter, events[0].Type)
+	require.Equal(t, "A", events[0].Properties["name"])
+
+	// still inside, not long enough to dwell
+	events, err = e.Update(Position{ObjectID: "truck", Time: t0.Add(30 * time.Second)}, []Feature{a})
+	require.NoError(t, err)
+	require.Empty(t, events)
+
+	events, err = e.Update(Position{ObjectID: "truck", Time: t0.Add(time.Minute)}, []Feature{a, b})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, Dwell, events[0].Type)
+	require.Equal(t, time.Minute, events[0].Duration)
+	require.Equal(t, Enter, events[1].Type)
+	require.Equal(t, uint32(2), events[1].FeatureID)
+
+	// dwell is emitted once per feature
+	events, err = e.Update(Position{ObjectID: "truck", Time: t0.Add(2 * time.Minute)}, []Feature{a, b})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, Dwell, events[0].Type)
+	require.Equal(t, uint32(2), events[0].FeatureID)
+
+	// out of order positions are ignored
+	events, err = e.Update(Position{ObjectID: "truck", Time: t0}, nil)
+	require.NoError(t, err)
+	require.Empty(t, events)
+
+	events, err = e.Update(Position{ObjectID: "truck", Time: t0.Add(3 * time.Minute)}, nil)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, Exit, events[0].Type)
+	require.Equal(t, uint32(1), events[0].FeatureID)
+	require.Equal(t, 3*time.Minute, events[0].Duration)
+	require.Equal(t, uint32(2), events[1].FeatureID)
+
+	_, err = e.Update(Position{ObjectID: "car", Time: t0}, nil)
+	require.Equal(t, ErrTooManyObjects, err)
+
+	// objects are tracked per dataset
+	e = New(Options{})
+	_, err = e.Update(Position{ObjectID: "truck", Dataset: "a"}, []Feature{a})
+	require.NoError(t, err)
+	events, err = e.Update(Position{ObjectID: "truck", Dataset: "b"}, []Feature{a})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, 2, e.Len())
+}
+
+func TestEngine_TTL(t *testing.T) {
+	e := New(Options{TTL: 10 * time.Millisecond})
+	_, err := e.Update(Position{ObjectID: "truck"}, nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, e.Len())
+
+	time.Sleep(20 * time.Millisecond)
+	_, err = e.Update(Position{ObjectID: "car"}, nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, e.Len())
+}
+
+func TestWebhook(t *testing.T) {
+	received := make(chan []map[string]interface{}, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var events []map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&events))
+		received <- events
+	}))
+	defer ts.Close()
+
+	wh := NewWebhook(ts.URL, time.Second, log.NewNopLogger())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go wh.Run(ctx)
+
+	wh.Send([]Event{{Type: Exit, ObjectID: "truck", FeatureID: 1, Duration: 90 * time.Second}})
+
+	select {
+	case events := <-received:
+		require.Len(t, events, 1)
+		require.Equal(t, "EXIT", events[0]["type"])
+		require.Equal(t, "truck", events[0]["object_id"])
+		require.Equal(t, 90.0, events[0]["duration"])
+	case <-time.After(5 * time.Second):
+		t.Fatal("no events received")
+	}
+}
@@ -0,0 +1,117 @@
+package geofence
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// webhookQueueSize the max number of events waiting to be posted
+const webhookQueueSize = 10000
+
+// webhookBatchSize the max number of events posted at once
+const webhookBatchSize = 500
+
+var (
+	webhookDroppedCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "insided_geofence",
+		Name:      "webhook_dropped_total",
+		Help:      "The total number of geofence events dropped because the webhook queue was full",
+	})
+	webhookErrorsCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "insided_geofence",
+		Name:      "webhook_errors_total",
+		Help:      "The total number of failed webhook posts",
+	})
+)
+
+// Sink receives the events of an Engine
+type Sink interface {
+	Send(events []Event)
+}
+
+// Webhook a Sink posting the events as a JSON array to an URL
+type Webhook struct {
+	url    string
+	client *http.Client
+	logger log.Logger
+	queue  chan Event
+}
+
+// NewWebhook returns a Webhook posting to url, call Run to post the events
+func NewWebhook(url string, timeout time.Duration, logger log.Logger) *Webhook {
+	return &Webhook{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+		logger: log.With(logger, "component", "geofence_webhook"),
+		queue:  make(chan Event, webhookQueueSize),
+	}
+}
+
+// Send queues events without blocking, the events are dropped when the queue is full
+func (w *Webhook) Send(events []Event) {
+	for _, e := range events {
+		select {
+		case w.queue <- e:
+		default:
+			webhookDroppedCounter.Inc()
+		}
+	}
+}
+
+// Run posts the queued events in batches until ctx is done
+func (w *Webhook) Run(ctx context.Context) error {
+	for {
+		var batch []Event
+		select {
+		case <-ctx.Done():
+			return nil
+		case e := <-w.queue:
+			batch = append(batch, e)
+		}
+	fill:
+		for len(batch) < webhookBatchSize {
+			select {
+			case e := <-w.queue:
+				batch = append(batch, e)
+			default:
+				break fill
+			}
+		}
+
+		if err := w.post(ctx, batch); err != nil {
+			webhookErrorsCounter.Inc()
+			level.Warn(w.logger).Log("msg", "failed to post geofence events", "error", err, "events", len(batch))
+		}
+	}
+}
+
+func (w *Webhook) post(ctx context.Context, events []Event) error {
+	b, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", w.url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook replied %s", resp.Status)
+	}
+	return nil
+}
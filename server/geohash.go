@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/golang/geo/s2"
+	structpb "github.com/golang/protobuf/ptypes/struct"
+	"github.com/gorilla/mux"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/insidesvc"
+)
+
+// GeohashHandler HTTP 1.1 Handler to query within the geohash cell {hash} returns GeoJSON,
+// at the center of the cell, or with mode=cell the features containing the whole cell
+func (s *Server) GeohashHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	ctx, span := tracer().Start(ctx, "GeohashHandler")
+	defer span.End()
+
+	rect, err := insideout.DecodeGeohash(mux.Vars(r)["hash"])
+	if err != nil {
+		http.Error(w, "invalid parameter hash", 400)
+		return
+	}
+
+	var cell *s2.Loop
+	switch r.URL.Query().Get("mode") {
+	case "", "center":
+	case "cell":
+		points := make([]s2.Point, 4)
+		for i := range points {
+			points[i] = s2.PointFromLatLng(rect.Vertex(i))
+		}
+		cell = s2.LoopFromPoints(points)
+	default:
+		http.Error(w, "invalid parameter mode", 400)
+		return
+	}
+
+	center := rect.Center()
+	s.writeWithin(ctx, w, r, center.Lat.Degrees(), center.Lng.Degrees(), cell)
+}
+
+// geohashResponses removes the responses whose polygon does not contain cell when not nil,
+// with precision adds the geohash of the centroid of their polygon
+func (s *Server) geohashResponses(
+	ctx context.Context, dataset string, resp *insidesvc.WithinResponse, cell *s2.Loop, precision int,
+) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ds, err := s.dataset(dataset)
+	if err != nil {
+		return err
+	}
+
+	kept := resp.Responses[:0]
+	for _, fresp := range resp.Responses {
+		f, err := s.feature(ctx, ds, fresp.Id)
+		if err != nil {
+			return err
+		}
+		pos := uint16(fresp.Feature.Properties[insidesvc.LoopIndexProperty].GetNumberValue())
+		l := f.Loops[pos]
+		if cell != nil && !l.Contains(cell) {
+			continue
+		}
+		// the cell must not intersect a hole
+		if cell != nil && intersectsHole(f, pos, cell) {
+			continue
+		}
+		if precision > 0 {
+			c := s2.LatLngFromPoint(s2.Point{Vector: l.Centroid().Normalize()})
+			fresp.Feature.Properties[insidesvc.CentroidGeohashProperty] = &structpb.Value{
+				Kind: &structpb.Value_StringValue{
+					StringValue: insideout.EncodeGeohash(c.Lat.Degrees(), c.Lng.Degrees(), precision),
+				},
+			}
+		}
+		kept = append(kept, fresp)
+	}
+	resp.Responses = kept
+	return nil
+}
+
+// intersectsHole returns true if cell intersects a hole of the loop pos of f
+func intersectsHole(f *insideout.Feature, pos uint16, cell *s2.Loop) bool {
+	for _, h := range holes(f, pos) {
+		if h.Intersects(cell) {
+			return true
+		}
+	}
+	return false
+}
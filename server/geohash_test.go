@@ -0,0 +1,64 @@
+package server
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom/encoding/geojson"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/insidesvc"
+)
+
+func TestServer_GeohashHandler(t *testing.T) {
+	a, clean := setup(t, "A", 0)
+	defer clean()
+
+	s, err := New(a, log.NewNopLogger(), nil, Options{Strategy: insideout.DBStrategy, CacheCount: 10, DatasetName: "A"})
+	require.NoError(t, err)
+
+	r := mux.NewRouter()
+	for _, route := range s.APIRoutes() {
+		r.Handle(route.Path, route.Handler).Methods(route.Methods...)
+	}
+	get := func(url string) (int, *geojson.FeatureCollection) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		if w.Code != 200 {
+			return w.Code, nil
+		}
+		fc := &geojson.FeatureCollection{}
+		require.NoError(t, fc.UnmarshalJSON(w.Body.Bytes()))
+		return w.Code, fc
+	}
+
+	hash := insideout.EncodeGeohash(0.5, 0.5, 5)
+	code, fc := get("/api/within/geohash/" + hash + "?mode=cell&centroid_geohash=6")
+	require.Equal(t, 200, code)
+	require.Len(t, fc.Features, 1)
+	require.Equal(t, "A", fc.Features[0].Properties["name"])
+	require.Equal(t, insideout.EncodeGeohash(0.5, 0.5, 6), fc.Features[0].Properties[insidesvc.CentroidGeohashProperty])
+
+	// s00 is larger than the polygon but its center is inside
+	code, _ = get("/api/within/geohash/s00")
+	require.Equal(t, 200, code)
+	code, _ = get("/api/within/geohash/s00?mode=cell")
+	require.Equal(t, 404, code)
+
+	code, _ = get("/api/within/A/geohash/" + hash)
+	require.Equal(t, 200, code)
+
+	code, _ = get("/api/within/geohash/s00a")
+	require.Equal(t, 400, code)
+	code, _ = get("/api/within/geohash/s00?mode=corner")
+	require.Equal(t, 400, code)
+
+	code, fc = get("/api/within/0.5/0.5?centroid_geohash=3")
+	require.Equal(t, 200, code)
+	require.Equal(t, "s00", fc.Features[0].Properties[insidesvc.CentroidGeohashProperty])
+	code, _ = get("/api/within/0.5/0.5?centroid_geohash=13")
+	require.Equal(t, 400, code)
+}
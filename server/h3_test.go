@@ -0,0 +1,93 @@
+//go:build cgo
+// +build cgo
+
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/geojson"
+	"github.com/uber/h3-go/v3"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/index/h3index"
+	"github.com/akhenakh/insideout/insidesvc"
+	"github.com/akhenakh/insideout/storage/bbolt"
+)
+
+func TestServer_WithinH3(t *testing.T) {
+	logger := log.NewNopLogger()
+
+	tmpFile, err := ioutil.TempFile(os.TempDir(), "insideout-test-")
+	require.NoError(t, err)
+	tmpFile.Close()
+	defer os.Remove(tmpFile.Name())
+
+	wstorage, wclose, err := bbolt.NewStorage(tmpFile.Name(), logger)
+	require.NoError(t, err)
+	fc := geojson.FeatureCollection{Features: []*geojson.Feature{{
+		Geometry:   geom.NewPolygonFlat(geom.XY, []float64{0, 0, 1, 0, 1, 1, 0, 1, 0, 0}, []int{10}),
+		Properties: map[string]interface{}{"name": "A"},
+	}}}
+	icoverer := &s2.RegionCoverer{MinLevel: 5, MaxLevel: 12, MaxCells: 24}
+	ocoverer := &s2.RegionCoverer{MinLevel: 5, MaxLevel: 12, MaxCells: 16}
+	require.NoError(t, wstorage.Index(fc, icoverer, ocoverer, 100, "A", "unittest"))
+	cover, err := h3index.Cover(wstorage, 6)
+	require.NoError(t, err)
+	require.NoError(t, wstorage.StoreH3Cover(cover))
+	require.NoError(t, wclose())
+
+	storage, sclose, err := bbolt.NewROStorage(tmpFile.Name(), logger)
+	require.NoError(t, err)
+	defer sclose()
+
+	s, err := New(storage, logger, nil, Options{Strategy: insideout.H3Strategy})
+	require.NoError(t, err)
+
+	for _, p := range [][2]float64{{0.5, 0.5}, {0.001, 0.999}} {
+		resp, err := s.Within(context.Background(), &insidesvc.WithinRequest{Lat: p[0], Lng: p[1], RemoveGeometries: true})
+		require.NoError(t, err)
+		require.Len(t, resp.Responses, 1)
+		require.Equal(t, h3.ToString(h3.FromGeo(h3.GeoCoord{Latitude: p[0], Longitude: p[1]}, 6)),
+			resp.Responses[0].Feature.Properties[insidesvc.H3CellProperty].GetStringValue())
+	}
+
+	resp, err := s.Within(context.Background(), &insidesvc.WithinRequest{Lat: 1.001, Lng: 0.5})
+	require.NoError(t, err)
+	require.Empty(t, resp.Responses)
+}
+
+func TestServer_H3WithoutCover(t *testing.T) {
+	storage, clean := setup(t, "A", 0)
+	defer clean()
+
+	_, err := New(storage, log.NewNopLogger(), nil, Options{Strategy: insideout.H3Strategy})
+	require.Error(t, err)
+}
+
+func TestServer_HolesH3(t *testing.T) {
+	path := indexHoles(t)
+	defer os.Remove(path)
+
+	wstorage, wclose, err := bbolt.NewStorage(path, log.NewNopLogger())
+	require.NoError(t, err)
+	cover, err := h3index.Cover(wstorage, 8)
+	require.NoError(t, err)
+	require.NoError(t, wstorage.StoreH3Cover(cover))
+	require.NoError(t, wclose())
+
+	storage, sclose, err := bbolt.NewROStorage(path, log.NewNopLogger())
+	require.NoError(t, err)
+	defer sclose()
+
+	s, err := New(storage, log.NewNopLogger(), nil, Options{Strategy: insideout.H3Strategy})
+	require.NoError(t, err)
+	checkHoles(t, s)
+}
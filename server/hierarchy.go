@@ -0,0 +1,77 @@
+package server
+
+import (
+	"sort"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/akhenakh/insideout"
+)
+
+// loadHierarchy returns the parent of each contained feature of storage, nil when it was not indexed with one
+func loadHierarchy(storage insideout.Store) (map[uint32]uint32, error) {
+	hs, ok := storage.(insideout.HierarchyStore)
+	if !ok {
+		return nil, nil
+	}
+	return hs.LoadHierarchy()
+}
+
+// ancestors returns the ids of the features containing id, from the outermost to its parent
+func (ds *dataset) ancestors(id uint32) []uint32 {
+	var ids []uint32
+	for {
+		parent, ok := ds.parents[id]
+		// a chain can't be longer than the features count, guards against a corrupted hierarchy
+		if !ok || len(ids) > len(ds.parents) {
+			break
+		}
+		ids = append(ids, parent)
+		id = parent
+	}
+	for i, j := 0, len(ids)-1; i < j; i, j = i+1, j-1 {
+		ids[i], ids[j] = ids[j], ids[i]
+	}
+	return ids
+}
+
+// arrangeHierarchy sets the ancestors of the matches, with hierarchy sorts them by depth keeping their order,
+// with deepestOnly removes the ancestors of other matches
+func arrangeHierarchy(ds *dataset, matches []match, hierarchy, deepestOnly bool) ([]match, error) {
+	if !hierarchy && !deepestOnly {
+		return matches, nil
+	}
+	if ds.parents == nil {
+		return nil, status.Errorf(codes.FailedPrecondition,
+			"dataset %s was indexed without hierarchy", ds.name)
+	}
+
+	for i := range matches {
+		matches[i].ancestors = ds.ancestors(matches[i].fid.ID)
+	}
+
+	if deepestOnly {
+		contains := make(map[uint32]bool)
+		for _, m := range matches {
+			for _, id := range m.ancestors {
+				contains[id] = true
+			}
+		}
+		deepest := matches[:0]
+		for _, m := range matches {
+			if !contains[m.fid.ID] {
+				deepest = append(deepest, m)
+			}
+		}
+		matches = deepest
+	}
+
+	if hierarchy {
+		sort.SliceStable(matches, func(i, j int) bool {
+			return len(matches[i].ancestors) < len(matches[j].ancestors)
+		})
+	}
+
+	return matches, nil
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/insidesvc"
+)
+
+func TestServer_WithinHierarchy(t *testing.T) {
+	storage, clean := setupNested(t, []nestedSquare{
+		{name: "city", size: 0.2},
+		{name: "country", size: 1},
+		{name: "region", size: 0.8},
+	})
+	defer clean()
+
+	s, err := New(storage, log.NewNopLogger(), nil, Options{Strategy: insideout.ShapeIndexStrategy})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	resp, err := s.Within(ctx, &insidesvc.WithinRequest{Lat: 0.5, Lng: 0.5, RemoveGeometries: true, Hierarchy: true})
+	require.NoError(t, err)
+	require.Len(t, resp.Responses, 3)
+	var names []string
+	for _, r := range resp.Responses {
+		names = append(names, r.Feature.Properties["name"].GetStringValue())
+	}
+	require.Equal(t, []string{"country", "region", "city"}, names)
+	require.Empty(t, resp.Responses[0].AncestorIds)
+	require.Equal(t, []uint32{1}, resp.Responses[1].AncestorIds)
+	require.Equal(t, []uint32{1, 2}, resp.Responses[2].AncestorIds)
+
+	resp, err = s.Within(ctx, &insidesvc.WithinRequest{Lat: 0.5, Lng: 0.5, RemoveGeometries: true, DeepestOnly: true})
+	require.NoError(t, err)
+	require.Len(t, resp.Responses, 1)
+	require.Equal(t, "city", resp.Responses[0].Feature.Properties["name"].GetStringValue())
+	require.Empty(t, resp.Responses[0].AncestorIds)
+
+	// outside the city
+	resp, err = s.Within(ctx, &insidesvc.WithinRequest{Lat: 0.2, Lng: 0.2, DeepestOnly: true})
+	require.NoError(t, err)
+	require.Len(t, resp.Responses, 1)
+	require.Equal(t, "region", resp.Responses[0].Feature.Properties["name"].GetStringValue())
+}
+
+func TestServer_WithinNoHierarchy(t *testing.T) {
+	storage, clean := setup(t, "A", 0)
+	defer clean()
+
+	s, err := New(storage, log.NewNopLogger(), nil, Options{Strategy: insideout.DBStrategy})
+	require.NoError(t, err)
+
+	_, err = s.Within(context.Background(), &insidesvc.WithinRequest{Lat: 0.5, Lng: 0.5, Hierarchy: true})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
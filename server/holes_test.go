@@ -0,0 +1,156 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/geojson"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/insidesvc"
+	"github.com/akhenakh/insideout/storage/bbolt"
+)
+
+// square returns a closed ring of the square from min to max, counterclockwise or clockwise for a hole
+func square(min, max float64, hole bool) []float64 {
+	if hole {
+		return []float64{min, min, min, max, max, max, max, min, min, min}
+	}
+	return []float64{min, min, max, min, max, max, min, max, min, min}
+}
+
+// indexHoles returns the path of a DB holding a park with a lake, a hole, and an island in the lake,
+// and the lake itself
+func indexHoles(t *testing.T) string {
+	tmpFile, err := ioutil.TempFile(os.TempDir(), "insideout-test-")
+	require.NoError(t, err)
+	tmpFile.Close()
+
+	wstorage, wclose, err := bbolt.NewStorage(tmpFile.Name(), log.NewNopLogger())
+	require.NoError(t, err)
+
+	park := append(square(0, 1, false), square(0.25, 0.75, true)...)
+	park = append(park, square(0.4, 0.6, false)...)
+	fc := geojson.FeatureCollection{Features: []*geojson.Feature{
+		{
+			Geometry:   geom.NewMultiPolygonFlat(geom.XY, park, [][]int{{10, 20}, {30}}),
+			Properties: map[string]interface{}{"name": "park"},
+		},
+		{
+			Geometry:   geom.NewPolygonFlat(geom.XY, square(0.25, 0.75, false), []int{10}),
+			Properties: map[string]interface{}{"name": "lake"},
+		},
+	}}
+
+	icoverer := &s2.RegionCoverer{MinLevel: 5, MaxLevel: 12, MaxCells: 24}
+	ocoverer := &s2.RegionCoverer{MinLevel: 5, MaxLevel: 12, MaxCells: 16}
+	require.NoError(t, wstorage.Index(fc, icoverer, ocoverer, 100, "holes", "unittest"))
+	require.NoError(t, wclose())
+
+	return tmpFile.Name()
+}
+
+// expectedHoles returns the sorted names of the features of indexHoles containing lat lng
+func expectedHoles(lat, lng float64) []string {
+	in := func(min, max float64) bool {
+		return lat > min && lat < max && lng > min && lng < max
+	}
+	var names []string
+	if in(0.25, 0.75) {
+		names = append(names, "lake")
+	}
+	if (in(0, 1) && !in(0.25, 0.75)) || in(0.4, 0.6) {
+		names = append(names, "park")
+	}
+	return names
+}
+
+// checkHoles queries s on a grid around the features of indexHoles and compares the results to the geometries
+func checkHoles(t *testing.T, s *Server) {
+	for lat := -0.075; lat < 1.1; lat += 0.05 {
+		for lng := -0.075; lng < 1.1; lng += 0.05 {
+			for _, exact := range []bool{false, true} {
+				resp, err := s.Within(context.Background(), &insidesvc.WithinRequest{
+					Lat: lat, Lng: lng, RemoveGeometries: true, Exact: exact,
+				})
+				require.NoError(t, err)
+				var names []string
+				for _, fresp := range resp.Responses {
+					names = append(names, fresp.Feature.Properties["name"].GetStringValue())
+				}
+				sort.Strings(names)
+				require.Equal(t, expectedHoles(lat, lng), names, "%v,%v exact %t", lat, lng, exact)
+			}
+		}
+	}
+}
+
+func TestServer_HolesStrategies(t *testing.T) {
+	path := indexHoles(t)
+	defer os.Remove(path)
+
+	storage, sclose, err := bbolt.NewROStorage(path, log.NewNopLogger())
+	require.NoError(t, err)
+	defer sclose()
+
+	for _, opts := range []Options{
+		{Strategy: insideout.DBStrategy},
+		{Strategy: insideout.DBStrategy, CacheCount: 10},
+		{Strategy: insideout.InsideTreeStrategy},
+		{Strategy: insideout.ShapeIndexStrategy},
+		{Strategy: insideout.ShapeIndexStrategy, ShapeIndexRegionLevel: 4},
+		{Strategy: insideout.MemoryStrategy},
+		{Strategy: insideout.HybridStrategy},
+	} {
+		name := opts.Strategy
+		if opts.CacheCount > 0 {
+			name += "_cache"
+		}
+		if opts.ShapeIndexRegionLevel > 0 {
+			name += "_regions"
+		}
+		t.Run(name, func(t *testing.T) {
+			s, err := New(storage, log.NewNopLogger(), nil, opts)
+			require.NoError(t, err)
+			checkHoles(t, s)
+		})
+	}
+}
+
+func TestServer_HolesFeature(t *testing.T) {
+	path := indexHoles(t)
+	defer os.Remove(path)
+
+	storage, sclose, err := bbolt.NewROStorage(path, log.NewNopLogger())
+	require.NoError(t, err)
+	defer sclose()
+
+	s, err := New(storage, log.NewNopLogger(), nil, Options{Strategy: insideout.DBStrategy})
+	require.NoError(t, err)
+
+	// in the lake, not in the park
+	resp, err := s.Intersect(context.Background(), &insidesvc.IntersectRequest{
+		Geometry:         &insidesvc.Geometry{Type: insidesvc.Geometry_POINT, Coordinates: []float64{0.3, 0.3}},
+		RemoveGeometries: true,
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Responses, 1)
+	require.Equal(t, "lake", resp.Responses[0].Feature.Properties["name"].GetStringValue())
+
+	// the whole geometry keeps the hole clockwise
+	f, err := storage.LoadFeature(0)
+	require.NoError(t, err)
+	g := wholeGeometry(f, 0).(*geom.MultiPolygon)
+	require.Equal(t, 2, g.NumPolygons())
+	require.Equal(t, 2, g.Polygon(0).NumLinearRings())
+	require.Equal(t, 1, g.Polygon(1).NumLinearRings())
+	require.Empty(t, insideout.ValidateGeometry(g))
+	require.InDelta(t, 0.75, f.PolygonArea(0)/f.Loops[0].Area(), 1e-3)
+}
@@ -1,20 +1,35 @@
 package server
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/gogo/protobuf/jsonpb"
+	"github.com/golang/geo/s2"
 	structpb "github.com/golang/protobuf/ptypes/struct"
 	"github.com/gorilla/mux"
-	"github.com/opentracing/opentracing-go"
 	"github.com/twpayne/go-geom"
 	"github.com/twpayne/go-geom/encoding/geojson"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/akhenakh/insideout"
 	"github.com/akhenakh/insideout/insidesvc"
 )
 
+// maxBodySize max size of a request body
+const maxBodySize = 10 << 20
+
+// maxBatchRequests max number of queries in a batch request
+const maxBatchRequests = 10000
+
 // DebugGetHandler HTTP 1.1 Handler to debug a feature
 func (s *Server) DebugGetHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -32,9 +47,12 @@ func (s *Server) DebugGetHandler(w http.ResponseWriter, r *http.Request) {
 
 	ctx := r.Context()
 
+	dataset := r.URL.Query().Get("dataset")
+
 	f, err := s.Get(ctx, &insidesvc.GetRequest{
 		Id:        uint32(fid),
 		LoopIndex: uint32(lidx),
+		Dataset:   dataset,
 	})
 	if err != nil {
 		http.Error(w, err.Error(), 500)
@@ -42,7 +60,13 @@ func (s *Server) DebugGetHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// get the s2 cells from the index
-	cs, err := s.storage.LoadCellStorage(uint32(fid))
+	var cs *insideout.CellsStorage
+	s.mu.RLock()
+	ds, err := s.dataset(dataset)
+	if err == nil {
+		cs, err = ds.storage.LoadCellStorage(uint32(fid))
+	}
+	s.mu.RUnlock()
 	if err != nil {
 		http.Error(w, err.Error(), 500)
 		return
@@ -73,8 +97,8 @@ func (s *Server) DebugGetHandler(w http.ResponseWriter, r *http.Request) {
 func (s *Server) WithinHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	span, ctx := opentracing.StartSpanFromContext(ctx, "WithinHandler")
-	defer span.Finish()
+	ctx, span := tracer().Start(ctx, "WithinHandler")
+	defer span.End()
 
 	vars := mux.Vars(r)
 
@@ -89,28 +113,487 @@ func (s *Server) WithinHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	s.writeWithin(ctx, w, r, lat, lng, nil)
+}
+
+// writeWithin answers the HTTP within request r for lat lng,
+// with cell only the features containing the whole cell are returned
+func (s *Server) writeWithin(ctx context.Context, w http.ResponseWriter, r *http.Request, lat, lng float64, cell *s2.Loop) {
+	vars := mux.Vars(r)
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format != "" && format != "geojson" {
+		http.Error(w, "invalid parameter format", 400)
+		return
+	}
+	var tolerance float64
+	var err error
+	if st := query.Get("simplify"); st != "" {
+		tolerance, err = strconv.ParseFloat(st, 64)
+		if err != nil || tolerance < 0 {
+			http.Error(w, "invalid parameter simplify", 400)
+			return
+		}
+	}
+
+	var order insidesvc.WithinRequest_Order
+	if o := query.Get("order"); o != "" {
+		v, ok := insidesvc.WithinRequest_Order_value[strings.ToUpper(o)]
+		if !ok {
+			http.Error(w, "invalid parameter order", 400)
+			return
+		}
+		order = insidesvc.WithinRequest_Order(v)
+	}
+	var limit int64
+	if l := query.Get("limit"); l != "" {
+		limit, err = strconv.ParseInt(l, 10, 32)
+		if err != nil || limit < 0 {
+			http.Error(w, "invalid parameter limit", 400)
+			return
+		}
+	}
+	var centroidPrecision int
+	if cp := query.Get("centroid_geohash"); cp != "" {
+		centroidPrecision, err = strconv.Atoi(cp)
+		if err != nil || centroidPrecision < 1 || centroidPrecision > insideout.MaxGeohashPrecision {
+			http.Error(w, "invalid parameter centroid_geohash", 400)
+			return
+		}
+	}
+
+	var at int64
+	if v := query.Get("at"); v != "" {
+		at, err = parseAt(v)
+		if err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+	}
+
 	resp, err := s.Within(ctx, &insidesvc.WithinRequest{
-		Lat: lat,
-		Lng: lng,
+		Lat:              lat,
+		Lng:              lng,
+		SelectProperties: query.Get("fields"),
+		Filter:           query.Get("filter"),
+		Dataset:          vars["dataset"],
+		BoundaryDistance: query.Get("boundary_distance") == "true",
+		Exact:            query.Get("exact") == "true",
+		Order:            order,
+		OrderProperty:    query.Get("order_property"),
+		OrderDesc:        query.Get("order_desc") == "true",
+		Limit:            int32(limit),
+		Hierarchy:        query.Get("hierarchy") == "true",
+		DeepestOnly:      query.Get("deepest_only") == "true",
+		Debug:            query.Get("debug") == "true",
+		At:               at,
+		Strategy:         query.Get("strategy"),
 	})
 	if err != nil {
+		if st, ok := status.FromError(err); ok {
+			switch st.Code() {
+			case codes.InvalidArgument, codes.FailedPrecondition:
+				http.Error(w, st.Message(), 400)
+				return
+			case codes.NotFound:
+				http.Error(w, st.Message(), 404)
+				return
+			case codes.DeadlineExceeded:
+				http.Error(w, st.Message(), 504)
+				return
+			}
+		}
 		http.Error(w, err.Error(), 500)
 		return
 	}
 
-	fc := &geojson.FeatureCollection{}
+	if cell != nil || centroidPrecision > 0 {
+		if err := s.geohashResponses(ctx, vars["dataset"], resp, cell, centroidPrecision); err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+	}
+
+	// the diagnostics are only in the messages, even without features
+	if resp.Debug != nil {
+		writeMessage(w, contentType(r.Header.Get("Accept")), resp)
+		return
+	}
 	if len(resp.Responses) == 0 {
 		http.Error(w, "{\"msg\": \"no features found at this location\"}", 404)
 		return
 	}
-	for _, fres := range resp.Responses {
-		f := &geojson.Feature{}
-		ng := geom.NewPolygonFlat(geom.XY, fres.Feature.Geometry.Coordinates, []int{len(fres.Feature.Geometry.Coordinates)})
-		f.Geometry = ng
-		f.Properties = insideout.ValueToProperties(fres.Feature.Properties)
-		fc.Features = append(fc.Features, f)
+	if ct := contentType(r.Header.Get("Accept")); ct == protobufContentType || ct == msgpackContentType {
+		writeMessage(w, ct, resp)
+		return
+	}
+
+	fc := featureCollection(resp.Responses)
+	for i, f := range fc.Features {
+		if format == "geojson" {
+			g, err := s.featureGeometry(ctx, vars["dataset"], resp.Responses[i].Id, tolerance)
+			if err != nil {
+				http.Error(w, err.Error(), 500)
+				return
+			}
+			f.ID = strconv.FormatUint(uint64(resp.Responses[i].Id), 10)
+			f.Geometry = g
+		}
+		f.Properties[insidesvc.ExactProperty] = resp.Responses[i].Exact
+		if query.Get("hierarchy") == "true" {
+			f.Properties[insidesvc.AncestorIDsProperty] = resp.Responses[i].AncestorIds
+		}
+		if query.Get("boundary_distance") == "true" {
+			f.Properties[insidesvc.BoundaryDistanceProperty] = resp.Responses[i].BoundaryDistance
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json, err := fc.MarshalJSON()
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	w.Write(json)
+}
+
+// WithinBatchHandler HTTP 1.1 Handler to query within for several points,
+// POST a WithinBatchRequest encoded as JSON, protobuf or msgpack according to Content-Type,
+// returns a WithinBatchResponse encoded according to Accept, JSON by default
+func (s *Server) WithinBatchHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	ctx, span := tracer().Start(ctx, "WithinBatchHandler")
+	defer span.End()
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxBodySize))
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	req := &insidesvc.WithinBatchRequest{}
+	if err := readMessage(contentType(r.Header.Get("Content-Type")), body, req); err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), 400)
+		return
+	}
+	if len(req.Requests) > maxBatchRequests {
+		http.Error(w, fmt.Sprintf("too many requests in batch, max %d", maxBatchRequests), 400)
+		return
+	}
+
+	dataset := mux.Vars(r)["dataset"]
+	resp := &insidesvc.WithinBatchResponse{Responses: make([]*insidesvc.WithinResponse, len(req.Requests))}
+	for i, wreq := range req.Requests {
+		if wreq.Dataset == "" {
+			wreq.Dataset = dataset
+		}
+		wresp, err := s.Within(ctx, wreq)
+		if err != nil {
+			if st, ok := status.FromError(err); ok {
+				switch st.Code() {
+				case codes.InvalidArgument:
+					http.Error(w, st.Message(), 400)
+					return
+				case codes.NotFound:
+					http.Error(w, st.Message(), 404)
+					return
+				case codes.DeadlineExceeded:
+					http.Error(w, st.Message(), 504)
+					return
+				}
+			}
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		resp.Responses[i] = wresp
+	}
+
+	writeMessage(w, contentType(r.Header.Get("Accept")), resp)
+}
+
+// NearestHandler HTTP 1.1 Handler to query the nearest feature returns GeoJSON
+func (s *Server) NearestHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	ctx, span := tracer().Start(ctx, "NearestHandler")
+	defer span.End()
+
+	vars := mux.Vars(r)
+
+	lat, err := strconv.ParseFloat(vars["lat"], 64)
+	if err != nil {
+		http.Error(w, "invalid parameter lat", 400)
+		return
+	}
+	lng, err := strconv.ParseFloat(vars["lng"], 64)
+	if err != nil {
+		http.Error(w, "invalid parameter lng", 400)
+		return
+	}
+
+	var maxDistance float64
+	if md := r.URL.Query().Get("max_distance"); md != "" {
+		maxDistance, err = strconv.ParseFloat(md, 64)
+		if err != nil {
+			http.Error(w, "invalid parameter max_distance", 400)
+			return
+		}
+	}
+
+	resp, err := s.Nearest(ctx, &insidesvc.NearestRequest{
+		Lat:         lat,
+		Lng:         lng,
+		MaxDistance: maxDistance,
+		Dataset:     vars["dataset"],
+	})
+	if err != nil {
+		if st, ok := status.FromError(err); ok {
+			switch st.Code() {
+			case codes.NotFound:
+				http.Error(w, st.Message(), 404)
+				return
+			case codes.DeadlineExceeded:
+				http.Error(w, st.Message(), 504)
+				return
+			}
+		}
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	if resp.Response == nil {
+		http.Error(w, "{\"msg\": \"no features found near this location\"}", 404)
+		return
+	}
+
+	fres := resp.Response
+	f := &geojson.Feature{}
+	ng := geom.NewPolygonFlat(geom.XY, fres.Feature.Geometry.Coordinates, []int{len(fres.Feature.Geometry.Coordinates)})
+	f.Geometry = ng
+	f.Properties = insideout.ValueToProperties(fres.Feature.Properties)
+	f.Properties[insidesvc.DistanceProperty] = resp.Distance
+	fc := &geojson.FeatureCollection{Features: []*geojson.Feature{f}}
+
+	w.Header().Set("Content-Type", "application/json")
+	json, err := fc.MarshalJSON()
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	w.Write(json)
+}
+
+// FeatureHandler HTTP 1.1 Handler returning the feature fid with all its polygons as GeoJSON,
+// or the Feature message according to Accept, ?simplify= the Douglas-Peucker tolerance in meters
+func (s *Server) FeatureHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	ctx, span := tracer().Start(ctx, "FeatureHandler")
+	defer span.End()
+
+	vars := mux.Vars(r)
+	req := &insidesvc.GetFeatureRequest{Dataset: vars["dataset"]}
+	// the stable id of the feature, the value of its id property
+	if external, _ := strconv.ParseBool(r.URL.Query().Get("external")); external {
+		req.ExternalId = vars["fid"]
+	} else {
+		fid, err := strconv.ParseUint(vars["fid"], 10, 32)
+		if err != nil {
+			http.Error(w, "invalid parameter fid", 400)
+			return
+		}
+		req.Id = uint32(fid)
+	}
+	if st := r.URL.Query().Get("simplify"); st != "" {
+		tolerance, err := strconv.ParseFloat(st, 64)
+		if err != nil || tolerance < 0 {
+			http.Error(w, "invalid parameter simplify", 400)
+			return
+		}
+		req.Simplify = tolerance
+	}
+
+	feature, err := s.GetFeature(ctx, req)
+	if err != nil {
+		if st, ok := status.FromError(err); ok {
+			switch st.Code() {
+			case codes.InvalidArgument, codes.FailedPrecondition:
+				http.Error(w, st.Message(), 400)
+				return
+			case codes.NotFound:
+				http.Error(w, st.Message(), 404)
+				return
+			}
+		}
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	if ct := contentType(r.Header.Get("Accept")); ct == protobufContentType || ct == msgpackContentType {
+		writeMessage(w, ct, feature)
+		return
+	}
+
+	f, err := geoJSONFeature(feature)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	f.ID = strconv.FormatUint(uint64(feature.Properties[insidesvc.FeatureIDProperty].GetNumberValue()), 10)
+
+	w.Header().Set("Content-Type", "application/json")
+	json, err := f.MarshalJSON()
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	w.Write(json)
+}
+
+// ListFeaturesHandler HTTP 1.1 Handler to find features by their properties,
+// ?prop.name=value for each property equal to a value, combined with the filter conditions,
+// returns a ListFeaturesResponse encoded according to Accept, JSON by default
+func (s *Server) ListFeaturesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	ctx, span := tracer().Start(ctx, "ListFeaturesHandler")
+	defer span.End()
+
+	query := r.URL.Query()
+	var conditions []string
+	if f := query.Get("filter"); f != "" {
+		conditions = append(conditions, f)
+	}
+	var props []string
+	for k := range query {
+		if strings.HasPrefix(k, "prop.") {
+			props = append(props, k)
+		}
+	}
+	sort.Strings(props)
+	for _, k := range props {
+		v := query.Get(k)
+		if strings.Contains(v, ",") {
+			http.Error(w, "invalid parameter "+k, 400)
+			return
+		}
+		conditions = append(conditions, strings.TrimPrefix(k, "prop.")+"="+v)
+	}
+
+	var limit int64
+	if l := query.Get("limit"); l != "" {
+		var err error
+		limit, err = strconv.ParseInt(l, 10, 32)
+		if err != nil || limit < 0 {
+			http.Error(w, "invalid parameter limit", 400)
+			return
+		}
+	}
+
+	resp, err := s.ListFeatures(ctx, &insidesvc.ListFeaturesRequest{
+		Filter:           strings.Join(conditions, ","),
+		SelectProperties: query.Get("fields"),
+		Limit:            int32(limit),
+		Cursor:           query.Get("cursor"),
+		Dataset:          mux.Vars(r)["dataset"],
+	})
+	if err != nil {
+		if st, ok := status.FromError(err); ok {
+			switch st.Code() {
+			case codes.InvalidArgument:
+				http.Error(w, st.Message(), 400)
+				return
+			case codes.NotFound:
+				http.Error(w, st.Message(), 404)
+				return
+			case codes.DeadlineExceeded:
+				http.Error(w, st.Message(), 504)
+				return
+			}
+		}
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	writeMessage(w, contentType(r.Header.Get("Accept")), resp)
+}
+
+// nextCursorHeader the HTTP header holding the cursor of the next page of a paginated response
+const nextCursorHeader = "X-Next-Cursor"
+
+// IntersectHandler HTTP 1.1 Handler to query features intersecting a geometry returns GeoJSON
+// POST a GeoJSON geometry or feature (Point, LineString, Polygon) or GET ?bbox=minLng,minLat,maxLng,maxLat,
+// paginated with ?limit= and ?cursor= set to the X-Next-Cursor header of the previous response
+func (s *Server) IntersectHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	ctx, span := tracer().Start(ctx, "IntersectHandler")
+	defer span.End()
+
+	var g *insidesvc.Geometry
+	if bbox := r.URL.Query().Get("bbox"); bbox != "" {
+		var err error
+		g, err = bboxGeometry(bbox)
+		if err != nil {
+			http.Error(w, "invalid parameter bbox", 400)
+			return
+		}
+	} else {
+		body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxBodySize))
+		if err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+		g, err = geoJSONGeometry(body)
+		if err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
 	}
 
+	var limit int64
+	if l := r.URL.Query().Get("limit"); l != "" {
+		var err error
+		limit, err = strconv.ParseInt(l, 10, 32)
+		if err != nil || limit < 0 {
+			http.Error(w, "invalid parameter limit", 400)
+			return
+		}
+	}
+
+	resp, err := s.Intersect(ctx, &insidesvc.IntersectRequest{
+		Geometry: g,
+		Dataset:  mux.Vars(r)["dataset"],
+		Limit:    int32(limit),
+		Cursor:   r.URL.Query().Get("cursor"),
+	})
+	if err != nil {
+		if st, ok := status.FromError(err); ok {
+			switch st.Code() {
+			case codes.InvalidArgument:
+				http.Error(w, st.Message(), 400)
+				return
+			case codes.NotFound:
+				http.Error(w, st.Message(), 404)
+				return
+			case codes.DeadlineExceeded:
+				http.Error(w, st.Message(), 504)
+				return
+			}
+		}
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	if len(resp.Responses) == 0 {
+		http.Error(w, "{\"msg\": \"no features found intersecting this geometry\"}", 404)
+		return
+	}
+	fc := featureCollection(resp.Responses)
+
+	if resp.NextCursor != "" {
+		w.Header().Set(nextCursorHeader, resp.NextCursor)
+	}
 	w.Header().Set("Content-Type", "application/json")
 	json, err := fc.MarshalJSON()
 	if err != nil {
@@ -119,3 +602,113 @@ func (s *Server) WithinHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	w.Write(json)
 }
+
+// featureCollection returns a GeoJSON FeatureCollection from features responses
+func featureCollection(resps []*insidesvc.FeatureResponse) *geojson.FeatureCollection {
+	fc := &geojson.FeatureCollection{}
+	for _, fres := range resps {
+		f := &geojson.Feature{}
+		ng := geom.NewPolygonFlat(geom.XY, fres.Feature.Geometry.Coordinates, []int{len(fres.Feature.Geometry.Coordinates)})
+		f.Geometry = ng
+		f.Properties = insideout.ValueToProperties(fres.Feature.Properties)
+		fc.Features = append(fc.Features, f)
+	}
+	return fc
+}
+
+// featureGeometry returns all the polygons of the feature id of dataset,
+// simplified with Douglas-Peucker when toleranceMeters is not 0
+func (s *Server) featureGeometry(ctx context.Context, dataset string, id uint32, toleranceMeters float64) (geom.T, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ds, err := s.dataset(dataset)
+	if err != nil {
+		return nil, err
+	}
+	f, err := s.feature(ctx, ds, id)
+	if err != nil {
+		return nil, err
+	}
+	if !s.live(ctx, f.Properties) {
+		return nil, status.Error(codes.NotFound, "can't found feature")
+	}
+	return wholeGeometry(f, toleranceMeters), nil
+}
+
+// wholeGeometry returns all the polygons of f with their holes, a polygon or a multipolygon,
+// simplified with Douglas-Peucker when toleranceMeters is not 0
+func wholeGeometry(f *insideout.Feature, toleranceMeters float64) geom.T {
+	var g geom.T
+	if len(f.Loops) == 1 {
+		c, ends := insideout.CoordinatesFromPolygon(f.Loops[0], holes(f, 0))
+		g = geom.NewPolygonFlat(geom.XY, c, ends)
+	} else {
+		var flat []float64
+		endss := make([][]int, len(f.Loops))
+		for i, l := range f.Loops {
+			c, ends := insideout.CoordinatesFromPolygon(l, holes(f, uint16(i)))
+			for _, e := range ends {
+				endss[i] = append(endss[i], len(flat)+e)
+			}
+			flat = append(flat, c...)
+		}
+		g = geom.NewMultiPolygonFlat(geom.XY, flat, endss)
+	}
+
+	if toleranceMeters > 0 {
+		g, _, _ = insideout.SimplifyGeometry(g, toleranceMeters)
+	}
+	return g
+}
+
+// bboxGeometry returns a polygon from a minLng,minLat,maxLng,maxLat bbox
+func bboxGeometry(bbox string) (*insidesvc.Geometry, error) {
+	vals := strings.Split(bbox, ",")
+	if len(vals) != 4 {
+		return nil, errors.New("invalid bbox")
+	}
+	var c [4]float64
+	for i, v := range vals {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, err
+		}
+		c[i] = f
+	}
+	minLng, minLat, maxLng, maxLat := c[0], c[1], c[2], c[3]
+	return &insidesvc.Geometry{
+		Type: insidesvc.Geometry_POLYGON,
+		Coordinates: []float64{
+			minLng, minLat,
+			maxLng, minLat,
+			maxLng, maxLat,
+			minLng, maxLat,
+			minLng, minLat,
+		},
+	}, nil
+}
+
+// geoJSONGeometry decodes a GeoJSON geometry or feature
+func geoJSONGeometry(body []byte) (*insidesvc.Geometry, error) {
+	var gt geom.T
+	if err := geojson.Unmarshal(body, &gt); err != nil || gt == nil {
+		f := &geojson.Feature{}
+		if err := f.UnmarshalJSON(body); err != nil {
+			return nil, errors.New("invalid GeoJSON")
+		}
+		gt = f.Geometry
+	}
+
+	switch g := gt.(type) {
+	case *geom.Point:
+		return &insidesvc.Geometry{Type: insidesvc.Geometry_POINT, Coordinates: g.FlatCoords()}, nil
+	case *geom.LineString:
+		return &insidesvc.Geometry{Type: insidesvc.Geometry_LINESTRING, Coordinates: g.FlatCoords()}, nil
+	case *geom.Polygon:
+		// only supports outer ring
+		return &insidesvc.Geometry{Type: insidesvc.Geometry_POLYGON, Coordinates: g.LinearRing(0).FlatCoords()}, nil
+	}
+
+	return nil, errors.New("unsupported GeoJSON geometry")
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/geojson"
+	"github.com/vmihailenco/msgpack/v4"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/insidesvc"
+)
+
+func TestServer_WithinHandlerGeoJSON(t *testing.T) {
+	a, clean := setup(t, "A", 0)
+	defer clean()
+
+	s, err := New(a, log.NewNopLogger(), nil, Options{Strategy: insideout.DBStrategy, CacheCount: 10})
+	require.NoError(t, err)
+
+	r := mux.NewRouter()
+	for _, route := range s.APIRoutes() {
+		r.Handle(route.Path, route.Handler).Methods(route.Methods...)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/within/0.5/0.5?format=geojson&simplify=10", nil))
+	require.Equal(t, 200, w.Code)
+
+	fc := &geojson.FeatureCollection{}
+	require.NoError(t, fc.UnmarshalJSON(w.Body.Bytes()))
+	require.Len(t, fc.Features, 1)
+	require.Equal(t, "0", fc.Features[0].ID)
+	require.Equal(t, "A", fc.Features[0].Properties["name"])
+	require.IsType(t, &geom.Polygon{}, fc.Features[0].Geometry)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/within/0.5/0.5?format=kml", nil))
+	require.Equal(t, 400, w.Code)
+}
+
+func TestServer_WithinHandlerEncodings(t *testing.T) {
+	a, clean := setup(t, "A", 0)
+	defer clean()
+
+	s, err := New(a, log.NewNopLogger(), nil, Options{Strategy: insideout.DBStrategy, CacheCount: 10})
+	require.NoError(t, err)
+
+	r := mux.NewRouter()
+	for _, route := range s.APIRoutes() {
+		r.Handle(route.Path, route.Handler).Methods(route.Methods...)
+	}
+
+	// protobuf
+	req := httptest.NewRequest("GET", "/api/within/0.5/0.5", nil)
+	req.Header.Set("Accept", "application/x-protobuf")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, 200, w.Code)
+	require.Equal(t, protobufContentType, w.Header().Get("Content-Type"))
+	resp := &insidesvc.WithinResponse{}
+	require.NoError(t, proto.Unmarshal(w.Body.Bytes(), resp))
+	require.Len(t, resp.Responses, 1)
+	require.Equal(t, "A", resp.Responses[0].Feature.Properties["name"].GetStringValue())
+
+	// msgpack
+	req = httptest.NewRequest("GET", "/api/within/0.5/0.5", nil)
+	req.Header.Set("Accept", "application/msgpack, application/json;q=0.5")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, 200, w.Code)
+	var mresp mpWithinResponse
+	require.NoError(t, msgpack.Unmarshal(w.Body.Bytes(), &mresp))
+	require.Len(t, mresp.Responses, 1)
+	require.Equal(t, "A", mresp.Responses[0].Feature.Properties["name"])
+	require.Equal(t, "POLYGON", mresp.Responses[0].Feature.Geometry.Type)
+
+	// debug as JSON, even without features
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/within/10/10?debug=true", nil))
+	require.Equal(t, 200, w.Code)
+	resp = &insidesvc.WithinResponse{}
+	require.NoError(t, jsonpb.Unmarshal(w.Body, resp))
+	require.Empty(t, resp.Responses)
+	require.Equal(t, insideout.DBStrategy, resp.Debug.Strategy)
+
+	// batch as JSON
+	body := `{"requests": [{"lat": 0.5, "lng": 0.5}, {"lat": 10, "lng": 10}]}`
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/api/within", strings.NewReader(body)))
+	require.Equal(t, 200, w.Code)
+	bresp := &insidesvc.WithinBatchResponse{}
+	require.NoError(t, jsonpb.Unmarshal(w.Body, bresp))
+	require.Len(t, bresp.Responses, 2)
+	require.Len(t, bresp.Responses[0].Responses, 1)
+	require.Len(t, bresp.Responses[1].Responses, 0)
+
+	// batch as protobuf
+	b, err := proto.Marshal(&insidesvc.WithinBatchRequest{Requests: []*insidesvc.WithinRequest{{Lat: 0.5, Lng: 0.5}}})
+	require.NoError(t, err)
+	req = httptest.NewRequest("POST", "/api/within", bytes.NewReader(b))
+	req.Header.Set("Content-Type", protobufContentType)
+	req.Header.Set("Accept", protobufContentType)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, 200, w.Code)
+	bresp = &insidesvc.WithinBatchResponse{}
+	require.NoError(t, proto.Unmarshal(w.Body.Bytes(), bresp))
+	require.Len(t, bresp.Responses, 1)
+	require.Len(t, bresp.Responses[0].Responses, 1)
+
+	// batch as msgpack to an unknown dataset
+	b, err = msgpack.Marshal(&mpWithinBatchRequest{Requests: []mpWithinRequest{{Lat: 0.5, Lng: 0.5}}})
+	require.NoError(t, err)
+	req = httptest.NewRequest("POST", "/api/within/unknown", bytes.NewReader(b))
+	req.Header.Set("Content-Type", msgpackContentType)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, 404, w.Code)
+}
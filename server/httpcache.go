@@ -0,0 +1,116 @@
+package server
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/akhenakh/insideout/server/tenant"
+)
+
+// timezoneETagPeriod the UTC offsets change on the quarter hours, the ETags of the responses holding them too
+const timezoneETagPeriod = 15 * time.Minute
+
+// CacheValidated returns h answering the GET requests with an ETag derived from the version of the queried dataset
+// and Cache-Control with the HTTPCacheMaxAge option, the requests with a matching If-None-Match get 304 Not Modified.
+// The responses vary with Accept, and with the tenant API key header when the tenants are enabled
+func (s *Server) CacheValidated(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// the debug responses hold the timings of the lookup
+		debug, _ := strconv.ParseBool(r.URL.Query().Get("debug"))
+		if s.opts.HTTPCacheMaxAge < 0 || debug || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+			h(w, r)
+			return
+		}
+
+		name := mux.Vars(r)["dataset"]
+		if name == "" {
+			name = r.URL.Query().Get("dataset")
+		}
+		s.mu.RLock()
+		ds, err := s.dataset(name)
+		var version string
+		if err == nil {
+			version = ds.version
+		}
+		s.mu.RUnlock()
+		if err != nil {
+			// the unknown dataset is reported by h
+			h(w, r)
+			return
+		}
+
+		// the URL identifies the response, the representation and the visible features vary with the headers
+		hash := fnv.New64a()
+		fmt.Fprint(hash, ds.name, version, r.Header.Get("Accept"))
+		cacheControl := "public"
+		if t, ok := tenant.FromContext(r.Context()); ok {
+			fmt.Fprint(hash, t.Name)
+			cacheControl = "private"
+		}
+		if s.opts.TimezoneProperty != "" {
+			fmt.Fprint(hash, time.Now().Truncate(timezoneETagPeriod).Unix())
+		}
+		if s.opts.ExpiryProperty != "" || s.opts.ValidFromProperty != "" || s.opts.ValidToProperty != "" {
+			fmt.Fprint(hash, time.Now().Truncate(expiryETagPeriod).Unix())
+		}
+		if maxAge := int(s.opts.HTTPCacheMaxAge.Seconds()); maxAge > 0 {
+			cacheControl += fmt.Sprintf(", max-age=%d", maxAge)
+		} else {
+			cacheControl += ", no-cache"
+		}
+		etag := fmt.Sprintf(`W/"%x"`, hash.Sum64())
+
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", cacheControl)
+		w.Header().Add("Vary", "Accept")
+		if s.opts.Tenants && s.opts.TenantKeyHeader != "" {
+			w.Header().Add("Vary", s.opts.TenantKeyHeader)
+		}
+		if etagMatch(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		h(&cacheWriter{ResponseWriter: w}, r)
+	}
+}
+
+// etagMatch returns true if the If-None-Match header value matches etag, weak comparison
+func etagMatch(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// cacheWriter removes the cache headers of the responses not depending on the dataset only, the errors,
+// not found is kept, a point outside of any feature stays outside until the dataset changes
+type cacheWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (cw *cacheWriter) WriteHeader(status int) {
+	if !cw.wroteHeader && status != http.StatusOK && status != http.StatusNotFound {
+		cw.Header().Del("ETag")
+		cw.Header().Set("Cache-Control", "no-store")
+	}
+	cw.wroteHeader = true
+	cw.ResponseWriter.WriteHeader(status)
+}
+
+func (cw *cacheWriter) Write(b []byte) (int, error) {
+	cw.wroteHeader = true
+	return cw.ResponseWriter.Write(b)
+}
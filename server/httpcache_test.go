@@ -0,0 +1,124 @@
+package server
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/insidesvc"
+	"github.com/akhenakh/insideout/server/tenant"
+)
+
+func TestServer_CacheValidated(t *testing.T) {
+	storage, clean := setupRW(t, "A", 0)
+	defer clean()
+
+	s, err := New(storage, log.NewNopLogger(), nil, Options{
+		Strategy: insideout.DBStrategy, ReadWrite: true, HTTPCacheMaxAge: time.Hour,
+	})
+	require.NoError(t, err)
+
+	r := mux.NewRouter()
+	for _, route := range s.APIRoutes() {
+		r.Handle(route.Path, route.Handler).Methods(route.Methods...)
+	}
+	get := func(path, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/api/within/0.5/0.5", "")
+	require.Equal(t, 200, w.Code)
+	require.Equal(t, "public, max-age=3600", w.Header().Get("Cache-Control"))
+	require.Equal(t, []string{"Accept"}, w.Header()["Vary"])
+	etag := w.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	// unchanged
+	w = get("/api/within/0.5/0.5", etag)
+	require.Equal(t, 304, w.Code)
+	require.Empty(t, w.Body.Bytes())
+	require.Equal(t, []string{"Accept"}, w.Header()["Vary"])
+	w = get("/api/within/0.5/0.5", `"other", `+etag)
+	require.Equal(t, 304, w.Code)
+
+	// outside of the features, cached until the dataset changes
+	w = get("/api/within/20.5/20.5", "")
+	require.Equal(t, 404, w.Code)
+	require.Equal(t, etag, w.Header().Get("ETag"))
+
+	// not cached
+	w = get("/api/within/north/0.5", "")
+	require.Equal(t, 400, w.Code)
+	require.Empty(t, w.Header().Get("ETag"))
+	require.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	w = get("/api/within/0.5/0.5?debug=true", "")
+	require.Empty(t, w.Header().Get("ETag"))
+
+	// a write changes the version of the dataset
+	_, err = s.InsertFeature(context.Background(), &insidesvc.InsertFeatureRequest{Feature: squareFeature("B", 20)})
+	require.NoError(t, err)
+	w = get("/api/within/0.5/0.5", etag)
+	require.Equal(t, 200, w.Code)
+	require.NotEqual(t, etag, w.Header().Get("ETag"))
+
+	// per tenant
+	req := httptest.NewRequest("GET", "/api/within/0.5/0.5", nil)
+	req = req.WithContext(tenant.NewContext(req.Context(), &tenant.Tenant{Name: "acme"}))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, "private, max-age=3600", w.Header().Get("Cache-Control"))
+	require.NotEqual(t, etag, w.Header().Get("ETag"))
+
+	// always revalidated
+	s.opts.HTTPCacheMaxAge = 0
+	require.Equal(t, "public, no-cache", get("/api/within/0.5/0.5", "").Header().Get("Cache-Control"))
+
+	// disabled
+	s.opts.HTTPCacheMaxAge = -1
+	w = get("/api/within/0.5/0.5", "")
+	require.Empty(t, w.Header().Get("ETag"))
+	require.Empty(t, w.Header().Get("Cache-Control"))
+}
+
+func TestServer_CacheValidatedVary(t *testing.T) {
+	storage, clean := setupRW(t, "A", 0)
+	defer clean()
+
+	s, err := New(storage, log.NewNopLogger(), nil, Options{
+		Strategy: insideout.DBStrategy, ReadWrite: true, HTTPCacheMaxAge: time.Hour,
+		Tenants: true, TenantKeyHeader: "X-API-Key",
+	})
+	require.NoError(t, err)
+
+	r := mux.NewRouter()
+	for _, route := range s.APIRoutes() {
+		r.Handle(route.Path, route.Handler).Methods(route.Methods...)
+	}
+	get := func(accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/within/0.5/0.5", nil)
+		req.Header.Set("Accept", accept)
+		req = req.WithContext(tenant.NewContext(req.Context(), &tenant.Tenant{Name: "acme"}))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// the representations differ, so do their ETags, the caches must key them by Accept and by tenant
+	w := get("application/json")
+	require.Equal(t, []string{"Accept", "X-API-Key"}, w.Header()["Vary"])
+	pw := get(protobufContentType)
+	require.Equal(t, []string{"Accept", "X-API-Key"}, pw.Header()["Vary"])
+	require.NotEqual(t, w.Header().Get("ETag"), pw.Header().Get("ETag"))
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/insidesvc"
+)
+
+// rect returns the coordinates of the rectangle polygon from lng0 lat0 to lng1 lat1
+func rect(lng0, lat0, lng1, lat1 float64) []float64 {
+	return []float64{lng0, lat0, lng1, lat0, lng1, lat1, lng0, lat1, lng0, lat0}
+}
+
+func TestServer_Intersect(t *testing.T) {
+	storage, clean := setup(t, "A", 0)
+	defer clean()
+
+	s, err := New(storage, log.NewNopLogger(), nil, Options{Strategy: insideout.DBStrategy})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		g    *insidesvc.Geometry
+		want []string
+	}{
+		{"overlapping polygon", &insidesvc.Geometry{Type: insidesvc.Geometry_POLYGON, Coordinates: rect(0.5, 0.5, 1.5, 1.5)}, []string{"A"}},
+		{"overlapping polygon across a side", &insidesvc.Geometry{Type: insidesvc.Geometry_POLYGON, Coordinates: rect(0.9, -1, 1.1, 2)}, []string{"A"}},
+		{"containing polygon", &insidesvc.Geometry{Type: insidesvc.Geometry_POLYGON, Coordinates: rect(-1, -1, 2, 2)}, []string{"A"}},
+		{"contained polygon", &insidesvc.Geometry{Type: insidesvc.Geometry_POLYGON, Coordinates: rect(0.2, 0.2, 0.4, 0.4)}, []string{"A"}},
+		{"disjoint polygon", &insidesvc.Geometry{Type: insidesvc.Geometry_POLYGON, Coordinates: rect(5, 5, 6, 6)}, nil},
+		// the polygons touching a feature by a side or a corner do not share any interior point
+		{"polygon sharing a side", &insidesvc.Geometry{Type: insidesvc.Geometry_POLYGON, Coordinates: rect(1, 0, 2, 1)}, nil},
+		{"polygon sharing a corner", &insidesvc.Geometry{Type: insidesvc.Geometry_POLYGON, Coordinates: rect(1, 1, 2, 2)}, nil},
+		{"crossing linestring", &insidesvc.Geometry{Type: insidesvc.Geometry_LINESTRING, Coordinates: []float64{0.5, 0.5, 3, 3}}, []string{"A"}},
+		{"disjoint linestring", &insidesvc.Geometry{Type: insidesvc.Geometry_LINESTRING, Coordinates: []float64{2, 0, 2, 3}}, nil},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := s.Intersect(context.Background(), &insidesvc.IntersectRequest{Geometry: tt.g, RemoveGeometries: true})
+			require.NoError(t, err)
+			var names []string
+			for _, r := range resp.Responses {
+				names = append(names, r.Feature.Properties["name"].GetStringValue())
+			}
+			require.Equal(t, tt.want, names)
+		})
+	}
+}
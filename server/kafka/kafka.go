@@ -0,0 +1,146 @@
+// Package kafka consumes positions from a Kafka topic and produces the enriched positions or geofence events to another one
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	kafkago "github.com/segmentio/kafka-go"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/akhenakh/insideout/server/bridge"
+)
+
+var (
+	consumedCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "insided_kafka",
+		Name:      "consumed_total",
+		Help:      "The total number of position messages consumed",
+	})
+	producedCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "insided_kafka",
+		Name:      "produced_total",
+		Help:      "The total number of messages produced",
+	})
+	skippedCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "insided_kafka",
+		Name:      "skipped_total",
+		Help:      "The total number of position messages skipped because they could not be processed",
+	})
+)
+
+// Options for a Bridge
+type Options struct {
+	Brokers []string
+
+	// GroupID the consumer group, the partitions of the input topic are shared by its members
+	GroupID string
+
+	InputTopic  string
+	OutputTopic string
+
+	// Concurrency the number of consumers of the group run by the Bridge, each one is assigned partitions
+	Concurrency int
+}
+
+// Bridge consumes positions and produces their results
+type Bridge struct {
+	opts   Options
+	proc   *bridge.Processor
+	writer *kafkago.Writer
+	logger log.Logger
+}
+
+// New returns a Bridge processing the messages with proc, call Run to start consuming
+func New(opts Options, proc *bridge.Processor, logger log.Logger) (*Bridge, error) {
+	if len(opts.Brokers) == 0 || opts.InputTopic == "" || opts.OutputTopic == "" || opts.GroupID == "" {
+		return nil, errors.New("kafka brokers, group and input and output topics are required")
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+
+	return &Bridge{
+		opts: opts,
+		proc: proc,
+		writer: kafkago.NewWriter(kafkago.WriterConfig{
+			Brokers: opts.Brokers,
+			Topic:   opts.OutputTopic,
+			// messages are keyed by object id, the messages of an object stay ordered
+			Balancer: &kafkago.Hash{},
+		}),
+		logger: log.With(logger, "component", "kafka"),
+	}, nil
+}
+
+// Run consumes the input topic until ctx is done or a message can't be produced,
+// a message is committed once its results are produced
+func (b *Bridge) Run(ctx context.Context) error {
+	defer b.writer.Close()
+
+	g, ctx := errgroup.WithContext(ctx)
+	for i := 0; i < b.opts.Concurrency; i++ {
+		g.Go(func() error {
+			return b.consume(ctx)
+		})
+	}
+	level.Info(b.logger).Log("msg", "consuming positions", "topic", b.opts.InputTopic, "group", b.opts.GroupID,
+		"concurrency", b.opts.Concurrency)
+
+	if err := g.Wait(); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+	return nil
+}
+
+func (b *Bridge) consume(ctx context.Context) error {
+	r := kafkago.NewReader(kafkago.ReaderConfig{
+		Brokers:  b.opts.Brokers,
+		GroupID:  b.opts.GroupID,
+		Topic:    b.opts.InputTopic,
+		MinBytes: 1,
+		MaxBytes: 10 << 20,
+	})
+	defer r.Close()
+
+	for {
+		m, err := r.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to fetch kafka message: %w", err)
+		}
+		consumedCounter.Inc()
+
+		msgs, err := b.proc.Process(ctx, m.Value)
+		if err != nil {
+			skippedCounter.Inc()
+			level.Debug(b.logger).Log("msg", "skipping position message", "error", err,
+				"partition", m.Partition, "offset", m.Offset)
+		}
+
+		if len(msgs) > 0 {
+			kmsgs := make([]kafkago.Message, len(msgs))
+			for i, msg := range msgs {
+				kmsgs[i] = kafkago.Message{Key: []byte(msg.Key), Value: msg.Value}
+			}
+			if err := b.writer.WriteMessages(ctx, kmsgs...); err != nil {
+				return fmt.Errorf("failed to produce kafka messages: %w", err)
+			}
+			producedCounter.Add(float64(len(kmsgs)))
+		}
+
+		if err := r.CommitMessages(ctx, m); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to commit kafka message: %w", err)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// the local cache tiers of the cacheCounter, the shared cache tiers are shared_feature and shared_result
+const (
+	featureTier = "feature"
+	resultTier  = "result"
+)
+
+// countBuckets buckets of the per query counts
+var countBuckets = []float64{0, 1, 2, 4, 8, 16, 32, 64, 128, 256, 512}
+
+var (
+	withinDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "insided_server",
+		Name:      "within_duration_seconds",
+		Help:      "Duration of the within lookups, cached when answered from the results caches",
+		Buckets:   prometheus.ExponentialBuckets(0.00001, 2, 18),
+	}, []string{"dataset", "strategy", "cached"})
+
+	indexDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "insided_server",
+		Name:      "index_duration_seconds",
+		Help:      "Duration of the index lookups of the within queries",
+		Buckets:   prometheus.ExponentialBuckets(0.00001, 2, 18),
+	}, []string{"dataset", "strategy"})
+
+	candidatesHistogram = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "insided_server",
+		Name:      "index_candidates",
+		Help:      "Loops returned by the index per within query, inside or maybe inside to be tested",
+		Buckets:   countBuckets,
+	}, []string{"dataset", "strategy", "kind"})
+
+	pipHistogram = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "insided_server",
+		Name:      "pip_tests",
+		Help:      "Point in polygon tests per within query",
+		Buckets:   countBuckets,
+	}, []string{"dataset", "strategy"})
+
+	cacheCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "insided_server",
+		Name:      "cache_requests_total",
+		Help:      "Cache lookups per dataset and cache tier, by result hit or miss",
+	}, []string{"dataset", "tier", "result"})
+
+	cellFilterCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "insided_server",
+		Name:      "cell_filter_rejects_total",
+		Help:      "Within queries rejected by the cell filter without querying the index",
+	}, []string{"dataset"})
+
+	coverageCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "insided_server",
+		Name:      "coverage_rejects_total",
+		Help:      "Within queries outside of the coverage of the dataset rejected without querying the index",
+	}, []string{"dataset"})
+
+	shadowCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "insided_server",
+		Name:      "shadow_queries_total",
+		Help:      "Within queries run again with the shadow strategy, by result match, mismatch, error or dropped",
+	}, []string{"dataset", "strategy", "result"})
+
+	shadowDelta = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "insided_server",
+		Name:      "shadow_latency_delta_seconds",
+		Help:      "Duration of the shadow strategy lookup minus the one of the server strategy for the same within query",
+		Buckets:   []float64{-0.1, -0.01, -0.001, -0.0001, -0.00001, 0, 0.00001, 0.0001, 0.001, 0.01, 0.1},
+	}, []string{"dataset", "strategy"})
+
+	candidateCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "insided_server",
+		Name:      "candidate_queries_total",
+		Help:      "Within queries run again with the candidate of the dataset, by result match, mismatch, error or dropped",
+	}, []string{"dataset", "result"})
+)
+
+// observeCache counts a lookup of the cache tier of ds
+func observeCache(ds *dataset, tier string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	cacheCounter.WithLabelValues(ds.name, tier, result).Inc()
+}
+
+// observeWithin records the duration of a within lookup started at start
+func (s *Server) observeWithin(ds *dataset, strategy string, start time.Time, cached bool) {
+	c := "false"
+	if cached {
+		c = "true"
+	}
+	withinDuration.WithLabelValues(ds.name, strategy, c).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/golang/geo/s2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/insidesvc"
+)
+
+func TestServer_Metrics(t *testing.T) {
+	a, clean := setup(t, "A", 0)
+	defer clean()
+
+	s, err := New(a, log.NewNopLogger(), nil, Options{
+		Strategy:         insideout.DBStrategy,
+		CacheCount:       10,
+		ResultCacheLevel: 10,
+		ResultCacheCount: 10,
+		DatasetName:      "metrics",
+	})
+	require.NoError(t, err)
+
+	resp, err := s.Within(context.Background(), &insidesvc.WithinRequest{Lat: 0.5, Lng: 0.5})
+	require.NoError(t, err)
+	require.Len(t, resp.Responses, 1)
+
+	// the cache is populated asynchronously
+	cellID := s2.CellIDFromLatLng(s2.LatLngFromDegrees(0.5, 0.5)).Parent(10)
+	require.Eventually(t, func() bool {
+		_, ok := s.datasets["metrics"].results.Get(uint64(cellID))
+		return ok
+	}, time.Second, 10*time.Millisecond)
+
+	resp, err = s.Within(context.Background(), &insidesvc.WithinRequest{Lat: 0.5, Lng: 0.5})
+	require.NoError(t, err)
+	require.Len(t, resp.Responses, 1)
+
+	require.Equal(t, 1.0, testutil.ToFloat64(cacheCounter.WithLabelValues("metrics", resultTier, "miss")))
+	require.Equal(t, 1.0, testutil.ToFloat64(cacheCounter.WithLabelValues("metrics", resultTier, "hit")))
+
+	histogram := func(o prometheus.Observer) *dto.Histogram {
+		m := &dto.Metric{}
+		require.NoError(t, o.(prometheus.Histogram).Write(m))
+		return m.Histogram
+	}
+	require.Equal(t, uint64(1), histogram(withinDuration.WithLabelValues("metrics", insideout.DBStrategy, "false")).GetSampleCount())
+	require.Equal(t, uint64(1), histogram(withinDuration.WithLabelValues("metrics", insideout.DBStrategy, "true")).GetSampleCount())
+	require.Equal(t, uint64(1), histogram(indexDuration.WithLabelValues("metrics", insideout.DBStrategy)).GetSampleCount())
+
+	candidates := histogram(candidatesHistogram.WithLabelValues("metrics", insideout.DBStrategy, "inside")).GetSampleSum() +
+		histogram(candidatesHistogram.WithLabelValues("metrics", insideout.DBStrategy, "maybe_inside")).GetSampleSum()
+	require.Equal(t, 1.0, candidates)
+	pip := histogram(pipHistogram.WithLabelValues("metrics", insideout.DBStrategy))
+	require.Equal(t, uint64(1), pip.GetSampleCount())
+	require.LessOrEqual(t, pip.GetSampleSum(), 1.0)
+}
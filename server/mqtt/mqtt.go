@@ -0,0 +1,192 @@
+// Package mqtt subscribes to the positions published by devices on an MQTT broker
+// and publishes the enriched positions or geofence events back to the broker
+package mqtt
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	log "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/akhenakh/insideout/server/bridge"
+)
+
+// IDPlaceholder is replaced by the object id in the result topic
+const IDPlaceholder = "{id}"
+
+// queueSize the max number of received positions waiting to be processed
+const queueSize = 1000
+
+var (
+	consumedCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "insided_mqtt",
+		Name:      "consumed_total",
+		Help:      "The total number of position messages received",
+	})
+	publishedCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "insided_mqtt",
+		Name:      "published_total",
+		Help:      "The total number of messages published",
+	})
+	skippedCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "insided_mqtt",
+		Name:      "skipped_total",
+		Help:      "The total number of position messages skipped because they could not be processed or published",
+	})
+)
+
+// Options for a Bridge
+type Options struct {
+	// Broker URL tcp://host:1883, ssl://host:8883 or ws://host:80
+	Broker   string
+	ClientID string
+	Username string
+	Password string
+
+	// Topic filter of the positions, the level matched by its first + wildcard is the id of the positions without id
+	Topic string
+
+	// ResultTopic the topic the results are published to, IDPlaceholder is replaced by the object id
+	ResultTopic string
+
+	// QoS of the subscription and the publications 0, 1 or 2
+	QoS byte
+
+	// TLSConfig to connect to the broker, nil for the default configuration of ssl:// brokers
+	TLSConfig *tls.Config
+
+	// PublishTimeout the max time to wait for a publication to be acknowledged
+	PublishTimeout time.Duration
+}
+
+// Bridge receives positions and publishes their results
+type Bridge struct {
+	opts   Options
+	proc   *bridge.Processor
+	client paho.Client
+	queue  chan paho.Message
+	done   chan struct{}
+	logger log.Logger
+}
+
+// New returns a Bridge processing the messages with proc, call Run to connect to the broker
+func New(opts Options, proc *bridge.Processor, logger log.Logger) (*Bridge, error) {
+	if opts.Broker == "" || opts.Topic == "" || opts.ResultTopic == "" {
+		return nil, errors.New("mqtt broker, topic and result topic are required")
+	}
+	if opts.QoS > 2 {
+		return nil, fmt.Errorf("invalid mqtt QoS %d", opts.QoS)
+	}
+	if opts.PublishTimeout <= 0 {
+		opts.PublishTimeout = 10 * time.Second
+	}
+
+	b := &Bridge{
+		opts:   opts,
+		proc:   proc,
+		queue:  make(chan paho.Message, queueSize),
+		done:   make(chan struct{}),
+		logger: log.With(logger, "component", "mqtt"),
+	}
+
+	co := paho.NewClientOptions().
+		AddBroker(opts.Broker).
+		SetClientID(opts.ClientID).
+		SetUsername(opts.Username).
+		SetPassword(opts.Password).
+		SetAutoReconnect(true).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			level.Warn(b.logger).Log("msg", "lost mqtt connection", "error", err)
+		}).
+		// subscribing on every connection restores the subscription after a reconnection
+		SetOnConnectHandler(func(c paho.Client) {
+			t := c.Subscribe(opts.Topic, opts.QoS, b.receive)
+			if t.Wait() && t.Error() != nil {
+				level.Error(b.logger).Log("msg", "can't subscribe to mqtt topic", "error", t.Error(), "topic", opts.Topic)
+				return
+			}
+			level.Info(b.logger).Log("msg", "subscribed to positions", "topic", opts.Topic, "qos", opts.QoS)
+		})
+	if opts.TLSConfig != nil {
+		co.SetTLSConfig(opts.TLSConfig)
+	}
+	b.client = paho.NewClient(co)
+
+	return b, nil
+}
+
+// receive queues the messages, blocking the client when the queue is full until Run returns
+func (b *Bridge) receive(_ paho.Client, m paho.Message) {
+	select {
+	case b.queue <- m:
+	case <-b.done:
+	}
+}
+
+// Run connects to the broker and processes the received positions until ctx is done
+func (b *Bridge) Run(ctx context.Context) error {
+	t := b.client.Connect()
+	if t.Wait() && t.Error() != nil {
+		return fmt.Errorf("can't connect to mqtt broker: %w", t.Error())
+	}
+	defer b.client.Disconnect(250)
+	defer close(b.done)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case m := <-b.queue:
+			b.process(ctx, m)
+		}
+	}
+}
+
+func (b *Bridge) process(ctx context.Context, m paho.Message) {
+	consumedCounter.Inc()
+
+	msgs, err := b.proc.ProcessID(ctx, m.Payload(), topicID(b.opts.Topic, m.Topic()))
+	if err != nil {
+		skippedCounter.Inc()
+		level.Debug(b.logger).Log("msg", "skipping position message", "error", err, "topic", m.Topic())
+		return
+	}
+
+	for _, msg := range msgs {
+		topic := strings.Replace(b.opts.ResultTopic, IDPlaceholder, msg.Key, -1)
+		t := b.client.Publish(topic, b.opts.QoS, false, msg.Value)
+		if !t.WaitTimeout(b.opts.PublishTimeout) {
+			skippedCounter.Inc()
+			level.Warn(b.logger).Log("msg", "mqtt publication timed out", "topic", topic)
+			continue
+		}
+		if t.Error() != nil {
+			skippedCounter.Inc()
+			level.Warn(b.logger).Log("msg", "can't publish mqtt message", "error", t.Error(), "topic", topic)
+			continue
+		}
+		publishedCounter.Inc()
+	}
+}
+
+// topicID returns the level of topic matched by the first + wildcard of filter, empty if there is none
+func topicID(filter, topic string) string {
+	levels := strings.Split(topic, "/")
+	for i, f := range strings.Split(filter, "/") {
+		if i >= len(levels) || f == "#" {
+			return ""
+		}
+		if f == "+" {
+			return levels[i]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,22 @@
+package mqtt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTopicID(t *testing.T) {
+	tests := []struct {
+		filter, topic, want string
+	}{
+		{"devices/+/position", "devices/truck1/position", "truck1"},
+		{"fleet/+/+/gps", "fleet/paris/truck1/gps", "paris"},
+		{"devices/#", "devices/truck1/position", ""},
+		{"devices/position", "devices/position", ""},
+		{"devices/+/position", "devices", ""},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, topicID(tt.filter, tt.topic), tt.filter)
+	}
+}
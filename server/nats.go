@@ -0,0 +1,54 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/nats-io/nats.go"
+	"google.golang.org/grpc/codes"
+
+	"github.com/akhenakh/insideout/insidesvc"
+)
+
+// natsTimeout the max time to process a NATS request, requesters usually gave up before
+const natsTimeout = 10 * time.Second
+
+// NATSHandler NATS request handler of WithinRequest messages replying WithinReply messages,
+// requests and replies are JSON as jsonpb when the request is a JSON object, protobuf otherwise
+func (s *Server) NATSHandler(m *nats.Msg) {
+	if m.Reply == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), natsTimeout)
+	defer cancel()
+
+	ctx, span := tracer().Start(ctx, "NATSHandler")
+	defer span.End()
+
+	ct := protobufContentType
+	if bytes.HasPrefix(bytes.TrimSpace(m.Data), []byte("{")) {
+		ct = jsonContentType
+	}
+
+	reply := &insidesvc.WithinReply{}
+	req := &insidesvc.WithinRequest{}
+	if err := readMessage(ct, m.Data, req); err != nil {
+		reply.Error = &insidesvc.Error{Code: int32(codes.InvalidArgument), Message: "invalid request: " + err.Error()}
+	} else if resp, err := s.Within(ctx, req); err != nil {
+		reply.Error = insidesvc.StatusError(err)
+	} else {
+		reply.Response = resp
+	}
+
+	_, b, err := encodeMessage(ct, reply)
+	if err != nil {
+		level.Error(s.logger).Log("msg", "can't encode NATS reply", "error", err)
+		return
+	}
+	if err := m.Respond(b); err != nil {
+		level.Debug(s.logger).Log("msg", "NATS reply failed", "error", err)
+	}
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	natsserver "github.com/nats-io/nats-server/v2/server"
+	natstest "github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/insidesvc"
+)
+
+func TestServer_NATSHandler(t *testing.T) {
+	a, clean := setup(t, "A", 0)
+	defer clean()
+
+	s, err := New(a, log.NewNopLogger(), nil, Options{Strategy: insideout.DBStrategy, CacheCount: 10})
+	require.NoError(t, err)
+
+	ns := natstest.RunServer(&natsserver.Options{Host: "127.0.0.1", Port: natsserver.RANDOM_PORT, NoSigs: true})
+	defer ns.Shutdown()
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	defer nc.Close()
+
+	_, err = nc.Subscribe("insided.within", s.NATSHandler)
+	require.NoError(t, err)
+
+	// protobuf
+	b, err := proto.Marshal(&insidesvc.WithinRequest{Lat: 0.5, Lng: 0.5, RemoveGeometries: true})
+	require.NoError(t, err)
+	m, err := nc.Request("insided.within", b, time.Second)
+	require.NoError(t, err)
+	reply := &insidesvc.WithinReply{}
+	require.NoError(t, proto.Unmarshal(m.Data, reply))
+	require.Nil(t, reply.Error)
+	require.Len(t, reply.Response.Responses, 1)
+	require.Equal(t, "A", reply.Response.Responses[0].Feature.Properties["name"].GetStringValue())
+
+	// JSON
+	m, err = nc.Request("insided.within", []byte(`{"lat": 0.5, "lng": 0.5, "remove_geometries": true}`), time.Second)
+	require.NoError(t, err)
+	reply = &insidesvc.WithinReply{}
+	require.NoError(t, jsonpb.Unmarshal(bytes.NewReader(m.Data), reply))
+	require.Len(t, reply.Response.Responses, 1)
+
+	m, err = nc.Request("insided.within", []byte(`{"lat": 0.5, "lng": 0.5, "dataset": "unknown"}`), time.Second)
+	require.NoError(t, err)
+	reply = &insidesvc.WithinReply{}
+	require.NoError(t, jsonpb.Unmarshal(bytes.NewReader(m.Data), reply))
+	require.Nil(t, reply.Response)
+	require.Equal(t, int32(codes.NotFound), reply.Error.Code)
+
+	m, err = nc.Request("insided.within", []byte(`{"lat": "north"}`), time.Second)
+	require.NoError(t, err)
+	reply = &insidesvc.WithinReply{}
+	require.NoError(t, jsonpb.Unmarshal(bytes.NewReader(m.Data), reply))
+	require.Equal(t, int32(codes.InvalidArgument), reply.Error.Code)
+}
@@ -0,0 +1,326 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Route an HTTP API route, registered by insided and documented in the OpenAPI spec
+type Route struct {
+	Path    string
+	Methods []string
+	Summary string
+	Params  []Param
+	// Body the description of the request body, empty for none
+	Body string
+	// Response the description of the response body, empty for a GeoJSON FeatureCollection
+	Response string
+	// Encoded the body and the response can be protobuf or msgpack encoded messages, negotiated with Content-Type and Accept
+	Encoded bool
+	Handler http.HandlerFunc
+}
+
+// Param a query or path parameter of a Route
+type Param struct {
+	Name        string
+	In          string
+	Type        string
+	Description string
+}
+
+// MetricsName returns the route path without braces, used to label the metrics
+func (r Route) MetricsName() string {
+	return strings.NewReplacer("{", "", "}", "").Replace(r.Path)
+}
+
+var (
+	latParam     = Param{"lat", "path", "number", "latitude in degrees"}
+	lngParam     = Param{"lng", "path", "number", "longitude in degrees"}
+	datasetParam = Param{"dataset", "path", "string", "dataset to query"}
+	fieldsParam  = Param{"fields", "query", "string", "comma separated list of properties to return, empty for all"}
+	filterParam  = Param{"filter", "query", "string", "comma separated list of conditions on properties key=value or key!=value"}
+)
+
+// APIRoutes returns the routes of the HTTP API
+func (s *Server) APIRoutes() []Route {
+	withinParams := []Param{
+		fieldsParam,
+		filterParam,
+		{"boundary_distance", "query", "boolean", "add the distance in meters to the feature boundary in the insided_boundary_distance property"},
+		{"exact", "query", "boolean", "test the point against every polygon and skip the results cache"},
+		{"order", "query", "string", "ordering of the features: insertion (default), area (smallest first) or property"},
+		{"order_property", "query", "string", "property ordering the features with order=property, numbers before strings, features without it last"},
+		{"order_desc", "query", "boolean", "reverse the order"},
+		{"limit", "query", "integer", "max number of features returned after filtering and ordering, 0 for all"},
+		{"hierarchy", "query", "boolean", "return the features from the outermost to the deepest, with the ids of their ancestors in the insided_ancestor_ids property, requires a DB indexed with -hierarchy"},
+		{"deepest_only", "query", "boolean", "only return the deepest features, the ones not containing another returned feature, requires a DB indexed with -hierarchy"},
+		{"format", "query", "string", "geojson to return the whole geometries of the features instead of the matched polygons"},
+		{"simplify", "query", "number", "with format=geojson the Douglas-Peucker tolerance in meters to simplify the geometries, 0 to disable"},
+		{"at", "query", "string", "only return the features valid at this time, from their validity properties: RFC 3339 time, date or unix milliseconds, the current time by default"},
+		{"strategy", "query", "string", "strategy answering the query among the strategies of the dataset info, to compare them, skips the results cache, the server strategy by default"},
+		{"debug", "query", "boolean", "return a WithinResponse message with the diagnostics of the lookup instead of GeoJSON: cell of the point, candidates and timings"},
+		{"centroid_geohash", "query", "integer", "add the geohash of the centroid of the matched polygon with this precision, 1 to 12, in the insided_centroid_geohash property"},
+	}
+	geohashParams := append([]Param{
+		{"hash", "path", "string", "geohash of the cell to query"},
+		{"mode", "query", "string", "center (default) to query the center of the cell, cell to only return the features containing the whole cell"},
+	}, withinParams...)
+	nearestParams := []Param{
+		{"max_distance", "query", "number", "max distance in meters to look for the nearest feature, 0 for the server default"},
+	}
+	intersectParams := []Param{
+		{"bbox", "query", "string", "minLng,minLat,maxLng,maxLat, replaces the request body"},
+		{"limit", "query", "integer", "max number of features returned, 0 or above the server limit for the server limit"},
+		{"cursor", "query", "string", "the X-Next-Cursor header of the previous response to get the next features, set when more features intersect"},
+	}
+	featureParams := []Param{
+		{"fid", "path", "integer", "id of the feature, the insided_fid property of the within responses"},
+		{"external", "query", "boolean", "fid is the value of the id property of the feature, for the datasets indexed with -idProperty"},
+		{"simplify", "query", "number", "the Douglas-Peucker tolerance in meters to simplify the geometry, 0 to disable"},
+	}
+	listParams := []Param{
+		{"prop.{name}", "query", "string", "value of the property name of the features, for each property to match"},
+		fieldsParam,
+		filterParam,
+		{"limit", "query", "integer", "max number of features returned, 0 or above the server limit for the server limit"},
+		{"cursor", "query", "string", "the next_cursor of the previous response to get the next features, set when more features match"},
+	}
+	intersectBody := "a GeoJSON geometry or feature, Point, LineString or Polygon"
+	withinBatchBody := "a WithinBatchRequest message"
+	withinBatchResponse := "a WithinBatchResponse message"
+	listResponse := "a ListFeaturesResponse message, the features ordered by id without their geometries"
+	featureResponse := "a GeoJSON Feature identified by the feature id, or a Feature message"
+	reverseResponse := "a JSON object, the formatted address and the ids of the features containing lat lng"
+
+	routes := []Route{
+		// before the lat lng routes matching the same paths
+		{
+			Path: "/api/within/geohash/{hash}", Methods: []string{"GET"}, Handler: s.CacheValidated(s.GeohashHandler),
+			Summary: "features containing the geohash cell in the default dataset",
+			Params:  geohashParams, Encoded: true,
+		},
+		{
+			Path: "/api/within/{dataset}/geohash/{hash}", Methods: []string{"GET"}, Handler: s.CacheValidated(s.GeohashHandler),
+			Summary: "features containing the geohash cell",
+			Params:  append([]Param{datasetParam}, geohashParams...), Encoded: true,
+		},
+		{
+			Path: "/api/within/{lat}/{lng}", Methods: []string{"GET"}, Handler: s.CacheValidated(s.WithinHandler),
+			Summary: "features containing lat lng in the default dataset",
+			Params:  append([]Param{latParam, lngParam}, withinParams...), Encoded: true,
+		},
+		{
+			Path: "/api/within/{dataset}/{lat}/{lng}", Methods: []string{"GET"}, Handler: s.CacheValidated(s.WithinHandler),
+			Summary: "features containing lat lng",
+			Params:  append([]Param{datasetParam, latParam, lngParam}, withinParams...), Encoded: true,
+		},
+		{
+			Path: "/api/within", Methods: []string{"POST"}, Handler: s.WithinBatchHandler,
+			Summary: "features containing each point of a batch in the default dataset",
+			Body:    withinBatchBody, Response: withinBatchResponse, Encoded: true,
+		},
+		{
+			Path: "/api/within/{dataset}", Methods: []string{"POST"}, Handler: s.WithinBatchHandler,
+			Summary: "features containing each point of a batch",
+			Params:  []Param{datasetParam}, Body: withinBatchBody, Response: withinBatchResponse, Encoded: true,
+		},
+		{
+			Path: "/api/nearest/{lat}/{lng}", Methods: []string{"GET"}, Handler: s.CacheValidated(s.NearestHandler),
+			Summary: "feature containing lat lng or the closest one in the default dataset",
+			Params:  append([]Param{latParam, lngParam}, nearestParams...),
+		},
+		{
+			Path: "/api/nearest/{dataset}/{lat}/{lng}", Methods: []string{"GET"}, Handler: s.CacheValidated(s.NearestHandler),
+			Summary: "feature containing lat lng or the closest one",
+			Params:  append([]Param{datasetParam, latParam, lngParam}, nearestParams...),
+		},
+		{
+			Path: "/api/intersect", Methods: []string{"GET", "POST"}, Handler: s.CacheValidated(s.IntersectHandler),
+			Summary: "features intersecting a geometry in the default dataset",
+			Params:  intersectParams, Body: intersectBody,
+		},
+		{
+			Path: "/api/intersect/{dataset}", Methods: []string{"GET", "POST"}, Handler: s.CacheValidated(s.IntersectHandler),
+			Summary: "features intersecting a geometry",
+			Params:  append([]Param{datasetParam}, intersectParams...), Body: intersectBody,
+		},
+		{
+			Path: "/api/features", Methods: []string{"GET"}, Handler: s.CacheValidated(s.ListFeaturesHandler),
+			Summary: "ids and properties of the features matching conditions on their properties in the default dataset",
+			Params:  listParams, Response: listResponse, Encoded: true,
+		},
+		{
+			Path: "/api/features/{dataset}", Methods: []string{"GET"}, Handler: s.CacheValidated(s.ListFeaturesHandler),
+			Summary: "ids and properties of the features matching conditions on their properties",
+			Params:  append([]Param{datasetParam}, listParams...), Response: listResponse, Encoded: true,
+		},
+		{
+			Path: "/api/feature/{fid}", Methods: []string{"GET"}, Handler: s.CacheValidated(s.FeatureHandler),
+			Summary: "feature fid with all its polygons in the default dataset",
+			Params:  featureParams, Response: featureResponse, Encoded: true,
+		},
+		{
+			Path: "/api/feature/{dataset}/{fid}", Methods: []string{"GET"}, Handler: s.CacheValidated(s.FeatureHandler),
+			Summary: "feature fid with all its polygons",
+			Params:  append([]Param{datasetParam}, featureParams...), Response: featureResponse, Encoded: true,
+		},
+		{
+			Path: "/api/coverage", Methods: []string{"GET"}, Handler: s.CacheValidated(s.CoverageHandler),
+			Summary: "cells containing all the features of the default dataset, a feature by cell",
+		},
+		{
+			Path: "/api/coverage/{dataset}", Methods: []string{"GET"}, Handler: s.CacheValidated(s.CoverageHandler),
+			Summary: "cells containing all the features of the dataset, a feature by cell",
+			Params:  []Param{datasetParam},
+		},
+	}
+
+	// the reverse endpoints are only served with address templates
+	if len(s.reverse) > 0 {
+		routes = append(routes, Route{
+			Path: "/api/reverse/{lat}/{lng}", Methods: []string{"GET"}, Handler: s.CacheValidated(s.ReverseHandler),
+			Summary: "address of lat lng in the default dataset",
+			Params:  []Param{latParam, lngParam}, Response: reverseResponse,
+		}, Route{
+			Path: "/api/reverse/{dataset}/{lat}/{lng}", Methods: []string{"GET"}, Handler: s.CacheValidated(s.ReverseHandler),
+			Summary: "address of lat lng",
+			Params:  []Param{datasetParam, latParam, lngParam}, Response: reverseResponse,
+		})
+	}
+
+	// the write endpoints are only served in read write mode
+	if s.opts.ReadWrite {
+		idParam := Param{"id", "path", "integer", "id of the feature"}
+		featureBody := "a GeoJSON Feature, Polygon or MultiPolygon, with their holes"
+		writeResponse := "a JSON object, the id of the written feature"
+		routes = append(routes, Route{
+			Path: "/api/features", Methods: []string{"POST"}, Handler: s.FeatureWriteHandler,
+			Summary: "insert a feature in the default dataset",
+			Body:    featureBody, Response: writeResponse,
+		}, Route{
+			Path: "/api/features/{dataset}", Methods: []string{"POST"}, Handler: s.FeatureWriteHandler,
+			Summary: "insert a feature",
+			Params:  []Param{datasetParam}, Body: featureBody, Response: writeResponse,
+		}, Route{
+			Path: "/api/features/{id}", Methods: []string{"PUT", "DELETE"}, Handler: s.FeatureWriteHandler,
+			Summary: "replace or delete a feature of the default dataset",
+			Params:  []Param{idParam}, Body: featureBody, Response: writeResponse,
+		}, Route{
+			Path: "/api/features/{dataset}/{id}", Methods: []string{"PUT", "DELETE"}, Handler: s.FeatureWriteHandler,
+			Summary: "replace or delete a feature",
+			Params:  []Param{datasetParam, idParam}, Body: featureBody, Response: writeResponse,
+		})
+	}
+	return routes
+}
+
+// OpenAPI returns the OpenAPI 3 document describing routes
+func OpenAPI(routes []Route, version string) map[string]interface{} {
+	paths := make(map[string]interface{}, len(routes))
+	for _, r := range routes {
+		var params []interface{}
+		for _, p := range r.Params {
+			params = append(params, map[string]interface{}{
+				"name":        p.Name,
+				"in":          p.In,
+				"required":    p.In == "path",
+				"description": p.Description,
+				"schema":      map[string]interface{}{"type": p.Type},
+			})
+		}
+
+		ok := map[string]interface{}{
+			"description": "a GeoJSON FeatureCollection",
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{
+					"schema": map[string]interface{}{"$ref": "#/components/schemas/FeatureCollection"},
+				},
+			},
+		}
+		if r.Response != "" {
+			ok = map[string]interface{}{"description": r.Response, "content": content(r.Encoded)}
+		} else if r.Encoded {
+			ok["description"] = "a GeoJSON FeatureCollection, or the gRPC response message with the protobuf or msgpack Accept types"
+			for k, v := range messageContent() {
+				ok["content"].(map[string]interface{})[k] = v
+			}
+		}
+
+		ops := make(map[string]interface{}, len(r.Methods))
+		for _, m := range r.Methods {
+			op := map[string]interface{}{
+				"summary":    r.Summary,
+				"parameters": params,
+				"responses": map[string]interface{}{
+					"200": ok,
+					"400": map[string]interface{}{"description": "invalid parameters"},
+					"404": map[string]interface{}{"description": "no features found or unknown dataset"},
+				},
+			}
+			if r.Body != "" && (m == "POST" || m == "PUT") {
+				op["requestBody"] = map[string]interface{}{
+					"description": r.Body,
+					"required":    true,
+					"content":     content(r.Encoded),
+				}
+			}
+			ops[strings.ToLower(m)] = op
+		}
+		paths[r.Path] = ops
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "insided HTTP API",
+			"version": version,
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": map[string]interface{}{
+				"FeatureCollection": map[string]interface{}{
+					"type":        "object",
+					"description": "GeoJSON FeatureCollection, the insided_* properties are added by the server",
+					"properties": map[string]interface{}{
+						"type":     map[string]interface{}{"type": "string"},
+						"features": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "object"}},
+					},
+				},
+			},
+		},
+	}
+}
+
+// content returns the JSON content of a body, with the protobuf and msgpack alternatives when encoded
+func content(encoded bool) map[string]interface{} {
+	c := map[string]interface{}{
+		jsonContentType: map[string]interface{}{"schema": map[string]interface{}{"type": "object"}},
+	}
+	if encoded {
+		for k, v := range messageContent() {
+			c[k] = v
+		}
+	}
+	return c
+}
+
+// messageContent returns the protobuf and msgpack encoded message contents
+func messageContent() map[string]interface{} {
+	binary := map[string]interface{}{"schema": map[string]interface{}{"type": "string", "format": "binary"}}
+	return map[string]interface{}{
+		protobufContentType: binary,
+		msgpackContentType:  binary,
+	}
+}
+
+// OpenAPIHandler HTTP 1.1 Handler returning the OpenAPI document of the HTTP API
+func (s *Server) OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	b, err := json.Marshal(OpenAPI(s.APIRoutes(), s.opts.Version))
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+
+	"github.com/akhenakh/insideout"
+)
+
+var pathParamRe = regexp.MustCompile(`{([^}]+)}`)
+
+func TestServer_OpenAPI(t *testing.T) {
+	a, clean := setup(t, "A", 0)
+	defer clean()
+
+	s, err := New(a, log.NewNopLogger(), nil, Options{Strategy: insideout.DBStrategy, CacheCount: 10, Version: "test"})
+	require.NoError(t, err)
+
+	routes := s.APIRoutes()
+	for _, r := range routes {
+		// every path parameter is documented and every documented one is in the path
+		var declared []string
+		for _, p := range r.Params {
+			if p.In == "path" {
+				declared = append(declared, p.Name)
+			}
+		}
+		var inPath []string
+		for _, m := range pathParamRe.FindAllStringSubmatch(r.Path, -1) {
+			inPath = append(inPath, m[1])
+		}
+		require.Equal(t, inPath, declared, r.Path)
+	}
+
+	w := httptest.NewRecorder()
+	s.OpenAPIHandler(w, httptest.NewRequest("GET", "/api/openapi.json", nil))
+	require.Equal(t, 200, w.Code)
+
+	var doc struct {
+		Info struct {
+			Version string
+		}
+		Paths map[string]map[string]interface{}
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	require.Equal(t, "test", doc.Info.Version)
+	require.Len(t, doc.Paths, len(routes))
+	require.Contains(t, doc.Paths["/api/intersect"], "post")
+	require.Equal(t, "/api/within/dataset/geohash/hash", routes[1].MetricsName())
+	require.Equal(t, "/api/within/dataset/lat/lng", routes[3].MetricsName())
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/insidesvc"
+)
+
+// match a loop containing the queried point
+type match struct {
+	fid     insideout.FeatureIndexResponse
+	feature *insideout.Feature
+	exact   bool
+
+	// ancestors the ids of the features containing it, set when a hierarchy is requested
+	ancestors []uint32
+}
+
+// orderMatches sorts the matches in place according to req, the ties keep the insertion order
+func orderMatches(req *insidesvc.WithinRequest, matches []match) error {
+	var less func(a, b match) bool
+	switch req.Order {
+	case insidesvc.WithinRequest_INSERTION:
+		less = func(a, b match) bool { return lessInsertion(a, b, req.OrderDesc) }
+	case insidesvc.WithinRequest_AREA:
+		areas := make(map[insideout.FeatureIndexResponse]float64, len(matches))
+		for _, m := range matches {
+			areas[m.fid] = m.feature.PolygonArea(m.fid.Pos)
+		}
+		less = func(a, b match) bool {
+			if areas[a.fid] != areas[b.fid] {
+				return (areas[a.fid] < areas[b.fid]) != req.OrderDesc
+			}
+			return lessInsertion(a, b, false)
+		}
+	case insidesvc.WithinRequest_PROPERTY:
+		if req.OrderProperty == "" {
+			return errors.New("order_property is required by the PROPERTY order")
+		}
+		less = func(a, b match) bool {
+			c, ok := compareProperties(a.feature.Properties[req.OrderProperty], b.feature.Properties[req.OrderProperty])
+			switch {
+			case !ok && c != 0:
+				// a missing or not comparable value is last
+				return c < 0
+			case c == 0:
+				return lessInsertion(a, b, false)
+			}
+			return (c < 0) != req.OrderDesc
+		}
+	default:
+		return errors.New("invalid order")
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool { return less(matches[i], matches[j]) })
+	return nil
+}
+
+func lessInsertion(a, b match, desc bool) bool {
+	if a.fid.ID != b.fid.ID {
+		return (a.fid.ID < b.fid.ID) != desc
+	}
+	return (a.fid.Pos < b.fid.Pos) != desc
+}
+
+// compareProperties compares two property values, numbers come before strings,
+// ok is false when one of them is missing or neither a number nor a string,
+// then c is -1 if only b is missing, 1 if only a is missing, 0 otherwise
+func compareProperties(a, b interface{}) (c int, ok bool) {
+	ka, va := propertyKey(a)
+	kb, vb := propertyKey(b)
+	switch {
+	case ka == 0 && kb == 0:
+		return 0, false
+	case ka == 0:
+		return 1, false
+	case kb == 0:
+		return -1, false
+	case ka != kb:
+		if ka < kb {
+			return -1, true
+		}
+		return 1, true
+	case ka == 1:
+		return compareFloats(va.(float64), vb.(float64)), true
+	}
+
+	sa, sb := va.(string), vb.(string)
+	switch {
+	case sa < sb:
+		return -1, true
+	case sa > sb:
+		return 1, true
+	}
+	return 0, true
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// propertyKey returns 1 and a float64 for numbers, 2 and the string for strings, 0 otherwise
+func propertyKey(v interface{}) (int, interface{}) {
+	switch tv := v.(type) {
+	case string:
+		return 2, tv
+	case float64:
+		return 1, tv
+	case float32:
+		return 1, float64(tv)
+	case int:
+		return 1, float64(tv)
+	case int64:
+		return 1, float64(tv)
+	case uint64:
+		return 1, float64(tv)
+	case int32:
+		return 1, float64(tv)
+	case uint32:
+		return 1, float64(tv)
+	}
+	return 0, nil
+}
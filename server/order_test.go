@@ -0,0 +1,132 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/geojson"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/insidesvc"
+	"github.com/akhenakh/insideout/storage/bbolt"
+)
+
+func TestServer_WithinOrder(t *testing.T) {
+	// nested squares centered on 0.5 0.5, inserted in this order
+	storage, clean := setupNested(t, []nestedSquare{
+		{name: "country", size: 1, props: map[string]interface{}{"admin_level": 2.0}},
+		{name: "city", size: 0.2, props: map[string]interface{}{"admin_level": 8.0}},
+		{name: "zone", size: 0.6},
+		{name: "region", size: 0.8, props: map[string]interface{}{"admin_level": 4.0}},
+	})
+	defer clean()
+
+	s, err := New(storage, log.NewNopLogger(), nil, Options{Strategy: insideout.ShapeIndexStrategy})
+	require.NoError(t, err)
+
+	names := func(req *insidesvc.WithinRequest) []string {
+		req.Lat, req.Lng, req.RemoveGeometries = 0.5, 0.5, true
+		resp, err := s.Within(context.Background(), req)
+		require.NoError(t, err)
+		var names []string
+		for _, r := range resp.Responses {
+			names = append(names, r.Feature.Properties["name"].GetStringValue())
+		}
+		return names
+	}
+
+	require.Equal(t, []string{"country", "city", "zone", "region"}, names(&insidesvc.WithinRequest{}))
+	require.Equal(t, []string{"region", "zone", "city", "country"},
+		names(&insidesvc.WithinRequest{OrderDesc: true}))
+	require.Equal(t, []string{"city", "zone", "region", "country"},
+		names(&insidesvc.WithinRequest{Order: insidesvc.WithinRequest_AREA}))
+	require.Equal(t, []string{"country"},
+		names(&insidesvc.WithinRequest{Order: insidesvc.WithinRequest_AREA, OrderDesc: true, Limit: 1}))
+	require.Equal(t, []string{"country", "region", "city", "zone"},
+		names(&insidesvc.WithinRequest{Order: insidesvc.WithinRequest_PROPERTY, OrderProperty: "admin_level"}))
+	require.Equal(t, []string{"city", "region", "country", "zone"},
+		names(&insidesvc.WithinRequest{Order: insidesvc.WithinRequest_PROPERTY, OrderProperty: "admin_level", OrderDesc: true}))
+	require.Equal(t, []string{"city", "country"},
+		names(&insidesvc.WithinRequest{Order: insidesvc.WithinRequest_PROPERTY, OrderProperty: "name", Limit: 2}))
+
+	_, err = s.Within(context.Background(), &insidesvc.WithinRequest{Lat: 0.5, Lng: 0.5, Order: insidesvc.WithinRequest_PROPERTY})
+	require.Error(t, err)
+	_, err = s.Within(context.Background(), &insidesvc.WithinRequest{Lat: 0.5, Lng: 0.5, Limit: -1})
+	require.Error(t, err)
+}
+
+func TestCompareProperties(t *testing.T) {
+	c, ok := compareProperties(2, 10.0)
+	require.True(t, ok)
+	require.Equal(t, -1, c)
+
+	c, ok = compareProperties("b", "a")
+	require.True(t, ok)
+	require.Equal(t, 1, c)
+
+	// numbers first
+	c, ok = compareProperties("1", 2)
+	require.True(t, ok)
+	require.Equal(t, 1, c)
+
+	// missing last
+	c, ok = compareProperties(nil, 2)
+	require.False(t, ok)
+	require.Equal(t, 1, c)
+	c, ok = compareProperties(true, nil)
+	require.False(t, ok)
+	require.Equal(t, 0, c)
+}
+
+type nestedSquare struct {
+	name  string
+	size  float64
+	props map[string]interface{}
+}
+
+// setupNested returns a storage with squares of size degrees centered on 0.5 0.5 and their hierarchy
+func setupNested(t *testing.T, squares []nestedSquare) (insideout.Store, func()) {
+	logger := log.NewNopLogger()
+
+	tmpFile, err := ioutil.TempFile(os.TempDir(), "insideout-test-")
+	require.NoError(t, err)
+	tmpFile.Close()
+
+	wstorage, wclose, err := bbolt.NewStorage(tmpFile.Name(), logger)
+	require.NoError(t, err)
+
+	var fc geojson.FeatureCollection
+	for _, sq := range squares {
+		lo, hi := 0.5-sq.size/2, 0.5+sq.size/2
+		props := map[string]interface{}{"name": sq.name}
+		for k, v := range sq.props {
+			props[k] = v
+		}
+		fc.Features = append(fc.Features, &geojson.Feature{
+			Geometry:   geom.NewPolygonFlat(geom.XY, []float64{lo, lo, hi, lo, hi, hi, lo, hi, lo, lo}, []int{10}),
+			Properties: props,
+		})
+	}
+
+	icoverer := &s2.RegionCoverer{MinLevel: 5, MaxLevel: 12, MaxCells: 24}
+	ocoverer := &s2.RegionCoverer{MinLevel: 5, MaxLevel: 12, MaxCells: 16}
+	require.NoError(t, wstorage.Index(fc, icoverer, ocoverer, 100, "nested", "unittest"))
+	parents, err := insideout.ComputeHierarchy(wstorage)
+	require.NoError(t, err)
+	require.NoError(t, wstorage.StoreHierarchy(parents))
+	require.NoError(t, wclose())
+
+	storage, sclose, err := bbolt.NewROStorage(tmpFile.Name(), logger)
+	require.NoError(t, err)
+
+	return storage, func() {
+		sclose()
+		os.Remove(tmpFile.Name())
+	}
+}
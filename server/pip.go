@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/golang/geo/s2"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/server/tenant"
+)
+
+// pipWorkersMinCandidates below this count of candidates the PIP tests of a query are performed one by one,
+// the goroutines cost more than the tests
+const pipWorkersMinCandidates = 8
+
+// candidate a feature returned by the index for a query
+type candidate struct {
+	fid insideout.FeatureIndexResponse
+	// inside returned in IDsInside by the index
+	inside bool
+	// test whether the loop must be tested against the point
+	test bool
+
+	// done the candidate was evaluated, the ones left after StopOnFirstFound are not
+	done     bool
+	accepted bool
+	f        *insideout.Feature
+}
+
+// filtering returns true when the features containing the point of a request can be filtered out,
+// by the property filter pf, the tenant of ctx, their expiry or their validity interval
+func (s *Server) filtering(ctx context.Context, pf propertyFilter) bool {
+	_, ok := tenant.FromContext(ctx)
+	return len(pf) > 0 || ok || s.opts.ExpiryProperty != "" ||
+		s.opts.ValidFromProperty != "" || s.opts.ValidToProperty != ""
+}
+
+// testCandidates evaluates the candidates in place, concurrently by PIPWorkers goroutines
+// when there are enough of them, the evaluation stops once a candidate is accepted with StopOnFirstFound,
+// with accept the features containing p are also filtered by accept before stopping
+func (s *Server) testCandidates(ctx context.Context, ds *dataset, ls insideout.LoopStore, cands []candidate, p s2.Point,
+	accept func(*insideout.Feature) bool) error {
+	stop := s.opts.StopOnFirstFound
+	eval := func(ctx context.Context, c *candidate) error {
+		if err := queryDone(ctx); err != nil {
+			return err
+		}
+		f, accepted, err := s.testCandidate(ctx, ds, ls, c.fid, p, c.test)
+		if err != nil {
+			return queryError(ctx, err)
+		}
+		if accepted && accept != nil {
+			accepted = accept(f)
+		}
+		c.f, c.accepted, c.done = f, accepted, true
+		return nil
+	}
+
+	workers := s.opts.PIPWorkers
+	if workers > len(cands) {
+		workers = len(cands)
+	}
+	if workers <= 1 || len(cands) < pipWorkersMinCandidates {
+		for i := range cands {
+			if err := eval(ctx, &cands[i]); err != nil {
+				return err
+			}
+			if stop && cands[i].accepted {
+				return nil
+			}
+		}
+		return nil
+	}
+
+	// each worker takes the next candidate, the results stay in the candidates order
+	next, found := int64(-1), int32(0)
+	g, gctx := errgroup.WithContext(ctx)
+	for w := 0; w < workers; w++ {
+		g.Go(func() error {
+			for {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= len(cands) || (stop && atomic.LoadInt32(&found) == 1) {
+					return nil
+				}
+				if err := eval(gctx, &cands[i]); err != nil {
+					return err
+				}
+				if stop && cands[i].accepted {
+					atomic.StoreInt32(&found, 1)
+				}
+			}
+		})
+	}
+	return g.Wait()
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/insidesvc"
+)
+
+func TestServer_PIPWorkers(t *testing.T) {
+	// 20 nested squares centered on 0.5 0.5
+	var squares []nestedSquare
+	for i := 0; i < 20; i++ {
+		squares = append(squares, nestedSquare{name: fmt.Sprintf("sq%02d", i), size: 1 - float64(i)*0.04})
+	}
+	storage, clean := setupNested(t, squares)
+	defer clean()
+
+	ctx := context.Background()
+	names := func(s *Server, lat, lng float64) []string {
+		// exact tests the inside candidates too
+		resp, err := s.Within(ctx, &insidesvc.WithinRequest{Lat: lat, Lng: lng, Exact: true, RemoveGeometries: true})
+		require.NoError(t, err)
+		var names []string
+		for _, r := range resp.Responses {
+			names = append(names, r.Feature.Properties["name"].GetStringValue())
+		}
+		return names
+	}
+
+	seq, err := New(storage, log.NewNopLogger(), nil, Options{Strategy: insideout.InsideTreeStrategy})
+	require.NoError(t, err)
+	conc, err := New(storage, log.NewNopLogger(), nil, Options{Strategy: insideout.InsideTreeStrategy, PIPWorkers: 4})
+	require.NoError(t, err)
+
+	for _, ll := range [][2]float64{{0.5, 0.5}, {0.5, 0.85}, {0.1, 0.9}, {0.98, 0.5}, {1.5, 0.5}} {
+		expected := names(seq, ll[0], ll[1])
+		require.Equal(t, expected, names(conc, ll[0], ll[1]), "at %v", ll)
+	}
+	require.Len(t, names(conc, 0.5, 0.5), 20)
+	require.Equal(t, []string{"sq00", "sq01", "sq02"}, names(conc, 0.5, 0.95))
+
+	// the evaluation stops once a feature is found
+	conc.opts.StopOnFirstFound = true
+	require.Len(t, names(conc, 0.5, 0.5), 1)
+	seq.opts.StopOnFirstFound = true
+	require.Len(t, names(seq, 0.5, 0.5), 1)
+
+	// canceled
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = conc.Within(cctx, &insidesvc.WithinRequest{Lat: 0.5, Lng: 0.5, Exact: true})
+	require.Error(t, err)
+}
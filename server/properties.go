@@ -0,0 +1,322 @@
+package server
+
+import (
+	"context"
+	"sort"
+	"strconv"
+
+	"github.com/go-kit/kit/log/level"
+	"go.opentelemetry.io/otel/label"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/insidesvc"
+)
+
+// maxListFeatures the max features returned by a ListFeatures request
+const maxListFeatures = 1000
+
+// propertyIndex the ids of the features by value of some of their properties
+type propertyIndex struct {
+	props map[string]bool
+	// ids the sorted ids of the features by insideout.PropertyIndexKey
+	ids map[string][]uint32
+	// keys the keys of each feature, to remove it
+	keys map[uint32][]string
+	// idProperty the indexed property holding the unique external id of each feature, empty for none
+	idProperty string
+}
+
+// buildPropertyIndex returns the index of the properties props of all the features of storage
+func buildPropertyIndex(storage insideout.Store, props []string) (*propertyIndex, error) {
+	pi := newPropertyIndex(props)
+	err := storage.LoadAllFeatures(func(fs *insideout.FeatureStorage, id uint32) error {
+		pi.add(id, fs.Properties)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pi, nil
+}
+
+// newPropertyIndex returns an empty index of the properties props
+func newPropertyIndex(props []string) *propertyIndex {
+	pi := &propertyIndex{
+		props: make(map[string]bool, len(props)),
+		ids:   make(map[string][]uint32),
+		keys:  make(map[uint32][]string),
+	}
+	for _, p := range props {
+		pi.props[p] = true
+	}
+	return pi
+}
+
+// storedPropertyIndex returns the index of the properties built from the one stored at index time
+func storedPropertyIndex(stored *insideout.PropertyIndex) *propertyIndex {
+	pi := newPropertyIndex(stored.Properties)
+	pi.idProperty = stored.IDProperty
+	for k, ids := range stored.IDs {
+		pi.ids[k] = ids
+		for _, id := range ids {
+			pi.keys[id] = append(pi.keys[id], k)
+		}
+	}
+	return pi
+}
+
+// loadPropertyIndex returns the index of the properties stored in storage by the indexer and of the PropertyIndex
+// option, built from a scan of the features when the stored one is stale, misses some of them or was never stored,
+// nil when no property is indexed
+func (s *Server) loadPropertyIndex(name string, storage insideout.Store, infos *insideout.IndexInfos, opts Options) (
+	*propertyIndex, error) {
+	var stored *insideout.PropertyIndex
+	if pis, ok := storage.(insideout.PropertyIndexStore); ok {
+		var err error
+		stored, err = pis.LoadPropertyIndex()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if stored == nil {
+		if len(opts.PropertyIndex) == 0 {
+			return nil, nil
+		}
+		return buildPropertyIndex(storage, opts.PropertyIndex)
+	}
+
+	props := append([]string{}, stored.Properties...)
+	covered := true
+	for _, p := range opts.PropertyIndex {
+		if !stored.Indexes(p) {
+			props = append(props, p)
+			covered = false
+		}
+	}
+	// the features written since are missing from the stored index
+	if !stored.IndexTime.Equal(infos.IndexTime) {
+		level.Warn(s.logger).Log("msg", "stale property index, the properties are indexed again",
+			"dataset", name, "property_index_time", stored.IndexTime, "index_time", infos.IndexTime)
+		covered = false
+	}
+	if !covered {
+		pi, err := buildPropertyIndex(storage, props)
+		if err != nil {
+			return nil, err
+		}
+		pi.idProperty = stored.IDProperty
+		return pi, nil
+	}
+	return storedPropertyIndex(stored), nil
+}
+
+// add indexes the properties of the feature id
+func (pi *propertyIndex) add(id uint32, props map[string]interface{}) {
+	for name, v := range props {
+		if !pi.props[name] {
+			continue
+		}
+		k := insideout.PropertyIndexKey(name, v)
+		ids := pi.ids[k]
+		i := sort.Search(len(ids), func(i int) bool { return ids[i] >= id })
+		if i < len(ids) && ids[i] == id {
+			continue
+		}
+		ids = append(ids, 0)
+		copy(ids[i+1:], ids[i:])
+		ids[i] = id
+		pi.ids[k] = ids
+		pi.keys[id] = append(pi.keys[id], k)
+	}
+}
+
+// remove removes the feature id from the index
+func (pi *propertyIndex) remove(id uint32) {
+	for _, k := range pi.keys[id] {
+		ids := pi.ids[k]
+		i := sort.Search(len(ids), func(i int) bool { return ids[i] >= id })
+		if i == len(ids) || ids[i] != id {
+			continue
+		}
+		ids = append(ids[:i], ids[i+1:]...)
+		if len(ids) == 0 {
+			delete(pi.ids, k)
+			continue
+		}
+		pi.ids[k] = ids
+	}
+	delete(pi.keys, id)
+}
+
+// candidates returns the sorted ids of the features that may match pf, from its condition on an indexed property
+// matching the fewest features, false when pf has no equality condition on an indexed property
+func (pi *propertyIndex) candidates(pf propertyFilter) ([]uint32, bool) {
+	var best []uint32
+	found := false
+	for _, c := range pf {
+		if c.negate || !pi.props[c.key] {
+			continue
+		}
+		// a condition value matches a string, a number or a boolean property
+		keys := []string{insideout.PropertyIndexKey(c.key, c.value)}
+		if f, err := strconv.ParseFloat(c.value, 64); err == nil {
+			keys = append(keys, insideout.PropertyIndexKey(c.key, f))
+		}
+		if b, err := strconv.ParseBool(c.value); err == nil {
+			keys = append(keys, insideout.PropertyIndexKey(c.key, b))
+		}
+		var ids []uint32
+		seen := make(map[uint32]bool)
+		for _, k := range keys {
+			for _, id := range pi.ids[k] {
+				if !seen[id] {
+					seen[id] = true
+					ids = append(ids, id)
+				}
+			}
+		}
+		if !found || len(ids) < len(best) {
+			best, found = ids, true
+		}
+	}
+	sort.Slice(best, func(i, j int) bool { return best[i] < best[j] })
+	return best, found
+}
+
+// externalIDs returns the ids of the features with the external id v, from its string, number or boolean value
+func (pi *propertyIndex) externalIDs(v string) []uint32 {
+	ids, _ := pi.candidates(propertyFilter{{key: pi.idProperty, value: v}})
+	return ids
+}
+
+// checkExternalID returns an error if the properties props of the feature id written to ds have no external id
+// or the external id of another feature, the caller must hold s.mu
+func checkExternalID(ds *dataset, id uint32, props map[string]interface{}) error {
+	if ds.properties == nil || ds.properties.idProperty == "" {
+		return nil
+	}
+	v, ok := props[ds.properties.idProperty]
+	if !ok || v == nil {
+		return status.Errorf(codes.InvalidArgument, "missing id property %s", ds.properties.idProperty)
+	}
+	for _, other := range ds.properties.ids[insideout.PropertyIndexKey(ds.properties.idProperty, v)] {
+		if other != id {
+			return status.Errorf(codes.AlreadyExists, "feature %d has the same id property %s %v", other, ds.properties.idProperty, v)
+		}
+	}
+	return nil
+}
+
+// reindexProperties updates the feature id of ds in its property index with its properties props,
+// nil for a deleted feature, the caller must hold s.mu
+func reindexProperties(ds *dataset, id uint32, props map[string]interface{}) {
+	if ds.properties == nil {
+		return
+	}
+	ds.properties.remove(id)
+	if props != nil {
+		ds.properties.add(id, props)
+	}
+}
+
+// ListFeatures returns the features matching the filter of req ordered by id exposed via gRPC,
+// from the property index when the filter has an equality condition on an indexed property,
+// from a scan of the storage otherwise
+func (s *Server) ListFeatures(
+	ctx context.Context, req *insidesvc.ListFeaturesRequest,
+) (resp *insidesvc.ListFeaturesResponse, terr error) {
+	ctx, span := tracer().Start(ctx, "ListFeatures")
+	defer span.End()
+
+	defer func() { s.handleError(ctx, terr, span) }()
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	pf, err := parsePropertyFilter(req.Filter)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	fields := parseFields(req.SelectProperties)
+	if req.Limit < 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid limit")
+	}
+	limit := int(req.Limit)
+	if limit == 0 || limit > maxListFeatures {
+		limit = maxListFeatures
+	}
+	var after *uint32
+	if req.Cursor != "" {
+		fid, err := DecodeCursor(req.Cursor)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		after = &fid.ID
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ds, err := s.dataset(req.Dataset)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []uint32
+	indexed := false
+	if ds.properties != nil {
+		ids, indexed = ds.properties.candidates(pf)
+	}
+	if !indexed {
+		// the features matching are read again for the page only
+		err := ds.storage.LoadAllFeatures(func(fs *insideout.FeatureStorage, id uint32) error {
+			if err := queryDone(ctx); err != nil {
+				return err
+			}
+			if (after == nil || id > *after) && s.live(ctx, fs.Properties) && pf.Match(fs.Properties) {
+				ids = append(ids, id)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, queryError(ctx, err)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	}
+	span.SetAttributes(
+		label.Bool("indexed", indexed),
+		label.Int("candidates_count", len(ids)),
+	)
+
+	resp = &insidesvc.ListFeaturesResponse{}
+	var last uint32
+	for _, id := range ids {
+		if after != nil && id <= *after {
+			continue
+		}
+		if err := queryDone(ctx); err != nil {
+			return nil, err
+		}
+		f, err := s.feature(ctx, ds, id)
+		if err != nil {
+			return nil, queryError(ctx, err)
+		}
+		if !s.live(ctx, f.Properties) || !pf.Match(f.Properties) {
+			continue
+		}
+		if len(resp.Responses) == limit {
+			resp.NextCursor = EncodeCursor(insideout.FeatureIndexResponse{ID: last})
+			break
+		}
+		fresp, err := newFeatureResponse(f, insideout.FeatureIndexResponse{ID: id}, true, fields)
+		if err != nil {
+			return nil, err
+		}
+		resp.Responses = append(resp.Responses, fresp)
+		last = id
+	}
+
+	return resp, nil
+}
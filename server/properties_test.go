@@ -0,0 +1,173 @@
+package server
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/insidesvc"
+)
+
+func TestServer_ListFeatures(t *testing.T) {
+	storage, clean := setupNested(t, []nestedSquare{
+		{name: "country", size: 1, props: map[string]interface{}{"admin_level": 2.0, "capital": false}},
+		{name: "city", size: 0.2, props: map[string]interface{}{"admin_level": 8.0, "capital": true}},
+		{name: "zone", size: 0.6},
+		{name: "region", size: 0.8, props: map[string]interface{}{"admin_level": 4.0}},
+		{name: "town", size: 0.1, props: map[string]interface{}{"admin_level": 8.0, "capital": false}},
+	})
+	defer clean()
+
+	ctx := context.Background()
+	for name, props := range map[string][]string{"scan": nil, "indexed": {"admin_level", "capital"}} {
+		t.Run(name, func(t *testing.T) {
+			s, err := New(storage, log.NewNopLogger(), nil, Options{Strategy: insideout.DBStrategy, PropertyIndex: props})
+			require.NoError(t, err)
+
+			list := func(req *insidesvc.ListFeaturesRequest) ([]string, string) {
+				resp, err := s.ListFeatures(ctx, req)
+				require.NoError(t, err)
+				var names []string
+				for _, r := range resp.Responses {
+					require.Nil(t, r.Feature.Geometry)
+					names = append(names, r.Feature.Properties["name"].GetStringValue())
+				}
+				return names, resp.NextCursor
+			}
+
+			names, cursor := list(&insidesvc.ListFeaturesRequest{})
+			require.Equal(t, []string{"country", "city", "zone", "region", "town"}, names)
+			require.Empty(t, cursor)
+
+			names, _ = list(&insidesvc.ListFeaturesRequest{Filter: "admin_level=8"})
+			require.Equal(t, []string{"city", "town"}, names)
+			names, _ = list(&insidesvc.ListFeaturesRequest{Filter: "admin_level=8.0,capital=false"})
+			require.Equal(t, []string{"town"}, names)
+			names, _ = list(&insidesvc.ListFeaturesRequest{Filter: "capital=true,name!=city"})
+			require.Empty(t, names)
+			names, _ = list(&insidesvc.ListFeaturesRequest{Filter: "name=zone"})
+			require.Equal(t, []string{"zone"}, names)
+
+			// paginated
+			names, cursor = list(&insidesvc.ListFeaturesRequest{Filter: "admin_level!=4", Limit: 2})
+			require.Equal(t, []string{"country", "city"}, names)
+			names, cursor = list(&insidesvc.ListFeaturesRequest{Filter: "admin_level!=4", Limit: 2, Cursor: cursor})
+			require.Equal(t, []string{"zone", "town"}, names)
+			require.Empty(t, cursor)
+
+			resp, err := s.ListFeatures(ctx, &insidesvc.ListFeaturesRequest{Filter: "admin_level=2", SelectProperties: "capital"})
+			require.NoError(t, err)
+			require.Len(t, resp.Responses, 1)
+			require.Equal(t, uint32(0), resp.Responses[0].Id)
+			require.Nil(t, resp.Responses[0].Feature.Properties["name"])
+			require.NotNil(t, resp.Responses[0].Feature.Properties["capital"])
+
+			_, err = s.ListFeatures(ctx, &insidesvc.ListFeaturesRequest{Filter: "admin_level"})
+			require.Equal(t, codes.InvalidArgument, status.Code(err))
+			_, err = s.ListFeatures(ctx, &insidesvc.ListFeaturesRequest{Limit: -1})
+			require.Equal(t, codes.InvalidArgument, status.Code(err))
+			_, err = s.ListFeatures(ctx, &insidesvc.ListFeaturesRequest{Dataset: "unknown"})
+			require.Equal(t, codes.NotFound, status.Code(err))
+
+			r := mux.NewRouter()
+			for _, route := range s.APIRoutes() {
+				r.Handle(route.Path, route.Handler).Methods(route.Methods...)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest("GET", "/api/features?prop.admin_level=8&prop.capital=true&fields=name", nil))
+			require.Equal(t, 200, w.Code)
+			lresp := &insidesvc.ListFeaturesResponse{}
+			require.NoError(t, jsonpb.Unmarshal(w.Body, lresp))
+			require.Len(t, lresp.Responses, 1)
+			require.Equal(t, "city", lresp.Responses[0].Feature.Properties["name"].GetStringValue())
+
+			w = httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest("GET", "/api/features?prop.name=a,b", nil))
+			require.Equal(t, 400, w.Code)
+		})
+	}
+}
+
+func TestServer_ListFeaturesWrite(t *testing.T) {
+	storage, clean := setupRW(t, "A", 0)
+	defer clean()
+
+	s, err := New(storage, log.NewNopLogger(), nil, Options{
+		Strategy: insideout.DBStrategy, ReadWrite: true, PropertyIndex: []string{"name"},
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	count := func(filter string) int {
+		resp, err := s.ListFeatures(ctx, &insidesvc.ListFeaturesRequest{Filter: filter})
+		require.NoError(t, err)
+		return len(resp.Responses)
+	}
+
+	require.Equal(t, 1, count("name=A"))
+	wresp, err := s.InsertFeature(ctx, &insidesvc.InsertFeatureRequest{Feature: squareFeature("B", 10)})
+	require.NoError(t, err)
+	require.Equal(t, 1, count("name=B"))
+
+	_, err = s.UpdateFeature(ctx, &insidesvc.UpdateFeatureRequest{Id: wresp.Id, Feature: squareFeature("C", 10)})
+	require.NoError(t, err)
+	require.Equal(t, 0, count("name=B"))
+	require.Equal(t, 1, count("name=C"))
+
+	_, err = s.DeleteFeature(ctx, &insidesvc.DeleteFeatureRequest{Id: wresp.Id})
+	require.NoError(t, err)
+	require.Equal(t, 0, count("name=C"))
+	require.Equal(t, 1, count(""))
+}
+
+func TestServer_StoredPropertyIndex(t *testing.T) {
+	storage, clean := setupRW(t, "A", 0)
+	defer clean()
+
+	pi, err := insideout.BuildPropertyIndex(storage, []string{"name"})
+	require.NoError(t, err)
+	require.NoError(t, storage.(insideout.PropertyIndexStore).StorePropertyIndex(pi))
+
+	ctx := context.Background()
+	list := func(s *Server, filter string) []string {
+		resp, err := s.ListFeatures(ctx, &insidesvc.ListFeaturesRequest{Filter: filter})
+		require.NoError(t, err)
+		var names []string
+		for _, r := range resp.Responses {
+			names = append(names, r.Feature.Properties["name"].GetStringValue())
+		}
+		return names
+	}
+
+	opts := Options{Strategy: insideout.DBStrategy, ReadWrite: true}
+	s, err := New(storage, log.NewNopLogger(), nil, opts)
+	require.NoError(t, err)
+	ds, err := s.dataset("")
+	require.NoError(t, err)
+	require.NotNil(t, ds.properties)
+	require.True(t, ds.properties.props["name"])
+	require.Equal(t, []string{"A"}, list(s, "name=A"))
+
+	// the written features are indexed
+	_, err = s.InsertFeature(ctx, &insidesvc.InsertFeatureRequest{Feature: squareFeature("B", 10)})
+	require.NoError(t, err)
+	require.Equal(t, []string{"B"}, list(s, "name=B"))
+
+	// the stored index is stale after the write, indexed again with the PropertyIndex option
+	opts.PropertyIndex = []string{"kind"}
+	s, err = New(storage, log.NewNopLogger(), nil, opts)
+	require.NoError(t, err)
+	ds, err = s.dataset("")
+	require.NoError(t, err)
+	require.True(t, ds.properties.props["name"])
+	require.True(t, ds.properties.props["kind"])
+	require.Equal(t, []string{"B"}, list(s, "name=B"))
+}
@@ -0,0 +1,120 @@
+package querylog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+)
+
+// validTable matches the table names, optionally prefixed by a database or schema
+var validTable = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// clickHouseTimeFormat the DateTime64(3) text format
+const clickHouseTimeFormat = "2006-01-02 15:04:05.000"
+
+// ClickHouse a Writer inserting the entries with the ClickHouse HTTP interface
+type ClickHouse struct {
+	url    *url.URL
+	table  string
+	client *http.Client
+}
+
+type clickHouseRow struct {
+	Time      string   `json:"time"`
+	Dataset   string   `json:"dataset"`
+	Lat       float64  `json:"lat"`
+	Lng       float64  `json:"lng"`
+	IDs       []uint32 `json:"ids"`
+	Matches   int      `json:"matches"`
+	LatencyUS int64    `json:"latency_us"`
+}
+
+// NewClickHouse returns a ClickHouse Writer to the HTTP interface at rawurl, like http://localhost:8123/?database=default,
+// the credentials are passed in its user and password query parameters, creates table when missing
+func NewClickHouse(rawurl, table string, timeout time.Duration) (*ClickHouse, error) {
+	if !validTable.MatchString(table) {
+		return nil, fmt.Errorf("invalid query log table name %q", table)
+	}
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ClickHouse URL: %w", err)
+	}
+
+	c := &ClickHouse{url: u, table: table, client: &http.Client{Timeout: timeout}}
+	err = c.exec(context.Background(), "CREATE TABLE IF NOT EXISTS "+table+` (
+		time DateTime64(3, 'UTC'),
+		dataset LowCardinality(String),
+		lat Float64,
+		lng Float64,
+		ids Array(UInt32),
+		matches UInt32,
+		latency_us UInt64
+	) ENGINE = MergeTree ORDER BY (dataset, time)`, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ClickHouse query log table %s: %w", table, err)
+	}
+	return c, nil
+}
+
+// Write inserts the entries in a single INSERT in the JSONEachRow format
+func (c *ClickHouse) Write(ctx context.Context, entries []Entry) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range entries {
+		ids := e.IDs
+		if ids == nil {
+			ids = []uint32{}
+		}
+		err := enc.Encode(clickHouseRow{
+			Time:      e.Time.UTC().Format(clickHouseTimeFormat),
+			Dataset:   e.Dataset,
+			Lat:       e.Lat,
+			Lng:       e.Lng,
+			IDs:       ids,
+			Matches:   len(e.IDs),
+			LatencyUS: e.Latency.Microseconds(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return c.exec(ctx, "INSERT INTO "+c.table+" FORMAT JSONEachRow", &buf)
+}
+
+// exec posts query with body as its data
+func (c *ClickHouse) exec(ctx context.Context, query string, body io.Reader) error {
+	u := *c.url
+	q := u.Query()
+	q.Set("query", query)
+	u.RawQuery = q.Encode()
+
+	if body == nil {
+		body = http.NoBody
+	}
+	req, err := http.NewRequest("POST", u.String(), body)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("ClickHouse replied %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// Close does nothing, the HTTP interface is stateless
+func (c *ClickHouse) Close() error {
+	return nil
+}
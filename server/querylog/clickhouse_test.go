@@ -0,0 +1,61 @@
+package querylog
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClickHouse(t *testing.T) {
+	var mu sync.Mutex
+	var queries []string
+	var rows []clickHouseRow
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		require.Equal(t, "analytics", r.URL.Query().Get("database"))
+		q := r.URL.Query().Get("query")
+		queries = append(queries, q)
+		if strings.HasPrefix(q, "INSERT") {
+			sc := bufio.NewScanner(r.Body)
+			for sc.Scan() {
+				var row clickHouseRow
+				require.NoError(t, json.Unmarshal(sc.Bytes(), &row))
+				rows = append(rows, row)
+			}
+		}
+		if strings.Contains(q, "broken") {
+			http.Error(w, "Code: 60. DB::Exception: Table broken doesn't exist", http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	c, err := NewClickHouse(ts.URL+"/?database=analytics", "query_log", time.Second)
+	require.NoError(t, err)
+	require.Len(t, queries, 1)
+	require.True(t, strings.HasPrefix(queries[0], "CREATE TABLE IF NOT EXISTS query_log"))
+
+	at := time.Date(2020, 5, 1, 12, 30, 0, 250e6, time.FixedZone("EDT", -4*3600))
+	err = c.Write(context.Background(), []Entry{
+		{Time: at, Dataset: "countries", Lat: 48.8, Lng: 2.3, IDs: []uint32{3, 7}, Latency: 1500 * time.Microsecond},
+		{Time: at, Dataset: "countries", Lat: 0, Lng: -30},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "INSERT INTO query_log FORMAT JSONEachRow", queries[1])
+	require.Equal(t, []clickHouseRow{
+		{Time: "2020-05-01 16:30:00.250", Dataset: "countries", Lat: 48.8, Lng: 2.3, IDs: []uint32{3, 7}, Matches: 2, LatencyUS: 1500},
+		{Time: "2020-05-01 16:30:00.250", Dataset: "countries", Lat: 0, Lng: -30, IDs: []uint32{}},
+	}, rows)
+
+	_, err = NewClickHouse(ts.URL+"/?database=analytics", "broken", time.Second)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "doesn't exist")
+}
@@ -0,0 +1,100 @@
+package querylog
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Postgres a Writer copying the entries to a PostgreSQL table
+type Postgres struct {
+	db *sql.DB
+	// copy the COPY statement of the table
+	copy string
+}
+
+// NewPostgres returns a Postgres Writer to the database at connString, a lib/pq URL, creates table when missing
+func NewPostgres(connString, table string) (*Postgres, error) {
+	if !validTable.MatchString(table) {
+		return nil, fmt.Errorf("invalid query log table name %q", table)
+	}
+	db, err := sql.Open("postgres", connString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PostgreSQL connection: %w", err)
+	}
+	db.SetMaxOpenConns(2)
+
+	p := &Postgres{db: db, copy: pq.CopyIn(table, pgColumns...)}
+	if i := strings.IndexByte(table, '.'); i > 0 {
+		p.copy = pq.CopyInSchema(table[:i], table[i+1:], pgColumns...)
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS ` + quoteTable(table) + ` (
+		time timestamptz NOT NULL,
+		dataset text NOT NULL,
+		lat double precision NOT NULL,
+		lng double precision NOT NULL,
+		ids bigint[] NOT NULL,
+		matches integer NOT NULL,
+		latency_us bigint NOT NULL
+	)`)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create PostgreSQL query log table %s: %w", table, err)
+	}
+	return p, nil
+}
+
+// pgColumns the columns of the COPY
+var pgColumns = []string{"time", "dataset", "lat", "lng", "ids", "matches", "latency_us"}
+
+// quoteTable quotes the table and its optional schema
+func quoteTable(table string) string {
+	parts := strings.Split(table, ".")
+	for i, p := range parts {
+		parts[i] = pq.QuoteIdentifier(p)
+	}
+	return strings.Join(parts, ".")
+}
+
+// Write copies the entries in a single transaction
+func (p *Postgres) Write(ctx context.Context, entries []Entry) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, p.copy)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		ids := make([]int64, len(e.IDs))
+		for i, id := range e.IDs {
+			ids[i] = int64(id)
+		}
+		_, err := stmt.ExecContext(ctx, e.Time.UTC(), e.Dataset, e.Lat, e.Lng, pq.Array(ids), len(e.IDs),
+			e.Latency.Microseconds())
+		if err != nil {
+			stmt.Close()
+			return err
+		}
+	}
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		stmt.Close()
+		return err
+	}
+	if err := stmt.Close(); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Close closes the connections
+func (p *Postgres) Close() error {
+	return p.db.Close()
+}
@@ -0,0 +1,12 @@
+package querylog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuoteTable(t *testing.T) {
+	require.Equal(t, `"query_log"`, quoteTable("query_log"))
+	require.Equal(t, `"analytics"."query_log"`, quoteTable("analytics.query_log"))
+}
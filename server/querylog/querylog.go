@@ -0,0 +1,178 @@
+// Package querylog exports the within queries served by insided, their point, matches and latency,
+// in batches to ClickHouse or PostgreSQL, for heatmaps and coverage analytics over the production traffic
+package querylog
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// DefaultTable the table receiving the queries when none is given
+const DefaultTable = "insideout_query_log"
+
+// flushTimeout caps the flush of the queued entries on shutdown
+const flushTimeout = 10 * time.Second
+
+var (
+	exportedCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "insided_querylog",
+		Name:      "exported_total",
+		Help:      "The total number of query log entries exported",
+	})
+	droppedCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "insided_querylog",
+		Name:      "dropped_total",
+		Help:      "The total number of query log entries dropped because the queue was full or the export failed",
+	})
+	errorsCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "insided_querylog",
+		Name:      "errors_total",
+		Help:      "The total number of failed batch exports",
+	})
+)
+
+// Entry a query served
+type Entry struct {
+	Time    time.Time
+	Dataset string
+	Lat     float64
+	Lng     float64
+	// IDs the ids of the matched features
+	IDs     []uint32
+	Latency time.Duration
+}
+
+// Writer exports a batch of entries to a database
+type Writer interface {
+	Write(ctx context.Context, entries []Entry) error
+	Close() error
+}
+
+// Options for a Sink
+type Options struct {
+	// BatchSize the max number of entries exported at once, 1000 when 0
+	BatchSize int
+
+	// FlushInterval the max time an entry waits to be exported, 5s when 0
+	FlushInterval time.Duration
+
+	// QueueSize the max number of entries waiting to be exported, the entries are dropped when it's full,
+	// 10 batches when 0
+	QueueSize int
+}
+
+// Sink queues the entries and exports them in batches with a Writer
+type Sink struct {
+	w      Writer
+	opts   Options
+	queue  chan Entry
+	logger log.Logger
+}
+
+// New returns a Sink exporting with w, call Run to export the entries
+func New(w Writer, opts Options, logger log.Logger) *Sink {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 1000
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = 5 * time.Second
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 10 * opts.BatchSize
+	}
+	return &Sink{
+		w:      w,
+		opts:   opts,
+		queue:  make(chan Entry, opts.QueueSize),
+		logger: log.With(logger, "component", "querylog"),
+	}
+}
+
+// Open returns the Writer of the database at url, a postgres:// or postgresql:// lib/pq URL for PostgreSQL,
+// an http:// or https:// URL of the ClickHouse HTTP interface for ClickHouse, table is created when missing
+func Open(url, table string) (Writer, error) {
+	if table == "" {
+		table = DefaultTable
+	}
+	switch {
+	case strings.HasPrefix(url, "postgres://"), strings.HasPrefix(url, "postgresql://"):
+		return NewPostgres(url, table)
+	case strings.HasPrefix(url, "http://"), strings.HasPrefix(url, "https://"):
+		return NewClickHouse(url, table, 10*time.Second)
+	}
+	return nil, fmt.Errorf("unsupported query log URL %s, expecting postgres:// or ClickHouse http(s)://", url)
+}
+
+// Log queues e without blocking, e is dropped when the queue is full
+func (s *Sink) Log(e Entry) {
+	select {
+	case s.queue <- e:
+	default:
+		droppedCounter.Inc()
+	}
+}
+
+// Run exports the queued entries when a batch is full or every flush interval until ctx is done,
+// then exports the remaining entries and closes the writer
+func (s *Sink) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.opts.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Entry, 0, s.opts.BatchSize)
+	for {
+		select {
+		case <-ctx.Done():
+			s.drain(batch)
+			return s.w.Close()
+		case e := <-s.queue:
+			batch = append(batch, e)
+			if len(batch) < s.opts.BatchSize {
+				continue
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+		}
+		s.export(ctx, batch)
+		batch = batch[:0]
+	}
+}
+
+// drain exports batch and the queued entries, with a context of its own since the Run one is done
+func (s *Sink) drain(batch []Entry) {
+	ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+	defer cancel()
+	for {
+		select {
+		case e := <-s.queue:
+			batch = append(batch, e)
+			if len(batch) < s.opts.BatchSize {
+				continue
+			}
+		default:
+		}
+		if len(batch) == 0 {
+			return
+		}
+		s.export(ctx, batch)
+		batch = batch[:0]
+	}
+}
+
+func (s *Sink) export(ctx context.Context, batch []Entry) {
+	if err := s.w.Write(ctx, batch); err != nil {
+		errorsCounter.Inc()
+		droppedCounter.Add(float64(len(batch)))
+		level.Warn(s.logger).Log("msg", "failed to export query log", "error", err, "entries", len(batch))
+		return
+	}
+	exportedCounter.Add(float64(len(batch)))
+}
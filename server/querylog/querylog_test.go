@@ -0,0 +1,108 @@
+package querylog
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+)
+
+// memWriter records the batches
+type memWriter struct {
+	mu      sync.Mutex
+	batches [][]Entry
+	err     error
+	closed  bool
+}
+
+func (w *memWriter) Write(ctx context.Context, entries []Entry) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return w.err
+	}
+	w.batches = append(w.batches, append([]Entry(nil), entries...))
+	return nil
+}
+
+func (w *memWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	return nil
+}
+
+func (w *memWriter) sizes() []int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var sizes []int
+	for _, b := range w.batches {
+		sizes = append(sizes, len(b))
+	}
+	return sizes
+}
+
+func TestSink_Batches(t *testing.T) {
+	w := &memWriter{}
+	s := New(w, Options{BatchSize: 2, FlushInterval: time.Hour}, log.NewNopLogger())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+
+	for i := 0; i < 5; i++ {
+		s.Log(Entry{Lat: float64(i), IDs: []uint32{uint32(i)}})
+	}
+	require.Eventually(t, func() bool { return len(w.sizes()) == 2 }, time.Second, time.Millisecond)
+	require.Equal(t, []int{2, 2}, w.sizes())
+
+	// the last entry is exported on shutdown
+	cancel()
+	require.NoError(t, <-done)
+	require.Equal(t, []int{2, 2, 1}, w.sizes())
+	require.Equal(t, 4.0, w.batches[2][0].Lat)
+	require.True(t, w.closed)
+}
+
+func TestSink_FlushInterval(t *testing.T) {
+	w := &memWriter{}
+	s := New(w, Options{BatchSize: 100, FlushInterval: 10 * time.Millisecond}, log.NewNopLogger())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+
+	s.Log(Entry{Lat: 1})
+	require.Eventually(t, func() bool { return len(w.sizes()) == 1 }, time.Second, time.Millisecond)
+}
+
+func TestSink_Drops(t *testing.T) {
+	w := &memWriter{err: errors.New("unavailable")}
+	s := New(w, Options{BatchSize: 1, QueueSize: 1}, log.NewNopLogger())
+
+	// the queue is full without Run, the second entry is dropped
+	s.Log(Entry{Lat: 1})
+	s.Log(Entry{Lat: 2})
+	require.Len(t, s.queue, 1)
+
+	// failing exports don't stop Run
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+	require.Eventually(t, func() bool { return len(s.queue) == 0 }, time.Second, time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+	require.Empty(t, w.sizes())
+}
+
+func TestOpen(t *testing.T) {
+	_, err := Open("mysql://localhost/logs", "")
+	require.Error(t, err)
+
+	_, err = Open("postgres://localhost/logs", "logs; DROP TABLE users")
+	require.Error(t, err)
+}
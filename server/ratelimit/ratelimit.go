@@ -0,0 +1,226 @@
+// Package ratelimit limits the requests rate per client, using a token bucket per known API key or per source IP
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// apiKeyPrefix separates API keys from IPs so a key can't drain the bucket of an IP
+const apiKeyPrefix = "key:"
+
+// sweepInterval is how often clients not seen for this long are forgotten
+const sweepInterval = 5 * time.Minute
+
+// maxClients is the max number of buckets, the clients seen once it is reached share overflowKey
+const maxClients = 100000
+
+// overflowKey the bucket of the clients exceeding maxClients
+const overflowKey = "overflow"
+
+var exceededCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "insided_ratelimit",
+	Name:      "exceeded_total",
+	Help:      "The total number of requests rejected by the rate limiter",
+}, []string{"transport"})
+
+// Options for a Limiter
+type Options struct {
+	// Rate the sustained number of requests per second allowed per client, 0 to allow all the requests
+	Rate float64
+
+	// Burst the max number of requests a client can perform at once, defaults to the rate
+	Burst int
+
+	// KeyHeader the header (or gRPC metadata) holding the client API key,
+	// clients are identified by their source IP when empty or missing
+	KeyHeader string
+
+	// ValidKey reports whether an API key is known, only the known keys get their own bucket,
+	// the requests with an unknown key are limited by their source IP, all of them when nil
+	ValidKey func(key string) bool
+}
+
+// Limiter holds a token bucket per client
+type Limiter struct {
+	limit     rate.Limit
+	burst     int
+	keyHeader string
+	validKey  func(key string) bool
+
+	mu        sync.Mutex
+	clients   map[string]*client
+	lastSweep time.Time
+}
+
+type client struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// New returns a Limiter
+func New(opts Options) *Limiter {
+	l := &Limiter{
+		keyHeader: opts.KeyHeader,
+		validKey:  opts.ValidKey,
+		clients:   make(map[string]*client),
+		lastSweep: time.Now(),
+	}
+	l.SetRate(opts.Rate, opts.Burst)
+	return l
+}
+
+// SetRate changes the rate and the burst of all the clients, a burst <= 0 defaults to the rate,
+// a rate <= 0 allows all the requests
+func (l *Limiter) SetRate(r float64, burst int) {
+	limit := rate.Limit(r)
+	if r <= 0 {
+		limit = rate.Inf
+	}
+	if burst <= 0 {
+		burst = int(math.Ceil(r))
+	}
+
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limit, l.burst = limit, burst
+	for _, c := range l.clients {
+		c.limiter.SetLimitAt(now, limit)
+		c.limiter.SetBurstAt(now, burst)
+	}
+}
+
+// Allow reports whether the client identified by key can perform a request now,
+// once maxClients are tracked the new clients share one bucket
+func (l *Limiter) Allow(key string) bool {
+	now := time.Now()
+
+	l.mu.Lock()
+	if l.limit == rate.Inf {
+		l.mu.Unlock()
+		return true
+	}
+	if now.Sub(l.lastSweep) > sweepInterval {
+		l.sweep(now)
+	}
+	c, ok := l.clients[key]
+	if !ok && len(l.clients) >= maxClients {
+		key = overflowKey
+		c, ok = l.clients[key]
+	}
+	if !ok {
+		c = &client{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.clients[key] = c
+	}
+	c.lastSeen = now
+	l.mu.Unlock()
+
+	return c.limiter.AllowN(now, 1)
+}
+
+// sweep forgets the clients not seen since sweepInterval, the caller must hold l.mu
+func (l *Limiter) sweep(now time.Time) {
+	for k, c := range l.clients {
+		if now.Sub(c.lastSeen) > sweepInterval {
+			delete(l.clients, k)
+		}
+	}
+	l.lastSweep = now
+}
+
+// retryAfter is the delay in seconds before a token is available again
+func (l *Limiter) retryAfter() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(math.Ceil(1 / float64(l.limit)))
+}
+
+// Handler is an HTTP middleware replying 429 Too Many Requests to clients above the limit
+func (l *Limiter) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := hostIP(r.RemoteAddr)
+		if l.keyHeader != "" {
+			if v := r.Header.Get(l.keyHeader); l.known(v) {
+				key = apiKeyPrefix + v
+			}
+		}
+
+		if !l.Allow(key) {
+			exceededCounter.WithLabelValues("http").Inc()
+			w.Header().Set("Retry-After", strconv.Itoa(l.retryAfter()))
+			http.Error(w, "{\"msg\": \"rate limit exceeded\"}", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// UnaryServerInterceptor returns ResourceExhausted to clients above the limit
+func (l *Limiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+		if !l.Allow(l.grpcKey(ctx)) {
+			exceededCounter.WithLabelValues("grpc").Inc()
+			return nil, status.Error(codes.ResourceExhausted, "rate limit exceeded")
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns ResourceExhausted to clients above the limit,
+// a stream counts as one request when it is opened
+func (l *Limiter) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler) error {
+		if !l.Allow(l.grpcKey(ss.Context())) {
+			exceededCounter.WithLabelValues("grpc").Inc()
+			return status.Error(codes.ResourceExhausted, "rate limit exceeded")
+		}
+		return handler(srv, ss)
+	}
+}
+
+// known reports whether the API key v gets its own bucket
+func (l *Limiter) known(v string) bool {
+	return v != "" && l.validKey != nil && l.validKey(v)
+}
+
+// grpcKey returns the known API key from the metadata or the peer IP
+func (l *Limiter) grpcKey(ctx context.Context) string {
+	if l.keyHeader != "" {
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if v := md.Get(strings.ToLower(l.keyHeader)); len(v) > 0 && l.known(v[0]) {
+				return apiKeyPrefix + v[0]
+			}
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return hostIP(p.Addr.String())
+	}
+	return ""
+}
+
+// hostIP strips the port from addr
+func hostIP(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
@@ -0,0 +1,143 @@
+package ratelimit
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	l := New(Options{Rate: 1, Burst: 2})
+
+	require.True(t, l.Allow("a"))
+	require.True(t, l.Allow("a"))
+	require.False(t, l.Allow("a"))
+
+	// buckets are per client
+	require.True(t, l.Allow("b"))
+}
+
+func TestLimiter_MaxClients(t *testing.T) {
+	l := New(Options{Rate: 1})
+
+	for i := 0; i < maxClients; i++ {
+		require.True(t, l.Allow(strconv.Itoa(i)))
+	}
+	require.Len(t, l.clients, maxClients)
+
+	// the new clients share one bucket, the known ones keep theirs
+	require.True(t, l.Allow("a"))
+	require.False(t, l.Allow("b"))
+	require.False(t, l.Allow("0"))
+	require.Len(t, l.clients, maxClients+1)
+
+	// the idle clients are forgotten
+	l.sweep(time.Now().Add(2 * sweepInterval))
+	require.Empty(t, l.clients)
+	require.True(t, l.Allow("b"))
+}
+
+func TestLimiter_SetRate(t *testing.T) {
+	l := New(Options{Rate: 1})
+
+	require.True(t, l.Allow("a"))
+	require.False(t, l.Allow("a"))
+
+	// disabled
+	l.SetRate(0, 0)
+	for i := 0; i < 10; i++ {
+		require.True(t, l.Allow("a"))
+	}
+
+	// existing clients are limited again
+	l.SetRate(1, 2)
+	require.True(t, l.Allow("b"))
+	require.True(t, l.Allow("b"))
+	require.False(t, l.Allow("b"))
+}
+
+// validKey the known API keys of the tests
+func validKey(key string) bool {
+	return key == "secret" || key == "10.0.0.4"
+}
+
+func TestLimiter_Handler(t *testing.T) {
+	l := New(Options{Rate: 1, KeyHeader: "X-API-Key", ValidKey: validKey})
+	h := l.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	do := func(ip, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/within/48.8/2.2", nil)
+		req.RemoteAddr = ip + ":1234"
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	require.Equal(t, http.StatusOK, do("10.0.0.1", "").Code)
+	w := do("10.0.0.1", "")
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	require.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	// same IP another key, then a key named after an IP
+	require.Equal(t, http.StatusOK, do("10.0.0.1", "secret").Code)
+	require.Equal(t, http.StatusTooManyRequests, do("10.0.0.2", "secret").Code)
+	require.Equal(t, http.StatusOK, do("10.0.0.3", "10.0.0.4").Code)
+	require.Equal(t, http.StatusOK, do("10.0.0.4", "").Code)
+
+	// unknown keys are limited by IP, rotating them does not help
+	require.Equal(t, http.StatusOK, do("10.0.0.5", "k0").Code)
+	for i := 1; i < 10; i++ {
+		require.Equal(t, http.StatusTooManyRequests, do("10.0.0.5", "k"+strconv.Itoa(i)).Code)
+	}
+	require.Len(t, l.clients, 5)
+
+	// without ValidKey all the keys are unknown
+	l = New(Options{Rate: 1, KeyHeader: "X-API-Key"})
+	h = l.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	require.Equal(t, http.StatusOK, do("10.0.0.1", "secret").Code)
+	require.Equal(t, http.StatusTooManyRequests, do("10.0.0.1", "other").Code)
+}
+
+func TestLimiter_UnaryServerInterceptor(t *testing.T) {
+	l := New(Options{Rate: 1, KeyHeader: "X-API-Key", ValidKey: validKey})
+	i := l.UnaryServerInterceptor()
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{
+		Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 1234},
+	})
+
+	resp, err := i(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+	require.NoError(t, err)
+	require.Equal(t, "ok", resp)
+
+	_, err = i(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	kctx := metadata.NewIncomingContext(ctx, metadata.Pairs("x-api-key", "secret"))
+	_, err = i(kctx, nil, &grpc.UnaryServerInfo{}, handler)
+	require.NoError(t, err)
+
+	// rotating unknown keys share the bucket of the peer
+	for _, k := range []string{"k1", "k2", "k3"} {
+		kctx := metadata.NewIncomingContext(ctx, metadata.Pairs("x-api-key", k))
+		_, err = i(kctx, nil, &grpc.UnaryServerInfo{}, handler)
+		require.Equal(t, codes.ResourceExhausted, status.Code(err), k)
+	}
+}
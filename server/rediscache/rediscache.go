@@ -0,0 +1,70 @@
+// Package rediscache is a Redis backed cache shared by several insided instances
+package rediscache
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// Options for the Redis cache
+type Options struct {
+	Addr     string
+	Password string
+	DB       int
+
+	// Prefix prepended to all the keys, to share a Redis with other applications
+	Prefix string
+
+	// TTL of the entries, 0 for no expiration
+	TTL time.Duration
+}
+
+// Cache stores values in Redis
+type Cache struct {
+	client *redis.Client
+	prefix string
+	ttl    time.Duration
+}
+
+// New connects to Redis and returns a Cache
+func New(opts Options) (*Cache, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     opts.Addr,
+		Password: opts.Password,
+		DB:       opts.DB,
+	})
+	if err := client.Ping().Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("can't connect to redis %s: %w", opts.Addr, err)
+	}
+
+	return &Cache{
+		client: client,
+		prefix: opts.Prefix,
+		ttl:    opts.TTL,
+	}, nil
+}
+
+// Get returns the value for key, false if not found
+func (c *Cache) Get(key string) ([]byte, bool, error) {
+	v, err := c.client.Get(c.prefix + key).Bytes()
+	if err == redis.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return v, true, nil
+}
+
+// Set stores v for key, expiring after the TTL
+func (c *Cache) Set(key string, v []byte) error {
+	return c.client.Set(c.prefix+key, v, c.ttl).Err()
+}
+
+// Close closes the connections to Redis
+func (c *Cache) Close() error {
+	return c.client.Close()
+}
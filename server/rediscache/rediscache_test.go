@@ -0,0 +1,46 @@
+package rediscache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	c, err := New(Options{Addr: mr.Addr(), Prefix: "insided:", TTL: time.Minute})
+	require.NoError(t, err)
+	defer c.Close()
+
+	_, ok, err := c.Get("k")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.NoError(t, c.Set("k", []byte("v")))
+	v, ok, err := c.Get("k")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, []byte("v"), v)
+
+	// keys are prefixed and expire
+	require.True(t, mr.Exists("insided:k"))
+	mr.FastForward(2 * time.Minute)
+	_, ok, err = c.Get("k")
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestNewUnreachable(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	addr := mr.Addr()
+	mr.Close()
+
+	_, err = New(Options{Addr: addr})
+	require.Error(t, err)
+}
@@ -0,0 +1,242 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/insidesvc"
+)
+
+// addressTemplate formats the properties of the features containing a point, like "{city}, {state}, {country}"
+type addressTemplate struct {
+	// literals[i] precedes fields[i], the last literal follows the last field
+	literals []string
+	fields   []addressField
+}
+
+// addressField a {property} or {property|key=value,...} placeholder,
+// replaced by the property of the first feature having it and matching the filter
+type addressField struct {
+	property string
+	filter   propertyFilter
+}
+
+// ReverseResponse the body returned by the reverse endpoints
+type ReverseResponse struct {
+	Address string   `json:"address"`
+	IDs     []uint32 `json:"ids"`
+}
+
+// ParseReverseTemplates parses a semicolon separated list of dataset=template,
+// a template without dataset is used for the datasets without their own
+func ParseReverseTemplates(expr string) (map[string]string, error) {
+	if expr == "" {
+		return nil, nil
+	}
+
+	templates := make(map[string]string)
+	for _, e := range strings.Split(expr, ";") {
+		var name string
+		// a = in a placeholder is a filter condition
+		if i := strings.Index(e, "="); i >= 0 && !strings.Contains(e[:i], "{") {
+			name, e = strings.TrimSpace(e[:i]), e[i+1:]
+		}
+		if _, ok := templates[name]; ok {
+			return nil, fmt.Errorf("duplicate reverse template for dataset %q", name)
+		}
+		templates[name] = e
+	}
+	return templates, nil
+}
+
+// parseAddressTemplate parses the {property} and {property|filter} placeholders of tmpl
+func parseAddressTemplate(tmpl string) (*addressTemplate, error) {
+	t := &addressTemplate{}
+	rest := tmpl
+	for {
+		start := strings.Index(rest, "{")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(rest[start:], "}")
+		if end < 0 {
+			return nil, fmt.Errorf("unclosed placeholder in template %q", tmpl)
+		}
+		end += start
+
+		var f addressField
+		kv := strings.SplitN(rest[start+1:end], "|", 2)
+		f.property = strings.TrimSpace(kv[0])
+		if f.property == "" {
+			return nil, fmt.Errorf("empty placeholder in template %q", tmpl)
+		}
+		if len(kv) == 2 {
+			pf, err := parsePropertyFilter(kv[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid placeholder in template %q: %w", tmpl, err)
+			}
+			f.filter = pf
+		}
+
+		t.literals = append(t.literals, rest[:start])
+		t.fields = append(t.fields, f)
+		rest = rest[end+1:]
+	}
+	if len(t.fields) == 0 {
+		return nil, fmt.Errorf("no placeholder in template %q", tmpl)
+	}
+	t.literals = append(t.literals, rest)
+	return t, nil
+}
+
+// Format returns the address for the properties of the features, in order of preference.
+// A missing field is removed with the separator preceding it, the result is empty when all the fields are missing.
+func (t *addressTemplate) Format(features []map[string]interface{}) string {
+	var sb strings.Builder
+	found := false
+	for i, f := range t.fields {
+		v, ok := f.value(features)
+		if !ok {
+			continue
+		}
+		// the leading literal is kept, a separator only between two fields
+		if i == 0 || found {
+			sb.WriteString(t.literals[i])
+		}
+		sb.WriteString(v)
+		found = true
+	}
+	if !found {
+		return ""
+	}
+	sb.WriteString(t.literals[len(t.fields)])
+	return sb.String()
+}
+
+func (f addressField) value(features []map[string]interface{}) (string, bool) {
+	for _, props := range features {
+		v, ok := props[f.property]
+		if !ok || !f.filter.Match(props) {
+			continue
+		}
+		var s string
+		switch tv := v.(type) {
+		case nil:
+			continue
+		case string:
+			s = tv
+		case float64:
+			s = strconv.FormatFloat(tv, 'f', -1, 64)
+		default:
+			s = fmt.Sprint(tv)
+		}
+		if s != "" {
+			return s, true
+		}
+	}
+	return "", false
+}
+
+// newReverseTemplates parses the templates of opts keyed by dataset name
+func newReverseTemplates(opts Options) (map[string]*addressTemplate, error) {
+	templates := make(map[string]*addressTemplate, len(opts.ReverseTemplates))
+	for name, tmpl := range opts.ReverseTemplates {
+		t, err := parseAddressTemplate(tmpl)
+		if err != nil {
+			return nil, err
+		}
+		templates[name] = t
+	}
+	return templates, nil
+}
+
+// Reverse returns the address of the point formatted with the template of the dataset,
+// the features are considered from the smallest to the largest
+func (s *Server) Reverse(ctx context.Context, dataset string, lat, lng float64) (*ReverseResponse, error) {
+	if dataset == "" {
+		dataset = s.defaultName
+	}
+	t, ok := s.reverse[dataset]
+	if !ok {
+		if t, ok = s.reverse[""]; !ok {
+			return nil, status.Errorf(codes.NotFound, "no reverse template for dataset %s", dataset)
+		}
+	}
+
+	resp, err := s.Within(ctx, &insidesvc.WithinRequest{
+		Lat:              lat,
+		Lng:              lng,
+		Dataset:          dataset,
+		RemoveGeometries: true,
+		Order:            insidesvc.WithinRequest_AREA,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	features := make([]map[string]interface{}, len(resp.Responses))
+	rr := &ReverseResponse{IDs: make([]uint32, len(resp.Responses))}
+	for i, fr := range resp.Responses {
+		features[i] = insideout.ValueToProperties(fr.Feature.Properties)
+		rr.IDs[i] = fr.Id
+	}
+	rr.Address = t.Format(features)
+	if rr.Address == "" {
+		return nil, status.Error(codes.NotFound, "no address at this location")
+	}
+	return rr, nil
+}
+
+// ReverseHandler HTTP 1.1 Handler returning the address of a point as JSON
+func (s *Server) ReverseHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	ctx, span := tracer().Start(ctx, "ReverseHandler")
+	defer span.End()
+
+	vars := mux.Vars(r)
+
+	lat, err := strconv.ParseFloat(vars["lat"], 64)
+	if err != nil {
+		http.Error(w, "invalid parameter lat", 400)
+		return
+	}
+	lng, err := strconv.ParseFloat(vars["lng"], 64)
+	if err != nil {
+		http.Error(w, "invalid parameter lng", 400)
+		return
+	}
+
+	resp, err := s.Reverse(ctx, vars["dataset"], lat, lng)
+	if err != nil {
+		if st, ok := status.FromError(err); ok {
+			switch st.Code() {
+			case codes.InvalidArgument:
+				http.Error(w, st.Message(), 400)
+				return
+			case codes.NotFound:
+				http.Error(w, st.Message(), 404)
+				return
+			case codes.DeadlineExceeded:
+				http.Error(w, st.Message(), 504)
+				return
+			}
+		}
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		http.Error(w, err.Error(), 500)
+	}
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+
+	"github.com/akhenakh/insideout"
+)
+
+func TestParseReverseTemplates(t *testing.T) {
+	templates, err := ParseReverseTemplates("{name|admin_level=8}, {name|admin_level=2};osm={city}, {country}")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"":    "{name|admin_level=8}, {name|admin_level=2}",
+		"osm": "{city}, {country}",
+	}, templates)
+
+	_, err = ParseReverseTemplates("{city};{country}")
+	require.Error(t, err)
+}
+
+func TestAddressTemplate_Format(t *testing.T) {
+	tmpl, err := parseAddressTemplate("{city}, {state}, {country}")
+	require.NoError(t, err)
+
+	features := []map[string]interface{}{
+		{"city": "Paris"},
+		{"state": "", "country": "France"},
+	}
+	require.Equal(t, "Paris, France", tmpl.Format(features))
+	require.Equal(t, "France", tmpl.Format(features[1:]))
+	require.Equal(t, "", tmpl.Format(nil))
+
+	tmpl, err = parseAddressTemplate("Near {name|admin_level=8} ({name|admin_level=2})")
+	require.NoError(t, err)
+	features = []map[string]interface{}{
+		{"name": "Paris", "admin_level": 8.0},
+		{"name": "France", "admin_level": 2.0},
+	}
+	require.Equal(t, "Near Paris (France)", tmpl.Format(features))
+
+	for _, invalid := range []string{"no placeholder", "{city", "{}, {country}", "{name|admin_level}"} {
+		_, err = parseAddressTemplate(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestServer_ReverseHandler(t *testing.T) {
+	storage, clean := setupNested(t, []nestedSquare{
+		{name: "France", size: 1, props: map[string]interface{}{"admin_level": 2.0}},
+		{name: "Paris", size: 0.2, props: map[string]interface{}{"admin_level": 8.0}},
+	})
+	defer clean()
+
+	s, err := New(storage, log.NewNopLogger(), nil, Options{
+		Strategy:         insideout.ShapeIndexStrategy,
+		DatasetName:      "nested",
+		ReverseTemplates: map[string]string{"nested": "{name|admin_level=8}, {name|admin_level=2}"},
+	})
+	require.NoError(t, err)
+
+	r := mux.NewRouter()
+	for _, route := range s.APIRoutes() {
+		r.Handle(route.Path, route.Handler).Methods(route.Methods...)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/reverse/0.5/0.5", nil))
+	require.Equal(t, 200, w.Code)
+	var resp ReverseResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(t, "Paris, France", resp.Address)
+	require.Equal(t, []uint32{1, 0}, resp.IDs)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/reverse/nested/0.1/0.1", nil))
+	require.Equal(t, 200, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(t, "France", resp.Address)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/reverse/10/10", nil))
+	require.Equal(t, 404, w.Code)
+
+	// no template for the dataset
+	require.NoError(t, s.AddDataset("other", storage))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/reverse/other/0.5/0.5", nil))
+	require.Equal(t, 404, w.Code)
+}
@@ -2,26 +2,39 @@ package server
 
 import (
 	"context"
-	"os"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/dgraph-io/ristretto"
 	log "github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
 	"github.com/golang/geo/s2"
 	structpb "github.com/golang/protobuf/ptypes/struct"
-	"github.com/opentracing/opentracing-go"
-	slog "github.com/opentracing/opentracing-go/log"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel/api/trace"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/label"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/health"
 	"google.golang.org/grpc/status"
 
 	"github.com/akhenakh/insideout"
 	"github.com/akhenakh/insideout/index/dbindex"
+	"github.com/akhenakh/insideout/index/h3index"
+	"github.com/akhenakh/insideout/index/hybridindex"
+	"github.com/akhenakh/insideout/index/memoryindex"
 	"github.com/akhenakh/insideout/index/shapeindex"
 	"github.com/akhenakh/insideout/index/treeindex"
 	"github.com/akhenakh/insideout/insidesvc"
+	"github.com/akhenakh/insideout/server/geofence"
+	"github.com/akhenakh/insideout/server/querylog"
+	"github.com/akhenakh/insideout/server/tenant"
 )
 
 var (
@@ -42,245 +55,1307 @@ var (
 		Name:      "feature_miss_hit",
 		Help:      "Features miss hits",
 	})
+
+	resultHitCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "insided_server",
+		Name:      "result_cache_hit",
+		Help:      "Within results cache hits",
+	})
+
+	resultMissCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "insided_server",
+		Name:      "result_cache_miss",
+		Help:      "Within results cache misses",
+	})
 )
 
 // Server exposes indexes services
 type Server struct {
-	storage      insideout.Store
+	// mu protects datasets against a concurrent Reload
+	mu           sync.RWMutex
+	datasets     map[string]*dataset
+	defaultName  string
 	logger       log.Logger
-	cache        *ristretto.Cache
 	healthServer *health.Server
-	idx          insideout.Index
+	opts         Options
+	startTime    time.Time
+
+	// reverse the address templates by dataset name, "" for the datasets without their own
+	reverse map[string]*addressTemplate
+
+	// locations the loaded time zones by name
+	locations sync.Map
+
+	// shadowSem limits the running shadow queries, nil without ShadowStrategy
+	shadowSem chan struct{}
+
+	// candidates the candidate datasets by the name of the dataset they are compared with, see SetCandidate
+	candidates map[string]*dataset
+
+	// candidateSem limits the running candidate queries
+	candidateSem chan struct{}
 }
 
 type Options struct {
 	StopOnFirstFound bool
 	CacheCount       int
 	Strategy         string
+
+	// Strategies the other strategies whose indexes are loaded for each dataset, selected by the strategy field
+	// of the within requests to compare their answers and latency with Strategy, not supported with ReadWrite
+	Strategies []string
+
+	// ShadowStrategy a strategy running again in the background a ShadowRatio fraction of the within queries
+	// answered by Strategy, the mismatching results are logged, counted and the latency deltas observed,
+	// loaded like Strategies, empty to disable
+	ShadowStrategy string
+
+	// ShadowRatio the fraction of the within queries run again with ShadowStrategy, from 0 to 1
+	ShadowRatio float64
+
+	// CandidateRatio the fraction of the within queries of a dataset run again with its candidate, from 0 to 1,
+	// see SetCandidate
+	CandidateRatio float64
+
+	// CandidateKey the property identifying the features across the releases of a dataset, compared between
+	// the results of a dataset and its candidate, their ids are compared when empty
+	CandidateKey string
+
+	// NearestMaxDistance in meters, the max distance to look for the nearest feature, 0 to disable
+	NearestMaxDistance float64
+
+	// ResultCacheLevel the S2 level of the cells keying the within results cache, 0 to disable,
+	// all the points of a cell share the result of the first one queried, level 20 cells are about 10m wide
+	ResultCacheLevel int
+
+	// ResultCacheCount the number of cells to cache results for
+	ResultCacheCount int
+
+	// SharedCache an optional cache shared with other servers for features and within results,
+	// results are shared only when the results cache is enabled
+	SharedCache SharedCache
+
+	// DatasetName the name of the default dataset, served when a request does not name one
+	DatasetName string
+
+	// Version of the running server, returned by Info
+	Version string
+
+	// Geofence the engine tracking the objects positions sent to Track, nil to disable Track
+	Geofence *geofence.Engine
+
+	// GeofenceSink an optional sink receiving all the geofence events
+	GeofenceSink geofence.Sink
+
+	// TimezoneProperty the property holding the IANA time zone name of the features, like tzid,
+	// adds their current UTC offset and DST status to the within and nearest responses, empty to disable
+	TimezoneProperty string
+
+	// ReadWrite serves the endpoints inserting, updating and deleting features,
+	// the storages must implement insideout.FeatureWriter
+	ReadWrite bool
+
+	// Tenants counts the features owned by each tenant when a dataset is loaded to enforce the tenants quotas,
+	// requests made by a tenant only see its own features, see the tenant package
+	Tenants bool
+
+	// TenantKeyHeader the header holding the API key of the tenants, the cacheable HTTP responses vary with it
+	TenantKeyHeader string
+
+	// ShapeIndexRegionLevel partitions the shapeindex strategy index by s2 cells of this level, 0 to disable,
+	// the index of a region is built by its first query, see shapeindex.RegionIndex
+	ShapeIndexRegionLevel int
+
+	// ShapeIndexMaxVertices the max count of vertices held by the built regions of a dataset, 0 for no limit,
+	// the least recently queried regions are evicted
+	ShapeIndexMaxVertices int
+
+	// MaxQueryDuration caps the duration of the within, nearest and intersect queries, 0 for no limit,
+	// the queries stop between the candidate features and the storages supporting it cancel their reads
+	MaxQueryDuration time.Duration
+	// HTTPCacheMaxAge the max age of the HTTP GET responses in the caches of the clients and CDNs,
+	// revalidated with their ETag once expired or always when 0, negative to disable the cache headers
+	HTTPCacheMaxAge time.Duration
+	// PropertyIndex the properties indexed in memory by value for ListFeatures,
+	// the filters without an equality condition on one of them scan the storage
+	PropertyIndex []string
+	// MaxIntersectResults the max features returned by an intersect query, the requests above it are capped
+	// and paginated with a cursor, 0 for no limit
+	MaxIntersectResults int
+	// PIPWorkers the goroutines testing the candidates of a query concurrently, when there are enough of them,
+	// 0 or 1 to test them one by one
+	PIPWorkers int
+
+	// CellFilter rejects the points outside of the indexed cells with the filter stored by the indexer,
+	// without reading the cells, for the db and hybrid strategies, see insideout.CellFilter
+	CellFilter bool
+
+	// ReverseTemplates the address templates of the reverse endpoints by dataset name,
+	// "" for the datasets without their own, see ParseReverseTemplates
+	ReverseTemplates map[string]string
+
+	// ExpiryProperty the property holding the expiry time of the features, an RFC 3339 string or unix seconds,
+	// the expired features are ignored by the queries until deleted by the sweeper, see RunExpirySweeper, empty to disable
+	ExpiryProperty string
+
+	// ValidFromProperty and ValidToProperty the properties holding the validity interval of the features,
+	// RFC 3339 strings, dates or unix seconds, the within queries only return the features valid at their time,
+	// empty to disable
+	ValidFromProperty string
+	ValidToProperty   string
+
+	// ShardCells the cells of the partition served by a shard, normalized, empty to serve all the features,
+	// only the features crossing them are loaded by the strategies holding the index in memory,
+	// the within queries outside of them are rejected and the intersect queries only cover them, see the shard package
+	ShardCells s2.CellUnion
+
+	// QueryLog an optional sink exporting the point, matched features and latency of the within queries
+	QueryLog *querylog.Sink
+}
+
+// dataset is a storage with its index, features and results caches
+type dataset struct {
+	name    string
+	storage insideout.Store
+	idx     insideout.Index
+	cache   *ristretto.Cache
+	results *ristretto.Cache
+	infos   *insideout.IndexInfos
+
+	// parents the parent id of each contained feature, nil when indexed without hierarchy
+	parents map[uint32]uint32
+
+	// filter the filter of the indexed cells, nil when not used
+	filter *insideout.CellFilter
+
+	// version identifies the content of storage in the shared cache
+	version string
+
+	// tenants the number of features owned by each tenant, nil when not counted
+	tenants map[string]uint32
+
+	// properties the index of the PropertyIndex properties, nil when not indexed
+	properties *propertyIndex
+
+	// idxs the indexes of the other Strategies by strategy, nil when none
+	idxs map[string]insideout.Index
+
+	// candidate is true for the candidate of a dataset, its queries are not shadowed
+	candidate bool
+}
+
+// New returns a Server, storage is the default dataset
+func New(storage insideout.Store, logger log.Logger, healthServer *health.Server,
+	opts Options) (*Server, error) {
+	logger = log.With(logger, "component", "server")
+
+	if opts.ShadowStrategy != "" {
+		if opts.ShadowStrategy == opts.Strategy {
+			return nil, errors.New("the shadow strategy is the server strategy")
+		}
+		if opts.ShadowRatio < 0 || opts.ShadowRatio > 1 {
+			return nil, fmt.Errorf("invalid shadow ratio %f", opts.ShadowRatio)
+		}
+		opts.Strategies = append(append([]string(nil), opts.Strategies...), opts.ShadowStrategy)
+	}
+	if opts.CandidateRatio < 0 || opts.CandidateRatio > 1 {
+		return nil, fmt.Errorf("invalid candidate ratio %f", opts.CandidateRatio)
+	}
+
+	// the writes only update the index of Strategy
+	if opts.ReadWrite && len(opts.Strategies) > 0 {
+		return nil, errors.New("the other strategies are not supported with ReadWrite")
+	}
+
+	s := &Server{
+		datasets:     make(map[string]*dataset),
+		defaultName:  opts.DatasetName,
+		logger:       logger,
+		healthServer: healthServer,
+		opts:         opts,
+		startTime:    time.Now(),
+		candidates:   make(map[string]*dataset),
+		candidateSem: make(chan struct{}, maxShadowQueries),
+	}
+
+	if opts.ShadowStrategy != "" {
+		s.shadowSem = make(chan struct{}, maxShadowQueries)
+	}
+
+	reverse, err := newReverseTemplates(opts)
+	if err != nil {
+		return nil, err
+	}
+	s.reverse = reverse
+
+	if err := s.AddDataset(opts.DatasetName, storage); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// newDataset loads the index and creates the cache for storage
+func (s *Server) newDataset(name string, storage insideout.Store) (*dataset, error) {
+	// the settings can be changed at runtime
+	s.mu.RLock()
+	opts := s.opts
+	s.mu.RUnlock()
+
+	sstorage, err := newShardStore(storage, opts.ShardCells)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load shard features: %w", err)
+	}
+	idx, err := newIndex(sstorage, opts)
+	if err != nil {
+		level.Error(s.logger).Log("msg", "failed to load index from storage", "error", err,
+			"strategy", opts.Strategy, "dataset", name)
+		return nil, err
+	}
+
+	idxs, err := newIndexes(sstorage, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	cache, err := newCache(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := newResultsCache(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	infos, err := storage.LoadIndexInfos()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index infos: %w", err)
+	}
+	if err := infos.Validate(); err != nil {
+		return nil, fmt.Errorf("incompatible index: %w", err)
+	}
+
+	parents, err := loadHierarchy(storage)
+	if err != nil {
+		return nil, err
+	}
+
+	filter, err := s.loadCellFilter(name, storage, infos, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cell filter: %w", err)
+	}
+
+	var tenants map[string]uint32
+	if opts.Tenants {
+		tenants, err = countTenantFeatures(storage)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count tenants features: %w", err)
+		}
+	}
+
+	properties, err := s.loadPropertyIndex(name, storage, infos, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to index properties: %w", err)
+	}
+
+	return &dataset{
+		name:       name,
+		storage:    storage,
+		idx:        idx,
+		cache:      cache,
+		results:    results,
+		infos:      infos,
+		parents:    parents,
+		filter:     filter,
+		version:    datasetVersion(infos),
+		tenants:    tenants,
+		properties: properties,
+		idxs:       idxs,
+	}, nil
+}
+
+// datasetVersion identifies the content of an index by its index time
+func datasetVersion(infos *insideout.IndexInfos) string {
+	return strconv.FormatInt(infos.IndexTime.UnixNano(), 36)
+}
+
+// AddDataset serves storage under name, requests select it with their dataset field
+func (s *Server) AddDataset(name string, storage insideout.Store) error {
+	s.mu.RLock()
+	_, ok := s.datasets[name]
+	s.mu.RUnlock()
+	if ok {
+		return fmt.Errorf("dataset %s already exists", name)
+	}
+
+	ds, err := s.newDataset(name, storage)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.datasets[name]; ok {
+		return fmt.Errorf("dataset %s already exists", name)
+	}
+	s.datasets[name] = ds
+
+	return nil
+}
+
+// Datasets returns the names of the served datasets
+func (s *Server) Datasets() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	names := make([]string, 0, len(s.datasets))
+	for name := range s.datasets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// dataset returns the dataset called name, the default one if name is empty,
+// the caller must hold s.mu
+func (s *Server) dataset(name string) (*dataset, error) {
+	if name == "" {
+		name = s.defaultName
+	}
+	ds, ok := s.datasets[name]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "unknown dataset %s", name)
+	}
+	return ds, nil
 }
 
-// New returns a Server
-func New(storage insideout.Store, logger log.Logger, healthServer *health.Server,
-	opts Options) (*Server, error) {
-	logger = log.With(logger, "component", "server")
+// newIndex creates and fills the index for the strategy in opts
+func newIndex(storage insideout.Store, opts Options) (insideout.Index, error) {
+	switch opts.Strategy {
+	case insideout.InsideTreeStrategy:
+		treeidx := treeindex.New(treeindex.Options{StopOnInsideFound: opts.StopOnFirstFound})
+		err := storage.LoadFeaturesCells(treeidx.Add)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load cells from storage: %w", err)
+		}
+		return treeidx, nil
+	case insideout.ShapeIndexStrategy:
+		if opts.ShapeIndexRegionLevel > 0 {
+			regionidx := shapeindex.NewRegionIndex(storage, shapeindex.RegionOptions{
+				Level:       opts.ShapeIndexRegionLevel,
+				MaxVertices: opts.ShapeIndexMaxVertices,
+			})
+			if err := regionidx.Load(); err != nil {
+				return nil, err
+			}
+			return regionidx, nil
+		}
+		shapeidx := shapeindex.New()
+		err := storage.LoadAllFeatures(shapeidx.Add)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load feature from storage: %w", err)
+		}
+		return shapeidx, nil
+	case insideout.DBStrategy, insideout.PostGISStrategy:
+		// the PostGIS storage tests the points in the database
+		return dbindex.New(storage, dbindex.Options{StopOnInsideFound: opts.StopOnFirstFound}), nil
+	case insideout.HybridStrategy:
+		hybrididx := hybridindex.New(storage, hybridindex.Options{StopOnInsideFound: opts.StopOnFirstFound})
+		if err := hybrididx.Load(); err != nil {
+			return nil, err
+		}
+		return hybrididx, nil
+	case insideout.H3Strategy:
+		hs, ok := storage.(insideout.H3Store)
+		if !ok {
+			return nil, errors.New("H3 cover not supported by the storage")
+		}
+		cover, err := hs.LoadH3Cover()
+		if err != nil {
+			return nil, err
+		}
+		if cover == nil {
+			return nil, errors.New("no H3 cover in the database, index it with -h3Resolution")
+		}
+		return h3index.New(cover)
+	case insideout.MemoryStrategy:
+		memidx := memoryindex.New(memoryindex.Options{StopOnInsideFound: opts.StopOnFirstFound})
+		if err := memidx.Load(storage); err != nil {
+			return nil, err
+		}
+		return memidx, nil
+	}
+
+	return nil, fmt.Errorf("unknown strategy %s", opts.Strategy)
+}
+
+// newCache returns a features cache, nil if disabled
+func newCache(opts Options) (*ristretto.Cache, error) {
+	// features are already in memory
+	if opts.CacheCount <= 0 || opts.Strategy == insideout.MemoryStrategy {
+		return nil, nil
+	}
+	return ristretto.NewCache(&ristretto.Config{
+		NumCounters: int64(opts.CacheCount) * 10, // number of keys to track frequency
+		MaxCost:     int64(opts.CacheCount),      // maximum cost of cache
+		BufferItems: 64,                          // number of keys per Get buffer.
+	})
+}
+
+// newResultsCache returns a within results cache, nil if disabled
+func newResultsCache(opts Options) (*ristretto.Cache, error) {
+	if opts.ResultCacheLevel <= 0 || opts.ResultCacheCount <= 0 {
+		return nil, nil
+	}
+	if opts.ResultCacheLevel > 30 {
+		return nil, fmt.Errorf("invalid result cache level %d", opts.ResultCacheLevel)
+	}
+	return ristretto.NewCache(&ristretto.Config{
+		NumCounters: int64(opts.ResultCacheCount) * 10,
+		MaxCost:     int64(opts.ResultCacheCount),
+		BufferItems: 64,
+	})
+}
+
+// Reload swaps the storage of the default dataset, see ReloadDataset
+func (s *Server) Reload(storage insideout.Store) (insideout.Store, error) {
+	return s.ReloadDataset(s.defaultName, storage)
+}
+
+// ReloadDataset swaps the underlying storage of the dataset name with storage, the index is rebuilt
+// from the new storage before the swap so queries are still served from the previous data during the load.
+// It returns the previous storage, which is no longer used by the server and can be closed.
+func (s *Server) ReloadDataset(name string, storage insideout.Store) (insideout.Store, error) {
+	s.mu.RLock()
+	_, err := s.dataset(name)
+	s.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	ds, err := s.newDataset(name, storage)
+	if err != nil {
+		return nil, err
+	}
+	s.warmup(ds)
+
+	// waiting for in flight queries to complete
+	s.mu.Lock()
+	old := s.datasets[name]
+	s.datasets[name] = ds
+	s.mu.Unlock()
+
+	old.close()
+
+	level.Info(s.logger).Log("msg", "storage reloaded", "strategy", s.opts.Strategy, "dataset", name)
+
+	return old.storage, nil
+}
+
+// featureIndex is implemented by indexes holding the features in memory
+type featureIndex interface {
+	Feature(id uint32) (*insideout.Feature, bool)
+}
+
+// feature fetch feature from cache, shared cache or storage
+func (s *Server) feature(ctx context.Context, ds *dataset, id uint32) (*insideout.Feature, error) {
+	if fidx, ok := ds.idx.(featureIndex); ok {
+		f, ok := fidx.Feature(id)
+		if !ok {
+			return nil, status.Error(codes.NotFound, "can't found feature")
+		}
+		return f, nil
+	}
+	if ds.cache != nil {
+		if fi, found := ds.cache.Get(id); found {
+			featureHitCounter.Inc()
+			observeCache(ds, featureTier, true)
+			return fi.(*insideout.Feature), nil
+		}
+		featureMissCounter.Inc()
+		observeCache(ds, featureTier, false)
+	}
+
+	var lf *insideout.Feature
+	var ok bool
+	if s.opts.SharedCache != nil {
+		lf, ok = s.sharedFeature(ds, id)
+	}
+	if !ok {
+		_, span := tracer().Start(ctx, "LoadFeature", trace.WithAttributes(
+			label.String("dataset", ds.name),
+			label.Uint32("fid", id),
+		))
+		var err error
+		if cs, ok := ds.storage.(insideout.ContextStore); ok {
+			lf, err = cs.LoadFeatureContext(ctx, id)
+		} else {
+			lf, err = ds.storage.LoadFeature(id)
+		}
+		span.End()
+		if err != nil {
+			return nil, err
+		}
+		if s.opts.SharedCache != nil {
+			s.setSharedFeature(ds, id, lf)
+		}
+	}
+
+	if ds.cache != nil {
+		ds.cache.Set(id, lf, 1)
+	}
+	return lf, nil
+}
+
+// Within query exposed via gRPC
+func (s *Server) Within(
+	ctx context.Context, req *insidesvc.WithinRequest,
+) (resp *insidesvc.WithinResponse, terr error) {
+	start := time.Now()
+	ctx, span := tracer().Start(ctx, "Within")
+	defer span.End()
+
+	defer func() { s.handleError(ctx, terr, span) }()
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	pf, err := parsePropertyFilter(req.Filter)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	fields := parseFields(req.SelectProperties)
+	if req.Limit < 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid negative limit")
+	}
+	if err := s.inShard(req.Lat, req.Lng); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ds, err := s.dataset(req.Dataset)
+	if err != nil {
+		return nil, err
+	}
+
+	span.SetAttributes(
+		label.Float64("lat", req.Lat),
+		label.Float64("lng", req.Lng),
+		label.String("dataset", ds.name),
+	)
+
+	strategy, idx, err := s.index(ds, req.Strategy)
+	if err != nil {
+		return nil, err
+	}
+
+	var dbg *insidesvc.WithinDebug
+	if req.Debug {
+		dbg = newWithinDebug(ds, strategy, req.Lat, req.Lng)
+	}
+	at := requestTime(req.At)
+	accept := func(f *insideout.Feature) bool {
+		return pf.Match(f.Properties) && s.live(ctx, f.Properties) && s.validAt(f.Properties, at)
+	}
+	// with StopOnFirstFound a feature filtered out must not end the search,
+	// the candidates are filtered as they are evaluated and the indexes return all of them
+	var stabAccept func(*insideout.Feature) bool
+	if s.opts.StopOnFirstFound && s.filtering(ctx, pf) {
+		stabAccept = accept
+		ctx = insideout.WithoutStop(ctx)
+	}
+
+	fids, features, exacts, err := s.stab(ctx, ds, strategy, req.Lat, req.Lng, req.Exact, stabAccept, dbg)
+	if err != nil {
+		return nil, err
+	}
+	if strategy == s.opts.Strategy && dbg == nil && stabAccept == nil {
+		if cds := s.candidate(ds); cds != nil {
+			s.compareCandidate(ds.name, cds, req.Lat, req.Lng, req.Exact, fids, features)
+		}
+	}
+
+	matches := make([]match, 0, len(fids))
+	for i, fid := range fids {
+		if !accept(features[i]) {
+			continue
+		}
+		matches = append(matches, match{fid: fid, feature: features[i], exact: exacts[i]})
+	}
+	if err := orderMatches(req, matches); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	matches, err = arrangeHierarchy(ds, matches, req.Hierarchy, req.DeepestOnly)
+	if err != nil {
+		return nil, err
+	}
+	if req.Limit > 0 && len(matches) > int(req.Limit) {
+		matches = matches[:req.Limit]
+	}
+
+	// the responses carry the H3 cell of the point with the h3 strategy
+	var h3Cell *structpb.Value
+	if h3idx, ok := idx.(*h3index.Index); ok {
+		h3Cell = &structpb.Value{
+			Kind: &structpb.Value_StringValue{StringValue: strconv.FormatUint(h3idx.H3Cell(req.Lat, req.Lng), 16)},
+		}
+	}
+
+	var fresps []*insidesvc.FeatureResponse
+
+	for _, m := range matches {
+		fresp, err := newFeatureResponse(m.feature, m.fid, req.RemoveGeometries, fields)
+		if err != nil {
+			return nil, err
+		}
+		fresp.Exact = m.exact
+		s.addTimezone(fresp, m.feature)
+		if h3Cell != nil {
+			fresp.Feature.Properties[insidesvc.H3CellProperty] = h3Cell
+		}
+		if req.Hierarchy {
+			fresp.AncestorIds = m.ancestors
+		}
+		if req.BoundaryDistance {
+			p := s2.PointFromLatLng(s2.LatLngFromDegrees(req.Lat, req.Lng))
+			fresp.BoundaryDistance = insideout.AngleToMeters(m.feature.DistanceToBoundary(m.fid.Pos, p))
+		}
+		fresps = append(fresps, fresp)
+	}
+
+	level.Debug(s.logger).Log("msg", "result stab",
+		"lat", req.Lat,
+		"lng", req.Lng,
+		"features_count", len(fresps))
+
+	resp = &insidesvc.WithinResponse{
+		Point: &insidesvc.Point{
+			Lat: req.Lat,
+			Lng: req.Lng,
+		},
+		Responses: fresps,
+		Debug:     dbg,
+	}
+
+	if s.opts.QueryLog != nil {
+		ids := make([]uint32, len(matches))
+		for i, m := range matches {
+			ids[i] = m.fid.ID
+		}
+		s.opts.QueryLog.Log(querylog.Entry{
+			Time:    start,
+			Dataset: ds.name,
+			Lat:     req.Lat,
+			Lng:     req.Lng,
+			IDs:     ids,
+			Latency: time.Since(start),
+		})
+	}
+
+	return resp, nil
+}
+
+// newWithinDebug returns the diagnostics of a lookup of lat lng in ds with the cell of the point
+// at the finest inside cover level
+func newWithinDebug(ds *dataset, strategy string, lat, lng float64) *insidesvc.WithinDebug {
+	lvl := ds.infos.MinCoverLevel
+	if ds.infos.InsideCover != nil {
+		lvl = ds.infos.InsideCover.MaxLevel
+	}
+	cell := s2.CellIDFromLatLng(s2.LatLngFromDegrees(lat, lng)).Parent(lvl)
+	return &insidesvc.WithinDebug{
+		CellToken: cell.ToToken(),
+		CellLevel: int32(lvl),
+		Strategy:  strategy,
+	}
+}
+
+// stab returns the loops containing lat lng and their features from the index of strategy,
+// from the results cache when enabled, a cached result is shared by all the points of a cell,
+// exacts reports for each loop if the point was tested against it,
+// with exact the results cache is skipped and the inside loops are tested too,
+// with dbg the results cache is skipped and dbg is filled with the candidates and the timings,
+// with accept only the features accepted are returned, the results cache and the shadow queries are skipped,
+// the results of the other strategies are not cached
+func (s *Server) stab(ctx context.Context, ds *dataset, strategy string, lat, lng float64, exact bool,
+	accept func(*insideout.Feature) bool, dbg *insidesvc.WithinDebug,
+) (fids []insideout.FeatureIndexResponse, features []*insideout.Feature, exacts []bool, err error) {
+	start := time.Now()
+	cached := ds.results != nil && strategy == s.opts.Strategy && accept == nil
+	var cellID s2.CellID
+	if cached {
+		cellID = s2.CellIDFromLatLng(s2.LatLngFromDegrees(lat, lng)).Parent(s.opts.ResultCacheLevel)
+		if !exact && dbg == nil {
+			if cfids, ok := s.cachedResult(ds, cellID); ok {
+				trace.SpanFromContext(ctx).SetAttributes(label.Bool("cached", true))
+				features := make([]*insideout.Feature, len(cfids))
+				for i, fid := range cfids {
+					f, err := s.feature(ctx, ds, fid.ID)
+					if err != nil {
+						return nil, nil, nil, err
+					}
+					features[i] = f
+				}
+				s.observeWithin(ds, strategy, start, true)
+				return cfids, features, make([]bool, len(cfids)), nil
+			}
+		}
+	}
+
+	_, ispan := tracer().Start(ctx, "IndexStab", trace.WithAttributes(label.String("strategy", strategy)))
+	idxResp, err := s.indexStab(ctx, ds, strategy, lat, lng)
+	if err != nil {
+		ispan.End()
+		return nil, nil, nil, queryError(ctx, err)
+	}
+	ispan.SetAttributes(
+		label.Int("inside_count", len(idxResp.IDsInside)),
+		label.Int("maybe_inside_count", len(idxResp.IDsMayBeInside)),
+	)
+	ispan.End()
+	indexDuration.WithLabelValues(ds.name, strategy).Observe(time.Since(start).Seconds())
+	pipStart := time.Now()
+	if dbg != nil {
+		dbg.IndexMicros = time.Since(start).Microseconds()
+	}
+	candidatesHistogram.WithLabelValues(ds.name, strategy, "inside").Observe(float64(len(idxResp.IDsInside)))
+	candidatesHistogram.WithLabelValues(ds.name, strategy, "maybe_inside").Observe(float64(len(idxResp.IDsMayBeInside)))
+
+	level.Debug(s.logger).Log("msg", "querying within",
+		"lat", lat,
+		"lng", lng,
+		"idx_resp", idxResp,
+	)
+
+	p := s2.PointFromLatLng(s2.LatLngFromDegrees(lat, lng))
+	insideExact := exactInside(strategy)
+	var pips int
+
+	// the features loads of the candidates are children of the PIP span
+	ctx, pspan := tracer().Start(ctx, "PIPTests")
+	defer func() {
+		pspan.SetAttributes(label.Int("pip_count", pips))
+		pspan.End()
+	}()
+
+	cands := make([]candidate, 0, len(idxResp.IDsInside)+len(idxResp.IDsMayBeInside))
+	for _, fid := range idxResp.IDsInside {
+		cands = append(cands, candidate{fid: fid, inside: true, test: exact && !insideExact})
+	}
+	for _, fid := range idxResp.IDsMayBeInside {
+		cands = append(cands, candidate{fid: fid, test: true})
+	}
+
+	err = s.testCandidates(ctx, ds, s.loopStore(ds), cands, p, accept)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	for _, c := range cands {
+		if !c.done {
+			continue
+		}
+		if c.test {
+			pips++
+		}
+		// the inside candidates are exact when tested or returned by an exact strategy
+		cexact := !c.inside || exact || insideExact
+		if dbg != nil {
+			dbg.Candidates = append(dbg.Candidates, &insidesvc.WithinCandidate{
+				Id: c.fid.ID, Pos: uint32(c.fid.Pos), InsideCell: c.inside, Tested: cexact, Accepted: c.accepted,
+			})
+		}
+		if !c.accepted {
+			continue
+		}
+		level.Debug(s.logger).Log("msg", "Found inside feature",
+			"fid", c.fid.ID,
+			"properties", c.f.Properties,
+			"loop #", c.fid.Pos,
+			"inside_cell", c.inside)
+
+		fids = append(fids, c.fid)
+		features = append(features, c.f)
+		exacts = append(exacts, cexact)
+	}
+
+	if cached {
+		ds.results.Set(uint64(cellID), fids, 1)
+		if s.opts.SharedCache != nil {
+			s.setSharedResult(ds, cellID, fids)
+		}
+	}
+
+	pipHistogram.WithLabelValues(ds.name, strategy).Observe(float64(pips))
+	s.observeWithin(ds, strategy, start, false)
+	if strategy == s.opts.Strategy && dbg == nil && accept == nil && !ds.candidate && s.shadowed() {
+		s.shadow(ds, lat, lng, exact, fids, time.Since(start))
+	}
+	if dbg != nil {
+		dbg.PipMicros = time.Since(pipStart).Microseconds()
+		dbg.TotalMicros = time.Since(start).Microseconds()
+	}
+
+	return fids, features, exacts, nil
+}
+
+// exactInside returns true when the inside ids returned by the strategy index were tested against the polygons,
+// the other strategies answer from the inside covering cells
+func exactInside(strategy string) bool {
+	return strategy == insideout.ShapeIndexStrategy || strategy == insideout.PostGISStrategy
+}
+
+// cachedResult returns the within result for the cell from the results cache or the shared cache
+func (s *Server) cachedResult(ds *dataset, cellID s2.CellID) ([]insideout.FeatureIndexResponse, bool) {
+	if v, ok := ds.results.Get(uint64(cellID)); ok {
+		resultHitCounter.Inc()
+		observeCache(ds, resultTier, true)
+		return v.([]insideout.FeatureIndexResponse), true
+	}
+	resultMissCounter.Inc()
+	observeCache(ds, resultTier, false)
+
+	if s.opts.SharedCache == nil {
+		return nil, false
+	}
+	fids, ok := s.sharedResult(ds, cellID)
+	if ok {
+		ds.results.Set(uint64(cellID), fids, 1)
+	}
+	return fids, ok
+}
+
+// Nearest query exposed via gRPC, returns the feature containing the point
+// or the feature with the closest boundary up to the max distance
+func (s *Server) Nearest(
+	ctx context.Context, req *insidesvc.NearestRequest,
+) (resp *insidesvc.NearestResponse, terr error) {
+	ctx, span := tracer().Start(ctx, "Nearest")
+	defer span.End()
+
+	defer func() { s.handleError(ctx, terr, span) }()
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	span.SetAttributes(
+		label.Float64("lat", req.Lat),
+		label.Float64("lng", req.Lng),
+	)
+
+	resp = &insidesvc.NearestResponse{
+		Point: &insidesvc.Point{
+			Lat: req.Lat,
+			Lng: req.Lng,
+		},
+	}
+
+	wresp, err := s.Within(ctx, &insidesvc.WithinRequest{
+		Lat:              req.Lat,
+		Lng:              req.Lng,
+		RemoveGeometries: req.RemoveGeometries,
+		Dataset:          req.Dataset,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(wresp.Responses) > 0 {
+		resp.Response = wresp.Responses[0]
+		return resp, nil
+	}
+
+	maxDistance := s.opts.NearestMaxDistance
+	if req.MaxDistance > 0 && req.MaxDistance < maxDistance {
+		maxDistance = req.MaxDistance
+	}
+	if maxDistance <= 0 {
+		return resp, nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-	var idx insideout.Index
+	ds, err := s.dataset(req.Dataset)
+	if err != nil {
+		return nil, err
+	}
 
-	switch opts.Strategy {
-	case insideout.InsideTreeStrategy:
-		treeidx := treeindex.New(treeindex.Options{StopOnInsideFound: opts.StopOnFirstFound})
-		err := storage.LoadFeaturesCells(treeidx.Add)
-		if err != nil {
-			level.Error(logger).Log("msg", "failed to load cells from storage", "error", err, "strategy", opts.Strategy)
-			os.Exit(2)
+	p := s2.PointFromLatLng(s2.LatLngFromDegrees(req.Lat, req.Lng))
+	maxAngle := insideout.MetersToAngle(maxDistance)
+	coverer := &s2.RegionCoverer{MaxLevel: 20, MaxCells: 16}
+	cu := covering(ctx, coverer, s2.CapFromCenterAngle(p, maxAngle))
+
+	fids, err := ds.storage.IntersectDB(cu)
+	if err != nil {
+		return nil, err
+	}
+
+	var nearest *insideout.FeatureIndexResponse
+	var nearestFeature *insideout.Feature
+	minAngle := maxAngle
+	for i, fid := range fids {
+		if err := queryDone(ctx); err != nil {
+			return nil, err
 		}
-		idx = treeidx
-	case insideout.ShapeIndexStrategy:
-		shapeidx := shapeindex.New()
-		err := storage.LoadAllFeatures(shapeidx.Add)
+		f, err := s.feature(ctx, ds, fid.ID)
 		if err != nil {
-			level.Error(logger).Log("msg", "failed to load feature from storage", "error", err, "strategy", opts.Strategy)
-			os.Exit(2)
+			return nil, queryError(ctx, err)
+		}
+		if !s.live(ctx, f.Properties) {
+			continue
+		}
+		d := f.DistanceToBoundary(fid.Pos, p)
+		if d <= minAngle {
+			minAngle = d
+			nearest = &fids[i]
+			nearestFeature = f
 		}
-		idx = shapeidx
-	case insideout.DBStrategy:
-		dbidx := dbindex.New(storage, dbindex.Options{StopOnInsideFound: opts.StopOnFirstFound})
-		idx = dbidx
 	}
 
-	s := &Server{
-		storage:      storage,
-		logger:       logger,
-		healthServer: healthServer,
-		idx:          idx,
+	if nearest == nil {
+		level.Debug(s.logger).Log("msg", "no nearest feature found",
+			"lat", req.Lat,
+			"lng", req.Lng,
+			"max_distance", maxDistance,
+			"candidates_count", len(fids))
+		return resp, nil
 	}
 
-	// cache
-	if opts.CacheCount > 0 {
-		cache, err := ristretto.NewCache(&ristretto.Config{
-			NumCounters: int64(opts.CacheCount) * 10, // number of keys to track frequency
-			MaxCost:     int64(opts.CacheCount),      // maximum cost of cache
-			BufferItems: 64,                          // number of keys per Get buffer.
-		})
-		if err != nil {
-			return nil, err
-		}
-		s.cache = cache
+	fresp, err := newFeatureResponse(nearestFeature, *nearest, req.RemoveGeometries, nil)
+	if err != nil {
+		return nil, err
 	}
+	s.addTimezone(fresp, nearestFeature)
+	resp.Response = fresp
+	resp.Distance = insideout.AngleToMeters(minAngle)
 
-	return s, nil
+	return resp, nil
 }
 
-// feature fetch feature from cache or
-func (s *Server) feature(id uint32) (*insideout.Feature, error) {
-	if s.cache == nil {
-		return s.storage.LoadFeature(id)
+// Intersect query exposed via gRPC, returns features intersecting the requested geometry
+func (s *Server) Intersect(
+	ctx context.Context, req *insidesvc.IntersectRequest,
+) (resp *insidesvc.IntersectResponse, terr error) {
+	ctx, span := tracer().Start(ctx, "Intersect")
+	defer span.End()
+
+	defer func() { s.handleError(ctx, terr, span) }()
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	if req.Geometry == nil {
+		return nil, status.Error(codes.InvalidArgument, "missing geometry")
+	}
+	if req.Limit < 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid limit")
+	}
+	limit := int(req.Limit)
+	if max := s.opts.MaxIntersectResults; max > 0 && (limit == 0 || limit > max) {
+		limit = max
 	}
-	fi, found := s.cache.Get(id)
-	if !found {
-		lf, err := s.storage.LoadFeature(id)
+	var after *insideout.FeatureIndexResponse
+	if req.Cursor != "" {
+		fid, err := DecodeCursor(req.Cursor)
 		if err != nil {
-			return nil, err
+			return nil, status.Error(codes.InvalidArgument, err.Error())
 		}
-		s.cache.Set(id, lf, 1)
-		featureMissCounter.Inc()
-		return lf, nil
+		after = &fid
 	}
 
-	featureHitCounter.Inc()
-	return fi.(*insideout.Feature), nil
-}
+	var region s2.Region
+	// intersects returns true if the geometry intersects the loop pos of f outside of its holes
+	var intersects func(f *insideout.Feature, pos uint16) bool
 
-// Within query exposed via gRPC
-func (s *Server) Within(
-	ctx context.Context, req *insidesvc.WithinRequest,
-) (resp *insidesvc.WithinResponse, terr error) {
-	span, _ := opentracing.StartSpanFromContext(ctx, "Within")
-	defer span.Finish()
+	c := req.Geometry.Coordinates
+	switch req.Geometry.Type {
+	case insidesvc.Geometry_POINT:
+		if len(c) != 2 {
+			return nil, status.Error(codes.InvalidArgument, "invalid point")
+		}
+		p := s2.PointFromLatLng(s2.LatLngFromDegrees(c[1], c[0]))
+		region = p
+		intersects = func(f *insideout.Feature, pos uint16) bool {
+			return f.PolygonContainsPoint(pos, p)
+		}
+	case insidesvc.Geometry_LINESTRING:
+		pl := insideout.PolylineFromCoordinates(c)
+		if pl == nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid linestring")
+		}
+		region = pl
+		intersects = func(f *insideout.Feature, pos uint16) bool {
+			if !insideout.LoopIntersectsPolyline(f.Loops[pos], pl) {
+				return false
+			}
+			for _, h := range holes(f, pos) {
+				if insideout.LoopContainsPolyline(h, pl) {
+					return false
+				}
+			}
+			return true
+		}
+	case insidesvc.Geometry_POLYGON:
+		ql := insideout.LoopFromCoordinates(c)
+		if ql == nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid polygon")
+		}
+		ql.Normalize()
+		region = ql
+		intersects = func(f *insideout.Feature, pos uint16) bool {
+			if !f.Loops[pos].Intersects(ql) {
+				return false
+			}
+			for _, h := range holes(f, pos) {
+				if h.Contains(ql) {
+					return false
+				}
+			}
+			return true
+		}
+	default:
+		return nil, status.Error(codes.InvalidArgument, "unsupported geometry type")
+	}
+
+	span.SetAttributes(
+		label.String("geometry_type", req.Geometry.Type.String()),
+	)
 
-	defer s.handleError(terr, span)
+	coverer := &s2.RegionCoverer{MaxLevel: 20, MaxCells: 32}
+	cu := covering(ctx, coverer, region)
+	if len(s.opts.ShardCells) > 0 {
+		cu = s2.CellUnionFromIntersection(cu, s.opts.ShardCells)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-	idxResp, err := s.idx.Stab(req.Lat, req.Lng)
+	ds, err := s.dataset(req.Dataset)
 	if err != nil {
 		return nil, err
 	}
 
-	level.Debug(s.logger).Log("msg", "querying within",
-		"lat", req.Lat,
-		"lng", req.Lng,
-		"idx_resp", idxResp,
-	)
-
-	span.LogFields(
-		slog.Float64("lat", req.Lat),
-		slog.Float64("lng", req.Lng),
-	)
+	fids, err := ds.storage.IntersectDB(cu)
+	if err != nil {
+		return nil, err
+	}
 
-	var fresps []*insidesvc.FeatureResponse
+	// stable ordering, the cursors rely on it
+	sort.Slice(fids, func(i, j int) bool {
+		return fidLess(fids[i], fids[j])
+	})
 
-	for _, fid := range idxResp.IDsInside {
-		f, err := s.feature(fid.ID)
-		if err != nil {
-			return nil, err
+	resp = &insidesvc.IntersectResponse{}
+	var last insideout.FeatureIndexResponse
+	for _, fid := range fids {
+		if after != nil && !fidLess(*after, fid) {
+			continue
 		}
-		level.Debug(s.logger).Log("msg", "Found inside feature",
-			"fid", fid.ID,
-			"properties", f.Properties,
-			"loop #", fid.Pos)
-
-		feature := &insidesvc.Feature{}
-
-		if !req.RemoveGeometries {
-			l := f.Loops[fid.Pos]
-			feature.Geometry = &insidesvc.Geometry{
-				Type:        insidesvc.Geometry_POLYGON,
-				Coordinates: insideout.CoordinatesFromLoops(l),
-			}
+		if err := queryDone(ctx); err != nil {
+			return nil, err
 		}
-
-		//TODO: filter properties
-		prop, err := insideout.PropertiesToValues(f)
+		f, err := s.feature(ctx, ds, fid.ID)
 		if err != nil {
-			return nil, err
+			return nil, queryError(ctx, err)
 		}
-		feature.Properties = prop
-		feature.Properties[insidesvc.LoopIndexProperty] = &structpb.Value{
-			Kind: &structpb.Value_NumberValue{NumberValue: float64(fid.Pos)},
+		if !s.live(ctx, f.Properties) || !intersects(f, fid.Pos) {
+			continue
 		}
-		feature.Properties[insidesvc.FeatureIDProperty] = &structpb.Value{
-			Kind: &structpb.Value_NumberValue{NumberValue: float64(fid.ID)},
+		if limit > 0 && len(resp.Responses) == limit {
+			// another feature intersects, the next page starts after the last returned
+			resp.NextCursor = EncodeCursor(last)
+			break
 		}
-
-		fresp := &insidesvc.FeatureResponse{
-			Id:      fid.ID,
-			Feature: feature,
+		fresp, err := newFeatureResponse(f, fid, req.RemoveGeometries, nil)
+		if err != nil {
+			return nil, err
 		}
-		fresps = append(fresps, fresp)
+		resp.Responses = append(resp.Responses, fresp)
+		last = fid
 	}
 
-	p := s2.PointFromLatLng(s2.LatLngFromDegrees(req.Lat, req.Lng))
-	for _, fid := range idxResp.IDsMayBeInside {
-		f, err := s.feature(fid.ID)
+	level.Debug(s.logger).Log("msg", "result intersect",
+		"geometry_type", req.Geometry.Type.String(),
+		"candidates_count", len(fids),
+		"features_count", len(resp.Responses),
+		"more", resp.NextCursor != "")
+
+	return resp, nil
+}
+
+// WithinStream query exposed via gRPC streaming, one response is sent for every request received,
+// backpressure is handled by the gRPC flow control.
+// The error of a request is sent in its response and the stream goes on, it ends when its context is done
+func (s *Server) WithinStream(stream insidesvc.Inside_WithinStreamServer) error {
+	ctx := stream.Context()
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		level.Debug(s.logger).Log("msg", "Found maybe inside feature",
-			"fid", fid.ID,
-			"properties", f.Properties,
-			"loop #", fid.Pos)
+		resp, err := s.Within(ctx, req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			resp = &insidesvc.WithinResponse{
+				Point: &insidesvc.Point{Lat: req.Lat, Lng: req.Lng},
+				Error: insidesvc.StatusError(err),
+			}
+		}
 
-		l := f.Loops[fid.Pos]
-		if !l.ContainsPoint(p) {
-			continue
+		if err := stream.Send(resp); err != nil {
+			return err
 		}
-		level.Debug(s.logger).Log("msg", "Found maybe inside feature PIP valid",
-			"fid", fid.ID,
-			"properties", f.Properties,
-			"loop #", fid.Pos)
+	}
+}
 
-		feature := &insidesvc.Feature{}
+// Track returns the geofence events of the objects positions sent on the stream,
+// the state of the objects is shared by all the streams
+func (s *Server) Track(stream insidesvc.Inside_TrackServer) error {
+	if s.opts.Geofence == nil {
+		return status.Error(codes.Unimplemented, "geofencing is not enabled")
+	}
 
-		if !req.RemoveGeometries {
-			feature.Geometry = &insidesvc.Geometry{
-				Type:        insidesvc.Geometry_POLYGON,
-				Coordinates: insideout.CoordinatesFromLoops(l),
-			}
+	ctx := stream.Context()
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
 		}
-
-		//TODO: filter properties
-		prop, err := insideout.PropertiesToValues(f)
 		if err != nil {
-			return nil, err
-		}
-		feature.Properties = prop
-		feature.Properties[insidesvc.LoopIndexProperty] = &structpb.Value{
-			Kind: &structpb.Value_NumberValue{NumberValue: float64(fid.Pos)},
+			return err
 		}
-		feature.Properties[insidesvc.FeatureIDProperty] = &structpb.Value{
-			Kind: &structpb.Value_NumberValue{NumberValue: float64(fid.ID)},
+
+		events, err := s.Geofence(ctx, req)
+		if err != nil {
+			return err
 		}
 
-		fresp := &insidesvc.FeatureResponse{
-			Id:      fid.ID,
-			Feature: feature,
+		for _, e := range events {
+			ge, err := geofenceEvent(e)
+			if err != nil {
+				return err
+			}
+			if err := stream.Send(ge); err != nil {
+				return err
+			}
 		}
-		fresps = append(fresps, fresp)
 	}
+}
 
-	level.Debug(s.logger).Log("msg", "result stab",
-		"lat", req.Lat,
-		"lng", req.Lng,
-		"features_count", len(fresps))
+// Geofence queries the features containing the position of a tracked object,
+// returns the geofence events of the object, also sent to the geofence sink
+func (s *Server) Geofence(ctx context.Context, req *insidesvc.TrackRequest) ([]geofence.Event, error) {
+	if s.opts.Geofence == nil {
+		return nil, status.Error(codes.Unimplemented, "geofencing is not enabled")
+	}
+	if req.Id == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing tracked object id")
+	}
 
-	resp = &insidesvc.WithinResponse{
-		Point: &insidesvc.Point{
-			Lat: req.Lat,
-			Lng: req.Lng,
-		},
-		Responses: fresps,
+	resp, err := s.Within(ctx, &insidesvc.WithinRequest{
+		Lat:              req.Lat,
+		Lng:              req.Lng,
+		RemoveGeometries: true,
+		SelectProperties: req.SelectProperties,
+		Filter:           req.Filter,
+		Dataset:          req.Dataset,
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return resp, nil
+	features := make([]geofence.Feature, len(resp.Responses))
+	for i, fr := range resp.Responses {
+		features[i] = geofence.Feature{ID: fr.Id, Properties: insideout.ValueToProperties(fr.Feature.Properties)}
+	}
+
+	p := geofence.Position{
+		ObjectID: req.Id,
+		Dataset:  req.Dataset,
+		Lat:      req.Lat,
+		Lng:      req.Lng,
+	}
+	if p.Dataset == "" {
+		p.Dataset = s.defaultName
+	}
+	// the objects of the tenants are namespaced, the sink receives the tenant prefixed ids
+	if t, ok := tenant.FromContext(ctx); ok {
+		p.ObjectID = t.Name + "/" + req.Id
+	}
+	if req.Time != 0 {
+		p.Time = time.Unix(0, req.Time*int64(time.Millisecond))
+	}
+
+	events, err := s.opts.Geofence.Update(p, features)
+	if err != nil {
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
+	if s.opts.GeofenceSink != nil && len(events) > 0 {
+		s.opts.GeofenceSink.Send(events)
+	}
+	if p.ObjectID != req.Id {
+		tevents := make([]geofence.Event, len(events))
+		for i, e := range events {
+			e.ObjectID = req.Id
+			tevents[i] = e
+		}
+		events = tevents
+	}
+	return events, nil
+}
+
+// geofenceEvent returns the gRPC message of e
+func geofenceEvent(e geofence.Event) (*insidesvc.GeofenceEvent, error) {
+	props, err := insideout.PropertiesToValues(&insideout.Feature{Properties: e.Properties})
+	if err != nil {
+		return nil, err
+	}
+	return &insidesvc.GeofenceEvent{
+		Type:       insidesvc.GeofenceEvent_Type(e.Type),
+		Id:         e.ObjectID,
+		Dataset:    e.Dataset,
+		FeatureId:  e.FeatureID,
+		Properties: props,
+		Point:      &insidesvc.Point{Lat: e.Lat, Lng: e.Lng},
+		Time:       e.Time.UnixNano() / int64(time.Millisecond),
+		Duration:   e.Duration.Milliseconds(),
+	}, nil
 }
 
 func (s *Server) Get(ctx context.Context, req *insidesvc.GetRequest) (feature *insidesvc.Feature, terr error) {
-	span, _ := opentracing.StartSpanFromContext(ctx, "Get")
-	defer span.Finish()
+	ctx, span := tracer().Start(ctx, "Get")
+	defer span.End()
 
-	defer s.handleError(terr, span)
+	defer func() { s.handleError(ctx, terr, span) }()
 
-	span.LogFields(
-		slog.Uint32("feature_id", req.Id),
-		slog.Uint32("loop_index", req.LoopIndex),
+	span.SetAttributes(
+		label.Uint32("feature_id", req.Id),
+		label.Uint32("loop_index", req.LoopIndex),
 	)
 
-	f, err := s.feature(req.Id)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ds, err := s.dataset(req.Dataset)
 	if err != nil {
 		return nil, err
 	}
 
-	if f == nil {
+	f, err := s.feature(ctx, ds, req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if f == nil || !s.live(ctx, f.Properties) {
 		return nil, status.Error(codes.NotFound, "can't found feature")
 	}
 
@@ -313,15 +1388,161 @@ func (s *Server) Get(ctx context.Context, req *insidesvc.GetRequest) (feature *i
 	return feature, nil
 }
 
-// Stab returns features containing lat lng
+// GetFeature returns the feature id with all its polygons exposed via gRPC,
+// simplified with Douglas-Peucker when requested
+func (s *Server) GetFeature(ctx context.Context, req *insidesvc.GetFeatureRequest) (feature *insidesvc.Feature, terr error) {
+	ctx, span := tracer().Start(ctx, "GetFeature")
+	defer span.End()
+
+	defer func() { s.handleError(ctx, terr, span) }()
+
+	span.SetAttributes(label.Uint32("feature_id", req.Id))
+
+	if req.Simplify < 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid simplify tolerance")
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ds, err := s.dataset(req.Dataset)
+	if err != nil {
+		return nil, err
+	}
+
+	id := req.Id
+	if req.ExternalId != "" {
+		if ds.properties == nil || ds.properties.idProperty == "" {
+			return nil, status.Errorf(codes.FailedPrecondition, "dataset %s was indexed without an id property", ds.name)
+		}
+		ids := ds.properties.externalIDs(req.ExternalId)
+		if len(ids) != 1 {
+			return nil, status.Errorf(codes.NotFound, "can't find feature %s", req.ExternalId)
+		}
+		id = ids[0]
+		span.SetAttributes(label.String("external_id", req.ExternalId))
+	}
+
+	f, err := s.feature(ctx, ds, id)
+	if err != nil || f == nil || !s.live(ctx, f.Properties) {
+		return nil, queryError(ctx, status.Errorf(codes.NotFound, "can't find feature %d", id))
+	}
+
+	g, err := geometryMessage(wholeGeometry(f, req.Simplify))
+	if err != nil {
+		return nil, err
+	}
+	prop, err := insideout.PropertiesToValues(f)
+	if err != nil {
+		return nil, err
+	}
+	prop[insidesvc.FeatureIDProperty] = &structpb.Value{
+		Kind: &structpb.Value_NumberValue{NumberValue: float64(id)},
+	}
+
+	return &insidesvc.Feature{Geometry: g, Properties: prop}, nil
+}
+
+// Info returns the served datasets and their index infos
+func (s *Server) Info(ctx context.Context, req *insidesvc.InfoRequest) (*insidesvc.InfoResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	resp := &insidesvc.InfoResponse{
+		DefaultDataset: s.defaultName,
+		Version:        s.opts.Version,
+		StartTime:      s.startTime.Unix(),
+		Uptime:         int64(time.Since(s.startTime).Seconds()),
+	}
+	t, isTenant := tenant.FromContext(ctx)
+	if isTenant {
+		resp.Tenant = t.Name
+		resp.TenantQuota = t.Quota
+	}
+	names := make([]string, 0, len(s.datasets))
+	for name := range s.datasets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		infos := s.datasets[name].infos
+		di := &insidesvc.DatasetInfo{
+			Name:           name,
+			Filename:       infos.Filename,
+			FeatureCount:   infos.FeatureCount,
+			IndexTime:      infos.IndexTime.Unix(),
+			IndexerVersion: infos.IndexerVersion,
+			MinCoverLevel:  int32(infos.MinCoverLevel),
+			Strategy:       s.opts.Strategy,
+			Strategies:     s.strategies(),
+			InsideCover:    coverOptions(infos.InsideCover),
+			OutsideCover:   coverOptions(infos.OutsideCover),
+			AutoCover:      infos.AutoCover,
+			Compression:    infos.Compression,
+			LoopEncoding:   infos.LoopEncoding,
+		}
+		if isTenant {
+			di.TenantFeatureCount = s.datasets[name].tenants[t.Name]
+		}
+		resp.Datasets = append(resp.Datasets, di)
+	}
+
+	return resp, nil
+}
+
+// coverOptions returns the coverer parameters o for Info, nil when unknown
+func coverOptions(o *insideout.CoverOptions) *insidesvc.CoverOptions {
+	if o == nil {
+		return nil
+	}
+	return &insidesvc.CoverOptions{
+		MinLevel: int32(o.MinLevel),
+		MaxLevel: int32(o.MaxLevel),
+		MaxCells: int32(o.MaxCells),
+		LevelMod: int32(o.LevelMod),
+	}
+}
+
+// indexStab returns the loops of the index of strategy of ds containing lat lng,
+// the points outside of the coverage of the DB or of the cells of the filter are rejected without querying the index,
+// the indexes implementing insideout.ContextIndex stop once ctx is done
+func (s *Server) indexStab(ctx context.Context, ds *dataset, strategy string, lat, lng float64) (insideout.IndexResponse, error) {
+	_, idx, err := s.index(ds, strategy)
+	if err != nil {
+		return insideout.IndexResponse{}, err
+	}
+	if !ds.infos.Covers(lat, lng) {
+		coverageCounter.WithLabelValues(ds.name).Inc()
+		return insideout.IndexResponse{}, nil
+	}
+	if ds.filter != nil && !ds.filter.MayCover(lat, lng) {
+		cellFilterCounter.WithLabelValues(ds.name).Inc()
+		return insideout.IndexResponse{}, nil
+	}
+	if cidx, ok := idx.(insideout.ContextIndex); ok {
+		return cidx.StabContext(ctx, lat, lng)
+	}
+	return idx.Stab(lat, lng)
+}
+
+// IndexStab returns features of the default dataset containing lat lng
 func (s *Server) IndexStab(lat, lng float64) ([]*insideout.Feature, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ds, err := s.dataset("")
+	if err != nil {
+		return nil, err
+	}
+
 	var res []*insideout.Feature
-	idxResp, err := s.idx.Stab(lat, lng)
+	idxResp, err := s.indexStab(context.Background(), ds, s.opts.Strategy, lat, lng)
 	if err != nil {
 		return nil, err
 	}
 	for _, fid := range idxResp.IDsInside {
-		f, err := s.feature(fid.ID)
+		f, err := s.feature(context.Background(), ds, fid.ID)
 		if err != nil {
 			return nil, err
 		}
@@ -332,13 +1553,14 @@ func (s *Server) IndexStab(lat, lng float64) ([]*insideout.Feature, error) {
 		res = append(res, f)
 	}
 
+	p := s2.PointFromLatLng(s2.LatLngFromDegrees(lat, lng))
+	ls := s.loopStore(ds)
 	for _, fid := range idxResp.IDsMayBeInside {
-		f, err := s.feature(fid.ID)
+		f, accepted, err := s.testCandidate(context.Background(), ds, ls, fid, p, true)
 		if err != nil {
 			return nil, err
 		}
-		l := f.Loops[fid.Pos]
-		if l.ContainsPoint(s2.PointFromLatLng(s2.LatLngFromDegrees(lat, lng))) {
+		if accepted {
 			level.Debug(s.logger).Log("msg", "Found outside + PIP feature",
 				"fid", fid.ID,
 				"properties", f.Properties,
@@ -349,18 +1571,101 @@ func (s *Server) IndexStab(lat, lng float64) ([]*insideout.Feature, error) {
 	return res, nil
 }
 
-func (s *Server) handleError(terr error, span opentracing.Span) {
+// holes returns the holes of the loop pos of f
+func holes(f *insideout.Feature, pos uint16) []*s2.Loop {
+	if int(pos) >= len(f.Holes) {
+		return nil
+	}
+	return f.Holes[pos]
+}
+
+// loopStore returns the storage of ds testing the loops in place, nil when the features are held in memory:
+// without features cache a decoded loop is tested once, not worth the allocations of its decoding and s2 index
+func (s *Server) loopStore(ds *dataset) insideout.LoopStore {
+	if ds.cache != nil {
+		return nil
+	}
+	if _, ok := ds.idx.(featureIndex); ok {
+		return nil
+	}
+	ls, _ := ds.storage.(insideout.LoopStore)
+	return ls
+}
+
+// testCandidate returns the feature of the candidate fid and whether its loop contains p outside of its holes when test is set,
+// the loop is tested in place by ls when not nil, the feature is then only loaded when accepted
+func (s *Server) testCandidate(ctx context.Context, ds *dataset, ls insideout.LoopStore,
+	fid insideout.FeatureIndexResponse, p s2.Point, test bool) (*insideout.Feature, bool, error) {
+	if test && ls != nil {
+		inside, err := ls.LoopContainsPoint(fid.ID, fid.Pos, p)
+		if err != nil || !inside {
+			return nil, false, err
+		}
+		test = false
+	}
+
+	f, err := s.feature(ctx, ds, fid.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	if !test {
+		return f, true, nil
+	}
+	return f, f.PolygonContainsPoint(fid.Pos, p), nil
+}
+
+// newFeatureResponse returns the response for the loop fid.Pos of f,
+// only the properties in fields are returned, all if fields is empty
+func newFeatureResponse(
+	f *insideout.Feature, fid insideout.FeatureIndexResponse, removeGeometries bool, fields []string,
+) (*insidesvc.FeatureResponse, error) {
+	feature := &insidesvc.Feature{}
+
+	if !removeGeometries {
+		l := f.Loops[fid.Pos]
+		feature.Geometry = &insidesvc.Geometry{
+			Type:        insidesvc.Geometry_POLYGON,
+			Coordinates: insideout.CoordinatesFromLoops(l),
+		}
+	}
+
+	prop, err := insideout.PropertiesToValues(f)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) > 0 {
+		selected := make(map[string]*structpb.Value, len(fields)+2)
+		for _, k := range fields {
+			if v, ok := prop[k]; ok {
+				selected[k] = v
+			}
+		}
+		prop = selected
+	}
+	feature.Properties = prop
+	feature.Properties[insidesvc.LoopIndexProperty] = &structpb.Value{
+		Kind: &structpb.Value_NumberValue{NumberValue: float64(fid.Pos)},
+	}
+	feature.Properties[insidesvc.FeatureIDProperty] = &structpb.Value{
+		Kind: &structpb.Value_NumberValue{NumberValue: float64(fid.ID)},
+	}
+
+	return &insidesvc.FeatureResponse{
+		Id:      fid.ID,
+		Feature: feature,
+	}, nil
+}
+
+func (s *Server) handleError(ctx context.Context, terr error, span trace.Span) {
 	if terr != nil {
-		// do not log not found as error
-		if status, ok := status.FromError(terr); ok && status.Code() == codes.NotFound {
+		// do not log not found and the queries canceled by the clients as error
+		if status, ok := status.FromError(terr); ok && (status.Code() == codes.NotFound || status.Code() == codes.Canceled) {
 			level.Debug(s.logger).Log("error", terr)
 			return
 		}
 		errorCounter.Inc()
-		span.LogFields(
-			slog.String("error", terr.Error()),
-		)
-		span.SetTag("error", true)
+		span.RecordError(ctx, terr)
+		span.SetStatus(otelcodes.Error, terr.Error())
 
 		level.Error(s.logger).Log("error", terr)
 	}
@@ -0,0 +1,480 @@
+package server
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/geojson"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/insidesvc"
+	"github.com/akhenakh/insideout/server/geofence"
+	"github.com/akhenakh/insideout/server/querylog"
+	"github.com/akhenakh/insideout/storage/bbolt"
+)
+
+func TestServer_Datasets(t *testing.T) {
+	a, clean := setup(t, "A", 0)
+	defer clean()
+	b, clean := setup(t, "B", 10)
+	defer clean()
+
+	s, err := New(a, log.NewNopLogger(), nil, Options{
+		Strategy:    insideout.DBStrategy,
+		CacheCount:  10,
+		DatasetName: "a",
+		Version:     "test",
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.AddDataset("b", b))
+	require.Error(t, s.AddDataset("b", b))
+	require.Equal(t, []string{"a", "b"}, s.Datasets())
+
+	ctx := context.Background()
+
+	// default dataset
+	resp, err := s.Within(ctx, &insidesvc.WithinRequest{Lat: 0.5, Lng: 0.5, RemoveGeometries: true})
+	require.NoError(t, err)
+	require.Len(t, resp.Responses, 1)
+	require.Equal(t, "A", resp.Responses[0].Feature.Properties["name"].GetStringValue())
+
+	resp, err = s.Within(ctx, &insidesvc.WithinRequest{Lat: 10.5, Lng: 10.5, Dataset: "b"})
+	require.NoError(t, err)
+	require.Len(t, resp.Responses, 1)
+	require.Equal(t, "B", resp.Responses[0].Feature.Properties["name"].GetStringValue())
+
+	resp, err = s.Within(ctx, &insidesvc.WithinRequest{Lat: 0.5, Lng: 0.5, Dataset: "b"})
+	require.NoError(t, err)
+	require.Len(t, resp.Responses, 0)
+
+	_, err = s.Within(ctx, &insidesvc.WithinRequest{Lat: 0.5, Lng: 0.5, Dataset: "unknown"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	info, err := s.Info(ctx, &insidesvc.InfoRequest{})
+	require.NoError(t, err)
+	require.Equal(t, "a", info.DefaultDataset)
+	require.Len(t, info.Datasets, 2)
+	require.Equal(t, "b", info.Datasets[1].Name)
+	require.Equal(t, uint32(1), info.Datasets[1].FeatureCount)
+	require.Equal(t, insideout.DBStrategy, info.Datasets[1].Strategy)
+	require.Equal(t, "test", info.Version)
+	require.Equal(t, int32(24), info.Datasets[1].InsideCover.MaxCells)
+	require.Equal(t, int32(16), info.Datasets[1].OutsideCover.MaxCells)
+
+	// swap the content of a
+	old, err := s.ReloadDataset("a", b)
+	require.NoError(t, err)
+	require.Equal(t, a, old)
+
+	resp, err = s.Within(ctx, &insidesvc.WithinRequest{Lat: 10.5, Lng: 10.5, Dataset: "a"})
+	require.NoError(t, err)
+	require.Len(t, resp.Responses, 1)
+	require.Equal(t, "B", resp.Responses[0].Feature.Properties["name"].GetStringValue())
+
+	_, err = s.ReloadDataset("unknown", b)
+	require.Error(t, err)
+}
+
+func TestServer_WithinStream(t *testing.T) {
+	storage, clean := setup(t, "A", 0)
+	defer clean()
+
+	s, err := New(storage, log.NewNopLogger(), nil, Options{Strategy: insideout.DBStrategy})
+	require.NoError(t, err)
+
+	lis := bufconn.Listen(1 << 20)
+	gs := grpc.NewServer()
+	insidesvc.RegisterInsideServer(gs, s)
+	go gs.Serve(lis)
+	defer gs.Stop()
+
+	conn, err := grpc.Dial("bufnet", grpc.WithInsecure(), grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return lis.Dial()
+	}))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	stream, err := insidesvc.NewInsideClient(conn).WithinStream(context.Background())
+	require.NoError(t, err)
+
+	// the invalid request gets its error, the stream goes on
+	reqs := []*insidesvc.WithinRequest{
+		{Lat: 0.5, Lng: 0.5},
+		{Lat: 0.5, Lng: 0.5, Limit: -1},
+		{Lat: 10.5, Lng: 10.5},
+		{Lat: 0.2, Lng: 0.8},
+	}
+	for _, req := range reqs {
+		require.NoError(t, stream.Send(req))
+	}
+	require.NoError(t, stream.CloseSend())
+
+	var resps []*insidesvc.WithinResponse
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		resps = append(resps, resp)
+	}
+	require.Len(t, resps, len(reqs))
+
+	require.Nil(t, resps[0].Error)
+	require.Len(t, resps[0].Responses, 1)
+
+	require.NotNil(t, resps[1].Error)
+	require.Equal(t, int32(codes.InvalidArgument), resps[1].Error.Code)
+	require.Equal(t, codes.InvalidArgument, status.Code(resps[1].Error.Err()))
+	require.Equal(t, 0.5, resps[1].Point.Lat)
+	require.Empty(t, resps[1].Responses)
+
+	require.Nil(t, resps[2].Error)
+	require.Empty(t, resps[2].Responses)
+
+	require.Nil(t, resps[3].Error)
+	require.Len(t, resps[3].Responses, 1)
+	require.Equal(t, 0.8, resps[3].Point.Lng)
+}
+
+func TestServer_BoundaryDistance(t *testing.T) {
+	a, clean := setup(t, "A", 0)
+	defer clean()
+
+	s, err := New(a, log.NewNopLogger(), nil, Options{Strategy: insideout.DBStrategy, CacheCount: 10})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	resp, err := s.Within(ctx, &insidesvc.WithinRequest{Lat: 0.5, Lng: 0.5})
+	require.NoError(t, err)
+	require.Len(t, resp.Responses, 1)
+	require.Zero(t, resp.Responses[0].BoundaryDistance)
+
+	// 0.2 degree from the south edge
+	resp, err = s.Within(ctx, &insidesvc.WithinRequest{Lat: 0.2, Lng: 0.5, BoundaryDistance: true})
+	require.NoError(t, err)
+	require.Len(t, resp.Responses, 1)
+	require.InDelta(t, 22239, resp.Responses[0].BoundaryDistance, 100)
+}
+
+func TestServer_Exact(t *testing.T) {
+	a, clean := setup(t, "A", 0)
+	defer clean()
+
+	s, err := New(a, log.NewNopLogger(), nil, Options{Strategy: insideout.InsideTreeStrategy, CacheCount: 10})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	// in an inside cell
+	resp, err := s.Within(ctx, &insidesvc.WithinRequest{Lat: 0.5, Lng: 0.5})
+	require.NoError(t, err)
+	require.Len(t, resp.Responses, 1)
+	require.False(t, resp.Responses[0].Exact)
+
+	resp, err = s.Within(ctx, &insidesvc.WithinRequest{Lat: 0.5, Lng: 0.5, Exact: true})
+	require.NoError(t, err)
+	require.Len(t, resp.Responses, 1)
+	require.True(t, resp.Responses[0].Exact)
+
+	// near the boundary, in an outside cell only
+	resp, err = s.Within(ctx, &insidesvc.WithinRequest{Lat: 0.0001, Lng: 0.5})
+	require.NoError(t, err)
+	require.Len(t, resp.Responses, 1)
+	require.True(t, resp.Responses[0].Exact)
+}
+
+func TestServer_WithinDebug(t *testing.T) {
+	a, clean := setup(t, "A", 0)
+	defer clean()
+
+	s, err := New(a, log.NewNopLogger(), nil, Options{
+		Strategy: insideout.InsideTreeStrategy, ResultCacheLevel: 10, ResultCacheCount: 100,
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	resp, err := s.Within(ctx, &insidesvc.WithinRequest{Lat: 0.5, Lng: 0.5})
+	require.NoError(t, err)
+	require.Nil(t, resp.Debug)
+
+	// in an inside cell, the cached result is not used
+	resp, err = s.Within(ctx, &insidesvc.WithinRequest{Lat: 0.5, Lng: 0.5, Debug: true})
+	require.NoError(t, err)
+	require.Len(t, resp.Responses, 1)
+	require.NotNil(t, resp.Debug)
+	require.Equal(t, int32(12), resp.Debug.CellLevel)
+	require.Equal(t, s2.CellIDFromLatLng(s2.LatLngFromDegrees(0.5, 0.5)).Parent(12).ToToken(), resp.Debug.CellToken)
+	require.Equal(t, insideout.InsideTreeStrategy, resp.Debug.Strategy)
+	require.Equal(t, []*insidesvc.WithinCandidate{{Id: 0, Pos: 0, InsideCell: true, Accepted: true}}, resp.Debug.Candidates)
+	require.True(t, resp.Debug.TotalMicros >= resp.Debug.IndexMicros)
+
+	// near the boundary, outside, in an outside cell only
+	resp, err = s.Within(ctx, &insidesvc.WithinRequest{Lat: -0.0001, Lng: 0.5, Debug: true})
+	require.NoError(t, err)
+	require.Empty(t, resp.Responses)
+	require.Equal(t, []*insidesvc.WithinCandidate{{Id: 0, Pos: 0, Tested: true}}, resp.Debug.Candidates)
+}
+
+func TestServer_WithinInPlace(t *testing.T) {
+	a, clean := setup(t, "A", 0)
+	defer clean()
+
+	// without features cache the loops are tested in the storage
+	s, err := New(a, log.NewNopLogger(), nil, Options{Strategy: insideout.DBStrategy})
+	require.NoError(t, err)
+	require.NotNil(t, s.loopStore(s.datasets[""]))
+
+	ctx := context.Background()
+	resp, err := s.Within(ctx, &insidesvc.WithinRequest{Lat: 0.0001, Lng: 0.5, Debug: true})
+	require.NoError(t, err)
+	require.Len(t, resp.Responses, 1)
+	require.Equal(t, "A", resp.Responses[0].Feature.Properties["name"].GetStringValue())
+	require.Equal(t, []*insidesvc.WithinCandidate{{Id: 0, Pos: 0, Tested: true, Accepted: true}}, resp.Debug.Candidates)
+
+	resp, err = s.Within(ctx, &insidesvc.WithinRequest{Lat: -0.0001, Lng: 0.5, Debug: true})
+	require.NoError(t, err)
+	require.Empty(t, resp.Responses)
+	require.Equal(t, []*insidesvc.WithinCandidate{{Id: 0, Pos: 0, Tested: true}}, resp.Debug.Candidates)
+
+	fs, err := s.IndexStab(0.0001, 0.5)
+	require.NoError(t, err)
+	require.Len(t, fs, 1)
+
+	s, err = New(a, log.NewNopLogger(), nil, Options{Strategy: insideout.DBStrategy, CacheCount: 10})
+	require.NoError(t, err)
+	require.Nil(t, s.loopStore(s.datasets[""]))
+}
+
+func TestServer_Geofence(t *testing.T) {
+	a, clean := setup(t, "A", 0)
+	defer clean()
+
+	s, err := New(a, log.NewNopLogger(), nil, Options{Strategy: insideout.DBStrategy, CacheCount: 10})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	_, err = s.Geofence(ctx, &insidesvc.TrackRequest{Id: "truck", Lat: 0.5, Lng: 0.5})
+	require.Equal(t, codes.Unimplemented, status.Code(err))
+
+	s.opts.Geofence = geofence.New(geofence.Options{})
+	_, err = s.Geofence(ctx, &insidesvc.TrackRequest{Lat: 0.5, Lng: 0.5})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	events, err := s.Geofence(ctx, &insidesvc.TrackRequest{Id: "truck", Lat: 0.5, Lng: 0.5, Time: 1000})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, geofence.Enter, events[0].Type)
+	require.Equal(t, "A", events[0].Properties["name"])
+
+	events, err = s.Geofence(ctx, &insidesvc.TrackRequest{Id: "truck", Lat: 5, Lng: 5, Time: 3000})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	ge, err := geofenceEvent(events[0])
+	require.NoError(t, err)
+	require.Equal(t, insidesvc.GeofenceEvent_EXIT, ge.Type)
+	require.Equal(t, int64(2000), ge.Duration)
+	require.Equal(t, int64(3000), ge.Time)
+	require.Equal(t, "A", ge.Properties["name"].GetStringValue())
+}
+
+// memQueryLog records the exported query log entries
+type memQueryLog struct {
+	entries []querylog.Entry
+}
+
+func (w *memQueryLog) Write(ctx context.Context, entries []querylog.Entry) error {
+	w.entries = append(w.entries, entries...)
+	return nil
+}
+
+func (w *memQueryLog) Close() error { return nil }
+
+func TestServer_QueryLog(t *testing.T) {
+	a, clean := setup(t, "A", 0)
+	defer clean()
+
+	w := &memQueryLog{}
+	ql := querylog.New(w, querylog.Options{}, log.NewNopLogger())
+	s, err := New(a, log.NewNopLogger(), nil, Options{Strategy: insideout.DBStrategy, DatasetName: "A", QueryLog: ql})
+	require.NoError(t, err)
+
+	_, err = s.Within(context.Background(), &insidesvc.WithinRequest{Lat: 0.5, Lng: 0.5})
+	require.NoError(t, err)
+	_, err = s.Within(context.Background(), &insidesvc.WithinRequest{Lat: 5, Lng: 5})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.NoError(t, ql.Run(ctx))
+
+	require.Len(t, w.entries, 2)
+	require.Equal(t, "A", w.entries[0].Dataset)
+	require.Equal(t, 0.5, w.entries[0].Lat)
+	require.Equal(t, []uint32{0}, w.entries[0].IDs)
+	require.True(t, w.entries[0].Latency > 0)
+	require.False(t, w.entries[0].Time.IsZero())
+	require.Equal(t, 5.0, w.entries[1].Lng)
+	require.Empty(t, w.entries[1].IDs)
+}
+
+func TestServer_ResultCache(t *testing.T) {
+	a, clean := setup(t, "A", 0)
+	defer clean()
+
+	s, err := New(a, log.NewNopLogger(), nil, Options{
+		Strategy:         insideout.DBStrategy,
+		ResultCacheLevel: 5,
+		ResultCacheCount: 100,
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	resp, err := s.Within(ctx, &insidesvc.WithinRequest{Lat: 0.5, Lng: 0.5})
+	require.NoError(t, err)
+	require.Len(t, resp.Responses, 1)
+
+	// the cache is populated asynchronously
+	cellID := s2.CellIDFromLatLng(s2.LatLngFromDegrees(0.5, 0.5)).Parent(5)
+	require.Eventually(t, func() bool {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		_, ok := s.datasets[""].results.Get(uint64(cellID))
+		return ok
+	}, time.Second, 10*time.Millisecond)
+
+	// a point outside the feature in the same level 5 cell shares the result
+	var lat, lng float64
+	found := false
+	for i := 0; i < 400 && !found; i++ {
+		lat, lng = -2+float64(i/20)*0.25, -2+float64(i%20)*0.25
+		found = (lat < 0 || lat > 1 || lng < 0 || lng > 1) &&
+			s2.CellIDFromLatLng(s2.LatLngFromDegrees(lat, lng)).Parent(5) == cellID
+	}
+	require.True(t, found)
+
+	resp, err = s.Within(ctx, &insidesvc.WithinRequest{Lat: lat, Lng: lng})
+	require.NoError(t, err)
+	require.Len(t, resp.Responses, 1)
+
+	// the cache is per dataset and emptied on reload
+	_, err = s.Reload(a)
+	require.NoError(t, err)
+	resp, err = s.Within(ctx, &insidesvc.WithinRequest{Lat: lat, Lng: lng})
+	require.NoError(t, err)
+	require.Len(t, resp.Responses, 0)
+}
+
+// mapCache is a SharedCache counting its hits
+type mapCache struct {
+	mu   sync.Mutex
+	m    map[string][]byte
+	hits int
+}
+
+func (c *mapCache) Get(key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.m[key]
+	if ok {
+		c.hits++
+	}
+	return v, ok, nil
+}
+
+func (c *mapCache) Set(key string, v []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.m[key] = v
+	return nil
+}
+
+func TestServer_SharedCache(t *testing.T) {
+	a, clean := setup(t, "A", 0)
+	defer clean()
+
+	shared := &mapCache{m: make(map[string][]byte)}
+	opts := Options{
+		Strategy:         insideout.DBStrategy,
+		CacheCount:       10,
+		ResultCacheLevel: 16,
+		ResultCacheCount: 10,
+		SharedCache:      shared,
+	}
+
+	sa, err := New(a, log.NewNopLogger(), nil, opts)
+	require.NoError(t, err)
+	sb, err := New(a, log.NewNopLogger(), nil, opts)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	resp, err := sa.Within(ctx, &insidesvc.WithinRequest{Lat: 0.5, Lng: 0.5})
+	require.NoError(t, err)
+	require.Len(t, resp.Responses, 1)
+	require.Len(t, shared.m, 2)
+	require.Equal(t, 0, shared.hits)
+
+	// the second server gets the result and the feature from the shared cache
+	resp, err = sb.Within(ctx, &insidesvc.WithinRequest{Lat: 0.5, Lng: 0.5})
+	require.NoError(t, err)
+	require.Len(t, resp.Responses, 1)
+	require.Equal(t, "A", resp.Responses[0].Feature.Properties["name"].GetStringValue())
+	require.Equal(t, 2, shared.hits)
+	require.NotNil(t, resp.Responses[0].Feature.Geometry)
+}
+
+// setup returns a storage with a one degree square feature called name at lng lat offset
+func setup(t *testing.T, name string, offset float64) (insideout.Store, func()) {
+	path := indexSquare(t, name, offset)
+
+	storage, sclose, err := bbolt.NewROStorage(path, log.NewNopLogger())
+	require.NoError(t, err)
+
+	return storage, func() {
+		sclose()
+		os.Remove(path)
+	}
+}
+
+// indexSquare returns the path of a DB holding a square of 1 degree at offset named name
+func indexSquare(t *testing.T, name string, offset float64) string {
+	logger := log.NewNopLogger()
+
+	tmpFile, err := ioutil.TempFile(os.TempDir(), "insideout-test-")
+	require.NoError(t, err)
+	tmpFile.Close()
+
+	wstorage, wclose, err := bbolt.NewStorage(tmpFile.Name(), logger)
+	require.NoError(t, err)
+
+	o := offset
+	fc := geojson.FeatureCollection{Features: []*geojson.Feature{{
+		Geometry: geom.NewPolygonFlat(geom.XY, []float64{o, o, o + 1, o, o + 1, o + 1, o, o + 1, o, o}, []int{10}),
+		Properties: map[string]interface{}{
+			"name": name,
+		},
+	}}}
+
+	icoverer := &s2.RegionCoverer{MinLevel: 5, MaxLevel: 12, MaxCells: 24}
+	ocoverer := &s2.RegionCoverer{MinLevel: 5, MaxLevel: 12, MaxCells: 16}
+	err = wstorage.Index(fc, icoverer, ocoverer, 100, name, "unittest")
+	require.NoError(t, err)
+	require.NoError(t, wclose())
+
+	return tmpFile.Name()
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"errors"
+
+	"github.com/dgraph-io/ristretto"
+)
+
+// stopOnInsideFoundSetter is implemented by the indexes supporting StopOnFirstFound
+type stopOnInsideFoundSetter interface {
+	SetStopOnInsideFound(stop bool)
+}
+
+// Settings the options of a Server that can be changed at runtime
+type Settings struct {
+	StopOnFirstFound bool
+	CacheCount       int
+	ResultCacheCount int
+}
+
+// Settings returns the current runtime settings
+func (s *Server) Settings() Settings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return Settings{
+		StopOnFirstFound: s.opts.StopOnFirstFound,
+		CacheCount:       s.opts.CacheCount,
+		ResultCacheCount: s.opts.ResultCacheCount,
+	}
+}
+
+// SetStopOnFirstFound changes StopOnFirstFound for all the datasets,
+// the shapeindex strategy does not support it and ignores it
+func (s *Server) SetStopOnFirstFound(stop bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.opts.StopOnFirstFound = stop
+	for _, dss := range []map[string]*dataset{s.datasets, s.candidates} {
+		for _, ds := range dss {
+			for _, idx := range ds.indexes() {
+				if idx, ok := idx.(stopOnInsideFoundSetter); ok {
+					idx.SetStopOnInsideFound(stop)
+				}
+			}
+		}
+	}
+}
+
+// ResizeCache replaces the features caches of all the datasets with caches of count features,
+// 0 to disable them, the cached features are dropped
+func (s *Server) ResizeCache(count int) error {
+	if count < 0 {
+		return errors.New("invalid negative cache count")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	opts := s.opts
+	opts.CacheCount = count
+	if err := s.swapCaches(opts, newCache, func(ds *dataset) **ristretto.Cache { return &ds.cache }); err != nil {
+		return err
+	}
+	s.opts.CacheCount = count
+	return nil
+}
+
+// ResizeResultCache replaces the within results caches of all the datasets with caches of count cells,
+// 0 to disable them, the cached results are dropped, it requires a result cache level
+func (s *Server) ResizeResultCache(count int) error {
+	if count < 0 {
+		return errors.New("invalid negative result cache count")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.opts.ResultCacheLevel <= 0 {
+		return errors.New("the results cache is disabled, it requires a result cache level")
+	}
+	opts := s.opts
+	opts.ResultCacheCount = count
+	if err := s.swapCaches(opts, newResultsCache, func(ds *dataset) **ristretto.Cache { return &ds.results }); err != nil {
+		return err
+	}
+	s.opts.ResultCacheCount = count
+	return nil
+}
+
+// swapCaches replaces the cache of every dataset by a cache created from opts, s.mu must be held
+func (s *Server) swapCaches(opts Options,
+	create func(Options) (*ristretto.Cache, error), cache func(*dataset) **ristretto.Cache) error {
+	caches := make(map[string]*ristretto.Cache, len(s.datasets))
+	for name := range s.datasets {
+		c, err := create(opts)
+		if err != nil {
+			for _, c := range caches {
+				if c != nil {
+					c.Close()
+				}
+			}
+			return err
+		}
+		caches[name] = c
+	}
+
+	for name, ds := range s.datasets {
+		old := cache(ds)
+		if *old != nil {
+			(*old).Close()
+		}
+		*old = caches[name]
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/insidesvc"
+)
+
+func TestServer_Settings(t *testing.T) {
+	a, clean := setup(t, "A", 0)
+	defer clean()
+
+	s, err := New(a, log.NewNopLogger(), nil, Options{
+		Strategy:         insideout.DBStrategy,
+		CacheCount:       10,
+		ResultCacheLevel: 10,
+		ResultCacheCount: 10,
+		DatasetName:      "settings",
+	})
+	require.NoError(t, err)
+	require.Equal(t, Settings{CacheCount: 10, ResultCacheCount: 10}, s.Settings())
+
+	s.SetStopOnFirstFound(true)
+	require.True(t, s.Settings().StopOnFirstFound)
+
+	require.Error(t, s.ResizeCache(-1))
+	require.NoError(t, s.ResizeCache(0))
+	require.Nil(t, s.datasets["settings"].cache)
+	require.NoError(t, s.ResizeCache(100))
+	require.NotNil(t, s.datasets["settings"].cache)
+
+	require.NoError(t, s.ResizeResultCache(0))
+	require.Nil(t, s.datasets["settings"].results)
+
+	require.Equal(t, Settings{StopOnFirstFound: true, CacheCount: 100}, s.Settings())
+
+	resp, err := s.Within(context.Background(), &insidesvc.WithinRequest{Lat: 0.5, Lng: 0.5})
+	require.NoError(t, err)
+	require.Len(t, resp.Responses, 1)
+
+	s, err = New(a, log.NewNopLogger(), nil, Options{Strategy: insideout.DBStrategy, DatasetName: "noresults"})
+	require.NoError(t, err)
+	require.Error(t, s.ResizeResultCache(10))
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+
+	"github.com/akhenakh/insideout"
+)
+
+// the results of the shadow queries counted by shadowCounter
+const (
+	shadowMatch    = "match"
+	shadowMismatch = "mismatch"
+	shadowError    = "error"
+	shadowDropped  = "dropped"
+)
+
+// maxShadowQueries the max shadow queries running at once, the others are dropped so a slow shadow strategy
+// does not pile up goroutines
+const maxShadowQueries = 64
+
+// shadowed returns true when a within query of the server strategy must be run again with the shadow strategy
+func (s *Server) shadowed() bool {
+	return s.shadowSem != nil && rand.Float64() < s.opts.ShadowRatio
+}
+
+// shadow runs again the within query of lat lng in ds with the shadow strategy in the background, then compares
+// its result with fids, answered by the server strategy in elapsed, the query is dropped when too many are running
+func (s *Server) shadow(ds *dataset, lat, lng float64, exact bool, fids []insideout.FeatureIndexResponse, elapsed time.Duration) {
+	strategy := s.opts.ShadowStrategy
+	select {
+	case s.shadowSem <- struct{}{}:
+	default:
+		shadowCounter.WithLabelValues(ds.name, strategy, shadowDropped).Inc()
+		return
+	}
+
+	go func() {
+		defer func() { <-s.shadowSem }()
+
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		// the storage of a reloaded dataset may be closed
+		if s.datasets[ds.name] != ds {
+			shadowCounter.WithLabelValues(ds.name, strategy, shadowDropped).Inc()
+			return
+		}
+
+		ctx, cancel := s.queryContext(context.Background())
+		defer cancel()
+		start := time.Now()
+		sfids, _, _, err := s.stab(ctx, ds, strategy, lat, lng, exact, nil, nil)
+		if err != nil {
+			shadowCounter.WithLabelValues(ds.name, strategy, shadowError).Inc()
+			level.Warn(s.logger).Log("msg", "shadow query failed", "dataset", ds.name, "strategy", strategy,
+				"lat", lat, "lng", lng, "error", err)
+			return
+		}
+		shadowDelta.WithLabelValues(ds.name, strategy).Observe((time.Since(start) - elapsed).Seconds())
+
+		missing, extra := diffResults(fids, sfids)
+		if len(missing) == 0 && len(extra) == 0 {
+			shadowCounter.WithLabelValues(ds.name, strategy, shadowMatch).Inc()
+			return
+		}
+		shadowCounter.WithLabelValues(ds.name, strategy, shadowMismatch).Inc()
+		level.Warn(s.logger).Log("msg", "shadow result mismatch", "dataset", ds.name,
+			"strategy", s.opts.Strategy, "shadow_strategy", strategy, "lat", lat, "lng", lng,
+			"missing", formatResults(missing), "extra", formatResults(extra))
+	}()
+}
+
+// diffResults returns the loops of a missing from b and the extra ones of b
+func diffResults(a, b []insideout.FeatureIndexResponse) (missing, extra []insideout.FeatureIndexResponse) {
+	in := func(fids []insideout.FeatureIndexResponse, fid insideout.FeatureIndexResponse) bool {
+		for _, f := range fids {
+			if f == fid {
+				return true
+			}
+		}
+		return false
+	}
+	for _, fid := range a {
+		if !in(b, fid) {
+			missing = append(missing, fid)
+		}
+	}
+	for _, fid := range b {
+		if !in(a, fid) {
+			extra = append(extra, fid)
+		}
+	}
+	return missing, extra
+}
+
+// formatResults returns the loops as sorted id:pos strings for the logs
+func formatResults(fids []insideout.FeatureIndexResponse) []string {
+	sort.Slice(fids, func(i, j int) bool {
+		if fids[i].ID == fids[j].ID {
+			return fids[i].Pos < fids[j].Pos
+		}
+		return fids[i].ID < fids[j].ID
+	})
+	res := make([]string, len(fids))
+	for i, fid := range fids {
+		res[i] = fmt.Sprintf("%d:%d", fid.ID, fid.Pos)
+	}
+	return res
+}
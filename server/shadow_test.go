@@ -0,0 +1,78 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/insidesvc"
+)
+
+func TestServer_Shadow(t *testing.T) {
+	storage, clean := setupRects(t, map[string][4]float64{
+		"inner": {0, 0, 1, 1},
+		"outer": {-1, -1, 2, 2},
+	})
+	defer clean()
+	logger := log.NewNopLogger()
+	ctx := context.Background()
+
+	for _, opts := range []Options{
+		{Strategy: insideout.DBStrategy, ShadowStrategy: insideout.DBStrategy, ShadowRatio: 1},
+		{Strategy: insideout.DBStrategy, ShadowStrategy: insideout.ShapeIndexStrategy, ShadowRatio: 2},
+	} {
+		_, err := New(storage, logger, nil, opts)
+		require.Error(t, err)
+	}
+
+	s, err := New(storage, logger, nil, Options{
+		DatasetName:    "shadow",
+		Strategy:       insideout.DBStrategy,
+		ShadowStrategy: insideout.ShapeIndexStrategy,
+		ShadowRatio:    1,
+	})
+	require.NoError(t, err)
+
+	info, err := s.Info(ctx, &insidesvc.InfoRequest{})
+	require.NoError(t, err)
+	require.Equal(t, []string{insideout.DBStrategy, insideout.ShapeIndexStrategy}, info.Datasets[0].Strategies)
+
+	match := shadowCounter.WithLabelValues("shadow", insideout.ShapeIndexStrategy, shadowMatch)
+	mismatch := shadowCounter.WithLabelValues("shadow", insideout.ShapeIndexStrategy, shadowMismatch)
+	matches, mismatches := testutil.ToFloat64(match), testutil.ToFloat64(mismatch)
+	for _, p := range [][2]float64{{0.5, 0.5}, {1.5, 1.5}, {-0.5, -0.5}} {
+		resp, err := s.Within(ctx, &insidesvc.WithinRequest{Lat: p[0], Lng: p[1]})
+		require.NoError(t, err)
+		require.NotEmpty(t, resp.Responses)
+	}
+	require.Eventually(t, func() bool { return testutil.ToFloat64(match) == matches+3 }, time.Second, 10*time.Millisecond)
+
+	// the requests for the shadow strategy are not shadowed
+	_, err = s.Within(ctx, &insidesvc.WithinRequest{Lat: 0.5, Lng: 0.5, Strategy: insideout.ShapeIndexStrategy})
+	require.NoError(t, err)
+
+	// a wrong answer of the server strategy
+	s.mu.RLock()
+	ds := s.datasets["shadow"]
+	s.mu.RUnlock()
+	s.shadow(ds, 0.5, 0.5, false, []insideout.FeatureIndexResponse{{ID: 99}}, 0)
+	require.Eventually(t, func() bool { return testutil.ToFloat64(mismatch) == mismatches+1 }, time.Second, 10*time.Millisecond)
+	require.Equal(t, matches+3, testutil.ToFloat64(match))
+}
+
+func TestDiffResults(t *testing.T) {
+	a := []insideout.FeatureIndexResponse{{ID: 1}, {ID: 2, Pos: 1}, {ID: 3}}
+	b := []insideout.FeatureIndexResponse{{ID: 3}, {ID: 2, Pos: 0}, {ID: 1}}
+	missing, extra := diffResults(a, b)
+	require.Equal(t, []string{"2:1"}, formatResults(missing))
+	require.Equal(t, []string{"2:0"}, formatResults(extra))
+
+	missing, extra = diffResults(a, a)
+	require.Empty(t, missing)
+	require.Empty(t, extra)
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"github.com/golang/geo/s2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/akhenakh/insideout"
+)
+
+// shardStore a storage whose indexes only load the features crossing the cells of a shard,
+// the features are still read by id from the whole storage
+type shardStore struct {
+	insideout.Store
+	// ids the features crossing the shard cells
+	ids map[uint32]struct{}
+}
+
+// newShardStore returns storage restricted to the features crossing cells, storage itself when cells is empty
+func newShardStore(storage insideout.Store, cells s2.CellUnion) (insideout.Store, error) {
+	if len(cells) == 0 {
+		return storage, nil
+	}
+	ids := make(map[uint32]struct{})
+	err := storage.LoadFeaturesCells(func(cellsIn []s2.CellUnion, cellsOut []s2.CellUnion, id uint32) {
+		if crosses(cells, cellsIn) || crosses(cells, cellsOut) {
+			ids[id] = struct{}{}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &shardStore{Store: storage, ids: ids}, nil
+}
+
+// crosses returns true if one of the cells of cus intersects cells
+func crosses(cells s2.CellUnion, cus []s2.CellUnion) bool {
+	for _, cu := range cus {
+		for _, c := range cu {
+			if cells.IntersectsCellID(c) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *shardStore) LoadAllFeatures(add func(*insideout.FeatureStorage, uint32) error) error {
+	return s.Store.LoadAllFeatures(func(fs *insideout.FeatureStorage, id uint32) error {
+		if _, ok := s.ids[id]; !ok {
+			return nil
+		}
+		return add(fs, id)
+	})
+}
+
+func (s *shardStore) LoadFeaturesCells(add func([]s2.CellUnion, []s2.CellUnion, uint32)) error {
+	return s.Store.LoadFeaturesCells(func(cellsIn []s2.CellUnion, cellsOut []s2.CellUnion, id uint32) {
+		if _, ok := s.ids[id]; ok {
+			add(cellsIn, cellsOut, id)
+		}
+	})
+}
+
+// inShard returns an OutOfRange error for a point outside of the shard cells
+func (s *Server) inShard(lat, lng float64) error {
+	if len(s.opts.ShardCells) == 0 {
+		return nil
+	}
+	if !s.opts.ShardCells.ContainsCellID(s2.CellIDFromLatLng(s2.LatLngFromDegrees(lat, lng))) {
+		return status.Errorf(codes.OutOfRange, "point %f %f outside of the shard cells", lat, lng)
+	}
+	return nil
+}
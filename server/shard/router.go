@@ -0,0 +1,319 @@
+package shard
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/golang/geo/s2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/insidesvc"
+	"github.com/akhenakh/insideout/server"
+)
+
+var routedCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "insiderouter",
+	Name:      "routed_total",
+	Help:      "The total number of requests forwarded to each shard",
+}, []string{"shard", "method"})
+
+// Router serves the Inside service by forwarding the requests to the shards of a map
+type Router struct {
+	m       *Map
+	clients map[string]insidesvc.InsideClient
+	logger  log.Logger
+}
+
+// NewRouter returns a Router forwarding the requests to the shards of m with their clients, by shard name
+func NewRouter(m *Map, clients map[string]insidesvc.InsideClient, logger log.Logger) (*Router, error) {
+	for _, sh := range m.Shards {
+		if _, ok := clients[sh.Name]; !ok {
+			return nil, fmt.Errorf("no client for shard %s", sh.Name)
+		}
+	}
+	return &Router{
+		m:       m,
+		clients: clients,
+		logger:  log.With(logger, "component", "router"),
+	}, nil
+}
+
+// Dial returns a Router connected to the addresses of the shards of m, the returned func closes the connections
+func Dial(m *Map, logger log.Logger, opts ...grpc.DialOption) (*Router, func() error, error) {
+	var conns []*grpc.ClientConn
+	closeAll := func() error {
+		var err error
+		for _, conn := range conns {
+			if cerr := conn.Close(); cerr != nil {
+				err = cerr
+			}
+		}
+		return err
+	}
+
+	clients := make(map[string]insidesvc.InsideClient)
+	for _, sh := range m.Shards {
+		if sh.Addr == "" {
+			closeAll()
+			return nil, nil, fmt.Errorf("shard %s has no address", sh.Name)
+		}
+		conn, err := grpc.Dial(sh.Addr, opts...)
+		if err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("can't dial shard %s: %w", sh.Name, err)
+		}
+		conns = append(conns, conn)
+		clients[sh.Name] = insidesvc.NewInsideClient(conn)
+	}
+
+	r, err := NewRouter(m, clients, logger)
+	if err != nil {
+		closeAll()
+		return nil, nil, err
+	}
+	return r, closeAll, nil
+}
+
+// client returns the client of sh and counts the request
+func (r *Router) client(sh *Shard, method string) insidesvc.InsideClient {
+	routedCounter.WithLabelValues(sh.Name, method).Inc()
+	return r.clients[sh.Name]
+}
+
+// Within forwards the request to the shard owning its point
+func (r *Router) Within(ctx context.Context, req *insidesvc.WithinRequest) (*insidesvc.WithinResponse, error) {
+	return r.client(r.m.Lookup(req.Lat, req.Lng), "Within").Within(ctx, req)
+}
+
+// WithinStream forwards each request of the stream to the shard owning its point,
+// the error of a request is sent in its response like the shards do
+func (r *Router) WithinStream(stream insidesvc.Inside_WithinStreamServer) error {
+	ctx := stream.Context()
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		resp, err := r.Within(ctx, req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			resp = &insidesvc.WithinResponse{
+				Point: &insidesvc.Point{Lat: req.Lat, Lng: req.Lng},
+				Error: insidesvc.StatusError(err),
+			}
+		}
+
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+}
+
+// Nearest forwards the request to the shard owning its point, the shards read the features
+// around it from the whole DB
+func (r *Router) Nearest(ctx context.Context, req *insidesvc.NearestRequest) (*insidesvc.NearestResponse, error) {
+	return r.client(r.m.Lookup(req.Lat, req.Lng), "Nearest").Nearest(ctx, req)
+}
+
+// Intersect fans out the request to the shards crossed by its geometry, the responses are merged
+// by feature id then polygon index, the features straddling several shards are returned once
+func (r *Router) Intersect(ctx context.Context, req *insidesvc.IntersectRequest) (*insidesvc.IntersectResponse, error) {
+	if req.Geometry == nil {
+		return nil, status.Error(codes.InvalidArgument, "missing geometry")
+	}
+	if req.Limit < 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid limit")
+	}
+
+	var region s2.Region
+	c := req.Geometry.Coordinates
+	switch req.Geometry.Type {
+	case insidesvc.Geometry_POINT:
+		if len(c) != 2 {
+			return nil, status.Error(codes.InvalidArgument, "invalid point")
+		}
+		region = s2.PointFromLatLng(s2.LatLngFromDegrees(c[1], c[0]))
+	case insidesvc.Geometry_LINESTRING:
+		pl := insideout.PolylineFromCoordinates(c)
+		if pl == nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid linestring")
+		}
+		region = pl
+	case insidesvc.Geometry_POLYGON:
+		l := insideout.LoopFromCoordinates(c)
+		if l == nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid polygon")
+		}
+		l.Normalize()
+		region = l
+	default:
+		return nil, status.Error(codes.InvalidArgument, "unsupported geometry type")
+	}
+
+	// the same covering as the shards
+	coverer := &s2.RegionCoverer{MaxLevel: 20, MaxCells: 32}
+	shards := r.m.Crossing(coverer.Covering(region))
+
+	resps := make([]*insidesvc.IntersectResponse, len(shards))
+	g, gctx := errgroup.WithContext(ctx)
+	for i, sh := range shards {
+		i, sh := i, sh
+		g.Go(func() error {
+			resp, err := r.client(sh, "Intersect").Intersect(gctx, req)
+			if err != nil {
+				return err
+			}
+			resps[i] = resp
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	return mergeIntersect(resps, int(req.Limit))
+}
+
+// mergeIntersect merges the pages of the shards sorted by polygon, the polygons after the last one of a shard
+// with more polygons may be missing from the page and are cut, then the merged page is cut at limit, 0 for no limit
+func mergeIntersect(resps []*insidesvc.IntersectResponse, limit int) (*insidesvc.IntersectResponse, error) {
+	type polygon struct {
+		fid  insideout.FeatureIndexResponse
+		resp *insidesvc.FeatureResponse
+	}
+
+	var cut *insideout.FeatureIndexResponse
+	seen := make(map[insideout.FeatureIndexResponse]bool)
+	var polygons []polygon
+	for _, resp := range resps {
+		var last insideout.FeatureIndexResponse
+		for _, fresp := range resp.Responses {
+			fid, err := polygonOf(fresp)
+			if err != nil {
+				return nil, err
+			}
+			last = fid
+			if seen[fid] {
+				continue
+			}
+			seen[fid] = true
+			polygons = append(polygons, polygon{fid: fid, resp: fresp})
+		}
+		if resp.NextCursor != "" && len(resp.Responses) > 0 && (cut == nil || less(last, *cut)) {
+			l := last
+			cut = &l
+		}
+	}
+	sort.Slice(polygons, func(i, j int) bool { return less(polygons[i].fid, polygons[j].fid) })
+
+	merged := &insidesvc.IntersectResponse{}
+	for i, p := range polygons {
+		if (cut != nil && less(*cut, p.fid)) || (limit > 0 && i == limit) {
+			merged.NextCursor = server.EncodeCursor(polygons[i-1].fid)
+			break
+		}
+		merged.Responses = append(merged.Responses, p.resp)
+	}
+	if merged.NextCursor == "" && cut != nil && len(merged.Responses) > 0 {
+		merged.NextCursor = server.EncodeCursor(polygons[len(merged.Responses)-1].fid)
+	}
+	return merged, nil
+}
+
+// polygonOf returns the polygon of a response from its feature id and loop index properties
+func polygonOf(fresp *insidesvc.FeatureResponse) (insideout.FeatureIndexResponse, error) {
+	fid := insideout.FeatureIndexResponse{ID: fresp.Id}
+	if fresp.Feature == nil {
+		return fid, status.Error(codes.Internal, "shard response without feature")
+	}
+	v, ok := fresp.Feature.Properties[insidesvc.LoopIndexProperty]
+	if !ok {
+		return fid, status.Error(codes.Internal, "shard response without loop index")
+	}
+	fid.Pos = uint16(v.GetNumberValue())
+	return fid, nil
+}
+
+// less returns true if the polygon a is before b, by feature id then polygon index
+func less(a, b insideout.FeatureIndexResponse) bool {
+	if a.ID == b.ID {
+		return a.Pos < b.Pos
+	}
+	return a.ID < b.ID
+}
+
+// first calls call with the clients of the shards in order until one does not return a NotFound error,
+// the features are read from the same DB by all the shards but the ones held in memory only by their shards
+func (r *Router) first(method string, call func(c insidesvc.InsideClient) error) error {
+	var err error
+	for _, sh := range r.m.Shards {
+		err = call(r.client(sh, method))
+		if status.Code(err) != codes.NotFound {
+			return err
+		}
+	}
+	return err
+}
+
+// Get forwards the request to the first shard finding the feature
+func (r *Router) Get(ctx context.Context, req *insidesvc.GetRequest) (resp *insidesvc.Feature, err error) {
+	err = r.first("Get", func(c insidesvc.InsideClient) error {
+		resp, err = c.Get(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+// GetFeature forwards the request to the first shard finding the feature
+func (r *Router) GetFeature(ctx context.Context, req *insidesvc.GetFeatureRequest) (resp *insidesvc.Feature, err error) {
+	err = r.first("GetFeature", func(c insidesvc.InsideClient) error {
+		resp, err = c.GetFeature(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+// ListFeatures forwards the request to the first shard, the features are listed from the whole DB
+func (r *Router) ListFeatures(ctx context.Context, req *insidesvc.ListFeaturesRequest) (*insidesvc.ListFeaturesResponse, error) {
+	return r.client(r.m.Shards[0], "ListFeatures").ListFeatures(ctx, req)
+}
+
+// Info forwards the request to the first shard
+func (r *Router) Info(ctx context.Context, req *insidesvc.InfoRequest) (*insidesvc.InfoResponse, error) {
+	return r.client(r.m.Shards[0], "Info").Info(ctx, req)
+}
+
+// Track is not routed, the tracked objects move across the shards
+func (r *Router) Track(stream insidesvc.Inside_TrackServer) error {
+	return status.Error(codes.Unimplemented, "Track is not served by the shard router")
+}
+
+// InsertFeature is not routed, the shards serve the DB read only
+func (r *Router) InsertFeature(ctx context.Context, req *insidesvc.InsertFeatureRequest) (*insidesvc.WriteFeatureResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "writes are not served by the shard router")
+}
+
+// UpdateFeature is not routed, the shards serve the DB read only
+func (r *Router) UpdateFeature(ctx context.Context, req *insidesvc.UpdateFeatureRequest) (*insidesvc.WriteFeatureResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "writes are not served by the shard router")
+}
+
+// DeleteFeature is not routed, the shards serve the DB read only
+func (r *Router) DeleteFeature(ctx context.Context, req *insidesvc.DeleteFeatureRequest) (*insidesvc.WriteFeatureResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "writes are not served by the shard router")
+}
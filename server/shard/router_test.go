@@ -0,0 +1,178 @@
+package shard
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/geojson"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/insidesvc"
+	"github.com/akhenakh/insideout/server"
+	"github.com/akhenakh/insideout/storage/bbolt"
+)
+
+// setup returns a Router of 2 shards serving the same DB: the positive quadrant of the face 0 and the rest
+func setup(t *testing.T) (*Router, func()) {
+	logger := log.NewNopLogger()
+
+	tmpFile, err := ioutil.TempFile(os.TempDir(), "insideout-test-")
+	require.NoError(t, err)
+	tmpFile.Close()
+
+	wstorage, wclose, err := bbolt.NewStorage(tmpFile.Name(), logger)
+	require.NoError(t, err)
+	var fc geojson.FeatureCollection
+	for _, r := range []struct {
+		name string
+		rect [4]float64
+	}{
+		{"inside", [4]float64{0, 0, 1, 1}},
+		{"straddles", [4]float64{-1, -1, 1, 1}},
+		{"west", [4]float64{-1, -1, -0.5, -0.5}},
+	} {
+		x0, y0, x1, y1 := r.rect[0], r.rect[1], r.rect[2], r.rect[3]
+		fc.Features = append(fc.Features, &geojson.Feature{
+			Geometry:   geom.NewPolygonFlat(geom.XY, []float64{x0, y0, x1, y0, x1, y1, x0, y1, x0, y0}, []int{10}),
+			Properties: map[string]interface{}{"name": r.name},
+		})
+	}
+	icoverer := &s2.RegionCoverer{MinLevel: 5, MaxLevel: 12, MaxCells: 24}
+	ocoverer := &s2.RegionCoverer{MinLevel: 5, MaxLevel: 12, MaxCells: 16}
+	require.NoError(t, wstorage.Index(fc, icoverer, ocoverer, 100, "rects", "unittest"))
+	require.NoError(t, wclose())
+
+	storage, sclose, err := bbolt.NewROStorage(tmpFile.Name(), logger)
+	require.NoError(t, err)
+
+	quadrant := s2.CellIDFromLatLng(s2.LatLngFromDegrees(0.5, 0.5)).Parent(1)
+	var faces s2.CellUnion
+	for f := 0; f < 6; f++ {
+		faces = append(faces, s2.CellIDFromFace(f))
+	}
+	var rest []string
+	for _, c := range s2.CellUnionFromDifference(faces, s2.CellUnion{quadrant}) {
+		rest = append(rest, c.ToToken())
+	}
+	m, err := Parse([]byte(fmt.Sprintf("shards:\n  - {name: quadrant, cells: [%s]}\n  - {name: rest, cells: [%s]}\n",
+		quadrant.ToToken(), strings.Join(rest, ", "))))
+	require.NoError(t, err)
+
+	var stops []func()
+	clients := make(map[string]insidesvc.InsideClient)
+	for _, sh := range m.Shards {
+		s, err := server.New(storage, logger, nil, server.Options{
+			Strategy:            insideout.ShapeIndexStrategy,
+			ShardCells:          sh.Cells,
+			MaxIntersectResults: 2,
+		})
+		require.NoError(t, err)
+
+		lis := bufconn.Listen(1 << 20)
+		gs := grpc.NewServer()
+		insidesvc.RegisterInsideServer(gs, s)
+		go gs.Serve(lis)
+
+		conn, err := grpc.Dial("bufnet", grpc.WithInsecure(), grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.Dial()
+		}))
+		require.NoError(t, err)
+		clients[sh.Name] = insidesvc.NewInsideClient(conn)
+		stops = append(stops, func() {
+			conn.Close()
+			gs.Stop()
+		})
+	}
+
+	r, err := NewRouter(m, clients, logger)
+	require.NoError(t, err)
+
+	return r, func() {
+		for _, stop := range stops {
+			stop()
+		}
+		sclose()
+		os.Remove(tmpFile.Name())
+	}
+}
+
+func names(resps []*insidesvc.FeatureResponse) []string {
+	var res []string
+	for _, r := range resps {
+		res = append(res, r.Feature.Properties["name"].GetStringValue())
+	}
+	return res
+}
+
+func TestRouter(t *testing.T) {
+	r, clean := setup(t)
+	defer clean()
+	ctx := context.Background()
+
+	resp, err := r.Within(ctx, &insidesvc.WithinRequest{Lat: 0.5, Lng: 0.5, RemoveGeometries: true})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"inside", "straddles"}, names(resp.Responses))
+
+	resp, err = r.Within(ctx, &insidesvc.WithinRequest{Lat: -0.7, Lng: -0.7, RemoveGeometries: true})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"straddles", "west"}, names(resp.Responses))
+
+	nresp, err := r.Nearest(ctx, &insidesvc.NearestRequest{Lat: -0.7, Lng: -0.7, RemoveGeometries: true})
+	require.NoError(t, err)
+	require.NotNil(t, nresp.Response)
+
+	// capped by the shards then paginated, the straddling features are returned once
+	geometry := &insidesvc.Geometry{
+		Type:        insidesvc.Geometry_POLYGON,
+		Coordinates: []float64{-20, -20, 20, -20, 20, 20, -20, 20, -20, -20},
+	}
+	var all []string
+	cursor := ""
+	for i := 0; i < 5; i++ {
+		iresp, err := r.Intersect(ctx, &insidesvc.IntersectRequest{Geometry: geometry, RemoveGeometries: true, Limit: 10, Cursor: cursor})
+		require.NoError(t, err)
+		all = append(all, names(iresp.Responses)...)
+		cursor = iresp.NextCursor
+		if cursor == "" {
+			break
+		}
+	}
+	require.Equal(t, []string{"inside", "straddles", "west"}, all)
+
+	all = nil
+	cursor = ""
+	for i := 0; i < 5; i++ {
+		iresp, err := r.Intersect(ctx, &insidesvc.IntersectRequest{Geometry: geometry, RemoveGeometries: true, Limit: 1, Cursor: cursor})
+		require.NoError(t, err)
+		require.Len(t, iresp.Responses, 1)
+		all = append(all, names(iresp.Responses)...)
+		cursor = iresp.NextCursor
+		if cursor == "" {
+			break
+		}
+	}
+	require.Equal(t, []string{"inside", "straddles", "west"}, all)
+
+	// a geometry in one shard
+	iresp, err := r.Intersect(ctx, &insidesvc.IntersectRequest{
+		Geometry:         &insidesvc.Geometry{Type: insidesvc.Geometry_POINT, Coordinates: []float64{0.5, 0.5}},
+		RemoveGeometries: true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"inside", "straddles"}, names(iresp.Responses))
+
+	f, err := r.GetFeature(ctx, &insidesvc.GetFeatureRequest{Id: 2})
+	require.NoError(t, err)
+	require.Equal(t, "west", f.Properties["name"].GetStringValue())
+}
@@ -0,0 +1,140 @@
+// Package shard partitions the planet into S2 cells served by distinct insided nodes, the shards,
+// and routes the queries to them. Every shard serves the same DB but only loads in memory the features
+// crossing its cells, see server.Options.ShardCells, the Router forwards a within query to the shard owning
+// its point and fans out an intersect query to all the shards its geometry crosses.
+package shard
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/golang/geo/s2"
+	"gopkg.in/yaml.v3"
+)
+
+// Shard a node serving the features crossing its cells
+type Shard struct {
+	Name string `yaml:"name"`
+
+	// Addr the gRPC host:port of the shard, used by the router
+	Addr string `yaml:"addr"`
+
+	// Tokens the tokens of the S2 cells of the shard, of any level
+	Tokens []string `yaml:"cells"`
+
+	// Cells the normalized cells of the shard
+	Cells s2.CellUnion `yaml:"-"`
+}
+
+// Map the shards partitioning the planet
+type Map struct {
+	Shards []*Shard `yaml:"shards"`
+}
+
+// Load returns the map of the YAML shards file at path, see Parse
+func Load(path string) (*Map, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(b)
+}
+
+// Parse returns the map of the YAML document b, the cells of the shards must cover the planet
+// without overlapping:
+//
+//	shards:
+//	  - name: americas
+//	    addr: shard-americas:9200
+//	    cells: ["7", "9"]
+//	  - name: rest
+//	    addr: shard-rest:9200
+//	    cells: ["1", "3", "5", "b"]
+func Parse(b []byte) (*Map, error) {
+	m := &Map{}
+	if err := yaml.Unmarshal(b, m); err != nil {
+		return nil, fmt.Errorf("can't parse shards: %w", err)
+	}
+	if len(m.Shards) == 0 {
+		return nil, fmt.Errorf("no shards")
+	}
+
+	names := make(map[string]bool)
+	var all s2.CellUnion
+	for _, sh := range m.Shards {
+		if sh == nil || sh.Name == "" {
+			return nil, fmt.Errorf("shard without name")
+		}
+		if names[sh.Name] {
+			return nil, fmt.Errorf("shard %s declared twice", sh.Name)
+		}
+		names[sh.Name] = true
+		if len(sh.Tokens) == 0 {
+			return nil, fmt.Errorf("shard %s has no cells", sh.Name)
+		}
+		sh.Cells = nil
+		for _, token := range sh.Tokens {
+			c := s2.CellIDFromToken(token)
+			if !c.IsValid() {
+				return nil, fmt.Errorf("shard %s: invalid cell token %q", sh.Name, token)
+			}
+			sh.Cells = append(sh.Cells, c)
+		}
+		sh.Cells.Normalize()
+		for _, o := range m.Shards {
+			if o == sh {
+				break
+			}
+			if o.Cells.Intersects(sh.Cells) {
+				return nil, fmt.Errorf("the cells of the shards %s and %s overlap", o.Name, sh.Name)
+			}
+		}
+		all = s2.CellUnionFromUnion(all, sh.Cells)
+	}
+
+	var faces s2.CellUnion
+	for f := 0; f < 6; f++ {
+		faces = append(faces, s2.CellIDFromFace(f))
+	}
+	if missing := s2.CellUnionFromDifference(faces, all); len(missing) > 0 {
+		return nil, fmt.Errorf("the shards do not cover the planet, cell %s is missing", missing[0].ToToken())
+	}
+
+	return m, nil
+}
+
+// Shard returns the shard name
+func (m *Map) Shard(name string) (*Shard, bool) {
+	for _, sh := range m.Shards {
+		if sh.Name == name {
+			return sh, true
+		}
+	}
+	return nil, false
+}
+
+// Lookup returns the shard owning the point lat lng
+func (m *Map) Lookup(lat, lng float64) *Shard {
+	c := s2.CellIDFromLatLng(s2.LatLngFromDegrees(lat, lng))
+	for _, sh := range m.Shards {
+		if sh.Cells.ContainsCellID(c) {
+			return sh
+		}
+	}
+	// unreachable, the shards cover the planet
+	return m.Shards[0]
+}
+
+// Crossing returns the shards whose cells intersect cu
+func (m *Map) Crossing(cu s2.CellUnion) []*Shard {
+	var res []*Shard
+	for _, sh := range m.Shards {
+		for _, c := range cu {
+			if sh.Cells.IntersectsCellID(c) {
+				res = append(res, sh)
+				break
+			}
+		}
+	}
+	return res
+}
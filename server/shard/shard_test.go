@@ -0,0 +1,54 @@
+package shard
+
+import (
+	"testing"
+
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/require"
+)
+
+const shards = `
+shards:
+  - name: americas
+    addr: shard-americas:9200
+    cells: ["7", "9"]
+  - name: rest
+    addr: shard-rest:9200
+    cells: [1, 3, 5, b]
+`
+
+func TestParse(t *testing.T) {
+	m, err := Parse([]byte(shards))
+	require.NoError(t, err)
+	require.Len(t, m.Shards, 2)
+
+	sh, ok := m.Shard("americas")
+	require.True(t, ok)
+	require.Equal(t, "shard-americas:9200", sh.Addr)
+	require.Len(t, sh.Cells, 2)
+	_, ok = m.Shard("europe")
+	require.False(t, ok)
+
+	// New York and Paris
+	require.Equal(t, "americas", m.Lookup(40.7, -74).Name)
+	require.Equal(t, "rest", m.Lookup(48.85, 2.35).Name)
+
+	cu := s2.CellUnion{s2.CellIDFromLatLng(s2.LatLngFromDegrees(48.85, 2.35)).Parent(10)}
+	require.Len(t, m.Crossing(cu), 1)
+	require.Len(t, m.Crossing(s2.CellUnion{s2.CellIDFromFace(3), s2.CellIDFromFace(0)}), 2)
+
+	for _, doc := range []string{
+		"",
+		"shards: [",
+		// overlapping
+		"shards:\n  - {name: a, cells: [1, 3, 5, 7, 9, b]}\n  - {name: b, cells: [1c]}\n",
+		// missing face 5
+		"shards:\n  - {name: a, cells: [1, 3, 5, 7, 9]}\n",
+		"shards:\n  - {name: a, cells: [1, 3, 5, 7, 9, zz]}\n",
+		"shards:\n  - {name: a, cells: [1, 3, 5]}\n  - {name: a, cells: [7, 9, b]}\n",
+		"shards:\n  - {name: a}\n",
+	} {
+		_, err := Parse([]byte(doc))
+		require.Error(t, err, doc)
+	}
+}
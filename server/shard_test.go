@@ -0,0 +1,107 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/geojson"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/insidesvc"
+	"github.com/akhenakh/insideout/storage/bbolt"
+)
+
+// setupRects returns a storage with a rectangle feature minLng minLat maxLng maxLat for each name
+func setupRects(t *testing.T, rects map[string][4]float64) (insideout.Store, func()) {
+	logger := log.NewNopLogger()
+
+	tmpFile, err := ioutil.TempFile(os.TempDir(), "insideout-test-")
+	require.NoError(t, err)
+	tmpFile.Close()
+
+	wstorage, wclose, err := bbolt.NewStorage(tmpFile.Name(), logger)
+	require.NoError(t, err)
+
+	var fc geojson.FeatureCollection
+	for name, r := range rects {
+		fc.Features = append(fc.Features, &geojson.Feature{
+			Geometry: geom.NewPolygonFlat(geom.XY,
+				[]float64{r[0], r[1], r[2], r[1], r[2], r[3], r[0], r[3], r[0], r[1]}, []int{10}),
+			Properties: map[string]interface{}{"name": name},
+		})
+	}
+
+	icoverer := &s2.RegionCoverer{MinLevel: 5, MaxLevel: 12, MaxCells: 24}
+	ocoverer := &s2.RegionCoverer{MinLevel: 5, MaxLevel: 12, MaxCells: 16}
+	require.NoError(t, wstorage.Index(fc, icoverer, ocoverer, 100, "rects", "unittest"))
+	require.NoError(t, wclose())
+
+	storage, sclose, err := bbolt.NewROStorage(tmpFile.Name(), logger)
+	require.NoError(t, err)
+
+	return storage, func() {
+		sclose()
+		os.Remove(tmpFile.Name())
+	}
+}
+
+func TestServer_Shard(t *testing.T) {
+	storage, clean := setupRects(t, map[string][4]float64{
+		"inside":    {0, 0, 1, 1},
+		"straddles": {-1, -1, 1, 1},
+		"outside":   {10, -11, 11, -10},
+	})
+	defer clean()
+
+	// the level 1 cell of the positive quadrant of face 0
+	cells := s2.CellUnion{s2.CellIDFromLatLng(s2.LatLngFromDegrees(0.5, 0.5)).Parent(1)}
+
+	sstorage, err := newShardStore(storage, cells)
+	require.NoError(t, err)
+	var loaded []string
+	require.NoError(t, sstorage.LoadAllFeatures(func(fs *insideout.FeatureStorage, id uint32) error {
+		loaded = append(loaded, fs.Properties["name"].(string))
+		return nil
+	}))
+	require.ElementsMatch(t, []string{"inside", "straddles"}, loaded)
+
+	names := func(resps []*insidesvc.FeatureResponse) []string {
+		var res []string
+		for _, r := range resps {
+			res = append(res, r.Feature.Properties["name"].GetStringValue())
+		}
+		return res
+	}
+
+	for _, strategy := range []string{insideout.ShapeIndexStrategy, insideout.DBStrategy} {
+		s, err := New(storage, log.NewNopLogger(), nil, Options{Strategy: strategy, ShardCells: cells})
+		require.NoError(t, err)
+
+		ctx := context.Background()
+		resp, err := s.Within(ctx, &insidesvc.WithinRequest{Lat: 0.5, Lng: 0.5, RemoveGeometries: true})
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"inside", "straddles"}, names(resp.Responses), strategy)
+
+		_, err = s.Within(ctx, &insidesvc.WithinRequest{Lat: -0.5, Lng: -0.5})
+		require.Equal(t, codes.OutOfRange, status.Code(err), strategy)
+
+		// only the part of the geometry in the shard is covered
+		iresp, err := s.Intersect(ctx, &insidesvc.IntersectRequest{
+			Geometry: &insidesvc.Geometry{
+				Type:        insidesvc.Geometry_POLYGON,
+				Coordinates: []float64{-20, -20, 20, -20, 20, 20, -20, 20, -20, -20},
+			},
+			RemoveGeometries: true,
+		})
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"inside", "straddles"}, names(iresp.Responses), strategy)
+	}
+}
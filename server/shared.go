@@ -0,0 +1,191 @@
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/fxamacker/cbor"
+	"github.com/go-kit/kit/log/level"
+	"github.com/golang/geo/s2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/akhenakh/insideout"
+)
+
+var (
+	sharedHitCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "insided_server",
+		Name:      "shared_cache_hit",
+		Help:      "Shared cache hits",
+	}, []string{"kind"})
+
+	sharedMissCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "insided_server",
+		Name:      "shared_cache_miss",
+		Help:      "Shared cache misses",
+	}, []string{"kind"})
+
+	sharedErrorCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "insided_server",
+		Name:      "shared_cache_error_total",
+		Help:      "Shared cache errors, the storage is used instead",
+	})
+)
+
+// SharedCache is a cache shared by several servers, such as Redis,
+// queried after the local caches and before the storage
+type SharedCache interface {
+	Get(key string) ([]byte, bool, error)
+	Set(key string, v []byte) error
+}
+
+// sharedKey namespaces key by dataset and index version, a reloaded DB does not read stale entries
+func (ds *dataset) sharedKey(kind string, id uint64) string {
+	return fmt.Sprintf("%s:%s:%s:%d", ds.name, ds.version, kind, id)
+}
+
+// sharedFeature returns the feature id from the shared cache
+func (s *Server) sharedFeature(ds *dataset, id uint32) (*insideout.Feature, bool) {
+	b, ok := s.sharedGet(ds, ds.sharedKey("f", uint64(id)), "feature")
+	if !ok {
+		return nil, false
+	}
+	f, err := decodeFeature(b)
+	if err != nil {
+		s.sharedError(err)
+		return nil, false
+	}
+	return f, true
+}
+
+// setSharedFeature stores the feature id in the shared cache
+func (s *Server) setSharedFeature(ds *dataset, id uint32, f *insideout.Feature) {
+	b, err := encodeFeature(f)
+	if err != nil {
+		s.sharedError(err)
+		return
+	}
+	if err := s.opts.SharedCache.Set(ds.sharedKey("f", uint64(id)), b); err != nil {
+		s.sharedError(err)
+	}
+}
+
+// sharedResult returns the within result for the cell from the shared cache
+func (s *Server) sharedResult(ds *dataset, cellID s2.CellID) ([]insideout.FeatureIndexResponse, bool) {
+	b, ok := s.sharedGet(ds, ds.sharedKey("r", uint64(cellID)), "result")
+	if !ok {
+		return nil, false
+	}
+	fids, err := decodeResult(b)
+	if err != nil {
+		s.sharedError(err)
+		return nil, false
+	}
+	return fids, true
+}
+
+// setSharedResult stores the within result for the cell in the shared cache
+func (s *Server) setSharedResult(ds *dataset, cellID s2.CellID, fids []insideout.FeatureIndexResponse) {
+	if err := s.opts.SharedCache.Set(ds.sharedKey("r", uint64(cellID)), encodeResult(fids)); err != nil {
+		s.sharedError(err)
+	}
+}
+
+func (s *Server) sharedGet(ds *dataset, key, kind string) ([]byte, bool) {
+	b, ok, err := s.opts.SharedCache.Get(key)
+	if err != nil {
+		s.sharedError(err)
+		return nil, false
+	}
+	if !ok {
+		sharedMissCounter.WithLabelValues(kind).Inc()
+		observeCache(ds, "shared_"+kind, false)
+		return nil, false
+	}
+	sharedHitCounter.WithLabelValues(kind).Inc()
+	observeCache(ds, "shared_"+kind, true)
+	return b, true
+}
+
+// sharedError logs shared cache errors, they are not returned to the clients
+func (s *Server) sharedError(err error) {
+	sharedErrorCounter.Inc()
+	level.Warn(s.logger).Log("msg", "shared cache error", "error", err)
+}
+
+// encodeFeature encodes f the same way as the storage
+func encodeFeature(f *insideout.Feature) ([]byte, error) {
+	fs := insideout.FeatureStorage{
+		Properties: f.Properties,
+		LoopsBytes: make([][]byte, len(f.Loops)),
+	}
+	for i, l := range f.Loops {
+		var buf bytes.Buffer
+		if err := l.Encode(&buf); err != nil {
+			return nil, err
+		}
+		fs.LoopsBytes[i] = buf.Bytes()
+	}
+	for i, hs := range f.Holes {
+		if fs.HolesBytes == nil {
+			fs.HolesBytes = make([][][]byte, len(f.Holes))
+		}
+		for _, h := range hs {
+			var buf bytes.Buffer
+			if err := h.Encode(&buf); err != nil {
+				return nil, err
+			}
+			fs.HolesBytes[i] = append(fs.HolesBytes[i], buf.Bytes())
+		}
+	}
+	return cbor.Marshal(fs, cbor.CanonicalEncOptions())
+}
+
+func decodeFeature(b []byte) (*insideout.Feature, error) {
+	fs := &insideout.FeatureStorage{}
+	if err := cbor.Unmarshal(b, fs); err != nil {
+		return nil, err
+	}
+	loops := make([]*s2.Loop, len(fs.LoopsBytes))
+	for i := range loops {
+		l, err := insideout.DecodeLoop(fs.LoopsBytes[i])
+		if err != nil {
+			return nil, err
+		}
+		loops[i] = l
+	}
+	holes, err := insideout.DecodeHoles(fs.HolesBytes)
+	if err != nil {
+		return nil, err
+	}
+	return &insideout.Feature{
+		Loops:      loops,
+		Holes:      holes,
+		Properties: fs.Properties,
+	}, nil
+}
+
+// encodeResult encodes the loops as the cells values: uint32 feature id followed by uint16 loop index
+func encodeResult(fids []insideout.FeatureIndexResponse) []byte {
+	b := make([]byte, 6*len(fids))
+	for i, fid := range fids {
+		binary.BigEndian.PutUint32(b[i*6:], fid.ID)
+		binary.BigEndian.PutUint16(b[i*6+4:], fid.Pos)
+	}
+	return b
+}
+
+func decodeResult(b []byte) ([]insideout.FeatureIndexResponse, error) {
+	if len(b)%6 != 0 {
+		return nil, errors.New("invalid cached result")
+	}
+	fids := make([]insideout.FeatureIndexResponse, len(b)/6)
+	for i := range fids {
+		fids[i].ID = binary.BigEndian.Uint32(b[i*6:])
+		fids[i].Pos = binary.BigEndian.Uint16(b[i*6+4:])
+	}
+	return fids, nil
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/akhenakh/insideout"
+)
+
+// newIndexes creates and fills the indexes of the other strategies of opts, by strategy
+func newIndexes(storage insideout.Store, opts Options) (map[string]insideout.Index, error) {
+	var idxs map[string]insideout.Index
+	for _, strategy := range opts.Strategies {
+		if idxs == nil {
+			idxs = make(map[string]insideout.Index)
+		}
+		if _, ok := idxs[strategy]; ok || strategy == opts.Strategy {
+			continue
+		}
+		o := opts
+		o.Strategy = strategy
+		idx, err := newIndex(storage, o)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load %s index: %w", strategy, err)
+		}
+		idxs[strategy] = idx
+	}
+	return idxs, nil
+}
+
+// index returns the index of ds for strategy with its name, the index of the server strategy when empty
+func (s *Server) index(ds *dataset, strategy string) (string, insideout.Index, error) {
+	if strategy == "" || strategy == s.opts.Strategy {
+		return s.opts.Strategy, ds.idx, nil
+	}
+	idx, ok := ds.idxs[strategy]
+	if !ok {
+		return "", nil, status.Errorf(codes.InvalidArgument, "strategy %s not loaded by the server", strategy)
+	}
+	return strategy, idx, nil
+}
+
+// strategies returns the strategies whose indexes are loaded, the server strategy first
+func (s *Server) strategies() []string {
+	res := []string{s.opts.Strategy}
+	for _, strategy := range s.opts.Strategies {
+		dup := false
+		for _, r := range res {
+			if r == strategy {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			res = append(res, strategy)
+		}
+	}
+	return res
+}
+
+// indexes returns the indexes of ds, the one of the server strategy first
+func (ds *dataset) indexes() []insideout.Index {
+	res := []insideout.Index{ds.idx}
+	for _, idx := range ds.idxs {
+		res = append(res, idx)
+	}
+	return res
+}
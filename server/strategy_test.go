@@ -0,0 +1,65 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/insidesvc"
+)
+
+func TestServer_Strategies(t *testing.T) {
+	storage, clean := setupRects(t, map[string][4]float64{
+		"inner": {0, 0, 1, 1},
+		"outer": {-1, -1, 2, 2},
+	})
+	defer clean()
+	logger := log.NewNopLogger()
+
+	_, err := New(storage, logger, nil, Options{
+		Strategy:   insideout.DBStrategy,
+		Strategies: []string{insideout.ShapeIndexStrategy},
+		ReadWrite:  true,
+	})
+	require.Error(t, err)
+
+	s, err := New(storage, logger, nil, Options{
+		Strategy:         insideout.DBStrategy,
+		Strategies:       []string{insideout.ShapeIndexStrategy, insideout.DBStrategy, insideout.InsideTreeStrategy},
+		ResultCacheLevel: 10,
+		ResultCacheCount: 100,
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.Warmup(context.Background()))
+	ctx := context.Background()
+
+	info, err := s.Info(ctx, &insidesvc.InfoRequest{})
+	require.NoError(t, err)
+	require.Equal(t, []string{insideout.DBStrategy, insideout.ShapeIndexStrategy, insideout.InsideTreeStrategy},
+		info.Datasets[0].Strategies)
+
+	for _, strategy := range []string{"", insideout.DBStrategy, insideout.ShapeIndexStrategy, insideout.InsideTreeStrategy} {
+		resp, err := s.Within(ctx, &insidesvc.WithinRequest{Lat: 0.5, Lng: 0.5, Strategy: strategy, Debug: true})
+		require.NoError(t, err, strategy)
+		require.Len(t, resp.Responses, 2, strategy)
+		if strategy == "" {
+			strategy = insideout.DBStrategy
+		}
+		require.Equal(t, strategy, resp.Debug.Strategy)
+	}
+
+	// the other strategies answer from their index, not from the cached results of the server strategy
+	resp, err := s.Within(ctx, &insidesvc.WithinRequest{Lat: 0.5, Lng: 0.5, Strategy: insideout.ShapeIndexStrategy})
+	require.NoError(t, err)
+	for _, fresp := range resp.Responses {
+		require.True(t, fresp.Exact)
+	}
+
+	_, err = s.Within(ctx, &insidesvc.WithinRequest{Lat: 0.5, Lng: 0.5, Strategy: insideout.MemoryStrategy})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"context"
+
+	"github.com/twpayne/go-geom/encoding/geojson"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/insidesvc"
+	"github.com/akhenakh/insideout/server/tenant"
+)
+
+// visible returns true if the feature with properties p can be seen by the tenant of the request,
+// the requests without a tenant see all the features
+func visible(ctx context.Context, p map[string]interface{}) bool {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return true
+	}
+	owner, _ := p[insidesvc.TenantProperty].(string)
+	return owner == t.Name
+}
+
+// countTenantFeatures returns the number of features of storage owned by each tenant
+func countTenantFeatures(storage insideout.Store) (map[string]uint32, error) {
+	counts := make(map[string]uint32)
+	err := storage.LoadAllFeatures(func(fs *insideout.FeatureStorage, id uint32) error {
+		if owner, ok := fs.Properties[insidesvc.TenantProperty].(string); ok {
+			counts[owner]++
+		}
+		return nil
+	})
+	return counts, err
+}
+
+// tenantFeatureCount returns the number of features owned by name in all the datasets,
+// the caller must hold s.mu
+func (s *Server) tenantFeatureCount(name string) uint32 {
+	var count uint32
+	for _, ds := range s.datasets {
+		count += ds.tenants[name]
+	}
+	return count
+}
+
+// claimFeature makes the tenant of the request the owner of the new feature f,
+// returns ResourceExhausted when the tenant already owns its quota of features, the caller must hold s.mu
+func (s *Server) claimFeature(ctx context.Context, f *geojson.Feature) error {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	if t.Quota > 0 && s.opts.Tenants && s.tenantFeatureCount(t.Name) >= t.Quota {
+		return status.Errorf(codes.ResourceExhausted, "tenant %s reached its quota of %d features", t.Name, t.Quota)
+	}
+	setOwner(ctx, f)
+	return nil
+}
+
+// setOwner sets the tenant of the request as the owner of f, a tenant can't give a feature away
+func setOwner(ctx context.Context, f *geojson.Feature) {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return
+	}
+	if f.Properties == nil {
+		f.Properties = make(map[string]interface{})
+	}
+	f.Properties[insidesvc.TenantProperty] = t.Name
+}
+
+// recountTenants moves a feature of ds from the owner in the properties old to the one in new,
+// nil for a created or deleted feature, the caller must hold s.mu
+func recountTenants(ds *dataset, old, new map[string]interface{}) {
+	if ds.tenants == nil {
+		return
+	}
+	if owner, ok := old[insidesvc.TenantProperty].(string); ok && ds.tenants[owner] > 0 {
+		ds.tenants[owner]--
+	}
+	if owner, ok := new[insidesvc.TenantProperty].(string); ok {
+		ds.tenants[owner]++
+	}
+}
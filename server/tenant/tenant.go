@@ -0,0 +1,181 @@
+// Package tenant isolates the features of the customers sharing a server, each tenant is identified by its API keys
+// and only sees the features it owns
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v3"
+)
+
+var rejectedCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "insided_tenant",
+	Name:      "rejected_total",
+	Help:      "The total number of requests rejected for a missing or unknown API key",
+}, []string{"transport"})
+
+// Tenant a customer of the server
+type Tenant struct {
+	Name string `yaml:"-"`
+
+	// Keys the API keys of the tenant
+	Keys []string `yaml:"keys"`
+
+	// Quota the max number of features the tenant can own, 0 for no limit
+	Quota uint32 `yaml:"quota"`
+}
+
+// Registry the tenants by API key
+type Registry struct {
+	keyHeader string
+	tenants   []*Tenant
+	byKey     map[string]*Tenant
+}
+
+// Load returns the registry of the YAML tenants file at path, see Parse
+func Load(path, keyHeader string) (*Registry, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(b, keyHeader)
+}
+
+// Parse returns the registry of the tenants of the YAML document b, keyed by tenant name:
+//
+//	acme:
+//	  keys: [3f2a9c, 77b01e]
+//	  quota: 1000
+//
+// keyHeader is the header (or gRPC metadata) holding the API key of the requests
+func Parse(b []byte, keyHeader string) (*Registry, error) {
+	if keyHeader == "" {
+		return nil, fmt.Errorf("missing API key header")
+	}
+
+	var doc map[string]*Tenant
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("can't parse tenants: %w", err)
+	}
+
+	r := &Registry{
+		keyHeader: keyHeader,
+		byKey:     make(map[string]*Tenant),
+	}
+	for name, t := range doc {
+		if name == "" || t == nil || len(t.Keys) == 0 {
+			return nil, fmt.Errorf("tenant %q has no API keys", name)
+		}
+		t.Name = name
+		for _, k := range t.Keys {
+			if k == "" {
+				return nil, fmt.Errorf("tenant %s has an empty API key", name)
+			}
+			if o, ok := r.byKey[k]; ok {
+				return nil, fmt.Errorf("API key shared by the tenants %s and %s", o.Name, name)
+			}
+			r.byKey[k] = t
+		}
+		r.tenants = append(r.tenants, t)
+	}
+	if len(r.tenants) == 0 {
+		return nil, fmt.Errorf("no tenants")
+	}
+	sort.Slice(r.tenants, func(i, j int) bool { return r.tenants[i].Name < r.tenants[j].Name })
+
+	return r, nil
+}
+
+// Tenants returns the tenants sorted by name
+func (r *Registry) Tenants() []*Tenant {
+	return r.tenants
+}
+
+// Lookup returns the tenant of the API key
+func (r *Registry) Lookup(key string) (*Tenant, bool) {
+	t, ok := r.byKey[key]
+	return t, ok
+}
+
+type contextKey struct{}
+
+// NewContext returns a context carrying t
+func NewContext(ctx context.Context, t *Tenant) context.Context {
+	return context.WithValue(ctx, contextKey{}, t)
+}
+
+// FromContext returns the tenant of the request, false for the requests not made by a tenant
+// such as the ones of the bridges
+func FromContext(ctx context.Context) (*Tenant, bool) {
+	t, ok := ctx.Value(contextKey{}).(*Tenant)
+	return t, ok
+}
+
+// Handler is an HTTP middleware replying 401 Unauthorized to the requests without a known API key,
+// the tenant is added to the context of the others
+func (r *Registry) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		t, ok := r.Lookup(req.Header.Get(r.keyHeader))
+		if !ok {
+			rejectedCounter.WithLabelValues("http").Inc()
+			http.Error(w, "{\"msg\": \"missing or unknown API key\"}", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, req.WithContext(NewContext(req.Context(), t)))
+	})
+}
+
+// UnaryServerInterceptor returns Unauthenticated to the requests without a known API key,
+// the tenant is added to the context of the others
+func (r *Registry) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+		t, ok := r.grpcTenant(ctx)
+		if !ok {
+			rejectedCounter.WithLabelValues("grpc").Inc()
+			return nil, status.Error(codes.Unauthenticated, "missing or unknown API key")
+		}
+		return handler(NewContext(ctx, t), req)
+	}
+}
+
+// StreamServerInterceptor returns Unauthenticated to the streams without a known API key,
+// the tenant is added to the context of the others
+func (r *Registry) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler) error {
+		t, ok := r.grpcTenant(ss.Context())
+		if !ok {
+			rejectedCounter.WithLabelValues("grpc").Inc()
+			return status.Error(codes.Unauthenticated, "missing or unknown API key")
+		}
+		ws := grpc_middleware.WrapServerStream(ss)
+		ws.WrappedContext = NewContext(ss.Context(), t)
+		return handler(srv, ws)
+	}
+}
+
+// grpcTenant returns the tenant of the API key in the metadata
+func (r *Registry) grpcTenant(ctx context.Context) (*Tenant, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, false
+	}
+	v := md.Get(strings.ToLower(r.keyHeader))
+	if len(v) == 0 {
+		return nil, false
+	}
+	return r.Lookup(v[0])
+}
@@ -0,0 +1,106 @@
+package tenant
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const tenants = `
+acme:
+  keys: [k1, k2]
+  quota: 10
+globex:
+  keys: [k3]
+`
+
+func TestParse(t *testing.T) {
+	r, err := Parse([]byte(tenants), "X-API-Key")
+	require.NoError(t, err)
+	require.Len(t, r.Tenants(), 2)
+	require.Equal(t, "acme", r.Tenants()[0].Name)
+
+	tn, ok := r.Lookup("k2")
+	require.True(t, ok)
+	require.Equal(t, "acme", tn.Name)
+	require.Equal(t, uint32(10), tn.Quota)
+
+	tn, ok = r.Lookup("k3")
+	require.True(t, ok)
+	require.Equal(t, "globex", tn.Name)
+	require.Zero(t, tn.Quota)
+
+	_, ok = r.Lookup("k4")
+	require.False(t, ok)
+
+	for _, doc := range []string{
+		"",
+		"acme:\n  quota: 10\n",
+		"acme:\n  keys: [k1]\nglobex:\n  keys: [k1]\n",
+		"acme: [",
+	} {
+		_, err := Parse([]byte(doc), "X-API-Key")
+		require.Error(t, err, doc)
+	}
+
+	_, err = Parse([]byte(tenants), "")
+	require.Error(t, err)
+}
+
+func TestRegistry_Handler(t *testing.T) {
+	r, err := Parse([]byte(tenants), "X-API-Key")
+	require.NoError(t, err)
+
+	var name string
+	h := r.Handler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		tn, ok := FromContext(req.Context())
+		require.True(t, ok)
+		name = tn.Name
+	}))
+
+	do := func(key string) int {
+		req := httptest.NewRequest("GET", "/api/within/48.8/2.2", nil)
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	require.Equal(t, http.StatusOK, do("k3"))
+	require.Equal(t, "globex", name)
+	require.Equal(t, http.StatusUnauthorized, do(""))
+	require.Equal(t, http.StatusUnauthorized, do("k4"))
+}
+
+func TestRegistry_UnaryServerInterceptor(t *testing.T) {
+	r, err := Parse([]byte(tenants), "X-API-Key")
+	require.NoError(t, err)
+
+	i := r.UnaryServerInterceptor()
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		tn, ok := FromContext(ctx)
+		require.True(t, ok)
+		return tn.Name, nil
+	}
+
+	_, err = i(context.Background(), nil, &grpc.UnaryServerInfo{}, handler)
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-api-key", "k4"))
+	_, err = i(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-api-key", "k1"))
+	resp, err := i(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+	require.NoError(t, err)
+	require.Equal(t, "acme", resp)
+}
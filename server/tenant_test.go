@@ -0,0 +1,139 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/insidesvc"
+	"github.com/akhenakh/insideout/server/tenant"
+)
+
+func TestServer_Tenants(t *testing.T) {
+	storage, clean := setupRW(t, "A", 0)
+	defer clean()
+
+	s, err := New(storage, log.NewNopLogger(), nil, Options{
+		Strategy:   insideout.MemoryStrategy,
+		CacheCount: 10,
+		ReadWrite:  true,
+		Tenants:    true,
+	})
+	require.NoError(t, err)
+
+	acme := tenant.NewContext(context.Background(), &tenant.Tenant{Name: "acme", Quota: 1})
+	globex := tenant.NewContext(context.Background(), &tenant.Tenant{Name: "globex"})
+	admin := context.Background()
+
+	within := func(ctx context.Context, lat, lng float64) []string {
+		resp, err := s.Within(ctx, &insidesvc.WithinRequest{Lat: lat, Lng: lng})
+		require.NoError(t, err)
+		var names []string
+		for _, fresp := range resp.Responses {
+			names = append(names, fresp.Feature.Properties["name"].GetStringValue())
+		}
+		return names
+	}
+
+	// the features without owner are only seen without tenant
+	require.Equal(t, []string{"A"}, within(admin, 0.5, 0.5))
+	require.Empty(t, within(acme, 0.5, 0.5))
+
+	wresp, err := s.InsertFeature(acme, &insidesvc.InsertFeatureRequest{Feature: squareFeature("B", 10)})
+	require.NoError(t, err)
+	require.Equal(t, []string{"B"}, within(acme, 10.5, 10.5))
+	require.Equal(t, []string{"B"}, within(admin, 10.5, 10.5))
+	require.Empty(t, within(globex, 10.5, 10.5))
+
+	_, err = s.Get(globex, &insidesvc.GetRequest{Id: wresp.Id})
+	require.Equal(t, codes.NotFound, status.Code(err))
+	f, err := s.Get(acme, &insidesvc.GetRequest{Id: wresp.Id})
+	require.NoError(t, err)
+	require.Equal(t, "acme", f.Properties[insidesvc.TenantProperty].GetStringValue())
+
+	// quota
+	_, err = s.InsertFeature(acme, &insidesvc.InsertFeatureRequest{Feature: squareFeature("C", 20)})
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// other tenants can't touch the feature
+	_, err = s.UpdateFeature(globex, &insidesvc.UpdateFeatureRequest{Id: wresp.Id, Feature: squareFeature("C", 20)})
+	require.Equal(t, codes.NotFound, status.Code(err))
+	_, err = s.DeleteFeature(globex, &insidesvc.DeleteFeatureRequest{Id: wresp.Id})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	info, err := s.Info(acme, &insidesvc.InfoRequest{})
+	require.NoError(t, err)
+	require.Equal(t, "acme", info.Tenant)
+	require.Equal(t, uint32(1), info.TenantQuota)
+	require.Equal(t, uint32(1), info.Datasets[0].TenantFeatureCount)
+
+	_, err = s.DeleteFeature(acme, &insidesvc.DeleteFeatureRequest{Id: wresp.Id})
+	require.NoError(t, err)
+	_, err = s.InsertFeature(acme, &insidesvc.InsertFeatureRequest{Feature: squareFeature("C", 20)})
+	require.NoError(t, err)
+	require.Equal(t, []string{"C"}, within(acme, 20.5, 20.5))
+
+	// counted again when loaded
+	require.NoError(t, s.AddDataset("B", storage))
+	info, err = s.Info(acme, &insidesvc.InfoRequest{})
+	require.NoError(t, err)
+	for _, di := range info.Datasets {
+		require.Equal(t, uint32(1), di.TenantFeatureCount)
+	}
+}
+
+func TestServer_TenantsStopOnFirstFound(t *testing.T) {
+	for _, strategy := range []string{
+		insideout.DBStrategy, insideout.InsideTreeStrategy, insideout.MemoryStrategy, insideout.HybridStrategy,
+	} {
+		strategy := strategy
+		t.Run(strategy, func(t *testing.T) {
+			storage, clean := setupRW(t, "A", 0)
+			defer clean()
+
+			s, err := New(storage, log.NewNopLogger(), nil, Options{
+				Strategy:         strategy,
+				CacheCount:       10,
+				ResultCacheLevel: 5,
+				ResultCacheCount: 100,
+				ReadWrite:        true,
+				Tenants:          true,
+				StopOnFirstFound: true,
+			})
+			require.NoError(t, err)
+
+			acme := tenant.NewContext(context.Background(), &tenant.Tenant{Name: "acme"})
+			globex := tenant.NewContext(context.Background(), &tenant.Tenant{Name: "globex"})
+
+			// the same square owned by both tenants, the feature of the other tenant must not end the search
+			_, err = s.InsertFeature(acme, &insidesvc.InsertFeatureRequest{Feature: squareFeature("B", 10)})
+			require.NoError(t, err)
+			_, err = s.InsertFeature(globex, &insidesvc.InsertFeatureRequest{Feature: squareFeature("C", 10)})
+			require.NoError(t, err)
+
+			for _, tc := range []struct {
+				ctx    context.Context
+				filter string
+				want   string
+			}{
+				{acme, "", "B"},
+				{globex, "", "C"},
+				{context.Background(), "name=B", "B"},
+				{context.Background(), "name=C", "C"},
+			} {
+				// in the inside cover and along the edges
+				for _, p := range [][2]float64{{10.5, 10.5}, {10.01, 10.01}} {
+					resp, err := s.Within(tc.ctx, &insidesvc.WithinRequest{Lat: p[0], Lng: p[1], Filter: tc.filter})
+					require.NoError(t, err)
+					require.Len(t, resp.Responses, 1, "tenant query %s %v", tc.want, p)
+					require.Equal(t, tc.want, resp.Responses[0].Feature.Properties["name"].GetStringValue())
+				}
+			}
+		})
+	}
+}
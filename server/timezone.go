@@ -0,0 +1,57 @@
+package server
+
+import (
+	"time"
+
+	// embeds the IANA time zone database, the images may not ship one
+	_ "time/tzdata"
+
+	"github.com/go-kit/kit/log/level"
+	structpb "github.com/golang/protobuf/ptypes/struct"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/insidesvc"
+)
+
+// addTimezone sets the current UTC offset and DST status of the time zone named by the
+// TimezoneProperty of f to the properties of fresp, the unknown time zones are skipped
+func (s *Server) addTimezone(fresp *insidesvc.FeatureResponse, f *insideout.Feature) {
+	if s.opts.TimezoneProperty == "" {
+		return
+	}
+	tzid, ok := f.Properties[s.opts.TimezoneProperty].(string)
+	if !ok || tzid == "" {
+		return
+	}
+
+	loc, err := s.location(tzid)
+	if err != nil {
+		level.Debug(s.logger).Log("msg", "unknown time zone", "error", err, "tzid", tzid)
+		return
+	}
+
+	now := time.Now().In(loc)
+	abbreviation, offset := now.Zone()
+	fresp.Feature.Properties[insidesvc.UTCOffsetProperty] = &structpb.Value{
+		Kind: &structpb.Value_NumberValue{NumberValue: float64(offset)},
+	}
+	fresp.Feature.Properties[insidesvc.DSTProperty] = &structpb.Value{
+		Kind: &structpb.Value_BoolValue{BoolValue: now.IsDST()},
+	}
+	fresp.Feature.Properties[insidesvc.TimezoneAbbreviationProperty] = &structpb.Value{
+		Kind: &structpb.Value_StringValue{StringValue: abbreviation},
+	}
+}
+
+// location returns the time zone named tzid, loaded once
+func (s *Server) location(tzid string) (*time.Location, error) {
+	if loc, ok := s.locations.Load(tzid); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(tzid)
+	if err != nil {
+		return nil, err
+	}
+	s.locations.Store(tzid, loc)
+	return loc, nil
+}
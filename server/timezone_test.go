@@ -0,0 +1,41 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/insidesvc"
+)
+
+func TestServer_WithinTimezone(t *testing.T) {
+	storage, clean := setupNested(t, []nestedSquare{
+		{name: "india", size: 1, props: map[string]interface{}{"tzid": "Asia/Kolkata"}},
+		{name: "unknown", size: 0.2, props: map[string]interface{}{"tzid": "Mars/Olympus_Mons"}},
+	})
+	defer clean()
+
+	s, err := New(storage, log.NewNopLogger(), nil, Options{
+		Strategy:         insideout.ShapeIndexStrategy,
+		TimezoneProperty: "tzid",
+	})
+	require.NoError(t, err)
+
+	resp, err := s.Within(context.Background(), &insidesvc.WithinRequest{Lat: 0.5, Lng: 0.5, RemoveGeometries: true})
+	require.NoError(t, err)
+	require.Len(t, resp.Responses, 2)
+
+	props := resp.Responses[0].Feature.Properties
+	require.Equal(t, 19800.0, props[insidesvc.UTCOffsetProperty].GetNumberValue())
+	require.False(t, props[insidesvc.DSTProperty].GetBoolValue())
+	require.Equal(t, "IST", props[insidesvc.TimezoneAbbreviationProperty].GetStringValue())
+
+	require.NotContains(t, resp.Responses[1].Feature.Properties, insidesvc.UTCOffsetProperty)
+
+	nresp, err := s.Nearest(context.Background(), &insidesvc.NearestRequest{Lat: 0.1, Lng: 0.1, RemoveGeometries: true})
+	require.NoError(t, err)
+	require.Equal(t, 19800.0, nresp.Response.Feature.Properties[insidesvc.UTCOffsetProperty].GetNumberValue())
+}
@@ -0,0 +1,28 @@
+package server
+
+import (
+	"context"
+
+	"github.com/golang/geo/s2"
+	"go.opentelemetry.io/otel/api/global"
+	"go.opentelemetry.io/otel/api/trace"
+	"go.opentelemetry.io/otel/label"
+)
+
+// tracerName the instrumentation name of the server spans
+const tracerName = "github.com/akhenakh/insideout/server"
+
+// tracer returns the tracer of the server spans, from the global provider configured by insided
+func tracer() trace.Tracer {
+	return global.Tracer(tracerName)
+}
+
+// covering returns the covering of region by coverer, traced as a span
+func covering(ctx context.Context, coverer *s2.RegionCoverer, region s2.Region) s2.CellUnion {
+	_, span := tracer().Start(ctx, "Covering")
+	defer span.End()
+
+	cu := coverer.Covering(region)
+	span.SetAttributes(label.Int("cells_count", len(cu)))
+	return cu
+}
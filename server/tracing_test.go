@@ -0,0 +1,58 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/api/global"
+	"go.opentelemetry.io/otel/sdk/export/trace/tracetest"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/insidesvc"
+)
+
+func TestServer_Tracing(t *testing.T) {
+	exp := tracetest.NewInMemoryExporter()
+	defer global.SetTracerProvider(global.TracerProvider())
+	global.SetTracerProvider(sdktrace.NewTracerProvider(
+		sdktrace.WithConfig(sdktrace.Config{DefaultSampler: sdktrace.AlwaysSample()}),
+		sdktrace.WithSyncer(exp),
+	))
+
+	a, clean := setup(t, "A", 0)
+	defer clean()
+
+	s, err := New(a, log.NewNopLogger(), nil, Options{Strategy: insideout.DBStrategy, NearestMaxDistance: 200000})
+	require.NoError(t, err)
+
+	resp, err := s.Within(context.Background(), &insidesvc.WithinRequest{Lat: 0.5, Lng: 0.5})
+	require.NoError(t, err)
+	require.Len(t, resp.Responses, 1)
+
+	spans := make(map[string]*exportSpan)
+	for _, sd := range exp.GetSpans() {
+		spans[sd.Name] = &exportSpan{id: sd.SpanContext.SpanID.String(), parent: sd.ParentSpanID.String()}
+	}
+	for _, name := range []string{"Within", "IndexStab", "PIPTests", "LoadFeature"} {
+		require.Contains(t, spans, name)
+	}
+	require.Equal(t, spans["Within"].id, spans["IndexStab"].parent)
+	require.Equal(t, spans["Within"].id, spans["PIPTests"].parent)
+	require.Equal(t, spans["PIPTests"].id, spans["LoadFeature"].parent)
+
+	exp.Reset()
+	_, err = s.Nearest(context.Background(), &insidesvc.NearestRequest{Lat: 2, Lng: 2, MaxDistance: 200000})
+	require.NoError(t, err)
+	var names []string
+	for _, sd := range exp.GetSpans() {
+		names = append(names, sd.Name)
+	}
+	require.Contains(t, names, "Covering")
+}
+
+type exportSpan struct {
+	id, parent string
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// dateLayout the layout of the dates without a time, at 00:00 UTC
+const dateLayout = "2006-01-02"
+
+// propertyTime returns the time held by the property value v, an RFC 3339 string, a date or a number of unix seconds
+func propertyTime(v interface{}) (time.Time, bool) {
+	var secs float64
+	switch v := v.(type) {
+	case string:
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			t, err = time.Parse(dateLayout, v)
+		}
+		return t, err == nil
+	case float64:
+		secs = v
+	case int:
+		secs = float64(v)
+	case int64:
+		secs = float64(v)
+	case uint64:
+		secs = float64(v)
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return time.Time{}, false
+		}
+		secs = f
+	default:
+		return time.Time{}, false
+	}
+	return time.Unix(0, int64(secs*float64(time.Second))), true
+}
+
+// validAt returns true if the feature with properties p is valid at t, from its valid from time included
+// to its valid to time excluded, the features without the validity properties are always valid
+func (s *Server) validAt(p map[string]interface{}, t time.Time) bool {
+	if s.opts.ValidFromProperty != "" {
+		if from, ok := propertyTime(p[s.opts.ValidFromProperty]); ok && t.Before(from) {
+			return false
+		}
+	}
+	if s.opts.ValidToProperty != "" {
+		if to, ok := propertyTime(p[s.opts.ValidToProperty]); ok && !t.Before(to) {
+			return false
+		}
+	}
+	return true
+}
+
+// requestTime returns the time of at as unix milliseconds, the current time when 0
+func requestTime(at int64) time.Time {
+	if at == 0 {
+		return time.Now()
+	}
+	return time.Unix(0, at*int64(time.Millisecond))
+}
+
+// parseAt returns the unix milliseconds of the at parameter of the HTTP API,
+// an RFC 3339 time, a date or unix milliseconds
+func parseAt(v string) (int64, error) {
+	if ms, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return ms, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		t, err = time.Parse(dateLayout, v)
+	}
+	if err != nil {
+		return 0, errors.New("invalid parameter at, expecting an RFC 3339 time, a date or unix milliseconds")
+	}
+	return t.UnixNano() / int64(time.Millisecond), nil
+}
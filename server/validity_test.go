@@ -0,0 +1,146 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/insidesvc"
+)
+
+func TestPropertyTime(t *testing.T) {
+	at := time.Date(2020, 6, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		v  interface{}
+		ok bool
+	}{
+		{"2020-06-01T12:00:00+02:00", true},
+		{"2020-06-01T10:00:00Z", true},
+		{float64(at.Unix()), true},
+		{at.Unix(), true},
+		{json.Number("1591005600"), true},
+		{"tomorrow", false},
+		{true, false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		got, ok := propertyTime(tt.v)
+		require.Equal(t, tt.ok, ok, tt.v)
+		if ok {
+			require.True(t, at.Equal(got), tt.v)
+		}
+	}
+
+	d, ok := propertyTime("2020-06-01")
+	require.True(t, ok)
+	require.True(t, d.Equal(time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestParseAt(t *testing.T) {
+	ms := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC).UnixNano() / int64(time.Millisecond)
+	for _, v := range []string{"2020-06-01", "2020-06-01T02:00:00+02:00", "1590969600000"} {
+		at, err := parseAt(v)
+		require.NoError(t, err, v)
+		require.Equal(t, ms, at, v)
+	}
+	_, err := parseAt("yesterday")
+	require.Error(t, err)
+}
+
+func TestServer_WithinAt(t *testing.T) {
+	storage, clean := setupNested(t, []nestedSquare{
+		{name: "county", size: 1, props: map[string]interface{}{"valid_to": "2000-01-01"}},
+		{name: "county merged", size: 0.8, props: map[string]interface{}{"valid_from": "2000-01-01"}},
+		{name: "city", size: 0.2, props: map[string]interface{}{"valid_from": "1950-06-01", "valid_to": "2010-01-01"}},
+		{name: "state", size: 2},
+	})
+	defer clean()
+
+	s, err := New(storage, log.NewNopLogger(), nil, Options{
+		Strategy:          insideout.DBStrategy,
+		ValidFromProperty: "valid_from",
+		ValidToProperty:   "valid_to",
+	})
+	require.NoError(t, err)
+
+	within := func(at time.Time) []string {
+		req := &insidesvc.WithinRequest{Lat: 0.5, Lng: 0.5, RemoveGeometries: true}
+		if !at.IsZero() {
+			req.At = at.UnixNano() / int64(time.Millisecond)
+		}
+		resp, err := s.Within(context.Background(), req)
+		require.NoError(t, err)
+		var names []string
+		for _, fresp := range resp.Responses {
+			names = append(names, fresp.Feature.Properties["name"].GetStringValue())
+		}
+		return names
+	}
+
+	require.ElementsMatch(t, []string{"county", "state"}, within(time.Date(1940, 1, 1, 0, 0, 0, 0, time.UTC)))
+	require.ElementsMatch(t, []string{"county", "city", "state"}, within(time.Date(1999, 12, 31, 23, 0, 0, 0, time.UTC)))
+	// valid to is excluded
+	require.ElementsMatch(t, []string{"county merged", "city", "state"}, within(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)))
+	require.ElementsMatch(t, []string{"county merged", "state"}, within(time.Time{}))
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/within/{lat}/{lng}", s.WithinHandler)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/within/0.5/0.5?at=1990-01-01", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), `"county"`)
+	require.NotContains(t, rec.Body.String(), "county merged")
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/within/0.5/0.5?at=yesterday", nil))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestServer_WithinAtStopOnFirstFound(t *testing.T) {
+	// the same square before and after a redistricting, the one not valid at the time must not end the search
+	storage, clean := setupNested(t, []nestedSquare{
+		{name: "county", size: 1, props: map[string]interface{}{"valid_to": "2000-01-01"}},
+		{name: "county merged", size: 1, props: map[string]interface{}{"valid_from": "2000-01-01"}},
+	})
+	defer clean()
+
+	for _, strategy := range []string{
+		insideout.DBStrategy, insideout.InsideTreeStrategy, insideout.MemoryStrategy, insideout.HybridStrategy,
+	} {
+		t.Run(strategy, func(t *testing.T) {
+			s, err := New(storage, log.NewNopLogger(), nil, Options{
+				Strategy:          strategy,
+				ValidFromProperty: "valid_from",
+				ValidToProperty:   "valid_to",
+				StopOnFirstFound:  true,
+			})
+			require.NoError(t, err)
+
+			for _, tc := range []struct {
+				at   time.Time
+				want string
+			}{
+				{time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC), "county"},
+				{time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), "county merged"},
+			} {
+				// in the inside cover and along the edges
+				for _, p := range [][2]float64{{0.5, 0.5}, {0.01, 0.01}} {
+					resp, err := s.Within(context.Background(), &insidesvc.WithinRequest{
+						Lat: p[0], Lng: p[1], RemoveGeometries: true, At: tc.at.UnixNano() / int64(time.Millisecond),
+					})
+					require.NoError(t, err)
+					require.Len(t, resp.Responses, 1, "%s %v", tc.at, p)
+					require.Equal(t, tc.want, resp.Responses[0].Feature.Properties["name"].GetStringValue())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+)
+
+// warmer is implemented by the indexes built by their first query
+type warmer interface {
+	Warmup()
+}
+
+// Warmup builds the indexes of all the datasets concurrently, the first queries don't stall on their build,
+// returns the ctx error when it is done before the end, the builds then go on in the background
+func (s *Server) Warmup(ctx context.Context) error {
+	s.mu.RLock()
+	var warmers []func()
+	for name, ds := range s.datasets {
+		for _, strategy := range s.strategies() {
+			_, idx, err := s.index(ds, strategy)
+			if err != nil {
+				continue
+			}
+			w, ok := idx.(warmer)
+			if !ok {
+				continue
+			}
+			name, strategy := name, strategy
+			warmers = append(warmers, func() {
+				start := time.Now()
+				w.Warmup()
+				level.Info(s.logger).Log("msg", "index warmed up", "dataset", name, "strategy", strategy,
+					"duration", time.Since(start))
+			})
+		}
+	}
+	s.mu.RUnlock()
+
+	done := make(chan struct{}, len(warmers))
+	for _, warmup := range warmers {
+		go func(warmup func()) {
+			warmup()
+			done <- struct{}{}
+		}(warmup)
+	}
+
+	for range warmers {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// warmup builds the indexes of ds before it is served
+func (s *Server) warmup(ds *dataset) {
+	for _, idx := range ds.indexes() {
+		if w, ok := idx.(warmer); ok {
+			w.Warmup()
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/index/shapeindex"
+)
+
+func TestServer_Warmup(t *testing.T) {
+	a, clean := setup(t, "A", 0)
+	defer clean()
+	b, cleanb := setup(t, "B", 10)
+	defer cleanb()
+
+	s, err := New(a, log.NewNopLogger(), nil, Options{Strategy: insideout.ShapeIndexStrategy, DatasetName: "a"})
+	require.NoError(t, err)
+	require.NoError(t, s.AddDataset("b", b))
+	require.False(t, s.datasets["a"].idx.(*shapeindex.Index).IsFresh())
+
+	require.NoError(t, s.Warmup(context.Background()))
+	require.True(t, s.datasets["a"].idx.(*shapeindex.Index).IsFresh())
+	require.True(t, s.datasets["b"].idx.(*shapeindex.Index).IsFresh())
+
+	// a reloaded dataset is warmed up before being served
+	_, err = s.ReloadDataset("b", b)
+	require.NoError(t, err)
+	require.True(t, s.datasets["b"].idx.(*shapeindex.Index).IsFresh())
+
+	// nothing to warm up
+	s, err = New(a, log.NewNopLogger(), nil, Options{Strategy: insideout.DBStrategy})
+	require.NoError(t, err)
+	require.NoError(t, s.Warmup(context.Background()))
+}
@@ -0,0 +1,151 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/gorilla/websocket"
+	"google.golang.org/grpc/status"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/insidesvc"
+)
+
+// maxTrackedIDs max number of objects tracked by a WebSocket connection
+const maxTrackedIDs = 10000
+
+var upgrader = websocket.Upgrader{
+	// the HTTP API is open to all origins
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// wsPosition a position of a tracked object sent by a WebSocket client
+type wsPosition struct {
+	ID  string  `json:"id"`
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// wsResult the features containing a position of a tracked object,
+// with the features entered and exited since its previous position
+type wsResult struct {
+	ID       string      `json:"id"`
+	Lat      float64     `json:"lat"`
+	Lng      float64     `json:"lng"`
+	Features []wsFeature `json:"features"`
+	Entered  []wsFeature `json:"entered,omitempty"`
+	Exited   []wsFeature `json:"exited,omitempty"`
+	Error    string      `json:"error,omitempty"`
+}
+
+type wsFeature struct {
+	ID         uint32                 `json:"fid"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// WSHandler WebSocket Handler, the client sends {id, lat, lng} JSON messages for the objects it tracks
+// and receives their within results, with ?changes=true only when an object entered or exited a feature,
+// ?dataset, ?fields and ?filter apply to all the queries of the connection
+func (s *Server) WSHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	changes := query.Get("changes") == "true"
+	tmpl := &insidesvc.WithinRequest{
+		RemoveGeometries: true,
+		SelectProperties: query.Get("fields"),
+		Filter:           query.Get("filter"),
+		Dataset:          query.Get("dataset"),
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has replied with an HTTP error
+		level.Debug(s.logger).Log("msg", "websocket upgrade failed", "error", err)
+		return
+	}
+	defer conn.Close()
+
+	// the last features of each tracked object
+	tracked := make(map[string][]wsFeature)
+
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				level.Debug(s.logger).Log("msg", "websocket read failed", "error", err)
+			}
+			return
+		}
+
+		var pos wsPosition
+		res := &wsResult{}
+		if err := json.Unmarshal(msg, &pos); err != nil {
+			res.Error = fmt.Sprintf("invalid message: %v", err)
+		} else {
+			res, err = s.wsWithin(r, tmpl, pos, tracked)
+			if err != nil {
+				res.Error = err.Error()
+			}
+		}
+
+		if changes && res.Error == "" && len(res.Entered) == 0 && len(res.Exited) == 0 {
+			continue
+		}
+		if err := conn.WriteJSON(res); err != nil {
+			level.Debug(s.logger).Log("msg", "websocket write failed", "error", err)
+			return
+		}
+	}
+}
+
+// wsWithin queries the features containing pos and diffs them with the previous ones of the object
+func (s *Server) wsWithin(r *http.Request, tmpl *insidesvc.WithinRequest, pos wsPosition,
+	tracked map[string][]wsFeature) (*wsResult, error) {
+	res := &wsResult{ID: pos.ID, Lat: pos.Lat, Lng: pos.Lng, Features: []wsFeature{}}
+
+	previous, ok := tracked[pos.ID]
+	if !ok && len(tracked) >= maxTrackedIDs {
+		return res, fmt.Errorf("too many tracked ids, max %d", maxTrackedIDs)
+	}
+
+	req := *tmpl
+	req.Lat, req.Lng = pos.Lat, pos.Lng
+	resp, err := s.Within(r.Context(), &req)
+	if err != nil {
+		if st, ok := status.FromError(err); ok {
+			return res, fmt.Errorf("%s", st.Message())
+		}
+		return res, err
+	}
+
+	for _, fr := range resp.Responses {
+		res.Features = append(res.Features, wsFeature{
+			ID:         fr.Id,
+			Properties: insideout.ValueToProperties(fr.Feature.Properties),
+		})
+	}
+	res.Entered = diffFeatures(res.Features, previous)
+	res.Exited = diffFeatures(previous, res.Features)
+	tracked[pos.ID] = res.Features
+
+	return res, nil
+}
+
+// diffFeatures returns the features of a missing from b
+func diffFeatures(a, b []wsFeature) []wsFeature {
+	var diff []wsFeature
+	for _, fa := range a {
+		found := false
+		for _, fb := range b {
+			if fa.ID == fb.ID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			diff = append(diff, fa)
+		}
+	}
+	return diff
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+
+	"github.com/akhenakh/insideout"
+)
+
+func TestServer_WSHandler(t *testing.T) {
+	a, clean := setup(t, "A", 0)
+	defer clean()
+
+	s, err := New(a, log.NewNopLogger(), nil, Options{Strategy: insideout.DBStrategy, CacheCount: 10})
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(http.HandlerFunc(s.WSHandler))
+	defer ts.Close()
+	url := "ws" + strings.TrimPrefix(ts.URL, "http")
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var res wsResult
+	require.NoError(t, conn.WriteJSON(wsPosition{ID: "truck", Lat: 0.5, Lng: 0.5}))
+	require.NoError(t, conn.ReadJSON(&res))
+	require.Equal(t, "truck", res.ID)
+	require.Len(t, res.Features, 1)
+	require.Equal(t, "A", res.Features[0].Properties["name"])
+	require.Len(t, res.Entered, 1)
+
+	res = wsResult{}
+	require.NoError(t, conn.WriteJSON(wsPosition{ID: "truck", Lat: 0.6, Lng: 0.6}))
+	require.NoError(t, conn.ReadJSON(&res))
+	require.Len(t, res.Features, 1)
+	require.Empty(t, res.Entered)
+
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("{")))
+	res = wsResult{}
+	require.NoError(t, conn.ReadJSON(&res))
+	require.NotEmpty(t, res.Error)
+
+	// only the changes
+	cconn, _, err := websocket.DefaultDialer.Dial(url+"?changes=true", nil)
+	require.NoError(t, err)
+	defer cconn.Close()
+
+	require.NoError(t, cconn.WriteJSON(wsPosition{ID: "truck", Lat: 0.5, Lng: 0.5}))
+	require.NoError(t, cconn.WriteJSON(wsPosition{ID: "truck", Lat: 0.6, Lng: 0.6}))
+	require.NoError(t, cconn.WriteJSON(wsPosition{ID: "truck", Lat: 5, Lng: 5}))
+	res = wsResult{}
+	require.NoError(t, cconn.ReadJSON(&res))
+	require.Len(t, res.Entered, 1)
+	res = wsResult{}
+	require.NoError(t, cconn.ReadJSON(&res))
+	require.Empty(t, res.Features)
+	require.Len(t, res.Exited, 1)
+	require.Equal(t, uint32(0), res.Exited[0].ID)
+}
@@ -0,0 +1,440 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/gorilla/mux"
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/geojson"
+	"go.opentelemetry.io/otel/label"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/index/dbindex"
+	"github.com/akhenakh/insideout/insidesvc"
+)
+
+// featureUpdater is implemented by the in memory indexes updated in place when a feature is written
+type featureUpdater interface {
+	Update(fs *insideout.FeatureStorage, cs *insideout.CellsStorage, id uint32) error
+	Remove(id uint32)
+}
+
+// InsertFeature indexes a new feature exposed via gRPC, the id is the next one of the dataset
+func (s *Server) InsertFeature(
+	ctx context.Context, req *insidesvc.InsertFeatureRequest,
+) (resp *insidesvc.WriteFeatureResponse, terr error) {
+	ctx, span := tracer().Start(ctx, "InsertFeature")
+	defer span.End()
+
+	defer func() { s.handleError(ctx, terr, span) }()
+
+	f, err := geoJSONFeature(req.Feature)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ds, fw, err := s.writableDataset(req.Dataset)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.claimFeature(ctx, f); err != nil {
+		return nil, err
+	}
+
+	id := ds.infos.FeatureCount
+	if err := checkExternalID(ds, id, f.Properties); err != nil {
+		return nil, err
+	}
+	span.SetAttributes(label.String("dataset", ds.name), label.Uint32("fid", id))
+	if err := s.writeFeature(ds, fw, f, id); err != nil {
+		return nil, err
+	}
+	recountTenants(ds, nil, f.Properties)
+	reindexProperties(ds, id, f.Properties)
+
+	return &insidesvc.WriteFeatureResponse{Id: id}, nil
+}
+
+// UpdateFeature replaces the feature id exposed via gRPC
+func (s *Server) UpdateFeature(
+	ctx context.Context, req *insidesvc.UpdateFeatureRequest,
+) (resp *insidesvc.WriteFeatureResponse, terr error) {
+	ctx, span := tracer().Start(ctx, "UpdateFeature")
+	defer span.End()
+
+	defer func() { s.handleError(ctx, terr, span) }()
+
+	f, err := geoJSONFeature(req.Feature)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ds, fw, err := s.writableDataset(req.Dataset)
+	if err != nil {
+		return nil, err
+	}
+
+	span.SetAttributes(label.String("dataset", ds.name), label.Uint32("fid", req.Id))
+	old, err := ds.storage.LoadFeature(req.Id)
+	if err != nil || !visible(ctx, old.Properties) {
+		return nil, status.Errorf(codes.NotFound, "can't find feature %d", req.Id)
+	}
+	setOwner(ctx, f)
+	if err := checkExternalID(ds, req.Id, f.Properties); err != nil {
+		return nil, err
+	}
+	if err := s.writeFeature(ds, fw, f, req.Id); err != nil {
+		return nil, err
+	}
+	recountTenants(ds, old.Properties, f.Properties)
+	reindexProperties(ds, req.Id, f.Properties)
+
+	return &insidesvc.WriteFeatureResponse{Id: req.Id}, nil
+}
+
+// DeleteFeature removes the feature id exposed via gRPC
+func (s *Server) DeleteFeature(
+	ctx context.Context, req *insidesvc.DeleteFeatureRequest,
+) (resp *insidesvc.WriteFeatureResponse, terr error) {
+	ctx, span := tracer().Start(ctx, "DeleteFeature")
+	defer span.End()
+
+	defer func() { s.handleError(ctx, terr, span) }()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ds, fw, err := s.writableDataset(req.Dataset)
+	if err != nil {
+		return nil, err
+	}
+
+	span.SetAttributes(label.String("dataset", ds.name), label.Uint32("fid", req.Id))
+	old, err := ds.storage.LoadFeature(req.Id)
+	if err != nil || !visible(ctx, old.Properties) {
+		return nil, status.Errorf(codes.NotFound, "can't find feature %d", req.Id)
+	}
+	found, err := s.removeFeature(ds, fw, req.Id, old.Properties)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, status.Errorf(codes.NotFound, "can't find feature %d", req.Id)
+	}
+
+	return &insidesvc.WriteFeatureResponse{Id: req.Id}, nil
+}
+
+// removeFeature deletes the feature id with properties p from the storage and the index of ds,
+// returns false when it does not exist, the caller must hold s.mu
+func (s *Server) removeFeature(ds *dataset, fw insideout.FeatureWriter, id uint32, p map[string]interface{}) (bool, error) {
+	found, err := fw.DeleteFeature(id)
+	if err != nil || !found {
+		return false, err
+	}
+	recountTenants(ds, p, nil)
+	reindexProperties(ds, id, nil)
+
+	if up, ok := ds.idx.(featureUpdater); ok {
+		up.Remove(id)
+	}
+	if err := s.invalidate(ds, id); err != nil {
+		return true, err
+	}
+
+	level.Info(s.logger).Log("msg", "feature deleted", "dataset", ds.name, "fid", id)
+
+	return true, nil
+}
+
+// writableDataset returns the dataset called name and its storage if the features can be written,
+// the caller must hold s.mu
+func (s *Server) writableDataset(name string) (*dataset, insideout.FeatureWriter, error) {
+	if !s.opts.ReadWrite {
+		return nil, nil, status.Error(codes.FailedPrecondition, "the server is read only")
+	}
+	ds, err := s.dataset(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	fw, ok := ds.storage.(insideout.FeatureWriter)
+	if !ok {
+		return nil, nil, status.Errorf(codes.FailedPrecondition, "the storage of dataset %s is not writable", ds.name)
+	}
+	if ds.parents != nil {
+		return nil, nil, status.Errorf(codes.FailedPrecondition,
+			"dataset %s was indexed with a hierarchy, it can't be updated at runtime", ds.name)
+	}
+	switch ds.idx.(type) {
+	case featureUpdater, *dbindex.Index:
+	default:
+		return nil, nil, status.Errorf(codes.FailedPrecondition,
+			"the %s strategy can't be updated at runtime", s.opts.Strategy)
+	}
+	return ds, fw, nil
+}
+
+// writeFeature stores f with id and updates the index of ds, the caller must hold s.mu
+func (s *Server) writeFeature(ds *dataset, fw insideout.FeatureWriter, f *geojson.Feature, id uint32) error {
+	ok, err := fw.WriteFeature(f, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return status.Error(codes.InvalidArgument, "can't cover the feature geometry")
+	}
+
+	// the storage based indexes already read the new cells
+	up, ok := ds.idx.(featureUpdater)
+	if ok || ds.filter != nil {
+		cs, err := ds.storage.LoadCellStorage(id)
+		if err != nil {
+			return err
+		}
+		// the cells of a replaced feature stay in the filter as false positives
+		if ds.filter != nil {
+			ds.filter.AddCells(cs.CellsIn, cs.CellsOut)
+		}
+		if ok {
+			lb, err := insideout.GeoJSONEncodeLoops(f)
+			if err != nil {
+				return err
+			}
+			hb, err := insideout.GeoJSONEncodeHoles(f)
+			if err != nil {
+				return err
+			}
+			fs := &insideout.FeatureStorage{Properties: f.Properties, LoopsBytes: lb, HolesBytes: hb}
+			if err := up.Update(fs, cs, id); err != nil {
+				return err
+			}
+		}
+	}
+	if err := s.invalidate(ds, id); err != nil {
+		return err
+	}
+
+	level.Info(s.logger).Log("msg", "feature written", "dataset", ds.name, "fid", id)
+
+	return nil
+}
+
+// invalidate removes the feature id and all the within results from the caches of ds,
+// the caller must hold s.mu
+func (s *Server) invalidate(ds *dataset, id uint32) error {
+	if ds.cache != nil {
+		ds.cache.Del(id)
+	}
+	if ds.results != nil {
+		ds.results.Clear()
+	}
+
+	// the new index time namespaces the shared cache entries
+	infos, err := ds.storage.LoadIndexInfos()
+	if err != nil {
+		return err
+	}
+	ds.infos = infos
+	ds.version = datasetVersion(infos)
+	return nil
+}
+
+// geoJSONFeature returns the GeoJSON feature of a polygon or multipolygon feature message
+func geoJSONFeature(f *insidesvc.Feature) (*geojson.Feature, error) {
+	if f == nil || f.Geometry == nil {
+		return nil, errors.New("missing geometry")
+	}
+
+	gf := &geojson.Feature{Properties: insideout.ValueToProperties(f.Properties)}
+	switch f.Geometry.Type {
+	case insidesvc.Geometry_POLYGON:
+		p, err := polygon(f.Geometry.Coordinates, f.Geometry.Ends)
+		if err != nil {
+			return nil, err
+		}
+		gf.Geometry = p
+	case insidesvc.Geometry_MULTIPOLYGON:
+		mp := geom.NewMultiPolygon(geom.XY)
+		for _, g := range f.Geometry.Geometries {
+			if g.Type != insidesvc.Geometry_POLYGON {
+				return nil, errors.New("invalid multipolygon")
+			}
+			p, err := polygon(g.Coordinates, g.Ends)
+			if err != nil {
+				return nil, err
+			}
+			if err := mp.Push(p); err != nil {
+				return nil, err
+			}
+		}
+		if mp.NumPolygons() == 0 {
+			return nil, errors.New("invalid multipolygon")
+		}
+		gf.Geometry = mp
+	default:
+		return nil, errors.New("unsupported geometry type, polygon or multipolygon")
+	}
+	return gf, nil
+}
+
+// polygon returns the polygon of the rings c ending at ends, the outer ring then its holes,
+// a single ring without ends
+func polygon(c []float64, ends []uint32) (*geom.Polygon, error) {
+	rends := []int{len(c)}
+	if len(ends) > 0 {
+		rends = make([]int, len(ends))
+		for i, e := range ends {
+			rends[i] = int(e)
+		}
+	}
+	start := 0
+	for _, e := range rends {
+		if e%2 != 0 || e-start < 2*3 {
+			return nil, errors.New("invalid polygon")
+		}
+		start = e
+	}
+	if start != len(c) {
+		return nil, errors.New("invalid polygon ring ends")
+	}
+	return geom.NewPolygonFlat(geom.XY, c, rends), nil
+}
+
+// featureMessage returns the feature message of a GeoJSON polygon or multipolygon feature
+func featureMessage(body []byte) (*insidesvc.Feature, error) {
+	f := &geojson.Feature{}
+	if err := f.UnmarshalJSON(body); err != nil {
+		return nil, errors.New("invalid GeoJSON feature")
+	}
+
+	g, err := geometryMessage(f.Geometry)
+	if err != nil {
+		return nil, err
+	}
+	fm := &insidesvc.Feature{Geometry: g}
+
+	prop, err := insideout.PropertiesToValues(&insideout.Feature{Properties: f.Properties})
+	if err != nil {
+		return nil, err
+	}
+	fm.Properties = prop
+	return fm, nil
+}
+
+// geometryMessage returns the geometry message of a polygon or multipolygon with their holes
+func geometryMessage(g geom.T) (*insidesvc.Geometry, error) {
+	switch g := g.(type) {
+	case *geom.Polygon:
+		return polygonMessage(g), nil
+	case *geom.MultiPolygon:
+		gm := &insidesvc.Geometry{Type: insidesvc.Geometry_MULTIPOLYGON}
+		for i := 0; i < g.NumPolygons(); i++ {
+			gm.Geometries = append(gm.Geometries, polygonMessage(g.Polygon(i)))
+		}
+		return gm, nil
+	default:
+		return nil, errors.New("unsupported GeoJSON geometry, Polygon or MultiPolygon")
+	}
+}
+
+// polygonMessage returns the polygon message of p, the ends of its rings are set when it has holes
+func polygonMessage(p *geom.Polygon) *insidesvc.Geometry {
+	gm := &insidesvc.Geometry{Type: insidesvc.Geometry_POLYGON, Coordinates: p.FlatCoords()}
+	if p.NumLinearRings() > 1 {
+		for _, e := range p.Ends() {
+			gm.Ends = append(gm.Ends, uint32(e))
+		}
+	}
+	return gm
+}
+
+// FeatureWriteHandler HTTP 1.1 Handler to write features in read write mode,
+// POST a GeoJSON feature to insert it, PUT one to replace the feature id, DELETE to remove the feature id,
+// returns the id of the written feature
+func (s *Server) FeatureWriteHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	ctx, span := tracer().Start(ctx, "FeatureWriteHandler")
+	defer span.End()
+
+	vars := mux.Vars(r)
+
+	var id uint32
+	if r.Method != http.MethodPost {
+		fid, err := strconv.ParseUint(vars["id"], 10, 32)
+		if err != nil {
+			http.Error(w, "invalid parameter id", 400)
+			return
+		}
+		id = uint32(fid)
+	}
+
+	var f *insidesvc.Feature
+	if r.Method != http.MethodDelete {
+		body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxBodySize))
+		if err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+		f, err = featureMessage(body)
+		if err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+	}
+
+	var resp *insidesvc.WriteFeatureResponse
+	var err error
+	switch r.Method {
+	case http.MethodPost:
+		resp, err = s.InsertFeature(ctx, &insidesvc.InsertFeatureRequest{Feature: f, Dataset: vars["dataset"]})
+	case http.MethodPut:
+		resp, err = s.UpdateFeature(ctx, &insidesvc.UpdateFeatureRequest{Id: id, Feature: f, Dataset: vars["dataset"]})
+	case http.MethodDelete:
+		resp, err = s.DeleteFeature(ctx, &insidesvc.DeleteFeatureRequest{Id: id, Dataset: vars["dataset"]})
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		if st, ok := status.FromError(err); ok {
+			switch st.Code() {
+			case codes.InvalidArgument, codes.FailedPrecondition:
+				http.Error(w, st.Message(), 400)
+				return
+			case codes.NotFound:
+				http.Error(w, st.Message(), 404)
+				return
+			case codes.ResourceExhausted:
+				http.Error(w, st.Message(), http.StatusForbidden)
+				return
+			case codes.AlreadyExists:
+				http.Error(w, st.Message(), http.StatusConflict)
+				return
+			}
+		}
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]uint32{"id": resp.Id}); err != nil {
+		http.Error(w, err.Error(), 500)
+	}
+}
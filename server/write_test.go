@@ -0,0 +1,281 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	structpb "github.com/golang/protobuf/ptypes/struct"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/insidesvc"
+	"github.com/akhenakh/insideout/storage/bbolt"
+)
+
+// squareFeature returns a feature message of a square of 1 degree at offset named name
+func squareFeature(name string, offset float64) *insidesvc.Feature {
+	o := offset
+	return &insidesvc.Feature{
+		Geometry: &insidesvc.Geometry{
+			Type:        insidesvc.Geometry_POLYGON,
+			Coordinates: []float64{o, o, o + 1, o, o + 1, o + 1, o, o + 1, o, o},
+		},
+		Properties: map[string]*structpb.Value{
+			"name": {Kind: &structpb.Value_StringValue{StringValue: name}},
+		},
+	}
+}
+
+func setupRW(t *testing.T, name string, offset float64) (insideout.Store, func()) {
+	path := indexSquare(t, name, offset)
+
+	storage, sclose, err := bbolt.NewRWStorage(path, log.NewNopLogger())
+	require.NoError(t, err)
+
+	return storage, func() {
+		sclose()
+		os.Remove(path)
+	}
+}
+
+func TestServer_WriteFeature(t *testing.T) {
+	for name, opts := range map[string]Options{
+		insideout.DBStrategy:         {Strategy: insideout.DBStrategy},
+		insideout.InsideTreeStrategy: {Strategy: insideout.InsideTreeStrategy},
+		insideout.ShapeIndexStrategy: {Strategy: insideout.ShapeIndexStrategy},
+		"shapeindex regions":         {Strategy: insideout.ShapeIndexStrategy, ShapeIndexRegionLevel: 3},
+		insideout.MemoryStrategy:     {Strategy: insideout.MemoryStrategy},
+		insideout.HybridStrategy:     {Strategy: insideout.HybridStrategy},
+	} {
+		opts := opts
+		t.Run(name, func(t *testing.T) {
+			storage, clean := setupRW(t, "A", 0)
+			defer clean()
+
+			opts.CacheCount = 10
+			opts.ResultCacheLevel = 5
+			opts.ResultCacheCount = 100
+			opts.ReadWrite = true
+			s, err := New(storage, log.NewNopLogger(), nil, opts)
+			require.NoError(t, err)
+
+			ctx := context.Background()
+			within := func(lat, lng float64) []string {
+				resp, err := s.Within(ctx, &insidesvc.WithinRequest{Lat: lat, Lng: lng})
+				require.NoError(t, err)
+				var names []string
+				for _, fresp := range resp.Responses {
+					names = append(names, fresp.Feature.Properties["name"].GetStringValue())
+				}
+				return names
+			}
+			require.Empty(t, within(10.5, 10.5))
+
+			wresp, err := s.InsertFeature(ctx, &insidesvc.InsertFeatureRequest{Feature: squareFeature("B", 10)})
+			require.NoError(t, err)
+			require.Equal(t, uint32(1), wresp.Id)
+			require.Equal(t, []string{"B"}, within(10.5, 10.5))
+			require.Equal(t, []string{"A"}, within(0.5, 0.5))
+
+			// moved and renamed
+			_, err = s.UpdateFeature(ctx, &insidesvc.UpdateFeatureRequest{Id: 1, Feature: squareFeature("C", 20)})
+			require.NoError(t, err)
+			require.Empty(t, within(10.5, 10.5))
+			require.Equal(t, []string{"C"}, within(20.5, 20.5))
+
+			_, err = s.DeleteFeature(ctx, &insidesvc.DeleteFeatureRequest{Id: 1})
+			require.NoError(t, err)
+			require.Empty(t, within(20.5, 20.5))
+			require.Equal(t, []string{"A"}, within(0.5, 0.5))
+
+			_, err = s.DeleteFeature(ctx, &insidesvc.DeleteFeatureRequest{Id: 1})
+			require.Equal(t, codes.NotFound, status.Code(err))
+			_, err = s.UpdateFeature(ctx, &insidesvc.UpdateFeatureRequest{Id: 1, Feature: squareFeature("C", 20)})
+			require.Equal(t, codes.NotFound, status.Code(err))
+
+			// the ids are not reused
+			wresp, err = s.InsertFeature(ctx, &insidesvc.InsertFeatureRequest{Feature: squareFeature("D", 10)})
+			require.NoError(t, err)
+			require.Equal(t, uint32(2), wresp.Id)
+		})
+	}
+}
+
+func TestServer_WriteFeatureHoles(t *testing.T) {
+	// a square of 3 degrees at 10,10 with a hole of 1 degree in its middle
+	holed := squareFeature("B", 0)
+	holed.Geometry = &insidesvc.Geometry{
+		Type: insidesvc.Geometry_POLYGON,
+		Coordinates: []float64{
+			10, 10, 13, 10, 13, 13, 10, 13, 10, 10,
+			11, 11, 11, 12, 12, 12, 12, 11, 11, 11,
+		},
+		Ends: []uint32{10, 20},
+	}
+
+	for name, opts := range map[string]Options{
+		insideout.DBStrategy:         {Strategy: insideout.DBStrategy},
+		insideout.InsideTreeStrategy: {Strategy: insideout.InsideTreeStrategy},
+		insideout.ShapeIndexStrategy: {Strategy: insideout.ShapeIndexStrategy},
+		insideout.MemoryStrategy:     {Strategy: insideout.MemoryStrategy},
+		insideout.HybridStrategy:     {Strategy: insideout.HybridStrategy},
+	} {
+		opts := opts
+		t.Run(name, func(t *testing.T) {
+			storage, clean := setupRW(t, "A", 0)
+			defer clean()
+
+			opts.ReadWrite = true
+			s, err := New(storage, log.NewNopLogger(), nil, opts)
+			require.NoError(t, err)
+
+			ctx := context.Background()
+			within := func(lat, lng float64) int {
+				resp, err := s.Within(ctx, &insidesvc.WithinRequest{Lat: lat, Lng: lng})
+				require.NoError(t, err)
+				return len(resp.Responses)
+			}
+
+			wresp, err := s.InsertFeature(ctx, &insidesvc.InsertFeatureRequest{Feature: holed})
+			require.NoError(t, err)
+			require.Equal(t, 1, within(10.5, 10.5))
+			require.Equal(t, 0, within(11.5, 11.5))
+
+			// multipolygon
+			mp := squareFeature("C", 0)
+			mp.Geometry = &insidesvc.Geometry{
+				Type:       insidesvc.Geometry_MULTIPOLYGON,
+				Geometries: []*insidesvc.Geometry{holed.Geometry, squareFeature("", 20).Geometry},
+			}
+			_, err = s.UpdateFeature(ctx, &insidesvc.UpdateFeatureRequest{Id: wresp.Id, Feature: mp})
+			require.NoError(t, err)
+			require.Equal(t, 1, within(12.5, 12.5))
+			require.Equal(t, 0, within(11.5, 11.5))
+			require.Equal(t, 1, within(20.5, 20.5))
+
+			f, err := s.GetFeature(ctx, &insidesvc.GetFeatureRequest{Id: wresp.Id})
+			require.NoError(t, err)
+			require.Len(t, f.Geometry.Geometries, 2)
+			require.Equal(t, []uint32{10, 20}, f.Geometry.Geometries[0].Ends)
+			require.Empty(t, f.Geometry.Geometries[1].Ends)
+		})
+	}
+}
+
+func TestServer_WriteFeatureInvalid(t *testing.T) {
+	storage, clean := setupRW(t, "A", 0)
+	defer clean()
+
+	s, err := New(storage, log.NewNopLogger(), nil, Options{Strategy: insideout.DBStrategy, ReadWrite: true})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	_, err = s.InsertFeature(ctx, &insidesvc.InsertFeatureRequest{})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = s.InsertFeature(ctx, &insidesvc.InsertFeatureRequest{Feature: &insidesvc.Feature{
+		Geometry: &insidesvc.Geometry{Type: insidesvc.Geometry_POINT, Coordinates: []float64{1, 1}},
+	}})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	for _, ends := range [][]uint32{{10, 18}, {10, 21}, {9, 20}, {10, 14, 20}, {20, 10}} {
+		f := squareFeature("B", 10)
+		f.Geometry.Coordinates = append(f.Geometry.Coordinates, 10.2, 10.2, 10.2, 10.8, 10.8, 10.8, 10.8, 10.2, 10.2, 10.2)
+		f.Geometry.Ends = ends
+		_, err = s.InsertFeature(ctx, &insidesvc.InsertFeatureRequest{Feature: f})
+		require.Equal(t, codes.InvalidArgument, status.Code(err), ends)
+	}
+
+	_, err = s.InsertFeature(ctx, &insidesvc.InsertFeatureRequest{Feature: squareFeature("B", 10), Dataset: "unknown"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	// read only
+	ro, err := New(storage, log.NewNopLogger(), nil, Options{Strategy: insideout.DBStrategy})
+	require.NoError(t, err)
+	_, err = ro.InsertFeature(ctx, &insidesvc.InsertFeatureRequest{Feature: squareFeature("B", 10)})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	_, err = ro.DeleteFeature(ctx, &insidesvc.DeleteFeatureRequest{Id: 0})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+func TestServer_FeatureWriteHandler(t *testing.T) {
+	storage, clean := setupRW(t, "A", 0)
+	defer clean()
+
+	s, err := New(storage, log.NewNopLogger(), nil, Options{
+		Strategy:    insideout.MemoryStrategy,
+		DatasetName: "A",
+		ReadWrite:   true,
+	})
+	require.NoError(t, err)
+
+	r := mux.NewRouter()
+	for _, route := range s.APIRoutes() {
+		r.Handle(route.Path, route.Handler).Methods(route.Methods...)
+	}
+
+	body := `{"type": "Feature", "properties": {"name": "B"},
+		"geometry": {"type": "MultiPolygon", "coordinates": [[[[10, 10], [11, 10], [11, 11], [10, 11], [10, 10]]]]}}`
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/api/features/A", bytes.NewBufferString(body)))
+	require.Equal(t, 200, w.Code)
+	var resp map[string]uint32
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(t, uint32(1), resp["id"])
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/within/10.5/10.5", nil))
+	require.Equal(t, 200, w.Code)
+	require.Contains(t, w.Body.String(), `"name":"B"`)
+
+	body = `{"type": "Feature", "properties": {"name": "C"},
+		"geometry": {"type": "Polygon", "coordinates": [[[10, 10], [11, 10], [11, 11], [10, 11], [10, 10]]]}}`
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("PUT", "/api/features/1", bytes.NewBufferString(body)))
+	require.Equal(t, 200, w.Code)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/within/10.5/10.5", nil))
+	require.Equal(t, 200, w.Code)
+	require.Contains(t, w.Body.String(), `"name":"C"`)
+
+	// a hole around the point
+	body = `{"type": "Feature", "properties": {"name": "D"},
+		"geometry": {"type": "Polygon", "coordinates": [[[10, 10], [11, 10], [11, 11], [10, 11], [10, 10]],
+		[[10.2, 10.2], [10.2, 10.8], [10.8, 10.8], [10.8, 10.2], [10.2, 10.2]]]}}`
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("PUT", "/api/features/1", bytes.NewBufferString(body)))
+	require.Equal(t, 200, w.Code)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/within/10.5/10.5", nil))
+	require.Equal(t, 404, w.Code)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/within/10.1/10.1", nil))
+	require.Equal(t, 200, w.Code)
+	require.Contains(t, w.Body.String(), `"name":"D"`)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("PUT", "/api/features/1", bytes.NewBufferString(`{"type": "Point"}`)))
+	require.Equal(t, 400, w.Code)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("DELETE", "/api/features/A/1", nil))
+	require.Equal(t, 200, w.Code)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("DELETE", "/api/features/1", nil))
+	require.Equal(t, 404, w.Code)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/within/10.5/10.5", nil))
+	require.Equal(t, 404, w.Code)
+}
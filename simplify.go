@@ -0,0 +1,121 @@
+package insideout
+
+import (
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+	"github.com/twpayne/go-geom"
+)
+
+// SimplifyGeometry simplifies the rings of the polygons of g with Douglas-Peucker,
+// removing the vertices closer than toleranceMeters to the simplified edges,
+// returns the simplified geometry and the vertex counts before and after,
+// rings that would get less than 3 distinct vertices are kept as is,
+// geometries other than polygons are returned as is
+func SimplifyGeometry(g geom.T, toleranceMeters float64) (geom.T, int, int) {
+	tolerance := MetersToAngle(toleranceMeters)
+
+	switch rg := g.(type) {
+	case *geom.Polygon:
+		p, before, after := simplifyPolygon(rg, tolerance)
+		return p, before, after
+	case *geom.MultiPolygon:
+		mp := geom.NewMultiPolygon(rg.Layout())
+		var before, after int
+		for i := 0; i < rg.NumPolygons(); i++ {
+			p, b, a := simplifyPolygon(rg.Polygon(i), tolerance)
+			before += b
+			after += a
+			if err := mp.Push(p); err != nil {
+				return g, 0, 0
+			}
+		}
+		return mp, before, after
+	default:
+		return g, 0, 0
+	}
+}
+
+func simplifyPolygon(p *geom.Polygon, tolerance s1.Angle) (*geom.Polygon, int, int) {
+	stride := p.Stride()
+	var flat []float64
+	var ends []int
+	var before int
+	for i := 0; i < p.NumLinearRings(); i++ {
+		c := p.LinearRing(i).FlatCoords()
+		before += len(c) / stride
+		flat = append(flat, simplifyRing(c, stride, tolerance)...)
+		ends = append(ends, len(flat))
+	}
+	return geom.NewPolygonFlat(p.Layout(), flat, ends), before, len(flat) / stride
+}
+
+// simplifyRing returns the flat coordinates of the closed ring c simplified
+func simplifyRing(c []float64, stride int, tolerance s1.Angle) []float64 {
+	n := len(c) / stride
+	if n < 5 {
+		return c
+	}
+
+	points := make([]s2.Point, n)
+	for i := range points {
+		points[i] = s2.PointFromLatLng(s2.LatLngFromDegrees(c[i*stride+1], c[i*stride]))
+	}
+
+	// the ring is split at its farthest vertex from the first one, both halves are simplified
+	far := 1
+	for i := 2; i < n-1; i++ {
+		if points[0].Distance(points[i]) > points[0].Distance(points[far]) {
+			far = i
+		}
+	}
+
+	keep := make([]bool, n)
+	keep[0], keep[far], keep[n-1] = true, true, true
+	douglasPeucker(points, 0, far, tolerance, keep)
+	douglasPeucker(points, far, n-1, tolerance, keep)
+
+	var count int
+	for _, k := range keep {
+		if k {
+			count++
+		}
+	}
+	// a closed triangle at least
+	if count < 4 {
+		return c
+	}
+
+	out := make([]float64, 0, count*stride)
+	for i, k := range keep {
+		if k {
+			out = append(out, c[i*stride:(i+1)*stride]...)
+		}
+	}
+	return out
+}
+
+// douglasPeucker marks in keep the vertices between first and last to keep, iteratively for the rings of millions of vertices
+func douglasPeucker(points []s2.Point, first, last int, tolerance s1.Angle, keep []bool) {
+	stack := [][2]int{{first, last}}
+	for len(stack) > 0 {
+		seg := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		a, b := seg[0], seg[1]
+		if b-a < 2 {
+			continue
+		}
+
+		var maxDist s1.Angle
+		index := -1
+		for i := a + 1; i < b; i++ {
+			if d := s2.DistanceFromSegment(points[i], points[a], points[b]); d > maxDist {
+				maxDist, index = d, i
+			}
+		}
+		if index == -1 || maxDist <= tolerance {
+			continue
+		}
+		keep[index] = true
+		stack = append(stack, [2]int{a, index}, [2]int{index, b})
+	}
+}
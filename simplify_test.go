@@ -0,0 +1,51 @@
+package insideout
+
+import (
+	"testing"
+
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom"
+)
+
+func TestSimplifyGeometry(t *testing.T) {
+	// 0.01 degree square with 100 points per edge
+	var flat []float64
+	corners := [][2]float64{{2, 48}, {2.01, 48}, {2.01, 48.01}, {2, 48.01}}
+	for i, c := range corners {
+		next := corners[(i+1)%len(corners)]
+		for j := 0; j < 100; j++ {
+			f := float64(j) / 100
+			flat = append(flat, c[0]+(next[0]-c[0])*f, c[1]+(next[1]-c[1])*f)
+		}
+	}
+	flat = append(flat, 2, 48)
+	p := geom.NewPolygonFlat(geom.XY, flat, []int{len(flat)})
+
+	g, before, after := SimplifyGeometry(p, 1)
+	require.Equal(t, 401, before)
+	require.Equal(t, 5, after)
+	require.Empty(t, ValidateGeometry(g))
+	l := LoopFromCoordinates(g.(*geom.Polygon).FlatCoords())
+	require.True(t, l.ContainsPoint(s2.PointFromLatLng(s2.LatLngFromDegrees(48.005, 2.005))))
+
+	// below the tolerance nothing is removed
+	_, before, after = SimplifyGeometry(geom.NewPolygonFlat(geom.XY,
+		[]float64{2, 48, 2.005, 48.001, 2.01, 48, 2.01, 48.01, 2, 48.01, 2, 48}, []int{12}), 1)
+	require.Equal(t, 6, before)
+	require.Equal(t, 6, after)
+
+	// multipolygon, the small triangle is kept as is
+	mp := geom.NewMultiPolygonFlat(geom.XY, append(flat, 4, 48, 4.001, 48, 4, 48.001, 4, 48), [][]int{{len(flat)}, {len(flat) + 8}})
+	g, before, after = SimplifyGeometry(mp, 1)
+	require.Equal(t, 405, before)
+	require.Equal(t, 9, after)
+	require.Equal(t, 2, g.(*geom.MultiPolygon).NumPolygons())
+
+	// not a polygon
+	pt := geom.NewPointFlat(geom.XY, []float64{2, 48})
+	g, before, after = SimplifyGeometry(pt, 1)
+	require.Equal(t, pt, g)
+	require.Zero(t, before)
+	require.Zero(t, after)
+}
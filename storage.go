@@ -1,13 +1,29 @@
 package insideout
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/golang/geo/s2"
 	"github.com/twpayne/go-geom/encoding/geojson"
 )
 
+// Storage backends
+const (
+	BBoltBackend   = "bbolt"
+	LevelDBBackend = "leveldb"
+	BadgerBackend  = "badger"
+	FlatBackend    = "flat"
+	SQLiteBackend  = "sqlite"
+)
+
+// Store is implemented by the storage backends: the indexer writes the features with IndexReader or Append,
+// the strategies read them back by id and by cell. The optional capabilities, like FeatureWriter, ContextStore
+// or H3Store, are interfaces checked by type assertion. The backends are registered by name in the storage package.
 type Store interface {
 	LoadFeature(id uint32) (*Feature, error)
 	LoadAllFeatures(add func(*FeatureStorage, uint32) error) error
@@ -16,8 +32,97 @@ type Store interface {
 	LoadIndexInfos() (*IndexInfos, error)
 	LoadMapInfos() (*MapInfos, bool, error)
 	StabDB(lat, lng float64, StopOnInsideFound bool) (IndexResponse, error)
+	IntersectDB(cu s2.CellUnion) ([]FeatureIndexResponse, error)
 	Index(fc geojson.FeatureCollection, icoverer *s2.RegionCoverer, ocoverer *s2.RegionCoverer,
 		warningCellsCover int, fileName, version string) error
+	IndexReader(r FeatureReader, icoverer *s2.RegionCoverer, ocoverer *s2.RegionCoverer,
+		warningCellsCover int, fileName, version string) error
+	IndexFeature(f *geojson.Feature, id uint32, icoverer *s2.RegionCoverer, ocoverer *s2.RegionCoverer,
+		warningCellsCover int) (bool, error)
+	Append(r FeatureReader, idProperty string, icoverer *s2.RegionCoverer, ocoverer *s2.RegionCoverer,
+		warningCellsCover int, fileName, version string) error
+}
+
+// ContextStore is implemented by the storages querying a remote database,
+// the queries are canceled once ctx is done
+type ContextStore interface {
+	StabDBContext(ctx context.Context, lat, lng float64, stopOnInsideFound bool) (IndexResponse, error)
+	LoadFeatureContext(ctx context.Context, id uint32) (*Feature, error)
+}
+
+// AppendFeatures indexes the features read from r into an existing store,
+// a feature whose idProperty value matches a stored feature replaces it, others are added after nextID.
+// Returns the next available feature id.
+func AppendFeatures(s Store, r FeatureReader, nextID uint32, idProperty string,
+	icoverer *s2.RegionCoverer, ocoverer *s2.RegionCoverer, warningCellsCover int) (uint32, error) {
+	ids := make(map[string]uint32)
+	if idProperty != "" {
+		err := s.LoadAllFeatures(func(fs *FeatureStorage, id uint32) error {
+			if v, ok := fs.Properties[idProperty]; ok {
+				ids[fmt.Sprint(v)] = id
+			}
+			return nil
+		})
+		if err != nil {
+			return 0, fmt.Errorf("can't load existing features: %w", err)
+		}
+	}
+
+	for {
+		f, err := r.Read()
+		if err == io.EOF {
+			return nextID, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("can't read feature: %w", err)
+		}
+
+		id, replace := nextID, false
+		key, hasKey := "", false
+		if v, ok := f.Properties[idProperty]; ok && idProperty != "" {
+			key, hasKey = fmt.Sprint(v), true
+			if eid, ok := ids[key]; ok {
+				id, replace = eid, true
+			}
+		}
+
+		indexed, err := s.IndexFeature(f, id, icoverer, ocoverer, warningCellsCover)
+		if err != nil {
+			return 0, err
+		}
+		if indexed && !replace {
+			if hasKey {
+				ids[key] = id
+			}
+			nextID++
+		}
+	}
+}
+
+// FeatureReader reads features one at a time, returns io.EOF when there are no more features
+type FeatureReader interface {
+	Read() (*geojson.Feature, error)
+}
+
+// FeatureCollectionReader a FeatureReader over an in memory FeatureCollection
+type FeatureCollectionReader struct {
+	fc  *geojson.FeatureCollection
+	pos int
+}
+
+// NewFeatureCollectionReader returns a FeatureReader reading the features of fc
+func NewFeatureCollectionReader(fc *geojson.FeatureCollection) *FeatureCollectionReader {
+	return &FeatureCollectionReader{fc: fc}
+}
+
+// Read returns the next feature of the collection
+func (r *FeatureCollectionReader) Read() (*geojson.Feature, error) {
+	if r.pos >= len(r.fc.Features) {
+		return nil, io.EOF
+	}
+	f := r.fc.Features[r.pos]
+	r.pos++
+	return f, nil
 }
 
 // FeatureStorage on disk storage of the feature
@@ -27,6 +132,19 @@ type FeatureStorage struct {
 	// Next entries are arrays since a multipolygon may contains multiple loop
 	// LoopsBytes encoded with s2 Loop encoder
 	LoopsBytes [][]byte
+
+	// HolesBytes the holes of each loop encoded as loops, absent for a feature without holes
+	HolesBytes [][][]byte `cbor:",omitempty"`
+}
+
+// Reset empties fs before decoding another feature in it,
+// the decoder merges the properties into an existing map
+func (fs *FeatureStorage) Reset() {
+	for k := range fs.Properties {
+		delete(fs.Properties, k)
+	}
+	fs.LoopsBytes = fs.LoopsBytes[:0]
+	fs.HolesBytes = nil
 }
 
 // CellsStorage are used to store indexed cells
@@ -46,6 +164,119 @@ type IndexInfos struct {
 	IndexerVersion string
 	FeatureCount   uint32
 	MinCoverLevel  int
+
+	// InsideCover and OutsideCover the coverers parameters used to index, nil for older DBs
+	InsideCover  *CoverOptions `json:",omitempty"`
+	OutsideCover *CoverOptions `json:",omitempty"`
+
+	// AutoCover the cover levels of each feature were tuned to its extent, see AutoCover
+	AutoCover bool `json:",omitempty"`
+
+	// Compression the codec compressing the encoded features, empty when not compressed, see Compression
+	Compression string `json:",omitempty"`
+
+	// LoopEncoding the encoding of the stored loops, empty for the s2 encoding, see LoopEncoder
+	LoopEncoding string `json:",omitempty"`
+
+	// Coverage the cells containing all the features, see ComputeCoverage, nil for older DBs
+	Coverage s2.CellUnion `json:",omitempty"`
+}
+
+// CoverOptions the parameters of an S2 region coverer
+type CoverOptions struct {
+	MinLevel int
+	MaxLevel int
+	MaxCells int
+	LevelMod int
+}
+
+// NewCoverOptions returns the parameters of c
+func NewCoverOptions(c *s2.RegionCoverer) *CoverOptions {
+	o := &CoverOptions{
+		MinLevel: c.MinLevel,
+		MaxLevel: c.MaxLevel,
+		MaxCells: c.MaxCells,
+		LevelMod: c.LevelMod,
+	}
+	// s2 uses 1 when unset
+	if o.LevelMod == 0 {
+		o.LevelMod = 1
+	}
+	return o
+}
+
+// Coverer returns a region coverer with the parameters
+func (o *CoverOptions) Coverer() *s2.RegionCoverer {
+	return &s2.RegionCoverer{MinLevel: o.MinLevel, MaxLevel: o.MaxLevel, MaxCells: o.MaxCells, LevelMod: o.LevelMod}
+}
+
+// Validate returns an error if the parameters can't be used to cover
+func (o *CoverOptions) Validate() error {
+	if o.MinLevel < 0 || o.MaxLevel > 30 || o.MinLevel > o.MaxLevel {
+		return fmt.Errorf("invalid cover levels min %d max %d, 0 <= min <= max <= 30", o.MinLevel, o.MaxLevel)
+	}
+	if o.MaxCells < 1 {
+		return fmt.Errorf("invalid cover max cells %d", o.MaxCells)
+	}
+	if o.LevelMod < 1 || o.LevelMod > 3 {
+		return fmt.Errorf("invalid cover level mod %d, 1 <= level mod <= 3", o.LevelMod)
+	}
+	return nil
+}
+
+// Validate returns an error if the index can't be queried
+func (infos *IndexInfos) Validate() error {
+	if infos.MinCoverLevel < 0 || infos.MinCoverLevel > 30 {
+		return fmt.Errorf("invalid min cover level %d", infos.MinCoverLevel)
+	}
+	for _, o := range []*CoverOptions{infos.InsideCover, infos.OutsideCover} {
+		if o == nil {
+			continue
+		}
+		if err := o.Validate(); err != nil {
+			return err
+		}
+		// cells are looked up from the min cover level, tuned covers may go below
+		if !infos.AutoCover && o.MinLevel < infos.MinCoverLevel {
+			return fmt.Errorf("cover min level %d below the min cover level %d", o.MinLevel, infos.MinCoverLevel)
+		}
+	}
+	return nil
+}
+
+// CheckCoverers returns an error if the coverers or the auto cover mode differ from the ones used to index,
+// the same parameters must be used when appending to a DB
+func (infos *IndexInfos) CheckCoverers(icoverer, ocoverer *s2.RegionCoverer, autoCover bool) error {
+	if infos.InsideCover != nil && infos.AutoCover != autoCover {
+		return fmt.Errorf("auto cover %t differs from the DB %t", autoCover, infos.AutoCover)
+	}
+	if infos.InsideCover != nil && *infos.InsideCover != *NewCoverOptions(icoverer) {
+		return fmt.Errorf("inside cover %+v differs from the DB %+v", *NewCoverOptions(icoverer), *infos.InsideCover)
+	}
+	if infos.OutsideCover != nil && *infos.OutsideCover != *NewCoverOptions(ocoverer) {
+		return fmt.Errorf("outside cover %+v differs from the DB %+v", *NewCoverOptions(ocoverer), *infos.OutsideCover)
+	}
+	return nil
+}
+
+// CheckCompression returns an error if codec differs from the one used to index,
+// the features of a DB are all compressed with the same codec
+func (infos *IndexInfos) CheckCompression(codec string) error {
+	if infos.Compression != codec {
+		return fmt.Errorf("compression %q differs from the DB %q", codec, infos.Compression)
+	}
+	return nil
+}
+
+// CheckLoopEncoding returns an error if encoding differs from the one used to index
+func (infos *IndexInfos) CheckLoopEncoding(encoding string) error {
+	if encoding == S2LoopEncoding {
+		encoding = ""
+	}
+	if infos.LoopEncoding != encoding {
+		return fmt.Errorf("loop encoding %q differs from the DB %q", encoding, infos.LoopEncoding)
+	}
+	return nil
 }
 
 // MapInfos used to store information about the map if any in DB
@@ -64,3 +295,151 @@ func (infos *IndexInfos) String() string {
 		infos.FeatureCount,
 	)
 }
+
+// ParallelStore is implemented by the storages able to cover the features concurrently while indexing
+type ParallelStore interface {
+	SetWorkers(n int)
+}
+
+// ProgressStore is implemented by the storages reporting the features and cells indexed
+type ProgressStore interface {
+	SetProgress(p *Progress)
+}
+
+// FeatureWriter is implemented by the storages updated at runtime, the features are covered
+// with the coverers recorded in the index infos
+type FeatureWriter interface {
+	// WriteFeature stores f with id, replacing a previously stored feature with the same id,
+	// returns false when f can't be covered
+	WriteFeature(f *geojson.Feature, id uint32) (bool, error)
+	// DeleteFeature removes the feature id, returns false when it does not exist
+	DeleteFeature(id uint32) (bool, error)
+}
+
+// CompactStore is implemented by the storages able to write a compacted copy of their DB while serving,
+// Compact returns the size of the copy written to path
+type CompactStore interface {
+	Compact(path string) (int64, error)
+}
+
+// ResumableStore is implemented by the storages checkpointing their position while indexing,
+// Resume continues an interrupted IndexReader, skipping the features already read from r
+type ResumableStore interface {
+	Resume(r FeatureReader, icoverer *s2.RegionCoverer, ocoverer *s2.RegionCoverer,
+		warningCellsCover int, fileName, version string) error
+	LoadCheckpoint() (*Checkpoint, error)
+}
+
+// Checkpoint is the position of an interrupted indexation
+type Checkpoint struct {
+	// Read count of features read from the input, including the ones that can't be covered
+	Read int `json:"read"`
+	// FeatureCount count of features indexed, the next id
+	FeatureCount  uint32 `json:"feature_count"`
+	MinCoverLevel int    `json:"min_cover_level"`
+	Filename      string `json:"filename"`
+
+	InsideCover  *CoverOptions `json:"inside_cover"`
+	OutsideCover *CoverOptions `json:"outside_cover"`
+	AutoCover    bool          `json:"auto_cover"`
+	Compression  string        `json:"compression,omitempty"`
+	LoopEncoding string        `json:"loop_encoding,omitempty"`
+}
+
+// Progress counts the features and cells indexed, safe for concurrent use, a nil Progress counts nothing
+type Progress struct {
+	features uint64
+	cells    uint64
+}
+
+// Add counts features and cells indexed
+func (p *Progress) Add(features, cells int) {
+	if p == nil {
+		return
+	}
+	atomic.AddUint64(&p.features, uint64(features))
+	atomic.AddUint64(&p.cells, uint64(cells))
+}
+
+// Features returns the count of features indexed
+func (p *Progress) Features() uint64 {
+	return atomic.LoadUint64(&p.features)
+}
+
+// Cells returns the count of cells indexed
+func (p *Progress) Cells() uint64 {
+	return atomic.LoadUint64(&p.cells)
+}
+
+// CellsCount returns the count of cells in the covers
+func CellsCount(cus ...[]s2.CellUnion) int {
+	var count int
+	for _, cu := range cus {
+		for _, c := range cu {
+			count += len(c)
+		}
+	}
+	return count
+}
+
+// AutoCoverStore is implemented by the storages able to tune the cover levels of each feature
+type AutoCoverStore interface {
+	SetAutoCover(enabled bool)
+}
+
+// AutoCover tunes the cover levels of each feature to its extent when enabled, embedded by the storages.
+// The coverers passed to the storage are the ones of a feature fitting a cell at their min level,
+// their levels are shifted for larger or smaller features.
+type AutoCover struct {
+	mu       sync.Mutex
+	enabled  bool
+	minLevel int
+}
+
+// SetAutoCover enables the tuning
+func (a *AutoCover) SetAutoCover(enabled bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.enabled = enabled
+	a.minLevel = -1
+}
+
+// AutoCovered returns true when the tuning is enabled
+func (a *AutoCover) AutoCovered() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.enabled
+}
+
+// FeatureCoverers returns the coverers to use for f, tuned to its extent when enabled
+func (a *AutoCover) FeatureCoverers(f *geojson.Feature, icoverer, ocoverer *s2.RegionCoverer) (*s2.RegionCoverer, *s2.RegionCoverer) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.enabled || f.Geometry == nil {
+		return icoverer, ocoverer
+	}
+	ic, oc := TunedCoverers(unwrappedBounds(f.Geometry), icoverer, ocoverer)
+	if l := MinCoverLevel(ic, oc); a.minLevel == -1 || l < a.minLevel {
+		a.minLevel = l
+	}
+	return ic, oc
+}
+
+// LowestCoverLevel returns the lowest level used by the covers, the tuned ones when enabled
+func (a *AutoCover) LowestCoverLevel(icoverer, ocoverer *s2.RegionCoverer) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.enabled && a.minLevel != -1 {
+		return a.minLevel
+	}
+	return MinCoverLevel(icoverer, ocoverer)
+}
+
+// ResumeCoverLevel records l as a level used by the covers of a previous indexation
+func (a *AutoCover) ResumeCoverLevel(l int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.minLevel == -1 || l < a.minLevel {
+		a.minLevel = l
+	}
+}
@@ -0,0 +1,116 @@
+package bbolt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/fxamacker/cbor"
+	"github.com/golang/geo/s2"
+	"go.etcd.io/bbolt"
+
+	"github.com/akhenakh/insideout"
+)
+
+// Check scans all the buckets in a single transaction, decoding every feature loop and cell entry
+func (s *Storage) Check(largest int) (*insideout.CheckReport, error) {
+	fc := insideout.NewFeatureChecker(largest)
+
+	err := s.View(func(tx *bbolt.Tx) error {
+		// the features are checked first to find the orphan cells
+		fb := tx.Bucket([]byte{insideout.FeaturePrefix()})
+		if fb == nil {
+			return fmt.Errorf("missing features bucket")
+		}
+		err := fb.ForEach(func(k, v []byte) error {
+			if len(k) != 5 {
+				return fmt.Errorf("invalid feature key %x", k)
+			}
+			id := binary.BigEndian.Uint32(k[1:])
+			fv, err := s.Decompress(nil, v)
+			if err != nil {
+				return fmt.Errorf("can't decompress feature %d: %w", id, err)
+			}
+			fs := &insideout.FeatureStorage{}
+			if err := cbor.NewDecoder(bytes.NewReader(fv)).Decode(fs); err != nil {
+				return fmt.Errorf("can't decode feature %d: %w", id, err)
+			}
+			fc.AddFeature(fs, id, len(v))
+			fc.AddEntry("features", len(k)+len(v))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		return tx.ForEach(func(name []byte, b *bbolt.Bucket) error {
+			switch {
+			case bytes.Equal(name, []byte{insideout.FeaturePrefix()}):
+				return nil
+			case bytes.Equal(name, []byte{insideout.CellPrefix()}):
+				return b.ForEach(func(k, v []byte) error {
+					return checkCellEntry(fc, k, v)
+				})
+			}
+
+			bname := bucketName(name)
+			return b.ForEach(func(k, v []byte) error {
+				fc.AddEntry(bname, len(k)+len(v))
+				return nil
+			})
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return fc.Report(), nil
+}
+
+// checkCellEntry adds an entry of the cells bucket: an inside cell, an outside cell or the cells of a feature
+func checkCellEntry(fc *insideout.FeatureChecker, k, v []byte) error {
+	if len(k) == 0 {
+		return fmt.Errorf("empty cell key")
+	}
+	switch k[0] {
+	case insideout.InsidePrefix(), insideout.OutsidePrefix():
+		if len(k) != 9 {
+			return fmt.Errorf("invalid cell key %x", k)
+		}
+		inside := k[0] == insideout.InsidePrefix()
+		fc.AddCell(s2.CellID(binary.BigEndian.Uint64(k[1:])), inside, v)
+		if inside {
+			fc.AddEntry("inside cells", len(k)+len(v))
+		} else {
+			fc.AddEntry("outside cells", len(k)+len(v))
+		}
+	case insideout.CellPrefix():
+		if len(k) != 5 {
+			return fmt.Errorf("invalid feature cells key %x", k)
+		}
+		fc.AddCells(binary.BigEndian.Uint32(k[1:]))
+		fc.AddEntry("feature cells", len(k)+len(v))
+	default:
+		fc.AddEntry(fmt.Sprintf("unknown %q", k[0]), len(k)+len(v))
+	}
+	return nil
+}
+
+// bucketName returns a readable name of the bucket name
+func bucketName(name []byte) string {
+	switch {
+	case bytes.Equal(name, insideout.InfoKey()):
+		return "infos"
+	case bytes.Equal(name, insideout.MapKey()):
+		return "map"
+	case bytes.Equal(name, insideout.HierarchyKey()):
+		return "hierarchy"
+	case bytes.Equal(name, insideout.H3Key()):
+		return "h3"
+	case bytes.Equal(name, insideout.CellFilterKey()):
+		return "cell filter"
+	case bytes.Equal(name, insideout.PropertiesKey()):
+		return "property index"
+	}
+	return fmt.Sprintf("%q", name)
+}
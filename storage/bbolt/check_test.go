@@ -0,0 +1,70 @@
+package bbolt
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom/encoding/geojson"
+	"go.etcd.io/bbolt"
+
+	"github.com/akhenakh/insideout"
+)
+
+func TestStorage_Check(t *testing.T) {
+	tmpDir, err := ioutil.TempDir(os.TempDir(), "insideout-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	icoverer := &s2.RegionCoverer{MinLevel: 10, MaxLevel: 16, MaxCells: 24}
+	ocoverer := &s2.RegionCoverer{MinLevel: 10, MaxLevel: 15, MaxCells: 16}
+
+	storage, close, err := NewStorage(filepath.Join(tmpDir, "inside.db"), log.NewNopLogger())
+	require.NoError(t, err)
+	defer close()
+	fc := geojson.FeatureCollection{Features: []*geojson.Feature{square(2, 48, "A"), square(3, 48, "B")}}
+	require.NoError(t, storage.Index(fc, icoverer, ocoverer, 100, "a.geojson", "unittest"))
+
+	r, err := storage.Check(1)
+	require.NoError(t, err)
+	require.True(t, r.OK())
+	require.Equal(t, 2, r.Features)
+	require.Equal(t, 2, r.Loops)
+	require.Equal(t, 8, r.Vertices)
+	require.Len(t, r.Largest, 1)
+
+	var inside, outside int
+	for _, ls := range r.Levels {
+		inside += ls.InsideEntries
+		outside += ls.OutsideEntries
+	}
+	require.NotZero(t, inside)
+	require.NotZero(t, outside)
+
+	names := make(map[string]int)
+	for _, b := range r.Buckets {
+		names[b.Name] = b.Keys
+	}
+	require.Equal(t, 2, names["features"])
+	require.Equal(t, 2, names["feature cells"])
+	require.Equal(t, 1, names["infos"])
+
+	// a feature removed without its cells
+	err = storage.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte{insideout.FeaturePrefix()}).Delete(insideout.FeatureKey(1))
+	})
+	require.NoError(t, err)
+
+	r, err = storage.Check(0)
+	require.NoError(t, err)
+	require.False(t, r.OK())
+	require.Equal(t, 1, r.Features)
+	require.Empty(t, r.Largest)
+	require.Equal(t, []uint32{1}, r.OrphanCells)
+	require.NotZero(t, r.OrphanEntries)
+	require.Empty(t, r.FeaturesWithoutCells)
+}
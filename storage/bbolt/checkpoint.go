@@ -0,0 +1,134 @@
+package bbolt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/fxamacker/cbor"
+	"github.com/golang/geo/s2"
+	"go.etcd.io/bbolt"
+
+	"github.com/akhenakh/insideout"
+)
+
+// checkpointKey the key of the checkpoint in the infos bucket, removed once the indexation is complete
+var checkpointKey = []byte("checkpoint")
+
+// SetProgress sets the counters updated while indexing
+func (s *Storage) SetProgress(p *insideout.Progress) {
+	s.progress = p
+}
+
+// LoadCheckpoint returns the position of an interrupted indexation, nil when there is none
+func (s *Storage) LoadCheckpoint() (*insideout.Checkpoint, error) {
+	var cp *insideout.Checkpoint
+	err := s.View(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(insideout.InfoKey())
+		if b == nil {
+			return nil
+		}
+		v := b.Get(checkpointKey)
+		if v == nil {
+			return nil
+		}
+		cp = &insideout.Checkpoint{}
+		return cbor.NewDecoder(bytes.NewReader(v)).Decode(cp)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can't read checkpoint: %w", err)
+	}
+	return cp, nil
+}
+
+// Resume continues an interrupted IndexReader with the same inputs and coverers,
+// the features already read according to the checkpoint are skipped,
+// the indexation starts from zero when no feature was stored
+func (s *Storage) Resume(r insideout.FeatureReader, icoverer *s2.RegionCoverer, ocoverer *s2.RegionCoverer,
+	warningCellsCover int, fileName, version string) error {
+	err := s.View(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(insideout.InfoKey())
+		if b != nil && b.Get(insideout.InfoKey()) != nil {
+			return errors.New("the indexation is complete")
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("can't resume: %w", err)
+	}
+
+	cp, err := s.LoadCheckpoint()
+	if err != nil {
+		return fmt.Errorf("can't resume: %w", err)
+	}
+	if cp == nil {
+		cp = s.newCheckpoint(icoverer, ocoverer, fileName)
+	}
+	if cp.Filename != fileName {
+		return fmt.Errorf("can't resume: input files %s differ from the checkpoint %s", fileName, cp.Filename)
+	}
+	infos := &insideout.IndexInfos{InsideCover: cp.InsideCover, OutsideCover: cp.OutsideCover, AutoCover: cp.AutoCover,
+		Compression: cp.Compression, LoopEncoding: cp.LoopEncoding}
+	if err := infos.CheckCoverers(icoverer, ocoverer, s.AutoCovered()); err != nil {
+		return fmt.Errorf("can't resume: %w", err)
+	}
+	if err := infos.CheckCompression(s.Codec()); err != nil {
+		return fmt.Errorf("can't resume: %w", err)
+	}
+	if err := infos.CheckLoopEncoding(s.LoopEncoding()); err != nil {
+		return fmt.Errorf("can't resume: %w", err)
+	}
+	if s.AutoCovered() && cp.FeatureCount > 0 {
+		s.ResumeCoverLevel(cp.MinCoverLevel)
+	}
+
+	if err := s.createBuckets(true); err != nil {
+		return err
+	}
+
+	return s.indexCheckpointed(r, cp, icoverer, ocoverer, warningCellsCover, fileName, version)
+}
+
+func (s *Storage) newCheckpoint(icoverer, ocoverer *s2.RegionCoverer, fileName string) *insideout.Checkpoint {
+	return &insideout.Checkpoint{
+		Filename:     fileName,
+		InsideCover:  insideout.NewCoverOptions(icoverer),
+		OutsideCover: insideout.NewCoverOptions(ocoverer),
+		AutoCover:    s.AutoCovered(),
+		Compression:  s.Codec(),
+		LoopEncoding: s.LoopEncoding(),
+	}
+}
+
+// indexCheckpointed indexes r from cp, writes the infos and removes the checkpoint
+func (s *Storage) indexCheckpointed(r insideout.FeatureReader, cp *insideout.Checkpoint,
+	icoverer *s2.RegionCoverer, ocoverer *s2.RegionCoverer, warningCellsCover int, fileName, version string) error {
+	count, err := s.indexParallel(r, cp, icoverer, ocoverer, warningCellsCover)
+	if err != nil {
+		return err
+	}
+
+	if err := s.writeInfos(count, s.LowestCoverLevel(icoverer, ocoverer), icoverer, ocoverer, fileName, version); err != nil {
+		return err
+	}
+
+	err = s.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(insideout.InfoKey()).Delete(checkpointKey)
+	})
+	if err != nil {
+		return fmt.Errorf("can't remove checkpoint: %w", err)
+	}
+	return nil
+}
+
+// putCheckpoint stores cp in tx
+func putCheckpoint(tx *bbolt.Tx, cp *insideout.Checkpoint) error {
+	v, err := cbor.Marshal(cp, cbor.CanonicalEncOptions())
+	if err != nil {
+		return fmt.Errorf("failed encoding checkpoint: %w", err)
+	}
+	if err := tx.Bucket(insideout.InfoKey()).Put(checkpointKey, v); err != nil {
+		return fmt.Errorf("failed store checkpoint into DB: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package bbolt
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom/encoding/geojson"
+
+	"github.com/akhenakh/insideout"
+)
+
+// failingReader fails after n features, once a checkpoint is stored
+type failingReader struct {
+	insideout.FeatureReader
+	n int
+	s *Storage
+}
+
+func (r *failingReader) Read() (*geojson.Feature, error) {
+	if r.n == 0 {
+		for i := 0; i < 100; i++ {
+			if cp, err := r.s.LoadCheckpoint(); err != nil || cp != nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return nil, errors.New("interrupted")
+	}
+	r.n--
+	return r.FeatureReader.Read()
+}
+
+func TestStorage_Resume(t *testing.T) {
+	logger := log.NewNopLogger()
+
+	tmpDir, err := ioutil.TempDir(os.TempDir(), "insideout-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	icoverer := &s2.RegionCoverer{MinLevel: 10, MaxLevel: 16, MaxCells: 24}
+	ocoverer := &s2.RegionCoverer{MinLevel: 10, MaxLevel: 15, MaxCells: 16}
+
+	fc := squaresCollection()
+	path := filepath.Join(tmpDir, "inside.db")
+
+	// interrupted after the first batch, written in the middle of the second one
+	wstorage, wclose, err := NewStorage(path, logger)
+	require.NoError(t, err)
+	wstorage.SetWorkers(4)
+	r := &failingReader{FeatureReader: insideout.NewFeatureCollectionReader(&fc), n: batchSize + 100, s: wstorage}
+	require.Error(t, wstorage.IndexReader(r, icoverer, ocoverer, 100, "squares.geojson", "unittest"))
+
+	cp, err := wstorage.LoadCheckpoint()
+	require.NoError(t, err)
+	require.NotNil(t, cp)
+	require.True(t, cp.Read >= batchSize && cp.Read <= batchSize+100)
+	require.True(t, cp.FeatureCount > 0 && int(cp.FeatureCount) < cp.Read)
+	require.NoError(t, wclose())
+
+	// different inputs
+	wstorage, wclose, err = NewStorage(path, logger)
+	require.NoError(t, err)
+	require.Error(t, wstorage.Resume(insideout.NewFeatureCollectionReader(&fc), icoverer, ocoverer, 100, "other.geojson", "unittest"))
+
+	// different cover
+	oc := &s2.RegionCoverer{MinLevel: 10, MaxLevel: 14, MaxCells: 16}
+	require.Error(t, wstorage.Resume(insideout.NewFeatureCollectionReader(&fc), icoverer, oc, 100, "squares.geojson", "unittest"))
+
+	p := &insideout.Progress{}
+	wstorage.SetProgress(p)
+	require.NoError(t, wstorage.Resume(insideout.NewFeatureCollectionReader(&fc), icoverer, ocoverer, 100, "squares.geojson", "unittest"))
+	require.Equal(t, uint64(len(fc.Features)-len(fc.Features)/10-1)-uint64(cp.FeatureCount), p.Features())
+	require.True(t, p.Cells() > p.Features())
+
+	cp, err = wstorage.LoadCheckpoint()
+	require.NoError(t, err)
+	require.Nil(t, cp)
+
+	// complete
+	require.Error(t, wstorage.Resume(insideout.NewFeatureCollectionReader(&fc), icoverer, ocoverer, 100, "squares.geojson", "unittest"))
+	require.NoError(t, wclose())
+
+	verifySquares(t, path, fc)
+}
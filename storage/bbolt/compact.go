@@ -0,0 +1,132 @@
+package bbolt
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"go.etcd.io/bbolt"
+)
+
+// compactTxMaxSize the size of the keys and values copied in one write transaction of the compacted DB
+const compactTxMaxSize = 64 << 20
+
+// Compact writes a compacted copy of the DB to path, without the free pages, returns the size of the copy.
+// The copy is a consistent snapshot read in a single transaction, the writes to the DB are not blocked
+// except the ones growing its file, path must not exist.
+func (s *Storage) Compact(path string) (int64, error) {
+	if path == s.Path() {
+		return 0, errors.New("can't compact the DB onto itself")
+	}
+	if _, err := os.Stat(path); err == nil {
+		return 0, fmt.Errorf("%s already exists", path)
+	} else if !os.IsNotExist(err) {
+		return 0, err
+	}
+
+	dst, err := bbolt.Open(path, 0600, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create DB at %s: %w", path, err)
+	}
+
+	if err := s.View(func(tx *bbolt.Tx) error {
+		return compact(dst, tx)
+	}); err != nil {
+		dst.Close()
+		os.Remove(path)
+		return 0, fmt.Errorf("failed to compact into %s: %w", path, err)
+	}
+
+	if err := dst.Close(); err != nil {
+		return 0, err
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	return fi.Size(), nil
+}
+
+// compact copies all the buckets of tx into dst, like bbolt compact,
+// committing a write transaction every compactTxMaxSize bytes
+func compact(dst *bbolt.DB, tx *bbolt.Tx) error {
+	dtx, err := dst.Begin(true)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// dtx is replaced after every commit
+		dtx.Rollback()
+	}()
+
+	var size int64
+	err = walk(tx, func(keys [][]byte, k, v []byte, seq uint64) error {
+		sz := int64(len(k) + len(v))
+		if size+sz > compactTxMaxSize && size > 0 {
+			if err := dtx.Commit(); err != nil {
+				return err
+			}
+			ntx, err := dst.Begin(true)
+			if err != nil {
+				return err
+			}
+			dtx, size = ntx, 0
+		}
+		size += sz
+
+		// a root bucket
+		if len(keys) == 0 {
+			b, err := dtx.CreateBucket(k)
+			if err != nil {
+				return err
+			}
+			return b.SetSequence(seq)
+		}
+
+		b := dtx.Bucket(keys[0])
+		for _, k := range keys[1:] {
+			b = b.Bucket(k)
+		}
+		// a nested bucket
+		if v == nil {
+			nb, err := b.CreateBucket(k)
+			if err != nil {
+				return err
+			}
+			return nb.SetSequence(seq)
+		}
+		return b.Put(k, v)
+	})
+	if err != nil {
+		return err
+	}
+	return dtx.Commit()
+}
+
+// walk calls fn for every bucket and key of tx, keys is the path of the bucket of k,
+// v is nil for the buckets, seq their sequence
+func walk(tx *bbolt.Tx, fn func(keys [][]byte, k, v []byte, seq uint64) error) error {
+	return tx.ForEach(func(name []byte, b *bbolt.Bucket) error {
+		return walkBucket(b, nil, name, nil, b.Sequence(), fn)
+	})
+}
+
+func walkBucket(b *bbolt.Bucket, keys [][]byte, k, v []byte, seq uint64,
+	fn func(keys [][]byte, k, v []byte, seq uint64) error) error {
+	if err := fn(keys, k, v, seq); err != nil {
+		return err
+	}
+	// not a bucket
+	if v != nil {
+		return nil
+	}
+
+	keys = append(keys, k)
+	return b.ForEach(func(k, v []byte) error {
+		if v == nil {
+			nb := b.Bucket(k)
+			return walkBucket(nb, keys, k, nil, nb.Sequence(), fn)
+		}
+		return walkBucket(b, keys, k, v, b.Sequence(), fn)
+	})
+}
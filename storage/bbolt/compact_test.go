@@ -0,0 +1,74 @@
+package bbolt
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom/encoding/geojson"
+)
+
+func TestStorage_Compact(t *testing.T) {
+	logger := log.NewNopLogger()
+
+	tmpDir, err := ioutil.TempDir(os.TempDir(), "insideout-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+	path := filepath.Join(tmpDir, "inside.db")
+
+	icoverer := &s2.RegionCoverer{MinLevel: 10, MaxLevel: 16, MaxCells: 24}
+	ocoverer := &s2.RegionCoverer{MinLevel: 10, MaxLevel: 15, MaxCells: 16}
+
+	wstorage, wclose, err := NewStorage(path, logger)
+	require.NoError(t, err)
+	var features []*geojson.Feature
+	for i := 0; i < 200; i++ {
+		features = append(features, square(float64(i%20)*0.2, 40+float64(i/20)*0.2, "A"))
+	}
+	require.NoError(t, wstorage.Index(geojson.FeatureCollection{Features: features}, icoverer, ocoverer, 100, "a.geojson", "unittest"))
+	require.NoError(t, wclose())
+
+	storage, close, err := NewRWStorage(path, logger)
+	require.NoError(t, err)
+	defer close()
+	for i := uint32(1); i < 200; i++ {
+		_, err := storage.DeleteFeature(i)
+		require.NoError(t, err)
+	}
+
+	_, err = storage.Compact(path)
+	require.Error(t, err)
+
+	cpath := filepath.Join(tmpDir, "compact.db")
+	size, err := storage.Compact(cpath)
+	require.NoError(t, err)
+	fi, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Less(t, size, fi.Size())
+
+	// the target must not exist
+	_, err = storage.Compact(cpath)
+	require.Error(t, err)
+
+	cstorage, cclose, err := NewROStorage(cpath, logger)
+	require.NoError(t, err)
+	defer cclose()
+	infos, err := cstorage.LoadIndexInfos()
+	require.NoError(t, err)
+	require.Equal(t, uint32(200), infos.FeatureCount)
+	r, err := cstorage.Check(0)
+	require.NoError(t, err)
+	require.True(t, r.OK())
+	require.Equal(t, 1, r.Features)
+
+	resp, err := cstorage.StabDB(40.05, 0.05, false)
+	require.NoError(t, err)
+	require.Len(t, append(resp.IDsInside, resp.IDsMayBeInside...), 1)
+	resp, err = cstorage.StabDB(40.05, 0.25, false)
+	require.NoError(t, err)
+	require.Empty(t, append(resp.IDsInside, resp.IDsMayBeInside...))
+}
@@ -0,0 +1,93 @@
+package bbolt
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom/encoding/geojson"
+
+	"github.com/akhenakh/insideout"
+)
+
+func TestStorage_FeatureEncodings(t *testing.T) {
+	logger := log.NewNopLogger()
+
+	tmpDir, err := ioutil.TempDir(os.TempDir(), "insideout-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	icoverer := &s2.RegionCoverer{MinLevel: 10, MaxLevel: 16, MaxCells: 24}
+	ocoverer := &s2.RegionCoverer{MinLevel: 10, MaxLevel: 15, MaxCells: 16}
+	fc := geojson.FeatureCollection{Features: []*geojson.Feature{square(2, 48, "A"), square(3, 48, "B")}}
+
+	for _, tc := range []struct{ codec, loopEncoding string }{
+		{insideout.SnappyCodec, ""},
+		{insideout.ZstdCodec, insideout.DeltaLoopEncoding},
+		{"", insideout.DeltaLoopEncoding},
+	} {
+		codec := tc.codec
+		path := filepath.Join(tmpDir, codec+tc.loopEncoding+".db")
+		wstorage, wclose, err := NewStorage(path, logger)
+		require.NoError(t, err)
+		require.Error(t, wstorage.SetCompression("lz4"))
+		require.NoError(t, wstorage.SetCompression(codec))
+		require.NoError(t, wstorage.SetLoopEncoding(tc.loopEncoding))
+		require.NoError(t, wstorage.Index(fc, icoverer, ocoverer, 100, "a.geojson", "unittest"))
+		require.NoError(t, wclose())
+
+		storage, close, err := NewRWStorage(path, logger)
+		require.NoError(t, err)
+		infos, err := storage.LoadIndexInfos()
+		require.NoError(t, err)
+		require.Equal(t, codec, infos.Compression)
+		require.Equal(t, codec, storage.Codec())
+		require.Equal(t, tc.loopEncoding, infos.LoopEncoding)
+		require.Equal(t, tc.loopEncoding, storage.LoopEncoding())
+
+		f, err := storage.LoadFeature(1)
+		require.NoError(t, err)
+		require.Equal(t, "B", f.Properties["name"])
+		require.Len(t, f.Loops, 1)
+
+		inside, err := storage.LoopContainsPoint(1, 0, s2.PointFromLatLng(s2.LatLngFromDegrees(48.05, 3.05)))
+		require.NoError(t, err)
+		require.True(t, inside)
+		inside, err = storage.LoopContainsPoint(1, 0, s2.PointFromLatLng(s2.LatLngFromDegrees(48.05, 2.05)))
+		require.NoError(t, err)
+		require.False(t, inside)
+
+		var names []interface{}
+		err = storage.LoadAllFeatures(func(fs *insideout.FeatureStorage, id uint32) error {
+			names = append(names, fs.Properties["name"])
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, []interface{}{"A", "B"}, names)
+
+		// written at runtime with the codec of the DB
+		ok, err := storage.WriteFeature(square(4, 48, "C"), 2)
+		require.NoError(t, err)
+		require.True(t, ok)
+		f, err = storage.LoadFeature(2)
+		require.NoError(t, err)
+		require.Equal(t, "C", f.Properties["name"])
+
+		r, err := storage.Check(0)
+		require.NoError(t, err)
+		require.True(t, r.OK())
+		require.Equal(t, 3, r.Features)
+		require.NoError(t, close())
+
+		// appending requires the same codec
+		astorage, aclose, err := NewStorage(path, logger)
+		require.NoError(t, err)
+		err = astorage.Append(insideout.NewFeatureCollectionReader(&fc), "", icoverer, ocoverer, 100, "a.geojson", "unittest")
+		require.Error(t, err)
+		require.NoError(t, aclose())
+	}
+}
@@ -0,0 +1,176 @@
+package bbolt
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/golang/geo/s2"
+	"github.com/twpayne/go-geom/encoding/geojson"
+	"go.etcd.io/bbolt"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/akhenakh/insideout"
+)
+
+// batchSize features written per transaction
+const batchSize = 256
+
+// SetWorkers sets the number of goroutines covering and encoding the features while indexing
+func (s *Storage) SetWorkers(n int) {
+	s.workers = n
+}
+
+type coverJob struct {
+	seq int
+	f   *geojson.Feature
+}
+
+type coverResult struct {
+	seq int
+	cf  *coveredFeature
+}
+
+// indexParallel covers the features read from r using s.workers goroutines,
+// a single writer stores them in read order, ids are the same as a sequential indexation,
+// starting from cp, the features already read are skipped and the checkpoint is stored with each batch,
+// returns the count of indexed features
+func (s *Storage) indexParallel(r insideout.FeatureReader, cp *insideout.Checkpoint, icoverer *s2.RegionCoverer, ocoverer *s2.RegionCoverer,
+	warningCellsCover int) (uint32, error) {
+	workers := s.workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	g, ctx := errgroup.WithContext(ctx)
+
+	jobs := make(chan coverJob, workers)
+	results := make(chan coverResult, workers)
+	// window bounds the features waiting to be written while a slow one is covered
+	window := make(chan struct{}, workers*batchSize)
+
+	// reader
+	g.Go(func() error {
+		defer close(jobs)
+		for i := 0; i < cp.Read; i++ {
+			if _, err := r.Read(); err != nil {
+				return fmt.Errorf("can't skip feature %d already indexed: %w", i, err)
+			}
+		}
+		for seq := 0; ; seq++ {
+			select {
+			case window <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			f, err := r.Read()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("can't read feature: %w", err)
+			}
+			select {
+			case jobs <- coverJob{seq: seq, f: f}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	})
+
+	// covering workers
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		g.Go(func() error {
+			defer wg.Done()
+			for j := range jobs {
+				cf, err := s.coverFeature(j.f, icoverer, ocoverer, warningCellsCover)
+				if err != nil {
+					return err
+				}
+				select {
+				case results <- coverResult{seq: j.seq, cf: cf}:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			return nil
+		})
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// writer, reordering the results
+	count := cp.FeatureCount
+	next := 0
+	pending := make(map[int]*coveredFeature)
+	var batch []*coveredFeature
+	// features that can't be covered since the last flush
+	var skipped int
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := s.Update(func(tx *bbolt.Tx) error {
+			for _, cf := range batch {
+				if err := s.putFeature(tx, cf, count); err != nil {
+					return err
+				}
+				count++
+			}
+			cp.Read += len(batch) + skipped
+			cp.FeatureCount = count
+			cp.MinCoverLevel = s.LowestCoverLevel(icoverer, ocoverer)
+			return putCheckpoint(tx, cp)
+		})
+		skipped = 0
+		batch = batch[:0]
+		return err
+	}
+
+	write := func() error {
+		for res := range results {
+			pending[res.seq] = res.cf
+			for {
+				cf, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+				next++
+				<-window
+				// features that can't be covered are skipped
+				if cf == nil {
+					skipped++
+					continue
+				}
+				batch = append(batch, cf)
+				if len(batch) >= batchSize {
+					if err := flush(); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		return flush()
+	}
+
+	if err := write(); err != nil {
+		cancel()
+		g.Wait()
+		return 0, err
+	}
+
+	if err := g.Wait(); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
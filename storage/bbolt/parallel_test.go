@@ -0,0 +1,93 @@
+package bbolt
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/geojson"
+
+	"github.com/akhenakh/insideout"
+)
+
+func TestStorage_IndexParallel(t *testing.T) {
+	logger := log.NewNopLogger()
+
+	tmpDir, err := ioutil.TempDir(os.TempDir(), "insideout-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	icoverer := &s2.RegionCoverer{MinLevel: 10, MaxLevel: 16, MaxCells: 24}
+	ocoverer := &s2.RegionCoverer{MinLevel: 10, MaxLevel: 15, MaxCells: 16}
+
+	fc := squaresCollection()
+
+	index := func(workers int) {
+		path := filepath.Join(tmpDir, "inside.db")
+		os.Remove(path)
+		wstorage, wclose, err := NewStorage(path, logger)
+		require.NoError(t, err)
+		wstorage.SetWorkers(workers)
+		require.NoError(t, wstorage.Index(fc, icoverer, ocoverer, 100, "squares.geojson", "unittest"))
+		require.NoError(t, wclose())
+
+		verifySquares(t, path, fc)
+	}
+
+	index(1)
+	index(4)
+}
+
+// squaresCollection returns more features than a batch, every 10th can't be covered
+func squaresCollection() geojson.FeatureCollection {
+	var fc geojson.FeatureCollection
+	for i := 0; i < 2*batchSize+10; i++ {
+		lng, lat := float64(i%30)*0.2, 40+float64(i/30)*0.2
+		f := &geojson.Feature{
+			Geometry: geom.NewPolygonFlat(geom.XY, []float64{
+				lng, lat, lng + 0.1, lat, lng + 0.1, lat + 0.1, lng, lat + 0.1, lng, lat,
+			}, []int{10}),
+			Properties: map[string]interface{}{"i": i},
+		}
+		if i%10 == 0 {
+			f.Geometry = geom.NewPointFlat(geom.XY, []float64{lng, lat})
+		}
+		fc.Features = append(fc.Features, f)
+	}
+
+	return fc
+}
+
+// verifySquares checks the DB at path indexed the features of squaresCollection
+func verifySquares(t *testing.T, path string, fc geojson.FeatureCollection) {
+	logger := log.NewNopLogger()
+	storage, close, err := NewROStorage(path, logger)
+	require.NoError(t, err)
+	defer close()
+
+	infos, err := storage.LoadIndexInfos()
+	require.NoError(t, err)
+	require.Equal(t, uint32(len(fc.Features)-len(fc.Features)/10-1), infos.FeatureCount)
+
+	var id uint32
+	for i := range fc.Features {
+		if i%10 == 0 {
+			continue
+		}
+		lng, lat := float64(i%30)*0.2+0.05, 40+float64(i/30)*0.2+0.05
+		resp, err := storage.StabDB(lat, lng, false)
+		require.NoError(t, err)
+		fids := append(resp.IDsInside, resp.IDsMayBeInside...)
+		require.Equal(t, []insideout.FeatureIndexResponse{{ID: id}}, fids, "feature %d", i)
+
+		f, err := storage.LoadFeature(id)
+		require.NoError(t, err)
+		require.EqualValues(t, i, f.Properties["i"])
+		id++
+	}
+}
@@ -0,0 +1,35 @@
+package bbolt
+
+import (
+	"fmt"
+
+	log "github.com/go-kit/kit/log"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/storage"
+)
+
+func init() {
+	storage.Register(insideout.BBoltBackend, open)
+}
+
+// open opens the storage at path in mode, the storage.Factory of bbolt
+func open(path string, mode storage.Mode, logger log.Logger) (insideout.Store, func() error, error) {
+	var s *Storage
+	var clean func() error
+	var err error
+	switch mode {
+	case storage.ReadOnly:
+		s, clean, err = NewROStorage(path, logger)
+	case storage.ReadWrite:
+		s, clean, err = NewRWStorage(path, logger)
+	case storage.Create:
+		s, clean, err = NewStorage(path, logger)
+	default:
+		return nil, nil, fmt.Errorf("unknown mode %v", mode)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return s, clean, nil
+}
@@ -24,6 +24,13 @@ var (
 			return &insideout.FeatureStorage{}
 		},
 	}
+
+	// featureBytesPool the buffers receiving the decompressed features
+	featureBytesPool = sync.Pool{
+		New: func() interface{} {
+			return new([]byte)
+		},
+	}
 )
 
 // Storage cold storage
@@ -31,6 +38,13 @@ type Storage struct {
 	*bbolt.DB
 	logger        log.Logger
 	minCoverLevel int
+	insideout.Compression
+
+	// indexing only
+	insideout.AutoCover
+	insideout.LoopEncoder
+	workers  int
+	progress *insideout.Progress
 }
 
 // NewStorage returns a cold storage using bboltdb
@@ -49,11 +63,24 @@ func NewStorage(path string, logger log.Logger) (*Storage, func() error, error)
 
 // NewROStorage returns a read only storage using bboltdb
 func NewROStorage(path string, logger log.Logger) (*Storage, func() error, error) {
-	// Creating DB
 	db, err := bbolt.Open(path, 0600, &bbolt.Options{ReadOnly: true})
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to open DB for reading at %s: %w", path, err)
 	}
+	return openStorage(db, logger)
+}
+
+// NewRWStorage returns a storage using an existing bboltdb, the features can be written at runtime
+func NewRWStorage(path string, logger log.Logger) (*Storage, func() error, error) {
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open DB for writing at %s: %w", path, err)
+	}
+	return openStorage(db, logger)
+}
+
+// openStorage returns a storage using the indexed db
+func openStorage(db *bbolt.DB, logger log.Logger) (*Storage, func() error, error) {
 
 	s := &Storage{
 		DB:     db,
@@ -62,9 +89,19 @@ func NewROStorage(path string, logger log.Logger) (*Storage, func() error, error
 
 	infos, err := s.LoadIndexInfos()
 	if err != nil {
+		db.Close()
 		return nil, nil, err
 	}
 	s.minCoverLevel = infos.MinCoverLevel
+	if err := s.SetCompression(infos.Compression); err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	// the features written at runtime use the encoding of the DB
+	if err := s.SetLoopEncoding(infos.LoopEncoding); err != nil {
+		db.Close()
+		return nil, nil, err
+	}
 
 	return s, db.Close, nil
 }
@@ -79,9 +116,13 @@ func (s *Storage) LoadFeature(id uint32) (*insideout.Feature, error) {
 		if v == nil {
 			return fmt.Errorf("feature id not found: %d", id)
 		}
+		v, err := s.Decompress(nil, v)
+		if err != nil {
+			return fmt.Errorf("can't read feature %d: %w", id, err)
+		}
 
-		dec := cbor.NewDecoder(bytes.NewReader(v))
-		return dec.Decode(fs)
+		// the byte strings are copied out of the DB
+		return cbor.Unmarshal(v, fs)
 	})
 	if err != nil {
 		return nil, err
@@ -89,31 +130,73 @@ func (s *Storage) LoadFeature(id uint32) (*insideout.Feature, error) {
 
 	loops := make([]*s2.Loop, len(fs.LoopsBytes))
 	for i := 0; i < len(loops); i++ {
-		l := &s2.Loop{}
-		if err = l.Decode(bytes.NewReader(fs.LoopsBytes[i])); err != nil {
+		l, err := insideout.DecodeLoop(fs.LoopsBytes[i])
+		if err != nil {
 			return nil, err
 		}
 		loops[i] = l
 	}
+	holes, err := insideout.DecodeHoles(fs.HolesBytes)
+	if err != nil {
+		return nil, err
+	}
 	f := &insideout.Feature{
 		Loops:      loops,
+		Holes:      holes,
 		Properties: fs.Properties,
 	}
 
 	return f, nil
 }
 
+// LoopContainsPoint tests p against the loop pos of the feature id and its holes in place, in the DB memory,
+// nothing is decoded nor copied, a compressed feature is decompressed into a pooled buffer
+func (s *Storage) LoopContainsPoint(id uint32, pos uint16, p s2.Point) (bool, error) {
+	var inside bool
+	buf := featureBytesPool.Get().(*[]byte)
+	defer featureBytesPool.Put(buf)
+	err := s.View(func(tx *bbolt.Tx) error {
+		v := tx.Bucket([]byte{insideout.FeaturePrefix()}).Get(insideout.FeatureKey(id))
+		if v == nil {
+			return fmt.Errorf("feature id not found: %d", id)
+		}
+		if s.Codec() != "" {
+			var err error
+			if *buf, err = s.Decompress(*buf, v); err != nil {
+				return fmt.Errorf("can't read feature %d: %w", id, err)
+			}
+			v = *buf
+		}
+		var err error
+		if inside, err = insideout.FeaturePolygonContainsPoint(v, int(pos), p); err != nil {
+			return fmt.Errorf("can't read loop %d of feature %d: %w", pos, id, err)
+		}
+		return nil
+	})
+	return inside, err
+}
+
 // LoadAllFeatures loads FeatureStorage from DB into idx
 // only useful to fill in memory shapeindex
 func (s *Storage) LoadAllFeatures(add func(*insideout.FeatureStorage, uint32) error) error {
 	err := s.View(func(tx *bbolt.Tx) error {
 		c := tx.Bucket([]byte{insideout.FeaturePrefix()}).Cursor()
 		prefix := []byte{insideout.FeaturePrefix()}
+		// the decoded byte strings are copied, the buffer is reused
+		var buf []byte
 		for key, value := c.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, value = c.Next() {
 			id := binary.BigEndian.Uint32(key[1:])
+			v, err := s.Decompress(buf, value)
+			if err != nil {
+				return fmt.Errorf("can't read feature %d: %w", id, err)
+			}
+			if s.Codec() != "" {
+				buf = v
+			}
 
-			dec := cbor.NewDecoder(bytes.NewReader(value))
+			dec := cbor.NewDecoder(bytes.NewReader(v))
 			fs := featureStoragePool.Get().(*insideout.FeatureStorage)
+			fs.Reset()
 			if err := dec.Decode(fs); err != nil {
 				featureStoragePool.Put(fs)
 				return err
@@ -183,6 +266,170 @@ func (s *Storage) LoadMapInfos() (*insideout.MapInfos, bool, error) {
 	return mapInfos, true, nil
 }
 
+// LoadHierarchy loads the parent id of each contained feature, nil when it was never stored
+func (s *Storage) LoadHierarchy() (map[uint32]uint32, error) {
+	var parents map[uint32]uint32
+	err := s.View(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(insideout.HierarchyKey())
+		if b == nil {
+			return nil
+		}
+		value := b.Get(insideout.HierarchyKey())
+		if value == nil {
+			return nil
+		}
+		dec := cbor.NewDecoder(bytes.NewReader(value))
+		return dec.Decode(&parents)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can't load hierarchy: %w", err)
+	}
+	return parents, nil
+}
+
+// StoreHierarchy stores the parent id of each contained feature
+func (s *Storage) StoreHierarchy(parents map[uint32]uint32) error {
+	value := new(bytes.Buffer)
+	enc := cbor.NewEncoder(value, cbor.CanonicalEncOptions())
+	if err := enc.Encode(parents); err != nil {
+		return fmt.Errorf("failed encoding hierarchy: %w", err)
+	}
+	err := s.Update(func(tx *bbolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(insideout.HierarchyKey())
+		if err != nil {
+			return err
+		}
+		return b.Put(insideout.HierarchyKey(), value.Bytes())
+	})
+	if err != nil {
+		return fmt.Errorf("can't store hierarchy: %w", err)
+	}
+	return nil
+}
+
+// LoadH3Cover loads the H3 cover of the polygons, nil when it was never stored
+func (s *Storage) LoadH3Cover() (*insideout.H3Cover, error) {
+	var cover *insideout.H3Cover
+	err := s.View(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(insideout.H3Key())
+		if b == nil {
+			return nil
+		}
+		value := b.Get(insideout.H3Key())
+		if value == nil {
+			return nil
+		}
+		dec := cbor.NewDecoder(bytes.NewReader(value))
+		return dec.Decode(&cover)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can't load H3 cover: %w", err)
+	}
+	return cover, nil
+}
+
+// StoreH3Cover stores the H3 cover of the polygons
+func (s *Storage) StoreH3Cover(cover *insideout.H3Cover) error {
+	value := new(bytes.Buffer)
+	enc := cbor.NewEncoder(value, cbor.CanonicalEncOptions())
+	if err := enc.Encode(cover); err != nil {
+		return fmt.Errorf("failed encoding H3 cover: %w", err)
+	}
+	err := s.Update(func(tx *bbolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(insideout.H3Key())
+		if err != nil {
+			return err
+		}
+		return b.Put(insideout.H3Key(), value.Bytes())
+	})
+	if err != nil {
+		return fmt.Errorf("can't store H3 cover: %w", err)
+	}
+	return nil
+}
+
+// LoadCellFilter loads the filter of the cells, nil when it was never stored
+func (s *Storage) LoadCellFilter() (*insideout.CellFilter, error) {
+	var filter *insideout.CellFilter
+	err := s.View(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(insideout.CellFilterKey())
+		if b == nil {
+			return nil
+		}
+		value := b.Get(insideout.CellFilterKey())
+		if value == nil {
+			return nil
+		}
+		dec := cbor.NewDecoder(bytes.NewReader(value))
+		return dec.Decode(&filter)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can't load cell filter: %w", err)
+	}
+	return filter, nil
+}
+
+// StoreCellFilter stores the filter of the cells
+func (s *Storage) StoreCellFilter(filter *insideout.CellFilter) error {
+	value := new(bytes.Buffer)
+	enc := cbor.NewEncoder(value, cbor.CanonicalEncOptions())
+	if err := enc.Encode(filter); err != nil {
+		return fmt.Errorf("failed encoding cell filter: %w", err)
+	}
+	err := s.Update(func(tx *bbolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(insideout.CellFilterKey())
+		if err != nil {
+			return err
+		}
+		return b.Put(insideout.CellFilterKey(), value.Bytes())
+	})
+	if err != nil {
+		return fmt.Errorf("can't store cell filter: %w", err)
+	}
+	return nil
+}
+
+// LoadPropertyIndex loads the index of the properties, nil when it was never stored
+func (s *Storage) LoadPropertyIndex() (*insideout.PropertyIndex, error) {
+	var pi *insideout.PropertyIndex
+	err := s.View(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(insideout.PropertiesKey())
+		if b == nil {
+			return nil
+		}
+		value := b.Get(insideout.PropertiesKey())
+		if value == nil {
+			return nil
+		}
+		dec := cbor.NewDecoder(bytes.NewReader(value))
+		return dec.Decode(&pi)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can't load property index: %w", err)
+	}
+	return pi, nil
+}
+
+// StorePropertyIndex stores the index of the properties
+func (s *Storage) StorePropertyIndex(pi *insideout.PropertyIndex) error {
+	value := new(bytes.Buffer)
+	enc := cbor.NewEncoder(value, cbor.CanonicalEncOptions())
+	if err := enc.Encode(pi); err != nil {
+		return fmt.Errorf("failed encoding property index: %w", err)
+	}
+	err := s.Update(func(tx *bbolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(insideout.PropertiesKey())
+		if err != nil {
+			return err
+		}
+		return b.Put(insideout.PropertiesKey(), value.Bytes())
+	})
+	if err != nil {
+		return fmt.Errorf("can't store property index: %w", err)
+	}
+	return nil
+}
+
 // LoadIndexInfos loads index infos from the DB
 func (s *Storage) LoadIndexInfos() (*insideout.IndexInfos, error) {
 	infos := &insideout.IndexInfos{}
@@ -300,191 +547,332 @@ func (s *Storage) StabDB(lat, lng float64, stopOnInsideFound bool) (insideout.In
 	return idxResp, nil
 }
 
+// IntersectDB returns polygon's ids with an outside cover intersecting cu
+func (s *Storage) IntersectDB(cu s2.CellUnion) ([]insideout.FeatureIndexResponse, error) {
+	m := make(map[insideout.FeatureIndexResponse]struct{})
+
+	addValue := func(v []byte) {
+		// read back the feature id and polygon index uint32 + uint16
+		for i := 0; i < len(v); i += 4 + 2 {
+			res := insideout.FeatureIndexResponse{}
+			res.ID = binary.BigEndian.Uint32(v[i : i+4])
+			res.Pos = binary.BigEndian.Uint16(v[i+4:])
+			m[res] = struct{}{}
+		}
+	}
+
+	err := s.View(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte{insideout.CellPrefix()})
+		curs := b.Cursor()
+		for _, c := range cu {
+			// indexed cells containing c
+			for l := s.minCoverLevel; l < c.Level(); l++ {
+				addValue(b.Get(insideout.OutsideKey(c.Parent(l))))
+			}
+
+			// indexed cells contained by c
+			startKey, stopKey := insideout.OutsideRangeKeys(c)
+			for k, v := curs.Seek(startKey); k != nil && bytes.Compare(k, stopKey) <= 0; k, v = curs.Next() {
+				addValue(v)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]insideout.FeatureIndexResponse, 0, len(m))
+	for fres := range m {
+		res = append(res, fres)
+	}
+
+	return res, nil
+}
+
 func (s *Storage) Index(fc geojson.FeatureCollection, icoverer *s2.RegionCoverer, ocoverer *s2.RegionCoverer,
 	warningCellsCover int, fileName, version string) error {
-	var count uint32
+	return s.IndexReader(insideout.NewFeatureCollectionReader(&fc), icoverer, ocoverer, warningCellsCover, fileName, version)
+}
 
-	logger := log.With(s.logger, "component", "indexer")
+// IndexReader indexes all the features read from r, one at a time
+func (s *Storage) IndexReader(r insideout.FeatureReader, icoverer *s2.RegionCoverer, ocoverer *s2.RegionCoverer,
+	warningCellsCover int, fileName, version string) error {
+	if err := s.createBuckets(false); err != nil {
+		return err
+	}
 
+	return s.indexCheckpointed(r, s.newCheckpoint(icoverer, ocoverer, fileName), icoverer, ocoverer,
+		warningCellsCover, fileName, version)
+}
+
+// createBuckets creates the buckets, keeping the existing ones when exist is true
+func (s *Storage) createBuckets(exist bool) error {
 	err := s.Update(func(tx *bbolt.Tx) error {
-		if _, err := tx.CreateBucket(insideout.InfoKey()); err != nil {
-			return err
-		}
-		if _, err := tx.CreateBucket([]byte{insideout.FeaturePrefix()}); err != nil {
-			return err
-		}
-		if _, err := tx.CreateBucket([]byte{insideout.CellPrefix()}); err != nil {
-			return err
+		for _, name := range [][]byte{insideout.InfoKey(), {insideout.FeaturePrefix()}, {insideout.CellPrefix()}} {
+			create := tx.CreateBucket
+			if exist {
+				create = tx.CreateBucketIfNotExists
+			}
+			if _, err := create(name); err != nil {
+				return err
+			}
 		}
 		return nil
 	})
 	if err != nil {
 		return fmt.Errorf("can't create bucket into DB: %w", err)
 	}
-	for _, f := range fc.Features {
-		f := f
-		// cover inside
-		cui, err := insideout.GeoJSONCoverCellUnion(f, icoverer, true)
-		if err != nil {
-			level.Warn(logger).Log("msg", "error covering inside", "error", err, "feature_properties", f.Properties)
+	return nil
+}
+
+// Append indexes the features read from r into an existing DB,
+// features with the same idProperty value as a stored feature replace it
+func (s *Storage) Append(r insideout.FeatureReader, idProperty string, icoverer *s2.RegionCoverer, ocoverer *s2.RegionCoverer,
+	warningCellsCover int, fileName, version string) error {
+	infos, err := s.LoadIndexInfos()
+	if err != nil {
+		return fmt.Errorf("can't append to DB: %w", err)
+	}
+	if err := infos.CheckCoverers(icoverer, ocoverer, s.AutoCovered()); err != nil {
+		return fmt.Errorf("can't append to DB: %w", err)
+	}
+	if err := infos.CheckCompression(s.Codec()); err != nil {
+		return fmt.Errorf("can't append to DB: %w", err)
+	}
+	if err := infos.CheckLoopEncoding(s.LoopEncoding()); err != nil {
+		return fmt.Errorf("can't append to DB: %w", err)
+	}
+
+	count, err := insideout.AppendFeatures(s, r, infos.FeatureCount, idProperty, icoverer, ocoverer, warningCellsCover)
+	if err != nil {
+		return err
+	}
+
+	// the existing cells may use a lower level
+	minCoverLevel := s.LowestCoverLevel(icoverer, ocoverer)
+	if infos.MinCoverLevel < minCoverLevel {
+		minCoverLevel = infos.MinCoverLevel
+	}
+
+	return s.writeInfos(count, minCoverLevel, icoverer, ocoverer, infos.Filename+","+fileName, version)
+}
+
+// IndexFeature covers and stores f with id, replacing a previously stored feature with the same id,
+// returns false when the feature can't be covered
+func (s *Storage) IndexFeature(f *geojson.Feature, id uint32, icoverer *s2.RegionCoverer, ocoverer *s2.RegionCoverer,
+	warningCellsCover int) (bool, error) {
+	cf, err := s.coverFeature(f, icoverer, ocoverer, warningCellsCover)
+	if err != nil {
+		return false, err
+	}
+	if cf == nil {
+		return false, nil
+	}
+
+	if err := s.removeFeatureCells(id); err != nil {
+		return false, fmt.Errorf("can't remove previous cells of feature %d: %w", id, err)
+	}
+
+	err = s.Update(func(tx *bbolt.Tx) error {
+		return s.putFeature(tx, cf, id)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// coveredFeature a feature covered and encoded, ready to be stored
+type coveredFeature struct {
+	properties map[string]interface{}
+
+	// covers to index, nil for the polygons with a cover too big
+	in, out []s2.CellUnion
+
+	fs, cs []byte
+}
+
+// coverFeature computes the covers of f and encodes it without accessing the DB,
+// returns nil when the feature can't be covered
+func (s *Storage) coverFeature(f *geojson.Feature, icoverer *s2.RegionCoverer, ocoverer *s2.RegionCoverer,
+	warningCellsCover int) (*coveredFeature, error) {
+	logger := log.With(s.logger, "component", "indexer")
+
+	icoverer, ocoverer = s.FeatureCoverers(f, icoverer, ocoverer)
+
+	// cover inside
+	cui, err := insideout.GeoJSONCoverCellUnion(f, icoverer, true)
+	if err != nil {
+		level.Warn(logger).Log("msg", "error covering inside", "error", err, "feature_properties", f.Properties)
+		return nil, nil
+	}
+
+	// cover outside
+	cuo, err := insideout.GeoJSONCoverCellUnion(f, ocoverer, false)
+	if err != nil {
+		level.Warn(logger).Log("msg", "error covering outside", "error", err, "feature_properties", f.Properties)
+		return nil, nil
+	}
+
+	cf := &coveredFeature{
+		properties: f.Properties,
+		in:         make([]s2.CellUnion, len(cui)),
+		out:        make([]s2.CellUnion, len(cuo)),
+	}
+	for fi, cu := range cui {
+		if warningCellsCover != 0 && len(cu) > warningCellsCover {
+			level.Warn(logger).Log(
+				"msg", fmt.Sprintf("inside cover too big %d cells, not indexing polygon #%d %s", len(cui), fi, f.Properties),
+				"feature_properties", f.Properties,
+			)
 			continue
 		}
-
-		// cover outside
-		cuo, err := insideout.GeoJSONCoverCellUnion(f, ocoverer, false)
-		if err != nil {
-			level.Warn(logger).Log("msg", "error covering outside", "error", err, "feature_properties", f.Properties)
+		cf.in[fi] = cu
+	}
+	for fi, cu := range cuo {
+		if warningCellsCover != 0 && len(cu) > warningCellsCover {
+			level.Warn(logger).Log(
+				"msg", fmt.Sprintf("outisde cover too big %d not indexing polygon #%d %s", len(cui), fi, f.Properties),
+				"feature_properties", f.Properties,
+			)
 			continue
 		}
+		cf.out[fi] = cu
+	}
 
-		// store interior cover
-		err = s.Update(func(tx *bbolt.Tx) error {
-			for fi, cu := range cui {
-				if warningCellsCover != 0 && len(cu) > warningCellsCover {
-					level.Warn(logger).Log(
-						"msg", fmt.Sprintf("inside cover too big %d cells, not indexing polygon #%d %s", len(cui), fi, f.Properties),
-						"feature_properties", f.Properties,
-					)
+	lb, err := s.EncodeLoops(f)
+	if err != nil {
+		return nil, fmt.Errorf("can't encode loop: %w", err)
+	}
+	hb, err := s.EncodeHoles(f)
+	if err != nil {
+		return nil, fmt.Errorf("can't encode holes: %w", err)
+	}
 
-					continue
-				}
-				for _, c := range cu {
-					// value is the feature id: current count, the polygon index in a multipolygon: fi
-					v := make([]byte, 6)
-					binary.BigEndian.PutUint32(v, count)
-					binary.BigEndian.PutUint16(v[4:], uint16(fi))
-					// append to existing if any
-					b := tx.Bucket([]byte{insideout.CellPrefix()})
-					ev := b.Get(insideout.InsideKey(c))
-
-					if ev != nil {
-						v = append(v, ev...)
-					}
-
-					err = b.Put(insideout.InsideKey(c), v)
-					if err != nil {
-						return err
-					}
-				}
-			}
-			return nil
-		})
-		if err != nil {
-			return fmt.Errorf("failed set inside cover into DB: %w", err)
-		}
-
-		// store outside cover
-		err = s.Update(func(tx *bbolt.Tx) error {
-			for fi, cu := range cuo {
-				if warningCellsCover != 0 && len(cu) > warningCellsCover {
-					level.Warn(logger).Log(
-						"msg", fmt.Sprintf("outisde cover too big %d not indexing polygon #%d %s", len(cui), fi, f.Properties),
-						"feature_properties", f.Properties,
-					)
-					continue
+	// TODO: filter cuo cui[fi].ContainsCellID(c)
+	cf.fs, err = cbor.Marshal(&insideout.FeatureStorage{Properties: f.Properties, LoopsBytes: lb, HolesBytes: hb}, cbor.CanonicalEncOptions())
+	if err != nil {
+		return nil, fmt.Errorf("can't encode FeatureStorage: %w", err)
+	}
+	if cf.fs, err = s.Compress(cf.fs); err != nil {
+		return nil, fmt.Errorf("can't compress FeatureStorage: %w", err)
+	}
+
+	// store cells for tree
+	cf.cs, err = cbor.Marshal(&insideout.CellsStorage{CellsIn: cui, CellsOut: cuo}, cbor.CanonicalEncOptions())
+	if err != nil {
+		return nil, fmt.Errorf("can't encode CellsStorage: %w", err)
+	}
+
+	return cf, nil
+}
+
+// putFeature stores the covered feature cf with id in tx
+func (s *Storage) putFeature(tx *bbolt.Tx, cf *coveredFeature, id uint32) error {
+	b := tx.Bucket([]byte{insideout.CellPrefix()})
+
+	put := func(cus []s2.CellUnion, key func(s2.CellID) []byte) error {
+		for fi, cu := range cus {
+			for _, c := range cu {
+				// value is the feature id, the polygon index in a multipolygon: fi
+				v := make([]byte, 6)
+				binary.BigEndian.PutUint32(v, id)
+				binary.BigEndian.PutUint16(v[4:], uint16(fi))
+				// append to existing if any
+				if ev := b.Get(key(c)); ev != nil {
+					v = append(v, ev...)
 				}
-				for _, c := range cu {
-					// TODO: filter cells already indexed by inside cover
-
-					// value is the feature id: current count, the polygon index in a multipolygon: fi
-					v := make([]byte, 6)
-					binary.BigEndian.PutUint32(v, count)
-					binary.BigEndian.PutUint16(v[4:], uint16(fi))
-					// append to existing if any
-					b := tx.Bucket([]byte{insideout.CellPrefix()})
-					ev := b.Get(insideout.OutsideKey(c))
-					if ev != nil {
-						v = append(v, ev...)
-					}
-
-					err = b.Put(insideout.OutsideKey(c), v)
-					if err != nil {
-						return err
-					}
+				if err := b.Put(key(c), v); err != nil {
+					return err
 				}
 			}
-			return nil
-		})
-		if err != nil {
-			return fmt.Errorf("failed set outside cover into DB: %w", err)
-		}
-
-		// store feature
-		if err := s.writeFeature(f, count, cui, cuo); err != nil {
-			return fmt.Errorf("can't store featrure into DB: %w", err)
 		}
+		return nil
+	}
 
-		// log.Println(f.Properties, len(cui), len(cuo))
-
-		count++
+	// store interior cover
+	if err := put(cf.in, insideout.InsideKey); err != nil {
+		return fmt.Errorf("failed set inside cover into DB: %w", err)
 	}
 
-	return s.writeInfos(icoverer, ocoverer, count, fileName, version)
-}
+	// store outside cover
+	// TODO: filter cells already indexed by inside cover
+	if err := put(cf.out, insideout.OutsideKey); err != nil {
+		return fmt.Errorf("failed set outside cover into DB: %w", err)
+	}
 
-func (s *Storage) writeFeature(f *geojson.Feature, id uint32, cui, cuo []s2.CellUnion) error {
 	// store feature
-	lb, err := insideout.GeoJSONEncodeLoops(f)
-	if err != nil {
-		return fmt.Errorf("can't encode loop: %w", err)
+	if err := tx.Bucket([]byte{insideout.FeaturePrefix()}).Put(insideout.FeatureKey(id), cf.fs); err != nil {
+		return fmt.Errorf("failed store feature into DB: %w", err)
+	}
+	if err := b.Put(insideout.CellKey(id), cf.cs); err != nil {
+		return fmt.Errorf("failed store feature into DB: %w", err)
 	}
 
-	b := new(bytes.Buffer)
-	enc := cbor.NewEncoder(b, cbor.CanonicalEncOptions())
+	s.progress.Add(1, insideout.CellsCount(cf.in, cf.out))
 
-	// TODO: filter cuo cui[fi].ContainsCellID(c)
-	fs := &insideout.FeatureStorage{Properties: f.Properties, LoopsBytes: lb}
-	if err := enc.Encode(fs); err != nil {
-		return fmt.Errorf("can't encode FeatureStorage: %w", err)
-	}
+	level.Debug(s.logger).Log(
+		"msg", "stored FeatureStorage",
+		"feature_properties", cf.properties,
+		"inside_loop_id", id,
+	)
 
-	err = s.Update(func(tx *bbolt.Tx) error {
-		bucket := tx.Bucket([]byte{insideout.FeaturePrefix()})
-		err := bucket.Put(insideout.FeatureKey(id), b.Bytes())
-		if err != nil {
-			return err
-		}
-		// store cells for tree
-		b = new(bytes.Buffer)
-		enc = cbor.NewEncoder(b, cbor.CanonicalEncOptions())
-		cs := &insideout.CellsStorage{
-			CellsIn:  cui,
-			CellsOut: cuo,
-		}
+	return nil
+}
 
-		if err := enc.Encode(cs); err != nil {
-			return fmt.Errorf("can't encode CellsStorage: %w", err)
+// removeFeatureCells removes the feature id from the inside and outside cells it was indexed in
+func (s *Storage) removeFeatureCells(id uint32) error {
+	return s.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte{insideout.CellPrefix()})
+		v := b.Get(insideout.CellKey(id))
+		if v == nil {
+			return nil
 		}
 
-		bucket = tx.Bucket([]byte{insideout.CellPrefix()})
-		err = bucket.Put(insideout.CellKey(id), b.Bytes())
-		if err != nil {
+		cs := &insideout.CellsStorage{}
+		dec := cbor.NewDecoder(bytes.NewReader(v))
+		if err := dec.Decode(cs); err != nil {
 			return err
 		}
 
-		level.Debug(s.logger).Log(
-			"msg", "stored FeatureStorage",
-			"feature_properties", f.Properties,
-			"loop_count", len(fs.LoopsBytes),
-			"inside_loop_id", id,
-		)
+		for _, cu := range cs.CellsIn {
+			for _, c := range cu {
+				if err := removeID(b, insideout.InsideKey(c), id); err != nil {
+					return err
+				}
+			}
+		}
+		for _, cu := range cs.CellsOut {
+			for _, c := range cu {
+				if err := removeID(b, insideout.OutsideKey(c), id); err != nil {
+					return err
+				}
+			}
+		}
 		return nil
 	})
-	if err != nil {
-		return fmt.Errorf("failed store feature into DB: %w", err)
-	}
-
-	return nil
 }
 
-func (s *Storage) writeInfos(icoverer *s2.RegionCoverer, ocoverer *s2.RegionCoverer,
-	fcount uint32, fileName, version string) error {
-	infoBytes := new(bytes.Buffer)
+// removeID removes the feature entries from the cell value at k
+func removeID(b *bbolt.Bucket, k []byte, id uint32) error {
+	v := b.Get(k)
+	if v == nil {
+		return nil
+	}
+	nv := insideout.RemoveIDFromCellValue(v, id)
+	if len(nv) == 0 {
+		return b.Delete(k)
+	}
+	return b.Put(k, nv)
+}
 
-	// Finding the lowest cover level
-	minCoverLevel := ocoverer.MinLevel
-	if icoverer.MinLevel < ocoverer.MinLevel {
-		minCoverLevel = icoverer.MinLevel
+func (s *Storage) writeInfos(fcount uint32, minCoverLevel int, icoverer, ocoverer *s2.RegionCoverer,
+	fileName, version string) error {
+	coverage, err := insideout.ComputeCoverage(s)
+	if err != nil {
+		return err
 	}
 
 	infos := &insideout.IndexInfos{
@@ -493,8 +881,20 @@ func (s *Storage) writeInfos(icoverer *s2.RegionCoverer, ocoverer *s2.RegionCove
 		IndexerVersion: version,
 		FeatureCount:   fcount,
 		MinCoverLevel:  minCoverLevel,
+		InsideCover:    insideout.NewCoverOptions(icoverer),
+		OutsideCover:   insideout.NewCoverOptions(ocoverer),
+		AutoCover:      s.AutoCovered(),
+		Compression:    s.Codec(),
+		LoopEncoding:   s.LoopEncoding(),
+		Coverage:       coverage,
 	}
 
+	return s.putInfos(infos)
+}
+
+// putInfos stores infos in the DB
+func (s *Storage) putInfos(infos *insideout.IndexInfos) error {
+	infoBytes := new(bytes.Buffer)
 	enc := cbor.NewEncoder(infoBytes, cbor.CanonicalEncOptions())
 	if err := enc.Encode(infos); err != nil {
 		return fmt.Errorf("failed encoding IndexInfos: %w", err)
@@ -0,0 +1,96 @@
+package bbolt
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/twpayne/go-geom/encoding/geojson"
+	"go.etcd.io/bbolt"
+
+	"github.com/akhenakh/insideout"
+)
+
+// WriteFeature covers f with the coverers recorded in the index infos and stores it with id,
+// replacing a previously stored feature with the same id, returns false when f can't be covered.
+// The features count, the coverage and the index time of the infos are updated.
+func (s *Storage) WriteFeature(f *geojson.Feature, id uint32) (bool, error) {
+	infos, err := s.LoadIndexInfos()
+	if err != nil {
+		return false, err
+	}
+	if infos.InsideCover == nil || infos.OutsideCover == nil {
+		return false, errors.New("the DB does not record its coverers, reindex it to write features")
+	}
+
+	s.SetAutoCover(infos.AutoCover)
+	icoverer, ocoverer := infos.InsideCover.Coverer(), infos.OutsideCover.Coverer()
+	indexed, err := s.IndexFeature(f, id, icoverer, ocoverer, 0)
+	if err != nil || !indexed {
+		return indexed, err
+	}
+
+	// the older DBs without coverage stay without, they can't reject anything
+	if len(infos.Coverage) > 0 {
+		cs, err := s.LoadCellStorage(id)
+		if err != nil {
+			return false, err
+		}
+		infos.Coverage = insideout.ExtendCoverage(infos.Coverage, cs.CellsOut...)
+	}
+
+	// a tuned cover may use a lower level
+	if l := s.LowestCoverLevel(icoverer, ocoverer); l < infos.MinCoverLevel {
+		infos.MinCoverLevel = l
+	}
+	if id >= infos.FeatureCount {
+		infos.FeatureCount = id + 1
+	}
+	if err := s.touchInfos(infos); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// DeleteFeature removes the feature id and its cells, returns false when it does not exist
+func (s *Storage) DeleteFeature(id uint32) (bool, error) {
+	infos, err := s.LoadIndexInfos()
+	if err != nil {
+		return false, err
+	}
+
+	if err := s.removeFeatureCells(id); err != nil {
+		return false, fmt.Errorf("can't remove the cells of feature %d: %w", id, err)
+	}
+
+	found := false
+	err = s.Update(func(tx *bbolt.Tx) error {
+		fb := tx.Bucket([]byte{insideout.FeaturePrefix()})
+		if fb.Get(insideout.FeatureKey(id)) == nil {
+			return nil
+		}
+		found = true
+		if err := fb.Delete(insideout.FeatureKey(id)); err != nil {
+			return err
+		}
+		return tx.Bucket([]byte{insideout.CellPrefix()}).Delete(insideout.CellKey(id))
+	})
+	if err != nil {
+		return false, fmt.Errorf("can't delete feature %d: %w", id, err)
+	}
+	if !found {
+		return false, nil
+	}
+
+	return true, s.touchInfos(infos)
+}
+
+// touchInfos stores infos as indexed now, the caches keyed by the index time are not read anymore
+func (s *Storage) touchInfos(infos *insideout.IndexInfos) error {
+	infos.IndexTime = time.Now()
+	if err := s.putInfos(infos); err != nil {
+		return err
+	}
+	s.minCoverLevel = infos.MinCoverLevel
+	return nil
+}
@@ -0,0 +1,110 @@
+package bbolt
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/require"
+	geom "github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/geojson"
+)
+
+func square(lng, lat float64, name string) *geojson.Feature {
+	return &geojson.Feature{
+		Geometry: geom.NewPolygonFlat(geom.XY, []float64{
+			lng, lat, lng + 0.1, lat, lng + 0.1, lat + 0.1, lng, lat + 0.1, lng, lat,
+		}, []int{10}),
+		Properties: map[string]interface{}{"name": name},
+	}
+}
+
+func TestStorage_WriteFeature(t *testing.T) {
+	logger := log.NewNopLogger()
+
+	tmpDir, err := ioutil.TempDir(os.TempDir(), "insideout-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+	path := filepath.Join(tmpDir, "inside.db")
+
+	icoverer := &s2.RegionCoverer{MinLevel: 10, MaxLevel: 16, MaxCells: 24}
+	ocoverer := &s2.RegionCoverer{MinLevel: 10, MaxLevel: 15, MaxCells: 16}
+
+	wstorage, wclose, err := NewStorage(path, logger)
+	require.NoError(t, err)
+	fc := geojson.FeatureCollection{Features: []*geojson.Feature{square(2, 48, "A")}}
+	require.NoError(t, wstorage.Index(fc, icoverer, ocoverer, 100, "a.geojson", "unittest"))
+	require.NoError(t, wclose())
+
+	storage, close, err := NewRWStorage(path, logger)
+	require.NoError(t, err)
+	defer close()
+	infos, err := storage.LoadIndexInfos()
+	require.NoError(t, err)
+	require.True(t, infos.Covers(48.05, 2.05))
+	require.False(t, infos.Covers(-33.9, 151.2))
+
+	// insert
+	ok, err := storage.WriteFeature(square(3, 48, "B"), 1)
+	require.NoError(t, err)
+	require.True(t, ok)
+	ninfos, err := storage.LoadIndexInfos()
+	require.NoError(t, err)
+	require.Equal(t, uint32(2), ninfos.FeatureCount)
+	require.True(t, ninfos.IndexTime.After(infos.IndexTime))
+
+	// far away, extends the coverage
+	ok, err = storage.WriteFeature(square(151.2, -33.9, "C"), 3)
+	require.NoError(t, err)
+	require.True(t, ok)
+	ninfos, err = storage.LoadIndexInfos()
+	require.NoError(t, err)
+	require.True(t, ninfos.Covers(-33.85, 151.25))
+	require.True(t, ninfos.Covers(48.05, 2.05))
+
+	resp, err := storage.StabDB(48.05, 3.05, false)
+	require.NoError(t, err)
+	require.Len(t, append(resp.IDsInside, resp.IDsMayBeInside...), 1)
+
+	// replace, the previous cells are removed
+	ok, err = storage.WriteFeature(square(4, 48, "B"), 1)
+	require.NoError(t, err)
+	require.True(t, ok)
+	resp, err = storage.StabDB(48.05, 3.05, false)
+	require.NoError(t, err)
+	require.Empty(t, append(resp.IDsInside, resp.IDsMayBeInside...))
+	resp, err = storage.StabDB(48.05, 4.05, false)
+	require.NoError(t, err)
+	require.Len(t, append(resp.IDsInside, resp.IDsMayBeInside...), 1)
+
+	f, err := storage.LoadFeature(1)
+	require.NoError(t, err)
+	require.Equal(t, "B", f.Properties["name"])
+
+	// not a polygon
+	ok, err = storage.WriteFeature(&geojson.Feature{Geometry: geom.NewPointFlat(geom.XY, []float64{2, 48})}, 2)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// delete
+	ok, err = storage.DeleteFeature(1)
+	require.NoError(t, err)
+	require.True(t, ok)
+	resp, err = storage.StabDB(48.05, 4.05, false)
+	require.NoError(t, err)
+	require.Empty(t, append(resp.IDsInside, resp.IDsMayBeInside...))
+	_, err = storage.LoadFeature(1)
+	require.Error(t, err)
+
+	ok, err = storage.DeleteFeature(1)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// the other feature is untouched
+	resp, err = storage.StabDB(48.05, 2.05, false)
+	require.NoError(t, err)
+	require.Len(t, append(resp.IDsInside, resp.IDsMayBeInside...), 1)
+}
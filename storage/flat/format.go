@@ -0,0 +1,193 @@
+package flat
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+/*
+File layout, integers are little endian, offsets are absolute from the start of the file:
+
+	header
+		magic         [8]byte "insflat" + format version
+		infos         section, cbor encoded IndexInfos
+		map infos     section, cbor encoded MapInfos, empty if none
+		inside cells  section, table of cellEntry sorted by cell id
+		outside cells section, table of cellEntry sorted by cell id
+		features      section, table of featureEntry sorted by feature id
+
+	cellEntry: cell id uint64, value offset uint64, value length uint32
+	the value is a list of feature id uint32 + loop index uint16 big endian,
+	as stored by the other backends
+
+	featureEntry: feature id uint32, feature offset uint64, feature length uint32,
+	cells offset uint64, cells length uint32
+	the feature is a cbor encoded FeatureStorage, the cells a cbor encoded CellsStorage
+
+Values and blobs are written before the tables, the header is written last.
+*/
+
+const (
+	formatVersion = 1
+
+	sectionSize      = 8 + 8
+	headerSize       = 8 + 5*sectionSize
+	cellEntrySize    = 8 + 8 + 4
+	featureEntrySize = 4 + 8 + 4 + 8 + 4
+)
+
+var magic = [8]byte{'i', 'n', 's', 'f', 'l', 'a', 't', formatVersion}
+
+// section a contiguous part of the file
+type section struct {
+	off, len uint64
+}
+
+type header struct {
+	infos, mapInfos, inside, outside, features section
+}
+
+func (h *header) sections() []*section {
+	return []*section{&h.infos, &h.mapInfos, &h.inside, &h.outside, &h.features}
+}
+
+func (h *header) encode() []byte {
+	b := make([]byte, headerSize)
+	copy(b, magic[:])
+	for i, s := range h.sections() {
+		binary.LittleEndian.PutUint64(b[8+i*sectionSize:], s.off)
+		binary.LittleEndian.PutUint64(b[8+i*sectionSize+8:], s.len)
+	}
+	return b
+}
+
+// decodeHeader reads and validates the header of data
+func decodeHeader(data []byte) (*header, error) {
+	if len(data) < headerSize {
+		return nil, errors.New("file too small to be a flat index")
+	}
+	if string(data[:7]) != string(magic[:7]) {
+		return nil, errors.New("not a flat index")
+	}
+	if data[7] != formatVersion {
+		return nil, fmt.Errorf("unsupported flat index version %d", data[7])
+	}
+
+	h := &header{}
+	for i, s := range h.sections() {
+		s.off = binary.LittleEndian.Uint64(data[8+i*sectionSize:])
+		s.len = binary.LittleEndian.Uint64(data[8+i*sectionSize+8:])
+		if s.off > uint64(len(data)) || s.len > uint64(len(data))-s.off {
+			return nil, errors.New("invalid flat index section, truncated file?")
+		}
+	}
+	if h.inside.len%cellEntrySize != 0 || h.outside.len%cellEntrySize != 0 {
+		return nil, errors.New("invalid flat index cells table")
+	}
+	if h.features.len%featureEntrySize != 0 {
+		return nil, errors.New("invalid flat index features table")
+	}
+	return h, nil
+}
+
+type cellEntry struct {
+	cellID uint64
+	value  section
+}
+
+func (e cellEntry) encode(b []byte) []byte {
+	var buf [cellEntrySize]byte
+	binary.LittleEndian.PutUint64(buf[:], e.cellID)
+	binary.LittleEndian.PutUint64(buf[8:], e.value.off)
+	binary.LittleEndian.PutUint32(buf[16:], uint32(e.value.len))
+	return append(b, buf[:]...)
+}
+
+type featureEntry struct {
+	id             uint32
+	feature, cells section
+}
+
+func (e featureEntry) encode(b []byte) []byte {
+	var buf [featureEntrySize]byte
+	binary.LittleEndian.PutUint32(buf[:], e.id)
+	binary.LittleEndian.PutUint64(buf[4:], e.feature.off)
+	binary.LittleEndian.PutUint32(buf[12:], uint32(e.feature.len))
+	binary.LittleEndian.PutUint64(buf[16:], e.cells.off)
+	binary.LittleEndian.PutUint32(buf[24:], uint32(e.cells.len))
+	return append(b, buf[:]...)
+}
+
+// cellTable a sorted table of cellEntry
+type cellTable []byte
+
+func (t cellTable) len() int {
+	return len(t) / cellEntrySize
+}
+
+func (t cellTable) cellID(i int) uint64 {
+	return binary.LittleEndian.Uint64(t[i*cellEntrySize:])
+}
+
+func (t cellTable) value(i int) section {
+	return section{
+		off: binary.LittleEndian.Uint64(t[i*cellEntrySize+8:]),
+		len: uint64(binary.LittleEndian.Uint32(t[i*cellEntrySize+16:])),
+	}
+}
+
+// search returns the index of the first entry with a cell id >= c
+func (t cellTable) search(c uint64) int {
+	lo, hi := 0, t.len()
+	for lo < hi {
+		m := int(uint(lo+hi) >> 1)
+		if t.cellID(m) < c {
+			lo = m + 1
+		} else {
+			hi = m
+		}
+	}
+	return lo
+}
+
+// featureTable a sorted table of featureEntry
+type featureTable []byte
+
+func (t featureTable) len() int {
+	return len(t) / featureEntrySize
+}
+
+func (t featureTable) entry(i int) featureEntry {
+	b := t[i*featureEntrySize:]
+	return featureEntry{
+		id: binary.LittleEndian.Uint32(b),
+		feature: section{
+			off: binary.LittleEndian.Uint64(b[4:]),
+			len: uint64(binary.LittleEndian.Uint32(b[12:])),
+		},
+		cells: section{
+			off: binary.LittleEndian.Uint64(b[16:]),
+			len: uint64(binary.LittleEndian.Uint32(b[24:])),
+		},
+	}
+}
+
+// find returns the entry for feature id
+func (t featureTable) find(id uint32) (featureEntry, bool) {
+	lo, hi := 0, t.len()
+	for lo < hi {
+		m := int(uint(lo+hi) >> 1)
+		if binary.LittleEndian.Uint32(t[m*featureEntrySize:]) < id {
+			lo = m + 1
+		} else {
+			hi = m
+		}
+	}
+	if lo < t.len() {
+		if e := t.entry(lo); e.id == id {
+			return e, true
+		}
+	}
+	return featureEntry{}, false
+}
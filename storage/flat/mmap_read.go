@@ -0,0 +1,22 @@
+//go:build windows || js
+// +build windows js
+
+package flat
+
+import (
+	"io"
+	"os"
+)
+
+// mmap reads the whole file on windows and WebAssembly
+func mmap(f *os.File, size int) ([]byte, error) {
+	b := make([]byte, size)
+	if _, err := io.ReadFull(f, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func munmap(b []byte) error {
+	return nil
+}
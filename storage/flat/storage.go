@@ -0,0 +1,407 @@
+// Package flat is a read only storage using a single compact file, mapped in memory,
+// written once by the indexer
+package flat
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/fxamacker/cbor"
+	log "github.com/go-kit/kit/log"
+	"github.com/golang/geo/s2"
+
+	"github.com/akhenakh/insideout"
+)
+
+var (
+	featureStoragePool = sync.Pool{
+		New: func() interface{} {
+			return &insideout.FeatureStorage{}
+		},
+	}
+
+	errWriteOnly = errors.New("flat storage can't be read while indexing")
+	errReadOnly  = errors.New("flat storage is read only")
+)
+
+// Storage flat file storage, read only once written
+type Storage struct {
+	logger        log.Logger
+	minCoverLevel int
+	insideout.AutoCover
+	insideout.LoopEncoder
+	progress *insideout.Progress
+
+	// read only
+	data     []byte
+	h        *header
+	inside   cellTable
+	outside  cellTable
+	features featureTable
+
+	// write mode, nil when read only
+	w *writer
+}
+
+// NewROStorage returns a read only storage mapping the file at path
+func NewROStorage(path string, logger log.Logger) (*Storage, func() error, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open DB for reading at %s: %w", path, err)
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	if fi.Size() < headerSize {
+		return nil, nil, fmt.Errorf("invalid flat index %s: file too small", path)
+	}
+
+	data, err := mmap(f, int(fi.Size()))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to map DB %s: %w", path, err)
+	}
+	closer := func() error {
+		return munmap(data)
+	}
+
+	s, err := NewROStorageFromBytes(data, logger)
+	if err != nil {
+		closer()
+		return nil, nil, fmt.Errorf("invalid flat index %s: %w", path, err)
+	}
+
+	return s, closer, nil
+}
+
+// NewROStorageFromBytes returns a read only storage reading the content of a flat file held in data,
+// like a downloaded index where there is no file system, data must not be modified afterward
+func NewROStorageFromBytes(data []byte, logger log.Logger) (*Storage, error) {
+	h, err := decodeHeader(data)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Storage{
+		logger:   logger,
+		data:     data,
+		h:        h,
+		inside:   cellTable(data[h.inside.off : h.inside.off+h.inside.len]),
+		outside:  cellTable(data[h.outside.off : h.outside.off+h.outside.len]),
+		features: featureTable(data[h.features.off : h.features.off+h.features.len]),
+	}
+
+	infos, err := s.LoadIndexInfos()
+	if err != nil {
+		return nil, err
+	}
+	s.minCoverLevel = infos.MinCoverLevel
+
+	return s, nil
+}
+
+// bytes returns the content of sec, checking its bounds
+func (s *Storage) bytes(sec section) ([]byte, error) {
+	if sec.off > uint64(len(s.data)) || sec.len > uint64(len(s.data))-sec.off {
+		return nil, errors.New("invalid flat index offset, corrupted file?")
+	}
+	return s.data[sec.off : sec.off+sec.len], nil
+}
+
+// LoadFeature loads one feature from the DB
+func (s *Storage) LoadFeature(id uint32) (*insideout.Feature, error) {
+	if s.w != nil {
+		return nil, errWriteOnly
+	}
+	e, ok := s.features.find(id)
+	if !ok {
+		return nil, fmt.Errorf("feature id not found: %d", id)
+	}
+	v, err := s.bytes(e.feature)
+	if err != nil {
+		return nil, err
+	}
+
+	fs := &insideout.FeatureStorage{}
+	dec := cbor.NewDecoder(bytes.NewReader(v))
+	if err := dec.Decode(fs); err != nil {
+		return nil, err
+	}
+
+	loops := make([]*s2.Loop, len(fs.LoopsBytes))
+	for i := 0; i < len(loops); i++ {
+		l, err := insideout.DecodeLoop(fs.LoopsBytes[i])
+		if err != nil {
+			return nil, err
+		}
+		loops[i] = l
+	}
+	holes, err := insideout.DecodeHoles(fs.HolesBytes)
+	if err != nil {
+		return nil, err
+	}
+	f := &insideout.Feature{
+		Loops:      loops,
+		Holes:      holes,
+		Properties: fs.Properties,
+	}
+
+	return f, nil
+}
+
+// LoopContainsPoint tests p against the loop pos of the feature id and its holes in place, in the mapped file,
+// nothing is decoded nor copied
+func (s *Storage) LoopContainsPoint(id uint32, pos uint16, p s2.Point) (bool, error) {
+	if s.w != nil {
+		return false, errWriteOnly
+	}
+	e, ok := s.features.find(id)
+	if !ok {
+		return false, fmt.Errorf("feature id not found: %d", id)
+	}
+	v, err := s.bytes(e.feature)
+	if err != nil {
+		return false, err
+	}
+	inside, err := insideout.FeaturePolygonContainsPoint(v, int(pos), p)
+	if err != nil {
+		return false, fmt.Errorf("can't read loop %d of feature %d: %w", pos, id, err)
+	}
+	return inside, nil
+}
+
+// LoadAllFeatures loads FeatureStorage from DB into idx
+// only useful to fill in memory shapeindex
+func (s *Storage) LoadAllFeatures(add func(*insideout.FeatureStorage, uint32) error) error {
+	if s.w != nil {
+		return errWriteOnly
+	}
+	for i := 0; i < s.features.len(); i++ {
+		e := s.features.entry(i)
+		v, err := s.bytes(e.feature)
+		if err != nil {
+			return err
+		}
+
+		dec := cbor.NewDecoder(bytes.NewReader(v))
+		fs := featureStoragePool.Get().(*insideout.FeatureStorage)
+		fs.Reset()
+		if err := dec.Decode(fs); err != nil {
+			featureStoragePool.Put(fs)
+			return err
+		}
+
+		if err := add(fs, e.id); err != nil {
+			featureStoragePool.Put(fs)
+			return err
+		}
+		featureStoragePool.Put(fs)
+	}
+	return nil
+}
+
+// LoadFeaturesCells loads CellsStorage from DB into idx
+// only useful to fill in memory tree indexes
+func (s *Storage) LoadFeaturesCells(add func([]s2.CellUnion, []s2.CellUnion, uint32)) error {
+	if s.w != nil {
+		return errWriteOnly
+	}
+	for i := 0; i < s.features.len(); i++ {
+		e := s.features.entry(i)
+		cs, err := s.cellStorage(e)
+		if err != nil {
+			return err
+		}
+		add(cs.CellsIn, cs.CellsOut, e.id)
+	}
+	return nil
+}
+
+// LoadMapInfos loads map infos from the DB if any
+func (s *Storage) LoadMapInfos() (*insideout.MapInfos, bool, error) {
+	if s.w != nil {
+		return nil, false, errWriteOnly
+	}
+	if s.h.mapInfos.len == 0 {
+		return nil, false, nil
+	}
+	v, err := s.bytes(s.h.mapInfos)
+	if err != nil {
+		return nil, false, err
+	}
+	mapInfos := &insideout.MapInfos{}
+	dec := cbor.NewDecoder(bytes.NewReader(v))
+	if err := dec.Decode(mapInfos); err != nil {
+		return nil, false, err
+	}
+	return mapInfos, true, nil
+}
+
+// LoadIndexInfos loads index infos from the DB
+func (s *Storage) LoadIndexInfos() (*insideout.IndexInfos, error) {
+	if s.w != nil {
+		return nil, errWriteOnly
+	}
+	if s.h.infos.len == 0 {
+		return nil, errors.New("can't find infos entries, invalid DB")
+	}
+	v, err := s.bytes(s.h.infos)
+	if err != nil {
+		return nil, err
+	}
+	infos := &insideout.IndexInfos{}
+	dec := cbor.NewDecoder(bytes.NewReader(v))
+	if err := dec.Decode(infos); err != nil {
+		return nil, err
+	}
+	return infos, nil
+}
+
+// LoadCellStorage loads cell storage from
+func (s *Storage) LoadCellStorage(id uint32) (*insideout.CellsStorage, error) {
+	if s.w != nil {
+		return nil, errWriteOnly
+	}
+	e, ok := s.features.find(id)
+	if !ok {
+		return nil, fmt.Errorf("feature id not found: %d", id)
+	}
+	return s.cellStorage(e)
+}
+
+func (s *Storage) cellStorage(e featureEntry) (*insideout.CellsStorage, error) {
+	v, err := s.bytes(e.cells)
+	if err != nil {
+		return nil, err
+	}
+	cs := &insideout.CellsStorage{}
+	dec := cbor.NewDecoder(bytes.NewReader(v))
+	if err := dec.Decode(cs); err != nil {
+		return nil, err
+	}
+	return cs, nil
+}
+
+func (s *Storage) StabDB(lat, lng float64, stopOnInsideFound bool) (insideout.IndexResponse, error) {
+	var idxResp insideout.IndexResponse
+	if s.w != nil {
+		return idxResp, errWriteOnly
+	}
+
+	ll := s2.LatLngFromDegrees(lat, lng)
+	p := s2.PointFromLatLng(ll)
+	c := s2.CellIDFromLatLng(ll)
+	cLookup := s2.CellFromPoint(p).ID().Parent(s.minCoverLevel)
+	mi := make(map[insideout.FeatureIndexResponse]struct{})
+
+	stop := uint64(cLookup.RangeMax())
+	for i := s.inside.search(uint64(cLookup.RangeMin())); i < s.inside.len() && s.inside.cellID(i) <= stop; i++ {
+		if !s2.CellID(s.inside.cellID(i)).Contains(c) {
+			continue
+		}
+		v, err := s.bytes(s.inside.value(i))
+		if err != nil {
+			return idxResp, err
+		}
+		// read back the feature id and polygon index uint32 + uint16
+		for j := 0; j+6 <= len(v); j += 4 + 2 {
+			res := insideout.FeatureIndexResponse{}
+			res.ID = binary.BigEndian.Uint32(v[j : j+4])
+			res.Pos = binary.BigEndian.Uint16(v[j+4:])
+			mi[res] = struct{}{}
+			if stopOnInsideFound {
+				idxResp.IDsInside = append(idxResp.IDsInside, res)
+				return idxResp, nil
+			}
+		}
+	}
+
+	// dedup
+	for res := range mi {
+		idxResp.IDsInside = append(idxResp.IDsInside, res)
+	}
+
+	mo := make(map[insideout.FeatureIndexResponse]struct{})
+	for i := s.outside.search(uint64(cLookup.RangeMin())); i < s.outside.len() && s.outside.cellID(i) <= stop; i++ {
+		if !s2.CellID(s.outside.cellID(i)).Contains(c) {
+			continue
+		}
+		v, err := s.bytes(s.outside.value(i))
+		if err != nil {
+			return idxResp, err
+		}
+		// read back the feature id and polygon index uint32 + uint16
+		for j := 0; j+6 <= len(v); j += 4 + 2 {
+			res := insideout.FeatureIndexResponse{}
+			res.ID = binary.BigEndian.Uint32(v[j : j+4])
+			res.Pos = binary.BigEndian.Uint16(v[j+4:])
+			// remove any answer matching inside
+			if _, ok := mi[res]; !ok {
+				mo[res] = struct{}{}
+			}
+		}
+	}
+
+	// dedup
+	for res := range mo {
+		idxResp.IDsMayBeInside = append(idxResp.IDsMayBeInside, res)
+	}
+
+	return idxResp, nil
+}
+
+// IntersectDB returns polygon's ids with an outside cover intersecting cu
+func (s *Storage) IntersectDB(cu s2.CellUnion) ([]insideout.FeatureIndexResponse, error) {
+	if s.w != nil {
+		return nil, errWriteOnly
+	}
+	m := make(map[insideout.FeatureIndexResponse]struct{})
+
+	addValue := func(i int) error {
+		v, err := s.bytes(s.outside.value(i))
+		if err != nil {
+			return err
+		}
+		// read back the feature id and polygon index uint32 + uint16
+		for j := 0; j+6 <= len(v); j += 4 + 2 {
+			res := insideout.FeatureIndexResponse{}
+			res.ID = binary.BigEndian.Uint32(v[j : j+4])
+			res.Pos = binary.BigEndian.Uint16(v[j+4:])
+			m[res] = struct{}{}
+		}
+		return nil
+	}
+
+	for _, c := range cu {
+		// indexed cells containing c
+		for l := s.minCoverLevel; l < c.Level(); l++ {
+			pc := uint64(c.Parent(l))
+			if i := s.outside.search(pc); i < s.outside.len() && s.outside.cellID(i) == pc {
+				if err := addValue(i); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		// indexed cells contained by c
+		stop := uint64(c.RangeMax())
+		for i := s.outside.search(uint64(c.RangeMin())); i < s.outside.len() && s.outside.cellID(i) <= stop; i++ {
+			if err := addValue(i); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	res := make([]insideout.FeatureIndexResponse, 0, len(m))
+	for fres := range m {
+		res = append(res, fres)
+	}
+
+	return res, nil
+}
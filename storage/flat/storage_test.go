@@ -0,0 +1,273 @@
+package flat
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/golang/geo/s2"
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/geojson"
+
+	"github.com/akhenakh/insideout"
+)
+
+func TestStorage_StabDB(t *testing.T) {
+	storage, clean := setup(t)
+	defer clean()
+
+	tests := []struct {
+		name     string
+		lat, lng float64
+		want     insideout.IndexResponse
+		wantErr  bool
+	}{
+		{"inside loop not within inside index",
+			47.39444367083928, -2.992874768945723,
+			insideout.IndexResponse{
+				IDsInside: nil,
+				IDsMayBeInside: []insideout.FeatureIndexResponse{insideout.FeatureIndexResponse{
+					ID:  0,
+					Pos: 1,
+				}},
+			},
+			false,
+		},
+		{"inside loop within inside index",
+			47.39650628189986, -2.9876390969486524,
+			insideout.IndexResponse{
+				IDsInside: []insideout.FeatureIndexResponse{insideout.FeatureIndexResponse{
+					ID:  0,
+					Pos: 1,
+				}},
+				IDsMayBeInside: nil,
+			},
+			false,
+		},
+		{"outside loop outside outside index",
+			47.37616957736262, -3.004367209321472,
+			insideout.IndexResponse{
+				IDsInside:      nil,
+				IDsMayBeInside: nil,
+			},
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := storage.StabDB(tt.lat, tt.lng, true)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("StabDB() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !cmp.Equal(got, tt.want) {
+				t.Errorf("StabDB() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	f, err := storage.LoadFeature(0)
+	require.NoError(t, err)
+	require.Len(t, f.Loops, 3)
+
+	// the loops tested in place agree with the decoded ones
+	for _, ll := range []s2.LatLng{
+		s2.LatLngFromDegrees(47.3944602327291, -2.9924373872714556),
+		s2.LatLngFromDegrees(47.38297924900667, -2.961873380366456),
+	} {
+		p := s2.PointFromLatLng(ll)
+		for pos, l := range f.Loops {
+			inside, err := storage.LoopContainsPoint(0, uint16(pos), p)
+			require.NoError(t, err)
+			require.Equal(t, l.ContainsPoint(p), inside)
+		}
+	}
+	_, err = storage.LoopContainsPoint(0, 3, s2.PointFromLatLng(s2.LatLngFromDegrees(0, 0)))
+	require.Error(t, err)
+
+	// 5km around a point outside
+	coverer := &s2.RegionCoverer{MaxLevel: 20, MaxCells: 16}
+	p := s2.PointFromLatLng(s2.LatLngFromDegrees(47.37616957736262, -3.004367209321472))
+	fids, err := storage.IntersectDB(coverer.Covering(s2.CapFromCenterAngle(p, insideout.MetersToAngle(5000))))
+	require.NoError(t, err)
+	require.Contains(t, fids, insideout.FeatureIndexResponse{ID: 0, Pos: 1})
+
+	fids, err = storage.IntersectDB(coverer.Covering(s2.CapFromCenterAngle(p, insideout.MetersToAngle(10))))
+	require.NoError(t, err)
+	require.Empty(t, fids)
+
+	infos, err := storage.LoadIndexInfos()
+	require.NoError(t, err)
+	require.Equal(t, "poly.geojson", infos.Filename)
+}
+
+func TestStorage_LoadAll(t *testing.T) {
+	storage, clean := setup(t)
+	defer clean()
+
+	var ids []uint32
+	err := storage.LoadAllFeatures(func(fs *insideout.FeatureStorage, id uint32) error {
+		require.Len(t, fs.LoopsBytes, 3)
+		ids = append(ids, id)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []uint32{0}, ids)
+
+	var count int
+	err = storage.LoadFeaturesCells(func(cui []s2.CellUnion, cuo []s2.CellUnion, id uint32) {
+		require.Len(t, cui, 3)
+		require.Len(t, cuo, 3)
+		count++
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+
+	cs, err := storage.LoadCellStorage(0)
+	require.NoError(t, err)
+	require.Len(t, cs.CellsOut, 3)
+
+	_, err = storage.LoadFeature(1)
+	require.Error(t, err)
+
+	_, ok, err := storage.LoadMapInfos()
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestNewROStorage_Invalid(t *testing.T) {
+	logger := log.NewNopLogger()
+
+	tmpFile, err := ioutil.TempFile(os.TempDir(), "insideout-test-")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+	_, err = tmpFile.Write(make([]byte, 2*headerSize))
+	require.NoError(t, err)
+	tmpFile.Close()
+
+	_, _, err = NewROStorage(tmpFile.Name(), logger)
+	require.Error(t, err)
+
+	// truncated file
+	storage, clean := setup(t)
+	defer clean()
+	require.NoError(t, ioutil.WriteFile(tmpFile.Name(), storage.data[:len(storage.data)-10], 0600))
+	_, _, err = NewROStorage(tmpFile.Name(), logger)
+	require.Error(t, err)
+
+	_, err = NewROStorageFromBytes(storage.data[:headerSize-1], logger)
+	require.Error(t, err)
+}
+
+func TestNewROStorageFromBytes(t *testing.T) {
+	storage, clean := setup(t)
+	defer clean()
+
+	data := append([]byte(nil), storage.data...)
+	bstorage, err := NewROStorageFromBytes(data, log.NewNopLogger())
+	require.NoError(t, err)
+
+	want, err := storage.StabDB(47.39444367083928, -2.992874768945723, false)
+	require.NoError(t, err)
+	got, err := bstorage.StabDB(47.39444367083928, -2.992874768945723, false)
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+	require.NotEmpty(t, got.IDsMayBeInside)
+}
+
+func TestStorage_Replace(t *testing.T) {
+	logger := log.NewNopLogger()
+
+	tmpDir, err := ioutil.TempDir(os.TempDir(), "insideout-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+	path := filepath.Join(tmpDir, "inside.flat")
+
+	wstorage, wclose, err := NewStorage(path, logger)
+	require.NoError(t, err)
+
+	icoverer := &s2.RegionCoverer{MinLevel: 10, MaxLevel: 16, MaxCells: 24}
+	ocoverer := &s2.RegionCoverer{MinLevel: 10, MaxLevel: 15, MaxCells: 16}
+
+	square := func(lng, lat float64) *geojson.Feature {
+		return &geojson.Feature{
+			Geometry: geom.NewPolygonFlat(geom.XY, []float64{
+				lng, lat, lng + 0.1, lat, lng + 0.1, lat + 0.1, lng, lat + 0.1, lng, lat,
+			}, []int{10}),
+			Properties: map[string]interface{}{"name": "square"},
+		}
+	}
+	_, err = wstorage.IndexFeature(square(2, 48), 0, icoverer, ocoverer, 100)
+	require.NoError(t, err)
+	_, err = wstorage.IndexFeature(square(3, 49), 0, icoverer, ocoverer, 100)
+	require.NoError(t, err)
+	require.NoError(t, wstorage.writeInfos(1, 10, icoverer, ocoverer, "squares", "unittest"))
+	require.Error(t, wstorage.Append(nil, "", icoverer, ocoverer, 100, "", ""))
+	require.NoError(t, wclose())
+
+	storage, close, err := NewROStorage(path, logger)
+	require.NoError(t, err)
+	defer close()
+
+	resp, err := storage.StabDB(48.05, 2.05, false)
+	require.NoError(t, err)
+	require.Empty(t, append(resp.IDsInside, resp.IDsMayBeInside...))
+
+	resp, err = storage.StabDB(49.05, 3.05, false)
+	require.NoError(t, err)
+	require.Len(t, append(resp.IDsInside, resp.IDsMayBeInside...), 1)
+}
+
+func setup(t *testing.T) (*Storage, func()) {
+	logger := log.NewNopLogger()
+
+	tmpDir, err := ioutil.TempDir(os.TempDir(), "insideout-test-")
+	require.NoError(t, err)
+	path := filepath.Join(tmpDir, "inside.flat")
+
+	wstorage, wclose, err := NewStorage(path, logger)
+	require.NoError(t, err)
+
+	var fc geojson.FeatureCollection
+
+	file, err := os.Open("../../index/testdata/poly.geojson")
+	require.NoError(t, err)
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+	err = decoder.Decode(&fc)
+	require.NoError(t, err)
+
+	icoverer := &s2.RegionCoverer{
+		MinLevel: 10,
+		MaxLevel: 16,
+		MaxCells: 24,
+	}
+	ocoverer := &s2.RegionCoverer{
+		MinLevel: 10,
+		MaxLevel: 15,
+		MaxCells: 16,
+	}
+
+	err = wstorage.Index(fc, icoverer, ocoverer, 100, "poly.geojson", "unittest")
+	require.NoError(t, err)
+
+	err = wclose()
+	require.NoError(t, err)
+
+	// RO storage
+	storage, close, err := NewROStorage(path, logger)
+	require.NoError(t, err)
+
+	return storage, func() {
+		close()
+		os.RemoveAll(tmpDir)
+	}
+}
@@ -0,0 +1,411 @@
+package flat
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/fxamacker/cbor"
+	log "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/golang/geo/s2"
+	"github.com/twpayne/go-geom/encoding/geojson"
+
+	"github.com/akhenakh/insideout"
+)
+
+// writer holds the index while it is built, features are spooled to a temporary file,
+// cells are kept in memory until the file is written on close
+type writer struct {
+	path  string
+	spool *os.File
+	size  uint64
+
+	inside   map[s2.CellID][]byte
+	outside  map[s2.CellID][]byte
+	features map[uint32]featureEntry
+	infos    []byte
+}
+
+// NewStorage returns a storage writing a flat index at path,
+// the file is written when the returned function is called after a successful indexation
+func NewStorage(path string, logger log.Logger) (*Storage, func() error, error) {
+	spool, err := ioutil.TempFile(filepath.Dir(path), ".insideout-flat-")
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't create temporary file: %w", err)
+	}
+
+	s := &Storage{
+		logger: logger,
+		w: &writer{
+			path:     path,
+			spool:    spool,
+			inside:   make(map[s2.CellID][]byte),
+			outside:  make(map[s2.CellID][]byte),
+			features: make(map[uint32]featureEntry),
+		},
+	}
+
+	return s, s.close, nil
+}
+
+func (s *Storage) Index(fc geojson.FeatureCollection, icoverer *s2.RegionCoverer, ocoverer *s2.RegionCoverer,
+	warningCellsCover int, fileName, version string) error {
+	return s.IndexReader(insideout.NewFeatureCollectionReader(&fc), icoverer, ocoverer, warningCellsCover, fileName, version)
+}
+
+// IndexReader indexes all the features read from r, one at a time
+func (s *Storage) IndexReader(r insideout.FeatureReader, icoverer *s2.RegionCoverer, ocoverer *s2.RegionCoverer,
+	warningCellsCover int, fileName, version string) error {
+	if s.w == nil {
+		return errReadOnly
+	}
+
+	var count uint32
+	for {
+		f, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("can't read feature: %w", err)
+		}
+
+		indexed, err := s.IndexFeature(f, count, icoverer, ocoverer, warningCellsCover)
+		if err != nil {
+			return err
+		}
+		if !indexed {
+			continue
+		}
+
+		count++
+	}
+
+	return s.writeInfos(count, s.LowestCoverLevel(icoverer, ocoverer), icoverer, ocoverer, fileName, version)
+}
+
+// Append is not supported, a flat index is written once
+func (s *Storage) Append(r insideout.FeatureReader, idProperty string, icoverer *s2.RegionCoverer, ocoverer *s2.RegionCoverer,
+	warningCellsCover int, fileName, version string) error {
+	return errors.New("can't append to a flat index, index all the files again")
+}
+
+// IndexFeature covers and stores f with id, replacing a previously stored feature with the same id,
+// returns false when the feature can't be covered
+func (s *Storage) IndexFeature(f *geojson.Feature, id uint32, icoverer *s2.RegionCoverer, ocoverer *s2.RegionCoverer,
+	warningCellsCover int) (bool, error) {
+	if s.w == nil {
+		return false, errReadOnly
+	}
+	logger := log.With(s.logger, "component", "indexer")
+
+	icoverer, ocoverer = s.FeatureCoverers(f, icoverer, ocoverer)
+
+	// cover inside
+	cui, err := insideout.GeoJSONCoverCellUnion(f, icoverer, true)
+	if err != nil {
+		level.Warn(logger).Log("msg", "error covering inside", "error", err, "feature_properties", f.Properties)
+		return false, nil
+	}
+
+	// cover outside
+	cuo, err := insideout.GeoJSONCoverCellUnion(f, ocoverer, false)
+	if err != nil {
+		level.Warn(logger).Log("msg", "error covering outside", "error", err, "feature_properties", f.Properties)
+		return false, nil
+	}
+
+	if err := s.removeFeatureCells(id); err != nil {
+		return false, fmt.Errorf("can't remove previous cells of feature %d: %w", id, err)
+	}
+
+	add := func(cells map[s2.CellID][]byte, cu s2.CellUnion, fi int) {
+		for _, c := range cu {
+			// value is the feature id, the polygon index in a multipolygon: fi
+			v := make([]byte, 6)
+			binary.BigEndian.PutUint32(v, id)
+			binary.BigEndian.PutUint16(v[4:], uint16(fi))
+			cells[c] = append(v, cells[c]...)
+		}
+	}
+
+	var cells int
+
+	// store interior cover
+	for fi, cu := range cui {
+		if warningCellsCover != 0 && len(cu) > warningCellsCover {
+			level.Warn(logger).Log(
+				"msg", fmt.Sprintf("inside cover too big %d cells, not indexing polygon #%d %s", len(cui), fi, f.Properties),
+				"feature_properties", f.Properties,
+			)
+			continue
+		}
+		cells += len(cu)
+		add(s.w.inside, cu, fi)
+	}
+
+	// store outside cover
+	for fi, cu := range cuo {
+		if warningCellsCover != 0 && len(cu) > warningCellsCover {
+			level.Warn(logger).Log(
+				"msg", fmt.Sprintf("outisde cover too big %d not indexing polygon #%d %s", len(cui), fi, f.Properties),
+				"feature_properties", f.Properties,
+			)
+			continue
+		}
+		cells += len(cu)
+		add(s.w.outside, cu, fi)
+	}
+
+	// store feature
+	if err := s.writeFeature(f, id, cui, cuo); err != nil {
+		return false, fmt.Errorf("can't store featrure into DB: %w", err)
+	}
+
+	s.progress.Add(1, cells)
+
+	return true, nil
+}
+
+// removeFeatureCells removes the feature id from the inside and outside cells it was indexed in
+func (s *Storage) removeFeatureCells(id uint32) error {
+	e, ok := s.w.features[id]
+	if !ok {
+		return nil
+	}
+
+	b := make([]byte, e.cells.len)
+	if _, err := s.w.spool.ReadAt(b, int64(e.cells.off)); err != nil {
+		return err
+	}
+	cs := &insideout.CellsStorage{}
+	dec := cbor.NewDecoder(bytes.NewReader(b))
+	if err := dec.Decode(cs); err != nil {
+		return err
+	}
+
+	remove := func(cells map[s2.CellID][]byte, cus []s2.CellUnion) {
+		for _, cu := range cus {
+			for _, c := range cu {
+				nv := insideout.RemoveIDFromCellValue(cells[c], id)
+				if len(nv) == 0 {
+					delete(cells, c)
+					continue
+				}
+				cells[c] = nv
+			}
+		}
+	}
+	remove(s.w.inside, cs.CellsIn)
+	remove(s.w.outside, cs.CellsOut)
+
+	delete(s.w.features, id)
+	return nil
+}
+
+func (s *Storage) writeFeature(f *geojson.Feature, id uint32, cui, cuo []s2.CellUnion) error {
+	// store feature
+	lb, err := s.EncodeLoops(f)
+	if err != nil {
+		return fmt.Errorf("can't encode loop: %w", err)
+	}
+	hb, err := s.EncodeHoles(f)
+	if err != nil {
+		return fmt.Errorf("can't encode holes: %w", err)
+	}
+
+	b := new(bytes.Buffer)
+	enc := cbor.NewEncoder(b, cbor.CanonicalEncOptions())
+
+	fs := &insideout.FeatureStorage{Properties: f.Properties, LoopsBytes: lb, HolesBytes: hb}
+	if err := enc.Encode(fs); err != nil {
+		return fmt.Errorf("can't encode FeatureStorage: %w", err)
+	}
+	e := featureEntry{id: id, feature: section{off: s.w.size, len: uint64(b.Len())}}
+
+	// store cells for tree
+	cs := &insideout.CellsStorage{
+		CellsIn:  cui,
+		CellsOut: cuo,
+	}
+	if err := enc.Encode(cs); err != nil {
+		return fmt.Errorf("can't encode CellsStorage: %w", err)
+	}
+	e.cells = section{off: e.feature.off + e.feature.len, len: uint64(b.Len()) - e.feature.len}
+
+	if _, err := s.w.spool.Write(b.Bytes()); err != nil {
+		return fmt.Errorf("failed store feature into temporary file: %w", err)
+	}
+	s.w.size += uint64(b.Len())
+	s.w.features[id] = e
+
+	level.Debug(s.logger).Log(
+		"msg", "stored FeatureStorage",
+		"feature_properties", f.Properties,
+		"loop_count", len(fs.LoopsBytes),
+		"inside_loop_id", id,
+	)
+
+	return nil
+}
+
+func (s *Storage) writeInfos(fcount uint32, minCoverLevel int, icoverer, ocoverer *s2.RegionCoverer,
+	fileName, version string) error {
+	infoBytes := new(bytes.Buffer)
+
+	// the outside covers contain the inside ones
+	cells := make(s2.CellUnion, 0, len(s.w.outside))
+	for c := range s.w.outside {
+		cells = append(cells, c)
+	}
+
+	infos := &insideout.IndexInfos{
+		Filename:       fileName,
+		IndexTime:      time.Now(),
+		IndexerVersion: version,
+		FeatureCount:   fcount,
+		MinCoverLevel:  minCoverLevel,
+		InsideCover:    insideout.NewCoverOptions(icoverer),
+		OutsideCover:   insideout.NewCoverOptions(ocoverer),
+		AutoCover:      s.AutoCovered(),
+		LoopEncoding:   s.LoopEncoding(),
+		Coverage:       insideout.ExtendCoverage(nil, cells),
+	}
+
+	enc := cbor.NewEncoder(infoBytes, cbor.CanonicalEncOptions())
+	if err := enc.Encode(infos); err != nil {
+		return fmt.Errorf("failed encoding IndexInfos: %w", err)
+	}
+	s.w.infos = infoBytes.Bytes()
+
+	return nil
+}
+
+// close writes the flat file if the indexation completed, and removes the temporary file
+func (s *Storage) close() error {
+	if s.w == nil {
+		return nil
+	}
+	defer os.Remove(s.w.spool.Name())
+	defer s.w.spool.Close()
+
+	if s.w.infos == nil {
+		level.Warn(s.logger).Log("msg", "indexation not completed, not writing flat index", "path", s.w.path)
+		return nil
+	}
+
+	tmpPath := s.w.path + ".tmp"
+	if err := s.writeFile(tmpPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("can't write flat index: %w", err)
+	}
+	return os.Rename(tmpPath, s.w.path)
+}
+
+func (s *Storage) writeFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	bw := bufio.NewWriterSize(f, 1<<20)
+	var h header
+	var off uint64
+	write := func(b []byte) (section, error) {
+		sec := section{off: off, len: uint64(len(b))}
+		if _, err := bw.Write(b); err != nil {
+			return sec, err
+		}
+		off += sec.len
+		return sec, nil
+	}
+
+	// placeholder, the header is written last
+	if _, err := write(make([]byte, headerSize)); err != nil {
+		return err
+	}
+
+	if h.infos, err = write(s.w.infos); err != nil {
+		return err
+	}
+	h.mapInfos = section{off: off}
+
+	writeCells := func(cells map[s2.CellID][]byte) (section, error) {
+		ids := make([]s2.CellID, 0, len(cells))
+		for c := range cells {
+			ids = append(ids, c)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+		table := make([]byte, 0, len(ids)*cellEntrySize)
+		for _, c := range ids {
+			v, err := write(cells[c])
+			if err != nil {
+				return section{}, err
+			}
+			table = cellEntry{cellID: uint64(c), value: v}.encode(table)
+		}
+		return write(table)
+	}
+	if h.inside, err = writeCells(s.w.inside); err != nil {
+		return err
+	}
+	if h.outside, err = writeCells(s.w.outside); err != nil {
+		return err
+	}
+
+	// copy the spooled features
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	if _, err := s.w.spool.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	base := off
+	if _, err := io.Copy(f, s.w.spool); err != nil {
+		return err
+	}
+	off += s.w.size
+
+	ids := make([]uint32, 0, len(s.w.features))
+	for id := range s.w.features {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	table := make([]byte, 0, len(ids)*featureEntrySize)
+	for _, id := range ids {
+		e := s.w.features[id]
+		e.feature.off += base
+		e.cells.off += base
+		table = e.encode(table)
+	}
+	if h.features, err = write(table); err != nil {
+		return err
+	}
+
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	if _, err := f.WriteAt(h.encode(), 0); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// SetProgress sets the counters updated while indexing
+func (s *Storage) SetProgress(p *insideout.Progress) {
+	s.progress = p
+}
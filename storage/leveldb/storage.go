@@ -0,0 +1,785 @@
+package leveldb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/fxamacker/cbor"
+	log "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/golang/geo/s2"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
+	"github.com/twpayne/go-geom/encoding/geojson"
+
+	"github.com/akhenakh/insideout"
+)
+
+var (
+	featureStoragePool = sync.Pool{
+		New: func() interface{} {
+			return &insideout.FeatureStorage{}
+		},
+	}
+)
+
+// Storage cold storage
+type Storage struct {
+	*leveldb.DB
+	logger        log.Logger
+	minCoverLevel int
+
+	// indexing only
+	insideout.AutoCover
+	insideout.LoopEncoder
+	progress *insideout.Progress
+}
+
+// NewStorage returns a cold storage using leveldb
+func NewStorage(path string, logger log.Logger) (*Storage, func() error, error) {
+	// Creating DB
+	db, err := leveldb.OpenFile(path, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &Storage{
+		DB:     db,
+		logger: logger,
+	}, db.Close, nil
+}
+
+// NewROStorage returns a read only storage using leveldb
+func NewROStorage(path string, logger log.Logger) (*Storage, func() error, error) {
+	db, err := leveldb.OpenFile(path, &opt.Options{ReadOnly: true, ErrorIfMissing: true})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open DB for reading at %s: %w", path, err)
+	}
+
+	s := &Storage{
+		DB:     db,
+		logger: logger,
+	}
+
+	infos, err := s.LoadIndexInfos()
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	s.minCoverLevel = infos.MinCoverLevel
+
+	return s, db.Close, nil
+}
+
+// LoadFeature loads one feature from the DB
+func (s *Storage) LoadFeature(id uint32) (*insideout.Feature, error) {
+	v, err := s.Get(insideout.FeatureKey(id), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, fmt.Errorf("feature id not found: %d", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	fs := &insideout.FeatureStorage{}
+	dec := cbor.NewDecoder(bytes.NewReader(v))
+	if err := dec.Decode(fs); err != nil {
+		return nil, err
+	}
+
+	loops := make([]*s2.Loop, len(fs.LoopsBytes))
+	for i := 0; i < len(loops); i++ {
+		l, err := insideout.DecodeLoop(fs.LoopsBytes[i])
+		if err != nil {
+			return nil, err
+		}
+		loops[i] = l
+	}
+	holes, err := insideout.DecodeHoles(fs.HolesBytes)
+	if err != nil {
+		return nil, err
+	}
+	f := &insideout.Feature{
+		Loops:      loops,
+		Holes:      holes,
+		Properties: fs.Properties,
+	}
+
+	return f, nil
+}
+
+// LoadAllFeatures loads FeatureStorage from DB into idx
+// only useful to fill in memory shapeindex
+func (s *Storage) LoadAllFeatures(add func(*insideout.FeatureStorage, uint32) error) error {
+	iter := s.NewIterator(util.BytesPrefix([]byte{insideout.FeaturePrefix()}), nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		id := binary.BigEndian.Uint32(iter.Key()[1:])
+
+		dec := cbor.NewDecoder(bytes.NewReader(iter.Value()))
+		fs := featureStoragePool.Get().(*insideout.FeatureStorage)
+		fs.Reset()
+		if err := dec.Decode(fs); err != nil {
+			featureStoragePool.Put(fs)
+			return err
+		}
+
+		if err := add(fs, id); err != nil {
+			featureStoragePool.Put(fs)
+			return err
+		}
+		featureStoragePool.Put(fs)
+	}
+
+	return iter.Error()
+}
+
+// LoadFeaturesCells loads CellsStorage from DB into idx
+// only useful to fill in memory tree indexes
+func (s *Storage) LoadFeaturesCells(add func([]s2.CellUnion, []s2.CellUnion, uint32)) error {
+	iter := s.NewIterator(util.BytesPrefix([]byte{insideout.CellPrefix()}), nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		// read back FeatureStorage
+		id := binary.BigEndian.Uint32(iter.Key()[1:])
+		dec := cbor.NewDecoder(bytes.NewReader(iter.Value()))
+		cs := &insideout.CellsStorage{}
+		if err := dec.Decode(cs); err != nil {
+			return err
+		}
+
+		add(cs.CellsIn, cs.CellsOut, id)
+	}
+
+	return iter.Error()
+}
+
+// LoadMapInfos loads map infos from the DB if any
+func (s *Storage) LoadMapInfos() (*insideout.MapInfos, bool, error) {
+	value, err := s.Get(insideout.MapKey(), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	mapInfos := &insideout.MapInfos{}
+	dec := cbor.NewDecoder(bytes.NewReader(value))
+	if err := dec.Decode(mapInfos); err != nil {
+		return nil, false, err
+	}
+
+	return mapInfos, true, nil
+}
+
+// LoadHierarchy loads the parent id of each contained feature, nil when it was never stored
+func (s *Storage) LoadHierarchy() (map[uint32]uint32, error) {
+	value, err := s.Get(insideout.HierarchyKey(), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't load hierarchy: %w", err)
+	}
+
+	var parents map[uint32]uint32
+	dec := cbor.NewDecoder(bytes.NewReader(value))
+	if err := dec.Decode(&parents); err != nil {
+		return nil, fmt.Errorf("can't load hierarchy: %w", err)
+	}
+	return parents, nil
+}
+
+// StoreHierarchy stores the parent id of each contained feature
+func (s *Storage) StoreHierarchy(parents map[uint32]uint32) error {
+	value := new(bytes.Buffer)
+	enc := cbor.NewEncoder(value, cbor.CanonicalEncOptions())
+	if err := enc.Encode(parents); err != nil {
+		return fmt.Errorf("failed encoding hierarchy: %w", err)
+	}
+	if err := s.Put(insideout.HierarchyKey(), value.Bytes(), nil); err != nil {
+		return fmt.Errorf("can't store hierarchy: %w", err)
+	}
+	return nil
+}
+
+// LoadH3Cover loads the H3 cover of the polygons, nil when it was never stored
+func (s *Storage) LoadH3Cover() (*insideout.H3Cover, error) {
+	value, err := s.Get(insideout.H3Key(), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't load H3 cover: %w", err)
+	}
+
+	var cover *insideout.H3Cover
+	dec := cbor.NewDecoder(bytes.NewReader(value))
+	if err := dec.Decode(&cover); err != nil {
+		return nil, fmt.Errorf("can't load H3 cover: %w", err)
+	}
+	return cover, nil
+}
+
+// StoreH3Cover stores the H3 cover of the polygons
+func (s *Storage) StoreH3Cover(cover *insideout.H3Cover) error {
+	value := new(bytes.Buffer)
+	enc := cbor.NewEncoder(value, cbor.CanonicalEncOptions())
+	if err := enc.Encode(cover); err != nil {
+		return fmt.Errorf("failed encoding H3 cover: %w", err)
+	}
+	if err := s.Put(insideout.H3Key(), value.Bytes(), nil); err != nil {
+		return fmt.Errorf("can't store H3 cover: %w", err)
+	}
+	return nil
+}
+
+// LoadCellFilter loads the filter of the cells, nil when it was never stored
+func (s *Storage) LoadCellFilter() (*insideout.CellFilter, error) {
+	value, err := s.Get(insideout.CellFilterKey(), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't load cell filter: %w", err)
+	}
+
+	var filter *insideout.CellFilter
+	dec := cbor.NewDecoder(bytes.NewReader(value))
+	if err := dec.Decode(&filter); err != nil {
+		return nil, fmt.Errorf("can't load cell filter: %w", err)
+	}
+	return filter, nil
+}
+
+// StoreCellFilter stores the filter of the cells
+func (s *Storage) StoreCellFilter(filter *insideout.CellFilter) error {
+	value := new(bytes.Buffer)
+	enc := cbor.NewEncoder(value, cbor.CanonicalEncOptions())
+	if err := enc.Encode(filter); err != nil {
+		return fmt.Errorf("failed encoding cell filter: %w", err)
+	}
+	if err := s.Put(insideout.CellFilterKey(), value.Bytes(), nil); err != nil {
+		return fmt.Errorf("can't store cell filter: %w", err)
+	}
+	return nil
+}
+
+// LoadPropertyIndex loads the index of the properties, nil when it was never stored
+func (s *Storage) LoadPropertyIndex() (*insideout.PropertyIndex, error) {
+	value, err := s.Get(insideout.PropertiesKey(), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't load property index: %w", err)
+	}
+
+	var pi *insideout.PropertyIndex
+	dec := cbor.NewDecoder(bytes.NewReader(value))
+	if err := dec.Decode(&pi); err != nil {
+		return nil, fmt.Errorf("can't load property index: %w", err)
+	}
+	return pi, nil
+}
+
+// StorePropertyIndex stores the index of the properties
+func (s *Storage) StorePropertyIndex(pi *insideout.PropertyIndex) error {
+	value := new(bytes.Buffer)
+	enc := cbor.NewEncoder(value, cbor.CanonicalEncOptions())
+	if err := enc.Encode(pi); err != nil {
+		return fmt.Errorf("failed encoding property index: %w", err)
+	}
+	if err := s.Put(insideout.PropertiesKey(), value.Bytes(), nil); err != nil {
+		return fmt.Errorf("can't store property index: %w", err)
+	}
+	return nil
+}
+
+// LoadIndexInfos loads index infos from the DB
+func (s *Storage) LoadIndexInfos() (*insideout.IndexInfos, error) {
+	infos := &insideout.IndexInfos{}
+
+	value, err := s.Get(insideout.InfoKey(), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, errors.New("can't find infos entries, invalid DB")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	dec := cbor.NewDecoder(bytes.NewReader(value))
+	if err := dec.Decode(infos); err != nil {
+		return nil, err
+	}
+
+	return infos, nil
+}
+
+// LoadCellStorage loads cell storage from
+func (s *Storage) LoadCellStorage(id uint32) (*insideout.CellsStorage, error) {
+	// get the s2 cells from the index
+	cs := &insideout.CellsStorage{}
+
+	v, err := s.Get(insideout.CellKey(id), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := cbor.NewDecoder(bytes.NewReader(v))
+	if err := dec.Decode(cs); err != nil {
+		return nil, err
+	}
+
+	return cs, nil
+}
+
+func (s *Storage) StabDB(lat, lng float64, stopOnInsideFound bool) (insideout.IndexResponse, error) {
+	var idxResp insideout.IndexResponse
+
+	ll := s2.LatLngFromDegrees(lat, lng)
+	p := s2.PointFromLatLng(ll)
+	c := s2.CellIDFromLatLng(ll)
+	cLookup := s2.CellFromPoint(p).ID().Parent(s.minCoverLevel)
+	mi := make(map[insideout.FeatureIndexResponse]struct{})
+
+	startKey, stopKey := insideout.InsideRangeKeys(cLookup)
+	iter := s.NewIterator(nil, nil)
+	defer iter.Release()
+
+	for ok := iter.Seek(startKey); ok && bytes.Compare(iter.Key(), stopKey) <= 0; ok = iter.Next() {
+		cr := s2.CellID(binary.BigEndian.Uint64(iter.Key()[1:]))
+		if !cr.Contains(c) {
+			continue
+		}
+		v := iter.Value()
+		// read back the feature id and polygon index uint32 + uint16
+		for i := 0; i < len(v); i += 4 + 2 {
+			res := insideout.FeatureIndexResponse{}
+			res.ID = binary.BigEndian.Uint32(v[i : i+4])
+			res.Pos = binary.BigEndian.Uint16(v[i+4:])
+			mi[res] = struct{}{}
+			if stopOnInsideFound {
+				idxResp.IDsInside = append(idxResp.IDsInside, res)
+				return idxResp, nil
+			}
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return idxResp, err
+	}
+
+	// dedup
+	for res := range mi {
+		idxResp.IDsInside = append(idxResp.IDsInside, res)
+	}
+
+	startKey, stopKey = insideout.OutsideRangeKeys(cLookup)
+	mo := make(map[insideout.FeatureIndexResponse]struct{})
+
+	for ok := iter.Seek(startKey); ok && bytes.Compare(iter.Key(), stopKey) <= 0; ok = iter.Next() {
+		cr := s2.CellID(binary.BigEndian.Uint64(iter.Key()[1:]))
+		if !cr.Contains(c) {
+			continue
+		}
+		v := iter.Value()
+		// read back the feature id and polygon index uint32 + uint16
+		for i := 0; i < len(v); i += 4 + 2 {
+			res := insideout.FeatureIndexResponse{}
+			res.ID = binary.BigEndian.Uint32(v[i : i+4])
+			res.Pos = binary.BigEndian.Uint16(v[i+4:])
+			// remove any answer matching inside
+			if _, ok := mi[res]; !ok {
+				mo[res] = struct{}{}
+			}
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return idxResp, err
+	}
+
+	// dedup
+	for res := range mo {
+		idxResp.IDsMayBeInside = append(idxResp.IDsMayBeInside, res)
+	}
+
+	return idxResp, nil
+}
+
+// IntersectDB returns polygon's ids with an outside cover intersecting cu
+func (s *Storage) IntersectDB(cu s2.CellUnion) ([]insideout.FeatureIndexResponse, error) {
+	m := make(map[insideout.FeatureIndexResponse]struct{})
+
+	addValue := func(v []byte) {
+		// read back the feature id and polygon index uint32 + uint16
+		for i := 0; i < len(v); i += 4 + 2 {
+			res := insideout.FeatureIndexResponse{}
+			res.ID = binary.BigEndian.Uint32(v[i : i+4])
+			res.Pos = binary.BigEndian.Uint16(v[i+4:])
+			m[res] = struct{}{}
+		}
+	}
+
+	iter := s.NewIterator(nil, nil)
+	defer iter.Release()
+
+	for _, c := range cu {
+		// indexed cells containing c
+		for l := s.minCoverLevel; l < c.Level(); l++ {
+			v, err := s.Get(insideout.OutsideKey(c.Parent(l)), nil)
+			if err == leveldb.ErrNotFound {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			addValue(v)
+		}
+
+		// indexed cells contained by c
+		startKey, stopKey := insideout.OutsideRangeKeys(c)
+		for ok := iter.Seek(startKey); ok && bytes.Compare(iter.Key(), stopKey) <= 0; ok = iter.Next() {
+			addValue(iter.Value())
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return nil, err
+	}
+
+	res := make([]insideout.FeatureIndexResponse, 0, len(m))
+	for fres := range m {
+		res = append(res, fres)
+	}
+
+	return res, nil
+}
+
+func (s *Storage) Index(fc geojson.FeatureCollection, icoverer *s2.RegionCoverer, ocoverer *s2.RegionCoverer,
+	warningCellsCover int, fileName, version string) error {
+	return s.IndexReader(insideout.NewFeatureCollectionReader(&fc), icoverer, ocoverer, warningCellsCover, fileName, version)
+}
+
+// IndexReader indexes all the features read from r, one at a time
+func (s *Storage) IndexReader(r insideout.FeatureReader, icoverer *s2.RegionCoverer, ocoverer *s2.RegionCoverer,
+	warningCellsCover int, fileName, version string) error {
+	var count uint32
+
+	for {
+		f, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("can't read feature: %w", err)
+		}
+
+		indexed, err := s.IndexFeature(f, count, icoverer, ocoverer, warningCellsCover)
+		if err != nil {
+			return err
+		}
+		if !indexed {
+			continue
+		}
+
+		count++
+	}
+
+	return s.writeInfos(count, s.LowestCoverLevel(icoverer, ocoverer), icoverer, ocoverer, fileName, version)
+}
+
+// Append indexes the features read from r into an existing DB,
+// features with the same idProperty value as a stored feature replace it
+func (s *Storage) Append(r insideout.FeatureReader, idProperty string, icoverer *s2.RegionCoverer, ocoverer *s2.RegionCoverer,
+	warningCellsCover int, fileName, version string) error {
+	infos, err := s.LoadIndexInfos()
+	if err != nil {
+		return fmt.Errorf("can't append to DB: %w", err)
+	}
+	if err := infos.CheckCoverers(icoverer, ocoverer, s.AutoCovered()); err != nil {
+		return fmt.Errorf("can't append to DB: %w", err)
+	}
+	if err := infos.CheckLoopEncoding(s.LoopEncoding()); err != nil {
+		return fmt.Errorf("can't append to DB: %w", err)
+	}
+
+	count, err := insideout.AppendFeatures(s, r, infos.FeatureCount, idProperty, icoverer, ocoverer, warningCellsCover)
+	if err != nil {
+		return err
+	}
+
+	// the existing cells may use a lower level
+	minCoverLevel := s.LowestCoverLevel(icoverer, ocoverer)
+	if infos.MinCoverLevel < minCoverLevel {
+		minCoverLevel = infos.MinCoverLevel
+	}
+
+	return s.writeInfos(count, minCoverLevel, icoverer, ocoverer, infos.Filename+","+fileName, version)
+}
+
+// IndexFeature covers and stores f with id, replacing a previously stored feature with the same id,
+// returns false when the feature can't be covered
+func (s *Storage) IndexFeature(f *geojson.Feature, id uint32, icoverer *s2.RegionCoverer, ocoverer *s2.RegionCoverer,
+	warningCellsCover int) (bool, error) {
+	logger := log.With(s.logger, "component", "indexer")
+
+	icoverer, ocoverer = s.FeatureCoverers(f, icoverer, ocoverer)
+
+	// cover inside
+	cui, err := insideout.GeoJSONCoverCellUnion(f, icoverer, true)
+	if err != nil {
+		level.Warn(logger).Log("msg", "error covering inside", "error", err, "feature_properties", f.Properties)
+		return false, nil
+	}
+
+	// cover outside
+	cuo, err := insideout.GeoJSONCoverCellUnion(f, ocoverer, false)
+	if err != nil {
+		level.Warn(logger).Log("msg", "error covering outside", "error", err, "feature_properties", f.Properties)
+		return false, nil
+	}
+
+	batch := newCellBatch(s.DB)
+	if err := s.removeFeatureCells(batch, id); err != nil {
+		return false, fmt.Errorf("can't remove previous cells of feature %d: %w", id, err)
+	}
+
+	var cells int
+
+	// store interior cover
+	for fi, cu := range cui {
+		if warningCellsCover != 0 && len(cu) > warningCellsCover {
+			level.Warn(logger).Log(
+				"msg", fmt.Sprintf("inside cover too big %d cells, not indexing polygon #%d %s", len(cui), fi, f.Properties),
+				"feature_properties", f.Properties,
+			)
+
+			continue
+		}
+		cells += len(cu)
+		for _, c := range cu {
+			if err := batch.append(insideout.InsideKey(c), id, uint16(fi)); err != nil {
+				return false, fmt.Errorf("failed set inside cover into DB: %w", err)
+			}
+		}
+	}
+
+	// store outside cover
+	for fi, cu := range cuo {
+		if warningCellsCover != 0 && len(cu) > warningCellsCover {
+			level.Warn(logger).Log(
+				"msg", fmt.Sprintf("outisde cover too big %d not indexing polygon #%d %s", len(cui), fi, f.Properties),
+				"feature_properties", f.Properties,
+			)
+			continue
+		}
+		cells += len(cu)
+		for _, c := range cu {
+			if err := batch.append(insideout.OutsideKey(c), id, uint16(fi)); err != nil {
+				return false, fmt.Errorf("failed set outside cover into DB: %w", err)
+			}
+		}
+	}
+
+	// store feature
+	if err := s.writeFeature(batch.Batch, f, id, cui, cuo); err != nil {
+		return false, fmt.Errorf("can't store featrure into DB: %w", err)
+	}
+
+	if err := s.Write(batch.Batch, nil); err != nil {
+		return false, fmt.Errorf("failed store feature into DB: %w", err)
+	}
+
+	s.progress.Add(1, cells)
+
+	return true, nil
+}
+
+// removeFeatureCells removes the feature id from the inside and outside cells it was indexed in
+func (s *Storage) removeFeatureCells(batch *cellBatch, id uint32) error {
+	v, err := s.Get(insideout.CellKey(id), nil)
+	if err == leveldb.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	cs := &insideout.CellsStorage{}
+	dec := cbor.NewDecoder(bytes.NewReader(v))
+	if err := dec.Decode(cs); err != nil {
+		return err
+	}
+
+	for _, cu := range cs.CellsIn {
+		for _, c := range cu {
+			if err := batch.remove(insideout.InsideKey(c), id); err != nil {
+				return err
+			}
+		}
+	}
+	for _, cu := range cs.CellsOut {
+		for _, c := range cu {
+			if err := batch.remove(insideout.OutsideKey(c), id); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// cellBatch accumulates cells values for one feature,
+// since a cell can be shared by multiple loops of the same feature,
+// pending values are looked up before the DB
+type cellBatch struct {
+	*leveldb.Batch
+	db      *leveldb.DB
+	pending map[string][]byte
+}
+
+func newCellBatch(db *leveldb.DB) *cellBatch {
+	return &cellBatch{
+		Batch:   new(leveldb.Batch),
+		db:      db,
+		pending: make(map[string][]byte),
+	}
+}
+
+// append adds the feature id and the polygon index to the existing value of key if any
+func (b *cellBatch) append(key []byte, id uint32, pos uint16) error {
+	// value is the feature id, the polygon index in a multipolygon: fi
+	v := make([]byte, 6)
+	binary.BigEndian.PutUint32(v, id)
+	binary.BigEndian.PutUint16(v[4:], pos)
+
+	ev, ok := b.pending[string(key)]
+	if !ok {
+		var err error
+		ev, err = b.db.Get(key, nil)
+		if err != nil && err != leveldb.ErrNotFound {
+			return err
+		}
+	}
+	v = append(v, ev...)
+
+	b.pending[string(key)] = v
+	b.Put(key, v)
+
+	return nil
+}
+
+// remove removes the entries of the feature id from the existing value of key if any
+func (b *cellBatch) remove(key []byte, id uint32) error {
+	ev, ok := b.pending[string(key)]
+	if !ok {
+		var err error
+		ev, err = b.db.Get(key, nil)
+		if err == leveldb.ErrNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	v := insideout.RemoveIDFromCellValue(ev, id)
+	b.pending[string(key)] = v
+	if len(v) == 0 {
+		b.Delete(key)
+		return nil
+	}
+	b.Put(key, v)
+
+	return nil
+}
+
+func (s *Storage) writeFeature(batch *leveldb.Batch, f *geojson.Feature, id uint32, cui, cuo []s2.CellUnion) error {
+	// store feature
+	lb, err := s.EncodeLoops(f)
+	if err != nil {
+		return fmt.Errorf("can't encode loop: %w", err)
+	}
+	hb, err := s.EncodeHoles(f)
+	if err != nil {
+		return fmt.Errorf("can't encode holes: %w", err)
+	}
+
+	b := new(bytes.Buffer)
+	enc := cbor.NewEncoder(b, cbor.CanonicalEncOptions())
+
+	fs := &insideout.FeatureStorage{Properties: f.Properties, LoopsBytes: lb, HolesBytes: hb}
+	if err := enc.Encode(fs); err != nil {
+		return fmt.Errorf("can't encode FeatureStorage: %w", err)
+	}
+	batch.Put(insideout.FeatureKey(id), b.Bytes())
+
+	// store cells for tree
+	b = new(bytes.Buffer)
+	enc = cbor.NewEncoder(b, cbor.CanonicalEncOptions())
+	cs := &insideout.CellsStorage{
+		CellsIn:  cui,
+		CellsOut: cuo,
+	}
+
+	if err := enc.Encode(cs); err != nil {
+		return fmt.Errorf("can't encode CellsStorage: %w", err)
+	}
+	batch.Put(insideout.CellKey(id), b.Bytes())
+
+	level.Debug(s.logger).Log(
+		"msg", "stored FeatureStorage",
+		"feature_properties", f.Properties,
+		"loop_count", len(fs.LoopsBytes),
+		"inside_loop_id", id,
+	)
+
+	return nil
+}
+
+func (s *Storage) writeInfos(fcount uint32, minCoverLevel int, icoverer, ocoverer *s2.RegionCoverer,
+	fileName, version string) error {
+	coverage, err := insideout.ComputeCoverage(s)
+	if err != nil {
+		return err
+	}
+
+	infoBytes := new(bytes.Buffer)
+
+	infos := &insideout.IndexInfos{
+		Filename:       fileName,
+		IndexTime:      time.Now(),
+		IndexerVersion: version,
+		FeatureCount:   fcount,
+		MinCoverLevel:  minCoverLevel,
+		InsideCover:    insideout.NewCoverOptions(icoverer),
+		OutsideCover:   insideout.NewCoverOptions(ocoverer),
+		AutoCover:      s.AutoCovered(),
+		LoopEncoding:   s.LoopEncoding(),
+		Coverage:       coverage,
+	}
+
+	enc := cbor.NewEncoder(infoBytes, cbor.CanonicalEncOptions())
+	if err := enc.Encode(infos); err != nil {
+		return fmt.Errorf("failed encoding IndexInfos: %w", err)
+	}
+
+	if err := s.Put(insideout.InfoKey(), infoBytes.Bytes(), nil); err != nil {
+		return fmt.Errorf("failed encoding IndexInfos: %w", err)
+	}
+
+	return nil
+}
+
+// SetProgress sets the counters updated while indexing
+func (s *Storage) SetProgress(p *insideout.Progress) {
+	s.progress = p
+}
@@ -0,0 +1,264 @@
+package leveldb
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/golang/geo/s2"
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/geojson"
+
+	"github.com/akhenakh/insideout"
+)
+
+func TestStorage_StabDB(t *testing.T) {
+	storage, clean := setup(t)
+	defer clean()
+
+	tests := []struct {
+		name     string
+		lat, lng float64
+		want     insideout.IndexResponse
+		wantErr  bool
+	}{
+		{"inside loop not within inside index",
+			47.39444367083928, -2.992874768945723,
+			insideout.IndexResponse{
+				IDsInside: nil,
+				IDsMayBeInside: []insideout.FeatureIndexResponse{insideout.FeatureIndexResponse{
+					ID:  0,
+					Pos: 1,
+				}},
+			},
+			false,
+		},
+		{"inside loop within inside index",
+			47.39650628189986, -2.9876390969486524,
+			insideout.IndexResponse{
+				IDsInside: []insideout.FeatureIndexResponse{insideout.FeatureIndexResponse{
+					ID:  0,
+					Pos: 1,
+				}},
+				IDsMayBeInside: nil,
+			},
+			false,
+		},
+		{"outside loop outside outside index",
+			47.37616957736262, -3.004367209321472,
+			insideout.IndexResponse{
+				IDsInside:      nil,
+				IDsMayBeInside: nil,
+			},
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := storage.StabDB(tt.lat, tt.lng, true)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("StabDB() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !cmp.Equal(got, tt.want) {
+				t.Errorf("StabDB() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	f, err := storage.LoadFeature(0)
+	require.NoError(t, err)
+	require.Len(t, f.Loops, 3)
+
+	// 5km around a point outside
+	coverer := &s2.RegionCoverer{MaxLevel: 20, MaxCells: 16}
+	p := s2.PointFromLatLng(s2.LatLngFromDegrees(47.37616957736262, -3.004367209321472))
+	fids, err := storage.IntersectDB(coverer.Covering(s2.CapFromCenterAngle(p, insideout.MetersToAngle(5000))))
+	require.NoError(t, err)
+	require.Contains(t, fids, insideout.FeatureIndexResponse{ID: 0, Pos: 1})
+
+	fids, err = storage.IntersectDB(coverer.Covering(s2.CapFromCenterAngle(p, insideout.MetersToAngle(10))))
+	require.NoError(t, err)
+	require.Empty(t, fids)
+
+	infos, err := storage.LoadIndexInfos()
+	require.NoError(t, err)
+	require.Equal(t, "poly.geojson", infos.Filename)
+}
+
+func setup(t *testing.T) (*Storage, func()) {
+	logger := log.NewNopLogger()
+
+	tmpDir, err := ioutil.TempDir(os.TempDir(), "insideout-test-")
+	require.NoError(t, err)
+	wstorage, wclose, err := NewStorage(tmpDir, logger)
+	require.NoError(t, err)
+
+	var fc geojson.FeatureCollection
+
+	file, err := os.Open("../../index/testdata/poly.geojson")
+	require.NoError(t, err)
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+	err = decoder.Decode(&fc)
+	require.NoError(t, err)
+
+	icoverer := &s2.RegionCoverer{
+		MinLevel: 10,
+		MaxLevel: 16,
+		MaxCells: 24,
+	}
+	ocoverer := &s2.RegionCoverer{
+		MinLevel: 10,
+		MaxLevel: 15,
+		MaxCells: 16,
+	}
+
+	err = wstorage.Index(fc, icoverer, ocoverer, 100, "poly.geojson", "unittest")
+	require.NoError(t, err)
+
+	err = wclose()
+	require.NoError(t, err)
+
+	// RO storage
+	storage, close, err := NewROStorage(tmpDir, logger)
+	require.NoError(t, err)
+
+	return storage, func() {
+		close()
+		os.RemoveAll(tmpDir)
+	}
+}
+
+func TestStorage_Append(t *testing.T) {
+	logger := log.NewNopLogger()
+
+	tmpDir, err := ioutil.TempDir(os.TempDir(), "insideout-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	storage, close, err := NewStorage(tmpDir, logger)
+	require.NoError(t, err)
+	defer close()
+
+	icoverer := &s2.RegionCoverer{MinLevel: 10, MaxLevel: 16, MaxCells: 24}
+	ocoverer := &s2.RegionCoverer{MinLevel: 10, MaxLevel: 15, MaxCells: 16}
+
+	var fc geojson.FeatureCollection
+	file, err := os.Open("../../index/testdata/poly.geojson")
+	require.NoError(t, err)
+	defer file.Close()
+	require.NoError(t, json.NewDecoder(file).Decode(&fc))
+	require.NoError(t, storage.Index(fc, icoverer, ocoverer, 100, "poly.geojson", "unittest"))
+
+	square := func(insee string, lng, lat float64) *geojson.Feature {
+		return &geojson.Feature{
+			Geometry: geom.NewPolygonFlat(geom.XY, []float64{
+				lng, lat, lng + 0.1, lat, lng + 0.1, lat + 0.1, lng, lat + 0.1, lng, lat,
+			}, []int{10}),
+			Properties: map[string]interface{}{"insee": insee},
+		}
+	}
+
+	// replacing Houat and adding a new feature
+	afc := &geojson.FeatureCollection{Features: []*geojson.Feature{
+		square("56086", 2, 48),
+		square("75056", 3, 49),
+	}}
+	err = storage.Append(insideout.NewFeatureCollectionReader(afc), "insee", icoverer, ocoverer, 100, "update.geojson", "unittest")
+	require.NoError(t, err)
+
+	ids := func(lat, lng float64) []uint32 {
+		resp, err := storage.StabDB(lat, lng, false)
+		require.NoError(t, err)
+		var res []uint32
+		for _, fres := range append(resp.IDsInside, resp.IDsMayBeInside...) {
+			res = append(res, fres.ID)
+		}
+		return res
+	}
+
+	require.Empty(t, ids(47.39650628189986, -2.9876390969486524))
+	require.Equal(t, []uint32{0}, ids(48.05, 2.05))
+	require.Equal(t, []uint32{1}, ids(49.05, 3.05))
+
+	f, err := storage.LoadFeature(0)
+	require.NoError(t, err)
+	require.Len(t, f.Loops, 1)
+
+	infos, err := storage.LoadIndexInfos()
+	require.NoError(t, err)
+	require.Equal(t, uint32(2), infos.FeatureCount)
+	require.Equal(t, "poly.geojson,update.geojson", infos.Filename)
+	require.Equal(t, &insideout.CoverOptions{MinLevel: 10, MaxLevel: 16, MaxCells: 24, LevelMod: 1}, infos.InsideCover)
+	require.NoError(t, infos.Validate())
+
+	// appending with other cover parameters
+	icoverer.MaxLevel = 18
+	err = storage.Append(insideout.NewFeatureCollectionReader(afc), "insee", icoverer, ocoverer, 100, "update.geojson", "unittest")
+	require.Error(t, err)
+}
+
+func TestStorage_AutoCover(t *testing.T) {
+	logger := log.NewNopLogger()
+
+	tmpDir, err := ioutil.TempDir(os.TempDir(), "insideout-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	wstorage, wclose, err := NewStorage(tmpDir, logger)
+	require.NoError(t, err)
+	wstorage.SetAutoCover(true)
+
+	icoverer := &s2.RegionCoverer{MinLevel: 10, MaxLevel: 16, MaxCells: 24}
+	ocoverer := &s2.RegionCoverer{MinLevel: 10, MaxLevel: 15, MaxCells: 16}
+
+	square := func(lng, lat, size float64) *geojson.Feature {
+		return &geojson.Feature{
+			Geometry: geom.NewPolygonFlat(geom.XY, []float64{
+				lng, lat, lng + size, lat, lng + size, lat + size, lng, lat + size, lng, lat,
+			}, []int{10}),
+		}
+	}
+	fc := geojson.FeatureCollection{Features: []*geojson.Feature{
+		square(2, 48, 0.001),
+		square(-30, 30, 20),
+	}}
+	require.NoError(t, wstorage.Index(fc, icoverer, ocoverer, 100, "squares.geojson", "unittest"))
+	require.NoError(t, wclose())
+
+	storage, close, err := NewROStorage(tmpDir, logger)
+	require.NoError(t, err)
+	defer close()
+
+	infos, err := storage.LoadIndexInfos()
+	require.NoError(t, err)
+	require.True(t, infos.AutoCover)
+	require.Less(t, infos.MinCoverLevel, 10)
+	require.NoError(t, infos.Validate())
+
+	ids := func(lat, lng float64) []uint32 {
+		resp, err := storage.StabDB(lat, lng, false)
+		require.NoError(t, err)
+		var res []uint32
+		for _, fres := range append(resp.IDsInside, resp.IDsMayBeInside...) {
+			res = append(res, fres.ID)
+		}
+		return res
+	}
+	require.Equal(t, []uint32{0}, ids(48.0005, 2.0005))
+	require.Equal(t, []uint32{1}, ids(40, -20))
+	require.Empty(t, ids(47, 2))
+
+	// the small square uses deeper cells
+	cs, err := storage.LoadCellStorage(0)
+	require.NoError(t, err)
+	require.Greater(t, cs.CellsOut[0][0].Level(), 15)
+}
@@ -0,0 +1,363 @@
+// Package postgis is a read only storage querying the polygons of an existing PostGIS table,
+// points are tested with ST_Contains in the database, no S2 index is needed
+package postgis
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/golang/geo/s2"
+	"github.com/lib/pq"
+	"github.com/twpayne/go-geom/encoding/geojson"
+	"github.com/twpayne/go-geom/encoding/wkb"
+
+	"github.com/akhenakh/insideout"
+)
+
+var errReadOnly = errors.New("postgis storage is read only")
+
+// Options for the PostGIS storage
+type Options struct {
+	// Table holding the polygons, can be schema qualified
+	Table string
+
+	// IDColumn an integer column identifying the features, defaults to id
+	IDColumn string
+
+	// GeomColumn the Polygon or MultiPolygon geometry column, defaults to geom,
+	// the other columns are returned as properties
+	GeomColumn string
+
+	// MaxConns the max number of open connections to the database, 0 for no limit
+	MaxConns int
+
+	// ConnMaxLifetime the max duration a connection is reused, 0 to reuse forever
+	ConnMaxLifetime time.Duration
+}
+
+// Storage PostGIS storage
+type Storage struct {
+	logger log.Logger
+	db     *sql.DB
+	opts   Options
+	infos  *insideout.IndexInfos
+
+	stabStmt      *sql.Stmt
+	stabFirstStmt *sql.Stmt
+	featureStmt   *sql.Stmt
+	intersectStmt *sql.Stmt
+}
+
+// NewROStorage returns a read only storage querying the table in opts on the database at connString,
+// connString is a lib/pq URL or key value connection string
+func NewROStorage(connString string, opts Options, logger log.Logger) (*Storage, func() error, error) {
+	if opts.Table == "" {
+		return nil, nil, errors.New("missing PostGIS table")
+	}
+	if opts.IDColumn == "" {
+		opts.IDColumn = "id"
+	}
+	if opts.GeomColumn == "" {
+		opts.GeomColumn = "geom"
+	}
+
+	db, err := sql.Open("postgres", connString)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open PostGIS connection: %w", err)
+	}
+	db.SetMaxOpenConns(opts.MaxConns)
+	if opts.MaxConns > 0 {
+		db.SetMaxIdleConns(opts.MaxConns)
+	}
+	db.SetConnMaxLifetime(opts.ConnMaxLifetime)
+
+	s := &Storage{
+		logger: logger,
+		db:     db,
+		opts:   opts,
+	}
+
+	if err := s.prepare(); err != nil {
+		s.close()
+		return nil, nil, err
+	}
+
+	return s, s.close, nil
+}
+
+// prepare reads the table infos and prepares the queries
+func (s *Storage) prepare() error {
+	table := quoteTable(s.opts.Table)
+	id := pq.QuoteIdentifier(s.opts.IDColumn)
+	g := pq.QuoteIdentifier(s.opts.GeomColumn)
+
+	var count uint32
+	var srid sql.NullInt64
+	err := s.db.QueryRow(fmt.Sprintf("SELECT count(*), max(ST_SRID(%s)) FROM %s", g, table)).Scan(&count, &srid)
+	if err != nil {
+		return fmt.Errorf("failed to query PostGIS table %s: %w", s.opts.Table, err)
+	}
+	if !srid.Valid || srid.Int64 == 0 {
+		srid.Int64 = 4326
+	}
+
+	var version string
+	if err := s.db.QueryRow("SELECT postgis_lib_version()").Scan(&version); err != nil {
+		return fmt.Errorf("PostGIS is not available: %w", err)
+	}
+
+	s.infos = &insideout.IndexInfos{
+		Filename:       s.opts.Table,
+		IndexTime:      time.Now(),
+		IndexerVersion: "postgis " + version,
+		FeatureCount:   count,
+	}
+
+	// the point is transformed once into the table SRID so the spatial index can be used,
+	// the polygon index in a multipolygon is the ST_Dump path, 0 for a polygon
+	point := fmt.Sprintf("ST_Transform(ST_SetSRID(ST_MakePoint($1, $2), 4326), %d)", srid.Int64)
+	stab := fmt.Sprintf(`SELECT t.%[1]s, COALESCE(d.path[1], 1) - 1
+		FROM %[3]s t, ST_Dump(t.%[2]s) d
+		WHERE ST_Contains(t.%[2]s, %[4]s) AND ST_Contains(d.geom, %[4]s)`, id, g, table, point)
+	envelope := fmt.Sprintf("ST_Transform(ST_MakeEnvelope($1, $2, $3, $4, 4326), %d)", srid.Int64)
+
+	queries := []struct {
+		stmt  **sql.Stmt
+		query string
+	}{
+		{&s.stabStmt, stab},
+		{&s.stabFirstStmt, stab + " LIMIT 1"},
+		{&s.featureStmt, fmt.Sprintf(`SELECT ST_AsBinary(ST_Force2D(ST_Transform(t.%[2]s, 4326))), to_jsonb(t) - %[4]s - %[5]s
+			FROM %[3]s t WHERE t.%[1]s = $1`, id, g, table, pq.QuoteLiteral(s.opts.IDColumn), pq.QuoteLiteral(s.opts.GeomColumn))},
+		{&s.intersectStmt, fmt.Sprintf(`SELECT t.%[1]s, COALESCE(d.path[1], 1) - 1
+			FROM %[3]s t, ST_Dump(t.%[2]s) d
+			WHERE t.%[2]s && %[4]s AND ST_Intersects(d.geom, %[4]s)`, id, g, table, envelope)},
+	}
+	for _, q := range queries {
+		stmt, err := s.db.Prepare(q.query)
+		if err != nil {
+			return fmt.Errorf("failed to prepare PostGIS query: %w", err)
+		}
+		*q.stmt = stmt
+	}
+
+	return nil
+}
+
+func (s *Storage) close() error {
+	for _, stmt := range []*sql.Stmt{s.stabStmt, s.stabFirstStmt, s.featureStmt, s.intersectStmt} {
+		if stmt != nil {
+			stmt.Close()
+		}
+	}
+	return s.db.Close()
+}
+
+// Ping checks the database is reachable
+func (s *Storage) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// quoteTable quotes a table name, optionally schema qualified
+func quoteTable(table string) string {
+	parts := strings.Split(table, ".")
+	for i, p := range parts {
+		parts[i] = pq.QuoteIdentifier(p)
+	}
+	return strings.Join(parts, ".")
+}
+
+// LoadFeature loads one feature from the DB
+func (s *Storage) LoadFeature(id uint32) (*insideout.Feature, error) {
+	return s.LoadFeatureContext(context.Background(), id)
+}
+
+// LoadFeatureContext loads one feature from the DB, the query is canceled once ctx is done
+func (s *Storage) LoadFeatureContext(ctx context.Context, id uint32) (*insideout.Feature, error) {
+	var b, props []byte
+	err := s.featureStmt.QueryRowContext(ctx, int64(id)).Scan(&b, &props)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("feature id not found: %d", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query feature %d: %w", id, err)
+	}
+
+	return decodeFeature(b, props)
+}
+
+// decodeFeature returns a feature from its WKB geometry and JSON properties
+func decodeFeature(b, props []byte) (*insideout.Feature, error) {
+	g, err := wkb.Unmarshal(b)
+	if err != nil {
+		return nil, fmt.Errorf("can't decode geometry: %w", err)
+	}
+
+	f := &insideout.Feature{}
+	if f.Loops, f.Holes, err = insideout.PolygonLoops(g); err != nil {
+		return nil, err
+	}
+
+	if len(props) > 0 {
+		if err := json.Unmarshal(props, &f.Properties); err != nil {
+			return nil, fmt.Errorf("can't decode properties: %w", err)
+		}
+	}
+
+	return f, nil
+}
+
+// LoadAllFeatures loads all the features from the table
+// only useful to fill in memory shapeindex
+func (s *Storage) LoadAllFeatures(add func(*insideout.FeatureStorage, uint32) error) error {
+	rows, err := s.db.Query(fmt.Sprintf("SELECT %s FROM %s", pq.QuoteIdentifier(s.opts.IDColumn), quoteTable(s.opts.Table)))
+	if err != nil {
+		return fmt.Errorf("failed to query features: %w", err)
+	}
+	var ids []uint32
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, uint32(id))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		f, err := s.LoadFeature(id)
+		if err != nil {
+			return err
+		}
+		fs := &insideout.FeatureStorage{
+			Properties: f.Properties,
+			LoopsBytes: make([][]byte, len(f.Loops)),
+		}
+		for i, l := range f.Loops {
+			lb := new(bytes.Buffer)
+			if err := l.Encode(lb); err != nil {
+				return fmt.Errorf("can't encode loop: %w", err)
+			}
+			fs.LoopsBytes[i] = lb.Bytes()
+		}
+		for i, hs := range f.Holes {
+			if fs.HolesBytes == nil {
+				fs.HolesBytes = make([][][]byte, len(f.Holes))
+			}
+			for _, h := range hs {
+				hb := new(bytes.Buffer)
+				if err := h.Encode(hb); err != nil {
+					return fmt.Errorf("can't encode hole: %w", err)
+				}
+				fs.HolesBytes[i] = append(fs.HolesBytes[i], hb.Bytes())
+			}
+		}
+		if err := add(fs, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadFeaturesCells is not supported, the features are not covered with cells
+func (s *Storage) LoadFeaturesCells(add func([]s2.CellUnion, []s2.CellUnion, uint32)) error {
+	return errors.New("postgis storage has no cells, use the postgis strategy")
+}
+
+// LoadCellStorage is not supported, the features are not covered with cells
+func (s *Storage) LoadCellStorage(id uint32) (*insideout.CellsStorage, error) {
+	return nil, errors.New("postgis storage has no cells")
+}
+
+// LoadMapInfos returns no map infos
+func (s *Storage) LoadMapInfos() (*insideout.MapInfos, bool, error) {
+	return nil, false, nil
+}
+
+// LoadIndexInfos returns infos about the table, read when the storage was opened
+func (s *Storage) LoadIndexInfos() (*insideout.IndexInfos, error) {
+	return s.infos, nil
+}
+
+// StabDB returns the polygons containing lat lng, the test is exact so all of them are inside
+func (s *Storage) StabDB(lat, lng float64, stopOnInsideFound bool) (insideout.IndexResponse, error) {
+	return s.StabDBContext(context.Background(), lat, lng, stopOnInsideFound)
+}
+
+// StabDBContext returns the polygons containing lat lng, the query is canceled once ctx is done
+func (s *Storage) StabDBContext(ctx context.Context, lat, lng float64, stopOnInsideFound bool) (
+	insideout.IndexResponse, error) {
+	var idxResp insideout.IndexResponse
+
+	stmt := s.stabStmt
+	if stopOnInsideFound {
+		stmt = s.stabFirstStmt
+	}
+
+	res, err := s.queryIDs(ctx, stmt, lng, lat)
+	if err != nil {
+		return idxResp, err
+	}
+	idxResp.IDsInside = res
+
+	return idxResp, nil
+}
+
+// IntersectDB returns polygon's ids intersecting the bounding rectangle of cu
+func (s *Storage) IntersectDB(cu s2.CellUnion) ([]insideout.FeatureIndexResponse, error) {
+	if len(cu) == 0 {
+		return nil, nil
+	}
+	r := cu.RectBound()
+	return s.queryIDs(context.Background(), s.intersectStmt, r.Lo().Lng.Degrees(), r.Lo().Lat.Degrees(), r.Hi().Lng.Degrees(), r.Hi().Lat.Degrees())
+}
+
+func (s *Storage) queryIDs(ctx context.Context, stmt *sql.Stmt, args ...interface{}) ([]insideout.FeatureIndexResponse, error) {
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query PostGIS: %w", err)
+	}
+	defer rows.Close()
+
+	var res []insideout.FeatureIndexResponse
+	for rows.Next() {
+		var id int64
+		var pos int
+		if err := rows.Scan(&id, &pos); err != nil {
+			return nil, err
+		}
+		res = append(res, insideout.FeatureIndexResponse{ID: uint32(id), Pos: uint16(pos)})
+	}
+	return res, rows.Err()
+}
+
+func (s *Storage) Index(fc geojson.FeatureCollection, icoverer *s2.RegionCoverer, ocoverer *s2.RegionCoverer,
+	warningCellsCover int, fileName, version string) error {
+	return errReadOnly
+}
+
+func (s *Storage) IndexReader(r insideout.FeatureReader, icoverer *s2.RegionCoverer, ocoverer *s2.RegionCoverer,
+	warningCellsCover int, fileName, version string) error {
+	return errReadOnly
+}
+
+func (s *Storage) IndexFeature(f *geojson.Feature, id uint32, icoverer *s2.RegionCoverer, ocoverer *s2.RegionCoverer,
+	warningCellsCover int) (bool, error) {
+	return false, errReadOnly
+}
+
+func (s *Storage) Append(r insideout.FeatureReader, idProperty string, icoverer *s2.RegionCoverer, ocoverer *s2.RegionCoverer,
+	warningCellsCover int, fileName, version string) error {
+	return errReadOnly
+}
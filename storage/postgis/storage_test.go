@@ -0,0 +1,52 @@
+package postgis
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/wkb"
+)
+
+func TestDecodeFeature(t *testing.T) {
+	mp := geom.NewMultiPolygonFlat(geom.XY, []float64{
+		2, 48, 3, 48, 3, 49, 2, 49, 2, 48,
+		4, 48, 5, 48, 5, 49, 4, 49, 4, 48,
+	}, [][]int{{10}, {20}})
+	b, err := wkb.Marshal(mp, binary.LittleEndian)
+	require.NoError(t, err)
+
+	f, err := decodeFeature(b, []byte(`{"name": "squares", "population": 42}`))
+	require.NoError(t, err)
+	require.Len(t, f.Loops, 2)
+	require.Equal(t, "squares", f.Properties["name"])
+	require.Equal(t, float64(42), f.Properties["population"])
+
+	require.True(t, f.Loops[0].ContainsPoint(s2.PointFromLatLng(s2.LatLngFromDegrees(48.5, 2.5))))
+	require.False(t, f.Loops[0].ContainsPoint(s2.PointFromLatLng(s2.LatLngFromDegrees(48.5, 4.5))))
+	require.True(t, f.Loops[1].ContainsPoint(s2.PointFromLatLng(s2.LatLngFromDegrees(48.5, 4.5))))
+
+	// no properties
+	p := geom.NewPolygonFlat(geom.XY, []float64{2, 48, 3, 48, 3, 49, 2, 48}, []int{8})
+	b, err = wkb.Marshal(p, binary.LittleEndian)
+	require.NoError(t, err)
+	f, err = decodeFeature(b, nil)
+	require.NoError(t, err)
+	require.Len(t, f.Loops, 1)
+	require.Nil(t, f.Properties)
+
+	// not a polygon
+	pt := geom.NewPointFlat(geom.XY, []float64{2, 48})
+	b, err = wkb.Marshal(pt, binary.LittleEndian)
+	require.NoError(t, err)
+	_, err = decodeFeature(b, nil)
+	require.Error(t, err)
+}
+
+func TestQuoteTable(t *testing.T) {
+	require.Equal(t, `"communes"`, quoteTable("communes"))
+	require.Equal(t, `"geo"."communes"`, quoteTable("geo.communes"))
+	require.Equal(t, `"bad""name"`, quoteTable(`bad"name`))
+}
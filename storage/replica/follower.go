@@ -0,0 +1,247 @@
+package replica
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// Options for a Follower
+type Options struct {
+	// Source the URL prefix of the DB files and their checksums, the /replica/ URL of the leader
+	// or an object store bucket served over HTTP
+	Source string
+
+	// Interval between the synchronizations of Run, 1m when 0
+	Interval time.Duration
+
+	// Client the HTTP client, http.DefaultClient when nil
+	Client *http.Client
+}
+
+// Follower fetches the DB files changed at the source
+type Follower struct {
+	opts   Options
+	sums   checksums
+	logger log.Logger
+}
+
+// NewFollower returns a Follower of the source of opts
+func NewFollower(opts Options, logger log.Logger) *Follower {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Minute
+	}
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	return &Follower{
+		opts:   opts,
+		logger: log.With(logger, "component", "replica"),
+	}
+}
+
+// Run synchronizes the DB files at paths every interval until ctx is done,
+// swap is called with the replaced paths to serve them, the failures are retried at the next interval
+func (f *Follower) Run(ctx context.Context, paths []string, swap func(paths []string) error) error {
+	ticker := time.NewTicker(f.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		replaced, err := f.Sync(ctx, paths)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			level.Error(f.logger).Log("msg", "replication failed, retrying at the next interval", "error", err)
+		}
+		if len(replaced) == 0 {
+			continue
+		}
+		if err := swap(replaced); err != nil {
+			errorsCounter.Inc()
+			level.Error(f.logger).Log("msg", "failed to serve the replicated DBs", "error", err)
+		}
+	}
+}
+
+// Sync fetches the DB files at paths changed at the source, returns the replaced paths,
+// the DBs are synchronized one after the other, a failure leaves the remaining ones untouched
+func (f *Follower) Sync(ctx context.Context, paths []string) ([]string, error) {
+	var replaced []string
+	for _, p := range paths {
+		ok, err := f.Fetch(ctx, p)
+		if err != nil {
+			errorsCounter.Inc()
+			return replaced, fmt.Errorf("failed to replicate %s: %w", p, err)
+		}
+		if ok {
+			replaced = append(replaced, p)
+		}
+	}
+	lastSyncGauge.SetToCurrentTime()
+	return replaced, nil
+}
+
+// Fetch replaces the DB file dst by the file of the source named like it when their checksums differ,
+// returns true when dst was replaced.
+// The DB is downloaded next to dst, an interrupted download of the same checksum is resumed,
+// then its checksum is verified before it's renamed to dst.
+func (f *Follower) Fetch(ctx context.Context, dst string) (bool, error) {
+	name := filepath.Base(dst)
+	u := strings.TrimSuffix(f.opts.Source, "/") + "/" + url.PathEscape(name)
+
+	sum, err := f.remoteSum(ctx, u+ChecksumExt)
+	if err != nil {
+		return false, err
+	}
+	local, err := f.sums.fileSum(dst)
+	if err != nil {
+		return false, err
+	}
+	if sum == local {
+		return false, nil
+	}
+
+	part := filepath.Join(filepath.Dir(dst), fmt.Sprintf(".%s.%s.part", name, sum[:16]))
+	if err := removeStaleParts(dst, part); err != nil {
+		return false, err
+	}
+
+	size, resumed, err := f.download(ctx, u, part)
+	if err != nil {
+		return false, err
+	}
+
+	got, err := hashFile(part)
+	if err != nil {
+		return false, err
+	}
+	if got != sum {
+		os.Remove(part)
+		return false, fmt.Errorf("checksum mismatch for %s: got %s expected %s", u, got, sum)
+	}
+
+	// the DB served keeps its opened file until it's swapped
+	if err := os.Rename(part, dst); err != nil {
+		return false, err
+	}
+	fetchedCounter.Inc()
+	level.Info(f.logger).Log("msg", "replicated DB", "path", dst, "sha256", sum, "size", size, "resumed_at", resumed)
+	return true, nil
+}
+
+// remoteSum returns the checksum at u
+func (f *Follower) remoteSum(ctx context.Context, u string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := f.opts.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("can't get checksum %s: %s", u, resp.Status)
+	}
+	return parseChecksum(resp.Body)
+}
+
+// download appends the missing part of the file at u to part, returns the size of part
+// and the offset the download resumed at
+func (f *Follower) download(ctx context.Context, u, part string) (int64, int64, error) {
+	file, err := os.OpenFile(part, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+
+	offset, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+	}
+	resp, err := f.opts.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		if !strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", offset)) {
+			return 0, 0, fmt.Errorf("unexpected range %q from %s", resp.Header.Get("Content-Range"), u)
+		}
+	case resp.StatusCode == http.StatusOK:
+		// the source does not support the range requests
+		offset = 0
+		if err := file.Truncate(0); err != nil {
+			return 0, 0, err
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return 0, 0, err
+		}
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		// larger than the source, restarted at the next sync
+		os.Remove(part)
+		return 0, 0, fmt.Errorf("can't resume %s at %d: %s", u, offset, resp.Status)
+	default:
+		return 0, 0, fmt.Errorf("can't get %s: %s", u, resp.Status)
+	}
+
+	n, err := io.Copy(file, resp.Body)
+	fetchedBytesCounter.Add(float64(n))
+	if err != nil {
+		// kept to be resumed
+		return 0, 0, err
+	}
+	if err := file.Sync(); err != nil {
+		return 0, 0, err
+	}
+	return offset + n, offset, file.Close()
+}
+
+// removeStaleParts removes the downloads of dst other than part, of the previous checksums
+func removeStaleParts(dst, part string) error {
+	parts, err := filepath.Glob(filepath.Join(filepath.Dir(dst), "."+escapeGlob(filepath.Base(dst))+".*.part"))
+	if err != nil {
+		return err
+	}
+	for _, p := range parts {
+		if p == part {
+			continue
+		}
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// escapeGlob escapes the glob meta characters of name
+func escapeGlob(name string) string {
+	return strings.NewReplacer(`*`, `\*`, `?`, `\?`, `[`, `\[`, `\`, `\\`).Replace(name)
+}
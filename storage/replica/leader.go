@@ -0,0 +1,76 @@
+package replica
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// Leader serves the DB files and their checksums to the followers, by their file names
+type Leader struct {
+	// lookup returns the path of the DB file name, false when it's not served
+	lookup func(name string) (string, bool)
+	sums   checksums
+}
+
+// NewLeader returns a Leader serving the DB files returned by lookup, called on each request
+// as the served DBs may be reloaded
+func NewLeader(lookup func(name string) (string, bool)) *Leader {
+	return &Leader{lookup: lookup}
+}
+
+// ServeHTTP serves the DB file named by the last element of the URL path or its checksum
+// when it ends with .sha256, the DB files support the range requests
+func (l *Leader) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := path.Base(r.URL.Path)
+	sumOnly := strings.HasSuffix(name, ChecksumExt)
+	name = strings.TrimSuffix(name, ChecksumExt)
+
+	p, ok := l.lookup(name)
+	if !ok {
+		http.Error(w, "unknown DB "+name, http.StatusNotFound)
+		return
+	}
+
+	// the DB is read from the same file as its checksum, even if it's replaced meanwhile
+	f, err := os.Open(p)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if fi.IsDir() {
+		http.Error(w, fmt.Sprintf("%s is %v", name, ErrNotFile), http.StatusNotImplemented)
+		return
+	}
+
+	sum, err := l.sums.sum(p, f)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if sumOnly {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-cache")
+		fmt.Fprintf(w, "%s  %s\n", sum, name)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("ETag", `"`+sum+`"`)
+	http.ServeContent(w, r, name, fi.ModTime(), f)
+}
@@ -0,0 +1,149 @@
+// Package replica replicates the DB files served by an insided leader to its followers:
+// the leader serves each DB file and its SHA-256 checksum over HTTP,
+//
+//	GET /replica/countries.db          the DB file, with range requests
+//	GET /replica/countries.db.sha256   its checksum in the sha256sum format
+//
+// and the followers periodically compare the checksum with the one of their DB, download the changed DBs
+// next to them, resuming the interrupted downloads, verify them then swap them in place.
+// A bucket of an object store served over HTTP, holding the DBs and their .sha256 files, is also a valid source.
+//
+// Only the backends storing a DB in a single file are replicated: bbolt, flat and sqlite.
+package replica
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// ChecksumExt the extension of the checksum of a DB file
+const ChecksumExt = ".sha256"
+
+var (
+	fetchedCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "insided_replica",
+		Name:      "fetched_total",
+		Help:      "The total number of DB files fetched from the replication source",
+	})
+	fetchedBytesCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "insided_replica",
+		Name:      "fetched_bytes_total",
+		Help:      "The total number of bytes downloaded from the replication source",
+	})
+	errorsCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "insided_replica",
+		Name:      "errors_total",
+		Help:      "The total number of failed synchronizations",
+	})
+	lastSyncGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "insided_replica",
+		Name:      "last_sync_timestamp_seconds",
+		Help:      "The time of the last successful synchronization with the replication source",
+	})
+)
+
+// ErrNotFile returned for a DB stored in a directory
+var ErrNotFile = errors.New("not a single file DB")
+
+// checksums caches the checksums of the files by path, recomputed when their size or modification time change
+type checksums struct {
+	mu    sync.Mutex
+	files map[string]checksum
+}
+
+type checksum struct {
+	size    int64
+	modTime time.Time
+	sum     string
+}
+
+// sum returns the hex SHA-256 checksum of the file f
+func (c *checksums) sum(path string, f *os.File) (string, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	if fi.IsDir() {
+		return "", fmt.Errorf("%s: %w", path, ErrNotFile)
+	}
+
+	c.mu.Lock()
+	cs, ok := c.files[path]
+	c.mu.Unlock()
+	if ok && cs.size == fi.Size() && cs.modTime.Equal(fi.ModTime()) {
+		return cs.sum, nil
+	}
+
+	sum, err := hashReader(io.NewSectionReader(f, 0, fi.Size()))
+	if err != nil {
+		return "", err
+	}
+	cs = checksum{size: fi.Size(), modTime: fi.ModTime(), sum: sum}
+
+	c.mu.Lock()
+	if c.files == nil {
+		c.files = make(map[string]checksum)
+	}
+	c.files[path] = cs
+	c.mu.Unlock()
+	return cs.sum, nil
+}
+
+// fileSum returns the checksum of the file at path, an empty checksum when it does not exist
+func (c *checksums) fileSum(path string) (string, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return c.sum(path, f)
+}
+
+// hashFile returns the hex SHA-256 checksum of the file at path, not cached
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return hashReader(f)
+}
+
+func hashReader(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// parseChecksum returns the checksum of the first line of r, in the sha256sum format or alone
+func parseChecksum(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(io.LimitReader(r, 4096)).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "", errors.New("empty checksum")
+	}
+	sum := strings.ToLower(fields[0])
+	if b, err := hex.DecodeString(sum); err != nil || len(b) != sha256.Size {
+		return "", fmt.Errorf("invalid SHA-256 checksum %q", fields[0])
+	}
+	return sum, nil
+}
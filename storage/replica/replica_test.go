@@ -0,0 +1,169 @@
+package replica
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+)
+
+func tempDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir(os.TempDir(), "insideout-test-")
+	require.NoError(t, err)
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func hexSum(b []byte) string {
+	s := sha256.Sum256(b)
+	return hex.EncodeToString(s[:])
+}
+
+func TestParseChecksum(t *testing.T) {
+	sum := hexSum([]byte("db"))
+	for _, v := range []string{sum, sum + "  countries.db\n", strings.ToUpper(sum) + "\n"} {
+		got, err := parseChecksum(strings.NewReader(v))
+		require.NoError(t, err, v)
+		require.Equal(t, sum, got)
+	}
+	for _, v := range []string{"", "abcd  countries.db", "\n"} {
+		_, err := parseChecksum(strings.NewReader(v))
+		require.Error(t, err, v)
+	}
+}
+
+func TestFollower_Leader(t *testing.T) {
+	leaderDir, clean := tempDir(t)
+	defer clean()
+	followerDir, fclean := tempDir(t)
+	defer fclean()
+
+	src := filepath.Join(leaderDir, "countries.db")
+	require.NoError(t, ioutil.WriteFile(src, []byte("version 1"), 0644))
+	require.NoError(t, os.Mkdir(filepath.Join(leaderDir, "leveldb.db"), 0755))
+
+	var ranges int32
+	leader := NewLeader(func(name string) (string, bool) {
+		if name != "countries.db" && name != "leveldb.db" {
+			return "", false
+		}
+		return filepath.Join(leaderDir, name), true
+	})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") != "" {
+			atomic.AddInt32(&ranges, 1)
+		}
+		leader.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/replica/countries.db.sha256")
+	require.NoError(t, err)
+	b, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.Equal(t, hexSum([]byte("version 1"))+"  countries.db\n", string(b))
+
+	for path, code := range map[string]int{"/replica/unknown.db": http.StatusNotFound, "/replica/leveldb.db": http.StatusNotImplemented} {
+		resp, err := http.Get(ts.URL + path)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, code, resp.StatusCode, path)
+	}
+
+	f := NewFollower(Options{Source: ts.URL + "/replica/"}, log.NewNopLogger())
+	ctx := context.Background()
+	dst := filepath.Join(followerDir, "countries.db")
+
+	// missing locally
+	replaced, err := f.Sync(ctx, []string{dst})
+	require.NoError(t, err)
+	require.Equal(t, []string{dst}, replaced)
+	b, err = ioutil.ReadFile(dst)
+	require.NoError(t, err)
+	require.Equal(t, "version 1", string(b))
+
+	// unchanged
+	ok, err := f.Fetch(ctx, dst)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// resumes the interrupted download, after removing the stale ones
+	v2 := []byte("version 2 of the DB")
+	require.NoError(t, ioutil.WriteFile(src, v2, 0644))
+	stale := filepath.Join(followerDir, ".countries.db.0123456789abcdef.part")
+	require.NoError(t, ioutil.WriteFile(stale, []byte("stale"), 0644))
+	part := filepath.Join(followerDir, fmt.Sprintf(".countries.db.%s.part", hexSum(v2)[:16]))
+	require.NoError(t, ioutil.WriteFile(part, v2[:10], 0644))
+
+	ok, err = f.Fetch(ctx, dst)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.EqualValues(t, 1, atomic.LoadInt32(&ranges))
+	b, err = ioutil.ReadFile(dst)
+	require.NoError(t, err)
+	require.Equal(t, v2, b)
+	for _, p := range []string{stale, part} {
+		_, err = os.Stat(p)
+		require.True(t, os.IsNotExist(err), p)
+	}
+
+	// a corrupted resumed download is discarded
+	v3 := []byte("version 3 of the DB")
+	require.NoError(t, ioutil.WriteFile(src, v3, 0644))
+	part = filepath.Join(followerDir, fmt.Sprintf(".countries.db.%s.part", hexSum(v3)[:16]))
+	require.NoError(t, ioutil.WriteFile(part, []byte("corrupted!"), 0644))
+	_, err = f.Fetch(ctx, dst)
+	require.Error(t, err)
+	_, err = os.Stat(part)
+	require.True(t, os.IsNotExist(err))
+	b, err = ioutil.ReadFile(dst)
+	require.NoError(t, err)
+	require.Equal(t, v2, b)
+
+	ok, err = f.Fetch(ctx, dst)
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestFollower_ObjectStore(t *testing.T) {
+	bucket, clean := tempDir(t)
+	defer clean()
+	followerDir, fclean := tempDir(t)
+	defer fclean()
+
+	db := []byte("object store DB")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(bucket, "countries.db"), db, 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(bucket, "countries.db.sha256"), []byte(hexSum(db)+"  countries.db\n"), 0644))
+	ts := httptest.NewServer(http.FileServer(http.Dir(bucket)))
+	defer ts.Close()
+
+	f := NewFollower(Options{Source: ts.URL}, log.NewNopLogger())
+	dst := filepath.Join(followerDir, "countries.db")
+	ok, err := f.Fetch(context.Background(), dst)
+	require.NoError(t, err)
+	require.True(t, ok)
+	b, err := ioutil.ReadFile(dst)
+	require.NoError(t, err)
+	require.Equal(t, db, b)
+
+	// a checksum not matching the DB
+	require.NoError(t, ioutil.WriteFile(filepath.Join(bucket, "countries.db.sha256"), []byte(hexSum([]byte("other"))), 0644))
+	_, err = f.Fetch(context.Background(), dst)
+	require.Error(t, err)
+	b, err = ioutil.ReadFile(dst)
+	require.NoError(t, err)
+	require.Equal(t, db, b)
+
+	_, err = f.Fetch(context.Background(), filepath.Join(followerDir, "missing.db"))
+	require.Error(t, err)
+}
@@ -0,0 +1,744 @@
+// Package sqlite stores the index in a single SQLite file in WAL mode, readable with the standard SQL tools:
+// the cells index is the cells table, the features are CBOR blobs in the features table
+// with their properties as JSON, and the index infos and the extra indexes are CBOR blobs in the meta table.
+// The cell ids are the S2 cell ids as signed 64 bits integers. It requires a binary built with cgo.
+package sqlite
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fxamacker/cbor"
+	log "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/golang/geo/s2"
+	"github.com/twpayne/go-geom/encoding/geojson"
+
+	// sqlite driver
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/akhenakh/insideout"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS features (
+	id INTEGER PRIMARY KEY,
+	properties TEXT,
+	feature BLOB NOT NULL,
+	cells BLOB NOT NULL
+);
+CREATE TABLE IF NOT EXISTS cells (
+	cell INTEGER NOT NULL,
+	inside INTEGER NOT NULL,
+	id INTEGER NOT NULL,
+	pos INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS cells_cell ON cells (inside, cell);
+CREATE INDEX IF NOT EXISTS cells_id ON cells (id);
+CREATE TABLE IF NOT EXISTS meta (
+	key TEXT PRIMARY KEY,
+	value BLOB NOT NULL
+);
+`
+
+// maxLevel the level of the S2 leaf cells
+const maxLevel = 30
+
+// the keys of the meta table
+const (
+	infosKey         = "infos"
+	mapKey           = "map"
+	hierarchyKey     = "hierarchy"
+	h3Key            = "h3"
+	cellFilterKey    = "cell_filter"
+	propertyIndexKey = "property_index"
+)
+
+var (
+	featureStoragePool = sync.Pool{
+		New: func() interface{} {
+			return &insideout.FeatureStorage{}
+		},
+	}
+)
+
+// Storage cold storage
+type Storage struct {
+	*sql.DB
+	logger log.Logger
+
+	mu            sync.RWMutex
+	minCoverLevel int
+
+	// indexing only
+	insideout.AutoCover
+	insideout.LoopEncoder
+	progress *insideout.Progress
+}
+
+// NewStorage returns a cold storage using SQLite, the file is created when missing
+func NewStorage(path string, logger log.Logger) (*Storage, func() error, error) {
+	db, err := openDB(path, "rwc")
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, nil, fmt.Errorf("can't create the tables of %s: %w", path, err)
+	}
+
+	return &Storage{
+		DB:     db,
+		logger: logger,
+	}, db.Close, nil
+}
+
+// NewROStorage returns a read only storage using SQLite
+func NewROStorage(path string, logger log.Logger) (*Storage, func() error, error) {
+	db, err := openDB(path, "ro")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open DB for reading at %s: %w", path, err)
+	}
+	return openStorage(db, logger)
+}
+
+// NewRWStorage returns a storage using an existing SQLite DB, the features can be written at runtime
+func NewRWStorage(path string, logger log.Logger) (*Storage, func() error, error) {
+	db, err := openDB(path, "rw")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open DB for writing at %s: %w", path, err)
+	}
+	return openStorage(db, logger)
+}
+
+// openDB opens the SQLite file at path in WAL mode, mode is ro, rw or rwc
+func openDB(path, mode string) (*sql.DB, error) {
+	q := url.Values{}
+	q.Set("mode", mode)
+	q.Set("_journal_mode", "WAL")
+	q.Set("_busy_timeout", "5000")
+	q.Set("_synchronous", "NORMAL")
+	db, err := sql.Open("sqlite3", "file:"+path+"?"+q.Encode())
+	if err != nil {
+		return nil, err
+	}
+	// sql.Open does not connect
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// openStorage returns a storage using the indexed db
+func openStorage(db *sql.DB, logger log.Logger) (*Storage, func() error, error) {
+	s := &Storage{
+		DB:     db,
+		logger: logger,
+	}
+
+	infos, err := s.LoadIndexInfos()
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	s.minCoverLevel = infos.MinCoverLevel
+
+	return s, db.Close, nil
+}
+
+// loadMeta decodes the value of key in the meta table into v, returns false when missing
+func (s *Storage) loadMeta(key string, v interface{}) (bool, error) {
+	var value []byte
+	err := s.QueryRow("SELECT value FROM meta WHERE key = ?", key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	dec := cbor.NewDecoder(bytes.NewReader(value))
+	if err := dec.Decode(v); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// storeMeta stores v encoded as the value of key in the meta table
+func (s *Storage) storeMeta(key string, v interface{}) error {
+	value := new(bytes.Buffer)
+	enc := cbor.NewEncoder(value, cbor.CanonicalEncOptions())
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	_, err := s.Exec("INSERT OR REPLACE INTO meta (key, value) VALUES (?, ?)", key, value.Bytes())
+	return err
+}
+
+// LoadFeature loads one feature from the DB
+func (s *Storage) LoadFeature(id uint32) (*insideout.Feature, error) {
+	var v []byte
+	err := s.QueryRow("SELECT feature FROM features WHERE id = ?", id).Scan(&v)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("feature id not found: %d", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	fs := &insideout.FeatureStorage{}
+	dec := cbor.NewDecoder(bytes.NewReader(v))
+	if err := dec.Decode(fs); err != nil {
+		return nil, err
+	}
+
+	loops := make([]*s2.Loop, len(fs.LoopsBytes))
+	for i := 0; i < len(loops); i++ {
+		l, err := insideout.DecodeLoop(fs.LoopsBytes[i])
+		if err != nil {
+			return nil, err
+		}
+		loops[i] = l
+	}
+	holes, err := insideout.DecodeHoles(fs.HolesBytes)
+	if err != nil {
+		return nil, err
+	}
+	f := &insideout.Feature{
+		Loops:      loops,
+		Holes:      holes,
+		Properties: fs.Properties,
+	}
+
+	return f, nil
+}
+
+// LoadAllFeatures loads FeatureStorage from DB into idx
+// only useful to fill in memory shapeindex
+func (s *Storage) LoadAllFeatures(add func(*insideout.FeatureStorage, uint32) error) error {
+	rows, err := s.Query("SELECT id, feature FROM features ORDER BY id")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var id uint32
+	var v []byte
+	for rows.Next() {
+		if err := rows.Scan(&id, &v); err != nil {
+			return err
+		}
+
+		dec := cbor.NewDecoder(bytes.NewReader(v))
+		fs := featureStoragePool.Get().(*insideout.FeatureStorage)
+		fs.Reset()
+		if err := dec.Decode(fs); err != nil {
+			featureStoragePool.Put(fs)
+			return err
+		}
+
+		if err := add(fs, id); err != nil {
+			featureStoragePool.Put(fs)
+			return err
+		}
+		featureStoragePool.Put(fs)
+	}
+
+	return rows.Err()
+}
+
+// LoadFeaturesCells loads CellsStorage from DB into idx
+// only useful to fill in memory tree indexes
+func (s *Storage) LoadFeaturesCells(add func([]s2.CellUnion, []s2.CellUnion, uint32)) error {
+	rows, err := s.Query("SELECT id, cells FROM features ORDER BY id")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var id uint32
+	var v []byte
+	for rows.Next() {
+		if err := rows.Scan(&id, &v); err != nil {
+			return err
+		}
+		dec := cbor.NewDecoder(bytes.NewReader(v))
+		cs := &insideout.CellsStorage{}
+		if err := dec.Decode(cs); err != nil {
+			return err
+		}
+
+		add(cs.CellsIn, cs.CellsOut, id)
+	}
+
+	return rows.Err()
+}
+
+// LoadMapInfos loads map infos from the DB if any
+func (s *Storage) LoadMapInfos() (*insideout.MapInfos, bool, error) {
+	mapInfos := &insideout.MapInfos{}
+	ok, err := s.loadMeta(mapKey, mapInfos)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	return mapInfos, true, nil
+}
+
+// LoadHierarchy loads the parent id of each contained feature, nil when it was never stored
+func (s *Storage) LoadHierarchy() (map[uint32]uint32, error) {
+	var parents map[uint32]uint32
+	if _, err := s.loadMeta(hierarchyKey, &parents); err != nil {
+		return nil, fmt.Errorf("can't load hierarchy: %w", err)
+	}
+	return parents, nil
+}
+
+// StoreHierarchy stores the parent id of each contained feature
+func (s *Storage) StoreHierarchy(parents map[uint32]uint32) error {
+	if err := s.storeMeta(hierarchyKey, parents); err != nil {
+		return fmt.Errorf("can't store hierarchy: %w", err)
+	}
+	return nil
+}
+
+// LoadH3Cover loads the H3 cover of the polygons, nil when it was never stored
+func (s *Storage) LoadH3Cover() (*insideout.H3Cover, error) {
+	var cover *insideout.H3Cover
+	if _, err := s.loadMeta(h3Key, &cover); err != nil {
+		return nil, fmt.Errorf("can't load H3 cover: %w", err)
+	}
+	return cover, nil
+}
+
+// StoreH3Cover stores the H3 cover of the polygons
+func (s *Storage) StoreH3Cover(cover *insideout.H3Cover) error {
+	if err := s.storeMeta(h3Key, cover); err != nil {
+		return fmt.Errorf("can't store H3 cover: %w", err)
+	}
+	return nil
+}
+
+// LoadCellFilter loads the filter of the cells, nil when it was never stored
+func (s *Storage) LoadCellFilter() (*insideout.CellFilter, error) {
+	var filter *insideout.CellFilter
+	if _, err := s.loadMeta(cellFilterKey, &filter); err != nil {
+		return nil, fmt.Errorf("can't load cell filter: %w", err)
+	}
+	return filter, nil
+}
+
+// StoreCellFilter stores the filter of the cells
+func (s *Storage) StoreCellFilter(filter *insideout.CellFilter) error {
+	if err := s.storeMeta(cellFilterKey, filter); err != nil {
+		return fmt.Errorf("can't store cell filter: %w", err)
+	}
+	return nil
+}
+
+// LoadPropertyIndex loads the index of the properties, nil when it was never stored
+func (s *Storage) LoadPropertyIndex() (*insideout.PropertyIndex, error) {
+	var pi *insideout.PropertyIndex
+	if _, err := s.loadMeta(propertyIndexKey, &pi); err != nil {
+		return nil, fmt.Errorf("can't load property index: %w", err)
+	}
+	return pi, nil
+}
+
+// StorePropertyIndex stores the index of the properties
+func (s *Storage) StorePropertyIndex(pi *insideout.PropertyIndex) error {
+	if err := s.storeMeta(propertyIndexKey, pi); err != nil {
+		return fmt.Errorf("can't store property index: %w", err)
+	}
+	return nil
+}
+
+// LoadIndexInfos loads index infos from the DB
+func (s *Storage) LoadIndexInfos() (*insideout.IndexInfos, error) {
+	infos := &insideout.IndexInfos{}
+	ok, err := s.loadMeta(infosKey, infos)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("can't find infos entries, invalid DB")
+	}
+	return infos, nil
+}
+
+// LoadCellStorage loads cell storage from
+func (s *Storage) LoadCellStorage(id uint32) (*insideout.CellsStorage, error) {
+	var v []byte
+	err := s.QueryRow("SELECT cells FROM features WHERE id = ?", id).Scan(&v)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("feature id not found: %d", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cs := &insideout.CellsStorage{}
+	dec := cbor.NewDecoder(bytes.NewReader(v))
+	if err := dec.Decode(cs); err != nil {
+		return nil, err
+	}
+
+	return cs, nil
+}
+
+// StabDB returns the polygons indexed in the cells containing the point at lat lng,
+// the cells are looked up by their ids, the parents of the point cell from the min cover level
+func (s *Storage) StabDB(lat, lng float64, stopOnInsideFound bool) (insideout.IndexResponse, error) {
+	var idxResp insideout.IndexResponse
+
+	c := s2.CellIDFromLatLng(s2.LatLngFromDegrees(lat, lng))
+	s.mu.RLock()
+	minCoverLevel := s.minCoverLevel
+	s.mu.RUnlock()
+
+	args := make([]interface{}, 0, maxLevel-minCoverLevel+1)
+	for l := minCoverLevel; l <= maxLevel; l++ {
+		args = append(args, int64(c.Parent(l)))
+	}
+	q := "SELECT inside, id, pos FROM cells WHERE inside IN (0, 1) AND cell IN (?" +
+		strings.Repeat(", ?", len(args)-1) + ") ORDER BY inside DESC"
+	rows, err := s.Query(q, args...)
+	if err != nil {
+		return idxResp, err
+	}
+	defer rows.Close()
+
+	mi := make(map[insideout.FeatureIndexResponse]struct{})
+	mo := make(map[insideout.FeatureIndexResponse]struct{})
+	for rows.Next() {
+		var inside bool
+		var res insideout.FeatureIndexResponse
+		if err := rows.Scan(&inside, &res.ID, &res.Pos); err != nil {
+			return idxResp, err
+		}
+		if !inside {
+			mo[res] = struct{}{}
+			continue
+		}
+		if stopOnInsideFound {
+			idxResp.IDsInside = append(idxResp.IDsInside, res)
+			return idxResp, nil
+		}
+		mi[res] = struct{}{}
+	}
+	if err := rows.Err(); err != nil {
+		return idxResp, err
+	}
+
+	// dedup
+	for res := range mi {
+		idxResp.IDsInside = append(idxResp.IDsInside, res)
+	}
+	for res := range mo {
+		// remove any answer matching inside
+		if _, ok := mi[res]; !ok {
+			idxResp.IDsMayBeInside = append(idxResp.IDsMayBeInside, res)
+		}
+	}
+
+	return idxResp, nil
+}
+
+// IntersectDB returns polygon's ids with an outside cover intersecting cu
+func (s *Storage) IntersectDB(cu s2.CellUnion) ([]insideout.FeatureIndexResponse, error) {
+	m := make(map[insideout.FeatureIndexResponse]struct{})
+
+	addRows := func(rows *sql.Rows) error {
+		defer rows.Close()
+		for rows.Next() {
+			var res insideout.FeatureIndexResponse
+			if err := rows.Scan(&res.ID, &res.Pos); err != nil {
+				return err
+			}
+			m[res] = struct{}{}
+		}
+		return rows.Err()
+	}
+
+	s.mu.RLock()
+	minCoverLevel := s.minCoverLevel
+	s.mu.RUnlock()
+
+	for _, c := range cu {
+		// indexed cells containing c
+		for l := minCoverLevel; l < c.Level(); l++ {
+			rows, err := s.Query("SELECT id, pos FROM cells WHERE inside = 0 AND cell = ?", int64(c.Parent(l)))
+			if err != nil {
+				return nil, err
+			}
+			if err := addRows(rows); err != nil {
+				return nil, err
+			}
+		}
+
+		// indexed cells contained by c, the range of a cell has the sign of its face
+		rows, err := s.Query("SELECT id, pos FROM cells WHERE inside = 0 AND cell BETWEEN ? AND ?",
+			int64(c.RangeMin()), int64(c.RangeMax()))
+		if err != nil {
+			return nil, err
+		}
+		if err := addRows(rows); err != nil {
+			return nil, err
+		}
+	}
+
+	res := make([]insideout.FeatureIndexResponse, 0, len(m))
+	for fres := range m {
+		res = append(res, fres)
+	}
+
+	return res, nil
+}
+
+func (s *Storage) Index(fc geojson.FeatureCollection, icoverer *s2.RegionCoverer, ocoverer *s2.RegionCoverer,
+	warningCellsCover int, fileName, version string) error {
+	return s.IndexReader(insideout.NewFeatureCollectionReader(&fc), icoverer, ocoverer, warningCellsCover, fileName, version)
+}
+
+// IndexReader indexes all the features read from r, one at a time
+func (s *Storage) IndexReader(r insideout.FeatureReader, icoverer *s2.RegionCoverer, ocoverer *s2.RegionCoverer,
+	warningCellsCover int, fileName, version string) error {
+	var count uint32
+
+	for {
+		f, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("can't read feature: %w", err)
+		}
+
+		indexed, err := s.IndexFeature(f, count, icoverer, ocoverer, warningCellsCover)
+		if err != nil {
+			return err
+		}
+		if !indexed {
+			continue
+		}
+
+		count++
+	}
+
+	return s.writeInfos(count, s.LowestCoverLevel(icoverer, ocoverer), icoverer, ocoverer, fileName, version)
+}
+
+// Append indexes the features read from r into an existing DB,
+// features with the same idProperty value as a stored feature replace it
+func (s *Storage) Append(r insideout.FeatureReader, idProperty string, icoverer *s2.RegionCoverer, ocoverer *s2.RegionCoverer,
+	warningCellsCover int, fileName, version string) error {
+	infos, err := s.LoadIndexInfos()
+	if err != nil {
+		return fmt.Errorf("can't append to DB: %w", err)
+	}
+	if err := infos.CheckCoverers(icoverer, ocoverer, s.AutoCovered()); err != nil {
+		return fmt.Errorf("can't append to DB: %w", err)
+	}
+	if err := infos.CheckLoopEncoding(s.LoopEncoding()); err != nil {
+		return fmt.Errorf("can't append to DB: %w", err)
+	}
+
+	count, err := insideout.AppendFeatures(s, r, infos.FeatureCount, idProperty, icoverer, ocoverer, warningCellsCover)
+	if err != nil {
+		return err
+	}
+
+	// the existing cells may use a lower level
+	minCoverLevel := s.LowestCoverLevel(icoverer, ocoverer)
+	if infos.MinCoverLevel < minCoverLevel {
+		minCoverLevel = infos.MinCoverLevel
+	}
+
+	return s.writeInfos(count, minCoverLevel, icoverer, ocoverer, infos.Filename+","+fileName, version)
+}
+
+// IndexFeature covers and stores f with id, replacing a previously stored feature with the same id,
+// returns false when the feature can't be covered
+func (s *Storage) IndexFeature(f *geojson.Feature, id uint32, icoverer *s2.RegionCoverer, ocoverer *s2.RegionCoverer,
+	warningCellsCover int) (bool, error) {
+	logger := log.With(s.logger, "component", "indexer")
+
+	icoverer, ocoverer = s.FeatureCoverers(f, icoverer, ocoverer)
+
+	// cover inside
+	cui, err := insideout.GeoJSONCoverCellUnion(f, icoverer, true)
+	if err != nil {
+		level.Warn(logger).Log("msg", "error covering inside", "error", err, "feature_properties", f.Properties)
+		return false, nil
+	}
+
+	// cover outside
+	cuo, err := insideout.GeoJSONCoverCellUnion(f, ocoverer, false)
+	if err != nil {
+		level.Warn(logger).Log("msg", "error covering outside", "error", err, "feature_properties", f.Properties)
+		return false, nil
+	}
+
+	tx, err := s.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM cells WHERE id = ?", id); err != nil {
+		return false, fmt.Errorf("can't remove previous cells of feature %d: %w", id, err)
+	}
+
+	stmt, err := tx.Prepare("INSERT INTO cells (cell, inside, id, pos) VALUES (?, ?, ?, ?)")
+	if err != nil {
+		return false, err
+	}
+	defer stmt.Close()
+
+	var cells int
+
+	// store interior cover
+	for fi, cu := range cui {
+		if warningCellsCover != 0 && len(cu) > warningCellsCover {
+			level.Warn(logger).Log(
+				"msg", fmt.Sprintf("inside cover too big %d cells, not indexing polygon #%d %s", len(cui), fi, f.Properties),
+				"feature_properties", f.Properties,
+			)
+
+			continue
+		}
+		cells += len(cu)
+		for _, c := range cu {
+			if _, err := stmt.Exec(int64(c), true, id, fi); err != nil {
+				return false, fmt.Errorf("failed set inside cover into DB: %w", err)
+			}
+		}
+	}
+
+	// store outside cover
+	for fi, cu := range cuo {
+		if warningCellsCover != 0 && len(cu) > warningCellsCover {
+			level.Warn(logger).Log(
+				"msg", fmt.Sprintf("outside cover too big %d not indexing polygon #%d %s", len(cui), fi, f.Properties),
+				"feature_properties", f.Properties,
+			)
+			continue
+		}
+		cells += len(cu)
+		for _, c := range cu {
+			if _, err := stmt.Exec(int64(c), false, id, fi); err != nil {
+				return false, fmt.Errorf("failed set outside cover into DB: %w", err)
+			}
+		}
+	}
+
+	// store feature
+	if err := s.writeFeature(tx, f, id, cui, cuo); err != nil {
+		return false, fmt.Errorf("can't store feature into DB: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed store feature into DB: %w", err)
+	}
+
+	s.progress.Add(1, cells)
+
+	return true, nil
+}
+
+func (s *Storage) writeFeature(tx *sql.Tx, f *geojson.Feature, id uint32, cui, cuo []s2.CellUnion) error {
+	lb, err := s.EncodeLoops(f)
+	if err != nil {
+		return fmt.Errorf("can't encode loop: %w", err)
+	}
+	hb, err := s.EncodeHoles(f)
+	if err != nil {
+		return fmt.Errorf("can't encode holes: %w", err)
+	}
+
+	fb := new(bytes.Buffer)
+	enc := cbor.NewEncoder(fb, cbor.CanonicalEncOptions())
+	fs := &insideout.FeatureStorage{Properties: f.Properties, LoopsBytes: lb, HolesBytes: hb}
+	if err := enc.Encode(fs); err != nil {
+		return fmt.Errorf("can't encode FeatureStorage: %w", err)
+	}
+
+	// store cells for tree
+	cb := new(bytes.Buffer)
+	enc = cbor.NewEncoder(cb, cbor.CanonicalEncOptions())
+	cs := &insideout.CellsStorage{
+		CellsIn:  cui,
+		CellsOut: cuo,
+	}
+	if err := enc.Encode(cs); err != nil {
+		return fmt.Errorf("can't encode CellsStorage: %w", err)
+	}
+
+	// the properties as JSON for the SQL tools, the feature blob is the one read
+	props, err := json.Marshal(f.Properties)
+	if err != nil {
+		return fmt.Errorf("can't encode properties: %w", err)
+	}
+
+	_, err = tx.Exec("INSERT OR REPLACE INTO features (id, properties, feature, cells) VALUES (?, ?, ?, ?)",
+		id, string(props), fb.Bytes(), cb.Bytes())
+	if err != nil {
+		return err
+	}
+
+	level.Debug(s.logger).Log(
+		"msg", "stored FeatureStorage",
+		"feature_properties", f.Properties,
+		"loop_count", len(fs.LoopsBytes),
+		"inside_loop_id", id,
+	)
+
+	return nil
+}
+
+func (s *Storage) writeInfos(fcount uint32, minCoverLevel int, icoverer, ocoverer *s2.RegionCoverer,
+	fileName, version string) error {
+	coverage, err := insideout.ComputeCoverage(s)
+	if err != nil {
+		return err
+	}
+
+	infos := &insideout.IndexInfos{
+		Filename:       fileName,
+		IndexTime:      time.Now(),
+		IndexerVersion: version,
+		FeatureCount:   fcount,
+		MinCoverLevel:  minCoverLevel,
+		InsideCover:    insideout.NewCoverOptions(icoverer),
+		OutsideCover:   insideout.NewCoverOptions(ocoverer),
+		AutoCover:      s.AutoCovered(),
+		LoopEncoding:   s.LoopEncoding(),
+		Coverage:       coverage,
+	}
+
+	return s.putInfos(infos)
+}
+
+// putInfos stores infos in the DB
+func (s *Storage) putInfos(infos *insideout.IndexInfos) error {
+	if err := s.storeMeta(infosKey, infos); err != nil {
+		return fmt.Errorf("failed encoding IndexInfos: %w", err)
+	}
+	s.mu.Lock()
+	s.minCoverLevel = infos.MinCoverLevel
+	s.mu.Unlock()
+	return nil
+}
+
+// SetProgress sets the counters updated while indexing
+func (s *Storage) SetProgress(p *insideout.Progress) {
+	s.progress = p
+}
@@ -0,0 +1,91 @@
+package sqlite
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/twpayne/go-geom/encoding/geojson"
+
+	"github.com/akhenakh/insideout"
+)
+
+// WriteFeature covers f with the coverers recorded in the index infos and stores it with id,
+// replacing a previously stored feature with the same id, returns false when f can't be covered.
+// The features count, the coverage and the index time of the infos are updated.
+func (s *Storage) WriteFeature(f *geojson.Feature, id uint32) (bool, error) {
+	infos, err := s.LoadIndexInfos()
+	if err != nil {
+		return false, err
+	}
+	if infos.InsideCover == nil || infos.OutsideCover == nil {
+		return false, errors.New("the DB does not record its coverers, reindex it to write features")
+	}
+
+	s.SetAutoCover(infos.AutoCover)
+	icoverer, ocoverer := infos.InsideCover.Coverer(), infos.OutsideCover.Coverer()
+	indexed, err := s.IndexFeature(f, id, icoverer, ocoverer, 0)
+	if err != nil || !indexed {
+		return indexed, err
+	}
+
+	if len(infos.Coverage) > 0 {
+		cs, err := s.LoadCellStorage(id)
+		if err != nil {
+			return false, err
+		}
+		infos.Coverage = insideout.ExtendCoverage(infos.Coverage, cs.CellsOut...)
+	}
+
+	// a tuned cover may use a lower level
+	if l := s.LowestCoverLevel(icoverer, ocoverer); l < infos.MinCoverLevel {
+		infos.MinCoverLevel = l
+	}
+	if id >= infos.FeatureCount {
+		infos.FeatureCount = id + 1
+	}
+	if err := s.touchInfos(infos); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// DeleteFeature removes the feature id and its cells, returns false when it does not exist
+func (s *Storage) DeleteFeature(id uint32) (bool, error) {
+	infos, err := s.LoadIndexInfos()
+	if err != nil {
+		return false, err
+	}
+
+	tx, err := s.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM cells WHERE id = ?", id); err != nil {
+		return false, fmt.Errorf("can't remove the cells of feature %d: %w", id, err)
+	}
+	res, err := tx.Exec("DELETE FROM features WHERE id = ?", id)
+	if err != nil {
+		return false, fmt.Errorf("can't delete feature %d: %w", id, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("can't delete feature %d: %w", id, err)
+	}
+	if n == 0 {
+		return false, nil
+	}
+
+	return true, s.touchInfos(infos)
+}
+
+// touchInfos stores infos as indexed now, the caches keyed by the index time are not read anymore
+func (s *Storage) touchInfos(infos *insideout.IndexInfos) error {
+	infos.IndexTime = time.Now()
+	return s.putInfos(infos)
+}
@@ -0,0 +1,109 @@
+// Package storage selects the storage backends by name: the backends register a Factory in their init,
+// and Open opens a database at a path prefixed with the name of its backend, like leveldb:///data/inside.db.
+//
+// The backends of this repository are registered by importing their package, a third party backend
+// is compiled in the commands by importing its package in a new file of the command, for its side effects:
+//
+//	import _ "example.com/insideout-fdb"
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	log "github.com/go-kit/kit/log"
+
+	"github.com/akhenakh/insideout"
+)
+
+// Mode the access mode of an opened database
+type Mode int
+
+const (
+	// ReadOnly opens an existing database for the queries
+	ReadOnly Mode = iota
+	// ReadWrite opens an existing database for the queries and the insided writes, see insideout.FeatureWriter
+	ReadWrite
+	// Create opens a database for the indexer, created when missing
+	Create
+)
+
+func (m Mode) String() string {
+	switch m {
+	case ReadOnly:
+		return "read only"
+	case ReadWrite:
+		return "read write"
+	case Create:
+		return "create"
+	}
+	return fmt.Sprintf("mode %d", int(m))
+}
+
+// Factory opens the database at path in mode, the returned func closes it,
+// a backend not supporting mode returns an UnsupportedModeError
+type Factory func(path string, mode Mode, logger log.Logger) (insideout.Store, func() error, error)
+
+// UnsupportedModeError returned by a backend not supporting a mode
+type UnsupportedModeError struct {
+	Backend string
+	Mode    Mode
+}
+
+func (e *UnsupportedModeError) Error() string {
+	return fmt.Sprintf("%s not supported by the %s storage backend", e.Mode, e.Backend)
+}
+
+var (
+	mu        sync.RWMutex
+	factories = make(map[string]Factory)
+)
+
+// Register makes the backend name available to Open, it panics when name is registered twice or f is nil
+func Register(name string, f Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	if f == nil {
+		panic("storage: Register factory is nil for backend " + name)
+	}
+	if _, dup := factories[name]; dup {
+		panic("storage: Register called twice for backend " + name)
+	}
+	factories[name] = f
+}
+
+// Backends returns the sorted names of the registered backends
+func Backends() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseURI returns the backend and the path of uri, backend://path, defaultBackend for a bare path
+func ParseURI(uri, defaultBackend string) (string, string) {
+	i := strings.Index(uri, "://")
+	if i <= 0 {
+		return defaultBackend, uri
+	}
+	return uri[:i], uri[i+len("://"):]
+}
+
+// Open opens the database at uri in mode with the backend named by its scheme, defaultBackend for a bare path
+func Open(uri, defaultBackend string, mode Mode, logger log.Logger) (insideout.Store, func() error, error) {
+	backend, path := ParseURI(uri, defaultBackend)
+
+	mu.RLock()
+	f, ok := factories[backend]
+	mu.RUnlock()
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown storage backend %s, registered: %s", backend, strings.Join(Backends(), "|"))
+	}
+	return f(path, mode, logger)
+}
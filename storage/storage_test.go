@@ -0,0 +1,73 @@
+package storage_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/storage"
+	_ "github.com/akhenakh/insideout/storage/bbolt"
+	_ "github.com/akhenakh/insideout/storage/leveldb"
+)
+
+func TestParseURI(t *testing.T) {
+	tests := []struct {
+		uri, backend, path string
+	}{
+		{"inside.db", "bbolt", "inside.db"},
+		{"/data/inside.db", "bbolt", "/data/inside.db"},
+		{"leveldb:///data/inside.db", "leveldb", "/data/inside.db"},
+		{"flat://inside.flat", "flat", "inside.flat"},
+		{"://inside.db", "bbolt", "://inside.db"},
+	}
+	for _, tt := range tests {
+		backend, path := storage.ParseURI(tt.uri, insideout.BBoltBackend)
+		require.Equal(t, tt.backend, backend, tt.uri)
+		require.Equal(t, tt.path, path, tt.uri)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	var opened string
+	storage.Register("test", func(path string, mode storage.Mode, logger log.Logger) (insideout.Store, func() error, error) {
+		opened = path
+		return nil, func() error { return nil }, nil
+	})
+	require.Contains(t, storage.Backends(), "test")
+	require.Contains(t, storage.Backends(), insideout.BBoltBackend)
+
+	_, _, err := storage.Open("test:///tmp/x", insideout.BBoltBackend, storage.ReadOnly, log.NewNopLogger())
+	require.NoError(t, err)
+	require.Equal(t, "/tmp/x", opened)
+
+	_, _, err = storage.Open("nope:///tmp/x", insideout.BBoltBackend, storage.ReadOnly, log.NewNopLogger())
+	require.Error(t, err)
+
+	require.Panics(t, func() {
+		storage.Register("test", func(string, storage.Mode, log.Logger) (insideout.Store, func() error, error) {
+			return nil, nil, nil
+		})
+	})
+}
+
+func TestOpen(t *testing.T) {
+	tmpFile, err := ioutil.TempFile(os.TempDir(), "insideout-test-")
+	require.NoError(t, err)
+	tmpFile.Close()
+	defer os.Remove(tmpFile.Name())
+
+	s, clean, err := storage.Open("bbolt://"+tmpFile.Name(), insideout.LevelDBBackend, storage.Create, log.NewNopLogger())
+	require.NoError(t, err)
+	require.NotNil(t, s)
+	require.NoError(t, clean())
+
+	_, _, err = storage.Open(tmpFile.Name(), insideout.LevelDBBackend, storage.ReadWrite, log.NewNopLogger())
+	var merr *storage.UnsupportedModeError
+	require.True(t, errors.As(err, &merr))
+	require.Equal(t, insideout.LevelDBBackend, merr.Backend)
+}
@@ -0,0 +1,275 @@
+// Package versions stages the versions of a dataset in a directory, to promote one of them
+// and roll back to the previous one without moving the files:
+//
+//	countries/versions.json         the served version and the previously served ones
+//	countries/versions/2020-05.db   a version, a DB of any storage backend
+//	countries/versions/2020-06.db
+//
+// A new version is staged by indexing it in the versions subdirectory. The state file is replaced atomically,
+// a Layout is not safe for concurrent promotes, insided serializes them.
+package versions
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// StateFile the file holding the state of the versions
+	StateFile = "versions.json"
+	// Subdir the subdirectory holding the versions
+	Subdir = "versions"
+)
+
+var (
+	// ErrNotLayout returned when a directory has no versions subdirectory
+	ErrNotLayout = errors.New("not a versions directory")
+	// ErrUnknownVersion returned for a version not in the versions subdirectory
+	ErrUnknownVersion = errors.New("unknown version")
+	// ErrNoPrevious returned by a rollback when no version was served before the current one
+	ErrNoPrevious = errors.New("no previous version")
+)
+
+// Version a version of the dataset
+type Version struct {
+	Name    string
+	Path    string
+	Size    int64
+	ModTime time.Time
+	Current bool
+}
+
+// state the content of the state file
+type state struct {
+	// Current the served version, the last one by name when empty
+	Current string `json:"current"`
+	// History the previously served versions, the last one is restored by a rollback
+	History []string `json:"history,omitempty"`
+}
+
+// Layout the versions directory of a dataset
+type Layout struct {
+	dir string
+}
+
+// IsLayout returns true if dir is a versions directory
+func IsLayout(dir string) bool {
+	fi, err := os.Stat(filepath.Join(dir, Subdir))
+	return err == nil && fi.IsDir()
+}
+
+// Open returns the Layout of dir, an ErrNotLayout error if it has no versions subdirectory
+func Open(dir string) (*Layout, error) {
+	if !IsLayout(dir) {
+		return nil, fmt.Errorf("%s: %w", dir, ErrNotLayout)
+	}
+	return &Layout{dir: dir}, nil
+}
+
+// Dir returns the directory of the layout
+func (l *Layout) Dir() string {
+	return l.dir
+}
+
+// List returns the versions sorted by name
+func (l *Layout) List() ([]Version, error) {
+	st, err := l.state()
+	if err != nil {
+		return nil, err
+	}
+	fis, err := ioutil.ReadDir(filepath.Join(l.dir, Subdir))
+	if err != nil {
+		return nil, err
+	}
+
+	var vs []Version
+	for _, fi := range fis {
+		// the temporary files of the writers and the side files of SQLite
+		if strings.HasPrefix(fi.Name(), ".") || sideFile(fi.Name()) {
+			continue
+		}
+		v := Version{
+			Name:    fi.Name(),
+			Path:    filepath.Join(l.dir, Subdir, fi.Name()),
+			Size:    fi.Size(),
+			ModTime: fi.ModTime(),
+		}
+		if fi.IsDir() {
+			v.Size, err = dirSize(v.Path)
+			if err != nil {
+				return nil, err
+			}
+		}
+		vs = append(vs, v)
+	}
+	sort.Slice(vs, func(i, j int) bool { return vs[i].Name < vs[j].Name })
+
+	current := st.Current
+	if current == "" && len(vs) > 0 {
+		current = vs[len(vs)-1].Name
+	}
+	for i := range vs {
+		vs[i].Current = vs[i].Name == current
+	}
+	return vs, nil
+}
+
+// Current returns the served version, the last one by name until one is promoted
+func (l *Layout) Current() (Version, error) {
+	vs, err := l.List()
+	if err != nil {
+		return Version{}, err
+	}
+	for _, v := range vs {
+		if v.Current {
+			return v, nil
+		}
+	}
+	st, err := l.state()
+	if err != nil {
+		return Version{}, err
+	}
+	if st.Current != "" {
+		return Version{}, fmt.Errorf("current version %s: %w", st.Current, ErrUnknownVersion)
+	}
+	return Version{}, fmt.Errorf("no version in %s", filepath.Join(l.dir, Subdir))
+}
+
+// Version returns the version name
+func (l *Layout) Version(name string) (Version, error) {
+	vs, err := l.List()
+	if err != nil {
+		return Version{}, err
+	}
+	for _, v := range vs {
+		if v.Name == name {
+			return v, nil
+		}
+	}
+	return Version{}, fmt.Errorf("%s: %w", name, ErrUnknownVersion)
+}
+
+// Previous returns the version restored by a rollback
+func (l *Layout) Previous() (Version, error) {
+	st, err := l.state()
+	if err != nil {
+		return Version{}, err
+	}
+	if len(st.History) == 0 {
+		return Version{}, ErrNoPrevious
+	}
+	return l.Version(st.History[len(st.History)-1])
+}
+
+// Promote makes name the current version, the current one is restored by a rollback
+func (l *Layout) Promote(name string) error {
+	if _, err := l.Version(name); err != nil {
+		return err
+	}
+	st, err := l.state()
+	if err != nil {
+		return err
+	}
+	current := st.Current
+	if current == "" {
+		// the last version by name is served until one is promoted
+		if v, err := l.Current(); err == nil {
+			current = v.Name
+		}
+	}
+	if current == name {
+		return nil
+	}
+	if current != "" {
+		st.History = append(st.History, current)
+	}
+	st.Current = name
+	return l.writeState(st)
+}
+
+// Rollback makes the previously current version the current one again, returns its name
+func (l *Layout) Rollback() (string, error) {
+	prev, err := l.Previous()
+	if err != nil {
+		return "", err
+	}
+	st, err := l.state()
+	if err != nil {
+		return "", err
+	}
+	st.Current = prev.Name
+	st.History = st.History[:len(st.History)-1]
+	return prev.Name, l.writeState(st)
+}
+
+func (l *Layout) state() (*state, error) {
+	b, err := ioutil.ReadFile(filepath.Join(l.dir, StateFile))
+	if os.IsNotExist(err) {
+		return &state{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	st := &state{}
+	if err := json.Unmarshal(b, st); err != nil {
+		return nil, fmt.Errorf("invalid versions state %s: %w", filepath.Join(l.dir, StateFile), err)
+	}
+	return st, nil
+}
+
+// writeState replaces the state file atomically
+func (l *Layout) writeState(st *state) error {
+	b, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(l.dir, "."+StateFile+"-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), filepath.Join(l.dir, StateFile))
+}
+
+// sideFile returns true for the files written next to a SQLite DB
+func sideFile(name string) bool {
+	for _, suffix := range []string{"-wal", "-shm", "-journal"} {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// dirSize returns the size of the files in dir, for the backends storing a DB as a directory
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.Walk(dir, func(_ string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() {
+			size += fi.Size()
+		}
+		return nil
+	})
+	return size, err
+}
@@ -0,0 +1,97 @@
+package versions
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func setup(t *testing.T, names ...string) (string, func()) {
+	dir, err := ioutil.TempDir(os.TempDir(), "insideout-test-")
+	require.NoError(t, err)
+	require.NoError(t, os.Mkdir(filepath.Join(dir, Subdir), 0755))
+	for _, name := range names {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, Subdir, name), []byte(name), 0644))
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func names(t *testing.T, l *Layout) []string {
+	vs, err := l.List()
+	require.NoError(t, err)
+	var res []string
+	for _, v := range vs {
+		res = append(res, v.Name)
+	}
+	return res
+}
+
+func TestLayout(t *testing.T) {
+	dir, clean := setup(t, "v2.db", "v1.db", "v3.sqlite", "v3.sqlite-wal", ".tmp")
+	defer clean()
+
+	_, err := Open(filepath.Join(dir, Subdir))
+	require.True(t, errors.Is(err, ErrNotLayout))
+
+	l, err := Open(dir)
+	require.NoError(t, err)
+	require.Equal(t, []string{"v1.db", "v2.db", "v3.sqlite"}, names(t, l))
+
+	// the last version by name until one is promoted
+	current, err := l.Current()
+	require.NoError(t, err)
+	require.Equal(t, "v3.sqlite", current.Name)
+	require.Equal(t, filepath.Join(dir, Subdir, "v3.sqlite"), current.Path)
+	require.EqualValues(t, len("v3.sqlite"), current.Size)
+	_, err = l.Previous()
+	require.True(t, errors.Is(err, ErrNoPrevious))
+
+	require.NoError(t, l.Promote("v1.db"))
+	require.NoError(t, l.Promote("v2.db"))
+	require.NoError(t, l.Promote("v2.db"))
+	require.True(t, errors.Is(l.Promote("v4.db"), ErrUnknownVersion))
+
+	// the state is persisted
+	l, err = Open(dir)
+	require.NoError(t, err)
+	current, err = l.Current()
+	require.NoError(t, err)
+	require.Equal(t, "v2.db", current.Name)
+	prev, err := l.Previous()
+	require.NoError(t, err)
+	require.Equal(t, "v1.db", prev.Name)
+
+	name, err := l.Rollback()
+	require.NoError(t, err)
+	require.Equal(t, "v1.db", name)
+	name, err = l.Rollback()
+	require.NoError(t, err)
+	require.Equal(t, "v3.sqlite", name)
+	_, err = l.Rollback()
+	require.True(t, errors.Is(err, ErrNoPrevious))
+
+	// the removed current version
+	require.NoError(t, l.Promote("v1.db"))
+	require.NoError(t, os.Remove(filepath.Join(dir, Subdir, "v1.db")))
+	_, err = l.Current()
+	require.True(t, errors.Is(err, ErrUnknownVersion))
+	require.NoError(t, l.Promote("v2.db"))
+	current, err = l.Current()
+	require.NoError(t, err)
+	require.Equal(t, "v2.db", current.Name)
+}
+
+func TestLayout_Empty(t *testing.T) {
+	dir, clean := setup(t)
+	defer clean()
+
+	l, err := Open(dir)
+	require.NoError(t, err)
+	_, err = l.Current()
+	require.Error(t, err)
+	require.Empty(t, names(t, l))
+}
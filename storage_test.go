@@ -0,0 +1,39 @@
+package insideout
+
+import (
+	"testing"
+
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndexInfos_Validate(t *testing.T) {
+	icoverer := &s2.RegionCoverer{MinLevel: 10, MaxLevel: 16, MaxCells: 24}
+	ocoverer := &s2.RegionCoverer{MinLevel: 8, MaxLevel: 15, MaxCells: 16, LevelMod: 2}
+
+	infos := &IndexInfos{
+		MinCoverLevel: MinCoverLevel(icoverer, ocoverer),
+		InsideCover:   NewCoverOptions(icoverer),
+		OutsideCover:  NewCoverOptions(ocoverer),
+	}
+	require.NoError(t, infos.Validate())
+	require.NoError(t, infos.CheckCoverers(icoverer, ocoverer, false))
+	require.Error(t, infos.CheckCoverers(ocoverer, icoverer, false))
+
+	// older DBs without cover parameters
+	require.NoError(t, (&IndexInfos{MinCoverLevel: 10}).Validate())
+	require.NoError(t, (&IndexInfos{MinCoverLevel: 10}).CheckCoverers(icoverer, ocoverer, false))
+
+	infos.MinCoverLevel = 12
+	require.Error(t, infos.Validate())
+
+	for _, o := range []CoverOptions{
+		{MinLevel: 16, MaxLevel: 10, MaxCells: 8, LevelMod: 1},
+		{MinLevel: 10, MaxLevel: 31, MaxCells: 8, LevelMod: 1},
+		{MinLevel: 10, MaxLevel: 16, MaxCells: 0, LevelMod: 1},
+		{MinLevel: 10, MaxLevel: 16, MaxCells: 8, LevelMod: 4},
+	} {
+		o := o
+		require.Error(t, o.Validate(), o)
+	}
+}
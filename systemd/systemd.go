@@ -0,0 +1,119 @@
+// Package systemd implements the systemd service notifications and socket activation,
+// see sd_notify(3) and sd_listen_fds(3), all are no-ops when the process is not started by systemd.
+package systemd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the first file descriptor passed by systemd, SD_LISTEN_FDS_START
+const listenFdsStart = 3
+
+const (
+	// Ready tells systemd the service is ready, Type=notify units are started once received
+	Ready = "READY=1"
+
+	// Reloading tells systemd the service is reloading its configuration, Ready is sent when done
+	Reloading = "RELOADING=1"
+
+	// Stopping tells systemd the service is shutting down
+	Stopping = "STOPPING=1"
+
+	// Watchdog keeps the watchdog of a WatchdogSec= unit from killing the service
+	Watchdog = "WATCHDOG=1"
+)
+
+// Notify sends the states, like Ready or "STATUS=...", to the notification socket of systemd,
+// it returns false when the process was not started by systemd
+func Notify(states ...string) (bool, error) {
+	path := os.Getenv("NOTIFY_SOCKET")
+	if path == "" {
+		return false, nil
+	}
+	// abstract namespace socket
+	if path[0] == '@' {
+		path = "\x00" + path[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		return false, fmt.Errorf("can't dial systemd notify socket: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(strings.Join(states, "\n"))); err != nil {
+		return false, fmt.Errorf("can't notify systemd: %w", err)
+	}
+	return true, nil
+}
+
+// WatchdogInterval returns the interval the Watchdog notifications must be sent at, half the watchdog timeout
+// of the unit, 0 when the watchdog is disabled
+func WatchdogInterval() (time.Duration, error) {
+	s := os.Getenv("WATCHDOG_USEC")
+	if s == "" {
+		return 0, nil
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0, nil
+	}
+	usec, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || usec <= 0 {
+		return 0, fmt.Errorf("invalid WATCHDOG_USEC %q", s)
+	}
+	return time.Duration(usec) * time.Microsecond / 2, nil
+}
+
+// Listeners returns the listeners passed by systemd socket activation by their FileDescriptorName=,
+// empty when the process was not activated by a socket. The environment is unset so the children don't inherit them.
+func Listeners() (map[string]net.Listener, error) {
+	defer func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+
+	lns := make(map[string]net.Listener)
+	if os.Getenv("LISTEN_PID") != strconv.Itoa(os.Getpid()) {
+		return lns, nil
+	}
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count < 0 {
+		return nil, fmt.Errorf("invalid LISTEN_FDS %q", os.Getenv("LISTEN_FDS"))
+	}
+
+	var names []string
+	if s := os.Getenv("LISTEN_FDNAMES"); s != "" {
+		names = strings.Split(s, ":")
+	}
+	if len(names) != count {
+		return nil, errors.New("the sockets must be named with FileDescriptorName=")
+	}
+
+	for i, name := range names {
+		f := os.NewFile(uintptr(listenFdsStart+i), name)
+		// FileListener dups the descriptor with close on exec
+		ln, err := net.FileListener(f)
+		f.Close()
+		if err == nil {
+			if _, ok := lns[name]; ok {
+				ln.Close()
+				err = errors.New("socket name used twice")
+			}
+		}
+		if err != nil {
+			for _, ln := range lns {
+				ln.Close()
+			}
+			return nil, fmt.Errorf("socket %s: %w", name, err)
+		}
+		lns[name] = ln
+	}
+	return lns, nil
+}
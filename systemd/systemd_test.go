@@ -0,0 +1,87 @@
+package systemd
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotify(t *testing.T) {
+	os.Unsetenv("NOTIFY_SOCKET")
+	ok, err := Notify(Ready)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	dir, err := ioutil.TempDir("", "insideout-systemd-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "notify")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	os.Setenv("NOTIFY_SOCKET", path)
+	defer os.Unsetenv("NOTIFY_SOCKET")
+	ok, err = Notify(Ready, "STATUS=serving")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	b := make([]byte, 1024)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	n, err := conn.Read(b)
+	require.NoError(t, err)
+	require.Equal(t, "READY=1\nSTATUS=serving", string(b[:n]))
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	defer os.Unsetenv("WATCHDOG_USEC")
+	defer os.Unsetenv("WATCHDOG_PID")
+
+	d, err := WatchdogInterval()
+	require.NoError(t, err)
+	require.Zero(t, d)
+
+	os.Setenv("WATCHDOG_USEC", "10000000")
+	d, err = WatchdogInterval()
+	require.NoError(t, err)
+	require.Equal(t, 5*time.Second, d)
+
+	// for another process
+	os.Setenv("WATCHDOG_PID", "1")
+	d, err = WatchdogInterval()
+	require.NoError(t, err)
+	require.Zero(t, d)
+
+	os.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()))
+	os.Setenv("WATCHDOG_USEC", "soon")
+	_, err = WatchdogInterval()
+	require.Error(t, err)
+}
+
+func TestListeners(t *testing.T) {
+	lns, err := Listeners()
+	require.NoError(t, err)
+	require.Empty(t, lns)
+
+	// for another process
+	os.Setenv("LISTEN_PID", "1")
+	os.Setenv("LISTEN_FDS", "1")
+	lns, err = Listeners()
+	require.NoError(t, err)
+	require.Empty(t, lns)
+	require.Empty(t, os.Getenv("LISTEN_FDS"))
+
+	// unnamed sockets
+	os.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+	os.Setenv("LISTEN_FDS", "2")
+	os.Setenv("LISTEN_FDNAMES", "grpc")
+	_, err = Listeners()
+	require.Error(t, err)
+}
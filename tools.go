@@ -1,11 +1,12 @@
 package insideout
 
 import (
-	"bytes"
 	"encoding/binary"
 	"fmt"
+	"math"
 	"strings"
 
+	"github.com/golang/geo/s1"
 	"github.com/golang/geo/s2"
 	spb "github.com/golang/protobuf/ptypes/struct"
 	"github.com/pkg/errors"
@@ -20,6 +21,10 @@ const (
 	cellPrefix    byte = 'C'
 	infoKey       byte = 'i'
 	mapKey        byte = 'm'
+	hierarchyKey  byte = 'h'
+	h3Key         byte = 'H'
+	cellFilterKey byte = 'b'
+	propertiesKey byte = 'p'
 	// reserved T & t for tiles
 	TilesURLPrefix byte = 't'
 	TilesPrefix    byte = 'T'
@@ -27,9 +32,16 @@ const (
 	InsideTreeStrategy = "insidetree"
 	DBStrategy         = "db"
 	ShapeIndexStrategy = "shapeindex"
+	MemoryStrategy     = "memory"
+	PostGISStrategy    = "postgis"
+	HybridStrategy     = "hybrid"
+	H3Strategy         = "h3"
+
+	// EarthRadiusMeters the mean earth radius used to convert s2 angles to meters
+	EarthRadiusMeters = 6371010.0
 )
 
-// GeoJSONCoverCellUnion generates an s2 cover normalized
+// GeoJSONCoverCellUnion generates an s2 cover normalized, the holes of the polygons excluded
 func GeoJSONCoverCellUnion(f *geojson.Feature, coverer *s2.RegionCoverer, interior bool) ([]s2.CellUnion, error) {
 	if f.Geometry == nil {
 		return nil, errors.New("invalid geometry")
@@ -38,8 +50,7 @@ func GeoJSONCoverCellUnion(f *geojson.Feature, coverer *s2.RegionCoverer, interi
 
 	switch rg := f.Geometry.(type) {
 	case *geom.Polygon:
-		// only supports outer ring
-		cup, err := coverPolygon(rg.FlatCoords(), coverer, interior)
+		cup, err := coverPolygon(rg, coverer, interior)
 		if err != nil {
 			return nil, errors.Wrap(err, "can't cover polygon")
 		}
@@ -47,7 +58,7 @@ func GeoJSONCoverCellUnion(f *geojson.Feature, coverer *s2.RegionCoverer, interi
 	case *geom.MultiPolygon:
 		for i := 0; i < rg.NumPolygons(); i++ {
 			p := rg.Polygon(i)
-			cup, err := coverPolygon(p.FlatCoords(), coverer, interior)
+			cup, err := coverPolygon(p, coverer, interior)
 			if err != nil {
 				return nil, errors.Wrapf(err, "can't cover multi polygon %d", i)
 			}
@@ -63,6 +74,12 @@ func GeoJSONCoverCellUnion(f *geojson.Feature, coverer *s2.RegionCoverer, interi
 
 // GeoJSONEncodeLoops encodes all MultiPolygons and Polygons as loops []byte
 func GeoJSONEncodeLoops(f *geojson.Feature) ([][]byte, error) {
+	return GeoJSONEncodeLoopsWith(f, S2LoopEncoding)
+}
+
+// GeoJSONEncodeLoopsWith encodes the exterior rings of all MultiPolygons and Polygons as loops []byte with encoding,
+// see EncodeLoop, the holes are encoded by GeoJSONEncodeHolesWith
+func GeoJSONEncodeLoopsWith(f *geojson.Feature, encoding string) ([][]byte, error) {
 	if f.Geometry == nil {
 		return nil, errors.New("invalid geometry")
 	}
@@ -70,25 +87,19 @@ func GeoJSONEncodeLoops(f *geojson.Feature) ([][]byte, error) {
 
 	switch rg := f.Geometry.(type) {
 	case *geom.Polygon:
-		// only supports outer ring
-		lb := new(bytes.Buffer)
-		l := LoopFromCoordinates(rg.FlatCoords())
-		err := l.Encode(lb)
+		lb, err := EncodeLoop(exteriorLoop(rg), encoding)
 		if err != nil {
 			return nil, errors.Wrap(err, "can't encode polygon")
 		}
-		b = append(b, lb.Bytes())
+		b = append(b, lb)
 
 	case *geom.MultiPolygon:
 		for i := 0; i < rg.NumPolygons(); i++ {
-			lb := new(bytes.Buffer)
-			p := rg.Polygon(i)
-			l := LoopFromCoordinates(p.FlatCoords())
-			err := l.Encode(lb)
+			lb, err := EncodeLoop(exteriorLoop(rg.Polygon(i)), encoding)
 			if err != nil {
 				return nil, errors.Wrap(err, "can't encode polygon")
 			}
-			b = append(b, lb.Bytes())
+			b = append(b, lb)
 		}
 
 	default:
@@ -98,8 +109,106 @@ func GeoJSONEncodeLoops(f *geojson.Feature) ([][]byte, error) {
 	return b, nil
 }
 
-// coverPolygon returns an s2 cover from a list of lng, lat forming a closed polygon
-func coverPolygon(c []float64, coverer *s2.RegionCoverer, interior bool) (s2.CellUnion, error) {
+// GeoJSONEncodeHoles encodes the holes of all MultiPolygons and Polygons as loops []byte
+func GeoJSONEncodeHoles(f *geojson.Feature) ([][][]byte, error) {
+	return GeoJSONEncodeHolesWith(f, S2LoopEncoding)
+}
+
+// GeoJSONEncodeHolesWith encodes the holes of each polygon of all MultiPolygons and Polygons as loops []byte
+// with encoding, normalized to contain the area they remove, nil when no polygon has holes
+func GeoJSONEncodeHolesWith(f *geojson.Feature, encoding string) ([][][]byte, error) {
+	var polygons []*geom.Polygon
+	switch rg := f.Geometry.(type) {
+	case *geom.Polygon:
+		polygons = []*geom.Polygon{rg}
+	case *geom.MultiPolygon:
+		for i := 0; i < rg.NumPolygons(); i++ {
+			polygons = append(polygons, rg.Polygon(i))
+		}
+	default:
+		return nil, errors.New("unsupported data type")
+	}
+
+	var b [][][]byte
+	for i, p := range polygons {
+		holes := holeLoops(p)
+		if len(holes) == 0 {
+			continue
+		}
+		if b == nil {
+			b = make([][][]byte, len(polygons))
+		}
+		for _, h := range holes {
+			hb, err := EncodeLoop(h, encoding)
+			if err != nil {
+				return nil, errors.Wrapf(err, "can't encode hole of polygon %d", i)
+			}
+			b[i] = append(b[i], hb)
+		}
+	}
+	return b, nil
+}
+
+// DecodeHoles decodes the holes encoded by GeoJSONEncodeHolesWith
+func DecodeHoles(hbs [][][]byte) ([][]*s2.Loop, error) {
+	if len(hbs) == 0 {
+		return nil, nil
+	}
+	holes := make([][]*s2.Loop, len(hbs))
+	for i, lbs := range hbs {
+		for j, lb := range lbs {
+			l, err := DecodeLoop(lb)
+			if err != nil {
+				return nil, errors.Wrapf(err, "can't decode hole %d of loop %d", j, i)
+			}
+			holes[i] = append(holes[i], l)
+		}
+	}
+	return holes, nil
+}
+
+// GeoJSONDecodeLoops decodes loops encoded by GeoJSONEncodeLoops to a Polygon, or a MultiPolygon for several loops
+func GeoJSONDecodeLoops(lbs [][]byte) (geom.T, error) {
+	return GeoJSONDecodePolygons(lbs, nil)
+}
+
+// GeoJSONDecodePolygons decodes loops encoded by GeoJSONEncodeLoops and their holes encoded by GeoJSONEncodeHoles
+// to a Polygon, or a MultiPolygon for several loops, the holes clockwise
+func GeoJSONDecodePolygons(lbs [][]byte, hbs [][][]byte) (geom.T, error) {
+	if len(lbs) == 0 {
+		return nil, errors.New("no loops")
+	}
+	holes, err := DecodeHoles(hbs)
+	if err != nil {
+		return nil, err
+	}
+	mp := geom.NewMultiPolygon(geom.XY)
+	for i, lb := range lbs {
+		l, err := DecodeLoop(lb)
+		if err != nil {
+			return nil, errors.Wrapf(err, "can't decode loop %d", i)
+		}
+		var hs []*s2.Loop
+		if i < len(holes) {
+			hs = holes[i]
+		}
+		c, ends := CoordinatesFromPolygon(l, hs)
+		if err := mp.Push(geom.NewPolygonFlat(geom.XY, c, ends)); err != nil {
+			return nil, err
+		}
+	}
+	if mp.NumPolygons() == 1 {
+		return mp.Polygon(0), nil
+	}
+	return mp, nil
+}
+
+// coverPolygon returns an s2 cover of the exterior ring of p without its holes
+func coverPolygon(p *geom.Polygon, coverer *s2.RegionCoverer, interior bool) (s2.CellUnion, error) {
+	if p.NumLinearRings() == 0 {
+		return nil, errors.New("invalid polygons no ring")
+	}
+	c := p.LinearRing(0).FlatCoords()
 	if len(c) < 6 {
 		return nil, errors.New("invalid polygons not enough coordinates for a closed polygon")
 	}
@@ -107,13 +216,91 @@ func coverPolygon(c []float64, coverer *s2.RegionCoverer, interior bool) (s2.Cel
 		return nil, errors.New("invalid polygons odd coordinates number")
 	}
 	l := LoopFromCoordinates(c)
-	if l.IsEmpty() || l.IsFull() || l.ContainsOrigin() {
+	if l.IsEmpty() || l.IsFull() {
 		return nil, errors.New("invalid polygons")
 	}
-	if interior {
-		return coverer.InteriorCovering(l), nil
+	// the loops around a pole are supported, a loop larger than a hemisphere is a clockwise ring
+	if l.Area() > 2*math.Pi {
+		return nil, errors.New("invalid polygons clockwise exterior ring")
+	}
+
+	// the polygon interior is the points contained by an odd number of its nested loops,
+	// the holes crossing the exterior ring are removed from the inside cover
+	loops := []*s2.Loop{l}
+	var crossing []*s2.Loop
+	for _, h := range holeLoops(p) {
+		if l.Contains(h) {
+			loops = append(loops, h)
+			continue
+		}
+		crossing = append(crossing, h)
+	}
+	var region s2.Region = l
+	if len(loops) > 1 {
+		region = s2.PolygonFromLoops(loops)
+	}
+	if !interior {
+		return coverer.Covering(region), nil
 	}
-	return coverer.Covering(l), nil
+	cu := coverer.InteriorCovering(region)
+	for _, h := range crossing {
+		cu = s2.CellUnionFromDifference(cu, coverer.Covering(h))
+	}
+	return cu, nil
+}
+
+// PolygonLoops returns the loops of the exterior rings of the polygons of g, a Polygon or a MultiPolygon,
+// and their holes, nil when no polygon has holes, as stored in a Feature
+func PolygonLoops(g geom.T) ([]*s2.Loop, [][]*s2.Loop, error) {
+	var polygons []*geom.Polygon
+	switch rg := g.(type) {
+	case *geom.Polygon:
+		polygons = []*geom.Polygon{rg}
+	case *geom.MultiPolygon:
+		for i := 0; i < rg.NumPolygons(); i++ {
+			polygons = append(polygons, rg.Polygon(i))
+		}
+	default:
+		return nil, nil, errors.New("unsupported data type")
+	}
+
+	loops := make([]*s2.Loop, len(polygons))
+	var holes [][]*s2.Loop
+	for i, p := range polygons {
+		if loops[i] = exteriorLoop(p); loops[i] == nil {
+			return nil, nil, errors.New("invalid polygons not enough coordinates for a closed polygon")
+		}
+		if hs := holeLoops(p); len(hs) > 0 {
+			if holes == nil {
+				holes = make([][]*s2.Loop, len(polygons))
+			}
+			holes[i] = hs
+		}
+	}
+	return loops, holes, nil
+}
+
+// exteriorLoop returns the loop of the exterior ring of p
+func exteriorLoop(p *geom.Polygon) *s2.Loop {
+	if p.NumLinearRings() == 0 {
+		return s2.EmptyLoop()
+	}
+	return LoopFromCoordinates(p.LinearRing(0).FlatCoords())
+}
+
+// holeLoops returns the loops of the holes of p normalized, a counterclockwise hole read as its complement is inverted,
+// the degenerate holes are skipped
+func holeLoops(p *geom.Polygon) []*s2.Loop {
+	var holes []*s2.Loop
+	for i := 1; i < p.NumLinearRings(); i++ {
+		l := LoopFromCoordinates(p.LinearRing(i).FlatCoords())
+		if l == nil || l.IsEmpty() || l.IsFull() {
+			continue
+		}
+		l.Normalize()
+		holes = append(holes, l)
+	}
+	return holes
 }
 
 // LoopFromCoordinates creates a LoopFence from a list of lng lat
@@ -127,15 +314,83 @@ func LoopFromCoordinates(c []float64) *s2.Loop {
 		points[i/2] = s2.PointFromLatLng(s2.LatLngFromDegrees(c[i+1], c[i]))
 	}
 
-	if points[0] == points[len(points)-1] {
-		// remove last item if same as 1st
-		points = append(points[:len(points)-1], points[len(points)-1+1:]...)
+	points = loopPoints(points)
+	if len(points) < 3 {
+		return s2.EmptyLoop()
 	}
 
 	loop := s2.LoopFromPoints(points)
 	return loop
 }
 
+// PolylineFromCoordinates creates a Polyline from a list of lng lat
+func PolylineFromCoordinates(c []float64) *s2.Polyline {
+	if len(c)%2 != 0 || len(c) < 2*2 {
+		return nil
+	}
+	lls := make([]s2.LatLng, len(c)/2)
+	for i := 0; i < len(c); i += 2 {
+		lls[i/2] = s2.LatLngFromDegrees(c[i+1], c[i])
+	}
+	return s2.PolylineFromLatLngs(lls)
+}
+
+// LoopIntersectsPolyline returns true if pl is crossing or inside l
+func LoopIntersectsPolyline(l *s2.Loop, pl *s2.Polyline) bool {
+	for _, p := range *pl {
+		if l.ContainsPoint(p) {
+			return true
+		}
+	}
+	for i := 0; i+1 < len(*pl); i++ {
+		for j := 0; j < l.NumEdges(); j++ {
+			e := l.Edge(j)
+			if s2.CrossingSign((*pl)[i], (*pl)[i+1], e.V0, e.V1) != s2.DoNotCross {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// LoopContainsPolyline returns true if pl is inside l without crossing it
+func LoopContainsPolyline(l *s2.Loop, pl *s2.Polyline) bool {
+	for _, p := range *pl {
+		if !l.ContainsPoint(p) {
+			return false
+		}
+	}
+	for i := 0; i+1 < len(*pl); i++ {
+		for j := 0; j < l.NumEdges(); j++ {
+			e := l.Edge(j)
+			if s2.CrossingSign((*pl)[i], (*pl)[i+1], e.V0, e.V1) != s2.DoNotCross {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// DistanceToLoop returns the distance from p to the closest edge of l
+func DistanceToLoop(p s2.Point, l *s2.Loop) s1.Angle {
+	minDist := s1.InfChordAngle()
+	for i := 0; i < l.NumEdges(); i++ {
+		e := l.Edge(i)
+		minDist, _ = s2.UpdateMinDistance(p, e.V0, e.V1, minDist)
+	}
+	return minDist.Angle()
+}
+
+// AngleToMeters converts an s2 angle to meters on earth
+func AngleToMeters(a s1.Angle) float64 {
+	return a.Radians() * EarthRadiusMeters
+}
+
+// MetersToAngle converts meters on earth to an s2 angle
+func MetersToAngle(m float64) s1.Angle {
+	return s1.Angle(m / EarthRadiusMeters)
+}
+
 // CoordinatesFromLoops returns []float64 as lng lat adding 1st as last suitable for GeoJSON
 func CoordinatesFromLoops(l *s2.Loop) []float64 {
 	points := l.Vertices()
@@ -152,6 +407,22 @@ func CoordinatesFromLoops(l *s2.Loop) []float64 {
 	return coords
 }
 
+// CoordinatesFromPolygon returns the flat lng lat coordinates and the ends of the rings of the polygon
+// of the loop l and its normalized holes, the holes clockwise, suitable for GeoJSON
+func CoordinatesFromPolygon(l *s2.Loop, holes []*s2.Loop) ([]float64, []int) {
+	c := CoordinatesFromLoops(l)
+	ends := []int{len(c)}
+	for _, h := range holes {
+		hc := CoordinatesFromLoops(h)
+		for i, j := 0, len(hc)-2; i < j; i, j = i+2, j-2 {
+			hc[i], hc[i+1], hc[j], hc[j+1] = hc[j], hc[j+1], hc[i], hc[i+1]
+		}
+		c = append(c, hc...)
+		ends = append(ends, len(c))
+	}
+	return c, ends
+}
+
 func InsideKey(c s2.CellID) []byte {
 	k := make([]byte, 1+8)
 	k[0] = insidePrefix
@@ -214,6 +485,36 @@ func MapKey() []byte {
 	return []byte{mapKey}
 }
 
+// HierarchyKey returns the key for the hierarchy entry
+func HierarchyKey() []byte {
+	return []byte{hierarchyKey}
+}
+
+// H3Key returns the key for the H3 cover entry
+func H3Key() []byte {
+	return []byte{h3Key}
+}
+
+// CellFilterKey returns the key for the cell filter entry
+func CellFilterKey() []byte {
+	return []byte{cellFilterKey}
+}
+
+// PropertiesKey returns the key for the property index entry
+func PropertiesKey() []byte {
+	return []byte{propertiesKey}
+}
+
+// InsidePrefix returns the key prefix for inside cells entry
+func InsidePrefix() byte {
+	return insidePrefix
+}
+
+// OutsidePrefix returns the key prefix for outside cells entry
+func OutsidePrefix() byte {
+	return outsidePrefix
+}
+
 // CellPrefix returns the key prefix for cells entry
 func CellPrefix() byte {
 	return cellPrefix
@@ -224,6 +525,54 @@ func FeaturePrefix() byte {
 	return featurePrefix
 }
 
+// RemoveIDFromCellValue returns a copy of an inside or outside cell value
+// without the entries of the feature id
+func RemoveIDFromCellValue(v []byte, id uint32) []byte {
+	res := make([]byte, 0, len(v))
+	for i := 0; i+6 <= len(v); i += 6 {
+		if binary.BigEndian.Uint32(v[i:]) == id {
+			continue
+		}
+		res = append(res, v[i:i+6]...)
+	}
+	return res
+}
+
+// MinCoverLevel returns the lowest level used by the coverers
+func MinCoverLevel(icoverer, ocoverer *s2.RegionCoverer) int {
+	if icoverer.MinLevel < ocoverer.MinLevel {
+		return icoverer.MinLevel
+	}
+	return ocoverer.MinLevel
+}
+
+// TunedCoverers returns coverers with the levels of icoverer and ocoverer shifted for a feature of bounds b,
+// by the difference between their min level and the level of the cells fitting b
+func TunedCoverers(b *geom.Bounds, icoverer, ocoverer *s2.RegionCoverer) (*s2.RegionCoverer, *s2.RegionCoverer) {
+	lo := s2.LatLngFromDegrees(b.Min(1), b.Min(0))
+	hi := s2.LatLngFromDegrees(b.Max(1), b.Max(0))
+	fit := s2.AvgDiagMetric.MinLevel(lo.Distance(hi).Radians())
+	shift := fit - MinCoverLevel(icoverer, ocoverer)
+
+	tune := func(c *s2.RegionCoverer) *s2.RegionCoverer {
+		tc := *c
+		tc.MinLevel = clampLevel(c.MinLevel + shift)
+		tc.MaxLevel = clampLevel(c.MaxLevel + shift)
+		return &tc
+	}
+	return tune(icoverer), tune(ocoverer)
+}
+
+func clampLevel(l int) int {
+	if l < 0 {
+		return 0
+	}
+	if l > 30 {
+		return 30
+	}
+	return l
+}
+
 // PropertiesToValues converts feature's properties to protobuf Value
 func PropertiesToValues(f *Feature) (map[string]*spb.Value, error) {
 	m := make(map[string]*spb.Value)
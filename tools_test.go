@@ -0,0 +1,91 @@
+package insideout
+
+import (
+	"testing"
+
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/geojson"
+)
+
+func TestTunedCoverers(t *testing.T) {
+	icoverer := &s2.RegionCoverer{MinLevel: 10, MaxLevel: 16, MaxCells: 24}
+	ocoverer := &s2.RegionCoverer{MinLevel: 10, MaxLevel: 15, MaxCells: 16}
+
+	tests := []struct {
+		name             string
+		bounds           *geom.Bounds
+		imin, imax, omax int
+	}{
+		{"city", geom.NewBounds(geom.XY).Set(2.25, 48.81, 2.42, 48.90), 10, 16, 15},
+		{"building", geom.NewBounds(geom.XY).Set(2.2945, 48.8584, 2.2950, 48.8587), 19, 25, 24},
+		{"continent", geom.NewBounds(geom.XY).Set(-10, 35, 40, 70), 2, 8, 7},
+		{"point", geom.NewBounds(geom.XY).Set(2, 48, 2, 48), 30, 30, 30},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ic, oc := TunedCoverers(tt.bounds, icoverer, ocoverer)
+			require.Equal(t, tt.imin, ic.MinLevel)
+			require.Equal(t, tt.imax, ic.MaxLevel)
+			require.Equal(t, tt.imin, oc.MinLevel)
+			require.Equal(t, tt.omax, oc.MaxLevel)
+			require.Equal(t, 24, ic.MaxCells)
+		})
+	}
+
+	// the coverers are not modified
+	require.Equal(t, 10, icoverer.MinLevel)
+}
+
+func TestGeoJSONDecodeLoops(t *testing.T) {
+	square := geom.NewPolygonFlat(geom.XY, []float64{2, 48, 3, 48, 3, 49, 2, 49, 2, 48}, []int{10})
+	lbs, err := GeoJSONEncodeLoops(&geojson.Feature{Geometry: square})
+	require.NoError(t, err)
+
+	g, err := GeoJSONDecodeLoops(lbs)
+	require.NoError(t, err)
+	p, ok := g.(*geom.Polygon)
+	require.True(t, ok)
+	require.InDeltaSlice(t, square.FlatCoords(), p.FlatCoords(), 1e-9)
+
+	g, err = GeoJSONDecodeLoops(append(lbs, lbs[0]))
+	require.NoError(t, err)
+	mp, ok := g.(*geom.MultiPolygon)
+	require.True(t, ok)
+	require.Equal(t, 2, mp.NumPolygons())
+
+	_, err = GeoJSONDecodeLoops(nil)
+	require.Error(t, err)
+}
+
+func TestGeoJSONDecodePolygons(t *testing.T) {
+	square := []float64{2, 48, 3, 48, 3, 49, 2, 49, 2, 48}
+	hole := []float64{2.2, 48.2, 2.2, 48.8, 2.8, 48.8, 2.8, 48.2, 2.2, 48.2}
+	f := &geojson.Feature{Geometry: geom.NewPolygonFlat(geom.XY, append(square, hole...), []int{10, 20})}
+
+	lbs, err := GeoJSONEncodeLoops(f)
+	require.NoError(t, err)
+	hbs, err := GeoJSONEncodeHoles(f)
+	require.NoError(t, err)
+	require.Len(t, hbs, 1)
+	require.Len(t, hbs[0], 1)
+
+	g, err := GeoJSONDecodePolygons(lbs, hbs)
+	require.NoError(t, err)
+	p, ok := g.(*geom.Polygon)
+	require.True(t, ok)
+	require.Equal(t, 2, p.NumLinearRings())
+	require.Empty(t, ValidateGeometry(p))
+
+	// the interior cover leaves the hole out
+	cus, err := GeoJSONCoverCellUnion(f, &s2.RegionCoverer{MinLevel: 1, MaxLevel: 14, MaxCells: 64}, true)
+	require.NoError(t, err)
+	require.False(t, cus[0].ContainsPoint(s2.PointFromLatLng(s2.LatLngFromDegrees(48.5, 2.5))))
+
+	// no holes
+	hbs, err = GeoJSONEncodeHoles(&geojson.Feature{Geometry: geom.NewPolygonFlat(geom.XY, square, []int{10})})
+	require.NoError(t, err)
+	require.Nil(t, hbs)
+}
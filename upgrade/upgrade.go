@@ -0,0 +1,153 @@
+// Package upgrade hands the listening sockets of a running process over to a new process of its upgraded binary,
+// so the connections queued during the upgrade are accepted by one or the other and none is refused.
+// The old process starts the new one with Upgrade, the new one gets the sockets with Inherited, warms up,
+// then calls the returned ready func: Upgrade returns and the old process shuts down gracefully.
+package upgrade
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// envNames the names of the handed over sockets, separated by colons
+	envNames = "INSIDEOUT_UPGRADE_FDNAMES"
+
+	// the readiness pipe of the new process, followed by the sockets
+	readyFd = 3
+)
+
+// ErrNotReady is returned by Upgrade when the new process exits before being ready
+var ErrNotReady = errors.New("the new process exited before being ready")
+
+// filer is implemented by the TCP and unix listeners
+type filer interface {
+	File() (*os.File, error)
+}
+
+// Upgrade starts the executable of the process, with the same arguments, handing it over lns, by name.
+// It returns the pid of the new process once ready, or an error if it exits first or is not ready before timeout,
+// it is then killed. The old process keeps the ownership of lns.
+func Upgrade(ctx context.Context, lns map[string]net.Listener, timeout time.Duration) (int, error) {
+	path, err := os.Executable()
+	if err != nil {
+		return 0, fmt.Errorf("can't find the executable: %w", err)
+	}
+
+	names := make([]string, 0, len(lns))
+	for name := range lns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	rd, wr, err := os.Pipe()
+	if err != nil {
+		return 0, err
+	}
+	defer rd.Close()
+
+	files := []*os.File{os.Stdin, os.Stdout, os.Stderr, wr}
+	defer func() {
+		for _, f := range files[readyFd:] {
+			f.Close()
+		}
+	}()
+	for _, name := range names {
+		fl, ok := lns[name].(filer)
+		if !ok {
+			return 0, fmt.Errorf("socket %s can't be handed over", name)
+		}
+		// a duplicate of the socket
+		f, err := fl.File()
+		if err != nil {
+			return 0, fmt.Errorf("socket %s: %w", name, err)
+		}
+		files = append(files, f)
+	}
+
+	var env []string
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, envNames+"=") && !strings.HasPrefix(kv, "LISTEN_") {
+			env = append(env, kv)
+		}
+	}
+	env = append(env, envNames+"="+strings.Join(names, ":"))
+
+	p, err := os.StartProcess(path, os.Args, &os.ProcAttr{Env: env, Files: files})
+	if err != nil {
+		return 0, fmt.Errorf("can't start %s: %w", path, err)
+	}
+
+	// the pipe is closed when the child exits or is ready
+	wr.Close()
+	files = files[:readyFd]
+	exited := make(chan error, 1)
+	go func() {
+		var b [1]byte
+		_, err := rd.Read(b[:])
+		exited <- err
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	select {
+	case err := <-exited:
+		if err == nil {
+			// Release resets Pid
+			pid := p.Pid
+			p.Release()
+			return pid, nil
+		}
+		if err == io.EOF {
+			err = ErrNotReady
+		}
+		_, _ = p.Wait()
+		return 0, err
+	case <-ctx.Done():
+		_ = p.Kill()
+		_, _ = p.Wait()
+		return 0, fmt.Errorf("the new process is not ready: %w", ctx.Err())
+	}
+}
+
+// Inherited returns the listeners handed over by an upgrading process, by name, empty when the process was not started
+// by Upgrade. ready notifies the old process to shut down, it must be called once the process is serving.
+func Inherited() (lns map[string]net.Listener, ready func() error, err error) {
+	lns = make(map[string]net.Listener)
+	s, ok := os.LookupEnv(envNames)
+	if !ok {
+		return lns, func() error { return nil }, nil
+	}
+	os.Unsetenv(envNames)
+
+	var names []string
+	if s != "" {
+		names = strings.Split(s, ":")
+	}
+	for i, name := range names {
+		f := os.NewFile(uintptr(readyFd+1+i), name)
+		ln, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			for _, ln := range lns {
+				ln.Close()
+			}
+			return nil, nil, fmt.Errorf("socket %s: %w", name, err)
+		}
+		lns[name] = ln
+	}
+
+	pipe := os.NewFile(readyFd, "upgrade")
+	return lns, func() error {
+		defer pipe.Close()
+		_, err := pipe.Write([]byte{1})
+		return err
+	}, nil
+}
@@ -0,0 +1,100 @@
+package upgrade
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const envMode = "INSIDEOUT_UPGRADE_TEST_MODE"
+
+func TestMain(m *testing.M) {
+	if _, ok := os.LookupEnv(envNames); ok {
+		child()
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// child is the upgraded process, started from the test binary
+func child() {
+	lns, ready, err := Inherited()
+	if err != nil {
+		os.Exit(2)
+	}
+	switch os.Getenv(envMode) {
+	case "fail":
+		os.Exit(1)
+	case "hang":
+		time.Sleep(time.Minute)
+	}
+	if err := ready(); err != nil {
+		os.Exit(2)
+	}
+	conn, err := lns["test"].Accept()
+	if err != nil {
+		os.Exit(2)
+	}
+	_, _ = conn.Write([]byte("upgraded"))
+	conn.Close()
+	os.Exit(0)
+}
+
+func TestUpgrade(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	lns := map[string]net.Listener{"test": ln}
+	ctx := context.Background()
+	defer os.Unsetenv(envMode)
+
+	os.Setenv(envMode, "fail")
+	_, err = Upgrade(ctx, lns, 10*time.Second)
+	require.Equal(t, ErrNotReady, err)
+
+	os.Setenv(envMode, "hang")
+	_, err = Upgrade(ctx, lns, 200*time.Millisecond)
+	require.Error(t, err)
+
+	// still served by the old process
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			conn.Write([]byte("old"))
+			conn.Close()
+		}
+	}()
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	b, err := ioutil.ReadAll(conn)
+	require.NoError(t, err)
+	require.Equal(t, "old", string(b))
+	conn.Close()
+
+	os.Setenv(envMode, "ok")
+	pid, err := Upgrade(ctx, lns, 10*time.Second)
+	require.NoError(t, err)
+	require.True(t, pid > 0)
+	require.NotEqual(t, os.Getpid(), pid)
+
+	// the old process stops accepting, the socket stays open in the new one
+	require.NoError(t, ln.Close())
+	conn, err = net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+	b, err = ioutil.ReadAll(conn)
+	require.NoError(t, err)
+	require.Equal(t, "upgraded", string(b))
+}
+
+func TestInherited(t *testing.T) {
+	lns, ready, err := Inherited()
+	require.NoError(t, err)
+	require.Empty(t, lns)
+	require.NoError(t, ready())
+}
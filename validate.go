@@ -0,0 +1,159 @@
+package insideout
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/golang/geo/s2"
+	"github.com/twpayne/go-geom"
+)
+
+// Problem is an issue found in a geometry, making it invalid or wrongly indexed
+type Problem struct {
+	// Polygon index in a MultiPolygon, 0 for a Polygon
+	Polygon int
+	// Ring index in the polygon, 0 for the exterior ring, -1 for the whole geometry
+	Ring int
+	Msg  string
+}
+
+func (p Problem) String() string {
+	if p.Ring == -1 {
+		return p.Msg
+	}
+	return fmt.Sprintf("polygon #%d ring #%d: %s", p.Polygon, p.Ring, p.Msg)
+}
+
+// ValidateGeometry returns the problems found in g, nil when it can be indexed as is.
+// Exterior rings are expected counterclockwise and holes clockwise (RFC 7946),
+// a clockwise exterior ring is read as its complement and rejected by the indexer.
+func ValidateGeometry(g geom.T) []Problem {
+	var problems []Problem
+
+	switch rg := g.(type) {
+	case *geom.Polygon:
+		problems = validatePolygon(rg, 0)
+	case *geom.MultiPolygon:
+		if rg.NumPolygons() == 0 {
+			return []Problem{{Ring: -1, Msg: "empty multipolygon"}}
+		}
+		for i := 0; i < rg.NumPolygons(); i++ {
+			problems = append(problems, validatePolygon(rg.Polygon(i), i)...)
+		}
+	case nil:
+		return []Problem{{Ring: -1, Msg: "missing geometry"}}
+	default:
+		return []Problem{{Ring: -1, Msg: fmt.Sprintf("unsupported geometry type %T, only polygons are indexed", g)}}
+	}
+
+	return problems
+}
+
+func validatePolygon(p *geom.Polygon, pi int) []Problem {
+	if p.NumLinearRings() == 0 {
+		return []Problem{{Polygon: pi, Msg: "empty polygon"}}
+	}
+
+	var problems []Problem
+	valid := make([]bool, p.NumLinearRings())
+	for ri := 0; ri < p.NumLinearRings(); ri++ {
+		msgs := validateRing(p.LinearRing(ri).FlatCoords(), p.Stride(), ri == 0)
+		for _, msg := range msgs {
+			problems = append(problems, Problem{Polygon: pi, Ring: ri, Msg: msg})
+		}
+		valid[ri] = len(msgs) == 0
+	}
+
+	// a hole crossing or outside its exterior ring
+	if !valid[0] || p.Stride() != 2 {
+		return problems
+	}
+	exterior := LoopFromCoordinates(p.LinearRing(0).FlatCoords())
+	for ri := 1; ri < p.NumLinearRings(); ri++ {
+		if !valid[ri] {
+			continue
+		}
+		h := LoopFromCoordinates(p.LinearRing(ri).FlatCoords())
+		h.Normalize()
+		if !exterior.Contains(h) {
+			problems = append(problems, Problem{Polygon: pi, Ring: ri, Msg: "hole not inside the exterior ring"})
+		}
+	}
+	return problems
+}
+
+// validateRing returns the problems of the ring c with stride coordinates per point
+func validateRing(c []float64, stride int, exterior bool) []string {
+	n := len(c) / stride
+	if n < 4 {
+		return []string{fmt.Sprintf("%d points, a ring needs at least 4", n)}
+	}
+
+	var problems []string
+	for i := 0; i < n; i++ {
+		lng, lat := c[i*stride], c[i*stride+1]
+		// the longitudes beyond 180 of the rings crossing the antimeridian are normalized
+		if math.IsNaN(lng) || math.IsNaN(lat) || lng < -360 || lng > 360 || lat < -90 || lat > 90 {
+			return []string{fmt.Sprintf("point #%d invalid coordinates %f,%f", i, lng, lat)}
+		}
+	}
+
+	if c[0] != c[(n-1)*stride] || c[1] != c[(n-1)*stride+1] {
+		problems = append(problems, "ring not closed")
+	}
+
+	// the points without the closing one, the same point of different coordinates on the antimeridian
+	// or at a pole is not a problem
+	points := make([]s2.Point, 0, n)
+	seen := make(map[[2]float64]int, n)
+	for i := 0; i < n; i++ {
+		coords := [2]float64{c[i*stride], c[i*stride+1]}
+		if i == n-1 && i > 0 && coords == [2]float64{c[0], c[1]} {
+			break
+		}
+		if i > 0 && coords == [2]float64{c[(i-1)*stride], c[(i-1)*stride+1]} {
+			problems = append(problems, fmt.Sprintf("point #%d duplicates the previous point", i))
+			continue
+		}
+		if j, ok := seen[coords]; ok {
+			problems = append(problems, fmt.Sprintf("ring touches itself at point #%d and #%d", j, i))
+		}
+		seen[coords] = i
+		points = append(points, s2.PointFromLatLng(s2.LatLngFromDegrees(coords[1], coords[0])))
+	}
+	points = loopPoints(points)
+	if len(points) < 3 {
+		return append(problems, "less than 3 distinct points")
+	}
+
+	if e1, e2, ok := selfIntersection(points); ok {
+		problems = append(problems, fmt.Sprintf("self-intersection between edges #%d and #%d", e1, e2))
+	}
+
+	switch cw := clockwise(points); {
+	case exterior && cw:
+		problems = append(problems, "exterior ring is clockwise")
+	case !exterior && !cw:
+		problems = append(problems, "hole is counterclockwise")
+	}
+
+	return problems
+}
+
+// selfIntersection returns the first pair of edges of the loop crossing each other
+func selfIntersection(points []s2.Point) (int, int, bool) {
+	l := s2.LoopFromPoints(points)
+	index := s2.NewShapeIndex()
+	index.Add(l)
+	q := s2.NewCrossingEdgeQuery(index)
+
+	for i := 0; i < l.NumEdges(); i++ {
+		e := l.Edge(i)
+		for _, j := range q.Crossings(e.V0, e.V1, l, s2.CrossingTypeInterior) {
+			if j != i {
+				return i, j, true
+			}
+		}
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,79 @@
+package insideout
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom"
+)
+
+func TestValidateGeometry(t *testing.T) {
+	square := []float64{2, 48, 3, 48, 3, 49, 2, 49, 2, 48}
+	hole := []float64{2.2, 48.2, 2.2, 48.8, 2.8, 48.8, 2.8, 48.2, 2.2, 48.2}
+
+	tests := []struct {
+		name string
+		g    geom.T
+		want []string
+	}{
+		{"valid", geom.NewPolygonFlat(geom.XY, square, []int{10}), nil},
+		{"valid with hole", geom.NewPolygonFlat(geom.XY, append(square, hole...), []int{10, 20}), nil},
+		{
+			"clockwise",
+			geom.NewPolygonFlat(geom.XY, []float64{2, 48, 2, 49, 3, 49, 3, 48, 2, 48}, []int{10}),
+			[]string{"polygon #0 ring #0: exterior ring is clockwise"},
+		},
+		{
+			"counterclockwise hole",
+			geom.NewPolygonFlat(geom.XY, append(square, 2.2, 48.2, 2.8, 48.2, 2.8, 48.8, 2.2, 48.8, 2.2, 48.2), []int{10, 20}),
+			[]string{"polygon #0 ring #1: hole is counterclockwise"},
+		},
+		{
+			"hole outside",
+			geom.NewPolygonFlat(geom.XY, append(square, 5, 5, 5, 6, 6, 6, 6, 5, 5, 5), []int{10, 20}),
+			[]string{"polygon #0 ring #1: hole not inside the exterior ring"},
+		},
+		{
+			"bowtie",
+			geom.NewPolygonFlat(geom.XY, []float64{2, 48, 3, 49, 3, 48, 2, 49, 2, 48}, []int{10}),
+			[]string{"polygon #0 ring #0: self-intersection between edges #0 and #2"},
+		},
+		{
+			"not closed",
+			geom.NewPolygonFlat(geom.XY, []float64{2, 48, 3, 48, 3, 49, 2, 49}, []int{8}),
+			[]string{"polygon #0 ring #0: ring not closed"},
+		},
+		{
+			"duplicate point",
+			geom.NewPolygonFlat(geom.XY, []float64{2, 48, 3, 48, 3, 48, 3, 49, 2, 49, 2, 48}, []int{12}),
+			[]string{"polygon #0 ring #0: point #2 duplicates the previous point"},
+		},
+		{
+			"too few points",
+			geom.NewPolygonFlat(geom.XY, []float64{2, 48, 3, 48, 2, 48}, []int{6}),
+			[]string{"polygon #0 ring #0: 3 points, a ring needs at least 4"},
+		},
+		{
+			"invalid coordinates",
+			geom.NewPolygonFlat(geom.XY, []float64{2, 48, 3, 48, 3, 91, 2, 48}, []int{8}),
+			[]string{"polygon #0 ring #0: point #2 invalid coordinates 3.000000,91.000000"},
+		},
+		{
+			"multipolygon",
+			geom.NewMultiPolygonFlat(geom.XY, append(square, 4, 48, 4, 49, 5, 49, 5, 48, 4, 48), [][]int{{10}, {20}}),
+			[]string{"polygon #1 ring #0: exterior ring is clockwise"},
+		},
+		{"point", geom.NewPointFlat(geom.XY, []float64{2, 48}), []string{"unsupported geometry type *geom.Point, only polygons are indexed"}},
+		{"nil", nil, []string{"missing geometry"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, p := range ValidateGeometry(tt.g) {
+				got = append(got, p.String())
+			}
+			require.Equal(t, tt.want, got)
+		})
+	}
+}